@@ -45,9 +45,11 @@ require (
 	github.com/walle/targz v0.0.0-20140417120357-57fe4206da5a
 	github.com/wk8/go-ordered-map/v2 v2.1.8
 	golang.org/x/exp v0.0.0-20250106191152-7588d65b2ba8
+	golang.org/x/sync v0.10.0
 	gopkg.in/k8snetworkplumbingwg/multus-cni.v4 v4.1.4
 	gopkg.in/yaml.v2 v2.4.0
 	k8s.io/api v0.31.5
+	k8s.io/apiextensions-apiserver v0.31.5
 	k8s.io/apimachinery v0.31.5
 	k8s.io/client-go v12.0.0+incompatible
 	k8s.io/utils v0.0.0-20241210054802-24370beab758
@@ -55,6 +57,7 @@ require (
 	open-cluster-management.io/governance-policy-propagator v0.15.0
 	open-cluster-management.io/multicloud-operators-subscription v0.15.0
 	sigs.k8s.io/controller-runtime v0.19.5
+	sigs.k8s.io/yaml v1.4.0
 )
 
 require (
@@ -169,7 +172,7 @@ require (
 	github.com/opencontainers/image-spec v1.1.0 // indirect
 	github.com/opencontainers/runtime-spec v1.2.0 // indirect
 	github.com/openshift-kni/cluster-group-upgrades-operator v0.0.0-20241213003211-a57a58a5c4f2
-	github.com/openshift/custom-resource-status v1.1.3-0.20220503160415-f2fdb4999d87 // indirect
+	github.com/openshift/custom-resource-status v1.1.3-0.20220503160415-f2fdb4999d87
 	github.com/openshift/elasticsearch-operator v0.0.0-20241202183904-81cd6e70c15e // indirect
 	github.com/openshift/library-go v0.0.0-20240903143724-7c5c5d305ac1 // indirect
 	github.com/openshift/machine-config-operator v0.0.1-0.20231024085435-7e1fb719c1ba
@@ -213,7 +216,6 @@ require (
 	golang.org/x/crypto v0.32.0
 	golang.org/x/net v0.34.0 // indirect
 	golang.org/x/oauth2 v0.25.0 // indirect
-	golang.org/x/sync v0.10.0 // indirect
 	golang.org/x/sys v0.29.0 // indirect
 	golang.org/x/term v0.28.0 // indirect
 	golang.org/x/text v0.21.0 // indirect
@@ -225,7 +227,6 @@ require (
 	gopkg.in/inf.v0 v0.9.1 // indirect
 	gopkg.in/natefinch/lumberjack.v2 v2.2.1 // indirect
 	gorm.io/gorm v1.25.12 // indirect
-	k8s.io/apiextensions-apiserver v0.31.5 // indirect
 	k8s.io/apiserver v0.31.5 // indirect
 	k8s.io/cli-runtime v0.31.5 // indirect
 	k8s.io/component-base v0.31.5 // indirect
@@ -244,7 +245,6 @@ require (
 	sigs.k8s.io/kustomize/api v0.18.0 // indirect
 	sigs.k8s.io/kustomize/kyaml v0.18.1 // indirect
 	sigs.k8s.io/structured-merge-diff/v4 v4.4.1 // indirect
-	sigs.k8s.io/yaml v1.4.0 // indirect
 )
 
 require gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,293 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-goinfra/pkg/assisted"
+	"github.com/openshift-kni/eco-goinfra/pkg/bmh"
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/configmap"
+	"github.com/openshift-kni/eco-goinfra/pkg/hive"
+	"github.com/openshift-kni/eco-goinfra/pkg/namespace"
+	"github.com/openshift-kni/eco-goinfra/pkg/ocm"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	"github.com/openshift-kni/eco-goinfra/pkg/secret"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	runtimeClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// adoptedSpecFields lists, by resource kind, the spec fields of adopted resources compared with their definitions
+// when drift checking is enabled.
+var adoptedSpecFields = map[string][]string{
+	"clusterdeployment": {"spec.baseDomain", "spec.clusterName", "spec.clusterInstallRef", "spec.pullSecretRef"},
+	"agentclusterinstall": {
+		"spec.clusterDeploymentRef", "spec.imageSetRef", "spec.networking", "spec.provisionRequirements",
+	},
+	"infraenv": {"spec.clusterRef", "spec.pullSecretRef", "spec.cpuArchitecture"},
+}
+
+// existenceChecker is implemented by the builders of the spoke resources.
+type existenceChecker interface {
+	Exists() bool
+}
+
+// WithDriftCheck makes Create compare the key spec fields of an existing clusterdeployment, agentclusterinstall and
+// infraenv it adopts with their definitions, failing when they differ instead of adopting them as they are.
+func (spoke *SpokeClusterResources) WithDriftCheck() *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	spoke.checkDrift = true
+
+	return spoke
+}
+
+// Adopt reconstructs the resources of the spoke whose namespace is name from the hub so that an installation
+// started by another process can be resumed or removed. The namespace must exist; the pull-secret,
+// clusterdeployment, agentclusterinstall, extra manifests and mirror registry configmaps, infraenvs, nmstateconfigs,
+// baremetalhosts and BMC secrets are pulled when present. Late binding infraenvs in other namespaces are not adopted.
+func Adopt(apiClient *clients.Settings, name string) (*SpokeClusterResources, error) {
+	if apiClient == nil {
+		return nil, fmt.Errorf("apiClient cannot be nil")
+	}
+
+	spoke := NewSpokeCluster(apiClient).WithName(name)
+	if spoke.err != nil {
+		return nil, spoke.err
+	}
+
+	var err error
+
+	spoke.Namespace, err = namespace.Pull(apiClient, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to adopt spoke %s: %w", name, err)
+	}
+
+	spoke.PullSecret, _ = secret.Pull(apiClient, fmt.Sprintf("%s-pull-secret", name), name)
+	spoke.customPullSecret = spoke.PullSecret != nil
+	spoke.ClusterDeployment, _ = hive.PullClusterDeployment(apiClient, name, name)
+
+	agentClusterInstallName := name
+	if spoke.ClusterDeployment != nil && spoke.ClusterDeployment.Definition.Spec.ClusterInstallRef != nil {
+		agentClusterInstallName = spoke.ClusterDeployment.Definition.Spec.ClusterInstallRef.Name
+	}
+
+	spoke.AgentClusterInstall, _ = assisted.PullAgentClusterInstall(apiClient, agentClusterInstallName, name)
+	if spoke.AgentClusterInstall != nil {
+		for _, reference := range spoke.AgentClusterInstall.Definition.Spec.ManifestsConfigMapRefs {
+			if extraManifest, err := configmap.Pull(apiClient, reference.Name, name); err == nil {
+				spoke.ExtraManifests = append(spoke.ExtraManifests, extraManifest)
+			}
+		}
+	}
+
+	spoke.MirrorRegistryConfigMap, _ = configmap.Pull(apiClient, fmt.Sprintf("%s-mirror-registry", name), name)
+
+	if err := spoke.adoptInfraEnvs(); err != nil {
+		return nil, fmt.Errorf("failed to adopt spoke %s: %w", name, err)
+	}
+
+	spoke.NMStateConfigs, err = assisted.ListNmStateConfigs(apiClient, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to adopt spoke %s nmstateconfigs: %w", name, err)
+	}
+
+	spoke.BareMetalHosts, err = bmh.List(apiClient, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to adopt spoke %s baremetalhosts: %w", name, err)
+	}
+
+	for _, bareMetalHost := range spoke.BareMetalHosts {
+		credentialsName := bareMetalHost.Definition.Spec.BMC.CredentialsName
+		if bmcSecret, err := secret.Pull(apiClient, credentialsName, name); err == nil {
+			spoke.BMCSecrets = append(spoke.BMCSecrets, bmcSecret)
+		}
+	}
+
+	spoke.ManagedCluster, _ = ocm.PullManagedCluster(apiClient, name)
+	spoke.KlusterletAddonConfig, _ = ocm.PullKAC(apiClient, name, name)
+
+	glog.V(ztpparams.ZTPLogLevel).Infof("Adopted existing spoke %s", name)
+
+	return spoke, nil
+}
+
+// adoptInfraEnvs pulls the infraenvs in the spoke namespace, the one named after the spoke being the spoke infraenv
+// and the others additional infraenvs.
+func (spoke *SpokeClusterResources) adoptInfraEnvs() error {
+	infraEnvList := &agentInstallV1Beta1.InfraEnvList{}
+
+	err := spoke.apiClient.List(context.TODO(), infraEnvList, runtimeClient.InNamespace(spoke.Name))
+	if err != nil {
+		return fmt.Errorf("failed to list infraenvs: %w", err)
+	}
+
+	sort.Slice(infraEnvList.Items, func(i, j int) bool {
+		return infraEnvList.Items[i].Name < infraEnvList.Items[j].Name
+	})
+
+	for _, infraEnv := range infraEnvList.Items {
+		infraEnvBuilder, err := assisted.PullInfraEnvInstall(spoke.apiClient, infraEnv.Name, spoke.Name)
+		if err != nil {
+			return err
+		}
+
+		if infraEnv.Name == spoke.Name {
+			spoke.InfraEnv = infraEnvBuilder
+
+			continue
+		}
+
+		spoke.AdditionalInfraEnvs = append(spoke.AdditionalInfraEnvs, infraEnvBuilder)
+	}
+
+	return nil
+}
+
+// createOrAdopt creates the resource of kind using create, retrying transient errors, unless it already exists in
+// which case the existing resource is adopted, verifying it against its definition when drift checking is enabled.
+// The error of ctx is returned without creating the resource when it is done. Created and adopted resources, and the
+// creation in flight when ctx is done, are recorded so that an interrupted Create can report them.
+func (spoke *SpokeClusterResources) createOrAdopt(
+	ctx context.Context, kind string, builder existenceChecker, create func() error) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	start := time.Now()
+
+	defer func() {
+		spoke.recordPhase("create "+kind, start, 0, err)
+
+		if ctx.Err() != nil {
+			spoke.recordInterruptedStep("create " + kind)
+		}
+	}()
+
+	if !builder.Exists() {
+		err := spoke.retryTransient(ctx, "create "+kind, create)
+		if err == nil {
+			spoke.recordCreated(kind)
+			spoke.recordRollback(kind, builder)
+		}
+
+		if !k8serrors.IsAlreadyExists(err) || !builder.Exists() {
+			return err
+		}
+	}
+
+	glog.V(ztpparams.ZTPLogLevel).Infof("Adopting existing %s of spoke %s", kind, spoke.Name)
+
+	if spoke.checkDrift {
+		if err := spoke.adoptedDrift(kind); err != nil {
+			return err
+		}
+	}
+
+	spoke.recordCreated(kind)
+
+	return nil
+}
+
+// recordCreated records that the resource of kind was created or adopted, so that it is listed by
+// CreateInterruptedError. It is safe to call from the concurrent creation stage of CreateWithContext.
+func (spoke *SpokeClusterResources) recordCreated(kind string) {
+	spoke.createdResourcesMutex.Lock()
+	defer spoke.createdResourcesMutex.Unlock()
+
+	spoke.createdResources = append(spoke.createdResources, kind)
+}
+
+// recordInterruptedStep records step as the step of CreateWithContext in flight when its context was done, unless
+// one was recorded already. It is safe to call from the concurrent creation stage of CreateWithContext.
+func (spoke *SpokeClusterResources) recordInterruptedStep(step string) {
+	spoke.createdResourcesMutex.Lock()
+	defer spoke.createdResourcesMutex.Unlock()
+
+	if spoke.interruptedStep == "" {
+		spoke.interruptedStep = step
+	}
+}
+
+// adoptedDrift returns an error listing the key spec fields set in the definition of the adopted resource of kind
+// that differ on the existing resource, or nil when they match or the kind is not checked. Fields only set on the
+// existing resource are left out as they are usually defaulted by the hub.
+func (spoke *SpokeClusterResources) adoptedDrift(kind string) error {
+	var definition, object runtime.Object
+
+	switch kind {
+	case "clusterdeployment":
+		definition, object = spoke.ClusterDeployment.Definition, spoke.ClusterDeployment.Object
+	case "agentclusterinstall":
+		definition, object = spoke.AgentClusterInstall.Definition, spoke.AgentClusterInstall.Object
+	case "infraenv":
+		definition, object = spoke.InfraEnv.Definition, spoke.InfraEnv.Object
+	default:
+		return nil
+	}
+
+	if reflect.ValueOf(object).IsNil() {
+		return fmt.Errorf("failed to get existing %s of spoke %s", kind, spoke.Name)
+	}
+
+	definitionFields, err := specFields(kind, definition)
+	if err != nil {
+		return err
+	}
+
+	objectFields, err := specFields(kind, object)
+	if err != nil {
+		return err
+	}
+
+	var drifted []string
+
+	for path, value := range definitionFields {
+		if existing := objectFields[path]; !reflect.DeepEqual(value, existing) {
+			drifted = append(drifted, fmt.Sprintf("%s: expected %v, found %v", path, value, existing))
+		}
+	}
+
+	if len(drifted) == 0 {
+		return nil
+	}
+
+	sort.Strings(drifted)
+
+	return fmt.Errorf("existing %s of spoke %s does not match its definition: %s",
+		kind, spoke.Name, strings.Join(drifted, ", "))
+}
+
+// specFields returns the flattened fields of object under the key spec fields of kind.
+func specFields(kind string, object runtime.Object) (map[string]interface{}, error) {
+	content, err := definitionToUnstructured(object)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert %s: %w", kind, err)
+	}
+
+	fields := make(map[string]interface{})
+	keyFields := make(map[string]interface{})
+
+	flattenFields("", content, fields)
+
+	for path, value := range fields {
+		path = strings.TrimPrefix(path, ".")
+
+		for _, keyField := range adoptedSpecFields[kind] {
+			if path == keyField || strings.HasPrefix(path, keyField+".") || strings.HasPrefix(path, keyField+"[") {
+				keyFields[path] = value
+			}
+		}
+	}
+
+	return keyFields, nil
+}
@@ -0,0 +1,110 @@
+package setup
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	hivev1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/hive/api/v1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	runtimeClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestCreateAdoptsExistingResources(t *testing.T) {
+	existing := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultPullSecret().
+		WithDefaultClusterDeployment().ClusterDeployment.Definition.DeepCopy()
+	existing.Spec.BaseDomain = "other.test.com"
+
+	imageSet := buildDummyClusterImageSet(testHubOCPXYVersion, "", "")
+
+	spoke, err := NewSpokeCluster(newTestClient(existing, imageSet)).WithName("spoke").WithDefaultPullSecret().
+		WithDefaultClusterDeployment().WithDefaultIPv4AgentClusterInstall().Create()
+	assert.Nil(t, err)
+	assert.Equal(t, "other.test.com", spoke.ClusterDeployment.Object.Spec.BaseDomain)
+
+	spoke, err = spoke.Create()
+	assert.Nil(t, err)
+	assert.True(t, spoke.AgentClusterInstall.Exists())
+
+	_, err = NewSpokeCluster(newTestClient(existing.DeepCopy())).WithName("spoke").WithDefaultPullSecret().
+		WithDefaultClusterDeployment().WithDriftCheck().Create()
+	assert.EqualError(t, err, "existing clusterdeployment of spoke spoke does not match its definition: "+
+		"spec.baseDomain: expected assisted.test.com, found other.test.com")
+}
+
+func TestCreateAdoptsOnAlreadyExists(t *testing.T) {
+	apiClient, testBuilder := clients.GetModifiableTestClients(clients.TestClientParams{
+		SchemeAttachers: []clients.SchemeAttacher{
+			v1beta1.AddToScheme,
+			agentInstallV1Beta1.AddToScheme,
+			hivev1.AddToScheme,
+		},
+	})
+
+	apiClient.Client = testBuilder.WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, client runtimeClient.WithWatch,
+			obj runtimeClient.Object, opts ...runtimeClient.CreateOption) error {
+			if err := client.Create(ctx, obj, opts...); err != nil {
+				return err
+			}
+
+			if _, ok := obj.(*hivev1.ClusterDeployment); ok {
+				return k8serrors.NewAlreadyExists(schema.GroupResource{Resource: "clusterdeployments"}, obj.GetName())
+			}
+
+			return nil
+		},
+	}).Build()
+
+	spoke, err := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultPullSecret().
+		WithDefaultClusterDeployment().WithDriftCheck().Create()
+	assert.Nil(t, err)
+	assert.NotNil(t, spoke.ClusterDeployment.Object)
+}
+
+func TestAdopt(t *testing.T) {
+	spokeNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "spoke"}}
+	pullSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "spoke-pull-secret", Namespace: "spoke"}}
+	extraManifest := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "spoke-manifests", Namespace: "spoke"}}
+	clusterDeployment := &hivev1.ClusterDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "spoke", Namespace: "spoke"},
+		Spec: hivev1.ClusterDeploymentSpec{
+			ClusterInstallRef: &hivev1.ClusterInstallLocalReference{Name: "spoke-install"},
+		},
+	}
+	agentClusterInstall := &v1beta1.AgentClusterInstall{
+		ObjectMeta: metav1.ObjectMeta{Name: "spoke-install", Namespace: "spoke"},
+		Spec: v1beta1.AgentClusterInstallSpec{
+			ManifestsConfigMapRefs: []v1beta1.ManifestsConfigMapReference{{Name: "spoke-manifests"}},
+		},
+	}
+	additionalInfraEnv := buildDummyInfraEnvObject("spoke-workers")
+
+	spoke, err := Adopt(newTestClient(spokeNamespace, pullSecret, extraManifest, clusterDeployment,
+		agentClusterInstall, buildDummyInfraEnvObject("spoke"), additionalInfraEnv), "spoke")
+	assert.Nil(t, err)
+	assert.Equal(t, "spoke", spoke.Name)
+	assert.NotNil(t, spoke.Namespace)
+	assert.NotNil(t, spoke.PullSecret)
+	assert.NotNil(t, spoke.ClusterDeployment)
+	assert.Equal(t, "spoke-install", spoke.AgentClusterInstall.Definition.Name)
+	assert.Len(t, spoke.ExtraManifests, 1)
+	assert.Nil(t, spoke.MirrorRegistryConfigMap)
+	assert.Equal(t, "spoke", spoke.InfraEnv.Definition.Name)
+	assert.Len(t, spoke.AdditionalInfraEnvs, 1)
+	assert.Equal(t, "spoke-workers", spoke.AdditionalInfraEnvs[0].Definition.Name)
+	assert.Empty(t, spoke.BareMetalHosts)
+
+	_, err = Adopt(newTestClient(), "spoke")
+	assert.ErrorContains(t, err, "failed to adopt spoke spoke: ")
+
+	_, err = Adopt(nil, "spoke")
+	assert.EqualError(t, err, "apiClient cannot be nil")
+}
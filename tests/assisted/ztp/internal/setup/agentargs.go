@@ -0,0 +1,212 @@
+package setup
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/assisted"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+)
+
+const (
+	nodeIPHintPath          = "/etc/default/nodeip-configuration"
+	nodeIPHintFileMode      = 0o644
+	ignitionOverrideVersion = "3.2.0"
+)
+
+// installerArgFlags lists the coreos-installer flags accepted by the assisted service in agent installerArgs and
+// whether each flag takes a value.
+var installerArgFlags = map[string]bool{
+	"--append-karg":    true,
+	"--delete-karg":    true,
+	"-n":               false,
+	"--copy-network":   false,
+	"--network-dir":    true,
+	"--save-partlabel": true,
+	"--save-partindex": true,
+	"--image-url":      true,
+	"--image-file":     true,
+}
+
+// SetAgentInstallerArgs sets the coreos-installer arguments of the spoke agent of hostname, replacing any previous
+// arguments. Only the flags accepted by the assisted service are allowed, either as "--flag value" or
+// "--flag=value".
+func (spoke *SpokeClusterResources) SetAgentInstallerArgs(hostname string, args []string) error {
+	if err := validateInstallerArgs(args); err != nil {
+		return err
+	}
+
+	installerArgs, err := encodeInstallerArgs(args)
+	if err != nil {
+		return err
+	}
+
+	agentObject, err := spoke.findAgentByHostname(hostname)
+	if err != nil {
+		return err
+	}
+
+	agent, err := assisted.PullAgent(spoke.apiClient, agentObject.Name, agentObject.Namespace)
+	if err != nil {
+		return fmt.Errorf("failed to pull agent of host %s: %w", hostname, err)
+	}
+
+	agent.Definition.Spec.InstallerArgs = installerArgs
+
+	if _, err := agent.Update(); err != nil {
+		return fmt.Errorf("failed to set installer args of agent %s: %w", agentObject.Name, err)
+	}
+
+	return nil
+}
+
+// SetAgentNodeIPHint makes the kubelet and CRI-O of the spoke host hostname use its address within cidr as node IP.
+// The hint is written to the nodeip-configuration defaults through the agent ignition config overrides, keeping
+// any other overridden file.
+func (spoke *SpokeClusterResources) SetAgentNodeIPHint(hostname, cidr string) error {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return fmt.Errorf("invalid node ip hint %q: must be in CIDR notation", cidr)
+	}
+
+	agentObject, err := spoke.findAgentByHostname(hostname)
+	if err != nil {
+		return err
+	}
+
+	agent, err := assisted.PullAgent(spoke.apiClient, agentObject.Name, agentObject.Namespace)
+	if err != nil {
+		return fmt.Errorf("failed to pull agent of host %s: %w", hostname, err)
+	}
+
+	overrides, err := nodeIPHintIgnitionOverride(agent.Definition.Spec.IgnitionConfigOverrides, network.IP.String())
+	if err != nil {
+		return fmt.Errorf("failed to set node ip hint of agent %s: %w", agentObject.Name, err)
+	}
+
+	agent.Definition.Spec.IgnitionConfigOverrides = overrides
+
+	if _, err := agent.Update(); err != nil {
+		return fmt.Errorf("failed to set node ip hint of agent %s: %w", agentObject.Name, err)
+	}
+
+	return nil
+}
+
+// findAgentByHostname returns the spoke agent whose requested or discovered hostname is hostname.
+func (spoke *SpokeClusterResources) findAgentByHostname(hostname string) (*agentInstallV1Beta1.Agent, error) {
+	if spoke.InfraEnv == nil {
+		return nil, fmt.Errorf("infraenv must be defined before updating agents")
+	}
+
+	agents, err := spoke.listAgents()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agents of spoke %s: %w", spoke.Name, err)
+	}
+
+	for _, agent := range agents {
+		if agent.Spec.Hostname == hostname || agent.Status.Inventory.Hostname == hostname {
+			return agent, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no agent found for host %s in spoke %s", hostname, spoke.Name)
+}
+
+// validateInstallerArgs checks that args only contain allowed coreos-installer flags, each followed by a value
+// when the flag takes one.
+func validateInstallerArgs(args []string) error {
+	for index := 0; index < len(args); index++ {
+		flag, value, hasValue := strings.Cut(args[index], "=")
+
+		takesValue, allowed := installerArgFlags[flag]
+		if !allowed {
+			return fmt.Errorf("installer arg %q is not an allowed coreos-installer flag", args[index])
+		}
+
+		if !takesValue {
+			if hasValue {
+				return fmt.Errorf("installer arg %s does not take a value", flag)
+			}
+
+			continue
+		}
+
+		if hasValue {
+			if value == "" {
+				return fmt.Errorf("installer arg %s requires a value", flag)
+			}
+
+			continue
+		}
+
+		if index+1 == len(args) || strings.HasPrefix(args[index+1], "-") {
+			return fmt.Errorf("installer arg %s requires a value", flag)
+		}
+
+		index++
+	}
+
+	return nil
+}
+
+// encodeInstallerArgs encodes args as the JSON array string expected by the agent installerArgs field.
+func encodeInstallerArgs(args []string) (string, error) {
+	if args == nil {
+		args = []string{}
+	}
+
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode installer args: %w", err)
+	}
+
+	return string(encoded), nil
+}
+
+// nodeIPHintIgnitionOverride returns the ignition config overrides with the nodeip-configuration defaults file
+// set to the node IP hint, replacing an existing file at the same path.
+func nodeIPHintIgnitionOverride(overrides, hint string) (string, error) {
+	config := map[string]interface{}{}
+
+	if overrides != "" {
+		if err := json.Unmarshal([]byte(overrides), &config); err != nil {
+			return "", fmt.Errorf("existing ignition config overrides are not valid JSON: %w", err)
+		}
+	}
+
+	if _, found := config["ignition"]; !found {
+		config["ignition"] = map[string]interface{}{"version": ignitionOverrideVersion}
+	}
+
+	storage, _ := config["storage"].(map[string]interface{})
+	if storage == nil {
+		storage = map[string]interface{}{}
+	}
+
+	existingFiles, _ := storage["files"].([]interface{})
+	files := []interface{}{}
+
+	for _, file := range existingFiles {
+		if fileObject, isObject := file.(map[string]interface{}); isObject && fileObject["path"] == nodeIPHintPath {
+			continue
+		}
+
+		files = append(files, file)
+	}
+
+	files = append(files,
+		ignitionFile(nodeIPHintPath, fmt.Sprintf("KUBELET_NODEIP_HINT=%s\n", hint), nodeIPHintFileMode))
+
+	storage["files"] = files
+	config["storage"] = storage
+
+	encoded, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode ignition config overrides: %w", err)
+	}
+
+	return string(encoded), nil
+}
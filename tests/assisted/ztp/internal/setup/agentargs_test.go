@@ -0,0 +1,123 @@
+package setup
+
+import (
+	"testing"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/assisted"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateInstallerArgs(t *testing.T) {
+	testCases := []struct {
+		args        []string
+		expectedErr string
+	}{
+		{args: nil},
+		{args: []string{"--copy-network"}},
+		{args: []string{"--append-karg", "ip=eth0:dhcp", "--copy-network", "-n"}},
+		{args: []string{"--append-karg=console=ttyS0", "--delete-karg", "rhgb"}},
+		{
+			args:        []string{"--unsupported"},
+			expectedErr: `installer arg "--unsupported" is not an allowed coreos-installer flag`,
+		},
+		{
+			args:        []string{"copy-network"},
+			expectedErr: `installer arg "copy-network" is not an allowed coreos-installer flag`,
+		},
+		{args: []string{"--append-karg"}, expectedErr: "installer arg --append-karg requires a value"},
+		{
+			args:        []string{"--append-karg", "--copy-network"},
+			expectedErr: "installer arg --append-karg requires a value",
+		},
+		{args: []string{"--append-karg="}, expectedErr: "installer arg --append-karg requires a value"},
+		{args: []string{"--copy-network=true"}, expectedErr: "installer arg --copy-network does not take a value"},
+	}
+
+	for _, testCase := range testCases {
+		err := validateInstallerArgs(testCase.args)
+
+		if testCase.expectedErr == "" {
+			assert.Nil(t, err, testCase.args)
+		} else {
+			assert.EqualError(t, err, testCase.expectedErr, testCase.args)
+		}
+	}
+}
+
+func TestEncodeInstallerArgs(t *testing.T) {
+	testCases := []struct {
+		args     []string
+		expected string
+	}{
+		{args: nil, expected: `[]`},
+		{args: []string{"--copy-network"}, expected: `["--copy-network"]`},
+		{
+			args:     []string{"--append-karg", `ip="eth0:dhcp"`, "--append-karg", "console=ttyS0,115200n8"},
+			expected: `["--append-karg","ip=\"eth0:dhcp\"","--append-karg","console=ttyS0,115200n8"]`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		encoded, err := encodeInstallerArgs(testCase.args)
+
+		assert.Nil(t, err)
+		assert.Equal(t, testCase.expected, encoded)
+	}
+}
+
+func TestSetAgentInstallerArgs(t *testing.T) {
+	apiClient := newTestClient(
+		buildDummyInfraEnvObject("spoke"),
+		buildDummyDiscoveredAgent("agent-0", "master-0", "", 0),
+	)
+	spoke := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultInfraEnv()
+
+	assert.Nil(t, spoke.SetAgentInstallerArgs("master-0", []string{"--copy-network", "--append-karg", "ip=dhcp"}))
+
+	agent, err := assisted.PullAgent(apiClient, "agent-0", "spoke")
+	assert.Nil(t, err)
+	assert.Equal(t, `["--copy-network","--append-karg","ip=dhcp"]`, agent.Object.Spec.InstallerArgs)
+
+	assert.EqualError(t, spoke.SetAgentInstallerArgs("master-1", []string{"--copy-network"}),
+		"no agent found for host master-1 in spoke spoke")
+	assert.EqualError(t, spoke.SetAgentInstallerArgs("master-0", []string{"--image-url"}),
+		"installer arg --image-url requires a value")
+}
+
+func TestSetAgentNodeIPHint(t *testing.T) {
+	apiClient := newTestClient(
+		buildDummyInfraEnvObject("spoke"),
+		buildDummyDiscoveredAgent("agent-0", "master-0", "", 0),
+	)
+	spoke := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultInfraEnv()
+
+	assert.Nil(t, spoke.SetAgentNodeIPHint("master-0", "192.168.254.10/24"))
+	assert.Nil(t, spoke.SetAgentNodeIPHint("master-0", "192.168.111.0/24"))
+
+	agent, err := assisted.PullAgent(apiClient, "agent-0", "spoke")
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"ignition":{"version":"3.2.0"},"storage":{"files":[{`+
+		`"path":"/etc/default/nodeip-configuration","mode":420,"overwrite":true,`+
+		`"contents":{"source":"data:text/plain;charset=utf-8;base64,`+
+		`S1VCRUxFVF9OT0RFSVBfSElOVD0xOTIuMTY4LjExMS4wCg=="}}]}}`,
+		agent.Object.Spec.IgnitionConfigOverrides)
+
+	assert.EqualError(t, spoke.SetAgentNodeIPHint("master-0", "192.168.111.10"),
+		`invalid node ip hint "192.168.111.10": must be in CIDR notation`)
+	assert.EqualError(t, spoke.SetAgentNodeIPHint("worker-0", "192.168.111.0/24"),
+		"no agent found for host worker-0 in spoke spoke")
+}
+
+func TestNodeIPHintIgnitionOverride(t *testing.T) {
+	overrides, err := nodeIPHintIgnitionOverride(
+		`{"ignition":{"version":"3.1.0"},"storage":{"files":[{"path":"/etc/motd","mode":420}]}}`, "10.0.0.0")
+
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"ignition":{"version":"3.1.0"},"storage":{"files":[{"path":"/etc/motd","mode":420},{`+
+		`"path":"/etc/default/nodeip-configuration","mode":420,"overwrite":true,`+
+		`"contents":{"source":"data:text/plain;charset=utf-8;base64,S1VCRUxFVF9OT0RFSVBfSElOVD0xMC4wLjAuMAo="}}]}}`,
+		overrides)
+
+	_, err = nodeIPHintIgnitionOverride("not json", "10.0.0.0")
+	assert.ErrorContains(t, err, "existing ignition config overrides are not valid JSON")
+}
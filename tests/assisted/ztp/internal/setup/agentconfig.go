@@ -0,0 +1,237 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-goinfra/pkg/assisted"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/models"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+)
+
+// AgentConfig is the configuration ConfigureAgents applies to the spec of a spoke agent. Empty fields are left
+// unchanged. InstallationDiskID is the id, /dev/disk/by-path or /dev/disk/by-id path, or device path of a disk of
+// the agent inventory.
+type AgentConfig struct {
+	Hostname           string
+	Role               string
+	InstallationDiskID string
+}
+
+// ConfigureAgents waits, up to the spoke wait timeout, for an agent to register to the spoke infraenvs for each key
+// of config, a MAC address or hostname reported by the agent, then sets the hostname, role and installation disk of
+// each agent and checks the update landed by reading the agent back. The error of a key matching no agent, or of a
+// disk missing from the agent inventory, lists what was discovered.
+func (spoke *SpokeClusterResources) ConfigureAgents(config map[string]AgentConfig) error {
+	return spoke.ConfigureAgentsWithContext(context.Background(), config)
+}
+
+// ConfigureAgentsWithContext configures the spoke agents like ConfigureAgents, stopping the wait for their
+// registration when ctx is done.
+func (spoke *SpokeClusterResources) ConfigureAgentsWithContext(
+	ctx context.Context, config map[string]AgentConfig) error {
+	if spoke.InfraEnv == nil {
+		return fmt.Errorf("infraenv must be defined before configuring agents")
+	}
+
+	if len(config) == 0 {
+		return fmt.Errorf("agent config cannot be empty")
+	}
+
+	keys := make([]string, 0, len(config))
+
+	for key, agentConfig := range config {
+		if agentConfig.Role != "" &&
+			agentConfig.Role != string(models.HostRoleMaster) && agentConfig.Role != string(models.HostRoleWorker) {
+			return fmt.Errorf("invalid role %q for host %s, must be %s or %s",
+				agentConfig.Role, key, models.HostRoleMaster, models.HostRoleWorker)
+		}
+
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	keyAgents, err := spoke.waitForConfiguredAgents(ctx, keys)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := spoke.configureAgent(key, keyAgents[key], config[key]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// waitForConfiguredAgents polls until every key matches a registered spoke agent and returns the matching agent of
+// each key.
+func (spoke *SpokeClusterResources) waitForConfiguredAgents(
+	ctx context.Context, keys []string) (map[string]*agentInstallV1Beta1.Agent, error) {
+	var (
+		agents    []*agentInstallV1Beta1.Agent
+		keyAgents map[string]*agentInstallV1Beta1.Agent
+		unmatched []string
+	)
+
+	options := spoke.resolveWaitOptions()
+
+	err := spoke.poll(ctx, "wait for configured agents", options, func(ctx context.Context) (bool, error) {
+		listed, err := spoke.listAgents()
+		if err != nil {
+			glog.V(ztpparams.ZTPLogLevel).Infof("Failed to list agents of spoke %s: %v", spoke.Name, err)
+
+			return false, nil
+		}
+
+		agents = latestAgentPerHost(spoke.ownAgents(listed))
+		keyAgents = map[string]*agentInstallV1Beta1.Agent{}
+		unmatched = nil
+
+		for _, key := range keys {
+			index := slices.IndexFunc(agents, func(agent *agentInstallV1Beta1.Agent) bool {
+				return agentMatchesKey(agent, key)
+			})
+			if index < 0 {
+				unmatched = append(unmatched, key)
+
+				continue
+			}
+
+			keyAgents[key] = agents[index]
+		}
+
+		return len(unmatched) == 0, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("timed out waiting for agents of spoke %s: no agent discovered for %s, "+
+			"discovered agents: %s", spoke.Name, strings.Join(unmatched, ", "), describeAgents(agents))
+	}
+
+	return keyAgents, nil
+}
+
+// configureAgent applies agentConfig to the spec of agentObject, matched by key, and checks the update landed.
+func (spoke *SpokeClusterResources) configureAgent(
+	key string, agentObject *agentInstallV1Beta1.Agent, agentConfig AgentConfig) error {
+	diskID := ""
+
+	if agentConfig.InstallationDiskID != "" {
+		disk := findInventoryDisk(agentObject, agentConfig.InstallationDiskID)
+		if disk == nil {
+			return fmt.Errorf("installation disk %s of host %s is not in the inventory of agent %s, "+
+				"discovered disks: %s",
+				agentConfig.InstallationDiskID, key, agentObject.Name, describeDisks(agentObject))
+		}
+
+		diskID = disk.ID
+	}
+
+	agent, err := assisted.PullAgent(spoke.apiClient, agentObject.Name, agentObject.Namespace)
+	if err != nil {
+		return fmt.Errorf("failed to pull agent of host %s: %w", key, err)
+	}
+
+	if agentConfig.Hostname != "" {
+		agent.WithHostName(agentConfig.Hostname)
+	}
+
+	if agentConfig.Role != "" {
+		agent.WithRole(agentConfig.Role)
+	}
+
+	if diskID != "" {
+		agent.WithInstallationDisk(diskID)
+	}
+
+	if _, err := agent.Update(); err != nil {
+		return fmt.Errorf("failed to configure agent %s of host %s: %w", agentObject.Name, key, err)
+	}
+
+	updated, err := assisted.PullAgent(spoke.apiClient, agentObject.Name, agentObject.Namespace)
+	if err != nil {
+		return fmt.Errorf("failed to pull agent of host %s after configuring it: %w", key, err)
+	}
+
+	spec := updated.Object.Spec
+
+	if (agentConfig.Hostname != "" && spec.Hostname != agentConfig.Hostname) ||
+		(agentConfig.Role != "" && string(spec.Role) != agentConfig.Role) ||
+		(diskID != "" && spec.InstallationDiskID != diskID) {
+		return fmt.Errorf("agent %s of host %s was not configured: hostname is %q, role is %q, "+
+			"installation disk is %q", agentObject.Name, key, spec.Hostname, spec.Role, spec.InstallationDiskID)
+	}
+
+	glog.V(ztpparams.ZTPLogLevel).Infof("Configured agent %s of host %s: hostname %q, role %q, installation disk %q",
+		agentObject.Name, key, spec.Hostname, spec.Role, spec.InstallationDiskID)
+
+	return nil
+}
+
+// agentMatchesKey returns true when key is a MAC address of an interface of the agent, or its hostname.
+func agentMatchesKey(agent *agentInstallV1Beta1.Agent, key string) bool {
+	for _, hostInterface := range agent.Status.Inventory.Interfaces {
+		if strings.EqualFold(hostInterface.MacAddress, key) {
+			return true
+		}
+	}
+
+	return agent.Spec.Hostname == key || agent.Status.Inventory.Hostname == key
+}
+
+// findInventoryDisk returns the disk of the agent inventory whose id, by-path, by-id or device path is diskID.
+func findInventoryDisk(agent *agentInstallV1Beta1.Agent, diskID string) *agentInstallV1Beta1.HostDisk {
+	for index, disk := range agent.Status.Inventory.Disks {
+		if diskID == disk.ID || diskID == disk.ByPath || diskID == disk.ByID || diskID == disk.Path {
+			return &agent.Status.Inventory.Disks[index]
+		}
+	}
+
+	return nil
+}
+
+// describeAgents returns the name, hostname and MAC addresses of each agent, none when there are no agents.
+func describeAgents(agents []*agentInstallV1Beta1.Agent) string {
+	if len(agents) == 0 {
+		return "none"
+	}
+
+	descriptions := make([]string, 0, len(agents))
+
+	for _, agent := range agents {
+		var macAddresses []string
+
+		for _, hostInterface := range agent.Status.Inventory.Interfaces {
+			if hostInterface.MacAddress != "" {
+				macAddresses = append(macAddresses, hostInterface.MacAddress)
+			}
+		}
+
+		descriptions = append(descriptions, fmt.Sprintf("%s (hostname %q, macs [%s])",
+			agent.Name, agentHostname(agent), strings.Join(macAddresses, " ")))
+	}
+
+	return strings.Join(descriptions, ", ")
+}
+
+// describeDisks returns the id of each disk of the agent inventory, none when no disk was reported.
+func describeDisks(agent *agentInstallV1Beta1.Agent) string {
+	if len(agent.Status.Inventory.Disks) == 0 {
+		return "none"
+	}
+
+	diskIDs := make([]string, 0, len(agent.Status.Inventory.Disks))
+
+	for _, disk := range agent.Status.Inventory.Disks {
+		diskIDs = append(diskIDs, disk.ID)
+	}
+
+	return strings.Join(diskIDs, ", ")
+}
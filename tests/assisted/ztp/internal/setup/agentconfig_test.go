@@ -0,0 +1,142 @@
+package setup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/assisted"
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/models"
+	hivev1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/hive/api/v1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+	runtimeClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+const testDiskByPath = "/dev/disk/by-path/pci-0000:00:05.0"
+
+func TestConfigureAgents(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        map[string]AgentConfig
+		expectedSpecs map[string]agentInstallV1Beta1.AgentSpec
+		expectedError string
+	}{
+		{
+			name: "by mac and hostname",
+			config: map[string]AgentConfig{
+				"52:54:00:00:00:01": {Hostname: "master-0", Role: "master", InstallationDiskID: testDiskByPath},
+				"spoke-host-1":      {Hostname: "worker-0", Role: "worker"},
+			},
+			expectedSpecs: map[string]agentInstallV1Beta1.AgentSpec{
+				"agent-0": {Hostname: "master-0", Role: models.HostRoleMaster, InstallationDiskID: testDiskByPath},
+				"agent-1": {Hostname: "worker-0", Role: models.HostRoleWorker},
+			},
+		},
+		{
+			name: "disk by device path",
+			config: map[string]AgentConfig{
+				"52:54:00:00:00:01": {InstallationDiskID: "/dev/vdb"},
+			},
+			expectedSpecs: map[string]agentInstallV1Beta1.AgentSpec{
+				"agent-0": {InstallationDiskID: "/dev/disk/by-path/pci-0000:00:06.0"},
+			},
+		},
+		{
+			name:   "unknown key",
+			config: map[string]AgentConfig{"52:54:00:00:00:09": {Hostname: "master-0"}},
+			expectedError: "timed out waiting for agents of spoke spoke: no agent discovered for 52:54:00:00:00:09, " +
+				`discovered agents: agent-0 (hostname "spoke-host-0", macs [52:54:00:00:00:01]), ` +
+				`agent-1 (hostname "spoke-host-1", macs [52:54:00:00:00:02])`,
+		},
+		{
+			name:   "unknown disk",
+			config: map[string]AgentConfig{"spoke-host-0": {InstallationDiskID: "/dev/disk/by-path/pci-0000:00:07.0"}},
+			expectedError: "installation disk /dev/disk/by-path/pci-0000:00:07.0 of host spoke-host-0 is not in the " +
+				"inventory of agent agent-0, discovered disks: /dev/disk/by-path/pci-0000:00:05.0, " +
+				"/dev/disk/by-path/pci-0000:00:06.0",
+		},
+		{
+			name:   "disk without inventory",
+			config: map[string]AgentConfig{"spoke-host-1": {InstallationDiskID: testDiskByPath}},
+			expectedError: "installation disk /dev/disk/by-path/pci-0000:00:05.0 of host spoke-host-1 is not in the " +
+				"inventory of agent agent-1, discovered disks: none",
+		},
+		{
+			name:          "invalid role",
+			config:        map[string]AgentConfig{"spoke-host-0": {Role: "bootstrap"}},
+			expectedError: `invalid role "bootstrap" for host spoke-host-0, must be master or worker`,
+		},
+		{name: "empty", expectedError: "agent config cannot be empty"},
+	}
+
+	for _, testCase := range testCases {
+		apiClient := newTestClient(buildDummyInfraEnvObject("spoke"), buildDummyDiskAgent(),
+			buildDummyDiscoveredAgent("agent-1", "spoke-host-1", "52:54:00:00:00:02", 0))
+
+		spoke := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultInfraEnv().
+			WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: 20 * time.Millisecond})
+
+		err := spoke.ConfigureAgents(testCase.config)
+		if testCase.expectedError != "" {
+			assert.EqualError(t, err, testCase.expectedError, testCase.name)
+
+			continue
+		}
+
+		assert.Nil(t, err, testCase.name)
+
+		for agentName, expectedSpec := range testCase.expectedSpecs {
+			agent, err := assisted.PullAgent(apiClient, agentName, "spoke")
+			assert.Nil(t, err, testCase.name)
+			assert.Equal(t, expectedSpec.Hostname, agent.Object.Spec.Hostname, testCase.name)
+			assert.Equal(t, expectedSpec.Role, agent.Object.Spec.Role, testCase.name)
+			assert.Equal(t, expectedSpec.InstallationDiskID, agent.Object.Spec.InstallationDiskID, testCase.name)
+		}
+	}
+
+	assert.EqualError(t, NewSpokeCluster(newTestClient()).WithName("spoke").ConfigureAgents(
+		map[string]AgentConfig{"spoke-host-0": {}}), "infraenv must be defined before configuring agents")
+}
+
+func TestConfigureAgentsNotLanded(t *testing.T) {
+	apiClient, testBuilder := clients.GetModifiableTestClients(clients.TestClientParams{
+		K8sMockObjects: []runtime.Object{buildDummyInfraEnvObject("spoke"), buildDummyDiskAgent()},
+		SchemeAttachers: []clients.SchemeAttacher{
+			v1beta1.AddToScheme,
+			agentInstallV1Beta1.AddToScheme,
+			hivev1.AddToScheme,
+		},
+	})
+
+	apiClient.Client = testBuilder.WithInterceptorFuncs(interceptor.Funcs{
+		Update: func(ctx context.Context, client runtimeClient.WithWatch, obj runtimeClient.Object,
+			opts ...runtimeClient.UpdateOption) error {
+			if agent, isAgent := obj.(*agentInstallV1Beta1.Agent); isAgent {
+				agent.Spec.Hostname = ""
+			}
+
+			return client.Update(ctx, obj, opts...)
+		},
+	}).Build()
+
+	err := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultInfraEnv().
+		WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Second}).
+		ConfigureAgents(map[string]AgentConfig{"spoke-host-0": {Hostname: "master-0", Role: "master"}})
+	assert.EqualError(t, err, `agent agent-0 of host spoke-host-0 was not configured: hostname is "", `+
+		`role is "master", installation disk is ""`)
+}
+
+func buildDummyDiskAgent() *agentInstallV1Beta1.Agent {
+	agent := buildDummyDiscoveredAgent("agent-0", "spoke-host-0", "52:54:00:00:00:01", 0)
+	agent.Status.Inventory.Disks = []agentInstallV1Beta1.HostDisk{
+		{ID: testDiskByPath, ByPath: testDiskByPath, Path: "/dev/vda"},
+		{ID: "/dev/disk/by-path/pci-0000:00:06.0", ByPath: "/dev/disk/by-path/pci-0000:00:06.0", Path: "/dev/vdb"},
+	}
+
+	return agent
+}
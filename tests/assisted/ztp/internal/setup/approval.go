@@ -0,0 +1,204 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-goinfra/pkg/assisted"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/models"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+	conditionsv1 "github.com/openshift/custom-resource-status/conditions/v1"
+)
+
+// ApproveAgents approves every agent registered to the spoke infraenvs and waits up to timeout, or the spoke wait
+// timeout when it is 0, until each of them reports being approved. Roles maps hostnames to the master or worker
+// role; when it is nil, roles are assigned in agent name order to match the control-plane and worker counts of the
+// agentclusterinstall, and are left to the assisted service when there is no agentclusterinstall. Worker agents also
+// get the node labels of the compute pool they are assigned to. On timeout, the error lists the agents that are not
+// approved yet and the failing validation IDs of insufficient agents.
+func (spoke *SpokeClusterResources) ApproveAgents(roles map[string]string, timeout time.Duration) error {
+	return spoke.ApproveAgentsWithContext(context.Background(), roles, timeout)
+}
+
+// ApproveAllAgents approves every agent registered to the spoke infraenvs, assigning their roles from the
+// agentclusterinstall counts, and waits up to the spoke wait timeout until they report being approved, like
+// ApproveAgents with nil roles.
+func (spoke *SpokeClusterResources) ApproveAllAgents() error {
+	return spoke.ApproveAgents(nil, 0)
+}
+
+// ApproveAgentsWithContext approves the spoke agents like ApproveAgents, stopping the wait for their approval when
+// ctx is done.
+func (spoke *SpokeClusterResources) ApproveAgentsWithContext(
+	ctx context.Context, roles map[string]string, timeout time.Duration) error {
+	if spoke.InfraEnv == nil {
+		return fmt.Errorf("infraenv must be defined before approving agents")
+	}
+
+	for hostname, role := range roles {
+		if role != string(models.HostRoleMaster) && role != string(models.HostRoleWorker) {
+			return fmt.Errorf("invalid role %q for host %s, must be %s or %s",
+				role, hostname, models.HostRoleMaster, models.HostRoleWorker)
+		}
+	}
+
+	agents, err := spoke.listAgents()
+	if err != nil {
+		return fmt.Errorf("failed to list agents of spoke %s: %w", spoke.Name, err)
+	}
+
+	agents = latestAgentPerHost(spoke.ownAgents(agents))
+	if len(agents) == 0 {
+		return fmt.Errorf("no agents registered to the infraenv of spoke %s", spoke.Name)
+	}
+
+	agentRoles, err := spoke.resolveAgentRoles(agents, roles)
+	if err != nil {
+		return err
+	}
+
+	agentNodeLabels := spoke.computePoolNodeLabels(agents, agentRoles)
+
+	for _, agentObject := range agents {
+		agent, err := assisted.PullAgent(spoke.apiClient, agentObject.Name, agentObject.Namespace)
+		if err != nil {
+			return fmt.Errorf("failed to pull agent %s: %w", agentObject.Name, err)
+		}
+
+		agent.WithApproval(true)
+
+		if role, found := agentRoles[agentObject.Name]; found {
+			agent.WithRole(role)
+		}
+
+		if nodeLabels, found := agentNodeLabels[agentObject.Name]; found {
+			agent.Definition.Spec.NodeLabels = nodeLabels
+		}
+
+		if _, err := agent.Update(); err != nil {
+			return fmt.Errorf("failed to approve agent %s: %w", agentObject.Name, err)
+		}
+	}
+
+	return spoke.waitForAgentsApproved(ctx, agents, timeout)
+}
+
+// resolveAgentRoles returns the role to assign to each agent, keyed by agent name. Every hostname of roles must
+// match a registered agent.
+func (spoke *SpokeClusterResources) resolveAgentRoles(
+	agents []*agentInstallV1Beta1.Agent, roles map[string]string) (map[string]string, error) {
+	agentRoles := map[string]string{}
+
+	if roles == nil {
+		if spoke.AgentClusterInstall == nil {
+			return agentRoles, nil
+		}
+
+		controlPlaneAgents := spoke.AgentClusterInstall.Definition.Spec.ProvisionRequirements.ControlPlaneAgents
+
+		for index, agent := range agents {
+			agentRoles[agent.Name] = string(models.HostRoleWorker)
+
+			if index < controlPlaneAgents {
+				agentRoles[agent.Name] = string(models.HostRoleMaster)
+			}
+		}
+
+		return agentRoles, nil
+	}
+
+	for hostname, role := range roles {
+		index := slices.IndexFunc(agents, func(agent *agentInstallV1Beta1.Agent) bool {
+			return agentHostname(agent) == hostname
+		})
+		if index < 0 {
+			return nil, fmt.Errorf("no agent registered for host %s of spoke %s", hostname, spoke.Name)
+		}
+
+		agentRoles[agents[index].Name] = role
+	}
+
+	return agentRoles, nil
+}
+
+// waitForAgentsApproved polls until every agent reports being approved through its RequirementsMet condition.
+func (spoke *SpokeClusterResources) waitForAgentsApproved(
+	ctx context.Context, agents []*agentInstallV1Beta1.Agent, timeout time.Duration) error {
+	options := spoke.resolveWaitOptions()
+
+	if timeout > 0 {
+		options.Timeout = timeout
+		options.Interval = min(options.Interval, timeout)
+	}
+
+	var pending []string
+
+	err := spoke.poll(ctx, "wait for agents approved", options, func(ctx context.Context) (bool, error) {
+		pending = nil
+
+		for _, agentObject := range agents {
+			agent, err := assisted.PullAgent(spoke.apiClient, agentObject.Name, agentObject.Namespace)
+			if err != nil {
+				glog.V(ztpparams.ZTPLogLevel).Infof("Failed to pull agent %s: %v", agentObject.Name, err)
+
+				pending = append(pending, fmt.Sprintf("%s could not be retrieved", agentObject.Name))
+
+				continue
+			}
+
+			if reason := agentNotApprovedReason(agent.Object); reason != "" {
+				pending = append(pending, reason)
+			}
+		}
+
+		return len(pending) == 0, nil
+	})
+	if err != nil {
+		return fmt.Errorf("timed out waiting for agents of spoke %s to be approved: %s",
+			spoke.Name, strings.Join(pending, "; "))
+	}
+
+	return nil
+}
+
+// agentNotApprovedReason returns why the agent does not report being approved yet, or an empty string once it does.
+func agentNotApprovedReason(agent *agentInstallV1Beta1.Agent) string {
+	state := agent.Status.DebugInfo.State
+	if state == models.HostStatusInsufficient || state == models.HostStatusInsufficientUnbound {
+		var validationIDs []string
+
+		for _, results := range agent.Status.ValidationsInfo {
+			for _, result := range results {
+				if result.Status == agentValidationFailure {
+					validationIDs = append(validationIDs, result.ID)
+				}
+			}
+		}
+
+		slices.Sort(validationIDs)
+
+		return fmt.Sprintf("%s is %s, failing validations: %s", agent.Name, state, strings.Join(validationIDs, ", "))
+	}
+
+	condition := conditionsv1.FindStatusCondition(
+		agent.Status.Conditions, agentInstallV1Beta1.RequirementsMetCondition)
+	if condition == nil || condition.Reason == agentInstallV1Beta1.AgentIsNotApprovedReason {
+		return fmt.Sprintf("%s is not approved", agent.Name)
+	}
+
+	return ""
+}
+
+// agentHostname returns the hostname requested for the agent, falling back to the one it reported.
+func agentHostname(agent *agentInstallV1Beta1.Agent) string {
+	if agent.Spec.Hostname != "" {
+		return agent.Spec.Hostname
+	}
+
+	return agent.Status.Inventory.Hostname
+}
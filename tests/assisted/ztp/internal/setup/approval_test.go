@@ -0,0 +1,122 @@
+package setup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/assisted"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/common"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/models"
+	conditionsv1 "github.com/openshift/custom-resource-status/conditions/v1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestApproveAgents(t *testing.T) {
+	testCases := []struct {
+		name          string
+		roles         map[string]string
+		expectedRoles map[string]models.HostRole
+		expectedError string
+	}{
+		{
+			name: "roles from agentclusterinstall counts",
+			expectedRoles: map[string]models.HostRole{
+				"agent-0": models.HostRoleMaster,
+				"agent-1": models.HostRoleWorker,
+			},
+		},
+		{
+			name:  "roles by hostname",
+			roles: map[string]string{"spoke-host-0": "worker", "spoke-host-1": "master"},
+			expectedRoles: map[string]models.HostRole{
+				"agent-0": models.HostRoleWorker,
+				"agent-1": models.HostRoleMaster,
+			},
+		},
+		{
+			name:          "unknown host",
+			roles:         map[string]string{"spoke-host-2": "master"},
+			expectedError: "no agent registered for host spoke-host-2 of spoke spoke",
+		},
+		{
+			name:          "invalid role",
+			roles:         map[string]string{"spoke-host-0": "bootstrap"},
+			expectedError: "invalid role \"bootstrap\" for host spoke-host-0, must be master or worker",
+		},
+	}
+
+	for _, testCase := range testCases {
+		apiClient := newTestClient(
+			buildDummyInfraEnvObject("spoke"),
+			buildDummyApprovableAgent("agent-0", "spoke-host-0", "52:54:00:00:00:01"),
+			buildDummyApprovableAgent("agent-1", "spoke-host-1", "52:54:00:00:00:02"),
+		)
+
+		spoke := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultSNOAgentClusterInstall().
+			WithDefaultInfraEnv().WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Second})
+
+		err := spoke.ApproveAgents(testCase.roles, 0)
+		if testCase.expectedError != "" {
+			assert.EqualError(t, err, testCase.expectedError, testCase.name)
+
+			continue
+		}
+
+		assert.Nil(t, err, testCase.name)
+
+		for agentName, role := range testCase.expectedRoles {
+			agent, err := assisted.PullAgent(apiClient, agentName, "spoke")
+			assert.Nil(t, err, testCase.name)
+			assert.True(t, agent.Object.Spec.Approved, testCase.name)
+			assert.Equal(t, role, agent.Object.Spec.Role, testCase.name)
+		}
+	}
+}
+
+func TestApproveAgentsInsufficient(t *testing.T) {
+	insufficientAgent := buildDummyApprovableAgent("agent-1", "spoke-host-1", "52:54:00:00:00:02")
+	insufficientAgent.Status.DebugInfo.State = models.HostStatusInsufficient
+	insufficientAgent.Status.ValidationsInfo = common.ValidationsStatus{
+		"hardware": {
+			{ID: "has-min-memory", Status: "failure"},
+			{ID: "has-inventory", Status: "success"},
+		},
+		"network": {{ID: "belongs-to-machine-cidr", Status: "failure"}},
+	}
+
+	notApprovedAgent := buildDummyApprovableAgent("agent-2", "spoke-host-2", "52:54:00:00:00:03")
+	notApprovedAgent.Status.Conditions[0].Reason = agentInstallV1Beta1.AgentIsNotApprovedReason
+
+	apiClient := newTestClient(
+		buildDummyInfraEnvObject("spoke"),
+		buildDummyApprovableAgent("agent-0", "spoke-host-0", "52:54:00:00:00:01"),
+		insufficientAgent, notApprovedAgent,
+	)
+
+	spoke := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultInfraEnv().
+		WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Second})
+
+	err := spoke.ApproveAgents(nil, 10*time.Millisecond)
+	assert.EqualError(t, err, "timed out waiting for agents of spoke spoke to be approved: agent-1 is insufficient, "+
+		"failing validations: belongs-to-machine-cidr, has-min-memory; agent-2 is not approved")
+
+	err = NewSpokeCluster(newTestClient(buildDummyInfraEnvObject("spoke"))).WithName("spoke").WithDefaultInfraEnv().
+		ApproveAgents(nil, 0)
+	assert.EqualError(t, err, "no agents registered to the infraenv of spoke spoke")
+
+	err = NewSpokeCluster(apiClient).WithName("spoke").ApproveAgents(nil, 0)
+	assert.EqualError(t, err, "infraenv must be defined before approving agents")
+}
+
+func buildDummyApprovableAgent(name, hostname, macAddress string) *agentInstallV1Beta1.Agent {
+	agent := buildDummyDiscoveredAgent(name, hostname, macAddress, 0)
+	agent.Status.Conditions = []conditionsv1.Condition{{
+		Type:   agentInstallV1Beta1.RequirementsMetCondition,
+		Status: corev1.ConditionTrue,
+		Reason: agentInstallV1Beta1.AgentReadyReason,
+	}}
+
+	return agent
+}
@@ -0,0 +1,279 @@
+package setup
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/hive"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+)
+
+const (
+	// CPUArchitectureX86_64 is the x86_64 CPU architecture.
+	CPUArchitectureX86_64 = "x86_64"
+	// CPUArchitectureARM64 is the arm64 CPU architecture.
+	CPUArchitectureARM64 = "arm64"
+	// CPUArchitecturePPC64LE is the ppc64le CPU architecture.
+	CPUArchitecturePPC64LE = "ppc64le"
+	// CPUArchitectureS390X is the s390x CPU architecture.
+	CPUArchitectureS390X = "s390x"
+	// CPUArchitectureMulti is the architecture of multi-arch release payloads.
+	CPUArchitectureMulti = "multi"
+
+	// ReleaseArchitectureAnnotation is the clusterimageset annotation describing the release payload architecture.
+	ReleaseArchitectureAnnotation = "release.openshift.io/architecture"
+)
+
+// BootMethod is the way spoke hosts boot the discovery image.
+type BootMethod string
+
+const (
+	// BootMethodFullISO boots hosts from the full discovery ISO.
+	BootMethodFullISO BootMethod = "full-iso"
+	// BootMethodMinimalISO boots hosts from the minimal discovery ISO, which downloads the rootfs at boot.
+	BootMethodMinimalISO BootMethod = "minimal-iso"
+	// BootMethodIPXE boots hosts over the network from the infraenv kernel, initrd and rootfs artifacts.
+	BootMethodIPXE BootMethod = "ipxe"
+)
+
+// architectureBootMethods maps the infraenv CPU architectures to the boot methods they support. The first boot
+// method of each architecture is used when none is selected. s390x hosts cannot boot ISOs and ppc64le hosts do not
+// support the minimal ISO.
+var architectureBootMethods = map[string][]BootMethod{
+	CPUArchitectureX86_64:  {BootMethodFullISO, BootMethodMinimalISO, BootMethodIPXE},
+	CPUArchitectureARM64:   {BootMethodFullISO, BootMethodMinimalISO, BootMethodIPXE},
+	CPUArchitecturePPC64LE: {BootMethodFullISO, BootMethodIPXE},
+	CPUArchitectureS390X:   {BootMethodIPXE},
+}
+
+// releaseTagArchitectures maps the architecture suffix of release image tags to CPU architectures.
+var releaseTagArchitectures = map[string]string{
+	"x86_64":  CPUArchitectureX86_64,
+	"amd64":   CPUArchitectureX86_64,
+	"aarch64": CPUArchitectureARM64,
+	"arm64":   CPUArchitectureARM64,
+	"ppc64le": CPUArchitecturePPC64LE,
+	"s390x":   CPUArchitectureS390X,
+	"multi":   CPUArchitectureMulti,
+}
+
+// WithInfraEnvCPUArchitecture sets the CPU architecture of the spoke hosts, one of x86_64, arm64, ppc64le or s390x.
+// Only the infraenv carries the architecture, so the clusterdeployment and agentclusterinstall are left untouched and
+// the clusterimageset must reference a payload of that architecture or a multi payload, which
+// SupportsCPUArchitecture checks. Architectures that cannot boot ISOs, such as s390x, also switch the spoke to the
+// iPXE boot method.
+func (spoke *SpokeClusterResources) WithInfraEnvCPUArchitecture(arch string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.InfraEnv == nil {
+		spoke.err = fmt.Errorf(
+			"WithInfraEnvCPUArchitecture: infraenv must be defined before setting its cpu architecture")
+
+		return spoke
+	}
+
+	bootMethods, found := architectureBootMethods[arch]
+	if !found {
+		spoke.err = fmt.Errorf("WithInfraEnvCPUArchitecture: unsupported infraenv cpu architecture %q", arch)
+
+		return spoke
+	}
+
+	spoke.InfraEnv.WithCPUType(arch)
+
+	if !bootMethods[0].isISO() {
+		spoke.bootMethod = bootMethods[0]
+	}
+
+	return spoke
+}
+
+// WithCPUArchitecture sets the CPU architecture of the spoke hosts.
+//
+// Deprecated: use WithInfraEnvCPUArchitecture instead.
+func (spoke *SpokeClusterResources) WithCPUArchitecture(arch string) *SpokeClusterResources {
+	return spoke.WithInfraEnvCPUArchitecture(arch)
+}
+
+// CPUArchitecture returns the CPU architecture of the spoke hosts, x86_64 unless another one was selected.
+func (spoke *SpokeClusterResources) CPUArchitecture() string {
+	return spoke.infraEnvCPUArchitecture()
+}
+
+// SupportsCPUArchitecture returns true when the clusterimageset of the spoke agentclusterinstall references a
+// payload able to install hosts of the spoke CPU architecture, so that specs can be skipped when it does not.
+func (spoke *SpokeClusterResources) SupportsCPUArchitecture() (bool, error) {
+	if spoke.AgentClusterInstall == nil || spoke.AgentClusterInstall.Definition.Spec.ImageSetRef == nil {
+		return false, fmt.Errorf("agentclusterinstall must reference a clusterimageset to check its architecture")
+	}
+
+	payloadArch, err := pullImageSetArchitecture(
+		spoke.apiClient, spoke.AgentClusterInstall.Definition.Spec.ImageSetRef.Name)
+	if err != nil {
+		return false, err
+	}
+
+	return payloadArch == spoke.CPUArchitecture() || payloadArch == CPUArchitectureMulti, nil
+}
+
+// WithBootMethod sets the boot method used by the spoke hosts. Selecting an ISO boot method for an architecture
+// that cannot boot it logs a warning here and fails Validate.
+func (spoke *SpokeClusterResources) WithBootMethod(method BootMethod) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	switch method {
+	case BootMethodFullISO, BootMethodMinimalISO, BootMethodIPXE:
+	default:
+		spoke.err = fmt.Errorf("WithBootMethod: unsupported boot method %q", method)
+
+		return spoke
+	}
+
+	spoke.bootMethod = method
+
+	if method.isISO() {
+		spoke.warnUnsupportedBootMethod(method)
+	}
+
+	return spoke
+}
+
+// isISO returns true when the boot method boots hosts from a discovery ISO.
+func (method BootMethod) isISO() bool {
+	return method == BootMethodFullISO || method == BootMethodMinimalISO
+}
+
+// infraEnvCPUArchitecture returns the CPU architecture of the spoke infraenv, defaulting to x86_64 like the
+// assisted service does.
+func (spoke *SpokeClusterResources) infraEnvCPUArchitecture() string {
+	if spoke.InfraEnv == nil || spoke.InfraEnv.Definition.Spec.CpuArchitecture == "" {
+		return CPUArchitectureX86_64
+	}
+
+	return spoke.InfraEnv.Definition.Spec.CpuArchitecture
+}
+
+// resolveBootMethod returns the selected boot method, falling back to the default of the infraenv architecture.
+func (spoke *SpokeClusterResources) resolveBootMethod() BootMethod {
+	if spoke.bootMethod != "" {
+		return spoke.bootMethod
+	}
+
+	if bootMethods, found := architectureBootMethods[spoke.infraEnvCPUArchitecture()]; found {
+		return bootMethods[0]
+	}
+
+	return BootMethodFullISO
+}
+
+// validateBootMethod checks that the infraenv architecture is supported and can boot the selected boot method.
+func (spoke *SpokeClusterResources) validateBootMethod() error {
+	arch := spoke.infraEnvCPUArchitecture()
+
+	bootMethods, found := architectureBootMethods[arch]
+	if !found {
+		return fmt.Errorf("unsupported infraenv cpu architecture %q", arch)
+	}
+
+	method := spoke.resolveBootMethod()
+
+	for _, supported := range bootMethods {
+		if method == supported {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("boot method %s is not supported for %s hosts", method, arch)
+}
+
+// warnUnsupportedBootMethod logs a warning when an ISO based helper is used for an architecture that cannot
+// boot the ISO.
+func (spoke *SpokeClusterResources) warnUnsupportedBootMethod(method BootMethod) {
+	arch := spoke.infraEnvCPUArchitecture()
+
+	for _, supported := range architectureBootMethods[arch] {
+		if method == supported {
+			return
+		}
+	}
+
+	glog.V(ztpparams.ZTPLogLevel).Infof(
+		"Warning: boot method %s is not supported for %s hosts of spoke %s", method, arch, spoke.Name)
+}
+
+// validateCPUArchitecture checks, for the profiles requiring it, that the clusterimageset of the agentclusterinstall
+// references a payload able to install hosts of the spoke CPU architecture.
+func (spoke *SpokeClusterResources) validateCPUArchitecture() error {
+	if !spoke.checkImageSetArch || spoke.AgentClusterInstall == nil ||
+		spoke.AgentClusterInstall.Definition.Spec.ImageSetRef == nil {
+		return nil
+	}
+
+	return validateImageSetArchitecture(
+		spoke.apiClient, spoke.AgentClusterInstall.Definition.Spec.ImageSetRef.Name, spoke.CPUArchitecture())
+}
+
+// validateImageSetArchitecture checks that the named clusterimageset references a payload able to install nodes
+// of the provided architecture, meaning either a payload of that architecture or a multi payload.
+func validateImageSetArchitecture(apiClient *clients.Settings, imageSetName, arch string) error {
+	payloadArch, err := pullImageSetArchitecture(apiClient, imageSetName)
+	if err != nil {
+		return err
+	}
+
+	if payloadArch != arch && payloadArch != CPUArchitectureMulti {
+		return fmt.Errorf("clusterimageset %s references a %s payload which cannot install %s nodes",
+			imageSetName, payloadArch, arch)
+	}
+
+	return nil
+}
+
+// pullImageSetArchitecture returns the architecture of the release payload referenced by the named clusterimageset.
+func pullImageSetArchitecture(apiClient *clients.Settings, imageSetName string) (string, error) {
+	imageSet, err := hive.PullClusterImageSet(apiClient, imageSetName)
+	if err != nil {
+		return "", fmt.Errorf("failed to pull clusterimageset %s: %w", imageSetName, err)
+	}
+
+	payloadArch, err := imageSetArchitecture(
+		imageSet.Definition.Annotations[ReleaseArchitectureAnnotation], imageSet.Definition.Spec.ReleaseImage)
+	if err != nil {
+		return "", fmt.Errorf("clusterimageset %s: %w", imageSetName, err)
+	}
+
+	return payloadArch, nil
+}
+
+// imageSetArchitecture returns the architecture of a release payload from the architecture annotation when set,
+// otherwise from the architecture suffix of the release image tag.
+func imageSetArchitecture(annotation, releaseImage string) (string, error) {
+	if annotation != "" {
+		if arch, found := releaseTagArchitectures[annotation]; found {
+			return arch, nil
+		}
+
+		return "", fmt.Errorf("unknown release architecture annotation %q", annotation)
+	}
+
+	tagStart := strings.LastIndex(releaseImage, ":")
+	if tagStart == -1 || strings.Contains(releaseImage[tagStart:], "/") || strings.Contains(releaseImage, "@") {
+		return "", fmt.Errorf("cannot determine the architecture of release image %q without a tag", releaseImage)
+	}
+
+	tag := releaseImage[tagStart+1:]
+
+	for suffix, arch := range releaseTagArchitectures {
+		if strings.HasSuffix(tag, "-"+suffix) {
+			return arch, nil
+		}
+	}
+
+	return "", fmt.Errorf("cannot determine the architecture of release image %q from its tag", releaseImage)
+}
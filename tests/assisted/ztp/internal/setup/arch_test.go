@@ -0,0 +1,246 @@
+package setup
+
+import (
+	"testing"
+
+	hivev1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/hive/api/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestImageSetArchitecture(t *testing.T) {
+	testCases := []struct {
+		annotation   string
+		releaseImage string
+		expectedArch string
+		expectedErr  string
+	}{
+		{
+			releaseImage: "quay.io/openshift-release-dev/ocp-release:4.16.0-aarch64",
+			expectedArch: CPUArchitectureARM64,
+		},
+		{
+			releaseImage: "quay.io/openshift-release-dev/ocp-release:4.16.0-multi",
+			expectedArch: CPUArchitectureMulti,
+		},
+		{
+			releaseImage: "registry.example.com:5000/ocp-release:4.16.0-x86_64",
+			expectedArch: CPUArchitectureX86_64,
+		},
+		{
+			annotation:   "multi",
+			releaseImage: "quay.io/openshift-release-dev/ocp-release:4.16.0-x86_64",
+			expectedArch: CPUArchitectureMulti,
+		},
+		{
+			annotation:   "sparc",
+			releaseImage: "quay.io/openshift-release-dev/ocp-release:4.16.0-x86_64",
+			expectedErr:  `unknown release architecture annotation "sparc"`,
+		},
+		{
+			releaseImage: "quay.io/openshift-release-dev/ocp-release@sha256:0123",
+			expectedErr: `cannot determine the architecture of release image ` +
+				`"quay.io/openshift-release-dev/ocp-release@sha256:0123" without a tag`,
+		},
+		{
+			releaseImage: "registry.example.com:5000/ocp-release",
+			expectedErr: `cannot determine the architecture of release image ` +
+				`"registry.example.com:5000/ocp-release" without a tag`,
+		},
+		{
+			releaseImage: "quay.io/openshift-release-dev/ocp-release:4.16.0",
+			expectedErr: `cannot determine the architecture of release image ` +
+				`"quay.io/openshift-release-dev/ocp-release:4.16.0" from its tag`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		arch, err := imageSetArchitecture(testCase.annotation, testCase.releaseImage)
+
+		if testCase.expectedErr == "" {
+			assert.Nil(t, err)
+		} else {
+			assert.EqualError(t, err, testCase.expectedErr)
+		}
+
+		assert.Equal(t, testCase.expectedArch, arch)
+	}
+}
+
+func TestARM64Profile(t *testing.T) {
+	testCases := []struct {
+		imageSet    *hivev1.ClusterImageSet
+		expectedErr string
+	}{
+		{
+			imageSet: buildDummyClusterImageSet("arm64-imageset", "", "quay.io/ocp-release:4.16.0-aarch64"),
+		},
+		{
+			imageSet: buildDummyClusterImageSet("arm64-imageset", "multi", "quay.io/ocp-release:4.16.0"),
+		},
+		{
+			imageSet: buildDummyClusterImageSet("arm64-imageset", "", "quay.io/ocp-release:4.16.0-x86_64"),
+			expectedErr: "clusterimageset arm64-imageset references a x86_64 payload which cannot install " +
+				"arm64 nodes",
+		},
+		{
+			imageSet: buildDummyClusterImageSet("arm64-imageset", "", "quay.io/ocp-release@sha256:0123"),
+			expectedErr: `clusterimageset arm64-imageset: cannot determine the architecture of release image ` +
+				`"quay.io/ocp-release@sha256:0123" without a tag`,
+		},
+		{
+			imageSet:    buildDummyClusterImageSet("other-imageset", "", "quay.io/ocp-release:4.16.0-aarch64"),
+			expectedErr: "failed to pull clusterimageset arm64-imageset: ",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := ARM64Profile(newTestClient(testCase.imageSet), "profile-spoke", "arm64-imageset")
+		assert.Nil(t, spoke.err)
+		assert.Equal(t, "arm64-imageset", spoke.AgentClusterInstall.Definition.Spec.ImageSetRef.Name)
+		assert.Equal(t, CPUArchitectureARM64, spoke.InfraEnv.Definition.Spec.CpuArchitecture)
+
+		err := spoke.validateCPUArchitecture()
+		if testCase.expectedErr == "" {
+			assert.Nil(t, err)
+		} else {
+			assert.ErrorContains(t, err, testCase.expectedErr)
+		}
+	}
+
+	assert.Nil(t, StandardHAProfile(newTestClient(), "profile-spoke").validateCPUArchitecture())
+}
+
+func TestWithInfraEnvCPUArchitecture(t *testing.T) {
+	testCases := []struct {
+		arch               string
+		expectedBootMethod BootMethod
+		expectedErr        string
+	}{
+		{arch: CPUArchitectureX86_64, expectedBootMethod: BootMethodFullISO},
+		{arch: CPUArchitectureARM64, expectedBootMethod: BootMethodFullISO},
+		{arch: CPUArchitecturePPC64LE, expectedBootMethod: BootMethodFullISO},
+		{arch: CPUArchitectureS390X, expectedBootMethod: BootMethodIPXE},
+		{
+			arch:        CPUArchitectureMulti,
+			expectedErr: `WithInfraEnvCPUArchitecture: unsupported infraenv cpu architecture "multi"`,
+		},
+		{arch: "sparc", expectedErr: `WithInfraEnvCPUArchitecture: unsupported infraenv cpu architecture "sparc"`},
+	}
+
+	for _, testCase := range testCases {
+		spoke := StandardHAProfile(newHubTestClient(), "arch-spoke").WithInfraEnvCPUArchitecture(testCase.arch)
+
+		if testCase.expectedErr == "" {
+			assert.Nil(t, spoke.Validate())
+			assert.Equal(t, testCase.arch, spoke.InfraEnv.Definition.Spec.CpuArchitecture)
+			assert.Equal(t, testCase.expectedBootMethod, spoke.resolveBootMethod())
+		} else {
+			assert.EqualError(t, spoke.Validate(), testCase.expectedErr)
+		}
+	}
+
+	spoke := NewSpokeCluster(newTestClient()).WithName("arch-spoke").WithInfraEnvCPUArchitecture(CPUArchitectureS390X)
+	assert.EqualError(t, spoke.err,
+		"WithInfraEnvCPUArchitecture: infraenv must be defined before setting its cpu architecture")
+}
+
+func TestSupportsCPUArchitecture(t *testing.T) {
+	testCases := []struct {
+		arch         string
+		releaseImage string
+		annotation   string
+		supported    bool
+	}{
+		{arch: CPUArchitectureX86_64, releaseImage: "quay.io/ocp-release:4.16.0-x86_64", supported: true},
+		{arch: CPUArchitectureARM64, releaseImage: "quay.io/ocp-release:4.16.0-x86_64", supported: false},
+		{arch: CPUArchitectureARM64, releaseImage: "quay.io/ocp-release:4.16.0-aarch64", supported: true},
+		{arch: CPUArchitectureS390X, releaseImage: "quay.io/ocp-release:4.16.0", annotation: "multi", supported: true},
+	}
+
+	for _, testCase := range testCases {
+		spoke := StandardHAProfile(newTestClient(
+			buildDummyClusterImageSet(testHubOCPXYVersion, testCase.annotation, testCase.releaseImage)), "arch-spoke").
+			WithInfraEnvCPUArchitecture(testCase.arch)
+		assert.Nil(t, spoke.err)
+		assert.Equal(t, testCase.arch, spoke.CPUArchitecture())
+
+		supported, err := spoke.SupportsCPUArchitecture()
+		assert.Nil(t, err)
+		assert.Equal(t, testCase.supported, supported)
+	}
+
+	spoke := StandardHAProfile(newTestClient(), "arch-spoke")
+	assert.Equal(t, CPUArchitectureX86_64, spoke.CPUArchitecture())
+
+	_, err := spoke.SupportsCPUArchitecture()
+	assert.ErrorContains(t, err, "failed to pull clusterimageset "+testHubOCPXYVersion)
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("arch-spoke").WithDefaultInfraEnv().
+		WithInfraEnvCPUArchitecture("sparc")
+	assert.EqualError(t, spoke.err, `WithInfraEnvCPUArchitecture: unsupported infraenv cpu architecture "sparc"`)
+}
+
+func TestBootMethodCompatibility(t *testing.T) {
+	testCases := []struct {
+		arch        string
+		bootMethod  BootMethod
+		expectedErr string
+	}{
+		{arch: CPUArchitectureX86_64, bootMethod: BootMethodFullISO},
+		{arch: CPUArchitectureX86_64, bootMethod: BootMethodMinimalISO},
+		{arch: CPUArchitectureX86_64, bootMethod: BootMethodIPXE},
+		{arch: CPUArchitectureARM64, bootMethod: BootMethodFullISO},
+		{arch: CPUArchitectureARM64, bootMethod: BootMethodMinimalISO},
+		{arch: CPUArchitectureARM64, bootMethod: BootMethodIPXE},
+		{arch: CPUArchitecturePPC64LE, bootMethod: BootMethodFullISO},
+		{
+			arch:        CPUArchitecturePPC64LE,
+			bootMethod:  BootMethodMinimalISO,
+			expectedErr: "boot method minimal-iso is not supported for ppc64le hosts",
+		},
+		{arch: CPUArchitecturePPC64LE, bootMethod: BootMethodIPXE},
+		{
+			arch:        CPUArchitectureS390X,
+			bootMethod:  BootMethodFullISO,
+			expectedErr: "boot method full-iso is not supported for s390x hosts",
+		},
+		{
+			arch:        CPUArchitectureS390X,
+			bootMethod:  BootMethodMinimalISO,
+			expectedErr: "boot method minimal-iso is not supported for s390x hosts",
+		},
+		{arch: CPUArchitectureS390X, bootMethod: BootMethodIPXE},
+		{arch: CPUArchitectureX86_64, bootMethod: "pxe", expectedErr: `WithBootMethod: unsupported boot method "pxe"`},
+	}
+
+	for _, testCase := range testCases {
+		spoke := StandardHAProfile(newHubTestClient(), "arch-spoke").
+			WithInfraEnvCPUArchitecture(testCase.arch).
+			WithBootMethod(testCase.bootMethod)
+
+		if testCase.expectedErr == "" {
+			assert.Nil(t, spoke.Validate())
+			assert.Equal(t, testCase.bootMethod, spoke.resolveBootMethod())
+		} else {
+			assert.EqualError(t, spoke.Validate(), testCase.expectedErr)
+		}
+	}
+}
+
+func buildDummyClusterImageSet(name, archAnnotation, releaseImage string) *hivev1.ClusterImageSet {
+	imageSet := &hivev1.ClusterImageSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: hivev1.ClusterImageSetSpec{
+			ReleaseImage: releaseImage,
+		},
+	}
+
+	if archAnnotation != "" {
+		imageSet.Annotations = map[string]string{ReleaseArchitectureAnnotation: archAnnotation}
+	}
+
+	return imageSet
+}
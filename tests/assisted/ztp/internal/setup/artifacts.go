@@ -0,0 +1,198 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/pod"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/find"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// artifactsLogLines is the number of lines collected from the end of the assisted pod logs.
+const artifactsLogLines int64 = 1000
+
+// artifactsHTTPClient downloads the agentclusterinstall logs. The logs are only collected when reachable, so a
+// hung download must not block the teardown.
+var artifactsHTTPClient = &http.Client{Timeout: time.Minute}
+
+// CollectArtifacts gathers the state of the spoke under a directory named after the spoke in dir, to debug a
+// failed install: the namespace events, the spoke resources and agents with their status, the agentclusterinstall
+// logs when the logs URL is reachable and the end of the assisted-service and assisted-image-service pod logs of
+// the hub. Secret data is redacted. Every artifact that can be collected is written even when others fail, and
+// the failures are returned joined. Suites call it from their JustAfterEach when a spec fails.
+func (spoke *SpokeClusterResources) CollectArtifacts(dir string) error {
+	if spoke.apiClient == nil {
+		return fmt.Errorf("apiClient cannot be nil")
+	}
+
+	if spoke.Name == "" {
+		return fmt.Errorf("cannot collect artifacts of spoke without name")
+	}
+
+	spokeDir := filepath.Join(dir, spoke.Name)
+
+	if err := os.MkdirAll(spokeDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create artifacts directory %s: %w", spokeDir, err)
+	}
+
+	var errs []error
+
+	collect := func(artifact string, collector func() error) {
+		if err := collector(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to collect %s of spoke %s: %w", artifact, spoke.Name, err))
+		}
+	}
+
+	collect("events", func() error { return spoke.collectEvents(filepath.Join(spokeDir, "events.txt")) })
+	collect("resources", func() error {
+		return spoke.DumpYAML(filepath.Join(spokeDir, "resources"), &DumpOptions{IncludeStatus: true})
+	})
+	collect("agents", func() error { return spoke.collectAgents(filepath.Join(spokeDir, "agents")) })
+
+	if spoke.AgentClusterInstall != nil {
+		collect("agentclusterinstall logs", func() error {
+			return spoke.collectClusterLogs(filepath.Join(spokeDir, "agentclusterinstall-logs.tar"))
+		})
+	}
+
+	for _, hubPod := range []struct {
+		container string
+		find      func(*clients.Settings) (*pod.Builder, error)
+	}{
+		{container: "assisted-service", find: find.AssistedServicePod},
+		{container: "assisted-image-service", find: find.AssistedImageServicePod},
+	} {
+		collect(hubPod.container+" logs", func() error {
+			return spoke.collectPodLogs(hubPod.find, hubPod.container, filepath.Join(spokeDir, hubPod.container+".log"))
+		})
+	}
+
+	return errors.Join(errs...)
+}
+
+// collectEvents writes the events of the spoke namespace to path, one per line in chronological order.
+func (spoke *SpokeClusterResources) collectEvents(path string) error {
+	eventList, err := spoke.apiClient.Events(spoke.Name).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	events := eventList.Items
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].LastTimestamp.Before(&events[j].LastTimestamp)
+	})
+
+	var builder strings.Builder
+
+	for _, event := range events {
+		builder.WriteString(fmt.Sprintf("%s %s %s %s/%s: %s\n", event.LastTimestamp.UTC().Format(time.RFC3339),
+			event.Type, event.Reason, event.InvolvedObject.Kind, event.InvolvedObject.Name, event.Message))
+	}
+
+	return os.WriteFile(path, []byte(builder.String()), 0o600)
+}
+
+// collectAgents writes every agent of the spoke with its status to its own YAML file in dir.
+func (spoke *SpokeClusterResources) collectAgents(dir string) error {
+	if spoke.InfraEnv == nil {
+		return nil
+	}
+
+	agents, err := spoke.listAgents()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	for _, agent := range agents {
+		resource, err := spoke.dumpResource(agent, &DumpOptions{IncludeStatus: true})
+		if err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, resource.name+".yaml"), resource.content, 0o600); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// collectClusterLogs downloads the logs of the agentclusterinstall debug info to path when the logs URL is set.
+func (spoke *SpokeClusterResources) collectClusterLogs(path string) error {
+	agentClusterInstall, err := spoke.AgentClusterInstall.Get()
+	if err != nil {
+		return err
+	}
+
+	logsURL := agentClusterInstall.Status.DebugInfo.LogsURL
+	if logsURL == "" {
+		return nil
+	}
+
+	response, err := artifactsHTTPClient.Get(logsURL)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s downloading %s", response.Status, logsURL)
+	}
+
+	logsFile, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(logsFile, response.Body)
+
+	return errors.Join(err, logsFile.Close())
+}
+
+// collectPodLogs writes the last artifactsLogLines lines of the container of the hub pod returned by findPod
+// to path.
+func (spoke *SpokeClusterResources) collectPodLogs(
+	findPod func(*clients.Settings) (*pod.Builder, error), container, path string) error {
+	podBuilder, err := findPod(spoke.apiClient)
+	if err != nil {
+		return err
+	}
+
+	tailLines := artifactsLogLines
+
+	logStream, err := spoke.apiClient.Pods(podBuilder.Definition.Namespace).GetLogs(podBuilder.Definition.Name,
+		&corev1.PodLogOptions{Container: container, TailLines: &tailLines}).Stream(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = logStream.Close()
+	}()
+
+	logs, err := io.ReadAll(logStream)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, logs, 0o600)
+}
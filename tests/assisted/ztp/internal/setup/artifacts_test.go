@@ -0,0 +1,92 @@
+package setup
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCollectArtifacts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		_, _ = writer.Write([]byte("cluster logs"))
+	}))
+	defer server.Close()
+
+	agentClusterInstall := buildDummyConditionAgentClusterInstall("Failed")
+	agentClusterInstall.Status.DebugInfo.LogsURL = server.URL
+
+	spoke := newConditionTestSpoke(
+		agentClusterInstall,
+		buildDummyConditionInfraEnv(),
+		buildDummyConditionAgent("agent-1"),
+		buildDummyEvent("later", "InstallFailed", time.Minute),
+		buildDummyEvent("earlier", "InstallStarted", 0),
+		buildDummyAssistedPod("assisted-service"),
+	)
+
+	dir := t.TempDir()
+
+	err := spoke.CollectArtifacts(dir)
+	assert.EqualError(t, err, "failed to collect assisted-image-service logs of spoke spoke: "+
+		"pod with label 'app=assisted-image-service' not currently running")
+
+	spokeDir := filepath.Join(dir, "spoke")
+
+	events, err := os.ReadFile(filepath.Join(spokeDir, "events.txt"))
+	assert.Nil(t, err)
+	assert.Regexp(t, `^\S+ Normal InstallStarted AgentClusterInstall/spoke: earlier\n`+
+		`\S+ Normal InstallFailed AgentClusterInstall/spoke: later\n$`, string(events))
+
+	aciContent := readDumpedResource(t, filepath.Join(spokeDir, "resources", "02-agentclusterinstall-spoke.yaml"))
+	assert.Contains(t, aciContent, "status")
+
+	agentContent := readDumpedResource(t, filepath.Join(spokeDir, "agents", "agent-1.yaml"))
+	assert.Equal(t, "Agent", agentContent["kind"])
+	assert.Contains(t, agentContent, "status")
+
+	clusterLogs, err := os.ReadFile(filepath.Join(spokeDir, "agentclusterinstall-logs.tar"))
+	assert.Nil(t, err)
+	assert.Equal(t, "cluster logs", string(clusterLogs))
+
+	assistedServiceLogs, err := os.ReadFile(filepath.Join(spokeDir, "assisted-service.log"))
+	assert.Nil(t, err)
+	assert.NotEmpty(t, assistedServiceLogs)
+	assert.NoFileExists(t, filepath.Join(spokeDir, "assisted-image-service.log"))
+}
+
+func TestCollectArtifactsErrors(t *testing.T) {
+	err := NewSpokeCluster(nil).WithName("spoke").CollectArtifacts(t.TempDir())
+	assert.EqualError(t, err, "apiClient cannot be nil")
+
+	err = NewSpokeCluster(newTestClient()).CollectArtifacts(t.TempDir())
+	assert.EqualError(t, err, "cannot collect artifacts of spoke without name")
+}
+
+func buildDummyEvent(name, reason string, after time.Duration) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: "spoke"},
+		InvolvedObject: corev1.ObjectReference{Kind: "AgentClusterInstall", Name: "spoke"},
+		Reason:         reason,
+		Message:        name,
+		Type:           corev1.EventTypeNormal,
+		LastTimestamp:  metav1.NewTime(time.Now().Add(after)),
+	}
+}
+
+func buildDummyAssistedPod(app string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      app + "-0",
+			Namespace: "multicluster-engine",
+			Labels:    map[string]string{"app": app},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: app, Image: app}}},
+	}
+}
@@ -0,0 +1,191 @@
+package setup
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	bmhv1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/openshift-kni/eco-goinfra/pkg/bmh"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/models"
+	"github.com/openshift-kni/eco-goinfra/pkg/secret"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// InfraEnvLabel is the label selecting the infraenv whose discovery image is booted by a baremetalhost.
+	InfraEnvLabel = "infraenvs.agent-install.openshift.io"
+
+	bareMetalHostBootMode = "UEFI"
+
+	bmacHostnameAnnotation = "bmac.agent-install.openshift.io/hostname"
+	bmacRoleAnnotation     = "bmac.agent-install.openshift.io/role"
+	inspectAnnotation      = "inspect.metal3.io"
+)
+
+// BMCCredentials are the credentials of a baremetalhost BMC.
+type BMCCredentials struct {
+	Username string
+	Password string
+}
+
+// BMHSpec describes a baremetalhost added by WithBareMetalHosts. Hostname and Role, master or worker, are set on the
+// agent of the host by the bare metal agent controller when not empty, and RootDeviceHints selects its installation
+// disk.
+type BMHSpec struct {
+	Name            string
+	BMCAddress      string
+	BootMACAddress  string
+	Credentials     BMCCredentials
+	Hostname        string
+	Role            string
+	RootDeviceHints *bmhv1alpha1.RootDeviceHints
+}
+
+// WithBareMetalHost adds a baremetalhost named name in the spoke namespace, along with its BMC credentials secret,
+// so that the baremetal-operator boots the host with the discovery image of the spoke infraenv. Automated cleaning
+// is disabled and the baremetalhosts are created after the infraenv.
+func (spoke *SpokeClusterResources) WithBareMetalHost(
+	name, bmcAddress, bootMACAddress string, credentials BMCCredentials) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.InfraEnv == nil {
+		spoke.err = fmt.Errorf("WithBareMetalHost: infraenv must be defined before adding baremetalhosts")
+
+		return spoke
+	}
+
+	if _, err := spoke.addBareMetalHost(name, bmcAddress, bootMACAddress, credentials); err != nil {
+		spoke.err = fmt.Errorf("WithBareMetalHost: %w", err)
+	}
+
+	return spoke
+}
+
+// WithBareMetalHosts adds a baremetalhost for each host, like WithBareMetalHost, for spokes booted by the
+// baremetal-operator in the converged flow. Inspection is disabled since the discovery agent reports the inventory,
+// and the hostname and role of each host are set as bare metal agent controller annotations.
+func (spoke *SpokeClusterResources) WithBareMetalHosts(hosts []BMHSpec) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.InfraEnv == nil {
+		spoke.err = fmt.Errorf("WithBareMetalHosts: infraenv must be defined before adding baremetalhosts")
+
+		return spoke
+	}
+
+	if len(hosts) == 0 {
+		spoke.err = fmt.Errorf("WithBareMetalHosts: at least one baremetalhost is required")
+
+		return spoke
+	}
+
+	for _, host := range hosts {
+		if host.Role != "" && host.Role != string(models.HostRoleMaster) && host.Role != string(models.HostRoleWorker) {
+			spoke.err = fmt.Errorf("WithBareMetalHosts: invalid role %q for baremetalhost %s, must be %s or %s",
+				host.Role, host.Name, models.HostRoleMaster, models.HostRoleWorker)
+
+			return spoke
+		}
+
+		if errs := validation.IsDNS1123Subdomain(host.Hostname); host.Hostname != "" && len(errs) > 0 {
+			spoke.err = fmt.Errorf("WithBareMetalHosts: invalid hostname %q for baremetalhost %s: %s",
+				host.Hostname, host.Name, strings.Join(errs, ", "))
+
+			return spoke
+		}
+
+		bareMetalHost, err := spoke.addBareMetalHost(host.Name, host.BMCAddress, host.BootMACAddress, host.Credentials)
+		if err != nil {
+			spoke.err = fmt.Errorf("WithBareMetalHosts: %w", err)
+
+			return spoke
+		}
+
+		bareMetalHost.Definition.Annotations = map[string]string{inspectAnnotation: "disabled"}
+
+		if host.Hostname != "" {
+			bareMetalHost.Definition.Annotations[bmacHostnameAnnotation] = host.Hostname
+		}
+
+		if host.Role != "" {
+			bareMetalHost.Definition.Annotations[bmacRoleAnnotation] = host.Role
+		}
+
+		if host.RootDeviceHints != nil {
+			bareMetalHost.Definition.Spec.RootDeviceHints = host.RootDeviceHints.DeepCopy()
+		}
+	}
+
+	return spoke
+}
+
+// addBareMetalHost validates the baremetalhost and adds it, along with its BMC credentials secret, to the spoke.
+func (spoke *SpokeClusterResources) addBareMetalHost(
+	name, bmcAddress, bootMACAddress string, credentials BMCCredentials) (*bmh.BmhBuilder, error) {
+	if err := spoke.validateBareMetalHost(name, bmcAddress, bootMACAddress, credentials); err != nil {
+		return nil, err
+	}
+
+	bmcSecret := secret.NewBuilder(
+		spoke.apiClient, fmt.Sprintf("%s-bmc-secret", name), spoke.Name, corev1.SecretTypeOpaque).
+		WithData(map[string][]byte{
+			"username": []byte(credentials.Username),
+			"password": []byte(credentials.Password),
+		})
+
+	bareMetalHost := bmh.NewBuilder(spoke.apiClient, name, spoke.Name,
+		bmcAddress, bmcSecret.Definition.Name, bootMACAddress, bareMetalHostBootMode)
+	if bareMetalHost == nil {
+		return nil, fmt.Errorf("failed to create baremetalhost builder %s", name)
+	}
+
+	bareMetalHost.Definition.Labels = map[string]string{InfraEnvLabel: spoke.InfraEnv.Definition.Name}
+	bareMetalHost.Definition.Spec.AutomatedCleaningMode = bmhv1alpha1.CleaningModeDisabled
+
+	spoke.BMCSecrets = append(spoke.BMCSecrets, bmcSecret)
+	spoke.BareMetalHosts = append(spoke.BareMetalHosts, bareMetalHost)
+
+	return bareMetalHost, nil
+}
+
+// validateBareMetalHost checks that the baremetalhost is well formed and that its name and boot MAC address are not
+// used by another baremetalhost of the spoke.
+func (spoke *SpokeClusterResources) validateBareMetalHost(
+	name, bmcAddress, bootMACAddress string, credentials BMCCredentials) error {
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return fmt.Errorf("invalid baremetalhost name %q: %s", name, strings.Join(errs, ", "))
+	}
+
+	if bmcAddress == "" {
+		return fmt.Errorf("baremetalhost %s bmc address cannot be empty", name)
+	}
+
+	macAddress, err := net.ParseMAC(bootMACAddress)
+	if err != nil {
+		return fmt.Errorf("baremetalhost %s has an invalid boot mac address %q", name, bootMACAddress)
+	}
+
+	if credentials.Username == "" || credentials.Password == "" {
+		return fmt.Errorf("baremetalhost %s bmc credentials require a username and a password", name)
+	}
+
+	for _, bareMetalHost := range spoke.BareMetalHosts {
+		if bareMetalHost.Definition.Name == name {
+			return fmt.Errorf("baremetalhost %s is already defined for spoke %s", name, spoke.Name)
+		}
+
+		existingMAC, _ := net.ParseMAC(bareMetalHost.Definition.Spec.BootMACAddress)
+		if existingMAC.String() == macAddress.String() {
+			return fmt.Errorf("baremetalhost %s boot mac address %s is already used by baremetalhost %s",
+				name, bootMACAddress, bareMetalHost.Definition.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package setup
+
+import (
+	"testing"
+	"time"
+
+	bmhv1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+var testBMCCredentials = BMCCredentials{Username: "admin", Password: "password"}
+
+func TestWithBareMetalHost(t *testing.T) {
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultInfraEnv().
+		WithBareMetalHost("spoke-master-0", "redfish-virtualmedia://10.1.1.1/redfish/v1/Systems/1",
+			"52:54:00:00:00:01", testBMCCredentials)
+	assert.Nil(t, spoke.err)
+	assert.Len(t, spoke.BareMetalHosts, 1)
+	assert.Len(t, spoke.BMCSecrets, 1)
+
+	bareMetalHost := spoke.BareMetalHosts[0].Definition
+	assert.Equal(t, "spoke", bareMetalHost.Namespace)
+	assert.Equal(t, map[string]string{InfraEnvLabel: "spoke"}, bareMetalHost.Labels)
+	assert.Equal(t, bmhv1alpha1.CleaningModeDisabled, bareMetalHost.Spec.AutomatedCleaningMode)
+	assert.Equal(t, "spoke-master-0-bmc-secret", bareMetalHost.Spec.BMC.CredentialsName)
+	assert.Equal(t, "spoke-master-0-bmc-secret", spoke.BMCSecrets[0].Definition.Name)
+	assert.Equal(t, map[string][]byte{"username": []byte("admin"), "password": []byte("password")},
+		spoke.BMCSecrets[0].Definition.Data)
+}
+
+func TestWithBareMetalHostErrors(t *testing.T) {
+	testCases := []struct {
+		name           string
+		bmcAddress     string
+		bootMACAddress string
+		credentials    BMCCredentials
+		expectedError  string
+	}{
+		{
+			name:           "spoke-master-0",
+			bmcAddress:     "redfish://10.1.1.2",
+			bootMACAddress: "52:54:00:00:00:02",
+			credentials:    testBMCCredentials,
+			expectedError:  "baremetalhost spoke-master-0 is already defined for spoke spoke",
+		},
+		{
+			name:           "spoke-master-1",
+			bmcAddress:     "redfish://10.1.1.2",
+			bootMACAddress: "52-54-00-00-00-01",
+			credentials:    testBMCCredentials,
+			expectedError: "baremetalhost spoke-master-1 boot mac address 52-54-00-00-00-01 is already used by " +
+				"baremetalhost spoke-master-0",
+		},
+		{
+			name:           "spoke-master-1",
+			bmcAddress:     "redfish://10.1.1.2",
+			bootMACAddress: "not-a-mac",
+			credentials:    testBMCCredentials,
+			expectedError:  "baremetalhost spoke-master-1 has an invalid boot mac address \"not-a-mac\"",
+		},
+		{
+			name:           "spoke-master-1",
+			bootMACAddress: "52:54:00:00:00:02",
+			credentials:    testBMCCredentials,
+			expectedError:  "baremetalhost spoke-master-1 bmc address cannot be empty",
+		},
+		{
+			name:           "spoke-master-1",
+			bmcAddress:     "redfish://10.1.1.2",
+			bootMACAddress: "52:54:00:00:00:02",
+			credentials:    BMCCredentials{Username: "admin"},
+			expectedError:  "baremetalhost spoke-master-1 bmc credentials require a username and a password",
+		},
+		{
+			name:           "Spoke_Master_1",
+			bmcAddress:     "redfish://10.1.1.2",
+			bootMACAddress: "52:54:00:00:00:02",
+			credentials:    testBMCCredentials,
+			expectedError:  "invalid baremetalhost name \"Spoke_Master_1\"",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultInfraEnv().
+			WithBareMetalHost("spoke-master-0", "redfish://10.1.1.1", "52:54:00:00:00:01", testBMCCredentials).
+			WithBareMetalHost(testCase.name, testCase.bmcAddress, testCase.bootMACAddress, testCase.credentials)
+		assert.ErrorContains(t, spoke.err, testCase.expectedError)
+	}
+
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").
+		WithBareMetalHost("spoke-master-0", "redfish://10.1.1.1", "52:54:00:00:00:01", testBMCCredentials)
+	assert.EqualError(t, spoke.err, "WithBareMetalHost: infraenv must be defined before adding baremetalhosts")
+}
+
+func TestWithBareMetalHosts(t *testing.T) {
+	hosts := []BMHSpec{
+		{
+			Name:            "spoke-master-0",
+			BMCAddress:      "redfish-virtualmedia://10.1.1.1/redfish/v1/Systems/1",
+			BootMACAddress:  "52:54:00:00:00:01",
+			Credentials:     testBMCCredentials,
+			Hostname:        "master-0",
+			Role:            "master",
+			RootDeviceHints: &bmhv1alpha1.RootDeviceHints{DeviceName: "/dev/vda"},
+		},
+		{
+			Name:           "spoke-worker-0",
+			BMCAddress:     "redfish-virtualmedia://10.1.1.2/redfish/v1/Systems/1",
+			BootMACAddress: "52:54:00:00:00:02",
+			Credentials:    testBMCCredentials,
+		},
+	}
+
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultInfraEnv().WithBareMetalHosts(hosts)
+	assert.Nil(t, spoke.err)
+	assert.Len(t, spoke.BareMetalHosts, 2)
+	assert.Len(t, spoke.BMCSecrets, 2)
+
+	master := spoke.BareMetalHosts[0].Definition
+	assert.Equal(t, map[string]string{InfraEnvLabel: "spoke"}, master.Labels)
+	assert.Equal(t, map[string]string{
+		inspectAnnotation:      "disabled",
+		bmacHostnameAnnotation: "master-0",
+		bmacRoleAnnotation:     "master",
+	}, master.Annotations)
+	assert.Equal(t, "/dev/vda", master.Spec.RootDeviceHints.DeviceName)
+	assert.Equal(t, "spoke-master-0-bmc-secret", master.Spec.BMC.CredentialsName)
+
+	worker := spoke.BareMetalHosts[1].Definition
+	assert.Equal(t, map[string]string{inspectAnnotation: "disabled"}, worker.Annotations)
+	assert.Nil(t, worker.Spec.RootDeviceHints)
+
+	invalidRole := hosts[1]
+	invalidRole.Role = "bootstrap"
+
+	invalidHostname := hosts[1]
+	invalidHostname.Hostname = "Worker_0"
+
+	testCases := []struct {
+		name          string
+		hosts         []BMHSpec
+		expectedError string
+	}{
+		{name: "no hosts", expectedError: "WithBareMetalHosts: at least one baremetalhost is required"},
+		{
+			name:  "invalid role",
+			hosts: []BMHSpec{invalidRole},
+			expectedError: `WithBareMetalHosts: invalid role "bootstrap" for baremetalhost spoke-worker-0, ` +
+				"must be master or worker",
+		},
+		{
+			name:          "invalid hostname",
+			hosts:         []BMHSpec{invalidHostname},
+			expectedError: `WithBareMetalHosts: invalid hostname "Worker_0" for baremetalhost spoke-worker-0`,
+		},
+		{
+			name:          "duplicate",
+			hosts:         []BMHSpec{hosts[0], hosts[0]},
+			expectedError: "WithBareMetalHosts: baremetalhost spoke-master-0 is already defined for spoke spoke",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultInfraEnv().
+			WithBareMetalHosts(testCase.hosts)
+		assert.ErrorContains(t, spoke.err, testCase.expectedError, testCase.name)
+	}
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithBareMetalHosts(hosts)
+	assert.EqualError(t, spoke.err, "WithBareMetalHosts: infraenv must be defined before adding baremetalhosts")
+}
+
+func TestCreateAndDeleteBareMetalHost(t *testing.T) {
+	spoke, err := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultNamespace().
+		WithDefaultPullSecret().WithDefaultInfraEnv().
+		WithBareMetalHost("spoke-master-0", "redfish://10.1.1.1", "52:54:00:00:00:01", testBMCCredentials).
+		WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Second}).Create()
+	assert.Nil(t, err)
+	assert.True(t, spoke.BareMetalHosts[0].Exists())
+	assert.True(t, spoke.BMCSecrets[0].Exists())
+
+	assert.Nil(t, spoke.Delete())
+	assert.False(t, spoke.BareMetalHosts[0].Exists())
+	assert.False(t, spoke.BMCSecrets[0].Exists())
+}
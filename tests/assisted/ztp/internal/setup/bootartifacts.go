@@ -0,0 +1,126 @@
+package setup
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+)
+
+// BootArtifacts holds the URLs the assisted-image-service serves the artifacts of the spoke discovery image at, used
+// to boot hosts over iPXE.
+type BootArtifacts struct {
+	KernelURL     string
+	InitrdURL     string
+	RootfsURL     string
+	IPXEScriptURL string
+}
+
+// BootArtifactsNotReadyError is returned by GetBootArtifacts when the discovery image of the infraenv is created but
+// the assisted-image-service has not published every boot artifact URL in its status yet, so callers can retry.
+type BootArtifactsNotReadyError struct {
+	InfraEnv string
+	Missing  []string
+}
+
+// Error returns the infraenv and the boot artifacts missing from its status.
+func (err *BootArtifactsNotReadyError) Error() string {
+	return fmt.Sprintf("infraenv %s has not published the %s boot artifacts",
+		err.InfraEnv, strings.Join(err.Missing, ", "))
+}
+
+// WithIPXEScriptType sets the type of the iPXE script served for the spoke infraenv, DiscoveryImageAlways to always
+// boot the discovery image or BootOrderControl to boot from disk once the host is installed.
+func (spoke *SpokeClusterResources) WithIPXEScriptType(scriptType string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.InfraEnv == nil {
+		spoke.err = fmt.Errorf("WithIPXEScriptType: infraenv must be defined before setting the ipxe script type")
+
+		return spoke
+	}
+
+	switch agentInstallV1Beta1.IPXEScriptType(scriptType) {
+	case agentInstallV1Beta1.DiscoveryImageAlways, agentInstallV1Beta1.BootOrderControl:
+	default:
+		spoke.err = fmt.Errorf("WithIPXEScriptType: unsupported ipxe script type %q, must be %s or %s",
+			scriptType, agentInstallV1Beta1.DiscoveryImageAlways, agentInstallV1Beta1.BootOrderControl)
+
+		return spoke
+	}
+
+	spoke.InfraEnv.WithIPXEScriptType(agentInstallV1Beta1.IPXEScriptType(scriptType))
+
+	return spoke
+}
+
+// GetBootArtifacts waits, like WaitForDiscoveryISO with the spoke wait timeout, until the discovery image of the
+// spoke infraenv is created and returns the boot artifact URLs published in its status. A BootArtifactsNotReadyError
+// is returned when some of them are not published yet.
+func (spoke *SpokeClusterResources) GetBootArtifacts() (*BootArtifacts, error) {
+	if spoke.InfraEnv == nil {
+		return nil, fmt.Errorf("infraenv must be defined before getting the boot artifacts")
+	}
+
+	if _, err := spoke.WaitForDiscoveryISO(0); err != nil {
+		return nil, err
+	}
+
+	status := spoke.InfraEnv.Object.Status.BootArtifacts
+	artifacts := &BootArtifacts{
+		KernelURL:     status.KernelURL,
+		InitrdURL:     status.InitrdURL,
+		RootfsURL:     status.RootfsURL,
+		IPXEScriptURL: status.IpxeScriptURL,
+	}
+
+	notReadyErr := &BootArtifactsNotReadyError{InfraEnv: spoke.InfraEnv.Definition.Name}
+
+	for _, artifact := range []struct {
+		name string
+		url  string
+	}{
+		{name: "kernel", url: artifacts.KernelURL},
+		{name: "initrd", url: artifacts.InitrdURL},
+		{name: "rootfs", url: artifacts.RootfsURL},
+		{name: "ipxe script", url: artifacts.IPXEScriptURL},
+	} {
+		if artifact.url == "" {
+			notReadyErr.Missing = append(notReadyErr.Missing, artifact.name)
+
+			continue
+		}
+
+		if err := validateBootArtifactURL(artifact.url); err != nil {
+			return nil, fmt.Errorf("infraenv %s published an invalid %s url: %w",
+				spoke.InfraEnv.Definition.Name, artifact.name, err)
+		}
+	}
+
+	if len(notReadyErr.Missing) > 0 {
+		return nil, notReadyErr
+	}
+
+	return artifacts, nil
+}
+
+// validateBootArtifactURL checks that rawURL is an absolute http or https URL.
+func validateBootArtifactURL(rawURL string) error {
+	artifactURL, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	if artifactURL.Scheme != "http" && artifactURL.Scheme != "https" {
+		return fmt.Errorf("%q is not an http or https url", rawURL)
+	}
+
+	if artifactURL.Host == "" {
+		return fmt.Errorf("%q has no host", rawURL)
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package setup
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestWithIPXEScriptType(t *testing.T) {
+	testCases := []struct {
+		scriptType    string
+		expectedError string
+	}{
+		{scriptType: "DiscoveryImageAlways"},
+		{scriptType: "BootOrderControl"},
+		{
+			scriptType: "Always",
+			expectedError: `WithIPXEScriptType: unsupported ipxe script type "Always", ` +
+				"must be DiscoveryImageAlways or BootOrderControl",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultInfraEnv().
+			WithIPXEScriptType(testCase.scriptType)
+
+		if testCase.expectedError != "" {
+			assert.EqualError(t, spoke.err, testCase.expectedError)
+
+			continue
+		}
+
+		assert.Nil(t, spoke.err)
+		assert.Equal(t, agentInstallV1Beta1.IPXEScriptType(testCase.scriptType),
+			spoke.InfraEnv.Definition.Spec.IPXEScriptType)
+	}
+
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithIPXEScriptType("BootOrderControl")
+	assert.EqualError(t, spoke.err,
+		"WithIPXEScriptType: infraenv must be defined before setting the ipxe script type")
+}
+
+func TestGetBootArtifacts(t *testing.T) {
+	httpArtifacts := agentInstallV1Beta1.BootArtifacts{
+		KernelURL:     "http://assisted-image-service.example.com:8080/boot-artifacts/kernel?arch=x86_64",
+		InitrdURL:     "http://assisted-image-service.example.com:8080/images/abc/pxe-initrd?arch=x86_64",
+		RootfsURL:     "http://assisted-image-service.example.com:8080/boot-artifacts/rootfs?arch=x86_64",
+		IpxeScriptURL: "http://assisted-service.example.com:8090/api/assisted-install/v2/infra-envs/abc/downloads",
+	}
+	httpsArtifacts := agentInstallV1Beta1.BootArtifacts{
+		KernelURL:     "https://assisted-image-service.example.com/boot-artifacts/kernel?arch=x86_64",
+		InitrdURL:     "https://assisted-image-service.example.com/images/abc/pxe-initrd?arch=x86_64",
+		RootfsURL:     "https://assisted-image-service.example.com/boot-artifacts/rootfs?arch=x86_64",
+		IpxeScriptURL: "https://assisted-service.example.com/api/assisted-install/v2/infra-envs/abc/downloads",
+	}
+
+	testCases := []struct {
+		name            string
+		artifacts       agentInstallV1Beta1.BootArtifacts
+		expectedMissing []string
+		expectedError   string
+	}{
+		{name: "http", artifacts: httpArtifacts},
+		{name: "https", artifacts: httpsArtifacts},
+		{
+			name:            "not published",
+			expectedMissing: []string{"kernel", "initrd", "rootfs", "ipxe script"},
+			expectedError:   "infraenv spoke has not published the kernel, initrd, rootfs, ipxe script boot artifacts",
+		},
+		{
+			name: "partially published",
+			artifacts: agentInstallV1Beta1.BootArtifacts{
+				KernelURL: httpsArtifacts.KernelURL, RootfsURL: httpsArtifacts.RootfsURL,
+			},
+			expectedMissing: []string{"initrd", "ipxe script"},
+			expectedError:   "infraenv spoke has not published the initrd, ipxe script boot artifacts",
+		},
+		{
+			name: "invalid scheme",
+			artifacts: agentInstallV1Beta1.BootArtifacts{
+				KernelURL:     "ftp://assisted-image-service.example.com/kernel",
+				InitrdURL:     httpsArtifacts.InitrdURL,
+				RootfsURL:     httpsArtifacts.RootfsURL,
+				IpxeScriptURL: httpsArtifacts.IpxeScriptURL,
+			},
+			expectedError: `infraenv spoke published an invalid kernel url: ` +
+				`"ftp://assisted-image-service.example.com/kernel" is not an http or https url`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		infraEnv := buildDummyInfraEnvObject("spoke")
+		infraEnv.Status = buildDummyInfraEnvStatus(corev1.ConditionTrue, "", testFullISOURL)
+		infraEnv.Status.BootArtifacts = testCase.artifacts
+
+		spoke := NewSpokeCluster(newTestClient(infraEnv)).WithName("spoke").WithDefaultInfraEnv().
+			WithBootMethod(BootMethodIPXE).
+			WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: 20 * time.Millisecond})
+
+		artifacts, err := spoke.GetBootArtifacts()
+
+		if testCase.expectedError != "" {
+			assert.EqualError(t, err, testCase.expectedError, testCase.name)
+			assert.Nil(t, artifacts, testCase.name)
+
+			var notReadyErr *BootArtifactsNotReadyError
+
+			assert.Equal(t, testCase.expectedMissing != nil, errors.As(err, &notReadyErr), testCase.name)
+
+			if notReadyErr != nil {
+				assert.Equal(t, testCase.expectedMissing, notReadyErr.Missing, testCase.name)
+			}
+
+			continue
+		}
+
+		assert.Nil(t, err, testCase.name)
+		assert.Equal(t, &BootArtifacts{
+			KernelURL:     testCase.artifacts.KernelURL,
+			InitrdURL:     testCase.artifacts.InitrdURL,
+			RootfsURL:     testCase.artifacts.RootfsURL,
+			IPXEScriptURL: testCase.artifacts.IpxeScriptURL,
+		}, artifacts, testCase.name)
+	}
+}
+
+func TestGetBootArtifactsErrors(t *testing.T) {
+	_, err := NewSpokeCluster(newTestClient()).WithName("spoke").GetBootArtifacts()
+	assert.EqualError(t, err, "infraenv must be defined before getting the boot artifacts")
+
+	infraEnv := buildDummyInfraEnvObject("spoke")
+	infraEnv.Status = buildDummyInfraEnvStatus(corev1.ConditionFalse, "generating image", "")
+
+	_, err = NewSpokeCluster(newTestClient(infraEnv)).WithName("spoke").WithDefaultInfraEnv().
+		WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: 5 * time.Millisecond}).
+		GetBootArtifacts()
+	assert.EqualError(t, err, "timed out waiting for discovery iso of spoke spoke: "+
+		"infraenv spoke condition ImageCreated is False: generating image")
+}
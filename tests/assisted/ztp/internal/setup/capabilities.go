@@ -0,0 +1,248 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/olm"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/find"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	runtimeClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	agentClusterInstallCRDName = "agentclusterinstalls.extensions.hive.openshift.io"
+	infraEnvCRDName            = "infraenvs.agent-install.openshift.io"
+)
+
+// HubFeature is a spoke builder feature that only newer assisted-service and hive operators support.
+type HubFeature string
+
+const (
+	// HubFeatureDualStackVIPs is the api and ingress vip lists of the agentclusterinstall holding a vip per address
+	// family.
+	HubFeatureDualStackVIPs HubFeature = "dual-stack api and ingress vips"
+	// HubFeatureKernelArguments is the kernel arguments of the infraenv.
+	HubFeatureKernelArguments HubFeature = "infraenv kernel arguments"
+	// HubFeaturePlatformNone is the None platform type of the agentclusterinstall.
+	HubFeaturePlatformNone HubFeature = "platform type None"
+)
+
+var (
+	hubCapabilitiesCache      = make(map[*clients.Settings]*HubCapabilities)
+	hubCapabilitiesCacheMutex sync.Mutex
+)
+
+// HubCapabilities describes the spoke builder features supported by the assisted-service and hive operators of a
+// hub, as discovered by GetHubCapabilities. AssistedVersion is the version of the operator deploying the
+// assisted-service, empty when it could not be found.
+type HubCapabilities struct {
+	AssistedVersion string
+	features        map[HubFeature]bool
+}
+
+// UnsupportedFeatureError is returned by Validate when the spoke uses a builder feature the hub does not support,
+// so that specs can skip rather than fail on older hubs.
+type UnsupportedFeatureError struct {
+	Feature         HubFeature
+	AssistedVersion string
+}
+
+// Error returns the unsupported feature along with the hub assisted-service version when known.
+func (unsupportedErr *UnsupportedFeatureError) Error() string {
+	if unsupportedErr.AssistedVersion == "" {
+		return fmt.Sprintf("hub does not support %s", unsupportedErr.Feature)
+	}
+
+	return fmt.Sprintf("hub does not support %s, assisted-service version is %s",
+		unsupportedErr.Feature, unsupportedErr.AssistedVersion)
+}
+
+// GetHubCapabilities returns the capabilities of the hub reached through apiClient, read from the installed
+// agentclusterinstall and infraenv CRD schemas and from the clusterserviceversion of the operator deploying the
+// assisted-service. The capabilities are discovered once per apiClient and cached for later calls.
+func GetHubCapabilities(apiClient *clients.Settings) (*HubCapabilities, error) {
+	if apiClient == nil {
+		return nil, fmt.Errorf("apiClient cannot be nil")
+	}
+
+	hubCapabilitiesCacheMutex.Lock()
+	defer hubCapabilitiesCacheMutex.Unlock()
+
+	if capabilities, cached := hubCapabilitiesCache[apiClient]; cached {
+		return capabilities, nil
+	}
+
+	capabilities, err := discoverHubCapabilities(apiClient)
+	if err != nil {
+		return nil, err
+	}
+
+	hubCapabilitiesCache[apiClient] = capabilities
+
+	return capabilities, nil
+}
+
+// Supports returns true when the hub supports feature.
+func (capabilities *HubCapabilities) Supports(feature HubFeature) bool {
+	return capabilities.features[feature]
+}
+
+// SupportsDualStackVIPs returns true when the agentclusterinstall of the hub accepts api and ingress vip lists.
+func (capabilities *HubCapabilities) SupportsDualStackVIPs() bool {
+	return capabilities.Supports(HubFeatureDualStackVIPs)
+}
+
+// SupportsKernelArguments returns true when the infraenv of the hub accepts kernel arguments.
+func (capabilities *HubCapabilities) SupportsKernelArguments() bool {
+	return capabilities.Supports(HubFeatureKernelArguments)
+}
+
+// SupportsPlatformNone returns true when the agentclusterinstall of the hub accepts the None platform type.
+func (capabilities *HubCapabilities) SupportsPlatformNone() bool {
+	return capabilities.Supports(HubFeaturePlatformNone)
+}
+
+// WithHubCapabilities sets the hub capabilities Validate checks the builder options of the spoke against, usually
+// the ones returned by GetHubCapabilities for the hub client. Passing nil disables the check.
+func (spoke *SpokeClusterResources) WithHubCapabilities(capabilities *HubCapabilities) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	spoke.hubCapabilities = capabilities
+
+	return spoke
+}
+
+// discoverHubCapabilities reads the capabilities of the hub from its CRD schemas and assisted-service operator.
+func discoverHubCapabilities(apiClient *clients.Settings) (*HubCapabilities, error) {
+	agentClusterInstallSpec, err := getCRDSpecSchema(apiClient, agentClusterInstallCRDName)
+	if err != nil {
+		return nil, err
+	}
+
+	infraEnvSpec, err := getCRDSpecSchema(apiClient, infraEnvCRDName)
+	if err != nil {
+		return nil, err
+	}
+
+	_, hasAPIVIPs := agentClusterInstallSpec.Properties["apiVIPs"]
+	_, hasIngressVIPs := agentClusterInstallSpec.Properties["ingressVIPs"]
+	_, hasKernelArguments := infraEnvSpec.Properties["kernelArguments"]
+
+	capabilities := &HubCapabilities{
+		AssistedVersion: findAssistedVersion(apiClient),
+		features: map[HubFeature]bool{
+			HubFeatureDualStackVIPs:   hasAPIVIPs && hasIngressVIPs,
+			HubFeatureKernelArguments: hasKernelArguments,
+			HubFeaturePlatformNone: schemaAllows(
+				agentClusterInstallSpec.Properties["platformType"], string(v1beta1.NonePlatformType)),
+		},
+	}
+
+	glog.V(ztpparams.ZTPLogLevel).Infof("Discovered hub capabilities for assisted-service %q: %v",
+		capabilities.AssistedVersion, capabilities.features)
+
+	return capabilities, nil
+}
+
+// getCRDSpecSchema returns the schema of the spec of the storage version of the named CRD.
+func getCRDSpecSchema(apiClient *clients.Settings, crdName string) (apiextv1.JSONSchemaProps, error) {
+	crd := &apiextv1.CustomResourceDefinition{}
+
+	if err := apiClient.Get(context.TODO(), runtimeClient.ObjectKey{Name: crdName}, crd); err != nil {
+		return apiextv1.JSONSchemaProps{}, fmt.Errorf("failed to get crd %s: %w", crdName, err)
+	}
+
+	for _, version := range crd.Spec.Versions {
+		if version.Storage && version.Schema != nil && version.Schema.OpenAPIV3Schema != nil {
+			return version.Schema.OpenAPIV3Schema.Properties["spec"], nil
+		}
+	}
+
+	return apiextv1.JSONSchemaProps{}, fmt.Errorf("crd %s has no storage version schema", crdName)
+}
+
+// schemaAllows returns true when the string value is allowed by the enum of schema, or when it has no enum.
+func schemaAllows(schema apiextv1.JSONSchemaProps, value string) bool {
+	if len(schema.Enum) == 0 {
+		return true
+	}
+
+	return slices.ContainsFunc(schema.Enum, func(allowed apiextv1.JSON) bool {
+		return string(allowed.Raw) == fmt.Sprintf("%q", value)
+	})
+}
+
+// findAssistedVersion returns the version of the multicluster engine or infrastructure operator installed in the
+// namespace of the assisted-service, or an empty string when it cannot be found.
+func findAssistedVersion(apiClient *clients.Settings) string {
+	assistedPod, err := find.AssistedServicePod(apiClient)
+	if err != nil {
+		glog.V(ztpparams.ZTPLogLevel).Infof("Failed to find the assisted-service pod: %v", err)
+
+		return ""
+	}
+
+	csvs, err := olm.ListClusterServiceVersion(apiClient, assistedPod.Definition.Namespace)
+	if err != nil {
+		glog.V(ztpparams.ZTPLogLevel).Infof("Failed to list clusterserviceversions of the assisted-service: %v", err)
+
+		return ""
+	}
+
+	for _, csv := range csvs {
+		if strings.HasPrefix(csv.Definition.Name, "multicluster-engine") ||
+			strings.HasPrefix(csv.Definition.Name, "assisted-service-operator") {
+			return csv.Definition.Spec.Version.String()
+		}
+	}
+
+	return ""
+}
+
+// validateHubCapabilities checks that the hub capabilities set using WithHubCapabilities support the builder
+// features used by the spoke.
+func (spoke *SpokeClusterResources) validateHubCapabilities() error {
+	if spoke.hubCapabilities == nil {
+		return nil
+	}
+
+	var required []HubFeature
+
+	if spoke.AgentClusterInstall != nil {
+		spec := spoke.AgentClusterInstall.Definition.Spec
+
+		if len(spec.APIVIPs) > 1 || len(spec.IngressVIPs) > 1 {
+			required = append(required, HubFeatureDualStackVIPs)
+		}
+
+		if spec.PlatformType == v1beta1.NonePlatformType {
+			required = append(required, HubFeaturePlatformNone)
+		}
+	}
+
+	for _, infraEnv := range spoke.allInfraEnvs() {
+		if len(infraEnv.Definition.Spec.KernelArguments) > 0 {
+			required = append(required, HubFeatureKernelArguments)
+
+			break
+		}
+	}
+
+	for _, feature := range required {
+		if !spoke.hubCapabilities.Supports(feature) {
+			return &UnsupportedFeatureError{Feature: feature, AssistedVersion: spoke.hubCapabilities.AssistedVersion}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package setup
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetHubCapabilities(t *testing.T) {
+	testCases := []struct {
+		name                string
+		agentClusterInstall map[string]apiextv1.JSONSchemaProps
+		infraEnv            map[string]apiextv1.JSONSchemaProps
+		expected            map[HubFeature]bool
+	}{
+		{
+			name: "current",
+			agentClusterInstall: map[string]apiextv1.JSONSchemaProps{
+				"apiVIPs":      {Type: "array"},
+				"ingressVIPs":  {Type: "array"},
+				"platformType": {Type: "string", Enum: testSchemaEnum("BareMetal", "None", "VSphere")},
+			},
+			infraEnv: map[string]apiextv1.JSONSchemaProps{"kernelArguments": {Type: "array"}},
+			expected: map[HubFeature]bool{
+				HubFeatureDualStackVIPs: true, HubFeatureKernelArguments: true, HubFeaturePlatformNone: true,
+			},
+		},
+		{
+			name: "legacy",
+			agentClusterInstall: map[string]apiextv1.JSONSchemaProps{
+				"apiVIP":       {Type: "string"},
+				"platformType": {Type: "string", Enum: testSchemaEnum("BareMetal", "VSphere")},
+			},
+			infraEnv: map[string]apiextv1.JSONSchemaProps{},
+			expected: map[HubFeature]bool{
+				HubFeatureDualStackVIPs: false, HubFeatureKernelArguments: false, HubFeaturePlatformNone: false,
+			},
+		},
+		{
+			name:                "platform without enum",
+			agentClusterInstall: map[string]apiextv1.JSONSchemaProps{"platformType": {Type: "string"}},
+			infraEnv:            map[string]apiextv1.JSONSchemaProps{},
+			expected: map[HubFeature]bool{
+				HubFeatureDualStackVIPs: false, HubFeatureKernelArguments: false, HubFeaturePlatformNone: true,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		apiClient := newTestClient(
+			buildDummyCRD(agentClusterInstallCRDName, testCase.agentClusterInstall),
+			buildDummyCRD(infraEnvCRDName, testCase.infraEnv))
+
+		capabilities, err := GetHubCapabilities(apiClient)
+		assert.Nil(t, err, testCase.name)
+		assert.Equal(t, testCase.expected, capabilities.features, testCase.name)
+		assert.Equal(t, testCase.expected[HubFeatureDualStackVIPs], capabilities.SupportsDualStackVIPs())
+		assert.Equal(t, testCase.expected[HubFeatureKernelArguments], capabilities.SupportsKernelArguments())
+		assert.Equal(t, testCase.expected[HubFeaturePlatformNone], capabilities.SupportsPlatformNone())
+		assert.Empty(t, capabilities.AssistedVersion, testCase.name)
+
+		cached, err := GetHubCapabilities(apiClient)
+		assert.Nil(t, err)
+		assert.Same(t, capabilities, cached, testCase.name)
+	}
+
+	_, err := GetHubCapabilities(newTestClient())
+	assert.ErrorContains(t, err, "failed to get crd agentclusterinstalls.extensions.hive.openshift.io")
+
+	_, err = GetHubCapabilities(nil)
+	assert.EqualError(t, err, "apiClient cannot be nil")
+}
+
+func TestValidateHubCapabilities(t *testing.T) {
+	legacy := &HubCapabilities{AssistedVersion: "2.3.0", features: map[HubFeature]bool{}}
+	current := &HubCapabilities{features: map[HubFeature]bool{
+		HubFeatureDualStackVIPs: true, HubFeatureKernelArguments: true, HubFeaturePlatformNone: true,
+	}}
+
+	testCases := []struct {
+		name         string
+		spoke        *SpokeClusterResources
+		capabilities *HubCapabilities
+		expected     HubFeature
+	}{
+		{
+			name:         "dual-stack vips",
+			spoke:        DualStackProfile(newHubTestClient(), "spoke"),
+			capabilities: legacy,
+			expected:     HubFeatureDualStackVIPs,
+		},
+		{
+			name:         "kernel arguments",
+			spoke:        StandardHAProfile(newHubTestClient(), "spoke").WithKernelArguments("fips=1"),
+			capabilities: legacy,
+			expected:     HubFeatureKernelArguments,
+		},
+		{
+			name:         "platform none",
+			spoke:        StandardHAProfile(newHubTestClient(), "spoke").WithUserManagedNetworking(true),
+			capabilities: legacy,
+			expected:     HubFeaturePlatformNone,
+		},
+		{name: "supported", spoke: DualStackProfile(newHubTestClient(), "spoke"), capabilities: current},
+		{name: "standard", spoke: StandardHAProfile(newHubTestClient(), "spoke"), capabilities: legacy},
+		{name: "unchecked", spoke: DualStackProfile(newHubTestClient(), "spoke")},
+	}
+
+	for _, testCase := range testCases {
+		err := testCase.spoke.WithHubCapabilities(testCase.capabilities).Validate()
+
+		if testCase.expected == "" {
+			assert.Nil(t, err, testCase.name)
+
+			continue
+		}
+
+		var unsupportedErr *UnsupportedFeatureError
+
+		assert.True(t, errors.As(err, &unsupportedErr), testCase.name)
+		assert.Equal(t, testCase.expected, unsupportedErr.Feature, testCase.name)
+		assert.EqualError(t, err, "hub does not support "+string(testCase.expected)+
+			", assisted-service version is 2.3.0", testCase.name)
+	}
+
+	assert.EqualError(t, &UnsupportedFeatureError{Feature: HubFeaturePlatformNone},
+		"hub does not support platform type None")
+}
+
+// testSchemaEnum returns the JSON enum of a CRD schema allowing the string values.
+func testSchemaEnum(values ...string) []apiextv1.JSON {
+	var enum []apiextv1.JSON
+
+	for _, value := range values {
+		enum = append(enum, apiextv1.JSON{Raw: []byte(`"` + value + `"`)})
+	}
+
+	return enum
+}
+
+// buildDummyCRD returns the named CRD whose storage version has a spec schema with the given properties.
+func buildDummyCRD(name string, specProperties map[string]apiextv1.JSONSchemaProps) *apiextv1.CustomResourceDefinition {
+	return &apiextv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: apiextv1.CustomResourceDefinitionSpec{
+			Versions: []apiextv1.CustomResourceDefinitionVersion{
+				{Name: "v1alpha1", Served: true},
+				{
+					Name:    "v1beta1",
+					Served:  true,
+					Storage: true,
+					Schema: &apiextv1.CustomResourceValidation{
+						OpenAPIV3Schema: &apiextv1.JSONSchemaProps{
+							Type: "object",
+							Properties: map[string]apiextv1.JSONSchemaProps{
+								"spec": {Type: "object", Properties: specProperties},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
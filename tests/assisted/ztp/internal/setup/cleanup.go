@@ -0,0 +1,75 @@
+package setup
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/namespace"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// CleanupLeakedSpokes removes the spokes left on the hub by earlier runs. Every namespace carrying the spoke
+// ownership label, matching selector when it is not empty and created more than olderThan ago, is adopted and
+// deleted like its spoke. Namespaces without the ownership label are never touched. When dryRun is true, the
+// spokes that would be removed are only reported. The returned map is keyed by namespace, holding the error of
+// removing each spoke or nil when it was, or would be, removed.
+func CleanupLeakedSpokes(
+	apiClient *clients.Settings, selector string, olderThan time.Duration, dryRun bool) (map[string]error, error) {
+	if apiClient == nil {
+		return nil, fmt.Errorf("apiClient cannot be nil")
+	}
+
+	if olderThan < 0 {
+		return nil, fmt.Errorf("olderThan cannot be negative")
+	}
+
+	labelSelector := SpokeOwnershipLabel
+	if selector != "" {
+		labelSelector = fmt.Sprintf("%s,%s", labelSelector, selector)
+	}
+
+	if _, err := labels.Parse(labelSelector); err != nil {
+		return nil, fmt.Errorf("invalid spoke selector %q: %w", selector, err)
+	}
+
+	namespaces, err := namespace.List(apiClient, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list spoke namespaces: %w", err)
+	}
+
+	results := make(map[string]error)
+	cutoff := time.Now().Add(-olderThan)
+
+	for _, namespaceBuilder := range namespaces {
+		spokeNamespace := namespaceBuilder.Object
+
+		if spokeNamespace.Labels[SpokeOwnershipLabel] == "" || !spokeNamespace.CreationTimestamp.Time.Before(cutoff) {
+			continue
+		}
+
+		if dryRun {
+			glog.V(ztpparams.ZTPLogLevel).Infof("Would remove leaked spoke namespace %s created at %s",
+				spokeNamespace.Name, spokeNamespace.CreationTimestamp)
+
+			results[spokeNamespace.Name] = nil
+
+			continue
+		}
+
+		glog.V(ztpparams.ZTPLogLevel).Infof("Removing leaked spoke namespace %s created at %s",
+			spokeNamespace.Name, spokeNamespace.CreationTimestamp)
+
+		spoke, err := Adopt(apiClient, spokeNamespace.Name)
+		if err == nil {
+			err = spoke.Delete()
+		}
+
+		results[spokeNamespace.Name] = err
+	}
+
+	return results, nil
+}
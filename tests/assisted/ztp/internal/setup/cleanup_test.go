@@ -0,0 +1,76 @@
+package setup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/hive"
+	"github.com/openshift-kni/eco-goinfra/pkg/namespace"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCleanupLeakedSpokes(t *testing.T) {
+	leakedClusterDeployment := NewSpokeCluster(newTestClient()).WithName("leaked").WithDefaultPullSecret().
+		WithDefaultClusterDeployment().ClusterDeployment.Definition
+	lookAlikeClusterDeployment := NewSpokeCluster(newTestClient()).WithName("look-alike").WithDefaultPullSecret().
+		WithDefaultClusterDeployment().ClusterDeployment.Definition
+
+	apiClient := newTestClient(leakedClusterDeployment, lookAlikeClusterDeployment)
+
+	for _, spokeNamespace := range []*corev1.Namespace{
+		buildDummyAgedNamespace("leaked", map[string]string{SpokeOwnershipLabel: "leaked", "suite": "ztp"}, time.Hour),
+		buildDummyAgedNamespace("other-suite", map[string]string{SpokeOwnershipLabel: "other-suite"}, time.Hour),
+		buildDummyAgedNamespace("recent", map[string]string{SpokeOwnershipLabel: "recent", "suite": "ztp"}, 0),
+		buildDummyAgedNamespace("look-alike", map[string]string{"suite": "ztp"}, time.Hour),
+	} {
+		_, err := apiClient.Namespaces().Create(context.TODO(), spokeNamespace, metav1.CreateOptions{})
+		assert.Nil(t, err)
+	}
+
+	results, err := CleanupLeakedSpokes(apiClient, "suite=ztp", time.Minute, true)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]error{"leaked": nil}, results)
+	assert.True(t, namespace.NewBuilder(apiClient, "leaked").Exists())
+
+	results, err = CleanupLeakedSpokes(apiClient, "suite=ztp", time.Minute, false)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]error{"leaked": nil}, results)
+	assert.False(t, namespace.NewBuilder(apiClient, "leaked").Exists())
+	assert.False(t, hive.NewABMClusterDeploymentBuilder(
+		apiClient, "leaked", "leaked", "leaked", "", "", metav1.LabelSelector{}).Exists())
+
+	for _, name := range []string{"other-suite", "recent", "look-alike"} {
+		assert.True(t, namespace.NewBuilder(apiClient, name).Exists(), "namespace %s", name)
+	}
+
+	_, err = hive.PullClusterDeployment(apiClient, "look-alike", "look-alike")
+	assert.Nil(t, err)
+
+	results, err = CleanupLeakedSpokes(apiClient, "", 0, true)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]error{"other-suite": nil, "recent": nil}, results)
+}
+
+func TestCleanupLeakedSpokesErrors(t *testing.T) {
+	_, err := CleanupLeakedSpokes(nil, "", time.Hour, true)
+	assert.EqualError(t, err, "apiClient cannot be nil")
+
+	_, err = CleanupLeakedSpokes(newTestClient(), "", -time.Hour, true)
+	assert.EqualError(t, err, "olderThan cannot be negative")
+
+	_, err = CleanupLeakedSpokes(newTestClient(), "suite in (", time.Hour, true)
+	assert.ErrorContains(t, err, `invalid spoke selector "suite in (": `)
+}
+
+func buildDummyAgedNamespace(name string, labels map[string]string, age time.Duration) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Labels:            labels,
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+		},
+	}
+}
@@ -0,0 +1,190 @@
+package setup
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/models"
+	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	workerPoolName       = "worker"
+	controlPlanePoolName = "master"
+)
+
+// computePool describes a group of worker agents sharing a machine config pool.
+type computePool struct {
+	name   string
+	count  int
+	labels map[string]string
+}
+
+// WithComputePool adds a compute machine pool with the provided name, worker agent count and node labels to the
+// spoke cluster. It can be called multiple times; the total count across pools must match the worker agents of
+// the agentclusterinstall at Create time. Every pool other than worker also gets a day-0 MachineConfigPool manifest.
+// ApproveAgents assigns the worker agents to the pools in agent name order, count agents per pool, and sets the
+// pool labels as their node labels, along with the pool role label for pools other than worker.
+func (spoke *SpokeClusterResources) WithComputePool(
+	name string, count int, labels map[string]string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.Name == "" {
+		spoke.err = fmt.Errorf("WithComputePool: spoke name must be set before adding compute pools")
+
+		return spoke
+	}
+
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		spoke.err = fmt.Errorf("WithComputePool: invalid compute pool name %q: %s", name, strings.Join(errs, ", "))
+
+		return spoke
+	}
+
+	if name == controlPlanePoolName {
+		spoke.err = fmt.Errorf("WithComputePool: compute pool name cannot be %s", controlPlanePoolName)
+
+		return spoke
+	}
+
+	if count <= 0 {
+		spoke.err = fmt.Errorf("WithComputePool: compute pool %s count must be greater than 0", name)
+
+		return spoke
+	}
+
+	for _, pool := range spoke.computePools {
+		if pool.name == name {
+			spoke.err = fmt.Errorf("WithComputePool: compute pool %s is already defined", name)
+
+			return spoke
+		}
+	}
+
+	spoke.computePools = append(spoke.computePools, computePool{name: name, count: count, labels: labels})
+
+	if name == workerPoolName {
+		return spoke
+	}
+
+	manifest, err := machineConfigPoolManifest(name, labels)
+	if err != nil {
+		spoke.err = fmt.Errorf("WithComputePool: %w", err)
+
+		return spoke
+	}
+
+	spoke.addExtraManifest(
+		fmt.Sprintf("%s-compute-pools", spoke.Name), fmt.Sprintf("50-%s-machineconfigpool.yaml", name), manifest)
+
+	return spoke
+}
+
+// applyComputePools checks that the compute pools reconcile with the agentclusterinstall worker agents and
+// maps them onto the agentclusterinstall compute machine pools.
+func (spoke *SpokeClusterResources) applyComputePools() error {
+	if len(spoke.computePools) == 0 {
+		return nil
+	}
+
+	if spoke.AgentClusterInstall == nil {
+		return fmt.Errorf("compute pools require an agentclusterinstall")
+	}
+
+	totalCount := 0
+	machinePools := []v1beta1.AgentMachinePool{}
+
+	for _, pool := range spoke.computePools {
+		totalCount += pool.count
+		machinePools = append(machinePools, v1beta1.AgentMachinePool{Name: pool.name})
+	}
+
+	workerAgents := spoke.AgentClusterInstall.Definition.Spec.ProvisionRequirements.WorkerAgents
+	if totalCount != workerAgents {
+		return fmt.Errorf("compute pools define %d worker agents but agentclusterinstall requires %d",
+			totalCount, workerAgents)
+	}
+
+	spoke.AgentClusterInstall.Definition.Spec.Compute = machinePools
+
+	return nil
+}
+
+// computePoolNodeLabels returns the node labels of the worker agents assigned to the compute pools, keyed by agent
+// name. The worker agents are assigned in order to the pools in the order they were added, count agents per pool.
+func (spoke *SpokeClusterResources) computePoolNodeLabels(
+	agents []*agentInstallV1Beta1.Agent, agentRoles map[string]string) map[string]map[string]string {
+	nodeLabels := map[string]map[string]string{}
+	poolIndex, assigned := 0, 0
+
+	for _, agent := range agents {
+		if agentRoles[agent.Name] != string(models.HostRoleWorker) {
+			continue
+		}
+
+		for poolIndex < len(spoke.computePools) && assigned == spoke.computePools[poolIndex].count {
+			poolIndex, assigned = poolIndex+1, 0
+		}
+
+		if poolIndex == len(spoke.computePools) {
+			break
+		}
+
+		nodeLabels[agent.Name] = computePoolLabels(spoke.computePools[poolIndex])
+		assigned++
+	}
+
+	return nodeLabels
+}
+
+// computePoolLabels returns the labels of the nodes of pool, which the MachineConfigPool of the pool selects.
+func computePoolLabels(pool computePool) map[string]string {
+	labels := map[string]string{}
+	if pool.name != workerPoolName {
+		labels[fmt.Sprintf("node-role.kubernetes.io/%s", pool.name)] = ""
+	}
+
+	for key, value := range pool.labels {
+		labels[key] = value
+	}
+
+	return labels
+}
+
+// machineConfigPoolManifest returns a MachineConfigPool manifest selecting worker and pool machine configs for
+// nodes carrying the pool role label and the provided labels.
+func machineConfigPoolManifest(name string, labels map[string]string) (string, error) {
+	nodeSelector := computePoolLabels(computePool{name: name, labels: labels})
+
+	manifest := map[string]interface{}{
+		"apiVersion": "machineconfiguration.openshift.io/v1",
+		"kind":       "MachineConfigPool",
+		"metadata": map[string]interface{}{
+			"name": name,
+		},
+		"spec": map[string]interface{}{
+			"machineConfigSelector": map[string]interface{}{
+				"matchExpressions": []map[string]interface{}{{
+					"key":      "machineconfiguration.openshift.io/role",
+					"operator": "In",
+					"values":   []string{workerPoolName, name},
+				}},
+			},
+			"nodeSelector": map[string]interface{}{
+				"matchLabels": nodeSelector,
+			},
+		},
+	}
+
+	content, err := yaml.Marshal(manifest)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal machineconfigpool %s: %w", name, err)
+	}
+
+	return string(content), nil
+}
@@ -0,0 +1,138 @@
+package setup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/assisted"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithComputePool(t *testing.T) {
+	testCases := []struct {
+		name          string
+		pools         []computePool
+		expectedErr   string
+		expectedPools []v1beta1.AgentMachinePool
+		expectedFiles []string
+	}{
+		{
+			name:          "single worker pool",
+			pools:         []computePool{{name: "worker", count: 2}},
+			expectedPools: []v1beta1.AgentMachinePool{{Name: "worker"}},
+		},
+		{
+			name: "worker and realtime pools",
+			pools: []computePool{
+				{name: "worker", count: 1},
+				{name: "realtime", count: 1, labels: map[string]string{"example.com/realtime": "true"}},
+			},
+			expectedPools: []v1beta1.AgentMachinePool{{Name: "worker"}, {Name: "realtime"}},
+			expectedFiles: []string{"50-realtime-machineconfigpool.yaml"},
+		},
+		{
+			name:        "count mismatch",
+			pools:       []computePool{{name: "worker", count: 2}, {name: "realtime", count: 1}},
+			expectedErr: "compute pools define 3 worker agents but agentclusterinstall requires 2",
+		},
+		{
+			name:        "duplicate pool",
+			pools:       []computePool{{name: "worker", count: 1}, {name: "worker", count: 1}},
+			expectedErr: "WithComputePool: compute pool worker is already defined",
+		},
+		{
+			name:        "control plane pool",
+			pools:       []computePool{{name: "master", count: 2}},
+			expectedErr: "WithComputePool: compute pool name cannot be master",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			spoke := StandardHAProfile(newHubTestClient(), "pools-spoke")
+
+			for _, pool := range testCase.pools {
+				spoke.WithComputePool(pool.name, pool.count, pool.labels)
+			}
+
+			_, err := spoke.Create()
+
+			if testCase.expectedErr != "" {
+				assert.EqualError(t, err, testCase.expectedErr)
+
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, testCase.expectedPools, spoke.AgentClusterInstall.Definition.Spec.Compute)
+
+			if len(testCase.expectedFiles) == 0 {
+				assert.Empty(t, spoke.ExtraManifests)
+
+				return
+			}
+
+			assert.Len(t, spoke.ExtraManifests, 1)
+			assert.True(t, spoke.ExtraManifests[0].Exists())
+			assert.Equal(t, []v1beta1.ManifestsConfigMapReference{{Name: "pools-spoke-compute-pools"}},
+				spoke.AgentClusterInstall.Definition.Spec.ManifestsConfigMapRefs)
+
+			for _, file := range testCase.expectedFiles {
+				assert.Contains(t, spoke.ExtraManifests[0].Definition.Data, file)
+			}
+		})
+	}
+}
+
+func TestApproveAgentsComputePoolLabels(t *testing.T) {
+	apiClient := newTestClient(
+		buildDummyInfraEnvObject("spoke"),
+		buildDummyApprovableAgent("agent-0", "spoke-host-0", "52:54:00:00:00:01"),
+		buildDummyApprovableAgent("agent-1", "spoke-host-1", "52:54:00:00:00:02"),
+		buildDummyApprovableAgent("agent-2", "spoke-host-2", "52:54:00:00:00:03"),
+	)
+
+	spoke := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultSNOAgentClusterInstall().
+		WithDefaultInfraEnv().WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Second}).
+		WithComputePool("worker", 1, map[string]string{"example.com/zone": "a"}).
+		WithComputePool("realtime", 1, map[string]string{"example.com/realtime": "true"})
+	spoke.AgentClusterInstall.Definition.Spec.ProvisionRequirements.WorkerAgents = 2
+
+	assert.Nil(t, spoke.ApproveAgents(nil, 0))
+
+	expectedLabels := map[string]map[string]string{
+		"agent-0": nil,
+		"agent-1": {"example.com/zone": "a"},
+		"agent-2": {"example.com/realtime": "true", "node-role.kubernetes.io/realtime": ""},
+	}
+
+	for agentName, labels := range expectedLabels {
+		agent, err := assisted.PullAgent(apiClient, agentName, "spoke")
+		assert.Nil(t, err, agentName)
+		assert.Equal(t, labels, agent.Object.Spec.NodeLabels, agentName)
+	}
+}
+
+func TestMachineConfigPoolManifest(t *testing.T) {
+	manifest, err := machineConfigPoolManifest("realtime", map[string]string{"example.com/realtime": "true"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, `apiVersion: machineconfiguration.openshift.io/v1
+kind: MachineConfigPool
+metadata:
+  name: realtime
+spec:
+  machineConfigSelector:
+    matchExpressions:
+    - key: machineconfiguration.openshift.io/role
+      operator: In
+      values:
+      - worker
+      - realtime
+  nodeSelector:
+    matchLabels:
+      example.com/realtime: "true"
+      node-role.kubernetes.io/realtime: ""
+`, manifest)
+}
@@ -0,0 +1,83 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/namespace"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SpokeOwnershipLabel is the label key set to the spoke name on every resource created by the spoke builder.
+const SpokeOwnershipLabel = "eco-gotests/spoke"
+
+// ActiveSpokeCount returns the number of spokes owning namespaces on the hub, counting the distinct values of the
+// spoke ownership label so that a spoke with a late-binding infraenv namespace is counted once.
+func ActiveSpokeCount(apiClient *clients.Settings) (int, error) {
+	if apiClient == nil {
+		return 0, fmt.Errorf("apiClient cannot be nil")
+	}
+
+	namespaces, err := namespace.List(apiClient, metav1.ListOptions{LabelSelector: SpokeOwnershipLabel})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list spoke namespaces: %w", err)
+	}
+
+	spokes := make(map[string]bool)
+
+	for _, namespaceBuilder := range namespaces {
+		spokes[namespaceBuilder.Object.Labels[SpokeOwnershipLabel]] = true
+	}
+
+	return len(spokes), nil
+}
+
+// WithConcurrencyLimit makes Create wait until fewer than limit spokes are active on the hub before creating
+// any resources. The wait uses the spoke wait options.
+func (spoke *SpokeClusterResources) WithConcurrencyLimit(limit int) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if limit <= 0 {
+		spoke.err = fmt.Errorf("WithConcurrencyLimit: concurrency limit must be greater than 0")
+
+		return spoke
+	}
+
+	spoke.concurrencyLimit = limit
+
+	return spoke
+}
+
+// waitForSpokeSlot waits until the number of active spokes drops below the concurrency limit. A spoke whose
+// namespace already exists holds a slot and does not wait.
+func (spoke *SpokeClusterResources) waitForSpokeSlot(ctx context.Context) error {
+	if spoke.concurrencyLimit == 0 || (spoke.Namespace != nil && spoke.Namespace.Exists()) {
+		return nil
+	}
+
+	activeSpokes := 0
+
+	err := spoke.poll(ctx, "wait for spoke slot", spoke.resolveWaitOptions(), func(ctx context.Context) (bool, error) {
+		var err error
+
+		activeSpokes, err = ActiveSpokeCount(spoke.apiClient)
+		if err != nil {
+			glog.V(ztpparams.ZTPLogLevel).Infof("Failed to count active spokes: %v", err)
+
+			return false, nil
+		}
+
+		return activeSpokes < spoke.concurrencyLimit, nil
+	})
+	if err != nil {
+		return fmt.Errorf("timed out waiting for fewer than %d active spokes, %d active: %w",
+			spoke.concurrencyLimit, activeSpokes, err)
+	}
+
+	return nil
+}
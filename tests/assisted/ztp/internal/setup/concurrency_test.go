@@ -0,0 +1,73 @@
+package setup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestActiveSpokeCount(t *testing.T) {
+	testClient := newTestClient(
+		buildDummyNamespace("spoke-a", map[string]string{SpokeOwnershipLabel: "spoke-a"}),
+		buildDummyNamespace("spoke-b", map[string]string{SpokeOwnershipLabel: "spoke-b"}),
+		buildDummyNamespace("spoke-b-infraenvs", map[string]string{SpokeOwnershipLabel: "spoke-b"}),
+		buildDummyNamespace("unrelated", map[string]string{"app": "unrelated"}),
+		buildDummyNamespace("openshift-config", nil))
+
+	count, err := ActiveSpokeCount(testClient)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+
+	_, err = ActiveSpokeCount(nil)
+	assert.EqualError(t, err, "apiClient cannot be nil")
+}
+
+func TestWithConcurrencyLimit(t *testing.T) {
+	testCases := []struct {
+		name        string
+		freeSlot    bool
+		expectedErr bool
+	}{
+		{name: "slot becomes free", freeSlot: true, expectedErr: false},
+		{name: "no free slot", freeSlot: false, expectedErr: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			testClient := newHubTestClient(
+				buildDummyNamespace("spoke-a", map[string]string{SpokeOwnershipLabel: "spoke-a"}),
+				buildDummyNamespace("spoke-b", map[string]string{SpokeOwnershipLabel: "spoke-b"}))
+
+			spoke := StandardHAProfile(testClient, "limited-spoke").WithConcurrencyLimit(2).
+				WithWaitOptions(&WaitOptions{Interval: time.Millisecond * 10, Timeout: time.Millisecond * 500})
+
+			if testCase.freeSlot {
+				go func() {
+					time.Sleep(time.Millisecond * 50)
+
+					_ = testClient.CoreV1Interface.Namespaces().Delete(context.TODO(), "spoke-a", metav1.DeleteOptions{})
+				}()
+			}
+
+			_, err := spoke.Create()
+			assert.Equal(t, testCase.expectedErr, err != nil)
+			assert.Equal(t, !testCase.expectedErr, spoke.Namespace.Exists())
+		})
+	}
+
+	assert.NotNil(t, NewSpokeCluster(newTestClient()).WithConcurrencyLimit(0).err)
+}
+
+func buildDummyNamespace(name string, labels map[string]string) runtime.Object {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+	}
+}
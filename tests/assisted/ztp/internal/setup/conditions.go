@@ -0,0 +1,418 @@
+package setup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-goinfra/pkg/ocm"
+	assistedHiveV1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/hive/api/v1"
+	hivev1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/hive/api/v1"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+	conditionsv1 "github.com/openshift/custom-resource-status/conditions/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ResourceKind is a kind of spoke resource whose conditions can be waited on.
+type ResourceKind string
+
+const (
+	// ResourceKindAgentClusterInstall is the spoke agentclusterinstall.
+	ResourceKindAgentClusterInstall ResourceKind = "AgentClusterInstall"
+	// ResourceKindClusterDeployment is the spoke clusterdeployment.
+	ResourceKindClusterDeployment ResourceKind = "ClusterDeployment"
+	// ResourceKindInfraEnv is the spoke infraenv.
+	ResourceKindInfraEnv ResourceKind = "InfraEnv"
+	// ResourceKindAgent is every agent registered to the spoke infraenv.
+	ResourceKindAgent ResourceKind = "Agent"
+	// ResourceKindManagedCluster is the managedcluster named after the spoke.
+	ResourceKindManagedCluster ResourceKind = "ManagedCluster"
+)
+
+// ErrClusterDeploymentNotConfigured is returned, wrapped, by the clusterdeployment wait helpers when the spoke has no
+// clusterdeployment builder.
+var ErrClusterDeploymentNotConfigured = errors.New("clusterdeployment is not configured")
+
+// clusterDeploymentFailureConditions are the clusterdeployment conditions that, when True, mean hive will not
+// complete the installation.
+var clusterDeploymentFailureConditions = []hivev1.ClusterDeploymentConditionType{
+	hivev1.ProvisionFailedCondition,
+	hivev1.ProvisionStoppedCondition,
+	hivev1.ClusterInstallFailedClusterDeploymentCondition,
+}
+
+// resourceCondition is a condition of a spoke resource, normalized from the hive, conditionsv1 and metav1
+// condition schemas.
+type resourceCondition struct {
+	Object             string
+	Type               string
+	Status             string
+	Reason             string
+	Message            string
+	LastTransitionTime time.Time
+}
+
+// resourceConditionGetters returns the current conditions of each kind of spoke resource.
+var resourceConditionGetters = map[ResourceKind]func(spoke *SpokeClusterResources) ([]resourceCondition, error){
+	ResourceKindAgentClusterInstall: (*SpokeClusterResources).agentClusterInstallConditions,
+	ResourceKindClusterDeployment:   (*SpokeClusterResources).clusterDeploymentConditions,
+	ResourceKindInfraEnv:            (*SpokeClusterResources).infraEnvConditions,
+	ResourceKindAgent:               (*SpokeClusterResources).agentConditions,
+	ResourceKindManagedCluster:      (*SpokeClusterResources).managedClusterConditions,
+}
+
+// WaitForResourceCondition waits up to timeout, or the spoke wait timeout when it is 0, until the condition of
+// condType on the spoke resource of kind has status. For agents, the condition must have status on every agent
+// registered to the infraenv. On timeout, the returned error contains a table of all conditions of the resource.
+func (spoke *SpokeClusterResources) WaitForResourceCondition(
+	kind ResourceKind, condType, status string, timeout time.Duration) error {
+	return spoke.WaitForResourceConditionWithContext(context.Background(), kind, condType, status, timeout)
+}
+
+// WaitForResourceConditionWithContext waits for the condition of the spoke resource like
+// WaitForResourceCondition, stopping the wait when ctx is done.
+func (spoke *SpokeClusterResources) WaitForResourceConditionWithContext(
+	ctx context.Context, kind ResourceKind, condType, status string, timeout time.Duration) error {
+	getConditions, found := resourceConditionGetters[kind]
+	if !found {
+		return fmt.Errorf("unsupported resource kind %q", kind)
+	}
+
+	options := spoke.resolveWaitOptions()
+
+	if timeout > 0 {
+		options.Timeout = timeout
+		options.Interval = min(options.Interval, timeout)
+	}
+
+	var (
+		conditions []resourceCondition
+		getErr     error
+		waitPhase  = fmt.Sprintf("wait for %s %s", kind, condType)
+	)
+
+	err := spoke.poll(ctx, waitPhase, options, func(ctx context.Context) (bool, error) {
+		conditions, getErr = getConditions(spoke)
+		if getErr != nil {
+			glog.V(ztpparams.ZTPLogLevel).Infof("Failed to get %s conditions of spoke %s: %v", kind, spoke.Name, getErr)
+
+			return false, nil
+		}
+
+		return conditionsMet(conditions, condType, status), nil
+	})
+	if err == nil {
+		return nil
+	}
+
+	if getErr != nil {
+		return fmt.Errorf("timed out waiting for %s condition %s to be %s: %w", kind, condType, status, getErr)
+	}
+
+	table := formatConditionTable(conditions)
+	glog.V(ztpparams.ZTPLogLevel).Infof("Conditions of %s of spoke %s:\n%s", kind, spoke.Name, table)
+
+	return fmt.Errorf("timed out waiting for %s condition %s to be %s, current conditions:\n%s",
+		kind, condType, status, table)
+}
+
+// WaitForClusterDeploymentCondition waits up to timeout, or the spoke wait timeout when it is 0, until the hive
+// condition of conditionType on the spoke clusterdeployment, such as ProvisionFailed or ClusterInstallCompleted, has
+// status. On timeout, the returned error contains a table of all conditions of the clusterdeployment.
+func (spoke *SpokeClusterResources) WaitForClusterDeploymentCondition(
+	conditionType string, status corev1.ConditionStatus, timeout time.Duration) error {
+	return spoke.WaitForClusterDeploymentConditionWithContext(context.Background(), conditionType, status, timeout)
+}
+
+// WaitForClusterDeploymentConditionWithContext waits for the hive condition of the clusterdeployment like
+// WaitForClusterDeploymentCondition, stopping the wait when ctx is done.
+func (spoke *SpokeClusterResources) WaitForClusterDeploymentConditionWithContext(
+	ctx context.Context, conditionType string, status corev1.ConditionStatus, timeout time.Duration) error {
+	if spoke.ClusterDeployment == nil {
+		return fmt.Errorf("%w: cannot wait for condition %s", ErrClusterDeploymentNotConfigured, conditionType)
+	}
+
+	return spoke.WaitForResourceConditionWithContext(
+		ctx, ResourceKindClusterDeployment, conditionType, string(status), timeout)
+}
+
+// WaitForClusterInstalled waits up to timeout, or the spoke wait timeout when it is 0, until hive marks the spoke
+// clusterdeployment installed. It stops waiting as soon as the ProvisionFailed, ProvisionStopped or
+// ClusterInstallFailed condition of the clusterdeployment is True. On failure or timeout, the returned error contains
+// a table of all conditions of the clusterdeployment.
+func (spoke *SpokeClusterResources) WaitForClusterInstalled(timeout time.Duration) error {
+	return spoke.WaitForClusterInstalledWithContext(context.Background(), timeout)
+}
+
+// WaitForClusterInstalledWithContext waits for hive to mark the clusterdeployment installed like
+// WaitForClusterInstalled, stopping the wait when ctx is done.
+func (spoke *SpokeClusterResources) WaitForClusterInstalledWithContext(
+	ctx context.Context, timeout time.Duration) error {
+	if spoke.ClusterDeployment == nil {
+		return fmt.Errorf("%w: cannot wait for the installation", ErrClusterDeploymentNotConfigured)
+	}
+
+	options := spoke.resolveWaitOptions()
+
+	if timeout > 0 {
+		options.Timeout = timeout
+		options.Interval = min(options.Interval, timeout)
+	}
+
+	var (
+		conditions []resourceCondition
+		getErr     error
+		failureErr error
+	)
+
+	err := spoke.poll(ctx, "wait for clusterdeployment installed", options,
+		func(ctx context.Context) (bool, error) {
+			clusterDeployment, err := spoke.ClusterDeployment.Get()
+			if err != nil {
+				glog.V(ztpparams.ZTPLogLevel).Infof("Failed to get clusterdeployment of spoke %s: %v", spoke.Name, err)
+
+				getErr = err
+
+				return false, nil
+			}
+
+			getErr = nil
+			conditions = fromClusterDeploymentConditions(clusterDeployment.Name, clusterDeployment.Status.Conditions)
+
+			for _, condition := range clusterDeployment.Status.Conditions {
+				if slices.Contains(clusterDeploymentFailureConditions, condition.Type) &&
+					condition.Status == corev1.ConditionTrue {
+					failureErr = fmt.Errorf("clusterdeployment of spoke %s failed with condition %s reason %s: %s, "+
+						"current conditions:\n%s", spoke.Name, condition.Type, condition.Reason, condition.Message,
+						formatConditionTable(conditions))
+
+					return false, failureErr
+				}
+			}
+
+			return clusterDeployment.Spec.Installed, nil
+		})
+
+	if failureErr != nil {
+		return failureErr
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	if getErr != nil {
+		return fmt.Errorf("timed out waiting for clusterdeployment of spoke %s to be installed: %w", spoke.Name, getErr)
+	}
+
+	return fmt.Errorf("timed out waiting for clusterdeployment of spoke %s to be installed, current conditions:\n%s",
+		spoke.Name, formatConditionTable(conditions))
+}
+
+// conditionsMet returns true when every object of the conditions has a condition of condType with status.
+func conditionsMet(conditions []resourceCondition, condType, status string) bool {
+	objects := map[string]bool{}
+
+	for _, condition := range conditions {
+		if _, found := objects[condition.Object]; !found {
+			objects[condition.Object] = false
+		}
+
+		if condition.Type == condType && condition.Status == status {
+			objects[condition.Object] = true
+		}
+	}
+
+	for _, met := range objects {
+		if !met {
+			return false
+		}
+	}
+
+	return len(objects) > 0
+}
+
+// formatConditionTable renders the conditions as a kubectl-style table without trailing whitespace.
+func formatConditionTable(conditions []resourceCondition) string {
+	if len(conditions) == 0 {
+		return "No conditions found"
+	}
+
+	var buffer bytes.Buffer
+
+	writer := tabwriter.NewWriter(&buffer, 0, 0, 3, ' ', 0)
+
+	fmt.Fprintln(writer, "NAME\tTYPE\tSTATUS\tREASON\tLAST TRANSITION\tMESSAGE")
+
+	for _, condition := range conditions {
+		lastTransition := "<unknown>"
+		if !condition.LastTransitionTime.IsZero() {
+			lastTransition = condition.LastTransitionTime.UTC().Format(time.RFC3339)
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n", condition.Object, condition.Type, condition.Status,
+			condition.Reason, lastTransition, condition.Message)
+	}
+
+	_ = writer.Flush()
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	for index, line := range lines {
+		lines[index] = strings.TrimRight(line, " ")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// agentClusterInstallConditions returns the conditions of the spoke agentclusterinstall.
+func (spoke *SpokeClusterResources) agentClusterInstallConditions() ([]resourceCondition, error) {
+	if spoke.AgentClusterInstall == nil {
+		return nil, fmt.Errorf("agentclusterinstall is not defined")
+	}
+
+	agentClusterInstall, err := spoke.AgentClusterInstall.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	return fromClusterInstallConditions(agentClusterInstall.Name, agentClusterInstall.Status.Conditions), nil
+}
+
+// clusterDeploymentConditions returns the conditions of the spoke clusterdeployment.
+func (spoke *SpokeClusterResources) clusterDeploymentConditions() ([]resourceCondition, error) {
+	if spoke.ClusterDeployment == nil {
+		return nil, fmt.Errorf("clusterdeployment is not defined")
+	}
+
+	clusterDeployment, err := spoke.ClusterDeployment.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	return fromClusterDeploymentConditions(clusterDeployment.Name, clusterDeployment.Status.Conditions), nil
+}
+
+// infraEnvConditions returns the conditions of the spoke infraenv.
+func (spoke *SpokeClusterResources) infraEnvConditions() ([]resourceCondition, error) {
+	if spoke.InfraEnv == nil {
+		return nil, fmt.Errorf("infraenv is not defined")
+	}
+
+	infraEnv, err := spoke.InfraEnv.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	return fromConditionsV1(infraEnv.Name, infraEnv.Status.Conditions), nil
+}
+
+// agentConditions returns the conditions of every agent registered to the spoke infraenv.
+func (spoke *SpokeClusterResources) agentConditions() ([]resourceCondition, error) {
+	if spoke.InfraEnv == nil {
+		return nil, fmt.Errorf("infraenv is not defined")
+	}
+
+	agents, err := spoke.InfraEnv.GetAllAgents()
+	if err != nil {
+		return nil, err
+	}
+
+	var conditions []resourceCondition
+
+	for _, agent := range agents {
+		conditions = append(conditions, fromConditionsV1(agent.Object.Name, agent.Object.Status.Conditions)...)
+	}
+
+	return conditions, nil
+}
+
+// managedClusterConditions returns the conditions of the managedcluster named after the spoke.
+func (spoke *SpokeClusterResources) managedClusterConditions() ([]resourceCondition, error) {
+	managedCluster, err := ocm.PullManagedCluster(spoke.apiClient, spoke.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return fromMetaV1Conditions(managedCluster.Object.Name, managedCluster.Object.Status.Conditions), nil
+}
+
+// fromClusterInstallConditions normalizes hive clusterinstall conditions.
+func fromClusterInstallConditions(
+	object string, conditions []assistedHiveV1.ClusterInstallCondition) []resourceCondition {
+	var normalized []resourceCondition
+
+	for _, condition := range conditions {
+		normalized = append(normalized, resourceCondition{
+			Object:             object,
+			Type:               string(condition.Type),
+			Status:             string(condition.Status),
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+			LastTransitionTime: condition.LastTransitionTime.Time,
+		})
+	}
+
+	return normalized
+}
+
+// fromClusterDeploymentConditions normalizes hive clusterdeployment conditions.
+func fromClusterDeploymentConditions(
+	object string, conditions []hivev1.ClusterDeploymentCondition) []resourceCondition {
+	var normalized []resourceCondition
+
+	for _, condition := range conditions {
+		normalized = append(normalized, resourceCondition{
+			Object:             object,
+			Type:               string(condition.Type),
+			Status:             string(condition.Status),
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+			LastTransitionTime: condition.LastTransitionTime.Time,
+		})
+	}
+
+	return normalized
+}
+
+// fromConditionsV1 normalizes the conditionsv1 conditions used by the infraenv and agents.
+func fromConditionsV1(object string, conditions []conditionsv1.Condition) []resourceCondition {
+	var normalized []resourceCondition
+
+	for _, condition := range conditions {
+		normalized = append(normalized, resourceCondition{
+			Object:             object,
+			Type:               string(condition.Type),
+			Status:             string(condition.Status),
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+			LastTransitionTime: condition.LastTransitionTime.Time,
+		})
+	}
+
+	return normalized
+}
+
+// fromMetaV1Conditions normalizes the metav1 conditions used by the managedcluster.
+func fromMetaV1Conditions(object string, conditions []metav1.Condition) []resourceCondition {
+	var normalized []resourceCondition
+
+	for _, condition := range conditions {
+		normalized = append(normalized, resourceCondition{
+			Object:             object,
+			Type:               condition.Type,
+			Status:             string(condition.Status),
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+			LastTransitionTime: condition.LastTransitionTime.Time,
+		})
+	}
+
+	return normalized
+}
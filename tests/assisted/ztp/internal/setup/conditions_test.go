@@ -0,0 +1,277 @@
+package setup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	assistedHiveV1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/hive/api/v1"
+	hivev1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/hive/api/v1"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/ocm/clusterv1"
+	conditionsv1 "github.com/openshift/custom-resource-status/conditions/v1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var testTransitionTime = metav1.NewTime(time.Date(2024, 6, 3, 16, 1, 45, 0, time.UTC))
+
+func TestWaitForResourceCondition(t *testing.T) {
+	testCases := []struct {
+		kind     ResourceKind
+		condType string
+		objects  []runtime.Object
+	}{
+		{
+			kind:     ResourceKindAgentClusterInstall,
+			condType: "Completed",
+			objects:  []runtime.Object{buildDummyConditionAgentClusterInstall("Completed")},
+		},
+		{
+			kind:     ResourceKindClusterDeployment,
+			condType: "Provisioned",
+			objects:  []runtime.Object{buildDummyConditionClusterDeployment()},
+		},
+		{
+			kind:     ResourceKindInfraEnv,
+			condType: "ImageCreated",
+			objects:  []runtime.Object{buildDummyConditionInfraEnv()},
+		},
+		{
+			kind:     ResourceKindAgent,
+			condType: "Validated",
+			objects:  []runtime.Object{buildDummyConditionInfraEnv(), buildDummyConditionAgent("agent-0")},
+		},
+		{
+			kind:     ResourceKindManagedCluster,
+			condType: "ManagedClusterJoined",
+			objects:  []runtime.Object{buildDummyManagedCluster()},
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := newConditionTestSpoke(testCase.objects...)
+
+		err := spoke.WaitForResourceCondition(testCase.kind, testCase.condType, "True", time.Second)
+		assert.Nil(t, err, testCase.kind)
+
+		err = spoke.WaitForResourceCondition(testCase.kind, testCase.condType, "False", 10*time.Millisecond)
+		assert.NotNil(t, err, testCase.kind)
+		assert.Contains(t, err.Error(), "STATUS", testCase.kind)
+		assert.Contains(t, err.Error(), testCase.condType, testCase.kind)
+
+		err = spoke.WaitForResourceCondition(testCase.kind, "Missing", "True", 10*time.Millisecond)
+		assert.NotNil(t, err, testCase.kind)
+	}
+}
+
+func TestWaitForResourceConditionErrors(t *testing.T) {
+	spoke := newConditionTestSpoke()
+
+	err := spoke.WaitForResourceCondition("Unknown", "Ready", "True", time.Second)
+	assert.Equal(t, `unsupported resource kind "Unknown"`, err.Error())
+
+	err = spoke.WaitForResourceCondition(ResourceKindInfraEnv, "ImageCreated", "True", 10*time.Millisecond)
+	assert.NotNil(t, err)
+	assert.NotContains(t, err.Error(), "NAME")
+
+	err = NewSpokeCluster(newTestClient()).WithName("spoke").
+		WaitForResourceCondition(ResourceKindAgentClusterInstall, "Completed", "True", 10*time.Millisecond)
+	assert.Contains(t, err.Error(), "agentclusterinstall is not defined")
+}
+
+func TestWaitForResourceConditionAllAgents(t *testing.T) {
+	readyAgent := buildDummyConditionAgent("agent-0")
+	pendingAgent := buildDummyConditionAgent("agent-1")
+	pendingAgent.Status.Conditions[0].Status = corev1.ConditionFalse
+
+	spoke := newConditionTestSpoke(buildDummyConditionInfraEnv(), readyAgent, pendingAgent)
+
+	err := spoke.WaitForResourceCondition(ResourceKindAgent, "Validated", "True", 10*time.Millisecond)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "agent-1")
+}
+
+func TestWaitForClusterDeploymentCondition(t *testing.T) {
+	spoke := newConditionTestSpoke(buildDummyConditionClusterDeployment())
+
+	err := spoke.WaitForClusterDeploymentCondition("Provisioned", corev1.ConditionTrue, time.Second)
+	assert.Nil(t, err)
+
+	err = spoke.WaitForClusterDeploymentCondition("ProvisionFailed", corev1.ConditionTrue, 10*time.Millisecond)
+	assert.ErrorContains(t, err, "timed out waiting for ClusterDeployment condition ProvisionFailed to be True, "+
+		"current conditions:\nNAME    TYPE          STATUS")
+
+	err = NewSpokeCluster(newTestClient()).WithName("spoke").
+		WaitForClusterDeploymentCondition("Provisioned", corev1.ConditionTrue, time.Second)
+	assert.ErrorIs(t, err, ErrClusterDeploymentNotConfigured)
+	assert.EqualError(t, err, "clusterdeployment is not configured: cannot wait for condition Provisioned")
+}
+
+func TestWaitForClusterInstalled(t *testing.T) {
+	installed := buildDummyConditionClusterDeployment()
+	installed.Spec.Installed = true
+
+	spoke := newConditionTestSpoke(installed)
+	assert.Nil(t, spoke.WaitForClusterInstalled(time.Second))
+
+	failed := buildDummyConditionClusterDeployment()
+	failed.Status.Conditions = append(failed.Status.Conditions, hivev1.ClusterDeploymentCondition{
+		Type:    hivev1.ClusterInstallFailedClusterDeploymentCondition,
+		Status:  corev1.ConditionTrue,
+		Reason:  "InstallationFailed",
+		Message: "The installation failed: timed out",
+	})
+
+	spoke = newConditionTestSpoke(failed).
+		WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Minute})
+
+	start := time.Now()
+	err := spoke.WaitForClusterInstalled(0)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.ErrorContains(t, err, "clusterdeployment of spoke spoke failed with condition ClusterInstallFailed "+
+		"reason InstallationFailed: The installation failed: timed out, current conditions:\n")
+	assert.ErrorContains(t, err, "spoke   Provisioned")
+
+	spoke = newConditionTestSpoke(buildDummyConditionClusterDeployment())
+	err = spoke.WaitForClusterInstalled(10 * time.Millisecond)
+	assert.ErrorContains(t, err, "timed out waiting for clusterdeployment of spoke spoke to be installed, "+
+		"current conditions:\nNAME")
+
+	err = newConditionTestSpoke().WaitForClusterInstalled(10 * time.Millisecond)
+	assert.ErrorContains(t, err, "timed out waiting for clusterdeployment of spoke spoke to be installed: ")
+	assert.NotContains(t, err.Error(), "NAME")
+
+	err = NewSpokeCluster(newTestClient()).WithName("spoke").WaitForClusterInstalled(time.Second)
+	assert.ErrorIs(t, err, ErrClusterDeploymentNotConfigured)
+}
+
+func TestNormalizeConditions(t *testing.T) {
+	expected := []resourceCondition{{
+		Object:             "object",
+		Type:               "Ready",
+		Status:             "True",
+		Reason:             "Done",
+		Message:            "The object is ready",
+		LastTransitionTime: testTransitionTime.Time,
+	}}
+
+	assert.Equal(t, expected, fromClusterInstallConditions("object", []assistedHiveV1.ClusterInstallCondition{{
+		Type: "Ready", Status: corev1.ConditionTrue, Reason: "Done", Message: "The object is ready",
+		LastTransitionTime: testTransitionTime, LastProbeTime: metav1.Now(),
+	}}))
+	assert.Equal(t, expected, fromClusterDeploymentConditions("object", []hivev1.ClusterDeploymentCondition{{
+		Type: "Ready", Status: corev1.ConditionTrue, Reason: "Done", Message: "The object is ready",
+		LastTransitionTime: testTransitionTime, LastProbeTime: metav1.Now(),
+	}}))
+	assert.Equal(t, expected, fromConditionsV1("object", []conditionsv1.Condition{{
+		Type: "Ready", Status: corev1.ConditionTrue, Reason: "Done", Message: "The object is ready",
+		LastTransitionTime: testTransitionTime, LastHeartbeatTime: metav1.Now(),
+	}}))
+	assert.Equal(t, expected, fromMetaV1Conditions("object", []metav1.Condition{{
+		Type: "Ready", Status: metav1.ConditionTrue, Reason: "Done", Message: "The object is ready",
+		LastTransitionTime: testTransitionTime, ObservedGeneration: 2,
+	}}))
+	assert.Nil(t, fromConditionsV1("object", nil))
+}
+
+func TestConditionsMet(t *testing.T) {
+	conditions := []resourceCondition{
+		{Object: "agent-0", Type: "Validated", Status: "True"},
+		{Object: "agent-0", Type: "Installed", Status: "False"},
+		{Object: "agent-1", Type: "Validated", Status: "True"},
+	}
+
+	assert.True(t, conditionsMet(conditions, "Validated", "True"))
+	assert.False(t, conditionsMet(conditions, "Installed", "False"))
+	assert.False(t, conditionsMet(conditions, "Validated", "False"))
+	assert.False(t, conditionsMet(nil, "Validated", "True"))
+}
+
+func TestFormatConditionTable(t *testing.T) {
+	table := formatConditionTable([]resourceCondition{
+		{
+			Object:             "spoke",
+			Type:               "Completed",
+			Status:             "False",
+			Reason:             "InstallationInProgress",
+			Message:            "The installation is in progress",
+			LastTransitionTime: testTransitionTime.Time,
+		},
+		{Object: "spoke", Type: "Validated", Status: "True", Reason: "ValidationsPassing"},
+	})
+
+	assertGolden(t, "conditions/table.txt", []byte(table))
+	assert.Equal(t, "No conditions found", formatConditionTable(nil))
+}
+
+// newConditionTestSpoke returns a spoke named spoke with all of its resources defined, backed by a fake client
+// holding objects.
+func newConditionTestSpoke(objects ...runtime.Object) *SpokeClusterResources {
+	apiClient := clients.GetTestClients(clients.TestClientParams{
+		K8sMockObjects: objects,
+		SchemeAttachers: []clients.SchemeAttacher{
+			v1beta1.AddToScheme,
+			agentInstallV1Beta1.AddToScheme,
+			hivev1.AddToScheme,
+			clusterv1.Install,
+		},
+	})
+
+	return NewSpokeCluster(apiClient).WithName("spoke").WithDefaultNamespace().WithDefaultClusterDeployment().
+		WithDefaultIPv4AgentClusterInstall().WithDefaultInfraEnv()
+}
+
+func buildDummyConditionAgentClusterInstall(condType string) *v1beta1.AgentClusterInstall {
+	return &v1beta1.AgentClusterInstall{
+		ObjectMeta: metav1.ObjectMeta{Name: "spoke", Namespace: "spoke"},
+		Status: v1beta1.AgentClusterInstallStatus{
+			Conditions: []assistedHiveV1.ClusterInstallCondition{
+				{Type: assistedHiveV1.ClusterInstallConditionType(condType), Status: corev1.ConditionTrue},
+			},
+		},
+	}
+}
+
+func buildDummyConditionClusterDeployment() *hivev1.ClusterDeployment {
+	return &hivev1.ClusterDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "spoke", Namespace: "spoke"},
+		Status: hivev1.ClusterDeploymentStatus{
+			Conditions: []hivev1.ClusterDeploymentCondition{{Type: "Provisioned", Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+func buildDummyConditionInfraEnv() *agentInstallV1Beta1.InfraEnv {
+	return &agentInstallV1Beta1.InfraEnv{
+		ObjectMeta: metav1.ObjectMeta{Name: "spoke", Namespace: "spoke"},
+		Status: agentInstallV1Beta1.InfraEnvStatus{
+			Conditions: []conditionsv1.Condition{{Type: "ImageCreated", Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+func buildDummyConditionAgent(name string) *agentInstallV1Beta1.Agent {
+	return &agentInstallV1Beta1.Agent{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "spoke",
+			Labels:    map[string]string{"infraenvs.agent-install.openshift.io": "spoke"},
+		},
+		Status: agentInstallV1Beta1.AgentStatus{
+			Conditions: []conditionsv1.Condition{{Type: "Validated", Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+func buildDummyManagedCluster() *clusterv1.ManagedCluster {
+	return &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "spoke"},
+		Status: clusterv1.ManagedClusterStatus{
+			Conditions: []metav1.Condition{{Type: "ManagedClusterJoined", Status: metav1.ConditionTrue}},
+		},
+	}
+}
@@ -0,0 +1,60 @@
+package setup
+
+import (
+	"sync"
+
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpconfig"
+	. "github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpinittools"
+)
+
+var (
+	ztpConfig      = ZTPConfig
+	ztpConfigMutex sync.RWMutex
+)
+
+// SetConfig overrides the ZTP configuration the spoke builders read their defaults from, such as the hub
+// pull-secret, OCP version, vips and networks, so that the package can be exercised against a fake client without
+// a hub. Passing nil restores the configuration loaded by ztpinittools. It returns a function restoring the previous
+// configuration, typically deferred by tests.
+func SetConfig(config *ztpconfig.ZTPConfig) func() {
+	if config == nil {
+		config = ZTPConfig
+	}
+
+	ztpConfigMutex.Lock()
+	defer ztpConfigMutex.Unlock()
+
+	previous := ztpConfig
+	ztpConfig = config
+
+	return func() {
+		ztpConfigMutex.Lock()
+		defer ztpConfigMutex.Unlock()
+
+		ztpConfig = previous
+	}
+}
+
+// hubConfig returns the hub section of the ZTP configuration, empty when it is not set.
+func hubConfig() *ztpconfig.HubConfig {
+	ztpConfigMutex.RLock()
+	defer ztpConfigMutex.RUnlock()
+
+	if ztpConfig == nil || ztpConfig.HubConfig == nil {
+		return &ztpconfig.HubConfig{}
+	}
+
+	return ztpConfig.HubConfig
+}
+
+// spokeConfig returns the spoke section of the ZTP configuration, empty when it is not set.
+func spokeConfig() *ztpconfig.SpokeConfig {
+	ztpConfigMutex.RLock()
+	defer ztpConfigMutex.RUnlock()
+
+	if ztpConfig == nil || ztpConfig.SpokeConfig == nil {
+		return &ztpconfig.SpokeConfig{}
+	}
+
+	return ztpConfig.SpokeConfig
+}
@@ -0,0 +1,44 @@
+package setup
+
+import (
+	"testing"
+
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpconfig"
+	. "github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpinittools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetConfig(t *testing.T) {
+	restore := SetConfig(&ztpconfig.ZTPConfig{
+		HubConfig:   &ztpconfig.HubConfig{HubOCPXYVersion: "4.15"},
+		SpokeConfig: &ztpconfig.SpokeConfig{SpokeBaseDomain: "lab.example.com", SpokeClusterCIDR: "10.140.0.0/14"},
+	})
+
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultClusterDeployment().
+		WithDefaultIPv4AgentClusterInstall()
+	assert.Nil(t, spoke.err)
+	assert.Equal(t, "lab.example.com", spoke.ClusterDeployment.Definition.Spec.BaseDomain)
+	assert.Equal(t, "4.15", spoke.AgentClusterInstall.Definition.Spec.ImageSetRef.Name)
+	assert.Equal(t, "10.140.0.0/14", spoke.AgentClusterInstall.Definition.Spec.Networking.ClusterNetwork[0].CIDR)
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultPullSecret()
+	assert.EqualError(t, spoke.err, "WithDefaultPullSecret: failed to pull hub pull-secret "+
+		"openshift-config/pull-secret: secret object pull-secret does not exist in namespace openshift-config")
+
+	restoreEmpty := SetConfig(&ztpconfig.ZTPConfig{})
+	assert.Equal(t, &ztpconfig.HubConfig{}, hubConfig())
+	assert.Equal(t, &ztpconfig.SpokeConfig{}, spokeConfig())
+
+	restoreEmpty()
+	assert.Equal(t, "lab.example.com", spokeConfig().SpokeBaseDomain)
+
+	restore()
+	assert.Same(t, testZTPConfig.HubConfig, hubConfig())
+	assert.Same(t, testZTPConfig.SpokeConfig, spokeConfig())
+
+	restore = SetConfig(nil)
+	assert.Same(t, ZTPConfig, ztpConfig)
+
+	restore()
+	assert.Same(t, testZTPConfig, ztpConfig)
+}
@@ -0,0 +1,126 @@
+package setup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCreateWithContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	spoke := NewSpokeCluster(newHubTestClient()).WithName("spoke").WithDefaultNamespace().WithDefaultPullSecret().
+		WithDefaultClusterDeployment()
+
+	_, err := spoke.CreateWithContext(ctx)
+	assert.EqualError(t, err, "creation of spoke spoke interrupted after creating no resources: context canceled")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, spoke.Namespace.Exists())
+
+	_, err = NewSpokeCluster(newHubTestClient()).WithName("").CreateWithContext(ctx)
+	assert.EqualError(t, err, "WithName: spoke name cannot be empty")
+}
+
+func TestCreateWithContextInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	apiClient := newFailingTestClient(func() error {
+		cancel()
+
+		return nil
+	})
+
+	spoke := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultNamespace().WithDefaultPullSecret().
+		WithDefaultClusterDeployment().WithDefaultManagedCluster()
+
+	_, err := spoke.CreateWithContext(ctx)
+
+	var interrupted *CreateInterruptedError
+
+	assert.ErrorAs(t, err, &interrupted)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []string{"namespace", "pull-secret", "clusterdeployment"}, interrupted.Created)
+	assert.Equal(t, "create clusterdeployment", interrupted.Step)
+	assert.EqualError(t, err, "creation of spoke spoke interrupted during create clusterdeployment after creating "+
+		"namespace, pull-secret, clusterdeployment: context canceled")
+	assert.True(t, spoke.ClusterDeployment.Exists())
+	assert.False(t, spoke.ManagedCluster.Exists())
+}
+
+func TestDeleteWithContextInterrupted(t *testing.T) {
+	agentClusterInstall := &v1beta1.AgentClusterInstall{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "spoke",
+			Namespace:  "spoke",
+			Finalizers: []string{testDeprovisionFinalizer},
+		},
+	}
+
+	spoke := NewSpokeCluster(newHubTestClient(agentClusterInstall)).WithName("spoke").WithDefaultPullSecret().
+		WithDefaultClusterDeployment().WithDefaultIPv4AgentClusterInstall().
+		WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Second, DeleteTimeout: time.Minute})
+
+	_, err := spoke.Create()
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err = spoke.DeleteWithContext(ctx)
+	assert.EqualError(t, err,
+		"deletion of spoke spoke interrupted during delete agentclusterinstall spoke: context deadline exceeded")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, spoke.ClusterDeployment.Exists())
+	assert.True(t, spoke.PullSecret.Exists())
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = spoke.DeleteWithContext(canceled)
+	assert.EqualError(t, err, "deletion of spoke spoke interrupted: context canceled")
+	assert.True(t, spoke.PullSecret.Exists())
+}
+
+func TestWaitWithContextCanceled(t *testing.T) {
+	infraEnv := buildDummyInfraEnvObject("spoke")
+	infraEnv.Status = buildDummyInfraEnvStatus(corev1.ConditionFalse, "image is being created", "")
+
+	spoke := NewSpokeCluster(newTestClient(infraEnv)).WithName("spoke").WithDefaultInfraEnv().
+		WithDefaultIPv4AgentClusterInstall().
+		WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Minute})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	_, err := spoke.WaitForDiscoveryISOWithContext(ctx, 0)
+	assert.NotNil(t, err)
+
+	err = spoke.WaitForInstallStartedWithContext(ctx, 0)
+	assert.NotNil(t, err)
+
+	_, err = spoke.WaitForAgentsRegisteredWithContext(ctx, 1, 0)
+	assert.NotNil(t, err)
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
+
+func TestWaitForDiscoveryISOWithContextDone(t *testing.T) {
+	spoke := NewSpokeCluster(newTestClient(buildDummyInfraEnvObject("spoke"))).WithName("spoke").
+		WithDefaultInfraEnv().
+		WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Minute, BackoffFactor: 2})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := spoke.WaitForDiscoveryISOWithContext(ctx, 0)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.EqualError(t, err, "timed out waiting for discovery iso of spoke spoke: context canceled")
+}
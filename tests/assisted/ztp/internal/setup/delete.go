@@ -0,0 +1,63 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	runtimeClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// waitForDeletion polls until the object of kind is removed from the hub, up to the delete timeout of the spoke
+// wait options. On timeout, the error names the finalizers remaining on the object so stuck deletions can be
+// attributed to the controller owning them.
+func (spoke *SpokeClusterResources) waitForDeletion(
+	ctx context.Context, kind string, object runtimeClient.Object) error {
+	options := spoke.deleteWaitOptions()
+	key := runtimeClient.ObjectKeyFromObject(object)
+
+	var getErr error
+
+	err := spoke.poll(ctx, "wait for "+kind+" deletion", options, func(ctx context.Context) (bool, error) {
+		getErr = spoke.apiClient.Get(ctx, key, object)
+		if k8serrors.IsNotFound(getErr) {
+			return true, nil
+		}
+
+		return false, nil
+	})
+	if err == nil {
+		return nil
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if getErr != nil {
+		return fmt.Errorf("%s %s was not confirmed removed within %s: %w", kind, key.Name, options.Timeout, getErr)
+	}
+
+	finalizers := "none"
+	if len(object.GetFinalizers()) > 0 {
+		finalizers = strings.Join(object.GetFinalizers(), ", ")
+	}
+
+	return fmt.Errorf("%s %s was not removed within %s, remaining finalizers: %s",
+		kind, key.Name, options.Timeout, finalizers)
+}
+
+// deleteWaitOptions returns the spoke wait options with the timeout set to the delete timeout.
+func (spoke *SpokeClusterResources) deleteWaitOptions() WaitOptions {
+	options := spoke.resolveWaitOptions()
+
+	options.Timeout = options.DeleteTimeout
+	if options.Timeout == 0 {
+		options.Timeout = defaultDeleteTimeout
+	}
+
+	options.Interval = min(options.Interval, options.Timeout)
+
+	return options
+}
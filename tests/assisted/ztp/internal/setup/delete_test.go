@@ -0,0 +1,46 @@
+package setup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testDeprovisionFinalizer = "agentclusterinstall.agent-install.openshift.io/ai-deprovision"
+
+func TestDeleteWaitsForFinalizers(t *testing.T) {
+	agentClusterInstall := &v1beta1.AgentClusterInstall{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "spoke",
+			Namespace:  "spoke",
+			Finalizers: []string{testDeprovisionFinalizer},
+		},
+	}
+
+	spoke := NewSpokeCluster(newHubTestClient(agentClusterInstall)).WithName("spoke").WithDefaultPullSecret().
+		WithDefaultClusterDeployment().WithDefaultIPv4AgentClusterInstall().
+		WithWaitOptions(&WaitOptions{
+			Interval: time.Millisecond, Timeout: time.Second, DeleteTimeout: 10 * time.Millisecond,
+		})
+
+	_, err := spoke.Create()
+	assert.Nil(t, err)
+
+	err = spoke.Delete()
+	assert.EqualError(t, err, "failed to delete agentclusterinstall spoke: agentclusterinstall spoke was not removed "+
+		"within 10ms, remaining finalizers: "+testDeprovisionFinalizer)
+	assert.False(t, spoke.ClusterDeployment.Exists())
+	assert.False(t, spoke.PullSecret.Exists())
+}
+
+func TestDeleteWaitOptions(t *testing.T) {
+	spoke := NewSpokeCluster(newTestClient()).WithWaitOptions(&WaitOptions{Interval: time.Minute, Timeout: time.Hour})
+	assert.Equal(t, WaitOptions{Interval: time.Minute, Timeout: defaultDeleteTimeout}, spoke.deleteWaitOptions())
+
+	spoke.WithWaitOptions(&WaitOptions{Interval: time.Minute, Timeout: time.Hour, DeleteTimeout: time.Second})
+	assert.Equal(t, WaitOptions{Interval: time.Second, Timeout: time.Second, DeleteTimeout: time.Second},
+		spoke.deleteWaitOptions())
+}
@@ -0,0 +1,267 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-goinfra/pkg/pod"
+	hivev1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/hive/api/v1"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtimeClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// hiveClusterDeploymentNameLabel and hiveUninstallLabel are set by hive on the uninstall job it creates when a
+	// clusterdeployment is deleted.
+	hiveClusterDeploymentNameLabel = "hive.openshift.io/cluster-deployment-name"
+	hiveUninstallLabel             = "hive.openshift.io/uninstall"
+	// deprovisionLogTailLines is the number of trailing log lines of each uninstall pod container surfaced when the
+	// uninstall job fails.
+	deprovisionLogTailLines = 50
+)
+
+// WithPreserveOnDelete sets preserveOnDelete on the spoke clusterdeployment, so that deleting it disconnects the
+// spoke from hive without deprovisioning the spoke infrastructure. Deprovision sets it on an existing
+// clusterdeployment before deleting it.
+func (spoke *SpokeClusterResources) WithPreserveOnDelete(preserve bool) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.ClusterDeployment == nil {
+		spoke.err = fmt.Errorf(
+			"WithPreserveOnDelete: clusterdeployment must be defined before setting preserveOnDelete")
+
+		return spoke
+	}
+
+	spoke.ClusterDeployment.Definition.Spec.PreserveOnDelete = preserve
+
+	return spoke
+}
+
+// Deprovision deletes the spoke clusterdeployment and waits, up to timeout or the spoke wait timeout when it is 0,
+// for hive to run the uninstall job in the spoke namespace and remove the clusterdeployment. When the uninstall job
+// fails, the error holds the tail of the logs of its pods and the remaining resources are left in place for
+// investigation. Once the clusterdeployment is removed, the remaining resources are deleted like Delete. When
+// preserveOnDelete is set, no uninstall job is expected and the spoke infrastructure is kept.
+func (spoke *SpokeClusterResources) Deprovision(timeout time.Duration) error {
+	return spoke.DeprovisionWithContext(context.Background(), timeout)
+}
+
+// DeprovisionWithContext deprovisions the spoke like Deprovision, stopping the wait for the uninstall job and the
+// deletion of the remaining resources when ctx is done.
+func (spoke *SpokeClusterResources) DeprovisionWithContext(ctx context.Context, timeout time.Duration) error {
+	if spoke.ClusterDeployment == nil {
+		return fmt.Errorf("%w: clusterdeployment must be defined before deprovisioning the spoke",
+			ErrClusterDeploymentNotConfigured)
+	}
+
+	clusterDeployment, preserve, err := spoke.deleteClusterDeployment(ctx)
+	if err != nil {
+		return err
+	}
+
+	if clusterDeployment != nil {
+		if err := spoke.waitForUninstall(ctx, clusterDeployment, preserve, timeout); err != nil {
+			return err
+		}
+	}
+
+	return spoke.DeleteWithContext(ctx)
+}
+
+// deleteClusterDeployment deletes the spoke clusterdeployment, setting preserveOnDelete on it first when it is set
+// on its definition, and returns the deleted clusterdeployment along with whether its infrastructure is preserved.
+// The returned clusterdeployment is nil when it does not exist.
+func (spoke *SpokeClusterResources) deleteClusterDeployment(
+	ctx context.Context) (*hivev1.ClusterDeployment, bool, error) {
+	clusterDeployment, err := spoke.ClusterDeployment.Get()
+	if k8serrors.IsNotFound(err) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get clusterdeployment of spoke %s: %w", spoke.Name, err)
+	}
+
+	preserve := clusterDeployment.Spec.PreserveOnDelete
+
+	if spoke.ClusterDeployment.Definition.Spec.PreserveOnDelete && !preserve {
+		patch := runtimeClient.MergeFrom(clusterDeployment.DeepCopy())
+		clusterDeployment.Spec.PreserveOnDelete = true
+
+		if err := spoke.apiClient.Patch(ctx, clusterDeployment, patch); err != nil {
+			return nil, false, fmt.Errorf("failed to set preserveOnDelete on clusterdeployment %s: %w", spoke.Name, err)
+		}
+
+		preserve = true
+	}
+
+	deleteStart := time.Now()
+	err = spoke.retryTransient(ctx, "delete clusterdeployment", spoke.ClusterDeployment.Delete)
+
+	spoke.recordPhase("delete clusterdeployment", deleteStart, 0, runtimeClient.IgnoreNotFound(err))
+
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return nil, false, fmt.Errorf("failed to delete clusterdeployment %s: %w", spoke.Name, err)
+	}
+
+	return clusterDeployment, preserve, nil
+}
+
+// waitForUninstall waits up to timeout, or the spoke wait timeout when it is 0, until the deleted clusterDeployment
+// is removed, failing early with the logs of the uninstall job when it fails. No uninstall job is expected when
+// preserve is set.
+func (spoke *SpokeClusterResources) waitForUninstall(
+	ctx context.Context, clusterDeployment *hivev1.ClusterDeployment, preserve bool, timeout time.Duration) error {
+	options := spoke.resolveWaitOptions()
+	if timeout > 0 {
+		options.Timeout = timeout
+		options.Interval = min(options.Interval, timeout)
+	}
+
+	var (
+		uninstallErr error
+		uninstallJob *batchv1.Job
+	)
+
+	err := spoke.poll(ctx, "wait for deprovision", options, func(ctx context.Context) (bool, error) {
+		if !preserve {
+			uninstallJob = spoke.getUninstallJob(ctx, clusterDeployment)
+
+			if uninstallJob != nil && uninstallJobFailed(uninstallJob) {
+				uninstallErr = spoke.uninstallJobFailure(uninstallJob)
+
+				return false, uninstallErr
+			}
+		}
+
+		err := spoke.apiClient.Get(ctx, runtimeClient.ObjectKeyFromObject(clusterDeployment), clusterDeployment)
+		if k8serrors.IsNotFound(err) {
+			return true, nil
+		}
+
+		glog.V(ztpparams.ZTPLogLevel).Infof("Waiting for clusterdeployment %s to be deprovisioned, uninstall job: %s",
+			spoke.Name, describeUninstallJob(uninstallJob, preserve))
+
+		return false, nil
+	})
+	if uninstallErr != nil {
+		return uninstallErr
+	}
+
+	if err != nil {
+		return fmt.Errorf("timed out waiting for deprovision of spoke %s, uninstall job: %s",
+			spoke.Name, describeUninstallJob(uninstallJob, preserve))
+	}
+
+	return nil
+}
+
+// getUninstallJob returns the most recent uninstall job hive created for the clusterdeployment, nil when there is
+// none yet or the jobs cannot be listed.
+func (spoke *SpokeClusterResources) getUninstallJob(
+	ctx context.Context, clusterDeployment runtimeClient.Object) *batchv1.Job {
+	jobList := &batchv1.JobList{}
+
+	err := spoke.apiClient.List(ctx, jobList, runtimeClient.InNamespace(clusterDeployment.GetNamespace()),
+		runtimeClient.MatchingLabels{
+			hiveClusterDeploymentNameLabel: clusterDeployment.GetName(),
+			hiveUninstallLabel:             "true",
+		})
+	if err != nil {
+		glog.V(ztpparams.ZTPLogLevel).Infof("Failed to list uninstall jobs of spoke %s: %v", spoke.Name, err)
+
+		return nil
+	}
+
+	var latest *batchv1.Job
+
+	for index := range jobList.Items {
+		job := &jobList.Items[index]
+
+		if latest == nil || latest.CreationTimestamp.Before(&job.CreationTimestamp) {
+			latest = job
+		}
+	}
+
+	return latest
+}
+
+// uninstallJobFailure returns the error describing the failed uninstall job, holding the tail of the logs of the
+// containers of its pods.
+func (spoke *SpokeClusterResources) uninstallJobFailure(job *batchv1.Job) error {
+	var logs strings.Builder
+
+	pods, err := pod.List(spoke.apiClient, job.Namespace, metav1.ListOptions{LabelSelector: "job-name=" + job.Name})
+	if err != nil {
+		fmt.Fprintf(&logs, "\nfailed to list pods of job %s: %v", job.Name, err)
+	}
+
+	for _, jobPod := range pods {
+		for _, container := range jobPod.Definition.Spec.Containers {
+			fmt.Fprintf(&logs, "\n--- %s/%s ---\n", jobPod.Definition.Name, container.Name)
+
+			log, err := jobPod.GetFullLog(container.Name)
+			if err != nil {
+				fmt.Fprintf(&logs, "failed to get logs: %v", err)
+
+				continue
+			}
+
+			logs.WriteString(tailLines(log, deprovisionLogTailLines))
+		}
+	}
+
+	return fmt.Errorf("uninstall job %s of spoke %s failed: %s%s",
+		job.Name, spoke.Name, describeUninstallJob(job, false), logs.String())
+}
+
+// uninstallJobFailed returns whether the job has failed for good.
+func uninstallJobFailed(job *batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
+
+// describeUninstallJob returns a short description of the state of the uninstall job for logs and errors.
+func describeUninstallJob(job *batchv1.Job, preserve bool) string {
+	if preserve {
+		return "none, preserveOnDelete is set"
+	}
+
+	if job == nil {
+		return "not created"
+	}
+
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return fmt.Sprintf("%s failed, %s: %s", job.Name, condition.Reason, condition.Message)
+		}
+	}
+
+	return fmt.Sprintf("%s active %d, succeeded %d, failed %d",
+		job.Name, job.Status.Active, job.Status.Succeeded, job.Status.Failed)
+}
+
+// tailLines returns the last count lines of text.
+func tailLines(text string, count int) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) > count {
+		lines = lines[len(lines)-count:]
+	}
+
+	return strings.Join(lines, "\n")
+}
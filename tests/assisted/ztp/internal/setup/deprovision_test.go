@@ -0,0 +1,156 @@
+package setup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestDeprovision(t *testing.T) {
+	testCases := []struct {
+		name          string
+		finalizer     bool
+		preserve      bool
+		objects       []runtime.Object
+		expectedError string
+	}{
+		{name: "deprovisioned"},
+		{name: "preserved", preserve: true},
+		{
+			name:      "uninstall failed",
+			finalizer: true,
+			objects:   []runtime.Object{buildDummyUninstallJob(true), buildDummyUninstallPod()},
+			expectedError: "uninstall job spoke-uninstall of spoke spoke failed: spoke-uninstall failed, " +
+				"BackoffLimitExceeded: Job has reached the specified backoff limit\n" +
+				"--- spoke-uninstall-x7k2p/deprovision ---\nfake logs",
+		},
+		{
+			name:      "uninstall running",
+			finalizer: true,
+			objects:   []runtime.Object{buildDummyUninstallJob(false)},
+			expectedError: "timed out waiting for deprovision of spoke spoke, uninstall job: spoke-uninstall " +
+				"active 1, succeeded 0, failed 0",
+		},
+		{
+			name:          "uninstall not created",
+			finalizer:     true,
+			expectedError: "timed out waiting for deprovision of spoke spoke, uninstall job: not created",
+		},
+		{
+			name:      "preserved pending",
+			finalizer: true,
+			preserve:  true,
+			objects:   []runtime.Object{buildDummyUninstallJob(true)},
+			expectedError: "timed out waiting for deprovision of spoke spoke, uninstall job: none, " +
+				"preserveOnDelete is set",
+		},
+	}
+
+	for _, testCase := range testCases {
+		clusterDeployment := buildDummyConditionClusterDeployment()
+		if testCase.finalizer {
+			clusterDeployment.Finalizers = []string{"hive.openshift.io/deprovision"}
+		}
+
+		spoke := newConditionTestSpoke(append(testCase.objects, clusterDeployment)...).
+			WithPreserveOnDelete(testCase.preserve).
+			WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Second})
+
+		err := spoke.Deprovision(20 * time.Millisecond)
+
+		if testCase.expectedError != "" {
+			assert.EqualError(t, err, testCase.expectedError, testCase.name)
+
+			live, getErr := spoke.ClusterDeployment.Get()
+			assert.Nil(t, getErr, testCase.name)
+			assert.Equal(t, testCase.preserve, live.Spec.PreserveOnDelete, testCase.name)
+
+			continue
+		}
+
+		assert.Nil(t, err, testCase.name)
+		assert.False(t, spoke.ClusterDeployment.Exists(), testCase.name)
+
+		phases := map[string]bool{}
+		for _, timing := range spoke.GetTimings().Phases {
+			phases[timing.Phase] = true
+		}
+
+		assert.True(t, phases["wait for deprovision"], testCase.name)
+		assert.True(t, phases["delete"], testCase.name)
+	}
+}
+
+func TestDeprovisionErrors(t *testing.T) {
+	err := NewSpokeCluster(newTestClient()).WithName("spoke").Deprovision(time.Second)
+	assert.ErrorIs(t, err, ErrClusterDeploymentNotConfigured)
+
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithPreserveOnDelete(true)
+	assert.EqualError(t, spoke.err,
+		"WithPreserveOnDelete: clusterdeployment must be defined before setting preserveOnDelete")
+
+	assert.Nil(t, newConditionTestSpoke().Deprovision(time.Second))
+
+	clusterDeployment := buildDummyConditionClusterDeployment()
+	clusterDeployment.Finalizers = []string{"hive.openshift.io/deprovision"}
+
+	spoke = newConditionTestSpoke(clusterDeployment).
+		WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Minute})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err = spoke.DeprovisionWithContext(ctx, 0)
+	assert.ErrorContains(t, err, "timed out waiting for deprovision of spoke spoke")
+	assert.True(t, spoke.ClusterDeployment.Exists())
+}
+
+func TestTailLines(t *testing.T) {
+	assert.Equal(t, "c\nd", tailLines("a\nb\nc\nd\n", 2))
+	assert.Equal(t, "a\nb", tailLines("a\nb", 5))
+}
+
+func buildDummyUninstallJob(failed bool) *batchv1.Job {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "spoke-uninstall",
+			Namespace: "spoke",
+			Labels: map[string]string{
+				hiveClusterDeploymentNameLabel: "spoke",
+				hiveUninstallLabel:             "true",
+			},
+		},
+		Status: batchv1.JobStatus{Active: 1},
+	}
+
+	if failed {
+		job.Status = batchv1.JobStatus{
+			Failed: 3,
+			Conditions: []batchv1.JobCondition{{
+				Type:    batchv1.JobFailed,
+				Status:  corev1.ConditionTrue,
+				Reason:  "BackoffLimitExceeded",
+				Message: "Job has reached the specified backoff limit",
+			}},
+		}
+	}
+
+	return job
+}
+
+func buildDummyUninstallPod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "spoke-uninstall-x7k2p",
+			Namespace: "spoke",
+			Labels:    map[string]string{"job-name": "spoke-uninstall"},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "deprovision"}}},
+	}
+}
@@ -0,0 +1,182 @@
+package setup
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// serverPopulatedMetadata lists the metadata fields set by the API server that are excluded from comparisons.
+var serverPopulatedMetadata = []string{
+	"creationTimestamp", "deletionGracePeriodSeconds", "deletionTimestamp", "generation", "managedFields",
+	"resourceVersion", "selfLink", "uid",
+}
+
+// ConfigChange describes a single field that differs between two spoke configurations.
+type ConfigChange struct {
+	Path   string
+	Before interface{}
+	After  interface{}
+}
+
+// ConfigDiff contains the fields added, removed and changed between two spoke configurations, sorted by path.
+type ConfigDiff struct {
+	Added   []ConfigChange
+	Removed []ConfigChange
+	Changed []ConfigChange
+}
+
+// DiffSpokeConfigs compares the definitions of the namespace, pull-secret, clusterdeployment, agentclusterinstall
+// and infraenv of two spoke clusters field by field, ignoring status and server-populated metadata.
+func DiffSpokeConfigs(before, after *SpokeClusterResources) (ConfigDiff, error) {
+	if before == nil || after == nil {
+		return ConfigDiff{}, fmt.Errorf("cannot diff nil spoke cluster resources")
+	}
+
+	beforeFields, err := before.flattenedDefinitions()
+	if err != nil {
+		return ConfigDiff{}, err
+	}
+
+	afterFields, err := after.flattenedDefinitions()
+	if err != nil {
+		return ConfigDiff{}, err
+	}
+
+	var diff ConfigDiff
+
+	for path, beforeValue := range beforeFields {
+		afterValue, found := afterFields[path]
+
+		switch {
+		case !found:
+			diff.Removed = append(diff.Removed, ConfigChange{Path: path, Before: beforeValue})
+		case !reflect.DeepEqual(beforeValue, afterValue):
+			diff.Changed = append(diff.Changed, ConfigChange{Path: path, Before: beforeValue, After: afterValue})
+		}
+	}
+
+	for path, afterValue := range afterFields {
+		if _, found := beforeFields[path]; !found {
+			diff.Added = append(diff.Added, ConfigChange{Path: path, After: afterValue})
+		}
+	}
+
+	for _, changes := range [][]ConfigChange{diff.Added, diff.Removed, diff.Changed} {
+		sort.Slice(changes, func(i, j int) bool {
+			return changes[i].Path < changes[j].Path
+		})
+	}
+
+	return diff, nil
+}
+
+// Empty returns true when the compared spoke configurations are identical.
+func (diff ConfigDiff) Empty() bool {
+	return len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Changed) == 0
+}
+
+// String renders the diff with one line per field, prefixed by + for added, - for removed and ~ for changed fields.
+func (diff ConfigDiff) String() string {
+	if diff.Empty() {
+		return "no differences\n"
+	}
+
+	var builder strings.Builder
+
+	for _, change := range diff.Added {
+		builder.WriteString(fmt.Sprintf("+ %s: %v\n", change.Path, change.After))
+	}
+
+	for _, change := range diff.Removed {
+		builder.WriteString(fmt.Sprintf("- %s: %v\n", change.Path, change.Before))
+	}
+
+	for _, change := range diff.Changed {
+		builder.WriteString(fmt.Sprintf("~ %s: %v -> %v\n", change.Path, change.Before, change.After))
+	}
+
+	return builder.String()
+}
+
+// flattenedDefinitions returns the fields of every instantiated core spoke resource definition keyed by
+// their path, prefixed with the resource kind.
+func (spoke *SpokeClusterResources) flattenedDefinitions() (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+
+	for kind, definition := range spoke.coreDefinitions() {
+		content, err := definitionToUnstructured(definition)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert %s definition: %w", kind, err)
+		}
+
+		flattenFields(kind, content, fields)
+	}
+
+	return fields, nil
+}
+
+// coreDefinitions returns the definitions of the instantiated core spoke resources keyed by kind.
+func (spoke *SpokeClusterResources) coreDefinitions() map[string]runtime.Object {
+	definitions := make(map[string]runtime.Object)
+
+	if spoke.Namespace != nil {
+		definitions["Namespace"] = spoke.Namespace.Definition
+	}
+
+	if spoke.PullSecret != nil {
+		definitions["Secret"] = spoke.PullSecret.Definition
+	}
+
+	if spoke.ClusterDeployment != nil {
+		definitions["ClusterDeployment"] = spoke.ClusterDeployment.Definition
+	}
+
+	if spoke.AgentClusterInstall != nil {
+		definitions["AgentClusterInstall"] = spoke.AgentClusterInstall.Definition
+	}
+
+	if spoke.InfraEnv != nil {
+		definitions["InfraEnv"] = spoke.InfraEnv.Definition
+	}
+
+	return definitions
+}
+
+// definitionToUnstructured converts a definition to its unstructured content without the status
+// and server-populated metadata.
+func definitionToUnstructured(definition runtime.Object) (map[string]interface{}, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(definition)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(content, "status")
+
+	if metadata, ok := content["metadata"].(map[string]interface{}); ok {
+		for _, field := range serverPopulatedMetadata {
+			delete(metadata, field)
+		}
+	}
+
+	return content, nil
+}
+
+// flattenFields adds every leaf value of content to fields keyed by its dotted path under prefix.
+func flattenFields(prefix string, content interface{}, fields map[string]interface{}) {
+	switch typedContent := content.(type) {
+	case map[string]interface{}:
+		for key, value := range typedContent {
+			flattenFields(fmt.Sprintf("%s.%s", prefix, key), value, fields)
+		}
+	case []interface{}:
+		for index, value := range typedContent {
+			flattenFields(fmt.Sprintf("%s[%d]", prefix, index), value, fields)
+		}
+	default:
+		fields[prefix] = typedContent
+	}
+}
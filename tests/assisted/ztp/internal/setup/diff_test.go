@@ -0,0 +1,82 @@
+package setup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffSpokeConfigs(t *testing.T) {
+	testCases := []struct {
+		name       string
+		before     *SpokeClusterResources
+		after      *SpokeClusterResources
+		goldenFile string
+	}{
+		{
+			name:       "identical",
+			before:     StandardHAProfile(newTestClient(), "diff-spoke"),
+			after:      StandardHAProfile(newTestClient(), "diff-spoke"),
+			goldenFile: "diff/identical.txt",
+		},
+		{
+			name:       "worker count",
+			before:     StandardHAProfile(newTestClient(), "diff-spoke"),
+			after:      CompactProfile(newTestClient(), "diff-spoke"),
+			goldenFile: "diff/worker-count.txt",
+		},
+		{
+			name:       "network stack",
+			before:     StandardHAProfile(newTestClient(), "diff-spoke"),
+			after:      DualStackProfile(newTestClient(), "diff-spoke"),
+			goldenFile: "diff/network-stack.txt",
+		},
+		{
+			name:   "image set and network type",
+			before: StandardHAProfile(newTestClient(), "diff-spoke"),
+			after: func() *SpokeClusterResources {
+				spoke := StandardHAProfile(newTestClient(), "diff-spoke")
+				spoke.AgentClusterInstall.WithImageSet("4.17").WithNetworkType("OVNKubernetes")
+
+				return spoke
+			}(),
+			goldenFile: "diff/image-set-network-type.txt",
+		},
+		{
+			name:   "removed infraenv",
+			before: StandardHAProfile(newTestClient(), "diff-spoke"),
+			after: func() *SpokeClusterResources {
+				spoke := StandardHAProfile(newTestClient(), "diff-spoke")
+				spoke.InfraEnv = nil
+
+				return spoke
+			}(),
+			goldenFile: "diff/removed-infraenv.txt",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			diff, err := DiffSpokeConfigs(testCase.before, testCase.after)
+
+			assert.Nil(t, err)
+			assertGolden(t, testCase.goldenFile, []byte(diff.String()))
+		})
+	}
+}
+
+func TestDiffSpokeConfigsServerFields(t *testing.T) {
+	before := StandardHAProfile(newTestClient(), "diff-spoke")
+	after := StandardHAProfile(newTestClient(), "diff-spoke")
+	after.AgentClusterInstall.Definition.ResourceVersion = "12345"
+	after.AgentClusterInstall.Definition.UID = "4a4b3c6e-0000-0000-0000-000000000000"
+	after.AgentClusterInstall.Definition.Status.Progress.TotalPercentage = 50
+
+	diff, err := DiffSpokeConfigs(before, after)
+
+	assert.Nil(t, err)
+	assert.True(t, diff.Empty())
+
+	_, err = DiffSpokeConfigs(before, nil)
+	assert.EqualError(t, err, "cannot diff nil spoke cluster resources")
+}
@@ -0,0 +1,222 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-goinfra/pkg/assisted"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+)
+
+// expectedHost is a host expected to register an agent, identified by its hostname and, when known, MAC address.
+type expectedHost struct {
+	hostname   string
+	macAddress string
+}
+
+// agentDiscovery tracks the agents registering to the spoke infraenvs and correlates them with the expected hosts.
+type agentDiscovery struct {
+	start         time.Time
+	expectedCount int
+	expectedHosts []expectedHost
+	firstSeen     map[string]time.Time
+	hostAgents    map[string]string
+}
+
+// WaitForAgentsDiscovered waits up to timeout, or the spoke wait timeout when it is 0, until an agent registered for
+// each host of the agentclusterinstall. When the spoke hosts are known, as with static networking, each expected
+// host must be matched by MAC address or hostname to an agent. On timeout, the error names the hosts that never
+// registered and how long each discovered agent took to register.
+func (spoke *SpokeClusterResources) WaitForAgentsDiscovered(timeout time.Duration) error {
+	return spoke.WaitForAgentsDiscoveredWithContext(context.Background(), timeout)
+}
+
+// WaitForAgentsDiscoveredWithContext waits for an agent to register for each host like WaitForAgentsDiscovered,
+// stopping the wait when ctx is done.
+func (spoke *SpokeClusterResources) WaitForAgentsDiscoveredWithContext(
+	ctx context.Context, timeout time.Duration) error {
+	if spoke.AgentClusterInstall == nil || spoke.InfraEnv == nil {
+		return fmt.Errorf("agentclusterinstall and infraenv must be defined before waiting for agents")
+	}
+
+	requirements := spoke.AgentClusterInstall.Definition.Spec.ProvisionRequirements
+	discovery := newAgentDiscovery(
+		time.Now(), requirements.ControlPlaneAgents+requirements.WorkerAgents, spoke.expectedHosts)
+
+	options := spoke.resolveWaitOptions()
+
+	if timeout > 0 {
+		options.Timeout = timeout
+		options.Interval = min(options.Interval, timeout)
+	}
+
+	err := spoke.poll(ctx, "wait for agents discovered", options, func(ctx context.Context) (bool, error) {
+		agents, err := spoke.listAgents()
+		if err != nil {
+			glog.V(ztpparams.ZTPLogLevel).Infof("Failed to list agents of spoke %s: %v", spoke.Name, err)
+
+			return false, nil
+		}
+
+		discovery.observe(agents, time.Now())
+
+		return discovery.complete(), nil
+	})
+	if err != nil {
+		return fmt.Errorf("timed out waiting for agents of spoke %s: %s", spoke.Name, discovery.report())
+	}
+
+	return nil
+}
+
+// listAgents returns the agents registered to the spoke infraenv and additional infraenvs, carrying the agent labels
+// set using WithAgentLabels.
+func (spoke *SpokeClusterResources) listAgents() ([]*agentInstallV1Beta1.Agent, error) {
+	var agents []*agentInstallV1Beta1.Agent
+
+	for _, infraEnv := range append([]*assisted.InfraEnvBuilder{spoke.InfraEnv}, spoke.AdditionalInfraEnvs...) {
+		infraEnvAgents, err := infraEnv.GetAllAgents()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, agent := range infraEnvAgents {
+			if spoke.hasAgentLabels(agent.Object) {
+				agents = append(agents, agent.Object)
+			}
+		}
+	}
+
+	return agents, nil
+}
+
+// newAgentDiscovery returns an agentDiscovery started at start waiting for expectedCount agents, or for an agent
+// of each expected host when there are any.
+func newAgentDiscovery(start time.Time, expectedCount int, expectedHosts []expectedHost) *agentDiscovery {
+	if len(expectedHosts) > 0 {
+		expectedCount = len(expectedHosts)
+	}
+
+	return &agentDiscovery{
+		start:         start,
+		expectedCount: expectedCount,
+		expectedHosts: expectedHosts,
+		firstSeen:     map[string]time.Time{},
+		hostAgents:    map[string]string{},
+	}
+}
+
+// observe records the first time each agent was seen, using its creation time when set, and matches the agents
+// to the expected hosts.
+func (discovery *agentDiscovery) observe(agents []*agentInstallV1Beta1.Agent, now time.Time) {
+	for _, agent := range agents {
+		if _, found := discovery.firstSeen[agent.Name]; !found {
+			seen := now
+			if created := agent.CreationTimestamp.Time; !created.IsZero() && created.Before(now) {
+				seen = created
+			}
+
+			if seen.Before(discovery.start) {
+				seen = discovery.start
+			}
+
+			discovery.firstSeen[agent.Name] = seen
+		}
+
+		for _, host := range discovery.expectedHosts {
+			if _, found := discovery.hostAgents[host.hostname]; !found && host.matches(agent) {
+				discovery.hostAgents[host.hostname] = agent.Name
+			}
+		}
+	}
+}
+
+// complete returns true when every expected host, or the expected number of agents, has been discovered.
+func (discovery *agentDiscovery) complete() bool {
+	if len(discovery.expectedHosts) > 0 {
+		return len(discovery.hostAgents) == len(discovery.expectedHosts)
+	}
+
+	return len(discovery.firstSeen) >= discovery.expectedCount
+}
+
+// report describes the hosts that were not discovered and how long each discovered agent took to register.
+func (discovery *agentDiscovery) report() string {
+	var missing []string
+
+	for _, host := range discovery.expectedHosts {
+		if _, found := discovery.hostAgents[host.hostname]; !found {
+			missing = append(missing, host.String())
+		}
+	}
+
+	agentNames := make([]string, 0, len(discovery.firstSeen))
+	for name := range discovery.firstSeen {
+		agentNames = append(agentNames, name)
+	}
+
+	sort.Slice(agentNames, func(i, j int) bool {
+		return discovery.firstSeen[agentNames[i]].Before(discovery.firstSeen[agentNames[j]])
+	})
+
+	agentHosts := map[string]string{}
+	for hostname, agentName := range discovery.hostAgents {
+		agentHosts[agentName] = hostname
+	}
+
+	discovered := make([]string, 0, len(agentNames))
+
+	for _, name := range agentNames {
+		entry := fmt.Sprintf("%s after %s", name, discovery.firstSeen[name].Sub(discovery.start).Round(time.Second))
+		if hostname, found := agentHosts[name]; found {
+			entry = fmt.Sprintf("%s (%s)", entry, hostname)
+		}
+
+		discovered = append(discovered, entry)
+	}
+
+	report := fmt.Sprintf("%d of %d agents discovered", len(discovery.firstSeen), discovery.expectedCount)
+
+	if len(discovery.expectedHosts) > 0 {
+		report = fmt.Sprintf("%d of %d expected hosts discovered",
+			len(discovery.hostAgents), len(discovery.expectedHosts))
+	}
+
+	if len(missing) > 0 {
+		report = fmt.Sprintf("%s, missing hosts: %s", report, strings.Join(missing, ", "))
+	}
+
+	if len(discovered) > 0 {
+		report = fmt.Sprintf("%s; discovered agents: %s", report, strings.Join(discovered, ", "))
+	}
+
+	return report
+}
+
+// matches returns true when the agent reports the host MAC address on one of its interfaces or the host hostname.
+func (host expectedHost) matches(agent *agentInstallV1Beta1.Agent) bool {
+	if host.macAddress != "" {
+		for _, hostInterface := range agent.Status.Inventory.Interfaces {
+			if strings.EqualFold(hostInterface.MacAddress, host.macAddress) {
+				return true
+			}
+		}
+	}
+
+	return host.hostname != "" &&
+		(agent.Spec.Hostname == host.hostname || agent.Status.Inventory.Hostname == host.hostname)
+}
+
+// String returns the hostname of the host followed by its MAC address when known.
+func (host expectedHost) String() string {
+	if host.macAddress == "" {
+		return host.hostname
+	}
+
+	return fmt.Sprintf("%s (%s)", host.hostname, host.macAddress)
+}
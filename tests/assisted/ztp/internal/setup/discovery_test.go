@@ -0,0 +1,147 @@
+package setup
+
+import (
+	"testing"
+	"time"
+
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var testDiscoveryStart = time.Date(2024, 6, 3, 16, 0, 0, 0, time.UTC)
+
+func TestAgentDiscoveryReport(t *testing.T) {
+	expectedHosts := []expectedHost{
+		{hostname: "master-0", macAddress: "52:54:00:00:00:01"},
+		{hostname: "master-1", macAddress: "52:54:00:00:00:02"},
+		{hostname: "master-2", macAddress: "52:54:00:00:00:03"},
+		{hostname: "worker-0"},
+		{hostname: "worker-1", macAddress: "52:54:00:00:00:05"},
+	}
+
+	testCases := []struct {
+		name             string
+		expectedHosts    []expectedHost
+		agents           []*agentInstallV1Beta1.Agent
+		expectedComplete bool
+		expectedReport   string
+	}{
+		{
+			name:          "partial discovery by mac and hostname",
+			expectedHosts: expectedHosts,
+			agents: []*agentInstallV1Beta1.Agent{
+				buildDummyDiscoveredAgent("agent-b", "", "52:54:00:00:00:02", 90*time.Second),
+				buildDummyDiscoveredAgent("agent-a", "", "52:54:00:00:00:01", 30*time.Second),
+				buildDummyDiscoveredAgent("agent-c", "worker-0", "52:54:00:00:00:99", 2*time.Minute),
+				buildDummyDiscoveredAgent("agent-d", "", "52:54:00:00:00:05", 3*time.Minute),
+			},
+			expectedReport: "4 of 5 expected hosts discovered, missing hosts: master-2 (52:54:00:00:00:03); " +
+				"discovered agents: agent-a after 30s (master-0), agent-b after 1m30s (master-1), " +
+				"agent-c after 2m0s (worker-0), agent-d after 3m0s (worker-1)",
+		},
+		{
+			name:          "unexpected agent",
+			expectedHosts: expectedHosts[:1],
+			agents: []*agentInstallV1Beta1.Agent{
+				buildDummyDiscoveredAgent("agent-z", "other", "52:54:00:00:00:99", time.Minute),
+			},
+			expectedReport: "0 of 1 expected hosts discovered, missing hosts: master-0 (52:54:00:00:00:01); " +
+				"discovered agents: agent-z after 1m0s",
+		},
+		{
+			name:          "all expected hosts",
+			expectedHosts: expectedHosts[:1],
+			agents: []*agentInstallV1Beta1.Agent{
+				buildDummyDiscoveredAgent("agent-a", "", "52:54:00:00:00:01", time.Minute),
+			},
+			expectedComplete: true,
+			expectedReport:   "1 of 1 expected hosts discovered; discovered agents: agent-a after 1m0s (master-0)",
+		},
+		{
+			name: "count based",
+			agents: []*agentInstallV1Beta1.Agent{
+				buildDummyDiscoveredAgent("agent-a", "", "", 10*time.Second),
+				buildDummyDiscoveredAgent("agent-b", "", "", 20*time.Second),
+			},
+			expectedReport: "2 of 5 agents discovered; discovered agents: agent-a after 10s, agent-b after 20s",
+		},
+		{
+			name:           "nothing discovered",
+			expectedReport: "0 of 5 agents discovered",
+		},
+	}
+
+	for _, testCase := range testCases {
+		discovery := newAgentDiscovery(testDiscoveryStart, 5, testCase.expectedHosts)
+		discovery.observe(testCase.agents, testDiscoveryStart.Add(time.Hour))
+
+		assert.Equal(t, testCase.expectedComplete, discovery.complete(), testCase.name)
+		assert.Equal(t, testCase.expectedReport, discovery.report(), testCase.name)
+	}
+}
+
+func TestAgentDiscoveryFirstSeen(t *testing.T) {
+	discovery := newAgentDiscovery(testDiscoveryStart, 2, nil)
+
+	agent := buildDummyDiscoveredAgent("agent-a", "", "", 0)
+	agent.CreationTimestamp = metav1.Time{}
+	discovery.observe([]*agentInstallV1Beta1.Agent{agent}, testDiscoveryStart.Add(time.Minute))
+	discovery.observe([]*agentInstallV1Beta1.Agent{agent}, testDiscoveryStart.Add(2*time.Minute))
+
+	existingAgent := buildDummyDiscoveredAgent("agent-b", "", "", -time.Hour)
+	discovery.observe([]*agentInstallV1Beta1.Agent{existingAgent}, testDiscoveryStart.Add(3*time.Minute))
+
+	assert.Equal(t, testDiscoveryStart.Add(time.Minute), discovery.firstSeen["agent-a"])
+	assert.Equal(t, testDiscoveryStart, discovery.firstSeen["agent-b"])
+	assert.True(t, discovery.complete())
+}
+
+func TestWaitForAgentsDiscovered(t *testing.T) {
+	hosts := []StaticHostConfig{
+		buildDummyStaticHost("192.168.254.10/24", "192.168.254.1"),
+		buildDummyStaticHost("192.168.254.11/24", "192.168.254.1"),
+	}
+	hosts[1].Hostname = "spoke-host-1"
+	hosts[1].MACAddress = "52:54:00:aa:bb:01"
+
+	apiClient := newTestClient(
+		buildDummyInfraEnvObject("spoke"),
+		buildDummyDiscoveredAgent("agent-0", hosts[0].Hostname, "", 0),
+	)
+
+	spoke := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultIPv4AgentClusterInstall().WithDefaultInfraEnv().
+		WithMinimalISOStaticNetworking(hosts)
+	assert.Nil(t, spoke.err)
+
+	err := spoke.WaitForAgentsDiscovered(10 * time.Millisecond)
+	assert.ErrorContains(t, err, "1 of 2 expected hosts discovered, missing hosts: spoke-host-1 (52:54:00:aa:bb:01)")
+	assert.ErrorContains(t, err, "agent-0 after")
+
+	spoke = NewSpokeCluster(apiClient).WithName("spoke").WithDefaultIPv4AgentClusterInstall().WithDefaultInfraEnv()
+	spoke.AgentClusterInstall.WithControlPlaneAgents(1).WithWorkerAgents(0)
+	assert.Nil(t, spoke.WaitForAgentsDiscovered(time.Second))
+
+	err = NewSpokeCluster(apiClient).WithName("spoke").WaitForAgentsDiscovered(time.Second)
+	assert.EqualError(t, err, "agentclusterinstall and infraenv must be defined before waiting for agents")
+}
+
+func buildDummyDiscoveredAgent(name, hostname, macAddress string, after time.Duration) *agentInstallV1Beta1.Agent {
+	agent := &agentInstallV1Beta1.Agent{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "spoke",
+			Labels:            map[string]string{"infraenvs.agent-install.openshift.io": "spoke"},
+			CreationTimestamp: metav1.NewTime(testDiscoveryStart.Add(after)),
+		},
+		Status: agentInstallV1Beta1.AgentStatus{
+			Inventory: agentInstallV1Beta1.HostInventory{Hostname: hostname},
+		},
+	}
+
+	if macAddress != "" {
+		agent.Status.Inventory.Interfaces = []agentInstallV1Beta1.HostInterface{{MacAddress: macAddress}}
+	}
+
+	return agent
+}
@@ -0,0 +1,153 @@
+package setup
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/models"
+)
+
+const (
+	// DiskEncryptionEnableOnNone disables disk encryption.
+	DiskEncryptionEnableOnNone = "none"
+	// DiskEncryptionEnableOnAll encrypts the disks of every node.
+	DiskEncryptionEnableOnAll = "all"
+	// DiskEncryptionEnableOnMasters encrypts the disks of the control-plane nodes.
+	DiskEncryptionEnableOnMasters = "masters"
+	// DiskEncryptionEnableOnWorkers encrypts the disks of the worker nodes.
+	DiskEncryptionEnableOnWorkers = "workers"
+
+	// DiskEncryptionModeTPMv2 binds the disk encryption key to the TPM 2.0 of each node.
+	DiskEncryptionModeTPMv2 = "tpmv2"
+	// DiskEncryptionModeTang binds the disk encryption key to tang servers.
+	DiskEncryptionModeTang = "tang"
+)
+
+// WithDiskEncryption sets the disk encryption of the spoke agentclusterinstall. enableOn selects the nodes whose
+// disks are encrypted and mode how the key is bound; the tang mode requires at least one tang server, each with a
+// URL and thumbprint, as returned by DeployTangServer. It can be called before or after the agentclusterinstall is
+// defined, since the settings are applied at Create time. Calling it again with different settings is an error.
+func (spoke *SpokeClusterResources) WithDiskEncryption(
+	enableOn, mode string, tangServers []TangInfo) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	diskEncryption, err := newDiskEncryption(enableOn, mode, tangServers)
+	if err != nil {
+		spoke.err = fmt.Errorf("WithDiskEncryption: %w", err)
+
+		return spoke
+	}
+
+	if spoke.diskEncryption != nil && !reflect.DeepEqual(spoke.diskEncryption, diskEncryption) {
+		spoke.err = fmt.Errorf("WithDiskEncryption: disk encryption is already enabled on %s with mode %s",
+			*spoke.diskEncryption.EnableOn, *spoke.diskEncryption.Mode)
+
+		return spoke
+	}
+
+	spoke.diskEncryption = diskEncryption
+	spoke.applyDiskEncryption()
+
+	return spoke
+}
+
+// WithDiskEncryptionForRoles sets the disk encryption of the spoke agentclusterinstall like WithDiskEncryption,
+// selecting the nodes whose disks are encrypted by their roles, master, worker or both.
+func (spoke *SpokeClusterResources) WithDiskEncryptionForRoles(
+	mode string, roles []string, tangServers []TangInfo) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	var masters, workers bool
+
+	for _, role := range roles {
+		switch role {
+		case string(models.HostRoleMaster):
+			masters = true
+		case string(models.HostRoleWorker):
+			workers = true
+		default:
+			spoke.err = fmt.Errorf("WithDiskEncryptionForRoles: invalid disk encryption role %q, must be %s or %s",
+				role, models.HostRoleMaster, models.HostRoleWorker)
+
+			return spoke
+		}
+	}
+
+	switch {
+	case masters && workers:
+		return spoke.WithDiskEncryption(DiskEncryptionEnableOnAll, mode, tangServers)
+	case masters:
+		return spoke.WithDiskEncryption(DiskEncryptionEnableOnMasters, mode, tangServers)
+	case workers:
+		return spoke.WithDiskEncryption(DiskEncryptionEnableOnWorkers, mode, tangServers)
+	default:
+		spoke.err = fmt.Errorf("WithDiskEncryptionForRoles: disk encryption requires at least one role")
+
+		return spoke
+	}
+}
+
+// applyDiskEncryption sets the disk encryption on the spoke agentclusterinstall when both are defined.
+func (spoke *SpokeClusterResources) applyDiskEncryption() {
+	if spoke.diskEncryption == nil || spoke.AgentClusterInstall == nil {
+		return
+	}
+
+	spoke.AgentClusterInstall.Definition.Spec.DiskEncryption = spoke.diskEncryption.DeepCopy()
+}
+
+// newDiskEncryption returns the agentclusterinstall disk encryption for the provided settings, or an error when
+// they are invalid.
+func newDiskEncryption(enableOn, mode string, tangServers []TangInfo) (*v1beta1.DiskEncryption, error) {
+	enableOnValues := []string{
+		DiskEncryptionEnableOnNone, DiskEncryptionEnableOnAll, DiskEncryptionEnableOnMasters,
+		DiskEncryptionEnableOnWorkers,
+	}
+
+	if !slices.Contains(enableOnValues, enableOn) {
+		return nil, fmt.Errorf("invalid disk encryption enableOn %q, must be one of %v", enableOn, enableOnValues)
+	}
+
+	diskEncryption := &v1beta1.DiskEncryption{EnableOn: &enableOn, Mode: &mode}
+
+	switch mode {
+	case DiskEncryptionModeTPMv2:
+		if len(tangServers) > 0 {
+			return nil, fmt.Errorf("tang servers can only be set with disk encryption mode %s", DiskEncryptionModeTang)
+		}
+	case DiskEncryptionModeTang:
+		if len(tangServers) == 0 {
+			return nil, fmt.Errorf("disk encryption mode %s requires at least one tang server", DiskEncryptionModeTang)
+		}
+
+		for index, tangServer := range tangServers {
+			if tangServer.URL == "" || tangServer.Thumbprint == "" {
+				return nil, fmt.Errorf("tang server %d requires both a URL and a thumbprint", index)
+			}
+
+			if parsedURL, err := url.Parse(tangServer.URL); err != nil || parsedURL.Host == "" {
+				return nil, fmt.Errorf("invalid tang server URL %q", tangServer.URL)
+			}
+		}
+
+		encodedServers, err := json.Marshal(tangServers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode tang servers: %w", err)
+		}
+
+		diskEncryption.TangServers = string(encodedServers)
+	default:
+		return nil, fmt.Errorf("invalid disk encryption mode %q, must be one of %v",
+			mode, []string{DiskEncryptionModeTPMv2, DiskEncryptionModeTang})
+	}
+
+	return diskEncryption, nil
+}
@@ -0,0 +1,143 @@
+package setup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithDiskEncryption(t *testing.T) {
+	tangServers := []TangInfo{
+		{URL: "http://tang1.example.com:7500", Thumbprint: "thumbprint1"},
+		{URL: "http://tang2.example.com:7500", Thumbprint: "thumbprint2"},
+	}
+
+	testCases := []struct {
+		enableOn            string
+		mode                string
+		tangServers         []TangInfo
+		expectedTangServers string
+		expectedErr         string
+	}{
+		{enableOn: DiskEncryptionEnableOnAll, mode: DiskEncryptionModeTPMv2},
+		{enableOn: DiskEncryptionEnableOnNone, mode: DiskEncryptionModeTPMv2},
+		{
+			enableOn:    DiskEncryptionEnableOnMasters,
+			mode:        DiskEncryptionModeTang,
+			tangServers: tangServers,
+			expectedTangServers: `[{"URL":"http://tang1.example.com:7500","Thumbprint":"thumbprint1"},` +
+				`{"URL":"http://tang2.example.com:7500","Thumbprint":"thumbprint2"}]`,
+		},
+		{
+			enableOn: "controlplane",
+			mode:     DiskEncryptionModeTPMv2,
+			expectedErr: `WithDiskEncryption: invalid disk encryption enableOn "controlplane", ` +
+				"must be one of [none all masters workers]",
+		},
+		{
+			enableOn:    DiskEncryptionEnableOnAll,
+			mode:        "luks",
+			expectedErr: `WithDiskEncryption: invalid disk encryption mode "luks", must be one of [tpmv2 tang]`,
+		},
+		{
+			enableOn:    DiskEncryptionEnableOnWorkers,
+			mode:        DiskEncryptionModeTang,
+			expectedErr: "WithDiskEncryption: disk encryption mode tang requires at least one tang server",
+		},
+		{
+			enableOn:    DiskEncryptionEnableOnWorkers,
+			mode:        DiskEncryptionModeTang,
+			tangServers: []TangInfo{{URL: "http://tang.example.com"}},
+			expectedErr: "WithDiskEncryption: tang server 0 requires both a URL and a thumbprint",
+		},
+		{
+			enableOn:    DiskEncryptionEnableOnWorkers,
+			mode:        DiskEncryptionModeTang,
+			tangServers: []TangInfo{{URL: "tang.example.com", Thumbprint: "thumbprint"}},
+			expectedErr: `WithDiskEncryption: invalid tang server URL "tang.example.com"`,
+		},
+		{
+			enableOn:    DiskEncryptionEnableOnAll,
+			mode:        DiskEncryptionModeTPMv2,
+			tangServers: tangServers,
+			expectedErr: "WithDiskEncryption: tang servers can only be set with disk encryption mode tang",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultIPv4AgentClusterInstall().
+			WithDiskEncryption(testCase.enableOn, testCase.mode, testCase.tangServers)
+
+		if testCase.expectedErr != "" {
+			assert.EqualError(t, spoke.err, testCase.expectedErr)
+
+			continue
+		}
+
+		assert.Nil(t, spoke.err)
+
+		diskEncryption := spoke.AgentClusterInstall.Definition.Spec.DiskEncryption
+		assert.Equal(t, testCase.enableOn, *diskEncryption.EnableOn)
+		assert.Equal(t, testCase.mode, *diskEncryption.Mode)
+		assert.Equal(t, testCase.expectedTangServers, diskEncryption.TangServers)
+	}
+}
+
+func TestWithDiskEncryptionForRoles(t *testing.T) {
+	tangServers := []TangInfo{{URL: "http://tang1.example.com:7500", Thumbprint: "thumbprint1"}}
+
+	testCases := []struct {
+		roles            []string
+		expectedEnableOn string
+		expectedErr      string
+	}{
+		{roles: []string{"master", "worker"}, expectedEnableOn: DiskEncryptionEnableOnAll},
+		{roles: []string{"master"}, expectedEnableOn: DiskEncryptionEnableOnMasters},
+		{roles: []string{"worker", "worker"}, expectedEnableOn: DiskEncryptionEnableOnWorkers},
+		{expectedErr: "WithDiskEncryptionForRoles: disk encryption requires at least one role"},
+		{
+			roles: []string{"bootstrap"},
+			expectedErr: `WithDiskEncryptionForRoles: invalid disk encryption role "bootstrap", ` +
+				"must be master or worker",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultIPv4AgentClusterInstall().
+			WithDiskEncryptionForRoles(DiskEncryptionModeTang, testCase.roles, tangServers)
+
+		if testCase.expectedErr != "" {
+			assert.EqualError(t, spoke.err, testCase.expectedErr)
+
+			continue
+		}
+
+		assert.Nil(t, spoke.err)
+
+		diskEncryption := spoke.AgentClusterInstall.Definition.Spec.DiskEncryption
+		assert.Equal(t, testCase.expectedEnableOn, *diskEncryption.EnableOn)
+		assert.Equal(t, DiskEncryptionModeTang, *diskEncryption.Mode)
+	}
+}
+
+func TestWithDiskEncryptionOrdering(t *testing.T) {
+	spoke := NewSpokeCluster(newHubTestClient()).WithName("spoke").WithDefaultNamespace().
+		WithDiskEncryption(DiskEncryptionEnableOnAll, DiskEncryptionModeTPMv2, nil).
+		WithDiskEncryption(DiskEncryptionEnableOnAll, DiskEncryptionModeTPMv2, nil).
+		WithDefaultIPv4AgentClusterInstall()
+	assert.Nil(t, spoke.err)
+
+	_, err := spoke.Create()
+	assert.Nil(t, err)
+	assert.Equal(t, DiskEncryptionModeTPMv2, *spoke.AgentClusterInstall.Object.Spec.DiskEncryption.Mode)
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").
+		WithDiskEncryption(DiskEncryptionEnableOnAll, DiskEncryptionModeTPMv2, nil).
+		WithDiskEncryption(DiskEncryptionEnableOnWorkers, DiskEncryptionModeTPMv2, nil)
+	assert.EqualError(t, spoke.err,
+		"WithDiskEncryption: disk encryption is already enabled on all with mode tpmv2")
+
+	_, err = NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultNamespace().
+		WithDiskEncryption(DiskEncryptionEnableOnAll, DiskEncryptionModeTPMv2, nil).Create()
+	assert.EqualError(t, err, "disk encryption requires an agentclusterinstall")
+}
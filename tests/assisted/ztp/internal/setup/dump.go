@@ -0,0 +1,200 @@
+package setup
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	runtimeClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+const (
+	// redactedSecretValue replaces the values of the secret data keys when secrets are not revealed.
+	redactedSecretValue = "REDACTED"
+	// redactedDockerConfigJSON replaces the docker config of pull-secrets when secrets are not revealed. It stays
+	// valid JSON so that the dumped pull-secret can still be applied.
+	redactedDockerConfigJSON = `{"auths":{}}`
+)
+
+// DumpOptions configures how DumpYAML and DumpToWriter serialize the spoke resources. A nil *DumpOptions dumps
+// re-applyable resources without status and with the secret data redacted.
+type DumpOptions struct {
+	// IncludeStatus keeps the status of the resources fetched from the hub, for post-mortem captures.
+	IncludeStatus bool
+	// RevealSecrets keeps the secret data, including the docker config of the pull-secrets, instead of
+	// redacting it.
+	RevealSecrets bool
+}
+
+// dumpedResource is a spoke resource serialized to YAML.
+type dumpedResource struct {
+	kind    string
+	name    string
+	content []byte
+}
+
+// DumpYAML writes every instantiated spoke resource to its own YAML file in dir, creating dir when it does not
+// exist. Resources existing on the hub are fetched, the others are dumped from their definition. Files are
+// prefixed by their position so that oc apply -f dir creates the namespaces before the resources they contain.
+func (spoke *SpokeClusterResources) DumpYAML(dir string, options *DumpOptions) error {
+	resources, err := spoke.dumpResources(options)
+	if err != nil {
+		return err
+	}
+
+	return writeResourcesToDir(dir, resources)
+}
+
+// writeResourcesToDir writes each resource to its own YAML file in dir, creating dir when it does not exist.
+func writeResourcesToDir(dir string, resources []dumpedResource) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create dump directory %s: %w", dir, err)
+	}
+
+	for index, resource := range resources {
+		fileName := fmt.Sprintf("%02d-%s-%s.yaml", index, strings.ToLower(resource.kind), resource.name)
+
+		if err := os.WriteFile(filepath.Join(dir, fileName), resource.content, 0o600); err != nil {
+			return fmt.Errorf("failed to write %s %s to %s: %w", resource.kind, resource.name, dir, err)
+		}
+	}
+
+	return nil
+}
+
+// DumpToWriter writes every instantiated spoke resource to writer as a multi-document YAML stream, in the same
+// order and with the same content as DumpYAML.
+func (spoke *SpokeClusterResources) DumpToWriter(writer io.Writer, options *DumpOptions) error {
+	if writer == nil {
+		return fmt.Errorf("writer cannot be nil")
+	}
+
+	resources, err := spoke.dumpResources(options)
+	if err != nil {
+		return err
+	}
+
+	return writeResources(writer, resources)
+}
+
+// writeResources writes the resources to writer as a multi-document YAML stream.
+func writeResources(writer io.Writer, resources []dumpedResource) error {
+	for _, resource := range resources {
+		if _, err := fmt.Fprintf(writer, "---\n%s", resource.content); err != nil {
+			return fmt.Errorf("failed to write %s %s: %w", resource.kind, resource.name, err)
+		}
+	}
+
+	return nil
+}
+
+// dumpResources serializes the instantiated spoke resources in creation order.
+func (spoke *SpokeClusterResources) dumpResources(options *DumpOptions) ([]dumpedResource, error) {
+	if spoke.err != nil {
+		return nil, spoke.err
+	}
+
+	if spoke.apiClient == nil {
+		return nil, fmt.Errorf("apiClient cannot be nil")
+	}
+
+	if options == nil {
+		options = &DumpOptions{}
+	}
+
+	var resources []dumpedResource
+
+	for _, definition := range spoke.definitions() {
+		object, ok := definition.DeepCopyObject().(runtimeClient.Object)
+		if !ok {
+			return nil, fmt.Errorf("failed to copy %s", definition.GetName())
+		}
+
+		err := spoke.apiClient.Get(context.TODO(), runtimeClient.ObjectKeyFromObject(definition), object)
+		if k8serrors.IsNotFound(err) {
+			object, _ = definition.DeepCopyObject().(runtimeClient.Object)
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to get %s: %w", definition.GetName(), err)
+		}
+
+		resource, err := spoke.dumpResource(object, options)
+		if err != nil {
+			return nil, err
+		}
+
+		resources = append(resources, resource)
+	}
+
+	return resources, nil
+}
+
+// dumpResource serializes object to YAML without its server-populated metadata, dropping its status unless
+// included by options and redacting the data of secrets unless revealed by options.
+func (spoke *SpokeClusterResources) dumpResource(
+	object runtimeClient.Object, options *DumpOptions) (dumpedResource, error) {
+	gvk, err := apiutil.GVKForObject(object, spoke.apiClient.Scheme())
+	if err != nil {
+		return dumpedResource{}, fmt.Errorf("failed to get kind of %s: %w", object.GetName(), err)
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+	if err != nil {
+		return dumpedResource{}, fmt.Errorf("failed to convert %s %s: %w", gvk.Kind, object.GetName(), err)
+	}
+
+	content["apiVersion"], content["kind"] = gvk.GroupVersion().String(), gvk.Kind
+
+	if metadata, ok := content["metadata"].(map[string]interface{}); ok {
+		for _, field := range serverPopulatedMetadata {
+			delete(metadata, field)
+		}
+	}
+
+	if !options.IncludeStatus {
+		delete(content, "status")
+	}
+
+	if gvk.Kind == "Secret" && !options.RevealSecrets {
+		redactSecretData(content)
+	}
+
+	yamlContent, err := yaml.Marshal(content)
+	if err != nil {
+		return dumpedResource{}, fmt.Errorf("failed to marshal %s %s: %w", gvk.Kind, object.GetName(), err)
+	}
+
+	return dumpedResource{kind: gvk.Kind, name: object.GetName(), content: yamlContent}, nil
+}
+
+// redactSecretData replaces the values of the data and stringData keys of the unstructured secret content. The
+// docker config of pull-secrets is replaced by an empty one so that the secret stays valid.
+func redactSecretData(content map[string]interface{}) {
+	for _, field := range []string{"data", "stringData"} {
+		data, ok := content[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		for key := range data {
+			value := redactedSecretValue
+			if key == corev1.DockerConfigJsonKey {
+				value = redactedDockerConfigJSON
+			}
+
+			if field == "data" {
+				value = base64.StdEncoding.EncodeToString([]byte(value))
+			}
+
+			data[key] = value
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package setup
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestDumpYAML(t *testing.T) {
+	spoke := NewSpokeCluster(newHubTestClient()).WithName("spoke").WithDefaultNamespace().
+		WithPullSecretData(map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{"hub":{}}}`)}).
+		WithDefaultClusterDeployment().WithDefaultIPv4AgentClusterInstall().WithDefaultInfraEnv()
+	assert.Nil(t, spoke.err)
+
+	_, err := spoke.Create()
+	assert.Nil(t, err)
+
+	dir := filepath.Join(t.TempDir(), "dump")
+
+	err = spoke.DumpYAML(dir, nil)
+	assert.Nil(t, err)
+
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+
+	var fileNames []string
+	for _, entry := range entries {
+		fileNames = append(fileNames, entry.Name())
+	}
+
+	assert.Equal(t, []string{
+		"00-namespace-spoke.yaml",
+		"01-secret-spoke-pull-secret.yaml",
+		"02-clusterdeployment-spoke.yaml",
+		"03-agentclusterinstall-spoke.yaml",
+		"04-infraenv-spoke.yaml",
+	}, fileNames)
+
+	secret := readDumpedResource(t, filepath.Join(dir, "01-secret-spoke-pull-secret.yaml"))
+	assert.Equal(t, "v1", secret["apiVersion"])
+	assert.Equal(t, "Secret", secret["kind"])
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte(redactedDockerConfigJSON)),
+		secret["data"].(map[interface{}]interface{})[corev1.DockerConfigJsonKey])
+
+	clusterDeployment := readDumpedResource(t, filepath.Join(dir, "02-clusterdeployment-spoke.yaml"))
+	assert.Equal(t, "hive.openshift.io/v1", clusterDeployment["apiVersion"])
+	assert.NotContains(t, clusterDeployment, "status")
+
+	metadata := clusterDeployment["metadata"].(map[interface{}]interface{})
+	for _, field := range serverPopulatedMetadata {
+		assert.NotContains(t, metadata, field)
+	}
+
+	assert.Equal(t, "spoke", metadata["labels"].(map[interface{}]interface{})[SpokeOwnershipLabel])
+}
+
+func TestDumpToWriter(t *testing.T) {
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultNamespace().
+		WithPullSecretData(map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{"hub":{}}}`)})
+	assert.Nil(t, spoke.err)
+
+	spoke.Namespace.Definition.Status.Phase = corev1.NamespaceActive
+
+	var writer bytes.Buffer
+
+	err := spoke.DumpToWriter(&writer, nil)
+	assert.Nil(t, err)
+
+	documents := strings.Split(strings.TrimPrefix(writer.String(), "---\n"), "---\n")
+	assert.Len(t, documents, 2)
+	assert.NotContains(t, documents[0], "status")
+	assert.NotContains(t, documents[1], base64.StdEncoding.EncodeToString([]byte(`{"auths":{"hub":{}}}`)))
+
+	writer.Reset()
+
+	err = spoke.DumpToWriter(&writer, &DumpOptions{IncludeStatus: true, RevealSecrets: true})
+	assert.Nil(t, err)
+	assert.Contains(t, writer.String(), "phase: Active")
+	assert.Contains(t, writer.String(), base64.StdEncoding.EncodeToString([]byte(`{"auths":{"hub":{}}}`)))
+}
+
+func TestDumpErrors(t *testing.T) {
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultNamespace()
+
+	err := spoke.DumpToWriter(nil, nil)
+	assert.EqualError(t, err, "writer cannot be nil")
+
+	err = NewSpokeCluster(nil).WithName("spoke").DumpToWriter(&bytes.Buffer{}, nil)
+	assert.EqualError(t, err, "apiClient cannot be nil")
+
+	spoke.err = fmt.Errorf("WithName: spoke name cannot be empty")
+
+	err = spoke.DumpYAML(t.TempDir(), nil)
+	assert.EqualError(t, err, "WithName: spoke name cannot be empty")
+}
+
+func TestRedactSecretData(t *testing.T) {
+	content := map[string]interface{}{
+		"data":       map[string]interface{}{corev1.DockerConfigJsonKey: "e30=", "password": "cGFzc3dvcmQ="},
+		"stringData": map[string]interface{}{"username": "admin"},
+	}
+
+	redactSecretData(content)
+
+	assert.Equal(t, map[string]interface{}{
+		"data": map[string]interface{}{
+			corev1.DockerConfigJsonKey: base64.StdEncoding.EncodeToString([]byte(redactedDockerConfigJSON)),
+			"password":                 base64.StdEncoding.EncodeToString([]byte(redactedSecretValue)),
+		},
+		"stringData": map[string]interface{}{"username": redactedSecretValue},
+	}, content)
+}
+
+// readDumpedResource returns the content of the dumped YAML file at path.
+func readDumpedResource(t *testing.T, path string) map[string]interface{} {
+	t.Helper()
+
+	fileContent, err := os.ReadFile(path)
+	assert.Nil(t, err)
+
+	content := make(map[string]interface{})
+
+	err = yaml.Unmarshal(fileContent, &content)
+	assert.Nil(t, err)
+
+	return content
+}
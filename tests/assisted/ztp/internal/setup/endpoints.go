@@ -0,0 +1,118 @@
+package setup
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Endpoint contains the parts of a hub URL needed to reach it or exclude it from proxying. Host never contains
+// the brackets surrounding IPv6 literals.
+type Endpoint struct {
+	Scheme string
+	Host   string
+	Port   string
+	IPv6   bool
+}
+
+// ParseEndpoint parses a URL such as an ISO, events or logs URL published by the hub, handling bracketed IPv6
+// literals. When the URL has no explicit port, the default port of the http and https schemes is used.
+func ParseEndpoint(rawURL string) (Endpoint, error) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return Endpoint{}, fmt.Errorf("failed to parse url %q: %w", rawURL, err)
+	}
+
+	host := parsedURL.Hostname()
+	if host == "" {
+		return Endpoint{}, fmt.Errorf("url %q does not contain a host", rawURL)
+	}
+
+	endpoint := Endpoint{
+		Scheme: parsedURL.Scheme,
+		Host:   host,
+		Port:   parsedURL.Port(),
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		endpoint.IPv6 = ip.To4() == nil
+	}
+
+	if endpoint.Port == "" {
+		switch endpoint.Scheme {
+		case "http":
+			endpoint.Port = "80"
+		case "https":
+			endpoint.Port = "443"
+		}
+	}
+
+	return endpoint, nil
+}
+
+// HostPort returns the endpoint host and port joined for dialing, bracketing IPv6 literals.
+func (endpoint Endpoint) HostPort() string {
+	return net.JoinHostPort(endpoint.Host, endpoint.Port)
+}
+
+// BuildNoProxy assembles a noProxy value from hostnames, domains, IP addresses and CIDRs. IPv6 entries may be
+// bracketed and are written unbracketed in canonical form, and duplicates are removed while keeping the order of
+// first appearance. In ipv6Only mode, IPv4 addresses and networks are dropped since they cannot be reached from an
+// IPv6-only hub.
+func BuildNoProxy(ipv6Only bool, entries ...string) (string, error) {
+	var noProxy []string
+
+	seen := make(map[string]bool)
+
+	for _, entry := range entries {
+		for _, item := range strings.Split(entry, ",") {
+			normalized, isIPv4, err := normalizeNoProxyEntry(item)
+			if err != nil {
+				return "", err
+			}
+
+			if normalized == "" || seen[normalized] || (ipv6Only && isIPv4) {
+				continue
+			}
+
+			seen[normalized] = true
+
+			noProxy = append(noProxy, normalized)
+		}
+	}
+
+	return strings.Join(noProxy, ","), nil
+}
+
+// normalizeNoProxyEntry returns the canonical form of a single noProxy entry and whether it is an IPv4 address
+// or network.
+func normalizeNoProxyEntry(entry string) (string, bool, error) {
+	entry = strings.TrimSpace(entry)
+	if entry == "" {
+		return "", false, nil
+	}
+
+	if strings.HasPrefix(entry, "[") {
+		closing := strings.Index(entry, "]")
+		if closing == -1 {
+			return "", false, fmt.Errorf("noProxy entry %q has an unterminated IPv6 literal", entry)
+		}
+
+		entry = entry[1:closing] + entry[closing+1:]
+	}
+
+	if ip, network, err := net.ParseCIDR(entry); err == nil {
+		return network.String(), ip.To4() != nil, nil
+	}
+
+	if ip := net.ParseIP(entry); ip != nil {
+		return ip.String(), ip.To4() != nil, nil
+	}
+
+	if strings.ContainsAny(entry, "[]/") || strings.Count(entry, ":") > 1 {
+		return "", false, fmt.Errorf("noProxy entry %q is not a valid host, domain, address or network", entry)
+	}
+
+	return strings.ToLower(entry), false, nil
+}
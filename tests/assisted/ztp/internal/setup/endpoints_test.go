@@ -0,0 +1,91 @@
+package setup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	testCases := []struct {
+		url              string
+		expectedEndpoint Endpoint
+		expectedHostPort string
+		expectedErr      bool
+	}{
+		{
+			url:              "https://[fd2e:6f44:5dd8:c956::16]:8443/images/abc?arch=x86_64&type=minimal-iso",
+			expectedEndpoint: Endpoint{Scheme: "https", Host: "fd2e:6f44:5dd8:c956::16", Port: "8443", IPv6: true},
+			expectedHostPort: "[fd2e:6f44:5dd8:c956::16]:8443",
+		},
+		{
+			url:              "http://[fd2e:6f44:5dd8:c956::16]/api/assisted-install/v2/events",
+			expectedEndpoint: Endpoint{Scheme: "http", Host: "fd2e:6f44:5dd8:c956::16", Port: "80", IPv6: true},
+			expectedHostPort: "[fd2e:6f44:5dd8:c956::16]:80",
+		},
+		{
+			url:              "https://192.168.254.12/images/abc",
+			expectedEndpoint: Endpoint{Scheme: "https", Host: "192.168.254.12", Port: "443"},
+			expectedHostPort: "192.168.254.12:443",
+		},
+		{
+			url:              "https://assisted-image-service.apps.hub.example.com/images",
+			expectedEndpoint: Endpoint{Scheme: "https", Host: "assisted-image-service.apps.hub.example.com", Port: "443"},
+			expectedHostPort: "assisted-image-service.apps.hub.example.com:443",
+		},
+		{url: "https://[fd2e:6f44:5dd8:c956::16/images", expectedErr: true},
+		{url: "/images/abc", expectedErr: true},
+	}
+
+	for _, testCase := range testCases {
+		endpoint, err := ParseEndpoint(testCase.url)
+
+		if testCase.expectedErr {
+			assert.NotNil(t, err, testCase.url)
+
+			continue
+		}
+
+		assert.Nil(t, err)
+		assert.Equal(t, testCase.expectedEndpoint, endpoint)
+		assert.Equal(t, testCase.expectedHostPort, endpoint.HostPort())
+	}
+}
+
+func TestBuildNoProxy(t *testing.T) {
+	testCases := []struct {
+		ipv6Only    bool
+		entries     []string
+		expected    string
+		expectedErr bool
+	}{
+		{
+			entries:  []string{".cluster.local,.svc", "192.168.254.0/24", "10.128.0.0/14", ".svc"},
+			expected: ".cluster.local,.svc,192.168.254.0/24,10.128.0.0/14",
+		},
+		{
+			entries:  []string{"[fd2e:6f44:5dd8:c956::16]", "fd2e:6f44:5dd8:c956:0:0:0:16", "FD01::/48", "[fd02::]/112"},
+			expected: "fd2e:6f44:5dd8:c956::16,fd01::/48,fd02::/112",
+		},
+		{
+			ipv6Only: true,
+			entries:  []string{"hub.example.com", "192.168.254.0/24", "192.168.254.5", "fd2e:6f44:5dd8:1::/64"},
+			expected: "hub.example.com,fd2e:6f44:5dd8:1::/64",
+		},
+		{entries: []string{"[fd2e:6f44:5dd8:c956::16"}, expectedErr: true},
+		{entries: []string{"fd2e:6f44:zz::/64"}, expectedErr: true},
+	}
+
+	for _, testCase := range testCases {
+		noProxy, err := BuildNoProxy(testCase.ipv6Only, testCase.entries...)
+
+		if testCase.expectedErr {
+			assert.NotNil(t, err)
+
+			continue
+		}
+
+		assert.Nil(t, err)
+		assert.Equal(t, testCase.expected, noProxy)
+	}
+}
@@ -0,0 +1,338 @@
+package setup
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-goinfra/pkg/configmap"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// extraManifestFile is a manifest file read from an extra manifests filesystem.
+type extraManifestFile struct {
+	key     string
+	content string
+}
+
+// WithExtraManifestsFromDir adds every YAML manifest found under dir, including its subdirectories, to the spoke
+// extra manifests. See WithExtraManifestsFromFS for how the manifests are validated and stored.
+func (spoke *SpokeClusterResources) WithExtraManifestsFromDir(dir string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if dir == "" {
+		spoke.err = fmt.Errorf("WithExtraManifestsFromDir: extra manifests directory cannot be empty")
+
+		return spoke
+	}
+
+	return spoke.WithExtraManifestsFromFS(os.DirFS(dir), ".")
+}
+
+// WithExtraManifestsFromFS adds every YAML manifest found under root in fsys, including its subdirectories, to the
+// spoke extra manifests, such as manifests compiled into the test binary with go:embed. Manifests are stored under
+// their path relative to root with slashes replaced by underscores, split across as many configmaps as needed to
+// stay below the configmap size limit. Root must be a relative path inside fsys. Files without a yaml or yml
+// extension and manifests without apiVersion and kind fail the spoke unless WithSkipInvalidExtraManifests is set.
+func (spoke *SpokeClusterResources) WithExtraManifestsFromFS(fsys fs.FS, root string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if fsys == nil {
+		spoke.err = fmt.Errorf("WithExtraManifestsFromFS: extra manifests filesystem cannot be nil")
+
+		return spoke
+	}
+
+	if !fs.ValidPath(root) {
+		spoke.err = fmt.Errorf(
+			"WithExtraManifestsFromFS: extra manifests root %q must be a relative path inside the filesystem", root)
+
+		return spoke
+	}
+
+	files, err := spoke.readExtraManifests(fsys, root)
+	if err != nil {
+		spoke.err = fmt.Errorf("WithExtraManifestsFromFS: %w", err)
+
+		return spoke
+	}
+
+	for _, file := range files {
+		if err := spoke.addSizedExtraManifest(file); err != nil {
+			spoke.err = fmt.Errorf("WithExtraManifestsFromFS: %w", err)
+
+			return spoke
+		}
+	}
+
+	return spoke
+}
+
+// WithExtraManifests adds a configmap named name to the spoke extra manifests, holding the manifests keyed by their
+// file name, and references it from the agentclusterinstall. Every manifest must be a YAML file of kubernetes
+// manifests. Calling it again with another name adds another configmap.
+func (spoke *SpokeClusterResources) WithExtraManifests(
+	name string, manifests map[string]string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		spoke.err = fmt.Errorf("WithExtraManifests: invalid configmap name %q: %s", name, strings.Join(errs, ", "))
+
+		return spoke
+	}
+
+	if len(manifests) == 0 {
+		spoke.err = fmt.Errorf("WithExtraManifests: configmap %s requires at least one manifest", name)
+
+		return spoke
+	}
+
+	for _, extraManifest := range spoke.ExtraManifests {
+		if extraManifest.Definition.Name == name {
+			spoke.err = fmt.Errorf("WithExtraManifests: extra manifests configmap %s is defined more than once", name)
+
+			return spoke
+		}
+	}
+
+	size := 0
+
+	for _, fileName := range slices.Sorted(maps.Keys(manifests)) {
+		file := extraManifestFile{key: fileName, content: manifests[fileName]}
+
+		if err := file.validate(); err != nil {
+			spoke.err = fmt.Errorf("WithExtraManifests: invalid extra manifest %s: %w", fileName, err)
+
+			return spoke
+		}
+
+		if spoke.extraManifestDefined(fileName) {
+			spoke.err = fmt.Errorf("WithExtraManifests: extra manifest %s is defined more than once", fileName)
+
+			return spoke
+		}
+
+		size += len(file.key) + len(file.content)
+	}
+
+	if size > maxConfigMapDataSize {
+		spoke.err = fmt.Errorf(
+			"WithExtraManifests: extra manifests of %d bytes exceed the configmap size limit of %d bytes",
+			size, maxConfigMapDataSize)
+
+		return spoke
+	}
+
+	for fileName, content := range manifests {
+		spoke.addExtraManifest(name, fileName, content)
+	}
+
+	if spoke.AgentClusterInstall != nil {
+		spoke.attachExtraManifests()
+	}
+
+	return spoke
+}
+
+// WithSkipInvalidExtraManifests makes WithExtraManifestsFromFS and WithExtraManifestsFromDir log and skip
+// non-YAML files and invalid manifests instead of failing the spoke. It must be set before adding the manifests.
+func (spoke *SpokeClusterResources) WithSkipInvalidExtraManifests(skip bool) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	spoke.skipInvalidExtraManifests = skip
+
+	return spoke
+}
+
+// readExtraManifests returns the manifest files found under root in fsys in lexical order.
+func (spoke *SpokeClusterResources) readExtraManifests(fsys fs.FS, root string) ([]extraManifestFile, error) {
+	var files []extraManifestFile
+
+	err := fs.WalkDir(fsys, root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			return nil
+		}
+
+		relativePath := strings.TrimPrefix(filePath, root+"/")
+		if root == "." {
+			relativePath = filePath
+		}
+
+		content, err := fs.ReadFile(fsys, filePath)
+		if err != nil {
+			return err
+		}
+
+		file := extraManifestFile{key: strings.ReplaceAll(relativePath, "/", "_"), content: string(content)}
+
+		if err := file.validate(); err != nil {
+			if !spoke.skipInvalidExtraManifests {
+				return fmt.Errorf("invalid extra manifest %s: %w", relativePath, err)
+			}
+
+			glog.V(ztpparams.ZTPLogLevel).Infof("Skipping extra manifest %s: %v", relativePath, err)
+		} else {
+			files = append(files, file)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read extra manifests from %s: %w", root, err)
+	}
+
+	return files, nil
+}
+
+// validate checks that the file is a YAML file whose documents are all kubernetes manifests.
+func (file extraManifestFile) validate() error {
+	if extension := path.Ext(file.key); extension != ".yaml" && extension != ".yml" {
+		return fmt.Errorf("not a yaml file")
+	}
+
+	if errs := validation.IsConfigMapKey(file.key); len(errs) > 0 {
+		return fmt.Errorf("invalid configmap key %q: %s", file.key, strings.Join(errs, ", "))
+	}
+
+	decoder := yaml.NewDecoder(strings.NewReader(file.content))
+
+	for {
+		var manifest map[string]interface{}
+
+		err := decoder.Decode(&manifest)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("invalid yaml: %w", err)
+		}
+
+		if manifest == nil {
+			continue
+		}
+
+		apiVersion, _ := manifest["apiVersion"].(string)
+		kind, _ := manifest["kind"].(string)
+
+		if apiVersion == "" || kind == "" {
+			return fmt.Errorf("manifest is missing apiVersion or kind")
+		}
+	}
+}
+
+// addSizedExtraManifest adds the file to the first spoke extra manifests configmap with room left for it.
+func (spoke *SpokeClusterResources) addSizedExtraManifest(file extraManifestFile) error {
+	size := len(file.key) + len(file.content)
+	if size > maxConfigMapDataSize {
+		return fmt.Errorf("extra manifest %s of %d bytes exceeds the configmap size limit of %d bytes",
+			file.key, size, maxConfigMapDataSize)
+	}
+
+	if spoke.extraManifestDefined(file.key) {
+		return fmt.Errorf("extra manifest %s is defined more than once", file.key)
+	}
+
+	for index := 0; ; index++ {
+		configMapName := fmt.Sprintf("%s-extra-manifests", spoke.Name)
+		if index > 0 {
+			configMapName = fmt.Sprintf("%s-%d", configMapName, index)
+		}
+
+		if spoke.extraManifestsDataSize(configMapName)+size <= maxConfigMapDataSize {
+			spoke.addExtraManifest(configMapName, file.key, file.content)
+
+			return nil
+		}
+	}
+}
+
+// extraManifestDefined returns true when fileName is already defined in one of the spoke extra manifests
+// configmaps. Assisted writes every manifest under its key, so keys must be unique across configmaps.
+func (spoke *SpokeClusterResources) extraManifestDefined(fileName string) bool {
+	for _, extraManifest := range spoke.ExtraManifests {
+		if _, found := extraManifest.Definition.Data[fileName]; found {
+			return true
+		}
+	}
+
+	return false
+}
+
+// extraManifestsDataSize returns the size of the data of the named extra manifests configmap, or 0 when it is
+// not instantiated.
+func (spoke *SpokeClusterResources) extraManifestsDataSize(configMapName string) int {
+	size := 0
+
+	for _, extraManifest := range spoke.ExtraManifests {
+		if extraManifest.Definition.Name != configMapName {
+			continue
+		}
+
+		for key, content := range extraManifest.Definition.Data {
+			size += len(key) + len(content)
+		}
+	}
+
+	return size
+}
+
+// addExtraManifest adds the manifest content under fileName to the named extra manifests configmap,
+// instantiating the configmap in the spoke namespace if it does not exist yet.
+func (spoke *SpokeClusterResources) addExtraManifest(configMapName, fileName, content string) {
+	for _, extraManifest := range spoke.ExtraManifests {
+		if extraManifest.Definition.Name == configMapName {
+			extraManifest.Definition.Data[fileName] = content
+
+			return
+		}
+	}
+
+	extraManifest := configmap.NewBuilder(spoke.apiClient, configMapName, spoke.Name)
+	extraManifest.Definition.Data = map[string]string{fileName: content}
+
+	spoke.ExtraManifests = append(spoke.ExtraManifests, extraManifest)
+}
+
+// attachExtraManifests references every extra manifests configmap from the agentclusterinstall.
+func (spoke *SpokeClusterResources) attachExtraManifests() {
+	for _, extraManifest := range spoke.ExtraManifests {
+		referenced := false
+
+		for _, reference := range spoke.AgentClusterInstall.Definition.Spec.ManifestsConfigMapRefs {
+			if reference.Name == extraManifest.Definition.Name {
+				referenced = true
+
+				break
+			}
+		}
+
+		if !referenced {
+			spoke.AgentClusterInstall.Definition.Spec.ManifestsConfigMapRefs = append(
+				spoke.AgentClusterInstall.Definition.Spec.ManifestsConfigMapRefs,
+				v1beta1.ManifestsConfigMapReference{Name: extraManifest.Definition.Name})
+		}
+	}
+}
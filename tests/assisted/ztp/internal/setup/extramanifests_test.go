@@ -0,0 +1,209 @@
+package setup
+
+import (
+	"embed"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+//go:embed testdata/extramanifests testdata/extramanifests-invalid
+var testExtraManifestsFS embed.FS
+
+func TestWithExtraManifestsFromFS(t *testing.T) {
+	testCases := []struct {
+		root         string
+		skipInvalid  bool
+		expectedKeys []string
+		expectedErr  string
+	}{
+		{
+			root:        "testdata/extramanifests",
+			expectedErr: "invalid extra manifest README.md: not a yaml file",
+		},
+		{
+			root:        "testdata/extramanifests",
+			skipInvalid: true,
+			expectedKeys: []string{
+				"01-namespace.yaml", "nested_02-configmap.yaml", "nested_deeper_03-machineconfigs.yml",
+			},
+		},
+		{
+			root:         "testdata/extramanifests/nested",
+			skipInvalid:  true,
+			expectedKeys: []string{"02-configmap.yaml", "deeper_03-machineconfigs.yml"},
+		},
+		{
+			root:        "testdata/extramanifests-invalid",
+			expectedErr: "invalid extra manifest missing-kind.yaml: manifest is missing apiVersion or kind",
+		},
+		{
+			root:        "testdata/extramanifests-invalid",
+			skipInvalid: true,
+		},
+		{
+			root:        "../testdata",
+			skipInvalid: true,
+			expectedErr: `extra manifests root "../testdata" must be a relative path inside the filesystem`,
+		},
+		{
+			root:        "testdata/missing",
+			expectedErr: "failed to read extra manifests from testdata/missing: ",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithSkipInvalidExtraManifests(testCase.skipInvalid).
+			WithExtraManifestsFromFS(testExtraManifestsFS, testCase.root)
+
+		if testCase.expectedErr != "" {
+			assert.ErrorContains(t, spoke.err, testCase.expectedErr, testCase.root)
+
+			continue
+		}
+
+		assert.Nil(t, spoke.err, testCase.root)
+
+		if len(testCase.expectedKeys) == 0 {
+			assert.Empty(t, spoke.ExtraManifests, testCase.root)
+
+			continue
+		}
+
+		assert.Len(t, spoke.ExtraManifests, 1, testCase.root)
+		assert.Equal(t, "spoke-extra-manifests", spoke.ExtraManifests[0].Definition.Name)
+		assert.Equal(t, "spoke", spoke.ExtraManifests[0].Definition.Namespace)
+
+		var keys []string
+
+		for key := range spoke.ExtraManifests[0].Definition.Data {
+			keys = append(keys, key)
+		}
+
+		assert.ElementsMatch(t, testCase.expectedKeys, keys, testCase.root)
+	}
+}
+
+func TestWithExtraManifestsFromFSSize(t *testing.T) {
+	manifest := "apiVersion: v1\nkind: ConfigMap\ndata:\n  key: " + strings.Repeat("a", maxConfigMapDataSize/2) + "\n"
+
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithExtraManifestsFromFS(fstest.MapFS{
+		"a.yaml": {Data: []byte(manifest)},
+		"b.yaml": {Data: []byte(manifest)},
+		"c.yaml": {Data: []byte("apiVersion: v1\nkind: Namespace\n")},
+	}, ".")
+
+	assert.Nil(t, spoke.err)
+	assert.Len(t, spoke.ExtraManifests, 2)
+	assert.Equal(t, "spoke-extra-manifests", spoke.ExtraManifests[0].Definition.Name)
+	assert.Contains(t, spoke.ExtraManifests[0].Definition.Data, "a.yaml")
+	assert.Contains(t, spoke.ExtraManifests[0].Definition.Data, "c.yaml")
+	assert.Equal(t, "spoke-extra-manifests-1", spoke.ExtraManifests[1].Definition.Name)
+	assert.Contains(t, spoke.ExtraManifests[1].Definition.Data, "b.yaml")
+
+	tooLarge := "apiVersion: v1\nkind: ConfigMap\ndata:\n  key: " + strings.Repeat("a", maxConfigMapDataSize) + "\n"
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").
+		WithExtraManifestsFromFS(fstest.MapFS{"large.yaml": {Data: []byte(tooLarge)}}, ".")
+	assert.ErrorContains(t, spoke.err, "extra manifest large.yaml of")
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").
+		WithExtraManifestsFromFS(fstest.MapFS{"a.yaml": {Data: []byte("apiVersion: v1\nkind: Namespace\n")}}, ".").
+		WithExtraManifestsFromFS(fstest.MapFS{"a.yaml": {Data: []byte("apiVersion: v1\nkind: Namespace\n")}}, ".")
+	assert.EqualError(t, spoke.err, "WithExtraManifestsFromFS: extra manifest a.yaml is defined more than once")
+}
+
+func TestWithExtraManifestsFromDir(t *testing.T) {
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithSkipInvalidExtraManifests(true).
+		WithExtraManifestsFromDir("testdata/extramanifests/nested")
+
+	assert.Nil(t, spoke.err)
+	assert.Len(t, spoke.ExtraManifests, 1)
+	assert.Len(t, spoke.ExtraManifests[0].Definition.Data, 2)
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithExtraManifestsFromDir("")
+	assert.EqualError(t, spoke.err, "WithExtraManifestsFromDir: extra manifests directory cannot be empty")
+}
+
+func TestWithExtraManifests(t *testing.T) {
+	chrony := "apiVersion: machineconfiguration.openshift.io/v1\nkind: MachineConfig\nmetadata:\n  name: chrony\n"
+	profile := "apiVersion: performance.openshift.io/v2\nkind: PerformanceProfile\nmetadata:\n  name: profile\n"
+
+	spoke := StandardHAProfile(newHubTestClient(), "manifests-spoke").
+		WithExtraManifests("chrony", map[string]string{"chrony.yaml": chrony}).
+		WithExtraManifests("performance", map[string]string{"profile.yaml": profile})
+	assert.Nil(t, spoke.err)
+	assert.Len(t, spoke.ExtraManifests, 2)
+	assert.Equal(t, map[string]string{"profile.yaml": profile}, spoke.ExtraManifests[1].Definition.Data)
+
+	_, err := spoke.Create()
+	assert.Nil(t, err)
+	assert.Less(t, slices.Index(spoke.createdResources, "extra manifests"),
+		slices.Index(spoke.createdResources, "agentclusterinstall"))
+	assert.Equal(t, []v1beta1.ManifestsConfigMapReference{{Name: "chrony"}, {Name: "performance"}},
+		spoke.AgentClusterInstall.Object.Spec.ManifestsConfigMapRefs)
+
+	assert.Nil(t, spoke.Delete())
+
+	for _, extraManifest := range spoke.ExtraManifests {
+		assert.False(t, extraManifest.Exists(), extraManifest.Definition.Name)
+	}
+}
+
+func TestWithExtraManifestsErrors(t *testing.T) {
+	namespace := "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: extra\n"
+
+	testCases := []struct {
+		name        string
+		manifests   map[string]string
+		expectedErr string
+	}{
+		{
+			name:        "Extra",
+			manifests:   map[string]string{"namespace.yaml": namespace},
+			expectedErr: `WithExtraManifests: invalid configmap name "Extra": `,
+		},
+		{
+			name:        "extra",
+			expectedErr: "WithExtraManifests: configmap extra requires at least one manifest",
+		},
+		{
+			name:      "extra",
+			manifests: map[string]string{"namespace.yaml": "kind: Namespace\n"},
+			expectedErr: "WithExtraManifests: invalid extra manifest namespace.yaml: " +
+				"manifest is missing apiVersion or kind",
+		},
+		{
+			name:        "extra",
+			manifests:   map[string]string{"namespace.yaml": "kind: [Namespace\n"},
+			expectedErr: "WithExtraManifests: invalid extra manifest namespace.yaml: invalid yaml: ",
+		},
+		{
+			name:        "extra",
+			manifests:   map[string]string{"namespace.json": namespace},
+			expectedErr: "WithExtraManifests: invalid extra manifest namespace.json: not a yaml file",
+		},
+		{
+			name:      "spoke-extra-manifests",
+			manifests: map[string]string{"other.yaml": namespace},
+			expectedErr: "WithExtraManifests: extra manifests configmap spoke-extra-manifests " +
+				"is defined more than once",
+		},
+		{
+			name:        "extra",
+			manifests:   map[string]string{"a.yaml": namespace},
+			expectedErr: "WithExtraManifests: extra manifest a.yaml is defined more than once",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := NewSpokeCluster(newTestClient()).WithName("spoke").
+			WithExtraManifestsFromFS(fstest.MapFS{"a.yaml": {Data: []byte(namespace)}}, ".").
+			WithExtraManifests(testCase.name, testCase.manifests)
+
+		assert.ErrorContains(t, spoke.err, testCase.expectedErr, testCase.name)
+	}
+}
@@ -0,0 +1,423 @@
+package setup
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/configmap"
+	"github.com/openshift-kni/eco-goinfra/pkg/deployment"
+	"github.com/openshift-kni/eco-goinfra/pkg/route"
+	"github.com/openshift-kni/eco-goinfra/pkg/service"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// FileServerImage is the httpd container image serving the files of a FileServer.
+	FileServerImage = "registry.redhat.io/rhel8/httpd-24"
+	// MaxFileServerSize is the maximum total size of the files served by a FileServer.
+	MaxFileServerSize = 64 * 1024 * 1024
+
+	fileServerPort       = 8080
+	fileServerRoot       = "/var/www/html"
+	fileServerChunksRoot = "/chunks"
+
+	// fileServerLabel is set to the file server name on its configmaps.
+	fileServerLabel = "eco-gotests/file-server"
+
+	// maxConfigMapDataSize keeps the file chunk configmaps well below the 1MiB object size limit.
+	maxConfigMapDataSize = 900 * 1024
+)
+
+// fileServerReadyTimeout is how long the file server deployment is waited for to be ready or removed.
+var fileServerReadyTimeout = 3 * time.Minute
+
+// fileServerContentTypes maps file extensions to the content type httpd serves them with, covering the types
+// httpd does not know by default.
+var fileServerContentTypes = map[string]string{
+	".ign":  "application/vnd.coreos.ignition+json",
+	".json": "application/json",
+	".yaml": "application/yaml",
+	".yml":  "application/yaml",
+	".img":  "application/octet-stream",
+	".iso":  "application/octet-stream",
+}
+
+// FileServer is an httpd deployment on the hub serving a fixed set of files, exposed through a route when the
+// route API is available and through its service otherwise.
+type FileServer struct {
+	Name      string
+	Namespace string
+	baseURL   string
+	files     []string
+	apiClient *clients.Settings
+}
+
+// DeployFileServer deploys an httpd server named name in the existing namespace serving files, keyed by file name.
+// The files are stored in configmaps, larger files split in chunks across several of them, and reassembled by an
+// init container. Use URL to get the URL of a file and Delete to remove the server.
+func DeployFileServer(
+	apiClient *clients.Settings, name, namespace string, files map[string][]byte) (*FileServer, error) {
+	if apiClient == nil {
+		return nil, fmt.Errorf("apiClient cannot be nil")
+	}
+
+	if err := validateServedFiles(files); err != nil {
+		return nil, err
+	}
+
+	fileServer := &FileServer{Name: name, Namespace: namespace, apiClient: apiClient}
+
+	if err := fileServer.deploy(files); err != nil {
+		if cleanupErr := fileServer.Delete(); cleanupErr != nil {
+			glog.V(ztpparams.ZTPLogLevel).Infof("Failed to clean up file server %s: %v", name, cleanupErr)
+		}
+
+		return nil, err
+	}
+
+	return fileServer, nil
+}
+
+// deploy creates the configmaps, deployment, service and route of the file server and records the served files.
+func (fileServer *FileServer) deploy(files map[string][]byte) error {
+	chunks, parts := chunkFiles(files, maxConfigMapDataSize)
+
+	for _, chunkConfigMap := range newFileServerConfigMaps(
+		fileServer.apiClient, fileServer.Name, fileServer.Namespace, chunks) {
+		if _, err := chunkConfigMap.Create(); err != nil {
+			return fmt.Errorf("failed to create file server configmap: %w", err)
+		}
+	}
+
+	_, err := newFileServerDeployment(fileServer.apiClient, fileServer.Name, fileServer.Namespace, len(chunks), parts).
+		CreateAndWaitUntilReady(fileServerReadyTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to deploy file server %s: %w", fileServer.Name, err)
+	}
+
+	fileServer.baseURL, err = exposeHubService(
+		fileServer.apiClient, fileServer.Name, fileServer.Namespace, fileServerPort)
+	if err != nil {
+		return err
+	}
+
+	for fileName := range files {
+		fileServer.files = append(fileServer.files, fileName)
+	}
+
+	sort.Strings(fileServer.files)
+
+	return nil
+}
+
+// URL returns the URL of the served file.
+func (fileServer *FileServer) URL(fileName string) (string, error) {
+	for _, served := range fileServer.files {
+		if served == fileName {
+			return fileServerURL(fileServer.baseURL, fileName)
+		}
+	}
+
+	return "", fmt.Errorf("file %s is not served by file server %s", fileName, fileServer.Name)
+}
+
+// URLs returns the URL of every served file keyed by file name.
+func (fileServer *FileServer) URLs() (map[string]string, error) {
+	urls := make(map[string]string)
+
+	for _, fileName := range fileServer.files {
+		fileURL, err := fileServerURL(fileServer.baseURL, fileName)
+		if err != nil {
+			return nil, err
+		}
+
+		urls[fileName] = fileURL
+	}
+
+	return urls, nil
+}
+
+// Delete removes the route, service, deployment and configmaps of the file server, ignoring those that are already
+// gone.
+func (fileServer *FileServer) Delete() error {
+	err := deleteHubService(fileServer.apiClient, fileServer.Name, fileServer.Namespace)
+	if err != nil {
+		return err
+	}
+
+	err = newFileServerDeployment(fileServer.apiClient, fileServer.Name, fileServer.Namespace, 0, nil).
+		DeleteAndWait(fileServerReadyTimeout)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete file server deployment: %w", err)
+	}
+
+	configMaps, err := configmap.List(fileServer.apiClient, fileServer.Namespace, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", fileServerLabel, fileServer.Name),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list file server configmaps: %w", err)
+	}
+
+	for _, configMap := range configMaps {
+		if err := configMap.Delete(); err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete file server configmap %s: %w", configMap.Definition.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// validateServedFiles checks that the files can be stored as configmap keys and fit the file server size limit.
+func validateServedFiles(files map[string][]byte) error {
+	if len(files) == 0 {
+		return fmt.Errorf("file server requires at least one file")
+	}
+
+	totalSize := 0
+
+	for fileName, content := range files {
+		if errs := validation.IsConfigMapKey(fileName); len(errs) > 0 {
+			return fmt.Errorf("invalid file name %q: %s", fileName, strings.Join(errs, ", "))
+		}
+
+		totalSize += len(content)
+	}
+
+	if totalSize > MaxFileServerSize {
+		return fmt.Errorf("files total %d bytes which exceeds the file server limit of %d bytes",
+			totalSize, MaxFileServerSize)
+	}
+
+	return nil
+}
+
+// chunkFiles splits the files into parts of at most chunkSize bytes and packs them into configmap data sets of at
+// most chunkSize bytes. It returns the data sets and, for every file, the ordered paths of its parts relative to
+// the chunks root, where the first path element is the index of the data set holding the part.
+func chunkFiles(files map[string][]byte, chunkSize int) ([]map[string][]byte, map[string][]string) {
+	var (
+		chunks      []map[string][]byte
+		currentSize int
+	)
+
+	parts := make(map[string][]string)
+	fileNames := make([]string, 0, len(files))
+
+	for fileName := range files {
+		fileNames = append(fileNames, fileName)
+	}
+
+	sort.Strings(fileNames)
+
+	for _, fileName := range fileNames {
+		content := files[fileName]
+		partCount := (len(content) + chunkSize - 1) / chunkSize
+
+		if partCount == 0 {
+			partCount = 1
+		}
+
+		for partIndex := 0; partIndex < partCount; partIndex++ {
+			part := content[partIndex*chunkSize : min((partIndex+1)*chunkSize, len(content))]
+
+			if len(chunks) == 0 || currentSize+len(part) > chunkSize {
+				chunks = append(chunks, make(map[string][]byte))
+				currentSize = 0
+			}
+
+			key := fileName
+			if partCount > 1 {
+				key = fmt.Sprintf("%s.part-%03d", fileName, partIndex)
+			}
+
+			chunks[len(chunks)-1][key] = part
+			currentSize += len(part)
+
+			parts[fileName] = append(parts[fileName], fmt.Sprintf("%d/%s", len(chunks)-1, key))
+		}
+	}
+
+	return chunks, parts
+}
+
+// newFileServerConfigMaps returns the configmap builders storing the file chunks and the httpd configuration.
+func newFileServerConfigMaps(
+	apiClient *clients.Settings, name, namespace string, chunks []map[string][]byte) []*configmap.Builder {
+	configMaps := []*configmap.Builder{
+		configmap.NewBuilder(apiClient, name+"-config", namespace).
+			WithData(map[string]string{"file-server.conf": fileServerHTTPDConfig()}),
+	}
+
+	for index, chunk := range chunks {
+		chunkConfigMap := configmap.NewBuilder(apiClient, fmt.Sprintf("%s-files-%d", name, index), namespace)
+		chunkConfigMap.Definition.BinaryData = chunk
+
+		configMaps = append(configMaps, chunkConfigMap)
+	}
+
+	for _, configMap := range configMaps {
+		configMap.Definition.Labels = map[string]string{fileServerLabel: name}
+	}
+
+	return configMaps
+}
+
+// newFileServerDeployment returns the httpd deployment builder mounting the chunk configmaps in an init container
+// that reassembles every file from its parts into the served directory.
+func newFileServerDeployment(apiClient *clients.Settings,
+	name, namespace string, chunkCount int, parts map[string][]string) *deployment.Builder {
+	labels := map[string]string{"app": name}
+	filesMount := corev1.VolumeMount{Name: "files", MountPath: fileServerRoot}
+
+	fileServerDeployment := deployment.NewBuilder(apiClient, name, namespace, labels, corev1.Container{
+		Name:  name,
+		Image: FileServerImage,
+		Ports: []corev1.ContainerPort{{ContainerPort: fileServerPort, Protocol: corev1.ProtocolTCP}},
+		VolumeMounts: []corev1.VolumeMount{filesMount, {
+			Name: "config", MountPath: "/etc/httpd/conf.d/file-server.conf", SubPath: "file-server.conf",
+		}},
+	}).WithVolume(corev1.Volume{
+		Name: "files", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	}).WithVolume(corev1.Volume{
+		Name: "config", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: name + "-config"},
+		}},
+	})
+
+	initMounts := []corev1.VolumeMount{filesMount}
+
+	for index := 0; index < chunkCount; index++ {
+		volumeName := fmt.Sprintf("chunks-%d", index)
+
+		fileServerDeployment.WithVolume(corev1.Volume{
+			Name: volumeName, VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: fmt.Sprintf("%s-files-%d", name, index)},
+			}},
+		})
+
+		initMounts = append(initMounts, corev1.VolumeMount{
+			Name: volumeName, MountPath: fmt.Sprintf("%s/%d", fileServerChunksRoot, index),
+		})
+	}
+
+	fileServerDeployment.Definition.Spec.Template.Spec.InitContainers = []corev1.Container{{
+		Name:         "assemble-files",
+		Image:        FileServerImage,
+		Command:      []string{"/bin/sh", "-c", assembleFilesScript(parts)},
+		VolumeMounts: initMounts,
+	}}
+
+	return fileServerDeployment
+}
+
+// assembleFilesScript returns the shell script concatenating the parts of every file into the served directory.
+// File names are configmap keys so they do not need quoting.
+func assembleFilesScript(parts map[string][]string) string {
+	fileNames := make([]string, 0, len(parts))
+
+	for fileName := range parts {
+		fileNames = append(fileNames, fileName)
+	}
+
+	sort.Strings(fileNames)
+
+	commands := []string{"set -e"}
+
+	for _, fileName := range fileNames {
+		var partPaths []string
+
+		for _, part := range parts[fileName] {
+			partPaths = append(partPaths, path.Join(fileServerChunksRoot, part))
+		}
+
+		commands = append(commands,
+			fmt.Sprintf("cat %s > %s", strings.Join(partPaths, " "), path.Join(fileServerRoot, fileName)))
+	}
+
+	return strings.Join(commands, "\n")
+}
+
+// fileServerHTTPDConfig returns the httpd configuration adding the file server content types.
+func fileServerHTTPDConfig() string {
+	extensions := make([]string, 0, len(fileServerContentTypes))
+
+	for extension := range fileServerContentTypes {
+		extensions = append(extensions, extension)
+	}
+
+	sort.Strings(extensions)
+
+	var config strings.Builder
+
+	for _, extension := range extensions {
+		config.WriteString(fmt.Sprintf("AddType %s %s\n", fileServerContentTypes[extension], extension))
+	}
+
+	return config.String()
+}
+
+// fileServerURL returns the URL of fileName under baseURL.
+func fileServerURL(baseURL, fileName string) (string, error) {
+	parsedURL, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse file server url %q: %w", baseURL, err)
+	}
+
+	return parsedURL.JoinPath(fileName).String(), nil
+}
+
+// exposeHubService creates a service selecting the pods labeled app=name and a route to it, returning the http URL of
+// the route. When the route API is not available or no host was admitted, the cluster-local URL of the service is
+// returned instead.
+func exposeHubService(apiClient *clients.Settings, name, namespace string, port int32) (string, error) {
+	_, err := service.NewBuilder(apiClient, name, namespace, map[string]string{"app": name}, corev1.ServicePort{
+		Port:     port,
+		Protocol: corev1.ProtocolTCP,
+	}).Create()
+	if err != nil {
+		return "", fmt.Errorf("failed to create service %s: %w", name, err)
+	}
+
+	serviceRoute, err := route.NewBuilder(apiClient, name, namespace, name).WithTargetPortNumber(port).Create()
+	if err != nil && !meta.IsNoMatchError(err) && !runtime.IsNotRegisteredError(err) {
+		return "", fmt.Errorf("failed to create route %s: %w", name, err)
+	}
+
+	if err == nil && serviceRoute.Definition.Spec.Host != "" {
+		return fmt.Sprintf("http://%s", serviceRoute.Definition.Spec.Host), nil
+	}
+
+	serviceEndpoint := Endpoint{
+		Scheme: "http", Host: fmt.Sprintf("%s.%s.svc.cluster.local", name, namespace), Port: strconv.Itoa(int(port)),
+	}
+
+	return serviceEndpoint.Scheme + "://" + serviceEndpoint.HostPort(), nil
+}
+
+// deleteHubService removes the route, when the route API is available, and the service created by exposeHubService.
+func deleteHubService(apiClient *clients.Settings, name, namespace string) error {
+	_, err := route.NewBuilder(apiClient, name, namespace, name).Delete()
+	if err != nil && !meta.IsNoMatchError(err) && !runtime.IsNotRegisteredError(err) && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete route %s: %w", name, err)
+	}
+
+	err = service.NewBuilder(apiClient, name, namespace, map[string]string{"app": name}, corev1.ServicePort{}).Delete()
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete service %s: %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package setup
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/configmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunkFiles(t *testing.T) {
+	files := map[string][]byte{
+		"config.ign":  []byte("0123456789"),
+		"small.yaml":  []byte("abc"),
+		"rootfs.img":  bytes.Repeat([]byte("r"), 25),
+		"empty.txt":   {},
+		"exact.bytes": bytes.Repeat([]byte("e"), 10),
+	}
+
+	chunks, parts := chunkFiles(files, 10)
+
+	assert.Equal(t, map[string][]string{
+		"config.ign":  {"0/config.ign"},
+		"empty.txt":   {"0/empty.txt"},
+		"exact.bytes": {"1/exact.bytes"},
+		"rootfs.img":  {"2/rootfs.img.part-000", "3/rootfs.img.part-001", "4/rootfs.img.part-002"},
+		"small.yaml":  {"4/small.yaml"},
+	}, parts)
+
+	assert.Len(t, chunks, 5)
+
+	for _, chunk := range chunks {
+		size := 0
+
+		for _, content := range chunk {
+			size += len(content)
+		}
+
+		assert.LessOrEqual(t, size, 10)
+	}
+
+	assert.Equal(t, bytes.Repeat([]byte("r"), 5), chunks[4]["rootfs.img.part-002"])
+	assert.Equal(t, []byte{}, chunks[0]["empty.txt"])
+
+	chunks, parts = chunkFiles(map[string][]byte{"a.yaml": []byte("aaa"), "b.yaml": []byte("bbb")}, 10)
+	assert.Len(t, chunks, 1)
+	assert.Equal(t, map[string][]string{"a.yaml": {"0/a.yaml"}, "b.yaml": {"0/b.yaml"}}, parts)
+}
+
+func TestDeployFileServerCleanup(t *testing.T) {
+	fileServerReadyTimeout = 10 * time.Millisecond
+
+	t.Cleanup(func() {
+		fileServerReadyTimeout = 3 * time.Minute
+	})
+
+	apiClient := newTestClient()
+
+	_, err := DeployFileServer(apiClient, "files", "file-ns", map[string][]byte{"config.ign": []byte("{}")})
+	assert.EqualError(t, err, "failed to deploy file server files: deployment files in namespace file-ns is not ready")
+
+	configMaps, err := configmap.List(apiClient, "file-ns")
+	assert.Nil(t, err)
+	assert.Empty(t, configMaps)
+	assert.False(t, newFileServerDeployment(apiClient, "files", "file-ns", 0, nil).Exists())
+}
+
+func TestAssembleFilesScript(t *testing.T) {
+	script := assembleFilesScript(map[string][]string{
+		"rootfs.img": {"0/rootfs.img.part-000", "1/rootfs.img.part-001"},
+		"config.ign": {"1/config.ign"},
+	})
+
+	assert.Equal(t, "set -e\n"+
+		"cat /chunks/1/config.ign > /var/www/html/config.ign\n"+
+		"cat /chunks/0/rootfs.img.part-000 /chunks/1/rootfs.img.part-001 > /var/www/html/rootfs.img", script)
+}
+
+func TestNewFileServerDeployment(t *testing.T) {
+	chunks, parts := chunkFiles(map[string][]byte{"config.ign": []byte("{}")}, maxConfigMapDataSize)
+	configMaps := newFileServerConfigMaps(newTestClient(), "files", "files-ns", chunks)
+
+	assert.Len(t, configMaps, 2)
+	assert.Equal(t, "files-config", configMaps[0].Definition.Name)
+	assert.Contains(t, configMaps[0].Definition.Data["file-server.conf"],
+		"AddType application/vnd.coreos.ignition+json .ign\n")
+	assert.Equal(t, "files-files-0", configMaps[1].Definition.Name)
+	assert.Equal(t, []byte("{}"), configMaps[1].Definition.BinaryData["config.ign"])
+
+	for _, configMap := range configMaps {
+		assert.Equal(t, map[string]string{fileServerLabel: "files"}, configMap.Definition.Labels)
+	}
+
+	podSpec := newFileServerDeployment(newTestClient(), "files", "files-ns", len(chunks), parts).
+		Definition.Spec.Template.Spec
+
+	assert.Len(t, podSpec.Volumes, 3)
+	assert.Equal(t, "files-files-0", podSpec.Volumes[2].ConfigMap.Name)
+	assert.Len(t, podSpec.InitContainers, 1)
+	assert.Equal(t, "/chunks/0", podSpec.InitContainers[0].VolumeMounts[1].MountPath)
+	assert.Equal(t, "/var/www/html", podSpec.Containers[0].VolumeMounts[0].MountPath)
+}
+
+func TestValidateServedFiles(t *testing.T) {
+	testCases := []struct {
+		files       map[string][]byte
+		expectedErr string
+	}{
+		{files: map[string][]byte{"config.ign": []byte("{}")}},
+		{files: map[string][]byte{}, expectedErr: "file server requires at least one file"},
+		{
+			files:       map[string][]byte{"ignition/config.ign": []byte("{}")},
+			expectedErr: `invalid file name "ignition/config.ign"`,
+		},
+		{
+			files:       map[string][]byte{"rootfs.img": make([]byte, MaxFileServerSize+1)},
+			expectedErr: "files total 67108865 bytes which exceeds the file server limit of 67108864 bytes",
+		},
+	}
+
+	for _, testCase := range testCases {
+		err := validateServedFiles(testCase.files)
+
+		if testCase.expectedErr == "" {
+			assert.Nil(t, err)
+		} else {
+			assert.ErrorContains(t, err, testCase.expectedErr)
+		}
+	}
+}
+
+func TestFileServerURL(t *testing.T) {
+	fileServer := &FileServer{
+		Name:    "files",
+		baseURL: "http://files-files-ns.apps.hub.example.com",
+		files:   []string{"config.ign", "rootfs.img"},
+	}
+
+	fileURL, err := fileServer.URL("config.ign")
+	assert.Nil(t, err)
+	assert.Equal(t, "http://files-files-ns.apps.hub.example.com/config.ign", fileURL)
+
+	_, err = fileServer.URL("missing.ign")
+	assert.EqualError(t, err, "file missing.ign is not served by file server files")
+
+	urls, err := fileServer.URLs()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"config.ign": "http://files-files-ns.apps.hub.example.com/config.ign",
+		"rootfs.img": "http://files-files-ns.apps.hub.example.com/rootfs.img",
+	}, urls)
+
+	fileServer.baseURL = "http://files.files-ns.svc.cluster.local:8080"
+	fileURL, err = fileServer.URL("rootfs.img")
+	assert.Nil(t, err)
+	assert.Equal(t, "http://files.files-ns.svc.cluster.local:8080/rootfs.img", fileURL)
+}
+
+func TestExposeHubService(t *testing.T) {
+	serviceURL, err := exposeHubService(newTestClient(), "files", "files-ns", fileServerPort)
+	assert.Nil(t, err)
+	assert.Equal(t, "http://files.files-ns.svc.cluster.local:8080", serviceURL)
+	assert.Nil(t, deleteHubService(newTestClient(), "files", "files-ns"))
+}
@@ -0,0 +1,239 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	bmhv1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/openshift-kni/eco-goinfra/pkg/namespace"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	hivev1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/hive/api/v1"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	runtimeClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const defaultForceDeleteTimeout = time.Minute * 10
+
+// DefaultForceDeleteFinalizerDomains are the domains of the assisted-service, hive and baremetal-operator finalizers
+// that ForceDelete removes when no allowlist is provided.
+var DefaultForceDeleteFinalizerDomains = []string{"agent-install.openshift.io", "hive.openshift.io", "metal3.io"}
+
+// ForceDeleteOptions configures ForceDelete. GracePeriod limits how long each resource is given to be removed by
+// its controllers before finalizers are stripped, the spoke delete timeout when unset. Timeout limits the whole
+// teardown, 10 minutes when unset. FinalizerDomains lists the domains of the finalizers that may be stripped, a
+// finalizer matching when its domain is one of them or a subdomain; DefaultForceDeleteFinalizerDomains when unset.
+type ForceDeleteOptions struct {
+	GracePeriod      time.Duration
+	Timeout          time.Duration
+	FinalizerDomains []string
+}
+
+// ForceDelete removes the spoke resources like Delete, giving each resource the grace period to be removed. When
+// resources remain, the allowlisted finalizers of the agentclusterinstalls, clusterdeployments, infraenvs, agents
+// and baremetalhosts in the spoke namespace are stripped, logging each of them, and the namespace is deleted. An
+// error is returned when the namespace is not removed within the timeout. Passing nil uses the default options.
+func (spoke *SpokeClusterResources) ForceDelete(options *ForceDeleteOptions) error {
+	return spoke.ForceDeleteWithContext(context.Background(), options)
+}
+
+// ForceDeleteWithContext removes the spoke resources like ForceDelete, stopping the deletion and the wait for the
+// namespace removal when ctx is done.
+func (spoke *SpokeClusterResources) ForceDeleteWithContext(ctx context.Context, options *ForceDeleteOptions) error {
+	if spoke.Name == "" {
+		return fmt.Errorf("spoke name must be set before force deleting the spoke")
+	}
+
+	resolved, err := spoke.resolveForceDeleteOptions(options)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+
+	waitOptions := spoke.resolveWaitOptions()
+	waitOptions.DeleteTimeout = resolved.GracePeriod
+	originalWaitOptions := spoke.waitOptions
+	spoke.waitOptions = &waitOptions
+
+	deleteErr := spoke.DeleteWithContext(ctx)
+
+	spoke.waitOptions = originalWaitOptions
+
+	if deleteErr == nil {
+		return nil
+	}
+
+	glog.V(ztpparams.ZTPLogLevel).Infof(
+		"Spoke %s resources were not removed within the grace period, stripping finalizers: %v", spoke.Name, deleteErr)
+
+	nsBuilder := spoke.Namespace
+	if nsBuilder == nil {
+		nsBuilder = namespace.NewBuilder(spoke.apiClient, spoke.Name)
+	}
+
+	waitOptions.Timeout = max(resolved.Timeout-time.Since(start), time.Millisecond)
+	waitOptions.Interval = min(waitOptions.Interval, waitOptions.Timeout)
+
+	var pending string
+
+	err = spoke.poll(ctx, "wait for force deletion", waitOptions, func(ctx context.Context) (bool, error) {
+		if err := spoke.stripFinalizers(ctx, resolved.FinalizerDomains); err != nil {
+			pending = err.Error()
+
+			return false, nil
+		}
+
+		if err := nsBuilder.Delete(); err != nil {
+			pending = fmt.Sprintf("failed to delete namespace %s: %v", spoke.Name, err)
+
+			return false, nil
+		}
+
+		err := spoke.apiClient.Get(ctx, runtimeClient.ObjectKey{Name: spoke.Name}, nsBuilder.Definition.DeepCopy())
+		if k8serrors.IsNotFound(err) {
+			return true, nil
+		}
+
+		pending = fmt.Sprintf("namespace %s still exists", spoke.Name)
+
+		return false, nil
+	})
+
+	if err != nil {
+		spoke.err = fmt.Errorf("namespace %s was not removed within %s: %s", spoke.Name, resolved.Timeout, pending)
+
+		return spoke.err
+	}
+
+	spoke.err = nil
+
+	return nil
+}
+
+// stripFinalizers removes the finalizers matching domains from the assisted, hive and baremetal-operator resources
+// in the spoke namespace.
+func (spoke *SpokeClusterResources) stripFinalizers(ctx context.Context, domains []string) error {
+	if err := spoke.apiClient.AttachScheme(bmhv1alpha1.AddToScheme); err != nil {
+		return fmt.Errorf("failed to attach baremetalhost scheme: %w", err)
+	}
+
+	lists := []struct {
+		kind string
+		list runtimeClient.ObjectList
+	}{
+		{kind: "agentclusterinstall", list: &v1beta1.AgentClusterInstallList{}},
+		{kind: "clusterdeployment", list: &hivev1.ClusterDeploymentList{}},
+		{kind: "infraenv", list: &agentInstallV1Beta1.InfraEnvList{}},
+		{kind: "agent", list: &agentInstallV1Beta1.AgentList{}},
+		{kind: "baremetalhost", list: &bmhv1alpha1.BareMetalHostList{}},
+	}
+
+	for _, resources := range lists {
+		if err := spoke.apiClient.List(ctx, resources.list, runtimeClient.InNamespace(spoke.Name)); err != nil {
+			return fmt.Errorf("failed to list %ss in namespace %s: %w", resources.kind, spoke.Name, err)
+		}
+
+		objects, err := meta.ExtractList(resources.list)
+		if err != nil {
+			return fmt.Errorf("failed to extract %ss in namespace %s: %w", resources.kind, spoke.Name, err)
+		}
+
+		for _, listed := range objects {
+			object, ok := listed.(runtimeClient.Object)
+			if !ok {
+				continue
+			}
+
+			if err := spoke.stripObjectFinalizers(ctx, resources.kind, object, domains); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// stripObjectFinalizers removes the finalizers matching domains from object, logging each removed finalizer.
+func (spoke *SpokeClusterResources) stripObjectFinalizers(
+	ctx context.Context, kind string, object runtimeClient.Object, domains []string) error {
+	var kept []string
+
+	for _, finalizer := range object.GetFinalizers() {
+		if !finalizerAllowed(finalizer, domains) {
+			kept = append(kept, finalizer)
+
+			continue
+		}
+
+		glog.V(ztpparams.ZTPLogLevel).Infof("Stripping finalizer %s from %s %s/%s",
+			finalizer, kind, object.GetNamespace(), object.GetName())
+	}
+
+	if len(kept) == len(object.GetFinalizers()) {
+		return nil
+	}
+
+	object.SetFinalizers(kept)
+
+	err := spoke.apiClient.Update(ctx, object)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("failed to strip finalizers from %s %s: %w", kind, object.GetName(), err)
+	}
+
+	return nil
+}
+
+// resolveForceDeleteOptions returns options with the defaults applied, or an error when they are invalid.
+func (spoke *SpokeClusterResources) resolveForceDeleteOptions(options *ForceDeleteOptions) (ForceDeleteOptions, error) {
+	var resolved ForceDeleteOptions
+
+	if options != nil {
+		resolved = *options
+	}
+
+	if resolved.GracePeriod < 0 {
+		return resolved, fmt.Errorf("force delete grace period cannot be negative")
+	}
+
+	if resolved.Timeout < 0 {
+		return resolved, fmt.Errorf("force delete timeout cannot be negative")
+	}
+
+	if resolved.GracePeriod == 0 {
+		resolved.GracePeriod = spoke.deleteWaitOptions().Timeout
+	}
+
+	if resolved.Timeout == 0 {
+		resolved.Timeout = defaultForceDeleteTimeout
+	}
+
+	if resolved.Timeout < resolved.GracePeriod {
+		return resolved, fmt.Errorf("force delete timeout %s cannot be less than the grace period %s",
+			resolved.Timeout, resolved.GracePeriod)
+	}
+
+	if len(resolved.FinalizerDomains) == 0 {
+		resolved.FinalizerDomains = DefaultForceDeleteFinalizerDomains
+	}
+
+	return resolved, nil
+}
+
+// finalizerAllowed returns true when the domain of finalizer is one of domains or a subdomain of one of them.
+func finalizerAllowed(finalizer string, domains []string) bool {
+	domain, _, _ := strings.Cut(finalizer, "/")
+
+	for _, allowed := range domains {
+		if domain == allowed || strings.HasSuffix(domain, "."+allowed) {
+			return true
+		}
+	}
+
+	return false
+}
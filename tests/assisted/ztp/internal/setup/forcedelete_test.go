@@ -0,0 +1,107 @@
+package setup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtimeClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const testForeignFinalizer = "example.com/keep"
+
+func TestForceDelete(t *testing.T) {
+	agentClusterInstall := &v1beta1.AgentClusterInstall{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "spoke",
+			Namespace:  "spoke",
+			Finalizers: []string{testDeprovisionFinalizer},
+		},
+	}
+	agent := buildDummyApprovableAgent("agent-0", "spoke-host-0", "52:54:00:00:00:01")
+	agent.Finalizers = []string{"agent.agent-install.openshift.io/ai-deprovision", testForeignFinalizer}
+
+	apiClient := newTestClient(agentClusterInstall, agent)
+	spoke := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultIPv4AgentClusterInstall().
+		WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Second})
+
+	err := spoke.ForceDelete(&ForceDeleteOptions{GracePeriod: 10 * time.Millisecond, Timeout: time.Second})
+	assert.Nil(t, err)
+
+	err = apiClient.Get(context.TODO(), runtimeClient.ObjectKeyFromObject(agentClusterInstall),
+		&v1beta1.AgentClusterInstall{})
+	assert.True(t, k8serrors.IsNotFound(err))
+
+	remainingAgent := &agentInstallV1Beta1.Agent{}
+	err = apiClient.Get(context.TODO(), runtimeClient.ObjectKeyFromObject(agent), remainingAgent)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{testForeignFinalizer}, remainingAgent.Finalizers)
+}
+
+func TestForceDeleteNamespaceStuck(t *testing.T) {
+	spokeNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "spoke"}}
+	spoke := NewSpokeCluster(newTestClient(spokeNamespace)).WithName("spoke").WithDefaultNamespace().
+		WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Second})
+
+	err := spoke.ForceDelete(&ForceDeleteOptions{GracePeriod: 10 * time.Millisecond, Timeout: 50 * time.Millisecond})
+	assert.EqualError(t, err, "namespace spoke was not removed within 50ms: namespace spoke still exists")
+}
+
+func TestResolveForceDeleteOptions(t *testing.T) {
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke")
+
+	options, err := spoke.resolveForceDeleteOptions(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, ForceDeleteOptions{
+		GracePeriod:      defaultDeleteTimeout,
+		Timeout:          defaultForceDeleteTimeout,
+		FinalizerDomains: DefaultForceDeleteFinalizerDomains,
+	}, options)
+
+	testCases := []struct {
+		options     ForceDeleteOptions
+		expectedErr string
+	}{
+		{
+			options:     ForceDeleteOptions{GracePeriod: -time.Second},
+			expectedErr: "force delete grace period cannot be negative",
+		},
+		{
+			options:     ForceDeleteOptions{Timeout: -time.Second},
+			expectedErr: "force delete timeout cannot be negative",
+		},
+		{
+			options:     ForceDeleteOptions{GracePeriod: time.Minute, Timeout: time.Second},
+			expectedErr: "force delete timeout 1s cannot be less than the grace period 1m0s",
+		},
+	}
+
+	for _, testCase := range testCases {
+		_, err := spoke.resolveForceDeleteOptions(&testCase.options)
+		assert.EqualError(t, err, testCase.expectedErr)
+	}
+}
+
+func TestFinalizerAllowed(t *testing.T) {
+	testCases := []struct {
+		finalizer string
+		expected  bool
+	}{
+		{finalizer: testDeprovisionFinalizer, expected: true},
+		{finalizer: "hive.openshift.io/deprovision", expected: true},
+		{finalizer: "baremetalhost.metal3.io", expected: true},
+		{finalizer: testForeignFinalizer, expected: false},
+		{finalizer: "notmetal3.io/cleanup", expected: false},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, finalizerAllowed(testCase.finalizer, DefaultForceDeleteFinalizerDomains),
+			testCase.finalizer)
+	}
+}
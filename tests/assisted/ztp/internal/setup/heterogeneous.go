@@ -0,0 +1,234 @@
+package setup
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/assisted"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/models"
+)
+
+// architectureRoles is the number of control plane and worker agents required of a CPU architecture.
+type architectureRoles struct {
+	controlPlane int
+	workers      int
+}
+
+// WithHeterogeneousWorkers adds count workers of the CPU architecture arch to a spoke whose control plane uses the
+// infraenv architecture. The workers boot from an additional infraenv of that architecture and the clusterimageset
+// of the agentclusterinstall must reference a multi payload. Use AssignAgentRoles once the agents are registered
+// to match the workers by architecture.
+func (spoke *SpokeClusterResources) WithHeterogeneousWorkers(arch string, count int) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.AgentClusterInstall == nil || spoke.InfraEnv == nil {
+		spoke.err = fmt.Errorf("WithHeterogeneousWorkers: " +
+			"agentclusterinstall and infraenv must be defined before adding heterogeneous workers")
+
+		return spoke
+	}
+
+	if count <= 0 {
+		spoke.err = fmt.Errorf("WithHeterogeneousWorkers: heterogeneous worker count must be greater than 0")
+
+		return spoke
+	}
+
+	if _, found := architectureBootMethods[arch]; !found {
+		spoke.err = fmt.Errorf("WithHeterogeneousWorkers: unsupported heterogeneous worker cpu architecture %q", arch)
+
+		return spoke
+	}
+
+	if controlPlaneArch := spoke.infraEnvCPUArchitecture(); arch == controlPlaneArch {
+		spoke.err = fmt.Errorf("WithHeterogeneousWorkers: "+
+			"heterogeneous worker architecture %s must differ from the control plane architecture", arch)
+
+		return spoke
+	}
+
+	if err := spoke.validateMultiImageSet(); err != nil {
+		spoke.err = fmt.Errorf("WithHeterogeneousWorkers: %w", err)
+
+		return spoke
+	}
+
+	if _, found := spoke.workerArchitectures[arch]; !found {
+		spoke.AdditionalInfraEnvs = append(spoke.AdditionalInfraEnvs, spoke.newArchitectureInfraEnv(arch))
+	}
+
+	if spoke.workerArchitectures == nil {
+		spoke.workerArchitectures = map[string]int{}
+	}
+
+	spoke.workerArchitectures[arch] += count
+	spoke.AgentClusterInstall.Definition.Spec.ProvisionRequirements.WorkerAgents += count
+
+	return spoke
+}
+
+// AssignAgentRoles sets the roles of the agents registered to the spoke infraenvs. Control plane agents and the
+// remaining workers are picked from the agents of the infraenv architecture and heterogeneous workers from the
+// agents of their architecture, as reported by the agent inventory, in name order.
+func (spoke *SpokeClusterResources) AssignAgentRoles() error {
+	if spoke.AgentClusterInstall == nil || spoke.InfraEnv == nil {
+		return fmt.Errorf("agentclusterinstall and infraenv must be defined before assigning agent roles")
+	}
+
+	agents, err := spoke.InfraEnv.GetAllAgents()
+	if err != nil {
+		return fmt.Errorf("failed to list agents of infraenv %s: %w", spoke.InfraEnv.Definition.Name, err)
+	}
+
+	for _, infraEnv := range spoke.AdditionalInfraEnvs {
+		additionalAgents, err := infraEnv.GetAllAgents()
+		if err != nil {
+			return fmt.Errorf("failed to list agents of infraenv %s: %w", infraEnv.Definition.Name, err)
+		}
+
+		agents = append(agents, additionalAgents...)
+	}
+
+	var agentObjects []*agentInstallV1Beta1.Agent
+
+	for _, agent := range agents {
+		agentObjects = append(agentObjects, agent.Object)
+	}
+
+	roles, err := assignAgentRoles(agentObjects, spoke.architectureRoles())
+	if err != nil {
+		return err
+	}
+
+	for _, agent := range agents {
+		role, found := roles[agent.Object.Name]
+		if !found {
+			continue
+		}
+
+		if _, err := agent.WithRole(string(role)).Update(); err != nil {
+			return fmt.Errorf("failed to set role %s on agent %s: %w", role, agent.Object.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// validateMultiImageSet checks that the clusterimageset of the agentclusterinstall references a multi payload.
+func (spoke *SpokeClusterResources) validateMultiImageSet() error {
+	if spoke.AgentClusterInstall.Definition.Spec.ImageSetRef == nil {
+		return fmt.Errorf("agentclusterinstall must reference a clusterimageset to add heterogeneous workers")
+	}
+
+	imageSetName := spoke.AgentClusterInstall.Definition.Spec.ImageSetRef.Name
+
+	payloadArch, err := pullImageSetArchitecture(spoke.apiClient, imageSetName)
+	if err != nil {
+		return err
+	}
+
+	if payloadArch != CPUArchitectureMulti {
+		return fmt.Errorf("clusterimageset %s references a %s payload but heterogeneous workers require a %s payload",
+			imageSetName, payloadArch, CPUArchitectureMulti)
+	}
+
+	return nil
+}
+
+// newArchitectureInfraEnv returns an infraenv builder for the arch hosts of the spoke, sharing the pull-secret,
+// cluster reference and ssh key of the spoke infraenv.
+func (spoke *SpokeClusterResources) newArchitectureInfraEnv(arch string) *assisted.InfraEnvBuilder {
+	var pullSecretName string
+
+	if spoke.InfraEnv.Definition.Spec.PullSecretRef != nil {
+		pullSecretName = spoke.InfraEnv.Definition.Spec.PullSecretRef.Name
+	}
+
+	infraEnv := assisted.NewInfraEnvBuilder(
+		spoke.apiClient, fmt.Sprintf("%s-%s", spoke.Name, arch), spoke.Name, pullSecretName).WithCPUType(arch)
+
+	if clusterRef := spoke.InfraEnv.Definition.Spec.ClusterRef; clusterRef != nil {
+		infraEnv.Definition.Spec.ClusterRef = clusterRef.DeepCopy()
+	}
+
+	infraEnv.Definition.Spec.SSHAuthorizedKey = spoke.InfraEnv.Definition.Spec.SSHAuthorizedKey
+
+	return infraEnv
+}
+
+// architectureRoles returns the number of control plane and worker agents required of each CPU architecture.
+func (spoke *SpokeClusterResources) architectureRoles() map[string]architectureRoles {
+	requirements := spoke.AgentClusterInstall.Definition.Spec.ProvisionRequirements
+	controlPlaneRoles := architectureRoles{
+		controlPlane: requirements.ControlPlaneAgents,
+		workers:      requirements.WorkerAgents,
+	}
+
+	roles := map[string]architectureRoles{}
+
+	for arch, count := range spoke.workerArchitectures {
+		roles[arch] = architectureRoles{workers: count}
+		controlPlaneRoles.workers -= count
+	}
+
+	roles[spoke.infraEnvCPUArchitecture()] = controlPlaneRoles
+
+	return roles
+}
+
+// assignAgentRoles returns the role of each agent, keyed by agent name, fulfilling the roles required of each CPU
+// architecture. Agents are picked in name order and agents left over are not assigned a role.
+func assignAgentRoles(
+	agents []*agentInstallV1Beta1.Agent, roles map[string]architectureRoles) (map[string]models.HostRole, error) {
+	agentsByArch := map[string][]string{}
+
+	for _, agent := range agents {
+		arch := agentArchitecture(agent)
+		agentsByArch[arch] = append(agentsByArch[arch], agent.Name)
+	}
+
+	var architectures []string
+
+	for arch := range roles {
+		architectures = append(architectures, arch)
+	}
+
+	sort.Strings(architectures)
+
+	assigned := map[string]models.HostRole{}
+
+	for _, arch := range architectures {
+		required := roles[arch]
+		names := agentsByArch[arch]
+		sort.Strings(names)
+
+		if len(names) < required.controlPlane+required.workers {
+			return nil, fmt.Errorf("%d %s agents are required but only %d are registered",
+				required.controlPlane+required.workers, arch, len(names))
+		}
+
+		for index, name := range names[:required.controlPlane+required.workers] {
+			assigned[name] = models.HostRoleWorker
+			if index < required.controlPlane {
+				assigned[name] = models.HostRoleMaster
+			}
+		}
+	}
+
+	return assigned, nil
+}
+
+// agentArchitecture returns the CPU architecture of the agent from its inventory, with architecture aliases such
+// as aarch64 converted to the infraenv architecture names.
+func agentArchitecture(agent *agentInstallV1Beta1.Agent) string {
+	inventoryArch := agent.Status.Inventory.Cpu.Architecture
+
+	if arch, found := releaseTagArchitectures[inventoryArch]; found {
+		return arch
+	}
+
+	return inventoryArch
+}
@@ -0,0 +1,193 @@
+package setup
+
+import (
+	"testing"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/assisted"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/models"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWithHeterogeneousWorkers(t *testing.T) {
+	testCases := []struct {
+		arch        string
+		count       int
+		releaseArch string
+		expectedErr string
+	}{
+		{arch: CPUArchitectureARM64, count: 2, releaseArch: "multi"},
+		{
+			arch:        CPUArchitectureARM64,
+			count:       2,
+			releaseArch: "x86_64",
+			expectedErr: "WithHeterogeneousWorkers: clusterimageset multi-imageset references a x86_64 payload " +
+				"but heterogeneous workers require a multi payload",
+		},
+		{
+			arch:        CPUArchitectureX86_64,
+			count:       2,
+			releaseArch: "multi",
+			expectedErr: "WithHeterogeneousWorkers: heterogeneous worker architecture x86_64 must differ from the " +
+				"control plane architecture",
+		},
+		{
+			arch:        CPUArchitectureARM64,
+			count:       0,
+			releaseArch: "multi",
+			expectedErr: "WithHeterogeneousWorkers: heterogeneous worker count must be greater than 0",
+		},
+		{
+			arch:        "riscv64",
+			count:       1,
+			releaseArch: "multi",
+			expectedErr: `WithHeterogeneousWorkers: unsupported heterogeneous worker cpu architecture "riscv64"`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		apiClient := newTestClient(
+			buildDummyClusterImageSet("multi-imageset", testCase.releaseArch, "quay.io/ocp-release:4.16.0"))
+		spoke := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultIPv4AgentClusterInstall().
+			WithDefaultInfraEnv()
+		spoke.AgentClusterInstall.WithImageSet("multi-imageset")
+
+		spoke.WithHeterogeneousWorkers(testCase.arch, testCase.count)
+
+		if testCase.expectedErr != "" {
+			assert.EqualError(t, spoke.err, testCase.expectedErr)
+			assert.Empty(t, spoke.AdditionalInfraEnvs)
+			assert.Equal(t, defaultWorkerAgents,
+				spoke.AgentClusterInstall.Definition.Spec.ProvisionRequirements.WorkerAgents)
+
+			continue
+		}
+
+		assert.Nil(t, spoke.err)
+		assert.Len(t, spoke.AdditionalInfraEnvs, 1)
+		assert.Equal(t, "spoke-arm64", spoke.AdditionalInfraEnvs[0].Definition.Name)
+		assert.Equal(t, CPUArchitectureARM64, spoke.AdditionalInfraEnvs[0].Definition.Spec.CpuArchitecture)
+		assert.Equal(t, "spoke-pull-secret", spoke.AdditionalInfraEnvs[0].Definition.Spec.PullSecretRef.Name)
+		assert.Equal(t, defaultWorkerAgents+testCase.count,
+			spoke.AgentClusterInstall.Definition.Spec.ProvisionRequirements.WorkerAgents)
+
+		spoke.WithHeterogeneousWorkers(testCase.arch, 1)
+		assert.Len(t, spoke.AdditionalInfraEnvs, 1)
+		assert.Equal(t, testCase.count+1, spoke.workerArchitectures[testCase.arch])
+	}
+
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithHeterogeneousWorkers(CPUArchitectureARM64, 1)
+	assert.EqualError(t, spoke.err,
+		"WithHeterogeneousWorkers: agentclusterinstall and infraenv must be defined before adding heterogeneous "+
+			"workers")
+}
+
+func TestAssignAgentRolesByArchitecture(t *testing.T) {
+	agents := []*agentInstallV1Beta1.Agent{
+		buildDummyInventoryAgent("agent-x86-2", "x86_64"),
+		buildDummyInventoryAgent("agent-x86-0", "x86_64"),
+		buildDummyInventoryAgent("agent-x86-1", "x86_64"),
+		buildDummyInventoryAgent("agent-x86-3", "x86_64"),
+		buildDummyInventoryAgent("agent-arm-1", "aarch64"),
+		buildDummyInventoryAgent("agent-arm-0", "aarch64"),
+		buildDummyInventoryAgent("agent-arm-2", "arm64"),
+	}
+
+	testCases := []struct {
+		roles         map[string]architectureRoles
+		expectedRoles map[string]models.HostRole
+		expectedErr   string
+	}{
+		{
+			roles: map[string]architectureRoles{
+				CPUArchitectureX86_64: {controlPlane: 3, workers: 1},
+				CPUArchitectureARM64:  {workers: 2},
+			},
+			expectedRoles: map[string]models.HostRole{
+				"agent-x86-0": models.HostRoleMaster,
+				"agent-x86-1": models.HostRoleMaster,
+				"agent-x86-2": models.HostRoleMaster,
+				"agent-x86-3": models.HostRoleWorker,
+				"agent-arm-0": models.HostRoleWorker,
+				"agent-arm-1": models.HostRoleWorker,
+			},
+		},
+		{
+			roles: map[string]architectureRoles{
+				CPUArchitectureX86_64: {controlPlane: 3},
+				CPUArchitectureARM64:  {workers: 4},
+			},
+			expectedErr: "4 arm64 agents are required but only 3 are registered",
+		},
+		{
+			roles: map[string]architectureRoles{
+				CPUArchitectureX86_64:  {controlPlane: 3},
+				CPUArchitecturePPC64LE: {workers: 1},
+			},
+			expectedErr: "1 ppc64le agents are required but only 0 are registered",
+		},
+	}
+
+	for _, testCase := range testCases {
+		roles, err := assignAgentRoles(agents, testCase.roles)
+
+		if testCase.expectedErr != "" {
+			assert.EqualError(t, err, testCase.expectedErr)
+
+			continue
+		}
+
+		assert.Nil(t, err)
+		assert.Equal(t, testCase.expectedRoles, roles)
+	}
+}
+
+func TestAssignAgentRoles(t *testing.T) {
+	apiClient := newTestClient(
+		buildDummyClusterImageSet("multi-imageset", "multi", "quay.io/ocp-release:4.16.0"),
+		buildDummyInfraEnvObject("spoke"),
+		buildDummyInfraEnvObject("spoke-arm64"),
+		buildDummyInventoryAgent("agent-x86-0", "x86_64"),
+		buildDummyInventoryAgent("agent-x86-1", "x86_64"),
+		buildDummyInventoryAgent("agent-x86-2", "x86_64"),
+		buildDummyInventoryAgent("agent-arm-0", "aarch64"),
+	)
+
+	spoke := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultIPv4AgentClusterInstall().WithDefaultInfraEnv()
+	spoke.AgentClusterInstall.WithImageSet("multi-imageset").WithWorkerAgents(0)
+	spoke.WithHeterogeneousWorkers(CPUArchitectureARM64, 1)
+	assert.Nil(t, spoke.err)
+
+	assert.Nil(t, spoke.AssignAgentRoles())
+
+	agent, err := assisted.PullAgent(apiClient, "agent-arm-0", "spoke")
+	assert.Nil(t, err)
+	assert.Equal(t, models.HostRoleWorker, agent.Object.Spec.Role)
+
+	agent, err = assisted.PullAgent(apiClient, "agent-x86-0", "spoke")
+	assert.Nil(t, err)
+	assert.Equal(t, models.HostRoleMaster, agent.Object.Spec.Role)
+}
+
+func buildDummyInventoryAgent(name, arch string) *agentInstallV1Beta1.Agent {
+	infraEnvName := "spoke"
+	if arch != CPUArchitectureX86_64 {
+		infraEnvName = "spoke-arm64"
+	}
+
+	return &agentInstallV1Beta1.Agent{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "spoke",
+			Labels:    map[string]string{"infraenvs.agent-install.openshift.io": infraEnvName},
+		},
+		Status: agentInstallV1Beta1.AgentStatus{
+			Inventory: agentInstallV1Beta1.HostInventory{Cpu: agentInstallV1Beta1.HostCPU{Architecture: arch}},
+		},
+	}
+}
+
+func buildDummyInfraEnvObject(name string) *agentInstallV1Beta1.InfraEnv {
+	return &agentInstallV1Beta1.InfraEnv{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "spoke"}}
+}
@@ -0,0 +1,111 @@
+package setup
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+
+	"github.com/hashicorp/go-version"
+)
+
+const (
+	ignitionOverrideMajorVersion = 3
+	ignitionOverrideFileMode     = 0o644
+)
+
+// WithIgnitionConfigOverride sets the ignition config override of the spoke infraenv, which is merged into the
+// discovery ignition. The override must be a JSON ignition config of version 3.x; IgnitionFilesOverride builds one
+// from file contents.
+func (spoke *SpokeClusterResources) WithIgnitionConfigOverride(override string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.InfraEnv == nil {
+		spoke.err = fmt.Errorf(
+			"WithIgnitionConfigOverride: infraenv must be defined before setting the ignition config override")
+
+		return spoke
+	}
+
+	if err := validateIgnitionOverride(override); err != nil {
+		spoke.err = fmt.Errorf("WithIgnitionConfigOverride: %w", err)
+
+		return spoke
+	}
+
+	spoke.InfraEnv.WithIgnitionConfigOverride(override)
+
+	return spoke
+}
+
+// IgnitionFilesOverride returns a minimal ignition config override writing each file, keyed by its absolute path,
+// with the provided contents.
+func IgnitionFilesOverride(files map[string]string) (string, error) {
+	if len(files) == 0 {
+		return "", fmt.Errorf("ignition files override requires at least one file")
+	}
+
+	paths := make([]string, 0, len(files))
+
+	for filePath := range files {
+		if !path.IsAbs(filePath) || path.Clean(filePath) != filePath {
+			return "", fmt.Errorf("ignition file path %q must be a clean absolute path", filePath)
+		}
+
+		paths = append(paths, filePath)
+	}
+
+	slices.Sort(paths)
+
+	ignitionFiles := make([]interface{}, 0, len(paths))
+
+	for _, filePath := range paths {
+		ignitionFiles = append(ignitionFiles, ignitionFile(filePath, files[filePath], ignitionOverrideFileMode))
+	}
+
+	encoded, err := json.Marshal(map[string]interface{}{
+		"ignition": map[string]interface{}{"version": ignitionOverrideVersion},
+		"storage":  map[string]interface{}{"files": ignitionFiles},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode ignition files override: %w", err)
+	}
+
+	return string(encoded), nil
+}
+
+// validateIgnitionOverride checks that override is a JSON ignition config with a 3.x version.
+func validateIgnitionOverride(override string) error {
+	var config struct {
+		Ignition struct {
+			Version string `json:"version"`
+		} `json:"ignition"`
+	}
+
+	if err := json.Unmarshal([]byte(override), &config); err != nil {
+		return fmt.Errorf("ignition config override is not valid JSON: %w", err)
+	}
+
+	ignitionVersion, err := version.NewVersion(config.Ignition.Version)
+	if err != nil || ignitionVersion.Segments()[0] != ignitionOverrideMajorVersion {
+		return fmt.Errorf("ignition config override version %q must be %d.x",
+			config.Ignition.Version, ignitionOverrideMajorVersion)
+	}
+
+	return nil
+}
+
+// ignitionFile returns an ignition storage file overwriting path with contents, encoded as a data URL.
+func ignitionFile(filePath, contents string, mode int) map[string]interface{} {
+	return map[string]interface{}{
+		"path":      filePath,
+		"mode":      mode,
+		"overwrite": true,
+		"contents": map[string]interface{}{
+			"source": "data:text/plain;charset=utf-8;base64," + base64.StdEncoding.EncodeToString([]byte(contents)),
+		},
+	}
+}
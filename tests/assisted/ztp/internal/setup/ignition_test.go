@@ -0,0 +1,80 @@
+package setup
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithIgnitionConfigOverride(t *testing.T) {
+	testCases := []struct {
+		override      string
+		expectedError string
+	}{
+		{override: `{"ignition":{"version":"3.2.0"}}`},
+		{override: `{"ignition":{"version":"3.4.0"},"storage":{"files":[]}}`},
+		{override: `{"ignition":`, expectedError: "ignition config override is not valid JSON"},
+		{
+			override:      `{"ignition":{"version":"2.2.0"}}`,
+			expectedError: `ignition config override version "2.2.0" must be 3.x`,
+		},
+		{override: `{"storage":{}}`, expectedError: `ignition config override version "" must be 3.x`},
+	}
+
+	for _, testCase := range testCases {
+		spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultInfraEnv().
+			WithIgnitionConfigOverride(testCase.override)
+
+		if testCase.expectedError != "" {
+			assert.ErrorContains(t, spoke.err, testCase.expectedError)
+			assert.Empty(t, spoke.InfraEnv.Definition.Spec.IgnitionConfigOverride)
+
+			continue
+		}
+
+		assert.Nil(t, spoke.err)
+		assert.Equal(t, testCase.override, spoke.InfraEnv.Definition.Spec.IgnitionConfigOverride)
+	}
+
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").
+		WithIgnitionConfigOverride(`{"ignition":{"version":"3.2.0"}}`)
+	assert.EqualError(t, spoke.err,
+		"WithIgnitionConfigOverride: infraenv must be defined before setting the ignition config override")
+}
+
+func TestIgnitionFilesOverride(t *testing.T) {
+	override, err := IgnitionFilesOverride(map[string]string{
+		"/usr/local/bin/test.sh":                       "#!/bin/bash\necho test\n",
+		"/etc/pki/ca-trust/source/anchors/test-ca.crt": "certificate",
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, validateIgnitionOverride(override))
+
+	var config struct {
+		Storage struct {
+			Files []struct {
+				Path     string `json:"path"`
+				Mode     int    `json:"mode"`
+				Contents struct {
+					Source string `json:"source"`
+				} `json:"contents"`
+			} `json:"files"`
+		} `json:"storage"`
+	}
+
+	assert.Nil(t, json.Unmarshal([]byte(override), &config))
+	assert.Len(t, config.Storage.Files, 2)
+	assert.Equal(t, "/etc/pki/ca-trust/source/anchors/test-ca.crt", config.Storage.Files[0].Path)
+	assert.Equal(t, "/usr/local/bin/test.sh", config.Storage.Files[1].Path)
+	assert.Equal(t, ignitionOverrideFileMode, config.Storage.Files[1].Mode)
+	assert.Equal(t, "data:text/plain;charset=utf-8;base64,"+base64.StdEncoding.EncodeToString(
+		[]byte("#!/bin/bash\necho test\n")), config.Storage.Files[1].Contents.Source)
+
+	_, err = IgnitionFilesOverride(nil)
+	assert.EqualError(t, err, "ignition files override requires at least one file")
+
+	_, err = IgnitionFilesOverride(map[string]string{"etc/test": "test"})
+	assert.EqualError(t, err, `ignition file path "etc/test" must be a clean absolute path`)
+}
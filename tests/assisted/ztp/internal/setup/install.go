@@ -0,0 +1,250 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	assistedHiveV1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/hive/api/v1"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	runtimeClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// installPhase is a point of the spoke installation that can be waited on.
+type installPhase string
+
+const (
+	installPhaseStarted   installPhase = "start"
+	installPhaseCompleted installPhase = "complete"
+)
+
+// WaitForInstallStarted waits up to timeout, or the spoke wait timeout when it is 0, until the installation of the
+// spoke agentclusterinstall is in progress or has completed. It returns early when the installation fails or stops,
+// or is held. Like WaitForInstallCompleted, it records the installation states it observes in the spoke timings.
+func (spoke *SpokeClusterResources) WaitForInstallStarted(timeout time.Duration) error {
+	return spoke.WaitForInstallStartedWithContext(context.Background(), timeout)
+}
+
+// WaitForInstallStartedWithContext waits for the installation to be in progress like WaitForInstallStarted,
+// stopping the wait when ctx is done.
+func (spoke *SpokeClusterResources) WaitForInstallStartedWithContext(ctx context.Context, timeout time.Duration) error {
+	return spoke.waitForInstall(ctx, installPhaseStarted, timeout)
+}
+
+// WaitForInstallCompleted waits up to timeout, or the spoke wait timeout when it is 0, until the spoke
+// agentclusterinstall reports the installation completed and the clusterdeployment is marked installed. It returns
+// early when the installation fails, stops or is held, and logs the installation progress each time it changes. Each
+// state of the agentclusterinstall debug info observed is recorded in the spoke timings as an "install state <state>"
+// phase, from when it was first observed until it changed or the wait ended.
+func (spoke *SpokeClusterResources) WaitForInstallCompleted(timeout time.Duration) error {
+	return spoke.WaitForInstallCompletedWithContext(context.Background(), timeout)
+}
+
+// WaitForInstallCompletedWithContext waits for the installation to complete like WaitForInstallCompleted,
+// stopping the wait when ctx is done.
+func (spoke *SpokeClusterResources) WaitForInstallCompletedWithContext(
+	ctx context.Context, timeout time.Duration) error {
+	return spoke.waitForInstall(ctx, installPhaseCompleted, timeout)
+}
+
+// WithHoldInstallation holds the installation of the spoke agentclusterinstall, so that agents can be changed
+// between their discovery and the installation. UnholdInstallation starts the installation once the spoke is
+// created.
+func (spoke *SpokeClusterResources) WithHoldInstallation() *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.AgentClusterInstall == nil {
+		spoke.err = fmt.Errorf(
+			"WithHoldInstallation: agentclusterinstall must be defined before holding the installation")
+
+		return spoke
+	}
+
+	spoke.AgentClusterInstall.Definition.Spec.HoldInstallation = true
+
+	return spoke
+}
+
+// UnholdInstallation patches the spoke agentclusterinstall on the hub to release an installation held by
+// WithHoldInstallation. It returns an error when the agentclusterinstall has not been created.
+func (spoke *SpokeClusterResources) UnholdInstallation() error {
+	if spoke.AgentClusterInstall == nil {
+		return fmt.Errorf("agentclusterinstall must be defined before releasing the installation")
+	}
+
+	if !spoke.AgentClusterInstall.Exists() {
+		return fmt.Errorf(
+			"agentclusterinstall of spoke %s must be created before releasing the installation", spoke.Name)
+	}
+
+	err := spoke.apiClient.Patch(context.TODO(), spoke.AgentClusterInstall.Object,
+		runtimeClient.RawPatch(types.MergePatchType, []byte(`{"spec":{"holdInstallation":false}}`)))
+	if err != nil {
+		return fmt.Errorf("failed to release the installation of spoke %s: %w", spoke.Name, err)
+	}
+
+	spoke.AgentClusterInstall.Definition.Spec.HoldInstallation = false
+
+	return nil
+}
+
+// waitForInstall polls the spoke agentclusterinstall until the installation reaches phase.
+func (spoke *SpokeClusterResources) waitForInstall(
+	ctx context.Context, phase installPhase, timeout time.Duration) error {
+	if spoke.AgentClusterInstall == nil {
+		return fmt.Errorf("agentclusterinstall must be defined before waiting for the installation")
+	}
+
+	if phase == installPhaseCompleted && spoke.ClusterDeployment == nil {
+		return fmt.Errorf("clusterdeployment must be defined before waiting for the installation to complete")
+	}
+
+	options := spoke.resolveWaitOptions()
+
+	if timeout > 0 {
+		options.Timeout = timeout
+		options.Interval = min(options.Interval, timeout)
+	}
+
+	var (
+		progress     int64 = -1
+		installErr   error
+		pending      string
+		installState string
+		stateStart   time.Time
+		waitPhase    = "wait for install " + string(phase)
+	)
+
+	err := spoke.poll(ctx, waitPhase, options, func(ctx context.Context) (bool, error) {
+		agentClusterInstall, err := spoke.AgentClusterInstall.Get()
+		if err != nil {
+			glog.V(ztpparams.ZTPLogLevel).Infof(
+				"Failed to get agentclusterinstall of spoke %s: %v", spoke.Name, err)
+
+			pending = err.Error()
+
+			return false, nil
+		}
+
+		if state := agentClusterInstall.Status.DebugInfo.State; state != installState {
+			if installState != "" {
+				spoke.recordPhase("install state "+installState, stateStart, 0, nil)
+			}
+
+			installState, stateStart = state, time.Now()
+		}
+
+		if percentage := agentClusterInstall.Status.Progress.TotalPercentage; percentage != progress {
+			progress = percentage
+
+			glog.V(ztpparams.ZTPLogLevel).Infof("Installation of spoke %s is %d%% complete, state %s: %s",
+				spoke.Name, percentage, agentClusterInstall.Status.DebugInfo.State,
+				agentClusterInstall.Status.DebugInfo.StateInfo)
+		}
+
+		var reached bool
+
+		reached, installErr = spoke.installPhaseReached(agentClusterInstall, phase, &pending)
+
+		return reached, installErr
+	})
+
+	if installState != "" {
+		spoke.recordPhase("install state "+installState, stateStart, 0, installErr)
+	}
+
+	if installErr != nil {
+		return installErr
+	}
+
+	if err != nil {
+		return fmt.Errorf("timed out waiting for the installation of spoke %s to %s: %s", spoke.Name, phase, pending)
+	}
+
+	return nil
+}
+
+// installPhaseReached returns true when the installation of agentClusterInstall reached phase, setting pending to
+// the reason it did not otherwise. An error is returned when the installation is held, failed or stopped.
+func (spoke *SpokeClusterResources) installPhaseReached(
+	agentClusterInstall *v1beta1.AgentClusterInstall, phase installPhase, pending *string) (bool, error) {
+	if agentClusterInstall.Spec.HoldInstallation {
+		return false, fmt.Errorf("installation of spoke %s is held, UnholdInstallation must be called first",
+			spoke.Name)
+	}
+
+	if err := installFailure(spoke.Name, agentClusterInstall); err != nil {
+		return false, err
+	}
+
+	completed := findClusterInstallCondition(agentClusterInstall, v1beta1.ClusterCompletedCondition)
+	if completed == nil {
+		*pending = fmt.Sprintf("agentclusterinstall has no %s condition", v1beta1.ClusterCompletedCondition)
+
+		return false, nil
+	}
+
+	*pending = fmt.Sprintf("agentclusterinstall condition %s is %s with reason %s: %s",
+		completed.Type, completed.Status, completed.Reason, completed.Message)
+
+	if completed.Status == corev1.ConditionTrue {
+		return phase == installPhaseStarted || spoke.clusterDeploymentInstalled(pending), nil
+	}
+
+	return phase == installPhaseStarted && completed.Reason == v1beta1.ClusterInstallationInProgressReason, nil
+}
+
+// clusterDeploymentInstalled returns true when the spoke clusterdeployment is marked installed, setting pending to
+// the reason it is not otherwise.
+func (spoke *SpokeClusterResources) clusterDeploymentInstalled(pending *string) bool {
+	clusterDeployment, err := spoke.ClusterDeployment.Get()
+	if err != nil {
+		*pending = fmt.Sprintf("failed to get clusterdeployment: %v", err)
+
+		return false
+	}
+
+	if !clusterDeployment.Spec.Installed {
+		*pending = fmt.Sprintf("clusterdeployment %s is not marked installed", clusterDeployment.Name)
+
+		return false
+	}
+
+	return true
+}
+
+// installFailure returns an error describing the failure when the installation of the agentclusterinstall failed
+// or stopped without completing, or nil otherwise.
+func installFailure(spokeName string, agentClusterInstall *v1beta1.AgentClusterInstall) error {
+	condition := findClusterInstallCondition(agentClusterInstall, v1beta1.ClusterFailedCondition)
+	if condition == nil || condition.Status != corev1.ConditionTrue {
+		condition = findClusterInstallCondition(agentClusterInstall, v1beta1.ClusterStoppedCondition)
+		if condition == nil || condition.Status != corev1.ConditionTrue ||
+			condition.Reason == v1beta1.ClusterStoppedCompletedReason {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("installation of spoke %s failed with reason %s: %s, state %s: %s",
+		spokeName, condition.Reason, condition.Message,
+		agentClusterInstall.Status.DebugInfo.State, agentClusterInstall.Status.DebugInfo.StateInfo)
+}
+
+// findClusterInstallCondition returns the condition of condType of the agentclusterinstall, or nil when it is not
+// set.
+func findClusterInstallCondition(agentClusterInstall *v1beta1.AgentClusterInstall,
+	condType assistedHiveV1.ClusterInstallConditionType) *assistedHiveV1.ClusterInstallCondition {
+	for index := range agentClusterInstall.Status.Conditions {
+		if agentClusterInstall.Status.Conditions[index].Type == condType {
+			return &agentClusterInstall.Status.Conditions[index]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,252 @@
+package setup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	assistedHiveV1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/hive/api/v1"
+	hivev1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/hive/api/v1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	runtimeClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestWaitForInstall(t *testing.T) {
+	inProgress := assistedHiveV1.ClusterInstallCondition{
+		Type:    v1beta1.ClusterCompletedCondition,
+		Status:  corev1.ConditionFalse,
+		Reason:  v1beta1.ClusterInstallationInProgressReason,
+		Message: "The installation is in progress: Installation in progress",
+	}
+	completed := assistedHiveV1.ClusterInstallCondition{
+		Type:   v1beta1.ClusterCompletedCondition,
+		Status: corev1.ConditionTrue,
+		Reason: v1beta1.ClusterInstalledReason,
+	}
+	notStarted := assistedHiveV1.ClusterInstallCondition{
+		Type:    v1beta1.ClusterCompletedCondition,
+		Status:  corev1.ConditionFalse,
+		Reason:  v1beta1.ClusterInstallationNotStartedReason,
+		Message: "The installation has not yet started",
+	}
+	failed := assistedHiveV1.ClusterInstallCondition{
+		Type:    v1beta1.ClusterFailedCondition,
+		Status:  corev1.ConditionTrue,
+		Reason:  v1beta1.ClusterFailedReason,
+		Message: "The installation failed: cluster has hosts in error",
+	}
+	cancelled := assistedHiveV1.ClusterInstallCondition{
+		Type:    v1beta1.ClusterStoppedCondition,
+		Status:  corev1.ConditionTrue,
+		Reason:  v1beta1.ClusterStoppedCanceledReason,
+		Message: v1beta1.ClusterStoppedCanceledMsg,
+	}
+	stoppedCompleted := assistedHiveV1.ClusterInstallCondition{
+		Type:   v1beta1.ClusterStoppedCondition,
+		Status: corev1.ConditionTrue,
+		Reason: v1beta1.ClusterStoppedCompletedReason,
+	}
+
+	testCases := []struct {
+		name              string
+		conditions        []assistedHiveV1.ClusterInstallCondition
+		installed         bool
+		held              bool
+		expectedStarted   string
+		expectedCompleted string
+	}{
+		{
+			name:       "in progress",
+			conditions: []assistedHiveV1.ClusterInstallCondition{inProgress},
+			expectedCompleted: "timed out waiting for the installation of spoke spoke to complete: " +
+				"agentclusterinstall condition Completed is False with reason InstallationInProgress: " +
+				"The installation is in progress: Installation in progress",
+		},
+		{
+			name:       "completed",
+			conditions: []assistedHiveV1.ClusterInstallCondition{completed, stoppedCompleted},
+			installed:  true,
+		},
+		{
+			name:       "completed but clusterdeployment not installed",
+			conditions: []assistedHiveV1.ClusterInstallCondition{completed},
+			expectedCompleted: "timed out waiting for the installation of spoke spoke to complete: " +
+				"clusterdeployment spoke is not marked installed",
+		},
+		{
+			name:       "not started",
+			conditions: []assistedHiveV1.ClusterInstallCondition{notStarted},
+			expectedStarted: "timed out waiting for the installation of spoke spoke to start: " +
+				"agentclusterinstall condition Completed is False with reason InstallationNotStarted: " +
+				"The installation has not yet started",
+			expectedCompleted: "timed out waiting for the installation of spoke spoke to complete: " +
+				"agentclusterinstall condition Completed is False with reason InstallationNotStarted: " +
+				"The installation has not yet started",
+		},
+		{
+			name:       "failed",
+			conditions: []assistedHiveV1.ClusterInstallCondition{inProgress, failed},
+			expectedStarted: "installation of spoke spoke failed with reason InstallationFailed: " +
+				"The installation failed: cluster has hosts in error, state error: cluster has hosts in error",
+			expectedCompleted: "installation of spoke spoke failed with reason InstallationFailed: " +
+				"The installation failed: cluster has hosts in error, state error: cluster has hosts in error",
+		},
+		{
+			name:       "cancelled",
+			conditions: []assistedHiveV1.ClusterInstallCondition{inProgress, cancelled},
+			expectedStarted: "installation of spoke spoke failed with reason InstallationCancelled: " +
+				"The installation has stopped because it was cancelled, state error: cluster has hosts in error",
+			expectedCompleted: "installation of spoke spoke failed with reason InstallationCancelled: " +
+				"The installation has stopped because it was cancelled, state error: cluster has hosts in error",
+		},
+		{
+			name:              "held",
+			conditions:        []assistedHiveV1.ClusterInstallCondition{notStarted},
+			held:              true,
+			expectedStarted:   "installation of spoke spoke is held, UnholdInstallation must be called first",
+			expectedCompleted: "installation of spoke spoke is held, UnholdInstallation must be called first",
+		},
+	}
+
+	for _, testCase := range testCases {
+		agentClusterInstall := &v1beta1.AgentClusterInstall{
+			ObjectMeta: metav1.ObjectMeta{Name: "spoke", Namespace: "spoke"},
+			Spec:       v1beta1.AgentClusterInstallSpec{HoldInstallation: testCase.held},
+			Status: v1beta1.AgentClusterInstallStatus{
+				Conditions: testCase.conditions,
+				Progress:   v1beta1.ClusterProgressInfo{TotalPercentage: 42},
+				DebugInfo:  v1beta1.DebugInfo{State: "error", StateInfo: "cluster has hosts in error"},
+			},
+		}
+		clusterDeployment := &hivev1.ClusterDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "spoke", Namespace: "spoke"},
+			Spec:       hivev1.ClusterDeploymentSpec{Installed: testCase.installed},
+		}
+
+		spoke := NewSpokeCluster(newTestClient(agentClusterInstall, clusterDeployment)).WithName("spoke").
+			WithDefaultClusterDeployment().WithDefaultIPv4AgentClusterInstall().
+			WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Second})
+
+		assertWaitError(t, testCase.expectedStarted, spoke.WaitForInstallStarted(10*time.Millisecond), testCase.name)
+		assertWaitError(t, testCase.expectedCompleted, spoke.WaitForInstallCompleted(10*time.Millisecond),
+			testCase.name)
+	}
+
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke")
+	assert.EqualError(t, spoke.WaitForInstallStarted(0),
+		"agentclusterinstall must be defined before waiting for the installation")
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultIPv4AgentClusterInstall()
+	assert.EqualError(t, spoke.WaitForInstallCompleted(0),
+		"clusterdeployment must be defined before waiting for the installation to complete")
+}
+
+func TestWaitForInstallCompletedStateTimings(t *testing.T) {
+	states := []string{"preparing-for-installation", "installing", "installing", "finalizing", "adding-hosts"}
+	gets := 0
+
+	apiClient, testBuilder := clients.GetModifiableTestClients(clients.TestClientParams{
+		K8sMockObjects: []runtime.Object{&hivev1.ClusterDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "spoke", Namespace: "spoke"},
+			Spec:       hivev1.ClusterDeploymentSpec{Installed: true},
+		}},
+		SchemeAttachers: []clients.SchemeAttacher{v1beta1.AddToScheme, hivev1.AddToScheme},
+	})
+
+	apiClient.Client = testBuilder.WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, client runtimeClient.WithWatch, key runtimeClient.ObjectKey,
+			obj runtimeClient.Object, opts ...runtimeClient.GetOption) error {
+			agentClusterInstall, isAgentClusterInstall := obj.(*v1beta1.AgentClusterInstall)
+			if !isAgentClusterInstall {
+				return client.Get(ctx, key, obj, opts...)
+			}
+
+			state := states[min(gets, len(states)-1)]
+			gets++
+
+			agentClusterInstall.ObjectMeta = metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}
+			agentClusterInstall.Status.DebugInfo.State = state
+			agentClusterInstall.Status.Conditions = []assistedHiveV1.ClusterInstallCondition{{
+				Type:   v1beta1.ClusterCompletedCondition,
+				Status: corev1.ConditionFalse,
+				Reason: v1beta1.ClusterInstallationInProgressReason,
+			}}
+
+			if state == "adding-hosts" {
+				agentClusterInstall.Status.Conditions[0].Status = corev1.ConditionTrue
+				agentClusterInstall.Status.Conditions[0].Reason = v1beta1.ClusterInstalledReason
+			}
+
+			return nil
+		},
+	}).Build()
+
+	spoke := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultClusterDeployment().
+		WithDefaultIPv4AgentClusterInstall().
+		WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Second})
+
+	assert.Nil(t, spoke.WaitForInstallCompleted(0))
+
+	var phases []string
+
+	for _, timing := range spoke.GetTimings().Phases {
+		phases = append(phases, timing.Phase)
+
+		assert.False(t, timing.End.Before(timing.Start), timing.Phase)
+	}
+
+	assert.Equal(t, []string{
+		"install state preparing-for-installation",
+		"install state installing",
+		"install state finalizing",
+		"wait for install complete",
+		"install state adding-hosts",
+	}, phases)
+}
+
+func TestHoldInstallation(t *testing.T) {
+	spoke := StandardHAProfile(newHubTestClient(), "hold-spoke").WithHoldInstallation()
+	assert.Nil(t, spoke.err)
+	assert.True(t, spoke.AgentClusterInstall.Definition.Spec.HoldInstallation)
+
+	assert.EqualError(t, spoke.UnholdInstallation(),
+		"agentclusterinstall of spoke hold-spoke must be created before releasing the installation")
+
+	_, err := spoke.Create()
+	assert.Nil(t, err)
+
+	agentClusterInstall, err := spoke.AgentClusterInstall.Get()
+	assert.Nil(t, err)
+	assert.True(t, agentClusterInstall.Spec.HoldInstallation)
+
+	assert.Nil(t, spoke.UnholdInstallation())
+
+	agentClusterInstall, err = spoke.AgentClusterInstall.Get()
+	assert.Nil(t, err)
+	assert.False(t, agentClusterInstall.Spec.HoldInstallation)
+	assert.False(t, spoke.AgentClusterInstall.Definition.Spec.HoldInstallation)
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithHoldInstallation()
+	assert.EqualError(t, spoke.err,
+		"WithHoldInstallation: agentclusterinstall must be defined before holding the installation")
+	assert.EqualError(t, spoke.UnholdInstallation(),
+		"agentclusterinstall must be defined before releasing the installation")
+}
+
+func assertWaitError(t *testing.T, expected string, err error, name string) {
+	t.Helper()
+
+	if expected == "" {
+		assert.Nil(t, err, name)
+
+		return
+	}
+
+	assert.EqualError(t, err, expected, name)
+}
@@ -0,0 +1,172 @@
+package setup
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// InstallConfigOverridesAnnotation is the agentclusterinstall annotation holding the install-config overrides.
+const InstallConfigOverridesAnnotation = "agent-install.openshift.io/install-config-overrides"
+
+const (
+	// CPUPartitioningAllNodes is the install-config cpuPartitioningMode enabling workload partitioning on all nodes.
+	CPUPartitioningAllNodes = "AllNodes"
+
+	// CapabilitySetNone is the install-config baselineCapabilitySet enabling no optional capability.
+	CapabilitySetNone = "None"
+	// CapabilitySetCurrent is the install-config baselineCapabilitySet enabling the optional capabilities of the
+	// installed release.
+	CapabilitySetCurrent = "vCurrent"
+
+	networkTypeOVNKubernetes = "OVNKubernetes"
+	networkTypeOpenShiftSDN  = "OpenShiftSDN"
+)
+
+// capabilitySetRegexp matches the install-config baselineCapabilitySet values.
+var capabilitySetRegexp = regexp.MustCompile(`^(None|vCurrent|v4\.\d+)$`)
+
+// WithInstallConfigOverride merges the install-config override, a JSON object, into the install-config overrides
+// annotation of the agentclusterinstall. Nested objects are merged key by key while other values, including arrays,
+// replace the existing ones, so overrides can be stacked.
+func (spoke *SpokeClusterResources) WithInstallConfigOverride(override string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.AgentClusterInstall == nil {
+		spoke.err = fmt.Errorf(
+			"WithInstallConfigOverride: agentclusterinstall must be defined before overriding the install-config")
+
+		return spoke
+	}
+
+	var overrideObject map[string]interface{}
+
+	if err := json.Unmarshal([]byte(override), &overrideObject); err != nil || overrideObject == nil {
+		spoke.err = fmt.Errorf("WithInstallConfigOverride: install-config override must be a JSON object: %q", override)
+
+		return spoke
+	}
+
+	annotations := spoke.AgentClusterInstall.Definition.Annotations
+	merged := map[string]interface{}{}
+
+	if existing, found := annotations[InstallConfigOverridesAnnotation]; found && existing != "" {
+		if err := json.Unmarshal([]byte(existing), &merged); err != nil || merged == nil {
+			spoke.err = fmt.Errorf(
+				"WithInstallConfigOverride: existing install-config overrides are not a JSON object: %q", existing)
+
+			return spoke
+		}
+	}
+
+	mergeInstallConfigOverride(merged, overrideObject)
+
+	mergedJSON, err := json.Marshal(merged)
+	if err != nil {
+		spoke.err = fmt.Errorf("WithInstallConfigOverride: failed to marshal install-config overrides: %w", err)
+
+		return spoke
+	}
+
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	annotations[InstallConfigOverridesAnnotation] = string(mergedJSON)
+	spoke.AgentClusterInstall.Definition.Annotations = annotations
+
+	return spoke
+}
+
+// WithInstallConfigOverrides merges overrides into the install-config overrides annotation of the
+// agentclusterinstall, in the same way as WithInstallConfigOverride, for callers building the overrides as a map.
+func (spoke *SpokeClusterResources) WithInstallConfigOverrides(
+	overrides map[string]interface{}) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	overridesJSON, err := json.Marshal(overrides)
+	if err != nil {
+		spoke.err = fmt.Errorf("WithInstallConfigOverrides: failed to marshal install-config overrides: %w", err)
+
+		return spoke
+	}
+
+	return spoke.WithInstallConfigOverride(string(overridesJSON))
+}
+
+// WithFIPS enables or disables FIPS mode through the install-config fips field.
+func (spoke *SpokeClusterResources) WithFIPS(enabled bool) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	return spoke.WithInstallConfigOverrides(map[string]interface{}{"fips": enabled})
+}
+
+// WithCapabilities trims the optional capabilities of the spoke through the install-config capabilities, enabling
+// the baseline capability set, None, vCurrent or a release such as v4.14, and the additional capabilities on top
+// of it.
+func (spoke *SpokeClusterResources) WithCapabilities(baseline string, additional []string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if !capabilitySetRegexp.MatchString(baseline) {
+		spoke.err = fmt.Errorf("WithCapabilities: invalid baseline capability set %q", baseline)
+
+		return spoke
+	}
+
+	capabilities := map[string]interface{}{"baselineCapabilitySet": baseline}
+
+	if len(additional) > 0 {
+		capabilities["additionalEnabledCapabilities"] = additional
+	}
+
+	return spoke.WithInstallConfigOverrides(map[string]interface{}{"capabilities": capabilities})
+}
+
+// WithCPUPartitioning enables workload partitioning on all nodes through the install-config cpuPartitioningMode.
+func (spoke *SpokeClusterResources) WithCPUPartitioning() *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	return spoke.WithInstallConfigOverride(fmt.Sprintf(`{"cpuPartitioningMode":%q}`, CPUPartitioningAllNodes))
+}
+
+// WithInstallConfigNetworkType overrides the install-config networking networkType, which is either OVNKubernetes
+// or OpenShiftSDN.
+func (spoke *SpokeClusterResources) WithInstallConfigNetworkType(networkType string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if networkType != networkTypeOVNKubernetes && networkType != networkTypeOpenShiftSDN {
+		spoke.err = fmt.Errorf("WithInstallConfigNetworkType: unsupported install-config network type %q", networkType)
+
+		return spoke
+	}
+
+	return spoke.WithInstallConfigOverride(fmt.Sprintf(`{"networking":{"networkType":%q}}`, networkType))
+}
+
+// mergeInstallConfigOverride merges override into overrides, merging nested objects and replacing other values.
+func mergeInstallConfigOverride(overrides, override map[string]interface{}) {
+	for key, value := range override {
+		overrideObject, isObject := value.(map[string]interface{})
+		existingObject, existingIsObject := overrides[key].(map[string]interface{})
+
+		if isObject && existingIsObject {
+			mergeInstallConfigOverride(existingObject, overrideObject)
+
+			continue
+		}
+
+		overrides[key] = value
+	}
+}
@@ -0,0 +1,112 @@
+package setup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstallConfigOverrides(t *testing.T) {
+	testCases := []struct {
+		name     string
+		override func(spoke *SpokeClusterResources) *SpokeClusterResources
+		expected string
+	}{
+		{
+			name: "cpu partitioning",
+			override: func(spoke *SpokeClusterResources) *SpokeClusterResources {
+				return spoke.WithCPUPartitioning()
+			},
+			expected: `{"cpuPartitioningMode":"AllNodes"}`,
+		},
+		{
+			name: "network type",
+			override: func(spoke *SpokeClusterResources) *SpokeClusterResources {
+				return spoke.WithInstallConfigNetworkType("OVNKubernetes")
+			},
+			expected: `{"networking":{"networkType":"OVNKubernetes"}}`,
+		},
+		{
+			name: "stacked with fips and capabilities",
+			override: func(spoke *SpokeClusterResources) *SpokeClusterResources {
+				return spoke.WithInstallConfigOverride(`{"fips":true}`).
+					WithCPUPartitioning().
+					WithInstallConfigOverride(`{"capabilities":{"baselineCapabilitySet":"None"}}`).
+					WithInstallConfigNetworkType("OpenShiftSDN").
+					WithInstallConfigOverride(`{"networking":{"machineNetwork":[{"cidr":"192.168.254.0/24"}]}}`)
+			},
+			expected: `{"capabilities":{"baselineCapabilitySet":"None"},"cpuPartitioningMode":"AllNodes","fips":true,` +
+				`"networking":{"machineNetwork":[{"cidr":"192.168.254.0/24"}],"networkType":"OpenShiftSDN"}}`,
+		},
+		{
+			name: "fips and capabilities conveniences",
+			override: func(spoke *SpokeClusterResources) *SpokeClusterResources {
+				return spoke.WithFIPS(true).WithCapabilities(CapabilitySetNone, []string{"marketplace"}).
+					WithInstallConfigOverrides(map[string]interface{}{
+						"capabilities": map[string]interface{}{"baselineCapabilitySet": "v4.14"},
+					})
+			},
+			expected: `{"capabilities":{"additionalEnabledCapabilities":["marketplace"],` +
+				`"baselineCapabilitySet":"v4.14"},"fips":true}`,
+		},
+		{
+			name: "capabilities without additional capabilities",
+			override: func(spoke *SpokeClusterResources) *SpokeClusterResources {
+				return spoke.WithFIPS(true).WithFIPS(false).WithCapabilities(CapabilitySetCurrent, nil)
+			},
+			expected: `{"capabilities":{"baselineCapabilitySet":"vCurrent"},"fips":false}`,
+		},
+		{
+			name: "later override wins",
+			override: func(spoke *SpokeClusterResources) *SpokeClusterResources {
+				return spoke.WithInstallConfigNetworkType("OpenShiftSDN").WithInstallConfigNetworkType("OVNKubernetes").
+					WithInstallConfigOverride(`{"capabilities":{"additionalEnabledCapabilities":["marketplace"]}}`).
+					WithInstallConfigOverride(`{"capabilities":{"additionalEnabledCapabilities":["Console"]}}`)
+			},
+			expected: `{"capabilities":{"additionalEnabledCapabilities":["Console"]},` +
+				`"networking":{"networkType":"OVNKubernetes"}}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultIPv4AgentClusterInstall()
+		spoke = testCase.override(spoke)
+
+		assert.Nil(t, spoke.err, testCase.name)
+		assert.JSONEq(t, testCase.expected,
+			spoke.AgentClusterInstall.Definition.Annotations[InstallConfigOverridesAnnotation], testCase.name)
+	}
+}
+
+func TestInstallConfigOverrideErrors(t *testing.T) {
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithCPUPartitioning()
+	assert.EqualError(t, spoke.err,
+		"WithInstallConfigOverride: agentclusterinstall must be defined before overriding the install-config")
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultIPv4AgentClusterInstall().
+		WithInstallConfigOverride(`["fips"]`)
+	assert.EqualError(t, spoke.err,
+		`WithInstallConfigOverride: install-config override must be a JSON object: "[\"fips\"]"`)
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultIPv4AgentClusterInstall().
+		WithInstallConfigNetworkType("Calico")
+	assert.EqualError(t, spoke.err, `WithInstallConfigNetworkType: unsupported install-config network type "Calico"`)
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultIPv4AgentClusterInstall().
+		WithCapabilities("v5", nil)
+	assert.EqualError(t, spoke.err, `WithCapabilities: invalid baseline capability set "v5"`)
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultIPv4AgentClusterInstall().
+		WithInstallConfigOverrides(nil)
+	assert.EqualError(t, spoke.err, `WithInstallConfigOverride: install-config override must be a JSON object: "null"`)
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultIPv4AgentClusterInstall().
+		WithInstallConfigOverrides(map[string]interface{}{"fips": make(chan int)})
+	assert.ErrorContains(t, spoke.err, "WithInstallConfigOverrides: failed to marshal install-config overrides")
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultIPv4AgentClusterInstall()
+	spoke.AgentClusterInstall.Definition.Annotations = map[string]string{InstallConfigOverridesAnnotation: "fips"}
+	spoke.WithCPUPartitioning()
+	assert.EqualError(t, spoke.err,
+		`WithInstallConfigOverride: existing install-config overrides are not a JSON object: "fips"`)
+}
@@ -0,0 +1,147 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/golang/glog"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+	conditionsv1 "github.com/openshift/custom-resource-status/conditions/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WaitForDiscoveryISO waits up to timeout, or the spoke wait timeout when it is 0, until the discovery image of the
+// spoke infraenv is created and returns its download URL. When an ISO boot method was selected with WithBootMethod,
+// the image type of the URL is set to it, see GetISODownloadURL. An image regenerated during the wait, because the
+// infraenv was updated, is only considered ready once its creation time is stable between two polls. On timeout, the
+// error contains the ImageCreated condition message.
+func (spoke *SpokeClusterResources) WaitForDiscoveryISO(timeout time.Duration) (string, error) {
+	return spoke.WaitForDiscoveryISOWithContext(context.Background(), timeout)
+}
+
+// WaitForDiscoveryISOWithContext waits for the discovery image of the spoke infraenv like WaitForDiscoveryISO,
+// stopping the wait when ctx is done.
+func (spoke *SpokeClusterResources) WaitForDiscoveryISOWithContext(
+	ctx context.Context, timeout time.Duration) (string, error) {
+	if spoke.InfraEnv == nil {
+		return "", fmt.Errorf("infraenv must be defined before waiting for the discovery iso")
+	}
+
+	options := spoke.resolveWaitOptions()
+
+	if timeout > 0 {
+		options.Timeout = timeout
+		options.Interval = min(options.Interval, timeout)
+	}
+
+	var (
+		infraEnv    *agentInstallV1Beta1.InfraEnv
+		createdTime *metav1.Time
+		getErr      error
+	)
+
+	err := spoke.poll(ctx, "wait for discovery iso", options, func(ctx context.Context) (bool, error) {
+		infraEnv, getErr = spoke.InfraEnv.Get()
+		if getErr != nil {
+			glog.V(ztpparams.ZTPLogLevel).Infof("Failed to get infraenv of spoke %s: %v", spoke.Name, getErr)
+
+			return false, nil
+		}
+
+		spoke.InfraEnv.Object = infraEnv
+
+		previousCreatedTime := createdTime
+		createdTime = infraEnv.Status.CreatedTime
+
+		if previousCreatedTime != nil && createdTime != nil && !previousCreatedTime.Equal(createdTime) {
+			glog.V(ztpparams.ZTPLogLevel).Infof("Discovery iso of infraenv %s was regenerated at %s",
+				infraEnv.Name, createdTime.UTC().Format(time.RFC3339))
+
+			return false, nil
+		}
+
+		return spoke.discoveryISOReady(infraEnv) == nil, nil
+	})
+	if err == nil {
+		return spoke.discoveryISOURL(infraEnv)
+	}
+
+	if getErr != nil {
+		return "", fmt.Errorf("timed out waiting for discovery iso of spoke %s: %w", spoke.Name, getErr)
+	}
+
+	if infraEnv == nil {
+		return "", fmt.Errorf("timed out waiting for discovery iso of spoke %s: %w", spoke.Name, err)
+	}
+
+	return "", fmt.Errorf("timed out waiting for discovery iso of spoke %s: %w",
+		spoke.Name, spoke.discoveryISOReady(infraEnv))
+}
+
+// GetISODownloadURL returns the discovery ISO download URL published in the status of the spoke infraenv, failing
+// when the image has not been created yet. The infraenv does not select the image type, so when an ISO boot method
+// was selected with WithBootMethod the type query parameter of the URL is set to it, making the image service serve
+// the full or minimal ISO regardless of its default.
+func (spoke *SpokeClusterResources) GetISODownloadURL() (string, error) {
+	if spoke.InfraEnv == nil {
+		return "", fmt.Errorf("infraenv must be defined before getting the discovery iso url")
+	}
+
+	infraEnv, err := spoke.InfraEnv.Get()
+	if err != nil {
+		return "", fmt.Errorf("failed to get infraenv of spoke %s: %w", spoke.Name, err)
+	}
+
+	spoke.InfraEnv.Object = infraEnv
+
+	if err := spoke.discoveryISOReady(infraEnv); err != nil {
+		return "", err
+	}
+
+	return spoke.discoveryISOURL(infraEnv)
+}
+
+// discoveryISOReady returns nil when the ImageCreated condition of infraEnv is true and its status publishes the
+// discovery ISO download URL, or an error describing why the image is not ready.
+func (spoke *SpokeClusterResources) discoveryISOReady(infraEnv *agentInstallV1Beta1.InfraEnv) error {
+	condition := conditionsv1.FindStatusCondition(
+		infraEnv.Status.Conditions, agentInstallV1Beta1.ImageCreatedCondition)
+	if condition == nil {
+		return fmt.Errorf("infraenv %s has no %s condition", infraEnv.Name, agentInstallV1Beta1.ImageCreatedCondition)
+	}
+
+	if condition.Status != corev1.ConditionTrue {
+		return fmt.Errorf("infraenv %s condition %s is %s: %s",
+			infraEnv.Name, condition.Type, condition.Status, condition.Message)
+	}
+
+	if infraEnv.Status.ISODownloadURL == "" || infraEnv.Status.CreatedTime == nil {
+		return fmt.Errorf("infraenv %s has not published the discovery iso url", infraEnv.Name)
+	}
+
+	return nil
+}
+
+// discoveryISOURL returns the discovery ISO download URL of infraEnv, with its type query parameter set to the boot
+// method selected with WithBootMethod when it is an ISO one. The URL is returned as is when no boot method was
+// selected.
+func (spoke *SpokeClusterResources) discoveryISOURL(infraEnv *agentInstallV1Beta1.InfraEnv) (string, error) {
+	if !spoke.bootMethod.isISO() {
+		return infraEnv.Status.ISODownloadURL, nil
+	}
+
+	isoURL, err := url.Parse(infraEnv.Status.ISODownloadURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse discovery iso url %q: %w", infraEnv.Status.ISODownloadURL, err)
+	}
+
+	query := isoURL.Query()
+	query.Set("type", string(spoke.bootMethod))
+	isoURL.RawQuery = query.Encode()
+
+	return isoURL.String(), nil
+}
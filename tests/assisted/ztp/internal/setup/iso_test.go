@@ -0,0 +1,211 @@
+package setup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	hivev1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/hive/api/v1"
+	conditionsv1 "github.com/openshift/custom-resource-status/conditions/v1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	runtimeClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+const (
+	testFullISOURL    = "https://assisted-image-service.example.com/images/abc?arch=x86_64&type=full-iso"
+	testMinimalISOURL = "https://assisted-image-service.example.com/images/abc?arch=x86_64&type=minimal-iso"
+)
+
+var testISOCreatedTime = metav1.NewTime(time.Date(2024, 6, 3, 16, 0, 0, 0, time.UTC))
+
+func TestGetISODownloadURL(t *testing.T) {
+	testCases := []struct {
+		name          string
+		bootMethod    BootMethod
+		status        agentInstallV1Beta1.InfraEnvStatus
+		expectedURL   string
+		expectedError string
+	}{
+		{
+			name:        "full iso created",
+			status:      buildDummyInfraEnvStatus(corev1.ConditionTrue, "", testFullISOURL),
+			expectedURL: testFullISOURL,
+		},
+		{
+			name:        "minimal iso created",
+			bootMethod:  BootMethodMinimalISO,
+			status:      buildDummyInfraEnvStatus(corev1.ConditionTrue, "", testMinimalISOURL),
+			expectedURL: testMinimalISOURL,
+		},
+		{
+			name:        "ipxe ignores image type",
+			bootMethod:  BootMethodIPXE,
+			status:      buildDummyInfraEnvStatus(corev1.ConditionTrue, "", testFullISOURL),
+			expectedURL: testFullISOURL,
+		},
+		{
+			name:        "minimal iso published without boot method",
+			status:      buildDummyInfraEnvStatus(corev1.ConditionTrue, "", testMinimalISOURL),
+			expectedURL: testMinimalISOURL,
+		},
+		{
+			name:        "full iso rewritten to minimal iso",
+			bootMethod:  BootMethodMinimalISO,
+			status:      buildDummyInfraEnvStatus(corev1.ConditionTrue, "", testFullISOURL),
+			expectedURL: testMinimalISOURL,
+		},
+		{
+			name:        "minimal iso rewritten to full iso",
+			bootMethod:  BootMethodFullISO,
+			status:      buildDummyInfraEnvStatus(corev1.ConditionTrue, "", testMinimalISOURL),
+			expectedURL: testFullISOURL,
+		},
+		{
+			name:   "image creation failed",
+			status: buildDummyInfraEnvStatus(corev1.ConditionFalse, "Failed to create image: invalid ignition", ""),
+			expectedError: "infraenv spoke condition ImageCreated is False: " +
+				"Failed to create image: invalid ignition",
+		},
+		{
+			name:          "url not published",
+			status:        buildDummyInfraEnvStatus(corev1.ConditionTrue, "", ""),
+			expectedError: "infraenv spoke has not published the discovery iso url",
+		},
+		{
+			name:          "no condition",
+			expectedError: "infraenv spoke has no ImageCreated condition",
+		},
+	}
+
+	for _, testCase := range testCases {
+		infraEnv := buildDummyInfraEnvObject("spoke")
+		infraEnv.Status = testCase.status
+
+		spoke := NewSpokeCluster(newTestClient(infraEnv)).WithName("spoke").WithDefaultInfraEnv()
+		if testCase.bootMethod != "" {
+			spoke.WithBootMethod(testCase.bootMethod)
+		}
+
+		isoURL, err := spoke.GetISODownloadURL()
+		if testCase.expectedError != "" {
+			assert.EqualError(t, err, testCase.expectedError, testCase.name)
+
+			continue
+		}
+
+		assert.Nil(t, err, testCase.name)
+		assert.Equal(t, testCase.expectedURL, isoURL, testCase.name)
+	}
+}
+
+func TestWaitForDiscoveryISO(t *testing.T) {
+	infraEnv := buildDummyInfraEnvObject("spoke")
+	infraEnv.Status = buildDummyInfraEnvStatus(corev1.ConditionTrue, "", testFullISOURL)
+
+	spoke := NewSpokeCluster(newTestClient(infraEnv)).WithName("spoke").WithDefaultInfraEnv().
+		WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Second})
+
+	isoURL, err := spoke.WaitForDiscoveryISO(0)
+	assert.Nil(t, err)
+	assert.Equal(t, testFullISOURL, isoURL)
+	assert.Equal(t, testFullISOURL, spoke.InfraEnv.Object.Status.ISODownloadURL)
+
+	infraEnv = buildDummyInfraEnvObject("spoke")
+	infraEnv.Status = buildDummyInfraEnvStatus(corev1.ConditionFalse, "Image is being generated", "")
+
+	spoke = NewSpokeCluster(newTestClient(infraEnv)).WithName("spoke").WithDefaultInfraEnv().
+		WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Second})
+
+	_, err = spoke.WaitForDiscoveryISO(10 * time.Millisecond)
+	assert.EqualError(t, err, "timed out waiting for discovery iso of spoke spoke: "+
+		"infraenv spoke condition ImageCreated is False: Image is being generated")
+
+	infraEnv = buildDummyInfraEnvObject("spoke")
+	infraEnv.Status = buildDummyInfraEnvStatus(corev1.ConditionTrue, "", testMinimalISOURL)
+
+	spoke = NewSpokeCluster(newTestClient(infraEnv)).WithName("spoke").WithDefaultInfraEnv().
+		WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Second})
+
+	start := time.Now()
+	isoURL, err = spoke.WaitForDiscoveryISO(0)
+	assert.Nil(t, err, "a minimal iso is ready when no boot method was selected")
+	assert.Equal(t, testMinimalISOURL, isoURL)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+	_, err = NewSpokeCluster(newTestClient()).WithName("spoke").WaitForDiscoveryISO(0)
+	assert.EqualError(t, err, "infraenv must be defined before waiting for the discovery iso")
+}
+
+func TestWaitForDiscoveryISORegenerated(t *testing.T) {
+	infraEnv := buildDummyInfraEnvObject("spoke")
+	infraEnv.Status = buildDummyInfraEnvStatus(corev1.ConditionTrue, "", testFullISOURL)
+
+	apiClient, testBuilder := clients.GetModifiableTestClients(clients.TestClientParams{
+		K8sMockObjects: []runtime.Object{infraEnv},
+		SchemeAttachers: []clients.SchemeAttacher{
+			v1beta1.AddToScheme,
+			agentInstallV1Beta1.AddToScheme,
+			hivev1.AddToScheme,
+		},
+	})
+
+	gets := 0
+	regeneratedTime := metav1.NewTime(testISOCreatedTime.Add(time.Minute))
+
+	apiClient.Client = testBuilder.WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, client runtimeClient.WithWatch, key runtimeClient.ObjectKey,
+			obj runtimeClient.Object, opts ...runtimeClient.GetOption) error {
+			if err := client.Get(ctx, key, obj, opts...); err != nil {
+				return err
+			}
+
+			if object, isInfraEnv := obj.(*agentInstallV1Beta1.InfraEnv); isInfraEnv {
+				gets++
+
+				if gets == 1 {
+					object.Status.ISODownloadURL = ""
+				}
+
+				if gets > 1 {
+					object.Status.CreatedTime = &regeneratedTime
+				}
+			}
+
+			return nil
+		},
+	}).Build()
+
+	spoke := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultInfraEnv().
+		WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Second})
+
+	isoURL, err := spoke.WaitForDiscoveryISO(0)
+	assert.Nil(t, err)
+	assert.Equal(t, testFullISOURL, isoURL)
+	assert.Equal(t, 3, gets)
+	assert.Equal(t, &regeneratedTime, spoke.InfraEnv.Object.Status.CreatedTime)
+}
+
+func buildDummyInfraEnvStatus(
+	status corev1.ConditionStatus, message, isoURL string) agentInstallV1Beta1.InfraEnvStatus {
+	infraEnvStatus := agentInstallV1Beta1.InfraEnvStatus{
+		Conditions: []conditionsv1.Condition{{
+			Type:    agentInstallV1Beta1.ImageCreatedCondition,
+			Status:  status,
+			Message: message,
+		}},
+	}
+
+	if isoURL != "" {
+		infraEnvStatus.ISODownloadURL = isoURL
+		infraEnvStatus.CreatedTime = &testISOCreatedTime
+	}
+
+	return infraEnvStatus
+}
@@ -0,0 +1,159 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/secret"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// adminKubeconfigKey is the key of the admin kubeconfig in the secret published by hive for an installed spoke.
+const adminKubeconfigKey = "kubeconfig"
+
+// ErrSpokeNotInstalled is returned, wrapped, by GetKubeconfig and GetSpokeClient when the clusterdeployment of the
+// spoke does not exist or is not installed yet.
+var ErrSpokeNotInstalled = errors.New("spoke cluster is not installed")
+
+// WithKubeconfigPath sets the path GetSpokeClient writes the admin kubeconfig of the spoke to, for suites that run
+// oc against the spoke. By default the kubeconfig is written to a temporary file.
+func (spoke *SpokeClusterResources) WithKubeconfigPath(path string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if path == "" {
+		spoke.err = fmt.Errorf("WithKubeconfigPath: kubeconfig path cannot be empty")
+
+		return spoke
+	}
+
+	spoke.kubeconfigPath = path
+
+	return spoke
+}
+
+// GetKubeconfig waits, up to the spoke wait timeout, for hive to publish the admin kubeconfig secret referenced by
+// the metadata of the installed spoke clusterdeployment and returns the kubeconfig. It returns ErrSpokeNotInstalled
+// without waiting when the clusterdeployment is not installed.
+func (spoke *SpokeClusterResources) GetKubeconfig() ([]byte, error) {
+	return spoke.GetKubeconfigWithContext(context.Background())
+}
+
+// GetKubeconfigWithContext returns the admin kubeconfig of the spoke like GetKubeconfig, stopping the wait for its
+// secret when ctx is done.
+func (spoke *SpokeClusterResources) GetKubeconfigWithContext(ctx context.Context) ([]byte, error) {
+	if spoke.ClusterDeployment == nil {
+		return nil, fmt.Errorf("clusterdeployment must be defined before getting the spoke kubeconfig")
+	}
+
+	clusterDeployment, err := spoke.ClusterDeployment.Get()
+	if k8serrors.IsNotFound(err) {
+		return nil, fmt.Errorf("%w: clusterdeployment %s does not exist", ErrSpokeNotInstalled, spoke.Name)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clusterdeployment of spoke %s: %w", spoke.Name, err)
+	}
+
+	if !clusterDeployment.Spec.Installed {
+		return nil, fmt.Errorf("%w: clusterdeployment %s is not installed", ErrSpokeNotInstalled, spoke.Name)
+	}
+
+	var (
+		kubeconfig []byte
+		lastErr    error
+		options    = spoke.resolveWaitOptions()
+	)
+
+	err = spoke.poll(ctx, "wait for admin kubeconfig", options, func(ctx context.Context) (bool, error) {
+		kubeconfig, lastErr = spoke.pullKubeconfig()
+		if lastErr != nil {
+			glog.V(ztpparams.ZTPLogLevel).Infof(
+				"Admin kubeconfig of spoke %s is not available: %v", spoke.Name, lastErr)
+
+			return false, nil
+		}
+
+		return true, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("timed out waiting for admin kubeconfig of spoke %s: %w", spoke.Name, lastErr)
+	}
+
+	return kubeconfig, nil
+}
+
+// GetSpokeClient returns an API client of the installed spoke built from the kubeconfig returned by GetKubeconfig.
+// The kubeconfig is written to the path set using WithKubeconfigPath, or to a temporary file, which is the
+// KubeconfigPath of the returned client.
+func (spoke *SpokeClusterResources) GetSpokeClient() (*clients.Settings, error) {
+	kubeconfig, err := spoke.GetKubeconfig()
+	if err != nil {
+		return nil, err
+	}
+
+	path := spoke.kubeconfigPath
+
+	if path == "" {
+		file, err := os.CreateTemp("", spoke.Name+"-kubeconfig-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create kubeconfig file of spoke %s: %w", spoke.Name, err)
+		}
+
+		path = file.Name()
+		_ = file.Close()
+	}
+
+	if err := os.WriteFile(path, kubeconfig, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write kubeconfig of spoke %s to %s: %w", spoke.Name, path, err)
+	}
+
+	glog.V(ztpparams.ZTPLogLevel).Infof("Creating api client of spoke %s from %s", spoke.Name, path)
+
+	spokeClient := clients.New(path)
+	if spokeClient == nil {
+		return nil, fmt.Errorf("failed to create api client of spoke %s from kubeconfig %s", spoke.Name, path)
+	}
+
+	return spokeClient, nil
+}
+
+// pullKubeconfig returns the admin kubeconfig of the spoke when hive has published it, checking that it can be
+// loaded.
+func (spoke *SpokeClusterResources) pullKubeconfig() ([]byte, error) {
+	clusterDeployment, err := spoke.ClusterDeployment.Get()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clusterdeployment: %w", err)
+	}
+
+	metadata := clusterDeployment.Spec.ClusterMetadata
+	if metadata == nil || metadata.AdminKubeconfigSecretRef.Name == "" {
+		return nil, fmt.Errorf("clusterdeployment %s does not reference an admin kubeconfig secret",
+			clusterDeployment.Name)
+	}
+
+	secretName := metadata.AdminKubeconfigSecretRef.Name
+
+	kubeconfigSecret, err := secret.Pull(spoke.apiClient, secretName, clusterDeployment.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull admin kubeconfig secret %s: %w", secretName, err)
+	}
+
+	kubeconfig := kubeconfigSecret.Object.Data[adminKubeconfigKey]
+	if len(kubeconfig) == 0 {
+		return nil, fmt.Errorf("admin kubeconfig secret %s has no %s key", secretName, adminKubeconfigKey)
+	}
+
+	if _, err := clientcmd.Load(kubeconfig); err != nil {
+		return nil, fmt.Errorf("admin kubeconfig secret %s holds an invalid kubeconfig: %w", secretName, err)
+	}
+
+	return kubeconfig, nil
+}
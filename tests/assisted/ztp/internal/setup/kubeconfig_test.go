@@ -0,0 +1,155 @@
+package setup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	hivev1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/hive/api/v1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: https://api.spoke.assisted.test.com:6443
+  name: spoke
+contexts:
+- context:
+    cluster: spoke
+    user: admin
+  name: admin
+current-context: admin
+users:
+- name: admin
+  user:
+    token: test-token
+`
+
+func TestGetKubeconfig(t *testing.T) {
+	testCases := []struct {
+		name        string
+		installed   bool
+		secretRef   string
+		data        map[string][]byte
+		expectedErr string
+	}{
+		{
+			name:      "published",
+			installed: true,
+			secretRef: "spoke-admin-kubeconfig",
+			data:      map[string][]byte{adminKubeconfigKey: []byte(testKubeconfig)},
+		},
+		{
+			name:        "not installed",
+			expectedErr: "spoke cluster is not installed: clusterdeployment spoke is not installed",
+		},
+		{
+			name:      "not referenced",
+			installed: true,
+			expectedErr: "timed out waiting for admin kubeconfig of spoke spoke: " +
+				"clusterdeployment spoke does not reference an admin kubeconfig secret",
+		},
+		{
+			name:      "missing key",
+			installed: true,
+			secretRef: "spoke-admin-kubeconfig",
+			data:      map[string][]byte{"raw-kubeconfig": []byte(testKubeconfig)},
+			expectedErr: "timed out waiting for admin kubeconfig of spoke spoke: " +
+				"admin kubeconfig secret spoke-admin-kubeconfig has no kubeconfig key",
+		},
+		{
+			name:      "invalid",
+			installed: true,
+			secretRef: "spoke-admin-kubeconfig",
+			data:      map[string][]byte{adminKubeconfigKey: []byte("clusters: {")},
+			expectedErr: "timed out waiting for admin kubeconfig of spoke spoke: " +
+				"admin kubeconfig secret spoke-admin-kubeconfig holds an invalid kubeconfig",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := NewSpokeCluster(newTestClient(buildDummyInstalledClusterDeployment(
+			testCase.installed, testCase.secretRef, testCase.data)...)).WithName("spoke").
+			WithDefaultClusterDeployment().
+			WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: 10 * time.Millisecond})
+
+		kubeconfig, err := spoke.GetKubeconfig()
+
+		if testCase.expectedErr != "" {
+			assert.ErrorContains(t, err, testCase.expectedErr, testCase.name)
+
+			continue
+		}
+
+		assert.Nil(t, err, testCase.name)
+		assert.Equal(t, testKubeconfig, string(kubeconfig), testCase.name)
+	}
+
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultClusterDeployment()
+	_, err := spoke.GetKubeconfig()
+	assert.ErrorIs(t, err, ErrSpokeNotInstalled)
+	assert.EqualError(t, err, "spoke cluster is not installed: clusterdeployment spoke does not exist")
+
+	_, err = NewSpokeCluster(newTestClient()).WithName("spoke").GetSpokeClient()
+	assert.EqualError(t, err, "clusterdeployment must be defined before getting the spoke kubeconfig")
+}
+
+func TestGetSpokeClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	objects := buildDummyInstalledClusterDeployment(
+		true, "spoke-admin-kubeconfig", map[string][]byte{adminKubeconfigKey: []byte(testKubeconfig)})
+
+	spoke := NewSpokeCluster(newTestClient(objects...)).WithName("spoke").WithDefaultClusterDeployment().
+		WithKubeconfigPath(path)
+
+	spokeClient, err := spoke.GetSpokeClient()
+	assert.Nil(t, err)
+	assert.Equal(t, path, spokeClient.KubeconfigPath)
+	assert.Equal(t, "https://api.spoke.assisted.test.com:6443", spokeClient.Config.Host)
+
+	kubeconfig, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, testKubeconfig, string(kubeconfig))
+
+	spoke = NewSpokeCluster(newTestClient(objects...)).WithName("spoke").WithDefaultClusterDeployment()
+
+	spokeClient, err = spoke.GetSpokeClient()
+	assert.Nil(t, err)
+	assert.FileExists(t, spokeClient.KubeconfigPath)
+	assert.Nil(t, os.Remove(spokeClient.KubeconfigPath))
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithKubeconfigPath("")
+	assert.EqualError(t, spoke.err, "WithKubeconfigPath: kubeconfig path cannot be empty")
+}
+
+// buildDummyInstalledClusterDeployment returns the spoke clusterdeployment, installed or not, referencing the admin
+// kubeconfig secret secretRef, along with that secret holding data when secretRef is set.
+func buildDummyInstalledClusterDeployment(installed bool, secretRef string, data map[string][]byte) []runtime.Object {
+	clusterDeployment := &hivev1.ClusterDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "spoke", Namespace: "spoke"},
+		Spec:       hivev1.ClusterDeploymentSpec{Installed: installed},
+	}
+
+	if installed {
+		clusterDeployment.Spec.ClusterMetadata = &hivev1.ClusterMetadata{
+			ClusterID:                "spoke-id",
+			InfraID:                  "spoke-infra",
+			AdminKubeconfigSecretRef: corev1.LocalObjectReference{Name: secretRef},
+		}
+	}
+
+	if secretRef == "" {
+		return []runtime.Object{clusterDeployment}
+	}
+
+	return []runtime.Object{clusterDeployment, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: secretRef, Namespace: "spoke"},
+		Data:       data,
+	}}
+}
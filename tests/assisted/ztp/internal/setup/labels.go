@@ -0,0 +1,278 @@
+package setup
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	runtimeClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// reservedMetadataDomains are the domains, along with their subdomains, of the label and annotation keys reserved
+// for Kubernetes.
+var reservedMetadataDomains = []string{"kubernetes.io", "k8s.io"}
+
+// WithLabels adds labels applied by Create to every spoke resource, on top of the labels of each resource so that
+// the provided values win. The SpokeOwnershipLabel label is always set to the spoke name and cannot be provided.
+// Keys under the kubernetes.io and k8s.io prefixes are reserved and rejected.
+func (spoke *SpokeClusterResources) WithLabels(labels map[string]string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if len(labels) == 0 {
+		spoke.err = fmt.Errorf("WithLabels: labels cannot be empty")
+
+		return spoke
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		if err := validateMetadataKey("label", key); err != nil {
+			spoke.err = fmt.Errorf("WithLabels: %w", err)
+
+			return spoke
+		}
+
+		if key == SpokeOwnershipLabel {
+			spoke.err = fmt.Errorf("WithLabels: label %s is reserved for the spoke name", key)
+
+			return spoke
+		}
+
+		if errs := validation.IsValidLabelValue(labels[key]); len(errs) > 0 {
+			spoke.err = fmt.Errorf("WithLabels: invalid value %q of label %s: %s",
+				labels[key], key, strings.Join(errs, ", "))
+
+			return spoke
+		}
+	}
+
+	if spoke.labels == nil {
+		spoke.labels = make(map[string]string)
+	}
+
+	maps.Copy(spoke.labels, labels)
+
+	return spoke
+}
+
+// WithAnnotations adds annotations applied by Create to every spoke resource, on top of the annotations of each
+// resource so that the provided values win. Keys under the kubernetes.io and k8s.io prefixes are reserved and
+// rejected.
+func (spoke *SpokeClusterResources) WithAnnotations(annotations map[string]string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if len(annotations) == 0 {
+		spoke.err = fmt.Errorf("WithAnnotations: annotations cannot be empty")
+
+		return spoke
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(annotations)) {
+		if err := validateMetadataKey("annotation", key); err != nil {
+			spoke.err = fmt.Errorf("WithAnnotations: %w", err)
+
+			return spoke
+		}
+	}
+
+	if spoke.annotations == nil {
+		spoke.annotations = make(map[string]string)
+	}
+
+	maps.Copy(spoke.annotations, annotations)
+
+	return spoke
+}
+
+// WithAgentLabels adds labels set in the agentLabels of the spoke infraenvs, which the assisted-service applies to
+// the agents registering through them. The agents listed by the wait and approval helpers are then filtered by these
+// labels, so that agents of other spokes sharing the namespace are ignored. Repeated calls merge the labels.
+func (spoke *SpokeClusterResources) WithAgentLabels(labels map[string]string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.InfraEnv == nil {
+		spoke.err = fmt.Errorf("WithAgentLabels: infraenv must be defined before setting agent labels")
+
+		return spoke
+	}
+
+	if len(labels) == 0 {
+		spoke.err = fmt.Errorf("WithAgentLabels: labels cannot be empty")
+
+		return spoke
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		if err := validateMetadataKey("agent label", key); err != nil {
+			spoke.err = fmt.Errorf("WithAgentLabels: %w", err)
+
+			return spoke
+		}
+
+		if errs := validation.IsValidLabelValue(labels[key]); len(errs) > 0 {
+			spoke.err = fmt.Errorf("WithAgentLabels: invalid value %q of agent label %s: %s",
+				labels[key], key, strings.Join(errs, ", "))
+
+			return spoke
+		}
+	}
+
+	if spoke.agentLabels == nil {
+		spoke.agentLabels = make(map[string]string)
+	}
+
+	maps.Copy(spoke.agentLabels, labels)
+	spoke.applyAgentLabels()
+
+	return spoke
+}
+
+// applyAgentLabels merges the agent labels of the spoke into the agentLabels of every spoke infraenv.
+func (spoke *SpokeClusterResources) applyAgentLabels() {
+	if len(spoke.agentLabels) == 0 {
+		return
+	}
+
+	for _, infraEnv := range spoke.allInfraEnvs() {
+		if infraEnv.Definition.Spec.AgentLabels == nil {
+			infraEnv.Definition.Spec.AgentLabels = make(map[string]string)
+		}
+
+		maps.Copy(infraEnv.Definition.Spec.AgentLabels, spoke.agentLabels)
+	}
+}
+
+// hasAgentLabels returns true when the labels of agent contain the agent labels of the spoke.
+func (spoke *SpokeClusterResources) hasAgentLabels(agent *agentInstallV1Beta1.Agent) bool {
+	for key, value := range spoke.agentLabels {
+		if agent.Labels[key] != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// applyMetadata merges the spoke labels, the ownership label and the spoke annotations into the definitions of
+// every spoke resource, and the agent labels into the spoke infraenvs added after WithAgentLabels.
+func (spoke *SpokeClusterResources) applyMetadata() {
+	spoke.applyAgentLabels()
+
+	for _, definition := range spoke.definitions() {
+		labels := definition.GetLabels()
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+
+		maps.Copy(labels, spoke.labels)
+		labels[SpokeOwnershipLabel] = spoke.Name
+		definition.SetLabels(labels)
+
+		if len(spoke.annotations) == 0 {
+			continue
+		}
+
+		annotations := definition.GetAnnotations()
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+
+		maps.Copy(annotations, spoke.annotations)
+		definition.SetAnnotations(annotations)
+	}
+}
+
+// definitions returns the definitions of the instantiated spoke resources.
+func (spoke *SpokeClusterResources) definitions() []runtimeClient.Object {
+	var definitions []runtimeClient.Object
+
+	if spoke.Namespace != nil {
+		definitions = append(definitions, spoke.Namespace.Definition)
+	}
+
+	if spoke.PullSecret != nil {
+		definitions = append(definitions, spoke.PullSecret.Definition)
+	}
+
+	if spoke.InfraEnvNamespace != nil {
+		definitions = append(definitions, spoke.InfraEnvNamespace.Definition)
+	}
+
+	if spoke.InfraEnvPullSecret != nil {
+		definitions = append(definitions, spoke.InfraEnvPullSecret.Definition)
+	}
+
+	if spoke.ClusterDeployment != nil {
+		definitions = append(definitions, spoke.ClusterDeployment.Definition)
+	}
+
+	if spoke.AgentClusterInstall != nil {
+		definitions = append(definitions, spoke.AgentClusterInstall.Definition)
+	}
+
+	if spoke.ClusterImageSet != nil {
+		definitions = append(definitions, spoke.ClusterImageSet.Definition)
+	}
+
+	for _, infraEnv := range spoke.allInfraEnvs() {
+		definitions = append(definitions, infraEnv.Definition)
+	}
+
+	for _, extraManifest := range spoke.ExtraManifests {
+		definitions = append(definitions, extraManifest.Definition)
+	}
+
+	if spoke.MirrorRegistryConfigMap != nil {
+		definitions = append(definitions, spoke.MirrorRegistryConfigMap.Definition)
+	}
+
+	for _, nmStateConfig := range spoke.NMStateConfigs {
+		definitions = append(definitions, nmStateConfig.Definition)
+	}
+
+	for _, bmcSecret := range spoke.BMCSecrets {
+		definitions = append(definitions, bmcSecret.Definition)
+	}
+
+	for _, bareMetalHost := range spoke.BareMetalHosts {
+		definitions = append(definitions, bareMetalHost.Definition)
+	}
+
+	if spoke.ManagedCluster != nil {
+		definitions = append(definitions, spoke.ManagedCluster.Definition)
+	}
+
+	if spoke.KlusterletAddonConfig != nil {
+		definitions = append(definitions, spoke.KlusterletAddonConfig.Definition)
+	}
+
+	return definitions
+}
+
+// validateMetadataKey returns an error when key is not a qualified name or uses a reserved prefix.
+func validateMetadataKey(kind, key string) error {
+	if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+		return fmt.Errorf("invalid %s key %q: %s", kind, key, strings.Join(errs, ", "))
+	}
+
+	prefix, _, found := strings.Cut(key, "/")
+	if !found {
+		return nil
+	}
+
+	for _, domain := range reservedMetadataDomains {
+		if prefix == domain || strings.HasSuffix(prefix, "."+domain) {
+			return fmt.Errorf("%s key %s uses the reserved prefix %s", kind, key, domain)
+		}
+	}
+
+	return nil
+}
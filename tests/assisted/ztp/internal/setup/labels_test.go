@@ -0,0 +1,163 @@
+package setup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithLabelsErrors(t *testing.T) {
+	testCases := []struct {
+		labels      map[string]string
+		expectedErr string
+	}{
+		{labels: nil, expectedErr: "WithLabels: labels cannot be empty"},
+		{
+			labels:      map[string]string{"kubernetes.io/suite": "ztp"},
+			expectedErr: "WithLabels: label key kubernetes.io/suite uses the reserved prefix kubernetes.io",
+		},
+		{
+			labels:      map[string]string{"node-role.kubernetes.io/worker": ""},
+			expectedErr: "WithLabels: label key node-role.kubernetes.io/worker uses the reserved prefix kubernetes.io",
+		},
+		{
+			labels:      map[string]string{"k8s.io/suite": "ztp"},
+			expectedErr: "WithLabels: label key k8s.io/suite uses the reserved prefix k8s.io",
+		},
+		{
+			labels:      map[string]string{SpokeOwnershipLabel: "other"},
+			expectedErr: "WithLabels: label eco-gotests/spoke is reserved for the spoke name",
+		},
+		{
+			labels:      map[string]string{"suite name": "ztp"},
+			expectedErr: `WithLabels: invalid label key "suite name": `,
+		},
+		{
+			labels:      map[string]string{"suite": "ztp run"},
+			expectedErr: `WithLabels: invalid value "ztp run" of label suite: `,
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithLabels(testCase.labels)
+		assert.ErrorContains(t, spoke.err, testCase.expectedErr)
+	}
+}
+
+func TestWithAnnotationsErrors(t *testing.T) {
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithAnnotations(nil)
+	assert.EqualError(t, spoke.err, "WithAnnotations: annotations cannot be empty")
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").
+		WithAnnotations(map[string]string{"kubernetes.io/description": "ztp"})
+	assert.EqualError(t, spoke.err,
+		"WithAnnotations: annotation key kubernetes.io/description uses the reserved prefix kubernetes.io")
+}
+
+func TestCreateAppliesMetadata(t *testing.T) {
+	spoke := NewSpokeCluster(newHubTestClient()).WithName("spoke").WithDefaultNamespace().WithDefaultPullSecret().
+		WithDefaultClusterDeployment().WithDefaultIPv4AgentClusterInstall().WithDefaultInfraEnv().
+		WithLabels(map[string]string{"suite": "ztp", "run": "1"}).
+		WithLabels(map[string]string{"run": "2"}).
+		WithAnnotations(map[string]string{"eco-gotests/description": "metadata test"})
+	assert.Nil(t, spoke.err)
+
+	spoke.InfraEnv.Definition.Labels = map[string]string{"suite": "infraenv", "agentclusterinstalls.agent": "spoke"}
+
+	_, err := spoke.Create()
+	assert.Nil(t, err)
+
+	expectedLabels := map[string]string{"suite": "ztp", "run": "2", SpokeOwnershipLabel: "spoke"}
+
+	for _, object := range []map[string]string{
+		spoke.Namespace.Object.Labels,
+		spoke.PullSecret.Object.Labels,
+		spoke.ClusterDeployment.Object.Labels,
+		spoke.AgentClusterInstall.Object.Labels,
+	} {
+		assert.Equal(t, expectedLabels, object)
+	}
+
+	assert.Equal(t, map[string]string{
+		"suite": "ztp", "run": "2", "agentclusterinstalls.agent": "spoke", SpokeOwnershipLabel: "spoke",
+	}, spoke.InfraEnv.Object.Labels)
+	assert.Equal(t, "metadata test", spoke.ClusterDeployment.Object.Annotations["eco-gotests/description"])
+	assert.Equal(t, "metadata test", spoke.InfraEnv.Object.Annotations["eco-gotests/description"])
+
+	unlabeled := NewSpokeCluster(newHubTestClient()).WithName("unlabeled").WithDefaultPullSecret()
+
+	_, err = unlabeled.Create()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{SpokeOwnershipLabel: "unlabeled"}, unlabeled.PullSecret.Object.Labels)
+	assert.Empty(t, unlabeled.PullSecret.Object.Annotations)
+}
+
+func TestWithAgentLabels(t *testing.T) {
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultInfraEnv().
+		WithAgentLabels(map[string]string{"ztp.example.com/spoke": "spoke", "pool": "a"}).
+		WithAgentLabels(map[string]string{"pool": "b"})
+	assert.Nil(t, spoke.err)
+	assert.Equal(t, map[string]string{"ztp.example.com/spoke": "spoke", "pool": "b"},
+		spoke.InfraEnv.Definition.Spec.AgentLabels)
+
+	spoke.WithDefaultInfraEnv()
+	assert.Empty(t, spoke.InfraEnv.Definition.Spec.AgentLabels)
+
+	spoke.applyMetadata()
+	assert.Equal(t, map[string]string{"ztp.example.com/spoke": "spoke", "pool": "b"},
+		spoke.InfraEnv.Definition.Spec.AgentLabels)
+
+	testCases := []struct {
+		spoke       *SpokeClusterResources
+		expectedErr string
+	}{
+		{
+			spoke:       NewSpokeCluster(newTestClient()).WithName("spoke"),
+			expectedErr: "WithAgentLabels: infraenv must be defined before setting agent labels",
+		},
+		{
+			spoke:       NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultInfraEnv(),
+			expectedErr: "WithAgentLabels: labels cannot be empty",
+		},
+	}
+
+	for _, testCase := range testCases {
+		assert.EqualError(t, testCase.spoke.WithAgentLabels(nil).err, testCase.expectedErr)
+	}
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultInfraEnv().
+		WithAgentLabels(map[string]string{"pool name": "a"})
+	assert.ErrorContains(t, spoke.err, `WithAgentLabels: invalid agent label key "pool name": `)
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultInfraEnv().
+		WithAgentLabels(map[string]string{"pool": "a b"})
+	assert.ErrorContains(t, spoke.err, `WithAgentLabels: invalid value "a b" of agent label pool: `)
+}
+
+func TestListAgentsFiltersAgentLabels(t *testing.T) {
+	labeledAgent := buildDummyDiscoveredAgent("agent-0", "spoke-host-0", "52:54:00:00:00:01", 0)
+	labeledAgent.Labels["pool"] = "a"
+	otherPoolAgent := buildDummyDiscoveredAgent("agent-1", "spoke-host-1", "52:54:00:00:00:02", 0)
+	otherPoolAgent.Labels["pool"] = "b"
+
+	apiClient := newTestClient(buildDummyInfraEnvObject("spoke"), labeledAgent, otherPoolAgent,
+		buildDummyDiscoveredAgent("agent-2", "spoke-host-2", "52:54:00:00:00:03", 0))
+
+	spoke := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultInfraEnv()
+
+	agents, err := spoke.listAgents()
+	assert.Nil(t, err)
+	assert.Len(t, agents, 3)
+
+	spoke.WithAgentLabels(map[string]string{"pool": "a"}).
+		WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Second})
+
+	agents, err = spoke.WaitForAgentsRegistered(1, 0)
+	assert.Nil(t, err)
+	assert.Len(t, agents, 1)
+	assert.Equal(t, "agent-0", agents[0].Name)
+
+	_, err = spoke.WaitForAgentsRegistered(2, 10*time.Millisecond)
+	assert.EqualError(t, err, "timed out waiting for 2 agents of spoke spoke, found 1")
+}
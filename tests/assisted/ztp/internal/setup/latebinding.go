@@ -0,0 +1,119 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-goinfra/pkg/assisted"
+	"github.com/openshift-kni/eco-goinfra/pkg/namespace"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+	"k8s.io/apimachinery/pkg/util/validation"
+	runtimeClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// WithLateBindingInfraEnv creates an infraenv without a cluster reference for late-binding flows, where agents are
+// bound to the spoke clusterdeployment after discovery using BindToCluster. The infraenv is created in
+// infraEnvNamespace, along with a copy of the spoke pull-secret, or in the spoke namespace when it is empty.
+func (spoke *SpokeClusterResources) WithLateBindingInfraEnv(infraEnvNamespace string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	pullSecretName := fmt.Sprintf("%s-pull-secret", spoke.Name)
+
+	if infraEnvNamespace == "" || infraEnvNamespace == spoke.Name {
+		spoke.InfraEnv = assisted.NewInfraEnvBuilder(spoke.apiClient, spoke.Name, spoke.Name, pullSecretName)
+
+		return spoke
+	}
+
+	if errs := validation.IsDNS1123Label(infraEnvNamespace); len(errs) > 0 {
+		spoke.err = fmt.Errorf("WithLateBindingInfraEnv: invalid infraenv namespace %q: %s",
+			infraEnvNamespace, strings.Join(errs, ", "))
+
+		return spoke
+	}
+
+	if spoke.PullSecret == nil {
+		spoke.err = fmt.Errorf("WithLateBindingInfraEnv: " +
+			"pull-secret must be defined before adding a late-binding infraenv in another namespace")
+
+		return spoke
+	}
+
+	spoke.InfraEnvNamespace = namespace.NewBuilder(spoke.apiClient, infraEnvNamespace)
+	spoke.InfraEnvPullSecret = spoke.newPullSecret(spoke.PullSecret.Definition.Data)
+	spoke.InfraEnvPullSecret.Definition.Namespace = infraEnvNamespace
+	spoke.InfraEnv = assisted.NewInfraEnvBuilder(spoke.apiClient, spoke.Name, infraEnvNamespace, pullSecretName)
+
+	return spoke
+}
+
+// BindToCluster binds every agent registered to the spoke infraenvs to the spoke clusterdeployment. Agents already
+// bound to it are left unchanged.
+func (spoke *SpokeClusterResources) BindToCluster() error {
+	if spoke.ClusterDeployment == nil {
+		return fmt.Errorf("clusterdeployment must be defined before binding agents")
+	}
+
+	agents, err := spoke.listAgents()
+	if err != nil {
+		return err
+	}
+
+	if len(agents) == 0 {
+		return fmt.Errorf("no agents registered to the infraenv of spoke %s", spoke.Name)
+	}
+
+	clusterRef := agentInstallV1Beta1.ClusterReference{
+		Name:      spoke.ClusterDeployment.Definition.Name,
+		Namespace: spoke.ClusterDeployment.Definition.Namespace,
+	}
+
+	for _, agentObject := range agents {
+		if agentObject.Spec.ClusterDeploymentName != nil && *agentObject.Spec.ClusterDeploymentName == clusterRef {
+			continue
+		}
+
+		agent, err := assisted.PullAgent(spoke.apiClient, agentObject.Name, agentObject.Namespace)
+		if err != nil {
+			return fmt.Errorf("failed to pull agent %s: %w", agentObject.Name, err)
+		}
+
+		agent.Definition.Spec.ClusterDeploymentName = &clusterRef
+
+		if _, err := agent.Update(); err != nil {
+			return fmt.Errorf("failed to bind agent %s to clusterdeployment %s: %w",
+				agentObject.Name, clusterRef.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// deleteInfraEnvNamespace deletes the late-binding infraenv namespace unless agents in it are still bound to a
+// clusterdeployment, since deleting the namespace would remove the agents of an installed cluster.
+func (spoke *SpokeClusterResources) deleteInfraEnvNamespace(ctx context.Context) error {
+	nsName := spoke.InfraEnvNamespace.Definition.Name
+	agentList := &agentInstallV1Beta1.AgentList{}
+
+	err := spoke.apiClient.List(ctx, agentList, runtimeClient.InNamespace(nsName))
+	if err != nil {
+		return fmt.Errorf("failed to list agents in infraenv namespace %s: %w", nsName, err)
+	}
+
+	for _, agent := range agentList.Items {
+		if agent.Spec.ClusterDeploymentName != nil {
+			glog.V(ztpparams.ZTPLogLevel).Infof(
+				"Keeping infraenv namespace %s since agent %s is bound to clusterdeployment %s",
+				nsName, agent.Name, agent.Spec.ClusterDeploymentName.Name)
+
+			return nil
+		}
+	}
+
+	return spoke.deleteNamespaceAndWait(ctx, spoke.InfraEnvNamespace)
+}
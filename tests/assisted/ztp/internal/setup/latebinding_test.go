@@ -0,0 +1,102 @@
+package setup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/assisted"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWithLateBindingInfraEnv(t *testing.T) {
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultPullSecret().
+		WithLateBindingInfraEnv("")
+	assert.Nil(t, spoke.err)
+	assert.Nil(t, spoke.InfraEnvNamespace)
+	assert.Equal(t, "spoke", spoke.InfraEnv.Definition.Namespace)
+	assert.Nil(t, spoke.InfraEnv.Definition.Spec.ClusterRef)
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultPullSecret().
+		WithLateBindingInfraEnv("spoke-infraenv")
+	assert.Nil(t, spoke.err)
+	assert.Equal(t, "spoke-infraenv", spoke.InfraEnvNamespace.Definition.Name)
+	assert.Equal(t, "spoke-infraenv", spoke.InfraEnv.Definition.Namespace)
+	assert.Equal(t, "spoke-infraenv", spoke.InfraEnvPullSecret.Definition.Namespace)
+	assert.Equal(t, "spoke-pull-secret", spoke.InfraEnv.Definition.Spec.PullSecretRef.Name)
+	assert.Equal(t, spoke.PullSecret.Definition.Data, spoke.InfraEnvPullSecret.Definition.Data)
+	assert.Nil(t, spoke.InfraEnv.Definition.Spec.ClusterRef)
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithLateBindingInfraEnv("spoke-infraenv")
+	assert.EqualError(t, spoke.err,
+		"WithLateBindingInfraEnv: pull-secret must be defined before adding a late-binding infraenv in another "+
+			"namespace")
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultPullSecret().
+		WithLateBindingInfraEnv("Spoke_InfraEnv")
+	assert.ErrorContains(t, spoke.err, "invalid infraenv namespace \"Spoke_InfraEnv\"")
+}
+
+func TestBindToCluster(t *testing.T) {
+	boundAgent := buildDummyLateBindingAgent("agent-0")
+	boundAgent.Spec.ClusterDeploymentName = &agentInstallV1Beta1.ClusterReference{Name: "spoke", Namespace: "spoke"}
+
+	apiClient := newTestClient(
+		buildDummyLateBindingAgent("agent-1"), boundAgent,
+		&agentInstallV1Beta1.InfraEnv{ObjectMeta: metav1.ObjectMeta{Name: "spoke", Namespace: "spoke-infraenv"}})
+
+	spoke := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultPullSecret().WithDefaultClusterDeployment().
+		WithLateBindingInfraEnv("spoke-infraenv")
+	assert.Nil(t, spoke.BindToCluster())
+
+	for _, agentName := range []string{"agent-0", "agent-1"} {
+		agent, err := assisted.PullAgent(apiClient, agentName, "spoke-infraenv")
+		assert.Nil(t, err)
+		assert.Equal(t, &agentInstallV1Beta1.ClusterReference{Name: "spoke", Namespace: "spoke"},
+			agent.Object.Spec.ClusterDeploymentName)
+	}
+
+	spoke = NewSpokeCluster(apiClient).WithName("spoke").WithDefaultPullSecret().
+		WithLateBindingInfraEnv("spoke-infraenv")
+	assert.EqualError(t, spoke.BindToCluster(), "clusterdeployment must be defined before binding agents")
+
+	spoke = NewSpokeCluster(newTestClient(
+		&agentInstallV1Beta1.InfraEnv{ObjectMeta: metav1.ObjectMeta{Name: "spoke", Namespace: "spoke"}})).
+		WithName("spoke").WithDefaultClusterDeployment().WithLateBindingInfraEnv("")
+	assert.EqualError(t, spoke.BindToCluster(), "no agents registered to the infraenv of spoke spoke")
+}
+
+func TestDeleteLateBindingInfraEnvNamespace(t *testing.T) {
+	spoke, err := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultNamespace().WithDefaultPullSecret().
+		WithLateBindingInfraEnv("spoke-infraenv").
+		WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Second}).Create()
+	assert.Nil(t, err)
+	assert.True(t, spoke.InfraEnvNamespace.Exists())
+	assert.True(t, spoke.InfraEnvPullSecret.Exists())
+
+	assert.Nil(t, spoke.Delete())
+	assert.False(t, spoke.InfraEnvNamespace.Exists())
+	assert.False(t, spoke.InfraEnvPullSecret.Exists())
+
+	boundAgent := buildDummyLateBindingAgent("agent-0")
+	boundAgent.Spec.ClusterDeploymentName = &agentInstallV1Beta1.ClusterReference{Name: "spoke", Namespace: "spoke"}
+
+	spoke, err = NewSpokeCluster(newTestClient(boundAgent)).WithName("spoke").WithDefaultPullSecret().
+		WithLateBindingInfraEnv("spoke-infraenv").
+		WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Second}).Create()
+	assert.Nil(t, err)
+
+	assert.Nil(t, spoke.Delete())
+	assert.True(t, spoke.InfraEnvNamespace.Exists())
+}
+
+func buildDummyLateBindingAgent(name string) *agentInstallV1Beta1.Agent {
+	return &agentInstallV1Beta1.Agent{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "spoke-infraenv",
+			Labels:    map[string]string{"infraenvs.agent-install.openshift.io": "spoke"},
+		},
+	}
+}
@@ -0,0 +1,155 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-goinfra/pkg/ocm"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/ocm/clusterv1"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/ocm/kacv1"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WithDefaultManagedCluster creates a managedcluster named after the spoke, accepted by the hub and labeled like the
+// managedclusters of ZTP sites, so that the installed spoke is imported into ACM. Create creates it after the
+// agentclusterinstall and Delete detaches it before removing the other spoke resources.
+func (spoke *SpokeClusterResources) WithDefaultManagedCluster() *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	managedCluster := ocm.NewManagedClusterBuilder(spoke.apiClient, spoke.Name)
+	if managedCluster == nil {
+		spoke.err = fmt.Errorf("WithDefaultManagedCluster: failed to create managedcluster builder for spoke %s",
+			spoke.Name)
+
+		return spoke
+	}
+
+	managedCluster.WithHubAcceptsClient(true).Definition.Labels = defaultManagedClusterLabels(spoke.Name)
+	spoke.ManagedCluster = managedCluster
+
+	return spoke
+}
+
+// WithDefaultKlusterletAddonConfig creates a klusterletaddonconfig named after the spoke in the spoke namespace,
+// enabling the application manager, certificate policy controller and policy controller addons used by ZTP.
+func (spoke *SpokeClusterResources) WithDefaultKlusterletAddonConfig() *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	klusterletAddonConfig := ocm.NewKACBuilder(spoke.apiClient, spoke.Name, spoke.Name)
+	if klusterletAddonConfig == nil {
+		spoke.err = fmt.Errorf(
+			"WithDefaultKlusterletAddonConfig: failed to create klusterletaddonconfig builder for spoke %s", spoke.Name)
+
+		return spoke
+	}
+
+	klusterletAddonConfig.Definition.Spec = kacv1.KlusterletAddonConfigSpec{
+		ClusterName:                spoke.Name,
+		ClusterNamespace:           spoke.Name,
+		ClusterLabels:              defaultManagedClusterLabels(spoke.Name),
+		ApplicationManagerConfig:   kacv1.KlusterletAddonAgentConfigSpec{Enabled: true},
+		CertPolicyControllerConfig: kacv1.KlusterletAddonAgentConfigSpec{Enabled: true},
+		PolicyController:           kacv1.KlusterletAddonAgentConfigSpec{Enabled: true},
+		SearchCollectorConfig:      kacv1.KlusterletAddonAgentConfigSpec{Enabled: false},
+	}
+	spoke.KlusterletAddonConfig = klusterletAddonConfig
+
+	return spoke
+}
+
+// WaitForManagedClusterAvailable waits up to timeout, or the spoke wait timeout when it is 0, until the spoke
+// managedcluster reports the ManagedClusterConditionAvailable condition true, meaning the installed spoke joined
+// the hub.
+func (spoke *SpokeClusterResources) WaitForManagedClusterAvailable(timeout time.Duration) error {
+	return spoke.WaitForManagedClusterAvailableWithContext(context.Background(), timeout)
+}
+
+// WaitForManagedClusterAvailableWithContext waits for the managedcluster to be available like
+// WaitForManagedClusterAvailable, stopping the wait when ctx is done.
+func (spoke *SpokeClusterResources) WaitForManagedClusterAvailableWithContext(
+	ctx context.Context, timeout time.Duration) error {
+	if spoke.ManagedCluster == nil {
+		return fmt.Errorf("managedcluster must be defined before waiting for it to be available")
+	}
+
+	options := spoke.resolveWaitOptions()
+
+	if timeout > 0 {
+		options.Timeout = timeout
+		options.Interval = min(options.Interval, timeout)
+	}
+
+	var pending string
+
+	err := spoke.poll(ctx, "wait for managedcluster", options, func(ctx context.Context) (bool, error) {
+		managedCluster, err := spoke.ManagedCluster.Get()
+		if err != nil {
+			pending = fmt.Sprintf("failed to get managedcluster: %v", err)
+
+			return false, nil
+		}
+
+		available := meta.FindStatusCondition(
+			managedCluster.Status.Conditions, clusterv1.ManagedClusterConditionAvailable)
+		if available == nil {
+			pending = fmt.Sprintf("managedcluster has no %s condition", clusterv1.ManagedClusterConditionAvailable)
+
+			return false, nil
+		}
+
+		pending = fmt.Sprintf("managedcluster condition %s is %s with reason %s: %s",
+			available.Type, available.Status, available.Reason, available.Message)
+
+		return available.Status == metav1.ConditionTrue, nil
+	})
+	if err != nil {
+		return fmt.Errorf("timed out waiting for managedcluster of spoke %s to be available: %s", spoke.Name, pending)
+	}
+
+	return nil
+}
+
+// defaultManagedClusterLabels returns the labels set on the managedclusters of ZTP sites, used for both the
+// managedcluster and the cluster labels of the klusterletaddonconfig.
+func defaultManagedClusterLabels(name string) map[string]string {
+	return map[string]string{"name": name, "cloud": "auto-detect", "vendor": "auto-detect"}
+}
+
+// RemoveManagedSpoke removes a spoke attached to ACM. The managedcluster named after the spoke is detached first,
+// waiting up to timeout for it to be removed so that the klusterlet is cleaned up on the spoke, then the spoke
+// resources are removed using Delete. Spokes without a managedcluster are only removed using Delete. The returned
+// error names the phase that failed.
+func (spoke *SpokeClusterResources) RemoveManagedSpoke(timeout time.Duration) error {
+	if spoke.apiClient == nil {
+		return fmt.Errorf("apiClient cannot be nil")
+	}
+
+	managedCluster := ocm.NewManagedClusterBuilder(spoke.apiClient, spoke.Name)
+	if managedCluster == nil {
+		return fmt.Errorf("failed to create managedcluster builder for spoke %s", spoke.Name)
+	}
+
+	if managedCluster.Exists() {
+		glog.V(ztpparams.ZTPLogLevel).Infof("Detaching managedcluster %s before deleting the spoke", spoke.Name)
+
+		if err := managedCluster.DeleteAndWait(timeout); err != nil {
+			return fmt.Errorf("failed to detach managedcluster %s: %w", spoke.Name, err)
+		}
+	} else {
+		glog.V(ztpparams.ZTPLogLevel).Infof("No managedcluster found for spoke %s, deleting the spoke", spoke.Name)
+	}
+
+	if err := spoke.Delete(); err != nil {
+		return fmt.Errorf("failed to delete spoke %s resources: %w", spoke.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	hivev1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/hive/api/v1"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/ocm/clusterv1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	runtimeClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestRemoveManagedSpoke(t *testing.T) {
+	testCases := []struct {
+		objects       []runtime.Object
+		expectedOrder []string
+	}{
+		{
+			objects:       []runtime.Object{buildDummyManagedCluster(), buildDummyConditionClusterDeployment()},
+			expectedOrder: []string{"ManagedCluster", "ClusterDeployment"},
+		},
+		{
+			objects:       []runtime.Object{buildDummyConditionClusterDeployment()},
+			expectedOrder: []string{"ClusterDeployment"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		var deleted []string
+
+		apiClient := newDeleteRecordingTestClient(&deleted, nil, testCase.objects...)
+		spoke := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultClusterDeployment()
+
+		assert.Nil(t, spoke.RemoveManagedSpoke(time.Second))
+		assert.Equal(t, testCase.expectedOrder, deleted)
+	}
+}
+
+func TestRemoveManagedSpokeDetachFailure(t *testing.T) {
+	var deleted []string
+
+	apiClient := newDeleteRecordingTestClient(&deleted, errors.New("detach failed"),
+		buildDummyManagedCluster(), buildDummyConditionClusterDeployment())
+	spoke := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultClusterDeployment()
+
+	err := spoke.RemoveManagedSpoke(time.Second)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "failed to detach managedcluster spoke")
+	assert.Empty(t, deleted)
+}
+
+func TestWithDefaultManagedCluster(t *testing.T) {
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultManagedCluster().
+		WithDefaultKlusterletAddonConfig()
+	assert.Nil(t, spoke.err)
+
+	expectedLabels := map[string]string{"name": "spoke", "cloud": "auto-detect", "vendor": "auto-detect"}
+
+	assert.True(t, spoke.ManagedCluster.Definition.Spec.HubAcceptsClient)
+	assert.Equal(t, expectedLabels, spoke.ManagedCluster.Definition.Labels)
+
+	klusterletAddonConfig := spoke.KlusterletAddonConfig.Definition
+	assert.Equal(t, "spoke", klusterletAddonConfig.Namespace)
+	assert.Equal(t, "spoke", klusterletAddonConfig.Spec.ClusterName)
+	assert.Equal(t, expectedLabels, klusterletAddonConfig.Spec.ClusterLabels)
+	assert.True(t, klusterletAddonConfig.Spec.ApplicationManagerConfig.Enabled)
+	assert.True(t, klusterletAddonConfig.Spec.PolicyController.Enabled)
+	assert.False(t, klusterletAddonConfig.Spec.SearchCollectorConfig.Enabled)
+}
+
+func TestCreateAndDeleteManagedCluster(t *testing.T) {
+	var deleted []string
+
+	apiClient := newDeleteRecordingTestClient(&deleted, nil)
+	spoke := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultNamespace().
+		WithPullSecretData(map[string][]byte{".dockerconfigjson": []byte("{}")}).WithDefaultClusterDeployment().
+		WithDefaultManagedCluster().WithDefaultKlusterletAddonConfig().
+		WithLabels(map[string]string{"suite": "ztp"})
+
+	_, err := spoke.Create()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"namespace", "pull-secret", "clusterdeployment", "managedcluster", "klusterletaddonconfig",
+	}, spoke.createdResources)
+	assert.True(t, spoke.KlusterletAddonConfig.Exists())
+	assert.Equal(t, "ztp", spoke.ManagedCluster.Object.Labels["suite"])
+	assert.Equal(t, "spoke", spoke.ManagedCluster.Object.Labels["name"])
+
+	adopted, err := Adopt(apiClient, "spoke")
+	assert.Nil(t, err)
+	assert.NotNil(t, adopted.ManagedCluster)
+	assert.NotNil(t, adopted.KlusterletAddonConfig)
+
+	assert.Nil(t, spoke.Delete())
+	assert.Equal(t, []string{"ManagedCluster", "ClusterDeployment"}, deleted)
+	assert.False(t, spoke.ManagedCluster.Exists())
+	assert.False(t, spoke.KlusterletAddonConfig.Exists())
+}
+
+func TestWaitForManagedClusterAvailable(t *testing.T) {
+	waitOptions := &WaitOptions{Interval: time.Millisecond, Timeout: 10 * time.Millisecond}
+
+	err := NewSpokeCluster(newTestClient()).WithName("spoke").WaitForManagedClusterAvailable(0)
+	assert.EqualError(t, err, "managedcluster must be defined before waiting for it to be available")
+
+	availableManagedCluster := buildDummyManagedCluster()
+	availableManagedCluster.Status.Conditions = append(availableManagedCluster.Status.Conditions,
+		metav1.Condition{Type: clusterv1.ManagedClusterConditionAvailable, Status: metav1.ConditionTrue})
+
+	spoke := NewSpokeCluster(newDeleteRecordingTestClient(&[]string{}, nil, availableManagedCluster)).
+		WithName("spoke").WithDefaultManagedCluster().WithWaitOptions(waitOptions)
+	assert.Nil(t, spoke.WaitForManagedClusterAvailable(0))
+
+	spoke = NewSpokeCluster(newDeleteRecordingTestClient(&[]string{}, nil, buildDummyManagedCluster())).
+		WithName("spoke").WithDefaultManagedCluster().WithWaitOptions(waitOptions)
+	assert.EqualError(t, spoke.WaitForManagedClusterAvailable(0), "timed out waiting for managedcluster of spoke "+
+		"spoke to be available: managedcluster has no ManagedClusterConditionAvailable condition")
+}
+
+// newDeleteRecordingTestClient returns a fake client holding objects that appends the kind of every deleted
+// managedcluster and clusterdeployment to deleted. When managedClusterErr is not nil, managedcluster deletions
+// fail with it.
+func newDeleteRecordingTestClient(
+	deleted *[]string, managedClusterErr error, objects ...runtime.Object) *clients.Settings {
+	apiClient, testBuilder := clients.GetModifiableTestClients(clients.TestClientParams{
+		K8sMockObjects: objects,
+		SchemeAttachers: []clients.SchemeAttacher{
+			v1beta1.AddToScheme,
+			agentInstallV1Beta1.AddToScheme,
+			hivev1.AddToScheme,
+			clusterv1.Install,
+		},
+	})
+
+	apiClient.Client = testBuilder.WithInterceptorFuncs(interceptor.Funcs{
+		Delete: func(ctx context.Context, client runtimeClient.WithWatch,
+			obj runtimeClient.Object, opts ...runtimeClient.DeleteOption) error {
+			switch obj.(type) {
+			case *clusterv1.ManagedCluster:
+				if managedClusterErr != nil {
+					return managedClusterErr
+				}
+
+				*deleted = append(*deleted, "ManagedCluster")
+			case *hivev1.ClusterDeployment:
+				*deleted = append(*deleted, "ClusterDeployment")
+			}
+
+			return client.Delete(ctx, obj, opts...)
+		},
+	}).Build()
+
+	return apiClient
+}
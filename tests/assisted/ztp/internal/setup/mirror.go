@@ -0,0 +1,181 @@
+package setup
+
+import (
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/containers/image/v5/pkg/sysregistriesv2"
+	"github.com/hashicorp/go-version"
+	"github.com/openshift-kni/eco-goinfra/pkg/configmap"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// MirrorRegistryCABundleKey is the mirror registry configmap key holding the registry CA bundle.
+	MirrorRegistryCABundleKey = "ca-bundle.crt"
+	// MirrorRegistryConfKey is the mirror registry configmap key holding the registries.conf mirror configuration.
+	MirrorRegistryConfKey = "registries.conf"
+
+	// mirrorSetName is the name of the imagedigestmirrorset or imagecontentsourcepolicy of the installed spoke.
+	mirrorSetName = "mirror-registry"
+)
+
+// imageDigestMirrorSetMinVersion is the first release supporting imagedigestmirrorsets, older spokes get an
+// imagecontentsourcepolicy instead.
+var imageDigestMirrorSetMinVersion = version.Must(version.NewVersion("4.13"))
+
+// registryMirror is a source repository and the mirrors it is pulled from by digest.
+type registryMirror struct {
+	source  string
+	mirrors []string
+}
+
+// WithMirrorRegistry configures the spoke for a disconnected mirror registry. A configmap holding the CA bundle and
+// registries.conf, in the same format as the agentserviceconfig mirror registry configmap, is created in the spoke
+// namespace and the CA bundle is set as the infraenv additional trust bundle so discovery hosts trust the mirror.
+// Either value may be empty, but not both. The mirrors of registries.conf are also added to the spoke extra manifests
+// as an imagedigestmirrorset, or an imagecontentsourcepolicy for spokes older than 4.13, so the installed cluster
+// keeps pulling by digest from the mirror.
+func (spoke *SpokeClusterResources) WithMirrorRegistry(caBundle, registriesConf string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.InfraEnv == nil {
+		spoke.err = fmt.Errorf("WithMirrorRegistry: infraenv must be defined before setting the mirror registry")
+
+		return spoke
+	}
+
+	if caBundle == "" && registriesConf == "" {
+		spoke.err = fmt.Errorf("WithMirrorRegistry: mirror registry requires a ca bundle or a registries.conf")
+
+		return spoke
+	}
+
+	data := map[string]string{}
+
+	if caBundle != "" {
+		if block, _ := pem.Decode([]byte(caBundle)); block == nil {
+			spoke.err = fmt.Errorf("WithMirrorRegistry: mirror registry ca bundle is not PEM encoded")
+
+			return spoke
+		}
+
+		data[MirrorRegistryCABundleKey] = caBundle
+		spoke.InfraEnv.Definition.Spec.AdditionalTrustBundle = caBundle
+	}
+
+	if strings.TrimSpace(registriesConf) != "" {
+		mirrors, err := parseRegistryMirrors(registriesConf)
+		if err != nil {
+			spoke.err = fmt.Errorf("WithMirrorRegistry: %w", err)
+
+			return spoke
+		}
+
+		if len(mirrors) > 0 {
+			manifestName, manifest, err := spoke.mirrorSetManifest(mirrors)
+			if err != nil {
+				spoke.err = fmt.Errorf("WithMirrorRegistry: %w", err)
+
+				return spoke
+			}
+
+			spoke.addExtraManifest(fmt.Sprintf("%s-mirror-manifests", spoke.Name), manifestName, manifest)
+		}
+
+		data[MirrorRegistryConfKey] = registriesConf
+	}
+
+	spoke.MirrorRegistryConfigMap = configmap.NewBuilder(
+		spoke.apiClient, fmt.Sprintf("%s-mirror-registry", spoke.Name), spoke.Name).WithData(data)
+
+	return spoke
+}
+
+// parseRegistryMirrors returns the registries of registriesConf that have mirrors, in order. The prefix of a
+// registry is its source when set, otherwise its location.
+func parseRegistryMirrors(registriesConf string) ([]registryMirror, error) {
+	var conf sysregistriesv2.V2RegistriesConf
+
+	if _, err := toml.Decode(registriesConf, &conf); err != nil {
+		return nil, fmt.Errorf("failed to parse registries.conf: %w", err)
+	}
+
+	var mirrors []registryMirror
+
+	for _, registry := range conf.Registries {
+		source := registry.Prefix
+		if source == "" {
+			source = registry.Location
+		}
+
+		if len(registry.Mirrors) == 0 {
+			continue
+		}
+
+		if source == "" {
+			return nil, fmt.Errorf("registries.conf registry with mirrors requires a prefix or location")
+		}
+
+		mirror := registryMirror{source: source}
+
+		for _, endpoint := range registry.Mirrors {
+			if endpoint.Location == "" {
+				return nil, fmt.Errorf("registries.conf mirror of %s requires a location", source)
+			}
+
+			mirror.mirrors = append(mirror.mirrors, endpoint.Location)
+		}
+
+		mirrors = append(mirrors, mirror)
+	}
+
+	return mirrors, nil
+}
+
+// mirrorSetManifest returns the file name and content of the imagedigestmirrorset, or imagecontentsourcepolicy
+// when the spoke clusterimageset is older than 4.13, configuring the mirrors on the installed spoke.
+func (spoke *SpokeClusterResources) mirrorSetManifest(mirrors []registryMirror) (string, string, error) {
+	useImageContentSourcePolicy := false
+
+	if imageSetXY, found := spoke.imageSetXYVersion(); found {
+		if imageSetVersion, err := version.NewVersion(imageSetXY); err == nil {
+			useImageContentSourcePolicy = imageSetVersion.LessThan(imageDigestMirrorSetMinVersion)
+		}
+	}
+
+	var entries []map[string]interface{}
+
+	for _, mirror := range mirrors {
+		entries = append(entries, map[string]interface{}{"source": mirror.source, "mirrors": mirror.mirrors})
+	}
+
+	fileName := "99-image-digest-mirror-set.yaml"
+	manifest := map[string]interface{}{
+		"apiVersion": "config.openshift.io/v1",
+		"kind":       "ImageDigestMirrorSet",
+		"metadata":   map[string]interface{}{"name": mirrorSetName},
+		"spec":       map[string]interface{}{"imageDigestMirrors": entries},
+	}
+
+	if useImageContentSourcePolicy {
+		fileName = "99-image-content-source-policy.yaml"
+		manifest = map[string]interface{}{
+			"apiVersion": "operator.openshift.io/v1alpha1",
+			"kind":       "ImageContentSourcePolicy",
+			"metadata":   map[string]interface{}{"name": mirrorSetName},
+			"spec":       map[string]interface{}{"repositoryDigestMirrors": entries},
+		}
+	}
+
+	content, err := yaml.Marshal(manifest)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to marshal %s: %w", manifest["kind"], err)
+	}
+
+	return fileName, string(content), nil
+}
@@ -0,0 +1,149 @@
+package setup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	testMirrorCABundle       = "-----BEGIN CERTIFICATE-----\nMIIBtest\n-----END CERTIFICATE-----\n"
+	testMirrorRegistriesConf = "[[registry]]\nlocation = \"quay.io\"\n\n[[registry.mirror]]\n" +
+		"location = \"mirror.example.com:5000\"\n"
+)
+
+func TestWithMirrorRegistry(t *testing.T) {
+	testCases := []struct {
+		caBundle       string
+		registriesConf string
+		expectedData   map[string]string
+		expectedError  string
+	}{
+		{
+			caBundle:       testMirrorCABundle,
+			registriesConf: testMirrorRegistriesConf,
+			expectedData: map[string]string{
+				MirrorRegistryCABundleKey: testMirrorCABundle,
+				MirrorRegistryConfKey:     testMirrorRegistriesConf,
+			},
+		},
+		{
+			caBundle:     testMirrorCABundle,
+			expectedData: map[string]string{MirrorRegistryCABundleKey: testMirrorCABundle},
+		},
+		{
+			registriesConf: testMirrorRegistriesConf,
+			expectedData:   map[string]string{MirrorRegistryConfKey: testMirrorRegistriesConf},
+		},
+		{expectedError: "WithMirrorRegistry: mirror registry requires a ca bundle or a registries.conf"},
+		{
+			caBundle:      "not a certificate",
+			expectedError: "WithMirrorRegistry: mirror registry ca bundle is not PEM encoded",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := NewSpokeCluster(newTestClient()).WithName("mirror-spoke").WithDefaultInfraEnv().
+			WithMirrorRegistry(testCase.caBundle, testCase.registriesConf)
+
+		if testCase.expectedError != "" {
+			assert.EqualError(t, spoke.err, testCase.expectedError)
+			assert.Nil(t, spoke.MirrorRegistryConfigMap)
+
+			continue
+		}
+
+		assert.Nil(t, spoke.err)
+		assert.Equal(t, "mirror-spoke-mirror-registry", spoke.MirrorRegistryConfigMap.Definition.Name)
+		assert.Equal(t, "mirror-spoke", spoke.MirrorRegistryConfigMap.Definition.Namespace)
+		assert.Equal(t, testCase.expectedData, spoke.MirrorRegistryConfigMap.Definition.Data)
+		assert.Equal(t, testCase.caBundle, spoke.InfraEnv.Definition.Spec.AdditionalTrustBundle)
+	}
+
+	spoke := NewSpokeCluster(newTestClient()).WithName("mirror-spoke").
+		WithMirrorRegistry(testMirrorCABundle, testMirrorRegistriesConf)
+	assert.EqualError(t, spoke.err, "WithMirrorRegistry: infraenv must be defined before setting the mirror registry")
+}
+
+func TestMirrorRegistryCreateAndDelete(t *testing.T) {
+	spoke, err := NewSpokeCluster(newTestClient()).WithName("mirror-spoke").WithDefaultPullSecret().
+		WithDefaultInfraEnv().WithMirrorRegistry(testMirrorCABundle, testMirrorRegistriesConf).Create()
+	assert.Nil(t, err)
+	assert.True(t, spoke.MirrorRegistryConfigMap.Exists())
+	assert.Equal(t, testMirrorCABundle, spoke.InfraEnv.Object.Spec.AdditionalTrustBundle)
+
+	assert.Nil(t, spoke.Delete())
+	assert.False(t, spoke.MirrorRegistryConfigMap.Exists())
+}
+
+func TestWithMirrorRegistryManifests(t *testing.T) {
+	registriesConf := "[[registry]]\nprefix = \"quay.io/openshift-release-dev/ocp-release\"\n" +
+		"location = \"quay.io/openshift-release-dev/ocp-release\"\n\n" +
+		"[[registry.mirror]]\nlocation = \"mirror.example.com:5000/ocp/release\"\n\n" +
+		"[[registry.mirror]]\nlocation = \"backup.example.com:5000/ocp/release\"\n\n" +
+		"[[registry]]\nlocation = \"registry.redhat.io\"\n\n" +
+		"[[registry.mirror]]\nlocation = \"mirror.example.com:5000/redhat\"\n\n" +
+		"[[registry]]\nlocation = \"docker.io\"\n"
+
+	testCases := []struct {
+		imageSet         string
+		expectedFileName string
+		expectedKind     string
+		expectedMirrors  string
+	}{
+		{
+			imageSet:         testHubOCPXYVersion,
+			expectedFileName: "99-image-digest-mirror-set.yaml",
+			expectedKind:     "ImageDigestMirrorSet",
+			expectedMirrors:  "imageDigestMirrors",
+		},
+		{
+			imageSet:         "4.12",
+			expectedFileName: "99-image-content-source-policy.yaml",
+			expectedKind:     "ImageContentSourcePolicy",
+			expectedMirrors:  "repositoryDigestMirrors",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := NewSpokeCluster(newTestClient()).WithName("mirror-spoke").WithDefaultInfraEnv().
+			WithDefaultIPv4AgentClusterInstall()
+		spoke.AgentClusterInstall.WithImageSet(testCase.imageSet)
+		spoke.WithMirrorRegistry(testMirrorCABundle, registriesConf)
+
+		assert.Nil(t, spoke.err, testCase.imageSet)
+		assert.Len(t, spoke.ExtraManifests, 1, testCase.imageSet)
+		assert.Equal(t, "mirror-spoke-mirror-manifests", spoke.ExtraManifests[0].Definition.Name, testCase.imageSet)
+
+		manifest := map[string]interface{}{}
+		assert.Nil(t, yaml.Unmarshal([]byte(spoke.ExtraManifests[0].Definition.Data[testCase.expectedFileName]),
+			&manifest), testCase.imageSet)
+		assert.Equal(t, testCase.expectedKind, manifest["kind"], testCase.imageSet)
+		assert.Equal(t, map[interface{}]interface{}{"name": "mirror-registry"}, manifest["metadata"], testCase.imageSet)
+		assert.Equal(t, map[interface{}]interface{}{testCase.expectedMirrors: []interface{}{
+			map[interface{}]interface{}{
+				"source": "quay.io/openshift-release-dev/ocp-release",
+				"mirrors": []interface{}{
+					"mirror.example.com:5000/ocp/release", "backup.example.com:5000/ocp/release",
+				},
+			},
+			map[interface{}]interface{}{
+				"source": "registry.redhat.io", "mirrors": []interface{}{"mirror.example.com:5000/redhat"},
+			},
+		}}, manifest["spec"], testCase.imageSet)
+	}
+
+	spoke := NewSpokeCluster(newTestClient()).WithName("mirror-spoke").WithDefaultInfraEnv().
+		WithMirrorRegistry("", "[[registry]]\nlocation = \"docker.io\"\n")
+	assert.Nil(t, spoke.err)
+	assert.Empty(t, spoke.ExtraManifests, "registries without mirrors need no manifest")
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("mirror-spoke").WithDefaultInfraEnv().
+		WithMirrorRegistry("", "[[registry]\n")
+	assert.ErrorContains(t, spoke.err, "WithMirrorRegistry: failed to parse registries.conf")
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("mirror-spoke").WithDefaultInfraEnv().
+		WithMirrorRegistry("", "[[registry]]\nlocation = \"quay.io\"\n\n[[registry.mirror]]\ninsecure = true\n")
+	assert.EqualError(t, spoke.err, "WithMirrorRegistry: registries.conf mirror of quay.io requires a location")
+}
@@ -0,0 +1,85 @@
+package setup
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/namespace"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	generatedNameLength   = 12
+	generatedNameAttempts = 2
+	generatedNameLetters  = "abcdefghijklmnopqrstuvwxyz"
+)
+
+// nameRandReader is the source of randomness of the generated spoke names.
+var nameRandReader io.Reader = rand.Reader
+
+// generateUnusedName returns prefix followed by a random suffix, truncating prefix so that the name fits in a
+// DNS-1123 label. The name is regenerated when its namespace already exists on the hub, up to the number of
+// generated name attempts.
+func (spoke *SpokeClusterResources) generateUnusedName(prefix string) (string, error) {
+	prefix = prefix[:min(len(prefix), validation.DNS1123LabelMaxLength-generatedNameLength)]
+
+	if prefix != "" {
+		if errs := validation.IsDNS1123Label(prefix + generatedNameLetters[:1]); len(errs) > 0 {
+			return "", fmt.Errorf("invalid name prefix %q: %s", prefix, strings.Join(errs, ", "))
+		}
+	}
+
+	var existing []string
+
+	for range generatedNameAttempts {
+		suffix, err := generateName(generatedNameLength)
+		if err != nil {
+			return "", err
+		}
+
+		name := prefix + suffix
+		if spoke.apiClient == nil || !namespace.NewBuilder(spoke.apiClient, name).Exists() {
+			return name, nil
+		}
+
+		existing = append(existing, name)
+	}
+
+	return "", fmt.Errorf("namespaces of generated spoke names %s already exist", strings.Join(existing, ", "))
+}
+
+// verifyGeneratedNamespace returns an error when the spoke name was generated and its namespace was created on the
+// hub by someone else since, so that the spoke of another process using the same name is not adopted.
+func (spoke *SpokeClusterResources) verifyGeneratedNamespace() error {
+	if !spoke.generatedName || !spoke.Namespace.Exists() {
+		return nil
+	}
+
+	return fmt.Errorf("namespace %s of generated spoke name already exists and was not created by this spoke",
+		spoke.Name)
+}
+
+// generateName generates a random string of lowercase letters matching the length supplied. Random bytes outside
+// the largest multiple of the number of letters are discarded so that every letter is equally likely.
+func generateName(n int) (string, error) {
+	const maxUnbiased = 256 - 256%len(generatedNameLetters)
+
+	name := make([]byte, 0, n)
+	buffer := make([]byte, n)
+
+	for len(name) < n {
+		if _, err := io.ReadFull(nameRandReader, buffer); err != nil {
+			return "", fmt.Errorf("failed to generate spoke name: %w", err)
+		}
+
+		for _, value := range buffer {
+			if int(value) < maxUnbiased && len(name) < n {
+				name = append(name, generatedNameLetters[int(value)%len(generatedNameLetters)])
+			}
+		}
+	}
+
+	return string(name), nil
+}
@@ -0,0 +1,118 @@
+package setup
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestGenerateNameUnique(t *testing.T) {
+	const generations = 10000
+
+	names := make(map[string]bool, generations)
+
+	for range generations {
+		name, err := generateName(generatedNameLength)
+		assert.Nil(t, err)
+		assert.Len(t, name, generatedNameLength)
+		assert.Empty(t, validation.IsDNS1123Label(name))
+		assert.False(t, names[name], "name %s generated twice", name)
+
+		names[name] = true
+	}
+}
+
+func TestWithAutoGeneratedName(t *testing.T) {
+	testCases := []struct {
+		prefix         []string
+		expectedPrefix string
+		expectedErr    string
+	}{
+		{prefix: nil, expectedPrefix: ""},
+		{prefix: []string{""}, expectedPrefix: ""},
+		{prefix: []string{"ztp-spoke-"}, expectedPrefix: "ztp-spoke-"},
+		{
+			prefix:         []string{strings.Repeat("ztp-", 15)},
+			expectedPrefix: strings.Repeat("ztp-", 15)[:validation.DNS1123LabelMaxLength-generatedNameLength],
+		},
+		{
+			prefix:      []string{"ZTP_"},
+			expectedErr: `WithAutoGeneratedName: invalid name prefix "ZTP_": `,
+		},
+		{
+			prefix:      []string{"-ztp"},
+			expectedErr: `WithAutoGeneratedName: invalid name prefix "-ztp": `,
+		},
+		{
+			prefix:      []string{"ztp-", "spoke-"},
+			expectedErr: "WithAutoGeneratedName: at most one name prefix can be provided, got 2",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := NewSpokeCluster(newTestClient()).WithAutoGeneratedName(testCase.prefix...)
+
+		if testCase.expectedErr != "" {
+			assert.ErrorContains(t, spoke.err, testCase.expectedErr)
+			assert.Empty(t, spoke.Name)
+
+			continue
+		}
+
+		assert.Nil(t, spoke.err)
+		assert.True(t, strings.HasPrefix(spoke.Name, testCase.expectedPrefix), "name: %s", spoke.Name)
+		assert.Len(t, spoke.Name, len(testCase.expectedPrefix)+generatedNameLength)
+		assert.Empty(t, validation.IsDNS1123Label(spoke.Name))
+	}
+}
+
+func TestWithAutoGeneratedNameCollision(t *testing.T) {
+	originalReader := nameRandReader
+
+	t.Cleanup(func() {
+		nameRandReader = originalReader
+	})
+
+	existingNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ztp-aaaaaaaaaaaa"}}
+
+	nameRandReader = bytes.NewReader(append(bytes.Repeat([]byte{0}, 12), bytes.Repeat([]byte{1}, 12)...))
+	spoke := NewSpokeCluster(newTestClient(existingNamespace)).WithAutoGeneratedName("ztp-")
+	assert.Nil(t, spoke.err)
+	assert.Equal(t, "ztp-bbbbbbbbbbbb", spoke.Name)
+
+	nameRandReader = bytes.NewReader(bytes.Repeat([]byte{0}, 24))
+	spoke = NewSpokeCluster(newTestClient(existingNamespace)).WithAutoGeneratedName("ztp-")
+	assert.EqualError(t, spoke.err, "WithAutoGeneratedName: namespaces of generated spoke names ztp-aaaaaaaaaaaa, "+
+		"ztp-aaaaaaaaaaaa already exist")
+
+	nameRandReader = bytes.NewReader(nil)
+	spoke = NewSpokeCluster(newTestClient()).WithAutoGeneratedName()
+	assert.EqualError(t, spoke.err, "WithAutoGeneratedName: failed to generate spoke name: EOF")
+}
+
+func TestCreateGeneratedNameNamespaceExists(t *testing.T) {
+	apiClient := newHubTestClient()
+
+	spoke := NewSpokeCluster(apiClient).WithAutoGeneratedName("ztp-").WithDefaultNamespace()
+	assert.Nil(t, spoke.err)
+
+	_, err := NewSpokeCluster(apiClient).WithName(spoke.Name).WithDefaultNamespace().Create()
+	assert.Nil(t, err)
+
+	_, err = spoke.Create()
+	assert.EqualError(t, err, "namespace "+spoke.Name+" of generated spoke name already exists and was not "+
+		"created by this spoke")
+
+	spoke = NewSpokeCluster(apiClient).WithAutoGeneratedName("ztp-").WithDefaultNamespace()
+
+	_, err = spoke.Create()
+	assert.Nil(t, err)
+
+	_, err = spoke.Create()
+	assert.Nil(t, err)
+}
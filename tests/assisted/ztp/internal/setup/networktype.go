@@ -0,0 +1,86 @@
+package setup
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/models"
+)
+
+// ruleSDNRequiresIPv4 is the networking rule enforced by the assisted service for the OpenShiftSDN network type.
+const ruleSDNRequiresIPv4 = "the OpenShiftSDN network type only supports IPv4 networking"
+
+// supportedNetworkTypes are the network types accepted by WithNetworkType.
+var supportedNetworkTypes = []string{models.ClusterNetworkTypeOVNKubernetes, models.ClusterNetworkTypeOpenShiftSDN}
+
+// WithNetworkType sets the network type of the spoke agentclusterinstall, either OVNKubernetes or OpenShiftSDN.
+// OpenShiftSDN is rejected when the agentclusterinstall networking is IPv6 or dual-stack.
+func (spoke *SpokeClusterResources) WithNetworkType(networkType string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.AgentClusterInstall == nil {
+		spoke.err = fmt.Errorf("WithNetworkType: agentclusterinstall must be defined before setting the network type")
+
+		return spoke
+	}
+
+	if !slices.Contains(supportedNetworkTypes, networkType) {
+		spoke.err = fmt.Errorf("WithNetworkType: invalid network type %q, must be one of %v",
+			networkType, supportedNetworkTypes)
+
+		return spoke
+	}
+
+	if err := spoke.validateNetworkType(networkType); err != nil {
+		spoke.err = fmt.Errorf("WithNetworkType: %w", err)
+
+		return spoke
+	}
+
+	spoke.AgentClusterInstall.Definition.Spec.Networking.NetworkType = networkType
+
+	return spoke
+}
+
+// NetworkType returns the network type of the spoke agentclusterinstall, or an empty string when it is not set
+// and the assisted service picks the default network type.
+func (spoke *SpokeClusterResources) NetworkType() string {
+	if spoke.AgentClusterInstall == nil {
+		return ""
+	}
+
+	return spoke.AgentClusterInstall.Definition.Spec.Networking.NetworkType
+}
+
+// validateNetworkType checks that networkType is compatible with the address families of the agentclusterinstall
+// networks.
+func (spoke *SpokeClusterResources) validateNetworkType(networkType string) error {
+	if spoke.AgentClusterInstall == nil || networkType != models.ClusterNetworkTypeOpenShiftSDN {
+		return nil
+	}
+
+	networking := spoke.AgentClusterInstall.Definition.Spec.Networking
+
+	var cidrs []string
+
+	for _, clusterNetwork := range networking.ClusterNetwork {
+		cidrs = append(cidrs, clusterNetwork.CIDR)
+	}
+
+	cidrs = append(cidrs, networking.ServiceNetwork...)
+
+	for _, machineNetwork := range networking.MachineNetwork {
+		cidrs = append(cidrs, machineNetwork.CIDR)
+	}
+
+	for _, cidr := range cidrs {
+		if !isIPv4CIDR(cidr) {
+			return fmt.Errorf("invalid agentclusterinstall networking: %s, found IPv6 network %s",
+				ruleSDNRequiresIPv4, cidr)
+		}
+	}
+
+	return nil
+}
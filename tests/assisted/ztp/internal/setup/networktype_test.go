@@ -0,0 +1,88 @@
+package setup
+
+import (
+	"testing"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithNetworkType(t *testing.T) {
+	testCases := []struct {
+		name        string
+		spoke       func() *SpokeClusterResources
+		networkType string
+		expectedErr string
+	}{
+		{
+			name:        "ipv4 ovn",
+			spoke:       NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultIPv4AgentClusterInstall,
+			networkType: models.ClusterNetworkTypeOVNKubernetes,
+		},
+		{
+			name:        "ipv4 sdn",
+			spoke:       NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultIPv4AgentClusterInstall,
+			networkType: models.ClusterNetworkTypeOpenShiftSDN,
+		},
+		{
+			name:        "ipv6 ovn",
+			spoke:       NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultIPv6AgentClusterInstall,
+			networkType: models.ClusterNetworkTypeOVNKubernetes,
+		},
+		{
+			name:        "ipv6 sdn",
+			spoke:       NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultIPv6AgentClusterInstall,
+			networkType: models.ClusterNetworkTypeOpenShiftSDN,
+			expectedErr: "WithNetworkType: invalid agentclusterinstall networking: the OpenShiftSDN network type " +
+				"only supports IPv4 networking, found IPv6 network fd01::/48",
+		},
+		{
+			name:        "dual-stack sdn",
+			spoke:       NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultDualStackAgentClusterInstall,
+			networkType: models.ClusterNetworkTypeOpenShiftSDN,
+			expectedErr: "WithNetworkType: invalid agentclusterinstall networking: the OpenShiftSDN network type " +
+				"only supports IPv4 networking, found IPv6 network fd01::/48",
+		},
+		{
+			name:        "invalid network type",
+			spoke:       NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultIPv4AgentClusterInstall,
+			networkType: "Calico",
+			expectedErr: `WithNetworkType: invalid network type "Calico", must be one of [OVNKubernetes OpenShiftSDN]`,
+		},
+		{
+			name:        "missing agentclusterinstall",
+			spoke:       NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultNamespace,
+			networkType: models.ClusterNetworkTypeOVNKubernetes,
+			expectedErr: "WithNetworkType: agentclusterinstall must be defined before setting the network type",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := testCase.spoke().WithNetworkType(testCase.networkType)
+
+		if testCase.expectedErr != "" {
+			assert.EqualError(t, spoke.err, testCase.expectedErr, testCase.name)
+
+			continue
+		}
+
+		assert.Nil(t, spoke.err, testCase.name)
+		assert.Equal(t, testCase.networkType, spoke.NetworkType(), testCase.name)
+		assert.Equal(t, testCase.networkType,
+			spoke.AgentClusterInstall.Definition.Spec.Networking.NetworkType, testCase.name)
+	}
+}
+
+func TestValidateNetworkType(t *testing.T) {
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultIPv4AgentClusterInstall().
+		WithNetworkType(models.ClusterNetworkTypeOpenShiftSDN)
+	assert.Nil(t, spoke.err)
+
+	spoke.AgentClusterInstall.Definition.Spec.Networking.ServiceNetwork = []string{"fd02::/112"}
+
+	assert.EqualError(t, spoke.validateNetworkType(spoke.NetworkType()),
+		"invalid agentclusterinstall networking: the OpenShiftSDN network type only supports IPv4 networking, "+
+			"found IPv6 network fd02::/112")
+
+	assert.Empty(t, NewSpokeCluster(newTestClient()).NetworkType())
+}
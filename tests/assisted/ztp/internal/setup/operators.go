@@ -0,0 +1,214 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/olm"
+	oplmV1alpha1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/olm/operators/v1alpha1"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+)
+
+// operatorManifestTemplate installs an operator from the redhat-operators catalog into its own namespace.
+const operatorManifestTemplate = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: %[1]s
+---
+apiVersion: operators.coreos.com/v1
+kind: OperatorGroup
+metadata:
+  name: %[1]s
+  namespace: %[1]s
+spec:
+  targetNamespaces:
+  - %[1]s
+---
+apiVersion: operators.coreos.com/v1alpha1
+kind: Subscription
+metadata:
+  name: %[2]s
+  namespace: %[1]s
+spec:
+  name: %[2]s
+  source: redhat-operators
+  sourceNamespace: openshift-marketplace
+  installPlanApproval: Automatic
+`
+
+// spokeOperator is an OLM operator the assisted service can install along with the spoke.
+type spokeOperator struct {
+	namespace  string
+	pkg        string
+	csvPrefix  string
+	minWorkers int
+}
+
+// spokeOperators lists the operators WithInstallOperators accepts, keyed by their assisted-service name.
+var spokeOperators = map[string]spokeOperator{
+	"cnv": {namespace: "openshift-cnv", pkg: "kubevirt-hyperconverged", csvPrefix: "kubevirt-hyperconverged-operator."},
+	"lso": {namespace: "openshift-local-storage", pkg: "local-storage-operator", csvPrefix: "local-storage-operator."},
+	"lvm": {namespace: "openshift-storage", pkg: "lvms-operator", csvPrefix: "lvms-operator."},
+	"mce": {namespace: "multicluster-engine", pkg: "multicluster-engine", csvPrefix: "multicluster-engine."},
+	"nmstate": {
+		namespace: "openshift-nmstate", pkg: "kubernetes-nmstate-operator", csvPrefix: "kubernetes-nmstate-operator.",
+	},
+	"odf": {namespace: "openshift-storage", pkg: "odf-operator", csvPrefix: "odf-operator.", minWorkers: 3},
+}
+
+// WithInstallOperators installs the operators, by their assisted-service name such as lvm, cnv, mce or odf, on the
+// spoke during the install. The namespace, operatorgroup and subscription of each operator are added to the spoke
+// extra manifests referenced by the agentclusterinstall. Validate checks the agentclusterinstall has the workers
+// required by the operators.
+func (spoke *SpokeClusterResources) WithInstallOperators(operators ...string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.AgentClusterInstall == nil {
+		spoke.err = fmt.Errorf("WithInstallOperators: agentclusterinstall must be defined before installing operators")
+
+		return spoke
+	}
+
+	if len(operators) == 0 {
+		spoke.err = fmt.Errorf("WithInstallOperators: operators cannot be empty")
+
+		return spoke
+	}
+
+	selected := slices.Clone(spoke.installOperators)
+
+	for _, operator := range operators {
+		if _, found := spokeOperators[operator]; !found {
+			spoke.err = fmt.Errorf("WithInstallOperators: unsupported operator %q, must be one of %s",
+				operator, strings.Join(slices.Sorted(maps.Keys(spokeOperators)), ", "))
+
+			return spoke
+		}
+
+		if !slices.Contains(selected, operator) {
+			selected = append(selected, operator)
+		}
+	}
+
+	for _, operator := range selected {
+		for _, other := range selected {
+			if operator < other && spokeOperators[operator].namespace == spokeOperators[other].namespace {
+				spoke.err = fmt.Errorf("WithInstallOperators: operators %s and %s cannot be installed together",
+					operator, other)
+
+				return spoke
+			}
+		}
+	}
+
+	for _, operator := range selected[len(spoke.installOperators):] {
+		spokeOperator := spokeOperators[operator]
+
+		spoke.addExtraManifest(spoke.Name+"-operators", operator+"-operator.yaml",
+			fmt.Sprintf(operatorManifestTemplate, spokeOperator.namespace, spokeOperator.pkg))
+	}
+
+	spoke.installOperators = selected
+	spoke.attachExtraManifests()
+
+	return spoke
+}
+
+// VerifyOperatorsInstalled waits up to timeout, or the spoke wait timeout when it is 0, until the
+// clusterserviceversion of every operator set using WithInstallOperators reaches the Succeeded phase on the spoke
+// reached by spokeClient. On timeout, the error lists the operators that are not installed yet.
+func (spoke *SpokeClusterResources) VerifyOperatorsInstalled(
+	spokeClient *clients.Settings, timeout time.Duration) error {
+	return spoke.VerifyOperatorsInstalledWithContext(context.Background(), spokeClient, timeout)
+}
+
+// VerifyOperatorsInstalledWithContext waits for the operators to be installed like VerifyOperatorsInstalled,
+// stopping the wait when ctx is done.
+func (spoke *SpokeClusterResources) VerifyOperatorsInstalledWithContext(
+	ctx context.Context, spokeClient *clients.Settings, timeout time.Duration) error {
+	if spokeClient == nil {
+		return fmt.Errorf("spokeClient cannot be nil")
+	}
+
+	options := spoke.resolveWaitOptions()
+
+	if timeout > 0 {
+		options.Timeout = timeout
+		options.Interval = min(options.Interval, timeout)
+	}
+
+	var pending []string
+
+	err := spoke.poll(ctx, "wait for operators installed", options, func(ctx context.Context) (bool, error) {
+		pending = nil
+
+		for _, operator := range slices.Sorted(slices.Values(spoke.installOperators)) {
+			if reason := operatorNotInstalledReason(spokeClient, operator); reason != "" {
+				glog.V(ztpparams.ZTPLogLevel).Infof("Operator %s of spoke %s is not installed: %s",
+					operator, spoke.Name, reason)
+
+				pending = append(pending, fmt.Sprintf("%s %s", operator, reason))
+			}
+		}
+
+		return len(pending) == 0, nil
+	})
+	if err != nil {
+		return fmt.Errorf("timed out waiting for operators of spoke %s to be installed: %s",
+			spoke.Name, strings.Join(pending, "; "))
+	}
+
+	return nil
+}
+
+// operatorNotInstalledReason returns why the clusterserviceversion of operator has not succeeded on the spoke, or
+// an empty string once it has.
+func operatorNotInstalledReason(spokeClient *clients.Settings, operator string) string {
+	spokeOperator := spokeOperators[operator]
+
+	csvs, err := olm.ListClusterServiceVersion(spokeClient, spokeOperator.namespace)
+	if err != nil {
+		return fmt.Sprintf("clusterserviceversions could not be listed: %v", err)
+	}
+
+	for _, csv := range csvs {
+		if !strings.HasPrefix(csv.Object.Name, spokeOperator.csvPrefix) {
+			continue
+		}
+
+		if csv.Object.Status.Phase != oplmV1alpha1.CSVPhaseSucceeded {
+			return fmt.Sprintf("clusterserviceversion %s is %s", csv.Object.Name, csv.Object.Status.Phase)
+		}
+
+		return ""
+	}
+
+	return fmt.Sprintf("has no clusterserviceversion in %s", spokeOperator.namespace)
+}
+
+// validateInstallOperators checks that the agentclusterinstall has the workers required by the operators set using
+// WithInstallOperators.
+func (spoke *SpokeClusterResources) validateInstallOperators() error {
+	if spoke.AgentClusterInstall == nil {
+		return nil
+	}
+
+	workers := spoke.AgentClusterInstall.Definition.Spec.ProvisionRequirements.WorkerAgents
+
+	for _, operator := range spoke.installOperators {
+		if minWorkers := spokeOperators[operator].minWorkers; workers < minWorkers {
+			return fmt.Errorf("operator %s requires at least %d workers, agentclusterinstall %s has %d",
+				operator, minWorkers, spoke.AgentClusterInstall.Definition.Name, workers)
+		}
+	}
+
+	return nil
+}
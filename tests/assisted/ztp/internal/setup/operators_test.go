@@ -0,0 +1,142 @@
+package setup
+
+import (
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	oplmV1alpha1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/olm/operators/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestWithInstallOperators(t *testing.T) {
+	testCases := []struct {
+		name          string
+		operators     [][]string
+		expectedKeys  []string
+		expectedError string
+	}{
+		{name: "single", operators: [][]string{{"lvm"}}, expectedKeys: []string{"lvm-operator.yaml"}},
+		{
+			name:         "accumulated",
+			operators:    [][]string{{"cnv", "lso"}, {"lso", "mce"}},
+			expectedKeys: []string{"cnv-operator.yaml", "lso-operator.yaml", "mce-operator.yaml"},
+		},
+		{
+			name:      "unsupported",
+			operators: [][]string{{"lvm", "gitops"}},
+			expectedError: `WithInstallOperators: unsupported operator "gitops", ` +
+				"must be one of cnv, lso, lvm, mce, nmstate, odf",
+		},
+		{
+			name:          "conflicting",
+			operators:     [][]string{{"odf"}, {"lvm"}},
+			expectedError: "WithInstallOperators: operators lvm and odf cannot be installed together",
+		},
+		{
+			name:          "empty",
+			operators:     [][]string{{}},
+			expectedError: "WithInstallOperators: operators cannot be empty",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := StandardHAProfile(newHubTestClient(), "spoke")
+
+		for _, operators := range testCase.operators {
+			spoke.WithInstallOperators(operators...)
+		}
+
+		if testCase.expectedError != "" {
+			assert.EqualError(t, spoke.err, testCase.expectedError, testCase.name)
+
+			continue
+		}
+
+		assert.Nil(t, spoke.err, testCase.name)
+		assert.Len(t, spoke.ExtraManifests, 1, testCase.name)
+
+		operatorManifests := spoke.ExtraManifests[0]
+		assert.Equal(t, "spoke-operators", operatorManifests.Definition.Name, testCase.name)
+		assert.ElementsMatch(t, testCase.expectedKeys,
+			slices.Collect(maps.Keys(operatorManifests.Definition.Data)), testCase.name)
+		assert.Equal(t, "spoke-operators",
+			spoke.AgentClusterInstall.Definition.Spec.ManifestsConfigMapRefs[0].Name, testCase.name)
+
+		for key, content := range operatorManifests.Definition.Data {
+			assert.Nil(t, extraManifestFile{key: key, content: content}.validate(), testCase.name)
+		}
+	}
+
+	spoke := StandardHAProfile(newHubTestClient(), "spoke").WithInstallOperators("nmstate")
+	assert.Contains(t, spoke.ExtraManifests[0].Definition.Data["nmstate-operator.yaml"],
+		"  name: kubernetes-nmstate-operator\n  namespace: openshift-nmstate\n")
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithInstallOperators("lvm")
+	assert.EqualError(t, spoke.err,
+		"WithInstallOperators: agentclusterinstall must be defined before installing operators")
+}
+
+func TestValidateInstallOperators(t *testing.T) {
+	testCases := []struct {
+		operator      string
+		workers       int
+		expectedError string
+	}{
+		{operator: "odf", workers: 3},
+		{operator: "odf", workers: 2, expectedError: "operator odf requires at least 3 workers, " +
+			"agentclusterinstall spoke has 2"},
+		{operator: "lvm"},
+	}
+
+	for _, testCase := range testCases {
+		spoke := StandardHAProfile(newHubTestClient(), "spoke").WithInstallOperators(testCase.operator)
+		spoke.AgentClusterInstall.Definition.Spec.ProvisionRequirements.WorkerAgents = testCase.workers
+
+		err := spoke.validateInstallOperators()
+		if testCase.expectedError != "" {
+			assert.EqualError(t, err, testCase.expectedError)
+			assert.EqualError(t, spoke.Validate(), testCase.expectedError)
+
+			continue
+		}
+
+		assert.Nil(t, err, testCase.operator)
+	}
+}
+
+func TestVerifyOperatorsInstalled(t *testing.T) {
+	spokeClient := clients.GetTestClients(clients.TestClientParams{
+		K8sMockObjects: []runtime.Object{
+			buildDummyCSV("lvms-operator.v4.16.0", "openshift-storage", oplmV1alpha1.CSVPhaseSucceeded),
+			buildDummyCSV("kubevirt-hyperconverged-operator.v4.16.3", "openshift-cnv",
+				oplmV1alpha1.CSVPhaseInstalling),
+		},
+		SchemeAttachers: []clients.SchemeAttacher{oplmV1alpha1.AddToScheme},
+	})
+
+	spoke := StandardHAProfile(newHubTestClient(), "spoke").
+		WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Second})
+
+	assert.Nil(t, spoke.VerifyOperatorsInstalled(spokeClient, 0))
+	assert.Nil(t, spoke.WithInstallOperators("lvm").VerifyOperatorsInstalled(spokeClient, 0))
+
+	err := spoke.WithInstallOperators("mce", "cnv").VerifyOperatorsInstalled(spokeClient, 10*time.Millisecond)
+	assert.EqualError(t, err, "timed out waiting for operators of spoke spoke to be installed: "+
+		"cnv clusterserviceversion kubevirt-hyperconverged-operator.v4.16.3 is Installing; "+
+		"mce has no clusterserviceversion in multicluster-engine")
+
+	assert.EqualError(t, spoke.VerifyOperatorsInstalled(nil, 0), "spokeClient cannot be nil")
+}
+
+func buildDummyCSV(
+	name, namespace string, phase oplmV1alpha1.ClusterServiceVersionPhase) *oplmV1alpha1.ClusterServiceVersion {
+	return &oplmV1alpha1.ClusterServiceVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Status:     oplmV1alpha1.ClusterServiceVersionStatus{Phase: phase},
+	}
+}
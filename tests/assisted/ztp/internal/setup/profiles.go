@@ -0,0 +1,61 @@
+package setup
+
+import (
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+)
+
+const compactWorkerAgents = 0
+
+// SNOProfile returns spoke cluster resources for a single-node spoke cluster. The agentclusterinstall matches
+// WithDefaultSNOAgentClusterInstall with 1 control-plane agent, 0 workers and user-managed networking, so no VIPs
+// are set.
+func SNOProfile(apiClient *clients.Settings, name string) *SpokeClusterResources {
+	return newProfile(apiClient, name).WithDefaultSNOAgentClusterInstall().WithDefaultInfraEnv()
+}
+
+// CompactProfile returns spoke cluster resources for a compact spoke cluster. The agentclusterinstall matches
+// WithDefaultIPv4AgentClusterInstall with 3 control-plane agents and 0 workers.
+func CompactProfile(apiClient *clients.Settings, name string) *SpokeClusterResources {
+	return newProfile(apiClient, name).
+		WithDefaultIPv4AgentClusterInstall().
+		WithAgentCounts(defaultControlPlaneAgents, compactWorkerAgents).
+		WithDefaultInfraEnv()
+}
+
+// StandardHAProfile returns spoke cluster resources for a highly available spoke cluster. The agentclusterinstall
+// matches WithDefaultIPv4AgentClusterInstall with 3 control-plane agents and 2 workers.
+func StandardHAProfile(apiClient *clients.Settings, name string) *SpokeClusterResources {
+	return newProfile(apiClient, name).WithDefaultIPv4AgentClusterInstall().WithDefaultInfraEnv()
+}
+
+// DualStackProfile returns spoke cluster resources for a dual-stack spoke cluster. The agentclusterinstall
+// matches WithDefaultDualStackAgentClusterInstall with 3 control-plane agents and 2 workers.
+func DualStackProfile(apiClient *clients.Settings, name string) *SpokeClusterResources {
+	return newProfile(apiClient, name).WithDefaultDualStackAgentClusterInstall().WithDefaultInfraEnv()
+}
+
+// ARM64Profile returns spoke cluster resources for an arm64 spoke cluster using the named clusterimageset. It
+// matches StandardHAProfile except for the image set and the infraenv cpuArchitecture. The agentclusterinstall has
+// no architecture field of its own, so the image set must reference an arm64 or multi payload; Validate and Create
+// check this against the hub.
+func ARM64Profile(apiClient *clients.Settings, name, imageSetName string) *SpokeClusterResources {
+	spoke := StandardHAProfile(apiClient, name).WithInfraEnvCPUArchitecture(CPUArchitectureARM64)
+	if spoke.err != nil {
+		return spoke
+	}
+
+	spoke.AgentClusterInstall.WithImageSet(imageSetName)
+	spoke.checkImageSetArch = true
+
+	return spoke
+}
+
+// newProfile returns spoke cluster resources with the namespace, pull-secret and clusterdeployment
+// defaults shared by all profiles.
+func newProfile(apiClient *clients.Settings, name string) *SpokeClusterResources {
+	return NewSpokeCluster(apiClient).
+		WithName(name).
+		WithDefaultNamespace().
+		WithDefaultPullSecret().
+		WithDefaultClusterDeployment()
+}
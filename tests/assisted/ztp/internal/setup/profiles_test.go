@@ -0,0 +1,47 @@
+package setup
+
+import (
+	"testing"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfiles(t *testing.T) {
+	testCases := []struct {
+		profile    func(*clients.Settings, string) *SpokeClusterResources
+		goldenFile string
+	}{
+		{profile: SNOProfile, goldenFile: "profiles/sno.yaml"},
+		{profile: CompactProfile, goldenFile: "profiles/compact.yaml"},
+		{profile: StandardHAProfile, goldenFile: "profiles/standard-ha.yaml"},
+		{profile: DualStackProfile, goldenFile: "profiles/dual-stack.yaml"},
+		{
+			profile: func(apiClient *clients.Settings, name string) *SpokeClusterResources {
+				return ARM64Profile(apiClient, name, "arm64-imageset")
+			},
+			goldenFile: "profiles/arm64.yaml",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := testCase.profile(newTestClient(), "profile-spoke")
+
+		assert.Nil(t, spoke.err)
+		assert.NotNil(t, spoke.Namespace)
+		assert.NotNil(t, spoke.PullSecret)
+		assert.NotNil(t, spoke.ClusterDeployment)
+		assert.NotNil(t, spoke.AgentClusterInstall)
+		assert.NotNil(t, spoke.InfraEnv)
+
+		assertGolden(t, testCase.goldenFile, definitionsYAML(t, spoke))
+	}
+}
+
+func TestProfileOverride(t *testing.T) {
+	spoke := CompactProfile(newTestClient(), "profile-spoke").WithDefaultIPv6AgentClusterInstall()
+
+	assert.Nil(t, spoke.err)
+	assert.Equal(t, "fd2e:6f44:5dd8:1::5", spoke.AgentClusterInstall.Definition.Spec.APIVIP)
+	assert.Equal(t, defaultWorkerAgents, spoke.AgentClusterInstall.Definition.Spec.ProvisionRequirements.WorkerAgents)
+}
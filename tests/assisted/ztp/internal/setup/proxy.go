@@ -0,0 +1,85 @@
+package setup
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+)
+
+// WithProxy sets the proxy used by the discovery agents and the installed spoke on the spoke infraenvs and
+// agentclusterinstall, whichever are defined. The agentclusterinstall machine networks are added to noProxy so that
+// traffic between the spoke hosts is not proxied.
+func (spoke *SpokeClusterResources) WithProxy(httpProxy, httpsProxy, noProxy string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.InfraEnv == nil && spoke.AgentClusterInstall == nil {
+		spoke.err = fmt.Errorf("WithProxy: infraenv or agentclusterinstall must be defined before setting the proxy")
+
+		return spoke
+	}
+
+	if httpProxy == "" && httpsProxy == "" {
+		spoke.err = fmt.Errorf("WithProxy: proxy requires an http or https proxy url")
+
+		return spoke
+	}
+
+	for _, proxyURL := range []string{httpProxy, httpsProxy} {
+		if err := validateProxyURL(proxyURL); err != nil {
+			spoke.err = fmt.Errorf("WithProxy: %w", err)
+
+			return spoke
+		}
+	}
+
+	var machineCIDRs []string
+
+	if spoke.AgentClusterInstall != nil {
+		for _, machineNetwork := range spoke.AgentClusterInstall.Definition.Spec.Networking.MachineNetwork {
+			machineCIDRs = append(machineCIDRs, machineNetwork.CIDR)
+		}
+	}
+
+	noProxy, err := BuildNoProxy(false, append([]string{noProxy}, machineCIDRs...)...)
+	if err != nil {
+		spoke.err = fmt.Errorf("WithProxy: %w", err)
+
+		return spoke
+	}
+
+	if spoke.InfraEnv != nil {
+		proxy := agentInstallV1Beta1.Proxy{HTTPProxy: httpProxy, HTTPSProxy: httpsProxy, NoProxy: noProxy}
+
+		spoke.InfraEnv.WithProxy(proxy)
+
+		for _, infraEnv := range spoke.AdditionalInfraEnvs {
+			infraEnv.WithProxy(proxy)
+		}
+	}
+
+	if spoke.AgentClusterInstall != nil {
+		spoke.AgentClusterInstall.Definition.Spec.Proxy = &v1beta1.Proxy{
+			HTTPProxy: httpProxy, HTTPSProxy: httpsProxy, NoProxy: noProxy,
+		}
+	}
+
+	return spoke
+}
+
+// validateProxyURL checks that proxyURL, when set, is an http or https URL.
+func validateProxyURL(proxyURL string) error {
+	if proxyURL == "" {
+		return nil
+	}
+
+	parsedURL, err := url.Parse(proxyURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return fmt.Errorf("invalid proxy url %q", proxyURL)
+	}
+
+	return nil
+}
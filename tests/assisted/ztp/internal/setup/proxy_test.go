@@ -0,0 +1,81 @@
+package setup
+
+import (
+	"testing"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithProxy(t *testing.T) {
+	testCases := []struct {
+		httpProxy       string
+		httpsProxy      string
+		noProxy         string
+		expectedNoProxy string
+		expectedError   string
+	}{
+		{
+			httpProxy:       "http://proxy.example.com:3128",
+			httpsProxy:      "http://proxy.example.com:3128",
+			noProxy:         ".example.com, 10.0.0.0/8",
+			expectedNoProxy: ".example.com,10.0.0.0/8,192.168.254.0/24",
+		},
+		{
+			httpsProxy:      "https://proxy.example.com:3129",
+			noProxy:         "192.168.254.0/24",
+			expectedNoProxy: "192.168.254.0/24",
+		},
+		{
+			httpProxy:       "http://proxy.example.com:3128",
+			expectedNoProxy: "192.168.254.0/24",
+		},
+		{noProxy: ".example.com", expectedError: "WithProxy: proxy requires an http or https proxy url"},
+		{httpProxy: "proxy.example.com:3128", expectedError: "WithProxy: invalid proxy url \"proxy.example.com:3128\""},
+		{
+			httpsProxy:    "ftp://proxy.example.com",
+			expectedError: "WithProxy: invalid proxy url \"ftp://proxy.example.com\"",
+		},
+		{
+			httpProxy:     "http://proxy.example.com:3128",
+			noProxy:       "[fd2e:6f44:5dd8:1::1",
+			expectedError: "WithProxy: noProxy entry \"[fd2e:6f44:5dd8:1::1\" has an unterminated IPv6 literal",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := NewSpokeCluster(newTestClient()).WithName("spoke").
+			WithDefaultSNOAgentClusterInstall().WithDefaultInfraEnv().
+			WithProxy(testCase.httpProxy, testCase.httpsProxy, testCase.noProxy)
+
+		if testCase.expectedError != "" {
+			assert.EqualError(t, spoke.err, testCase.expectedError)
+
+			continue
+		}
+
+		assert.Nil(t, spoke.err)
+		assert.Equal(t, &agentInstallV1Beta1.Proxy{
+			HTTPProxy:  testCase.httpProxy,
+			HTTPSProxy: testCase.httpsProxy,
+			NoProxy:    testCase.expectedNoProxy,
+		}, spoke.InfraEnv.Definition.Spec.Proxy)
+		assert.Equal(t, &v1beta1.Proxy{
+			HTTPProxy:  testCase.httpProxy,
+			HTTPSProxy: testCase.httpsProxy,
+			NoProxy:    testCase.expectedNoProxy,
+		}, spoke.AgentClusterInstall.Definition.Spec.Proxy)
+	}
+}
+
+func TestWithProxyPartialResources(t *testing.T) {
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultInfraEnv().
+		WithProxy("http://proxy.example.com:3128", "", ".example.com")
+	assert.Nil(t, spoke.err)
+	assert.Equal(t, ".example.com", spoke.InfraEnv.Definition.Spec.Proxy.NoProxy)
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithProxy("http://proxy.example.com:3128", "", "")
+	assert.EqualError(t, spoke.err,
+		"WithProxy: infraenv or agentclusterinstall must be defined before setting the proxy")
+}
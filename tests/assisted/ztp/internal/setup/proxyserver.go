@@ -0,0 +1,157 @@
+package setup
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/configmap"
+	"github.com/openshift-kni/eco-goinfra/pkg/deployment"
+	"github.com/openshift-kni/eco-goinfra/pkg/pod"
+	"github.com/openshift-kni/eco-goinfra/pkg/service"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// DefaultProxyServerImage is the squid container image used when ZTPConfig.HubProxyServerImage is not set.
+	DefaultProxyServerImage = "docker.io/ubuntu/squid:latest"
+
+	proxyServerName         = "squid-proxy"
+	proxyServerPort         = 3128
+	proxyServerReadyTimeout = 3 * time.Minute
+
+	// proxyServerConfig allows every client and disables caching, since the proxy only lives for a test.
+	proxyServerConfig = `http_port 3128
+http_access allow all
+cache deny all
+pid_filename none
+access_log stdio:/dev/stdout
+cache_log stdio:/dev/stderr
+`
+)
+
+// ProxyInfo contains the URL of a proxy server, used as both the http and https proxy of a spoke since squid
+// tunnels https through CONNECT.
+type ProxyInfo struct {
+	URL string
+}
+
+// DeployProxyServer deploys a squid proxy in the existing namespace on the hub and waits for it to be ready. The
+// proxy is exposed through a node port, since a route cannot carry proxied traffic, so its URL uses the address of
+// the hub node running it. Pass the URL to WithProxy and use DeleteProxyServer to remove it.
+func DeployProxyServer(apiClient *clients.Settings, namespace string) (ProxyInfo, error) {
+	if apiClient == nil {
+		return ProxyInfo{}, fmt.Errorf("apiClient cannot be nil")
+	}
+
+	_, err := newProxyServerConfigMap(apiClient, namespace).Create()
+	if err != nil {
+		return ProxyInfo{}, fmt.Errorf("failed to create proxy server configmap: %w", err)
+	}
+
+	_, err = newProxyServerDeployment(apiClient, namespace).CreateAndWaitUntilReady(proxyServerReadyTimeout)
+	if err != nil {
+		return ProxyInfo{}, fmt.Errorf("failed to deploy proxy server: %w", err)
+	}
+
+	proxyService, err := newProxyServerService(apiClient, namespace).Create()
+	if err != nil {
+		return ProxyInfo{}, fmt.Errorf("failed to create service %s: %w", proxyServerName, err)
+	}
+
+	proxyURL, err := proxyServerURL(apiClient, namespace, proxyService.Object.Spec.Ports[0].NodePort)
+	if err != nil {
+		return ProxyInfo{}, err
+	}
+
+	return ProxyInfo{URL: proxyURL}, nil
+}
+
+// DeleteProxyServer removes the proxy server deployed by DeployProxyServer from the namespace.
+func DeleteProxyServer(apiClient *clients.Settings, namespace string) error {
+	if apiClient == nil {
+		return fmt.Errorf("apiClient cannot be nil")
+	}
+
+	if err := newProxyServerService(apiClient, namespace).Delete(); err != nil {
+		return fmt.Errorf("failed to delete service %s: %w", proxyServerName, err)
+	}
+
+	if err := newProxyServerDeployment(apiClient, namespace).DeleteAndWait(proxyServerReadyTimeout); err != nil {
+		return fmt.Errorf("failed to delete proxy server deployment: %w", err)
+	}
+
+	if err := newProxyServerConfigMap(apiClient, namespace).Delete(); err != nil {
+		return fmt.Errorf("failed to delete proxy server configmap: %w", err)
+	}
+
+	return nil
+}
+
+// newProxyServerConfigMap returns the configmap builder holding the squid configuration of the proxy server.
+func newProxyServerConfigMap(apiClient *clients.Settings, namespace string) *configmap.Builder {
+	return configmap.NewBuilder(apiClient, proxyServerName+"-config", namespace).
+		WithData(map[string]string{"squid.conf": proxyServerConfig})
+}
+
+// newProxyServerDeployment returns the deployment builder of the proxy server in namespace.
+func newProxyServerDeployment(apiClient *clients.Settings, namespace string) *deployment.Builder {
+	image := DefaultProxyServerImage
+	if hubConfig().HubProxyServerImage != "" {
+		image = hubConfig().HubProxyServerImage
+	}
+
+	return deployment.NewBuilder(apiClient, proxyServerName, namespace, map[string]string{"app": proxyServerName},
+		corev1.Container{
+			Name:  proxyServerName,
+			Image: image,
+			Ports: []corev1.ContainerPort{{ContainerPort: proxyServerPort, Protocol: corev1.ProtocolTCP}},
+			VolumeMounts: []corev1.VolumeMount{{
+				Name: "config", MountPath: "/etc/squid/squid.conf", SubPath: "squid.conf",
+			}},
+			ReadinessProbe: &corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{
+					TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt32(proxyServerPort)},
+				},
+			},
+		}).WithVolume(corev1.Volume{
+		Name: "config", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: proxyServerName + "-config"},
+		}},
+	})
+}
+
+// newProxyServerService returns the node port service builder of the proxy server, letting the API allocate the
+// node port.
+func newProxyServerService(apiClient *clients.Settings, namespace string) *service.Builder {
+	proxyService := service.NewBuilder(apiClient, proxyServerName, namespace, map[string]string{"app": proxyServerName},
+		corev1.ServicePort{Port: proxyServerPort, Protocol: corev1.ProtocolTCP})
+	proxyService.Definition.Spec.Type = corev1.ServiceTypeNodePort
+
+	return proxyService
+}
+
+// proxyServerURL returns the URL of the proxy server at nodePort on the hub node running the proxy server pod.
+func proxyServerURL(apiClient *clients.Settings, namespace string, nodePort int32) (string, error) {
+	if nodePort == 0 {
+		return "", fmt.Errorf("service %s has no node port allocated", proxyServerName)
+	}
+
+	proxyPods, err := pod.List(apiClient, namespace, metav1.ListOptions{LabelSelector: "app=" + proxyServerName})
+	if err != nil {
+		return "", fmt.Errorf("failed to list proxy server pods: %w", err)
+	}
+
+	if len(proxyPods) == 0 || proxyPods[0].Object.Status.HostIP == "" {
+		return "", fmt.Errorf("no scheduled proxy server pod found in namespace %s", namespace)
+	}
+
+	proxyEndpoint := Endpoint{
+		Scheme: "http", Host: proxyPods[0].Object.Status.HostIP, Port: strconv.Itoa(int(nodePort)),
+	}
+
+	return proxyEndpoint.Scheme + "://" + proxyEndpoint.HostPort(), nil
+}
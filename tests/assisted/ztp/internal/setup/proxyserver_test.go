@@ -0,0 +1,108 @@
+package setup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestNewProxyServerDeployment(t *testing.T) {
+	proxyDeployment := newProxyServerDeployment(newTestClient(), "proxy-ns")
+
+	assert.Equal(t, "squid-proxy", proxyDeployment.Definition.Name)
+	assert.Equal(t, "proxy-ns", proxyDeployment.Definition.Namespace)
+
+	podSpec := proxyDeployment.Definition.Spec.Template.Spec
+	assert.Len(t, podSpec.Containers, 1)
+	assert.Equal(t, DefaultProxyServerImage, podSpec.Containers[0].Image)
+	assert.Equal(t, int32(proxyServerPort), podSpec.Containers[0].ReadinessProbe.TCPSocket.Port.IntVal)
+	assert.Equal(t, "/etc/squid/squid.conf", podSpec.Containers[0].VolumeMounts[0].MountPath)
+	assert.Equal(t, "squid-proxy-config", podSpec.Volumes[0].ConfigMap.Name)
+
+	proxyConfigMap := newProxyServerConfigMap(newTestClient(), "proxy-ns")
+	assert.Equal(t, "squid-proxy-config", proxyConfigMap.Definition.Name)
+	assert.Contains(t, proxyConfigMap.Definition.Data["squid.conf"], "http_port 3128\n")
+
+	proxyService := newProxyServerService(newTestClient(), "proxy-ns")
+	assert.Equal(t, corev1.ServiceTypeNodePort, proxyService.Definition.Spec.Type)
+	assert.Equal(t, int32(0), proxyService.Definition.Spec.Ports[0].NodePort)
+
+	testZTPConfig.HubProxyServerImage = "registry.example.com/squid:test"
+
+	t.Cleanup(func() {
+		testZTPConfig.HubProxyServerImage = ""
+	})
+
+	proxyDeployment = newProxyServerDeployment(newTestClient(), "proxy-ns")
+	assert.Equal(t, "registry.example.com/squid:test",
+		proxyDeployment.Definition.Spec.Template.Spec.Containers[0].Image)
+}
+
+func TestProxyServerURL(t *testing.T) {
+	testCases := []struct {
+		name          string
+		objects       []runtime.Object
+		nodePort      int32
+		expectedURL   string
+		expectedError string
+	}{
+		{
+			name:        "ipv4 node",
+			objects:     []runtime.Object{buildDummyProxyPod("192.168.254.20")},
+			nodePort:    31280,
+			expectedURL: "http://192.168.254.20:31280",
+		},
+		{
+			name:        "ipv6 node",
+			objects:     []runtime.Object{buildDummyProxyPod("fd2e:6f44:5dd8:1::20")},
+			nodePort:    31280,
+			expectedURL: "http://[fd2e:6f44:5dd8:1::20]:31280",
+		},
+		{
+			name:          "unscheduled pod",
+			objects:       []runtime.Object{buildDummyProxyPod("")},
+			nodePort:      31280,
+			expectedError: "no scheduled proxy server pod found in namespace proxy-ns",
+		},
+		{
+			name:          "no pod",
+			nodePort:      31280,
+			expectedError: "no scheduled proxy server pod found in namespace proxy-ns",
+		},
+		{
+			name:          "no node port",
+			objects:       []runtime.Object{buildDummyProxyPod("192.168.254.20")},
+			expectedError: "service squid-proxy has no node port allocated",
+		},
+	}
+
+	for _, testCase := range testCases {
+		proxyURL, err := proxyServerURL(newTestClient(testCase.objects...), "proxy-ns", testCase.nodePort)
+		if testCase.expectedError != "" {
+			assert.EqualError(t, err, testCase.expectedError, testCase.name)
+
+			continue
+		}
+
+		assert.Nil(t, err, testCase.name)
+		assert.Equal(t, testCase.expectedURL, proxyURL, testCase.name)
+	}
+}
+
+func TestDeployProxyServerNilClient(t *testing.T) {
+	_, err := DeployProxyServer(nil, "proxy-ns")
+	assert.EqualError(t, err, "apiClient cannot be nil")
+	assert.EqualError(t, DeleteProxyServer(nil, "proxy-ns"), "apiClient cannot be nil")
+}
+
+func buildDummyProxyPod(hostIP string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "squid-proxy-0", Namespace: "proxy-ns", Labels: map[string]string{"app": "squid-proxy"},
+		},
+		Status: corev1.PodStatus{HostIP: hostIP},
+	}
+}
@@ -0,0 +1,180 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+)
+
+const agentValidationFailure = "failure"
+
+// WaitForAgentsRegistered waits up to timeout, or the spoke wait timeout when it is 0, until expected hosts have
+// registered an agent labeled for the spoke infraenvs and returns those agents. Agents bound to another
+// clusterdeployment, as in shared namespaces, are ignored and a host that re-registered under a new agent is only
+// counted once, using its most recent agent. Since the agent builders of eco-goinfra are not exported, the agent
+// objects are returned; assisted.PullAgent returns a builder for each of them. On timeout, the error contains the
+// number of hosts found and the failed validations of each agent.
+func (spoke *SpokeClusterResources) WaitForAgentsRegistered(
+	expected int, timeout time.Duration) ([]*agentInstallV1Beta1.Agent, error) {
+	return spoke.WaitForAgentsRegisteredWithContext(context.Background(), expected, timeout)
+}
+
+// WaitForAgents waits up to timeout, or the spoke wait timeout when it is 0, until expected hosts have registered an
+// agent for the spoke and returns those agents, like WaitForAgentsRegistered.
+func (spoke *SpokeClusterResources) WaitForAgents(
+	expected int, timeout time.Duration) ([]*agentInstallV1Beta1.Agent, error) {
+	return spoke.WaitForAgentsRegistered(expected, timeout)
+}
+
+// WaitForAgentsRegisteredWithContext waits for the expected hosts to register agents like
+// WaitForAgentsRegistered, stopping the wait when ctx is done.
+func (spoke *SpokeClusterResources) WaitForAgentsRegisteredWithContext(
+	ctx context.Context, expected int, timeout time.Duration) ([]*agentInstallV1Beta1.Agent, error) {
+	if spoke.InfraEnv == nil {
+		return nil, fmt.Errorf("infraenv must be defined before waiting for agents")
+	}
+
+	if expected <= 0 {
+		return nil, fmt.Errorf("expected agent count must be greater than 0, got %d", expected)
+	}
+
+	options := spoke.resolveWaitOptions()
+
+	if timeout > 0 {
+		options.Timeout = timeout
+		options.Interval = min(options.Interval, timeout)
+	}
+
+	var (
+		registered []*agentInstallV1Beta1.Agent
+		listErr    error
+	)
+
+	err := spoke.poll(ctx, "wait for agents registered", options, func(ctx context.Context) (bool, error) {
+		var agents []*agentInstallV1Beta1.Agent
+
+		agents, listErr = spoke.listAgents()
+		if listErr != nil {
+			glog.V(ztpparams.ZTPLogLevel).Infof("Failed to list agents of spoke %s: %v", spoke.Name, listErr)
+
+			return false, nil
+		}
+
+		registered = latestAgentPerHost(spoke.ownAgents(agents))
+
+		return len(registered) >= expected, nil
+	})
+	if err == nil {
+		return registered, nil
+	}
+
+	if listErr != nil {
+		return nil, fmt.Errorf("timed out waiting for %d agents of spoke %s: %w", expected, spoke.Name, listErr)
+	}
+
+	report := fmt.Sprintf(
+		"timed out waiting for %d agents of spoke %s, found %d", expected, spoke.Name, len(registered))
+
+	for _, agent := range registered {
+		if failures := failedAgentValidations(agent); len(failures) > 0 {
+			report = fmt.Sprintf("%s; %s: %s", report, agent.Name, strings.Join(failures, ", "))
+		}
+	}
+
+	return nil, fmt.Errorf("%s", report)
+}
+
+// ownAgents returns the agents that are unbound or bound to the spoke clusterdeployment.
+func (spoke *SpokeClusterResources) ownAgents(agents []*agentInstallV1Beta1.Agent) []*agentInstallV1Beta1.Agent {
+	var owned []*agentInstallV1Beta1.Agent
+
+	for _, agent := range agents {
+		clusterRef := agent.Spec.ClusterDeploymentName
+		if clusterRef != nil && (spoke.ClusterDeployment == nil ||
+			clusterRef.Name != spoke.ClusterDeployment.Definition.Name ||
+			clusterRef.Namespace != spoke.ClusterDeployment.Definition.Namespace) {
+			continue
+		}
+
+		owned = append(owned, agent)
+	}
+
+	return owned
+}
+
+// latestAgentPerHost keeps the most recently created agent of each host, sorted by name, so a host re-registering
+// under a new agent is only counted once.
+func latestAgentPerHost(agents []*agentInstallV1Beta1.Agent) []*agentInstallV1Beta1.Agent {
+	hostAgents := map[string]*agentInstallV1Beta1.Agent{}
+
+	for _, agent := range agents {
+		hostID := agentHostID(agent)
+
+		current, found := hostAgents[hostID]
+		if !found || current.CreationTimestamp.Before(&agent.CreationTimestamp) {
+			hostAgents[hostID] = agent
+		}
+	}
+
+	latest := make([]*agentInstallV1Beta1.Agent, 0, len(hostAgents))
+	for _, agent := range hostAgents {
+		latest = append(latest, agent)
+	}
+
+	slices.SortFunc(latest, func(a, b *agentInstallV1Beta1.Agent) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return latest
+}
+
+// agentHostID identifies the host of an agent by its serial number, its lowest MAC address or its hostname, in that
+// order, falling back to the agent name before the inventory is reported.
+func agentHostID(agent *agentInstallV1Beta1.Agent) string {
+	inventory := agent.Status.Inventory
+
+	if inventory.SystemVendor.SerialNumber != "" {
+		return "serial:" + inventory.SystemVendor.SerialNumber
+	}
+
+	var macAddresses []string
+
+	for _, hostInterface := range inventory.Interfaces {
+		if hostInterface.MacAddress != "" {
+			macAddresses = append(macAddresses, strings.ToLower(hostInterface.MacAddress))
+		}
+	}
+
+	if len(macAddresses) > 0 {
+		return "mac:" + slices.Min(macAddresses)
+	}
+
+	if inventory.Hostname != "" {
+		return "hostname:" + inventory.Hostname
+	}
+
+	return "agent:" + agent.Name
+}
+
+// failedAgentValidations returns the messages of the failed validations of the agent, sorted by validation id.
+func failedAgentValidations(agent *agentInstallV1Beta1.Agent) []string {
+	var failures []string
+
+	for _, results := range agent.Status.ValidationsInfo {
+		for _, result := range results {
+			if result.Status == agentValidationFailure {
+				failures = append(failures, fmt.Sprintf("%s (%s)", result.ID, result.Message))
+			}
+		}
+	}
+
+	slices.Sort(failures)
+
+	return failures
+}
@@ -0,0 +1,106 @@
+package setup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/assisted"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/common"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitForAgentsRegistered(t *testing.T) {
+	reRegisteredAgent := buildDummyDiscoveredAgent("agent-0-new", "spoke-host-0", "52:54:00:00:00:01", time.Minute)
+	otherClusterAgent := buildDummyDiscoveredAgent("agent-other", "other-host", "52:54:00:00:00:99", 0)
+	otherClusterAgent.Spec.ClusterDeploymentName = &agentInstallV1Beta1.ClusterReference{
+		Name: "other", Namespace: "other"}
+	boundAgent := buildDummyDiscoveredAgent("agent-1", "spoke-host-1", "52:54:00:00:00:02", 0)
+	boundAgent.Spec.ClusterDeploymentName = &agentInstallV1Beta1.ClusterReference{Name: "spoke", Namespace: "spoke"}
+	boundAgent.Status.ValidationsInfo = common.ValidationsStatus{
+		"hardware": {
+			{ID: "has-min-memory", Status: "failure", Message: "The host has insufficient memory"},
+			{ID: "has-min-cpu-cores", Status: "success", Message: "Sufficient CPU cores"},
+		},
+		"network": {{ID: "belongs-to-machine-cidr", Status: "failure", Message: "Host does not belong to cidr"}},
+	}
+
+	apiClient := newTestClient(
+		buildDummyInfraEnvObject("spoke"),
+		buildDummyDiscoveredAgent("agent-0", "spoke-host-0", "52:54:00:00:00:01", 0),
+		reRegisteredAgent, otherClusterAgent, boundAgent,
+	)
+
+	spoke := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultClusterDeployment().WithDefaultInfraEnv().
+		WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Second})
+
+	agents, err := spoke.WaitForAgentsRegistered(2, 0)
+	assert.Nil(t, err)
+	assert.Len(t, agents, 2)
+	assert.Equal(t, "agent-0-new", agents[0].Name)
+	assert.Equal(t, "agent-1", agents[1].Name)
+
+	_, err = spoke.WaitForAgentsRegistered(3, 10*time.Millisecond)
+	assert.EqualError(t, err, "timed out waiting for 3 agents of spoke spoke, found 2; agent-1: "+
+		"belongs-to-machine-cidr (Host does not belong to cidr), has-min-memory (The host has insufficient memory)")
+
+	spoke = NewSpokeCluster(apiClient).WithName("spoke").WithDefaultInfraEnv().
+		WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Second})
+
+	agents, err = spoke.WaitForAgentsRegistered(1, 10*time.Millisecond)
+	assert.Nil(t, err)
+	assert.Len(t, agents, 1)
+	assert.Equal(t, "agent-0-new", agents[0].Name)
+
+	_, err = spoke.WaitForAgentsRegistered(0, 0)
+	assert.EqualError(t, err, "expected agent count must be greater than 0, got 0")
+
+	_, err = NewSpokeCluster(apiClient).WithName("spoke").WaitForAgentsRegistered(1, 0)
+	assert.EqualError(t, err, "infraenv must be defined before waiting for agents")
+}
+
+func TestWaitForAgentsAndApproveAllAgents(t *testing.T) {
+	apiClient := newTestClient(
+		buildDummyInfraEnvObject("spoke"),
+		buildDummyApprovableAgent("agent-0", "spoke-host-0", "52:54:00:00:00:01"),
+		buildDummyApprovableAgent("agent-1", "spoke-host-1", "52:54:00:00:00:02"),
+	)
+
+	spoke := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultSNOAgentClusterInstall().
+		WithDefaultInfraEnv().WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Second})
+
+	agents, err := spoke.WaitForAgents(2, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"agent-0", "agent-1"}, []string{agents[0].Name, agents[1].Name})
+
+	assert.Nil(t, spoke.ApproveAllAgents())
+
+	for agentName, role := range map[string]models.HostRole{
+		"agent-0": models.HostRoleMaster, "agent-1": models.HostRoleWorker,
+	} {
+		agent, err := assisted.PullAgent(apiClient, agentName, "spoke")
+		assert.Nil(t, err, agentName)
+		assert.True(t, agent.Object.Spec.Approved, agentName)
+		assert.Equal(t, role, agent.Object.Spec.Role, agentName)
+	}
+
+	_, err = spoke.WaitForAgents(0, 0)
+	assert.EqualError(t, err, "expected agent count must be greater than 0, got 0")
+	assert.EqualError(t, NewSpokeCluster(apiClient).WithName("spoke").ApproveAllAgents(),
+		"infraenv must be defined before approving agents")
+}
+
+func TestAgentHostID(t *testing.T) {
+	agent := buildDummyDiscoveredAgent("agent-0", "spoke-host-0", "", 0)
+	assert.Equal(t, "hostname:spoke-host-0", agentHostID(agent))
+
+	agent.Status.Inventory.Interfaces = []agentInstallV1Beta1.HostInterface{
+		{MacAddress: "52:54:00:00:00:0B"}, {MacAddress: "52:54:00:00:00:0a"}}
+	assert.Equal(t, "mac:52:54:00:00:00:0a", agentHostID(agent))
+
+	agent.Status.Inventory.SystemVendor.SerialNumber = "SN-0"
+	assert.Equal(t, "serial:SN-0", agentHostID(agent))
+
+	assert.Equal(t, "agent:agent-1", agentHostID(buildDummyDiscoveredAgent("agent-1", "", "", 0)))
+}
@@ -0,0 +1,221 @@
+package setup
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ReleaseFlavor is the distribution of the release payload installed on a spoke cluster.
+type ReleaseFlavor string
+
+const (
+	// ReleaseFlavorOCP is an OpenShift Container Platform release.
+	ReleaseFlavorOCP ReleaseFlavor = "ocp"
+	// ReleaseFlavorOKD is an OKD release, either FCOS or SCOS based.
+	ReleaseFlavorOKD ReleaseFlavor = "okd"
+)
+
+// xyVersionRegex matches the major.minor version contained in clusterimageset names such as 4.16 or okd-scos-4.16.
+var xyVersionRegex = regexp.MustCompile(`(\d+)\.(\d+)`)
+
+// ReleaseVersion is a parsed OCP or OKD release version. OKD versions carry an okd pre-release such as
+// 4.16.0-0.okd-scos-2024-06-03-160145.
+type ReleaseVersion struct {
+	Major  int
+	Minor  int
+	Flavor ReleaseFlavor
+	SCOS   bool
+}
+
+// ParseReleaseVersion parses an OCP or OKD release version string.
+func ParseReleaseVersion(releaseVersion string) (ReleaseVersion, error) {
+	parsedVersion, err := version.NewVersion(releaseVersion)
+	if err != nil {
+		return ReleaseVersion{}, fmt.Errorf("failed to parse release version %q: %w", releaseVersion, err)
+	}
+
+	segments := parsedVersion.Segments()
+	parsed := ReleaseVersion{Major: segments[0], Minor: segments[1], Flavor: ReleaseFlavorOCP}
+
+	if prerelease := parsedVersion.Prerelease(); strings.Contains(prerelease, "okd") {
+		parsed.Flavor = ReleaseFlavorOKD
+		parsed.SCOS = strings.Contains(prerelease, "okd-scos")
+	}
+
+	return parsed, nil
+}
+
+// XY returns the major.minor version.
+func (releaseVersion ReleaseVersion) XY() string {
+	return fmt.Sprintf("%d.%d", releaseVersion.Major, releaseVersion.Minor)
+}
+
+// WithOKDRelease sets the agentclusterinstall to install the OKD release of the named clusterimageset. OKD spokes
+// do not need Red Hat registry credentials in their pull-secret and their image set is not compared against the
+// hub version.
+func (spoke *SpokeClusterResources) WithOKDRelease(imageSetName string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.AgentClusterInstall == nil {
+		spoke.err = fmt.Errorf("WithOKDRelease: agentclusterinstall must be defined before setting an okd release")
+
+		return spoke
+	}
+
+	if imageSetName == "" {
+		spoke.err = fmt.Errorf("WithOKDRelease: okd clusterimageset name cannot be empty")
+
+		return spoke
+	}
+
+	spoke.AgentClusterInstall.WithImageSet(imageSetName)
+	spoke.releaseFlavor = ReleaseFlavorOKD
+
+	return spoke
+}
+
+// ReleaseFlavor returns the release flavor installed on the spoke cluster.
+func (spoke *SpokeClusterResources) ReleaseFlavor() ReleaseFlavor {
+	if spoke.releaseFlavor == "" {
+		return ReleaseFlavorOCP
+	}
+
+	return spoke.releaseFlavor
+}
+
+// VerifyReleaseVersion checks that clusterVersion, as reported by the installed spoke, is a release of the spoke
+// flavor and, when the clusterimageset name contains a major.minor version, that it matches.
+func (spoke *SpokeClusterResources) VerifyReleaseVersion(clusterVersion string) error {
+	parsed, err := ParseReleaseVersion(clusterVersion)
+	if err != nil {
+		return err
+	}
+
+	if parsed.Flavor != spoke.ReleaseFlavor() {
+		return fmt.Errorf("cluster version %s is an %s release but spoke %s expects an %s release",
+			clusterVersion, parsed.Flavor, spoke.Name, spoke.ReleaseFlavor())
+	}
+
+	if imageSetXY, found := spoke.imageSetXYVersion(); found && imageSetXY != parsed.XY() {
+		return fmt.Errorf("cluster version %s does not match clusterimageset version %s", clusterVersion, imageSetXY)
+	}
+
+	return nil
+}
+
+// FindOSImage returns the os image matching the major.minor openshiftVersion and CPU architecture. SCOS images are
+// only returned for OKD releases, for which they are preferred over other images of the same version.
+func FindOSImage(osImages []agentInstallV1Beta1.OSImage,
+	openshiftVersion, arch string, flavor ReleaseFlavor) (agentInstallV1Beta1.OSImage, error) {
+	var found []agentInstallV1Beta1.OSImage
+
+	for _, osImage := range osImages {
+		imageArch := osImage.CPUArchitecture
+		if imageArch == "" {
+			imageArch = CPUArchitectureX86_64
+		}
+
+		if osImage.OpenshiftVersion != openshiftVersion || imageArch != arch {
+			continue
+		}
+
+		if isSCOSImage(osImage) {
+			if flavor != ReleaseFlavorOKD {
+				continue
+			}
+
+			return osImage, nil
+		}
+
+		found = append(found, osImage)
+	}
+
+	if len(found) == 0 {
+		return agentInstallV1Beta1.OSImage{}, fmt.Errorf("no %s os image found for version %s and architecture %s",
+			flavor, openshiftVersion, arch)
+	}
+
+	return found[0], nil
+}
+
+// isSCOSImage returns true when the os image is a CentOS Stream CoreOS image.
+func isSCOSImage(osImage agentInstallV1Beta1.OSImage) bool {
+	return strings.Contains(osImage.Version, "scos") || strings.Contains(osImage.Url, "scos")
+}
+
+// imageSetXYVersion returns the major.minor version contained in the agentclusterinstall clusterimageset name.
+func (spoke *SpokeClusterResources) imageSetXYVersion() (string, bool) {
+	if spoke.AgentClusterInstall == nil || spoke.AgentClusterInstall.Definition.Spec.ImageSetRef == nil {
+		return "", false
+	}
+
+	match := xyVersionRegex.FindString(spoke.AgentClusterInstall.Definition.Spec.ImageSetRef.Name)
+
+	return match, match != ""
+}
+
+// validateImageSetVersion checks that OCP spokes do not use a clusterimageset newer than the hub. OKD versions are
+// not aligned with the hub version so OKD spokes are not checked.
+func (spoke *SpokeClusterResources) validateImageSetVersion() error {
+	if spoke.ReleaseFlavor() == ReleaseFlavorOKD || hubConfig().HubOCPXYVersion == "" {
+		return nil
+	}
+
+	imageSetXY, found := spoke.imageSetXYVersion()
+	if !found {
+		return nil
+	}
+
+	imageSetVersion, err := version.NewVersion(imageSetXY)
+	if err != nil {
+		return nil
+	}
+
+	hubVersion, err := version.NewVersion(hubConfig().HubOCPXYVersion)
+	if err != nil {
+		return nil
+	}
+
+	if imageSetVersion.GreaterThan(hubVersion) {
+		return fmt.Errorf("clusterimageset version %s is newer than hub version %s",
+			imageSetXY, hubConfig().HubOCPXYVersion)
+	}
+
+	return nil
+}
+
+// validatePullSecret checks that the spoke pull-secret is a valid docker config. OCP releases also require
+// registry credentials while OKD releases accept an empty set of auths. Pull-secrets with caller-supplied data are
+// left for the assisted service to validate.
+func (spoke *SpokeClusterResources) validatePullSecret() error {
+	if spoke.PullSecret == nil || spoke.customPullSecret {
+		return nil
+	}
+
+	dockerConfig, found := spoke.PullSecret.Definition.Data[corev1.DockerConfigJsonKey]
+	if !found {
+		return fmt.Errorf("pull-secret is missing the %s key", corev1.DockerConfigJsonKey)
+	}
+
+	var auths struct {
+		Auths map[string]json.RawMessage `json:"auths"`
+	}
+
+	if err := json.Unmarshal(dockerConfig, &auths); err != nil {
+		return fmt.Errorf("pull-secret is not a valid docker config: %w", err)
+	}
+
+	if len(auths.Auths) == 0 && spoke.ReleaseFlavor() != ReleaseFlavorOKD {
+		return fmt.Errorf("pull-secret must contain registry credentials for %s releases", ReleaseFlavorOCP)
+	}
+
+	return nil
+}
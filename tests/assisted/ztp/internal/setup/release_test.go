@@ -0,0 +1,200 @@
+package setup
+
+import (
+	"testing"
+
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestParseReleaseVersion(t *testing.T) {
+	testCases := []struct {
+		releaseVersion  string
+		expectedVersion ReleaseVersion
+		expectedErr     bool
+	}{
+		{
+			releaseVersion:  "4.16.3",
+			expectedVersion: ReleaseVersion{Major: 4, Minor: 16, Flavor: ReleaseFlavorOCP},
+		},
+		{
+			releaseVersion:  "4.17.0-rc.1",
+			expectedVersion: ReleaseVersion{Major: 4, Minor: 17, Flavor: ReleaseFlavorOCP},
+		},
+		{
+			releaseVersion:  "4.15.0-0.okd-2024-03-10-010116",
+			expectedVersion: ReleaseVersion{Major: 4, Minor: 15, Flavor: ReleaseFlavorOKD},
+		},
+		{
+			releaseVersion:  "4.16.0-0.okd-scos-2024-06-03-160145",
+			expectedVersion: ReleaseVersion{Major: 4, Minor: 16, Flavor: ReleaseFlavorOKD, SCOS: true},
+		},
+		{releaseVersion: "not-a-version", expectedErr: true},
+	}
+
+	for _, testCase := range testCases {
+		parsed, err := ParseReleaseVersion(testCase.releaseVersion)
+
+		assert.Equal(t, testCase.expectedErr, err != nil)
+		assert.Equal(t, testCase.expectedVersion, parsed)
+	}
+
+	assert.Equal(t, "4.16", ReleaseVersion{Major: 4, Minor: 16}.XY())
+}
+
+func TestVerifyReleaseVersion(t *testing.T) {
+	testCases := []struct {
+		okdImageSet    string
+		clusterVersion string
+		expectedErr    string
+	}{
+		{clusterVersion: "4.16.3"},
+		{
+			clusterVersion: "4.15.3",
+			expectedErr:    "cluster version 4.15.3 does not match clusterimageset version 4.16",
+		},
+		{
+			clusterVersion: "4.16.0-0.okd-scos-2024-06-03-160145",
+			expectedErr: "cluster version 4.16.0-0.okd-scos-2024-06-03-160145 is an okd release but spoke " +
+				"release-spoke expects an ocp release",
+		},
+		{okdImageSet: "okd-scos-4.16", clusterVersion: "4.16.0-0.okd-scos-2024-06-03-160145"},
+		{okdImageSet: "okd-latest", clusterVersion: "4.17.0-0.okd-scos-2024-09-11-081121"},
+		{
+			okdImageSet:    "okd-scos-4.16",
+			clusterVersion: "4.16.3",
+			expectedErr:    "cluster version 4.16.3 is an ocp release but spoke release-spoke expects an okd release",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := StandardHAProfile(newTestClient(), "release-spoke")
+		if testCase.okdImageSet != "" {
+			spoke.WithOKDRelease(testCase.okdImageSet)
+		}
+
+		err := spoke.VerifyReleaseVersion(testCase.clusterVersion)
+
+		if testCase.expectedErr == "" {
+			assert.Nil(t, err)
+		} else {
+			assert.EqualError(t, err, testCase.expectedErr)
+		}
+	}
+}
+
+func TestOKDReleaseValidation(t *testing.T) {
+	invalidPullSecretErr := "pull-secret is not a valid docker config: " +
+		"invalid character 'i' looking for beginning of value"
+
+	testCases := []struct {
+		okdImageSet    string
+		imageSet       string
+		pullSecretData string
+		expectedErr    string
+	}{
+		{pullSecretData: testPullSecretData},
+		{imageSet: "4.15", pullSecretData: testPullSecretData},
+		{
+			pullSecretData: `{"auths":{}}`,
+			expectedErr:    "pull-secret must contain registry credentials for ocp releases",
+		},
+		{
+			imageSet:       "4.17",
+			pullSecretData: testPullSecretData,
+			expectedErr:    "clusterimageset version 4.17 is newer than hub version 4.16",
+		},
+		{
+			pullSecretData: "invalid",
+			expectedErr:    invalidPullSecretErr,
+		},
+		{okdImageSet: "okd-scos-4.17", pullSecretData: `{"auths":{}}`},
+		{
+			okdImageSet:    "okd-scos-4.17",
+			pullSecretData: "invalid",
+			expectedErr:    invalidPullSecretErr,
+		},
+	}
+
+	for _, testCase := range testCases {
+		imageSetName := testHubOCPXYVersion
+		if testCase.imageSet != "" || testCase.okdImageSet != "" {
+			imageSetName = testCase.imageSet + testCase.okdImageSet
+		}
+
+		spoke := StandardHAProfile(newTestClient(buildDummyClusterImageSet(imageSetName, "", "")), "release-spoke")
+		spoke.PullSecret.Definition.Data = map[string][]byte{
+			corev1.DockerConfigJsonKey: []byte(testCase.pullSecretData),
+		}
+
+		if testCase.imageSet != "" {
+			spoke.AgentClusterInstall.WithImageSet(testCase.imageSet)
+		}
+
+		if testCase.okdImageSet != "" {
+			spoke.WithOKDRelease(testCase.okdImageSet)
+			assert.Equal(t, ReleaseFlavorOKD, spoke.ReleaseFlavor())
+			assert.Equal(t, testCase.okdImageSet, spoke.AgentClusterInstall.Definition.Spec.ImageSetRef.Name)
+		}
+
+		err := spoke.Validate()
+
+		if testCase.expectedErr == "" {
+			assert.Nil(t, err)
+		} else {
+			assert.EqualError(t, err, testCase.expectedErr)
+		}
+	}
+
+	spoke := NewSpokeCluster(newTestClient()).WithName("release-spoke").WithOKDRelease("okd-scos-4.16")
+	assert.EqualError(t, spoke.err, "WithOKDRelease: agentclusterinstall must be defined before setting an okd release")
+}
+
+func TestFindOSImage(t *testing.T) {
+	rhcosImage := agentInstallV1Beta1.OSImage{
+		OpenshiftVersion: "4.16", Version: "416.94.202406251923-0", Url: "https://example.com/rhcos-4.16-x86_64.iso",
+	}
+	scosImage := agentInstallV1Beta1.OSImage{
+		OpenshiftVersion: "4.16", Version: "416.9.202406031115-0", Url: "https://example.com/scos-4.16-x86_64.iso",
+		CPUArchitecture: CPUArchitectureX86_64,
+	}
+	arm64Image := agentInstallV1Beta1.OSImage{
+		OpenshiftVersion: "4.16", Version: "416.94.202406251923-0", Url: "https://example.com/rhcos-4.16-arm64.iso",
+		CPUArchitecture: CPUArchitectureARM64,
+	}
+	osImages := []agentInstallV1Beta1.OSImage{rhcosImage, scosImage, arm64Image}
+
+	testCases := []struct {
+		openshiftVersion string
+		arch             string
+		flavor           ReleaseFlavor
+		expectedImage    agentInstallV1Beta1.OSImage
+		expectedErr      string
+	}{
+		{openshiftVersion: "4.16", arch: CPUArchitectureX86_64, flavor: ReleaseFlavorOCP, expectedImage: rhcosImage},
+		{openshiftVersion: "4.16", arch: CPUArchitectureX86_64, flavor: ReleaseFlavorOKD, expectedImage: scosImage},
+		{openshiftVersion: "4.16", arch: CPUArchitectureARM64, flavor: ReleaseFlavorOKD, expectedImage: arm64Image},
+		{
+			openshiftVersion: "4.15",
+			arch:             CPUArchitectureX86_64,
+			flavor:           ReleaseFlavorOCP,
+			expectedErr:      "no ocp os image found for version 4.15 and architecture x86_64",
+		},
+	}
+
+	for _, testCase := range testCases {
+		osImage, err := FindOSImage(osImages, testCase.openshiftVersion, testCase.arch, testCase.flavor)
+
+		if testCase.expectedErr == "" {
+			assert.Nil(t, err)
+		} else {
+			assert.EqualError(t, err, testCase.expectedErr)
+		}
+
+		assert.Equal(t, testCase.expectedImage, osImage)
+	}
+
+	_, err := FindOSImage([]agentInstallV1Beta1.OSImage{scosImage}, "4.16", CPUArchitectureX86_64, ReleaseFlavorOCP)
+	assert.EqualError(t, err, "no ocp os image found for version 4.16 and architecture x86_64")
+}
@@ -0,0 +1,157 @@
+package setup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+
+	"github.com/containers/image/v5/docker/reference"
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-goinfra/pkg/hive"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	hivev1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/hive/api/v1"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+)
+
+// releaseImageSetPrefix prefixes the names of the clusterimagesets created by WithReleaseImage.
+const releaseImageSetPrefix = "eco-gotests"
+
+// releaseTagVersionRegex matches the release version at the start of release image tags such as 4.14.10-x86_64.
+var releaseTagVersionRegex = regexp.MustCompile(`^\d+\.\d+(\.\d+)?`)
+
+// WithReleaseImage sets the agentclusterinstall to install releaseImage, a pull spec referencing a tag or digest,
+// instead of the hub version. A clusterimageset of the hub already referencing releaseImage is reused, otherwise
+// one named after the release version and a hash of releaseImage is created along with the spoke. Delete only
+// removes the clusterimageset when the spoke created it and no other agentclusterinstall references it.
+func (spoke *SpokeClusterResources) WithReleaseImage(releaseImage string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.AgentClusterInstall == nil {
+		spoke.err = fmt.Errorf("WithReleaseImage: agentclusterinstall must be defined before setting a release image")
+
+		return spoke
+	}
+
+	imageSetName, err := releaseImageSetName(releaseImage)
+	if err != nil {
+		spoke.err = fmt.Errorf("WithReleaseImage: %w", err)
+
+		return spoke
+	}
+
+	imageSetList := &hivev1.ClusterImageSetList{}
+
+	if err := spoke.apiClient.List(context.TODO(), imageSetList); err != nil {
+		spoke.err = fmt.Errorf("WithReleaseImage: failed to list clusterimagesets: %w", err)
+
+		return spoke
+	}
+
+	spoke.ClusterImageSet = nil
+
+	for _, imageSet := range imageSetList.Items {
+		if imageSet.Spec.ReleaseImage == releaseImage {
+			glog.V(ztpparams.ZTPLogLevel).Infof(
+				"Reusing clusterimageset %s for release image %s of spoke %s", imageSet.Name, releaseImage, spoke.Name)
+
+			spoke.AgentClusterInstall.WithImageSet(imageSet.Name)
+
+			return spoke
+		}
+	}
+
+	spoke.ClusterImageSet = hive.NewClusterImageSetBuilder(spoke.apiClient, imageSetName, releaseImage)
+	spoke.AgentClusterInstall.WithImageSet(imageSetName)
+
+	return spoke
+}
+
+// releaseImageSetName returns the name of the clusterimageset created for releaseImage, or an error when it is
+// not a pull spec referencing a tag or digest.
+func releaseImageSetName(releaseImage string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(releaseImage)
+	if err != nil {
+		return "", fmt.Errorf("invalid release image %q: %w", releaseImage, err)
+	}
+
+	_, tagged := named.(reference.Tagged)
+	_, digested := named.(reference.Digested)
+
+	if !tagged && !digested {
+		return "", fmt.Errorf("release image %q must reference a tag or digest", releaseImage)
+	}
+
+	hash := sha256.Sum256([]byte(releaseImage))
+	suffix := hex.EncodeToString(hash[:])[:10]
+
+	if tagged {
+		if releaseVersion := releaseTagVersionRegex.FindString(named.(reference.Tagged).Tag()); releaseVersion != "" {
+			return fmt.Sprintf("%s-%s-%s", releaseImageSetPrefix, releaseVersion, suffix), nil
+		}
+	}
+
+	return fmt.Sprintf("%s-%s", releaseImageSetPrefix, suffix), nil
+}
+
+// createClusterImageSet creates the clusterimageset defined by WithReleaseImage, recording that the spoke owns it.
+// A clusterimageset created concurrently by another spoke is adopted without ownership.
+func (spoke *SpokeClusterResources) createClusterImageSet(ctx context.Context) error {
+	return spoke.createOrAdopt(ctx, "clusterimageset", spoke.ClusterImageSet, func() error {
+		imageSet, err := spoke.ClusterImageSet.Create()
+		if err != nil {
+			return err
+		}
+
+		spoke.ClusterImageSet = imageSet
+		spoke.ownsClusterImageSet = true
+
+		return nil
+	})
+}
+
+// clusterImageSetInUse returns true when an agentclusterinstall other than the spoke one references the
+// clusterimageset defined by WithReleaseImage.
+func (spoke *SpokeClusterResources) clusterImageSetInUse(ctx context.Context) (bool, error) {
+	agentClusterInstallList := &v1beta1.AgentClusterInstallList{}
+
+	if err := spoke.apiClient.List(ctx, agentClusterInstallList); err != nil {
+		return false, fmt.Errorf("failed to list agentclusterinstalls: %w", err)
+	}
+
+	for _, agentClusterInstall := range agentClusterInstallList.Items {
+		if spoke.AgentClusterInstall != nil &&
+			agentClusterInstall.Namespace == spoke.AgentClusterInstall.Definition.Namespace &&
+			agentClusterInstall.Name == spoke.AgentClusterInstall.Definition.Name {
+			continue
+		}
+
+		imageSetRef := agentClusterInstall.Spec.ImageSetRef
+		if imageSetRef != nil && imageSetRef.Name == spoke.ClusterImageSet.Definition.Name {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// validateReleaseImage checks that the agentclusterinstall still references the clusterimageset defined by
+// WithReleaseImage, which is lost when the agentclusterinstall is replaced afterwards.
+func (spoke *SpokeClusterResources) validateReleaseImage() error {
+	if spoke.ClusterImageSet == nil || spoke.AgentClusterInstall == nil {
+		return nil
+	}
+
+	imageSetName := spoke.ClusterImageSet.Definition.Name
+	imageSetRef := spoke.AgentClusterInstall.Definition.Spec.ImageSetRef
+
+	if imageSetRef == nil || imageSetRef.Name != imageSetName {
+		return fmt.Errorf("agentclusterinstall does not reference clusterimageset %s of release image %s",
+			imageSetName, spoke.ClusterImageSet.Definition.Spec.ReleaseImage)
+	}
+
+	return nil
+}
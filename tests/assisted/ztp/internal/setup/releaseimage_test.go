@@ -0,0 +1,131 @@
+package setup
+
+import (
+	"testing"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/hive"
+	assistedHiveV1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/hive/api/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+const testReleaseImage = "quay.io/openshift-release-dev/ocp-release:4.14.10-x86_64"
+
+func TestReleaseImageSetName(t *testing.T) {
+	testCases := []struct {
+		releaseImage string
+		expectedName string
+		expectedErr  string
+	}{
+		{releaseImage: testReleaseImage, expectedName: `^eco-gotests-4\.14\.10-[0-9a-f]{10}$`},
+		{releaseImage: "registry.example.com:5000/ocp/release:latest", expectedName: `^eco-gotests-[0-9a-f]{10}$`},
+		{
+			releaseImage: "quay.io/openshift-release-dev/ocp-release@sha256:" +
+				"2d0c5d6f2c4a1a4e8f0d5c3f0e5c3b5e4f8a9b7c6d5e4f3a2b1c0d9e8f7a6b5c",
+			expectedName: `^eco-gotests-[0-9a-f]{10}$`,
+		},
+		{
+			releaseImage: "quay.io/openshift-release-dev/ocp-release",
+			expectedErr:  `release image "quay.io/openshift-release-dev/ocp-release" must reference a tag or digest`,
+		},
+		{releaseImage: "Quay.io/OCP:4.14", expectedErr: `invalid release image "Quay.io/OCP:4.14": `},
+		{releaseImage: "", expectedErr: `invalid release image "": `},
+	}
+
+	for _, testCase := range testCases {
+		name, err := releaseImageSetName(testCase.releaseImage)
+
+		if testCase.expectedErr != "" {
+			assert.ErrorContains(t, err, testCase.expectedErr, testCase.releaseImage)
+
+			continue
+		}
+
+		assert.Nil(t, err, testCase.releaseImage)
+		assert.Regexp(t, testCase.expectedName, name, testCase.releaseImage)
+	}
+
+	first, _ := releaseImageSetName(testReleaseImage)
+	second, _ := releaseImageSetName(testReleaseImage)
+	other, _ := releaseImageSetName("quay.io/openshift-release-dev/ocp-release:4.14.10-aarch64")
+	assert.Equal(t, first, second)
+	assert.NotEqual(t, first, other)
+}
+
+func TestWithReleaseImage(t *testing.T) {
+	apiClient := newHubTestClient()
+	spoke := StandardHAProfile(apiClient, "release-spoke").WithReleaseImage(testReleaseImage)
+	assert.Nil(t, spoke.err)
+	assert.NotNil(t, spoke.ClusterImageSet)
+
+	imageSetName := spoke.ClusterImageSet.Definition.Name
+	assert.Equal(t, imageSetName, spoke.AgentClusterInstall.Definition.Spec.ImageSetRef.Name)
+
+	_, err := spoke.Create()
+	assert.Nil(t, err)
+	assert.True(t, spoke.ClusterImageSet.Exists())
+	assert.Equal(t, testReleaseImage, spoke.ClusterImageSet.Object.Spec.ReleaseImage)
+
+	reused := StandardHAProfile(apiClient, "reused-spoke").WithReleaseImage(testReleaseImage)
+	assert.Nil(t, reused.err)
+	assert.Nil(t, reused.ClusterImageSet)
+	assert.Equal(t, imageSetName, reused.AgentClusterInstall.Definition.Spec.ImageSetRef.Name)
+
+	_, err = reused.Create()
+	assert.Nil(t, err)
+
+	assert.Nil(t, spoke.Delete())
+	assert.True(t, spoke.ClusterImageSet.Exists(), "clusterimageset is still referenced by reused-spoke")
+
+	assert.Nil(t, reused.Delete())
+	assert.True(t, spoke.ClusterImageSet.Exists(), "clusterimageset is not owned by reused-spoke")
+
+	spoke = StandardHAProfile(newHubTestClient(), "release-spoke").WithReleaseImage(testReleaseImage)
+
+	_, err = spoke.Create()
+	assert.Nil(t, err)
+	assert.Nil(t, spoke.Delete())
+	assert.False(t, spoke.ClusterImageSet.Exists())
+}
+
+func TestWithReleaseImageAdopted(t *testing.T) {
+	apiClient := newHubTestClient()
+	spoke := StandardHAProfile(apiClient, "release-spoke").WithReleaseImage(testReleaseImage)
+	assert.Nil(t, spoke.err)
+
+	_, err := hive.NewClusterImageSetBuilder(
+		apiClient, spoke.ClusterImageSet.Definition.Name, testReleaseImage).Create()
+	assert.Nil(t, err)
+
+	_, err = spoke.Create()
+	assert.Nil(t, err)
+	assert.Contains(t, spoke.createdResources, "clusterimageset")
+
+	assert.Nil(t, spoke.Delete())
+	assert.True(t, spoke.ClusterImageSet.Exists(), "adopted clusterimageset is not owned by the spoke")
+}
+
+func TestWithReleaseImageValidation(t *testing.T) {
+	spoke := StandardHAProfile(newHubTestClient(), "release-spoke").WithReleaseImage("quay.io/ocp-release")
+	assert.EqualError(t, spoke.Validate(),
+		`WithReleaseImage: release image "quay.io/ocp-release" must reference a tag or digest`)
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithReleaseImage(testReleaseImage)
+	assert.EqualError(t, spoke.err,
+		"WithReleaseImage: agentclusterinstall must be defined before setting a release image")
+
+	spoke = StandardHAProfile(newHubTestClient(), "release-spoke").WithReleaseImage(testReleaseImage)
+	spoke.AgentClusterInstall.Definition.Spec.ImageSetRef = &assistedHiveV1.ClusterImageSetReference{
+		Name: testHubOCPXYVersion,
+	}
+	assert.ErrorContains(t, spoke.Validate(),
+		"agentclusterinstall does not reference clusterimageset eco-gotests-4.14.10-")
+
+	spoke = StandardHAProfile(newHubTestClient(), "release-spoke").WithReleaseImage(
+		"quay.io/openshift-release-dev/ocp-release:4.17.0-x86_64")
+	assert.ErrorContains(t, spoke.Validate(), "clusterimageset version 4.17 is newer than hub version 4.16")
+
+	existing := buildDummyClusterImageSet("4.14", "", testReleaseImage)
+	spoke = StandardHAProfile(newHubTestClient(existing), "release-spoke").WithReleaseImage(testReleaseImage)
+	assert.Nil(t, spoke.Validate())
+	assert.Equal(t, "4.14", spoke.AgentClusterInstall.Definition.Spec.ImageSetRef.Name)
+}
@@ -0,0 +1,75 @@
+package setup
+
+import (
+	"fmt"
+	"io"
+)
+
+// Render writes the spoke resources as CreateWithContext would create them to writer, as a multi-document YAML
+// stream in the order of DumpToWriter, without calling the API, so that they can be committed for a GitOps flow or
+// inspected. The labels, extra manifest references, compute pools, disk encryption and nmstateconfig selector added
+// at creation are applied first, but the checks of Validate against the hub are not made. Secret data is redacted as
+// with the default DumpOptions.
+func (spoke *SpokeClusterResources) Render(writer io.Writer) error {
+	if writer == nil {
+		return fmt.Errorf("writer cannot be nil")
+	}
+
+	resources, err := spoke.renderResources()
+	if err != nil {
+		return err
+	}
+
+	return writeResources(writer, resources)
+}
+
+// RenderToDir writes the spoke resources rendered like Render to their own YAML file in dir, named like the files of
+// DumpYAML, creating dir when it does not exist.
+func (spoke *SpokeClusterResources) RenderToDir(dir string) error {
+	resources, err := spoke.renderResources()
+	if err != nil {
+		return err
+	}
+
+	return writeResourcesToDir(dir, resources)
+}
+
+// renderResources applies the changes made to the spoke definitions at creation and serializes them.
+func (spoke *SpokeClusterResources) renderResources() ([]dumpedResource, error) {
+	if spoke.err != nil {
+		return nil, spoke.err
+	}
+
+	if spoke.apiClient == nil {
+		return nil, fmt.Errorf("apiClient cannot be nil")
+	}
+
+	if err := spoke.applyComputePools(); err != nil {
+		return nil, err
+	}
+
+	if spoke.diskEncryption != nil && spoke.AgentClusterInstall == nil {
+		return nil, fmt.Errorf("disk encryption requires an agentclusterinstall")
+	}
+
+	spoke.applyDiskEncryption()
+	spoke.applyMetadata()
+	spoke.applyNMStateConfigSelector()
+
+	if spoke.AgentClusterInstall != nil {
+		spoke.attachExtraManifests()
+	}
+
+	var resources []dumpedResource
+
+	for _, definition := range spoke.definitions() {
+		resource, err := spoke.dumpResource(definition, &DumpOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		resources = append(resources, resource)
+	}
+
+	return resources, nil
+}
@@ -0,0 +1,129 @@
+package setup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	hivev1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/hive/api/v1"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	runtimeClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+const (
+	testRenderNMStateYAML   = "interfaces:\n- name: eth0\n  type: ethernet\n  state: up\n"
+	testRenderExtraManifest = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\n  namespace: default\n"
+)
+
+func TestRender(t *testing.T) {
+	spoke := newRenderTestSpoke()
+	assert.Nil(t, spoke.err)
+
+	var writer bytes.Buffer
+
+	assert.Nil(t, spoke.Render(&writer))
+
+	documents := strings.Split(strings.TrimPrefix(writer.String(), "---\n"), "---\n")
+	assert.Len(t, documents, 7)
+
+	var agentClusterInstall, infraEnv map[string]interface{}
+
+	for _, document := range documents {
+		resource := map[string]interface{}{}
+		assert.Nil(t, yaml.Unmarshal([]byte(document), &resource))
+
+		metadata := resource["metadata"].(map[interface{}]interface{})
+		assert.Equal(t, "spoke", metadata["labels"].(map[interface{}]interface{})[SpokeOwnershipLabel],
+			resource["kind"])
+		assert.NotContains(t, resource, "status", resource["kind"])
+
+		switch resource["kind"] {
+		case "AgentClusterInstall":
+			agentClusterInstall = resource
+		case "InfraEnv":
+			infraEnv = resource
+		}
+	}
+
+	assert.NotContains(t, writer.String(), `{"auths":{"hub":{}}}`)
+	assert.Contains(t, documents[1], "kind: Secret")
+	assert.Equal(t, []interface{}{map[interface{}]interface{}{"name": "spoke-manifests"}},
+		agentClusterInstall["spec"].(map[interface{}]interface{})["manifestsConfigMapRefs"])
+
+	selector := infraEnv["spec"].(map[interface{}]interface{})["nmStateConfigLabelSelector"]
+	assert.Equal(t, map[interface{}]interface{}{StaticNetworkingLabel: "spoke"},
+		selector.(map[interface{}]interface{})["matchLabels"])
+
+	assert.EqualError(t, spoke.Render(nil), "writer cannot be nil")
+	assert.EqualError(t, NewSpokeCluster(newTestClient()).WithName("").Render(&writer),
+		"WithName: spoke name cannot be empty")
+}
+
+func TestRenderToDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "render")
+
+	assert.Nil(t, newRenderTestSpoke().RenderToDir(dir))
+
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+
+	var fileNames []string
+	for _, entry := range entries {
+		fileNames = append(fileNames, entry.Name())
+	}
+
+	assert.Equal(t, []string{
+		"00-namespace-spoke.yaml",
+		"01-secret-spoke-pull-secret.yaml",
+		"02-clusterdeployment-spoke.yaml",
+		"03-agentclusterinstall-spoke.yaml",
+		"04-infraenv-spoke.yaml",
+		"05-configmap-spoke-manifests.yaml",
+		"06-nmstateconfig-master-0.yaml",
+	}, fileNames)
+}
+
+// newRenderTestSpoke returns a spoke with extra manifests and an nmstateconfig whose client fails every call, so
+// that rendering it fails when it reaches the API.
+func newRenderTestSpoke() *SpokeClusterResources {
+	apiClient, testBuilder := clients.GetModifiableTestClients(clients.TestClientParams{
+		SchemeAttachers: []clients.SchemeAttacher{
+			v1beta1.AddToScheme,
+			agentInstallV1Beta1.AddToScheme,
+			hivev1.AddToScheme,
+		},
+	})
+
+	errUnexpectedCall := errors.New("unexpected api call")
+
+	apiClient.Client = testBuilder.WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, client runtimeClient.WithWatch, key runtimeClient.ObjectKey,
+			obj runtimeClient.Object, opts ...runtimeClient.GetOption) error {
+			return errUnexpectedCall
+		},
+		List: func(ctx context.Context, client runtimeClient.WithWatch,
+			list runtimeClient.ObjectList, opts ...runtimeClient.ListOption) error {
+			return errUnexpectedCall
+		},
+		Create: func(ctx context.Context, client runtimeClient.WithWatch,
+			obj runtimeClient.Object, opts ...runtimeClient.CreateOption) error {
+			return errUnexpectedCall
+		},
+	}).Build()
+
+	return NewSpokeCluster(apiClient).WithName("spoke").WithDefaultNamespace().
+		WithPullSecretData(map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{"hub":{}}}`)}).
+		WithDefaultClusterDeployment().WithDefaultIPv4AgentClusterInstall().WithDefaultInfraEnv().
+		WithExtraManifests("spoke-manifests", map[string]string{"01-test.yaml": testRenderExtraManifest}).
+		WithNMStateConfig("master-0", testRenderNMStateYAML, map[string]string{"eth0": "52:54:00:00:00:01"})
+}
@@ -0,0 +1,100 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+)
+
+const (
+	defaultRetries       = 3
+	defaultRetryInterval = time.Second
+	retryBackoffFactor   = 2
+	retryLogLevel        = 2
+)
+
+// retryTransient runs operation, retrying it with exponential backoff while it fails with a transient API error,
+// up to the number of retries and within the retry budget of the spoke wait options. Other errors are returned
+// immediately, the error of ctx is returned when it is done while waiting to retry, and an error still returned
+// after retrying notes how many attempts were made.
+func (spoke *SpokeClusterResources) retryTransient(
+	ctx context.Context, description string, operation func() error) error {
+	options := spoke.resolveWaitOptions()
+	retries, interval := options.retryLimits()
+	start := time.Now()
+
+	err := operation()
+	attempts := 1
+
+	for ; attempts <= retries && isTransientError(err); attempts++ {
+		if options.RetryBudget > 0 && time.Since(start)+interval > options.RetryBudget {
+			glog.V(retryLogLevel).Infof("Retry budget %s for %s of spoke %s exhausted", options.RetryBudget,
+				description, spoke.Name)
+
+			break
+		}
+
+		glog.V(retryLogLevel).Infof("Retrying %s for spoke %s in %s after transient error (attempt %d/%d): %v",
+			description, spoke.Name, interval, attempts, retries, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+
+		interval *= retryBackoffFactor
+		if interval > options.Timeout {
+			interval = options.Timeout
+		}
+
+		err = operation()
+	}
+
+	if err != nil && attempts > 1 {
+		return fmt.Errorf("%s for spoke %s failed after %d attempts: %w", description, spoke.Name, attempts, err)
+	}
+
+	return err
+}
+
+// retryLimits returns the number of retries and the initial retry interval, falling back to the defaults when
+// they are not set. A negative Retries disables retries.
+func (options WaitOptions) retryLimits() (int, time.Duration) {
+	retries, interval := options.Retries, options.RetryInterval
+
+	if retries == 0 {
+		retries = defaultRetries
+	}
+
+	if interval == 0 {
+		interval = defaultRetryInterval
+	}
+
+	return retries, interval
+}
+
+// isTransientError returns true for API errors caused by a temporarily unavailable or overloaded API server or a
+// dropped connection, which are worth retrying. Validation, authorization, conflict and already exists errors are
+// never transient, since retrying the same operation against the same stale object fails again.
+func isTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if k8serrors.IsInvalid(err) || k8serrors.IsBadRequest(err) || k8serrors.IsForbidden(err) ||
+		k8serrors.IsUnauthorized(err) || k8serrors.IsAlreadyExists(err) || k8serrors.IsNotFound(err) ||
+		k8serrors.IsConflict(err) {
+		return false
+	}
+
+	return k8serrors.IsTimeout(err) || k8serrors.IsServerTimeout(err) || k8serrors.IsServiceUnavailable(err) ||
+		k8serrors.IsTooManyRequests(err) || k8serrors.IsInternalError(err) ||
+		utilnet.IsConnectionRefused(err) || utilnet.IsConnectionReset(err) ||
+		strings.Contains(err.Error(), "connection refused") || strings.Contains(err.Error(), "connection reset by peer")
+}
@@ -0,0 +1,216 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	hivev1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/hive/api/v1"
+	"github.com/stretchr/testify/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	runtimeClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var (
+	testClusterDeploymentResource = schema.GroupResource{Group: "hive.openshift.io", Resource: "clusterdeployments"}
+	testForbiddenErr              = k8serrors.NewForbidden(
+		testClusterDeploymentResource, "spoke", errors.New("forbidden"))
+)
+
+func TestIsTransientError(t *testing.T) {
+	testCases := []struct {
+		err       error
+		transient bool
+	}{
+		{err: nil, transient: false},
+		{err: k8serrors.NewServiceUnavailable("unavailable"), transient: true},
+		{err: k8serrors.NewServerTimeout(testClusterDeploymentResource, "create", 1), transient: true},
+		{err: k8serrors.NewTimeoutError("timeout", 1), transient: true},
+		{err: k8serrors.NewInternalError(errors.New("internal")), transient: true},
+		{err: fmt.Errorf("failed to create: %w", syscall.ECONNREFUSED), transient: true},
+		{err: errors.New("dial tcp 10.0.0.1:6443: connect: connection refused"), transient: true},
+		{err: fmt.Errorf("failed to create: %w", syscall.ECONNRESET), transient: true},
+		{err: errors.New("read tcp 10.0.0.2:41234->10.0.0.1:6443: read: connection reset by peer"), transient: true},
+		{err: k8serrors.NewTooManyRequests("too many requests", 1), transient: true},
+		{err: k8serrors.NewConflict(testClusterDeploymentResource, "spoke", errors.New("conflict")), transient: false},
+		{err: k8serrors.NewInvalid(schema.GroupKind{Kind: "ClusterDeployment"}, "spoke", nil), transient: false},
+		{err: testForbiddenErr, transient: false},
+		{err: k8serrors.NewAlreadyExists(testClusterDeploymentResource, "spoke"), transient: false},
+		{err: errors.New("clusterdeployment spoke is invalid"), transient: false},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.transient, isTransientError(testCase.err), "error: %v", testCase.err)
+	}
+}
+
+func TestRetryTransient(t *testing.T) {
+	testCases := []struct {
+		name          string
+		failures      int
+		failureErr    error
+		retries       int
+		retryBudget   time.Duration
+		expectedCalls int
+		expectedErr   string
+	}{
+		{name: "success", failures: 0, retries: 3, expectedCalls: 1},
+		{
+			name:          "recovers",
+			failures:      2,
+			failureErr:    k8serrors.NewServiceUnavailable("unavailable"),
+			retries:       3,
+			expectedCalls: 3,
+		},
+		{
+			name:          "exhausted",
+			failures:      5,
+			failureErr:    k8serrors.NewServiceUnavailable("unavailable"),
+			retries:       2,
+			expectedCalls: 3,
+			expectedErr:   "exhausted for spoke spoke failed after 3 attempts: unavailable",
+		},
+		{
+			name:          "budget",
+			failures:      5,
+			failureErr:    k8serrors.NewServiceUnavailable("unavailable"),
+			retries:       5,
+			retryBudget:   2 * time.Millisecond,
+			expectedCalls: 2,
+			expectedErr:   "budget for spoke spoke failed after 2 attempts: unavailable",
+		},
+		{
+			name:          "disabled",
+			failures:      1,
+			failureErr:    k8serrors.NewServiceUnavailable("unavailable"),
+			retries:       -1,
+			expectedCalls: 1,
+			expectedErr:   "unavailable",
+		},
+		{
+			name:          "not transient",
+			failures:      1,
+			failureErr:    testForbiddenErr,
+			retries:       3,
+			expectedCalls: 1,
+			expectedErr:   testForbiddenErr.Error(),
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithWaitOptions(&WaitOptions{
+			Interval:      time.Millisecond,
+			Timeout:       time.Second,
+			Retries:       testCase.retries,
+			RetryInterval: time.Millisecond,
+			RetryBudget:   testCase.retryBudget,
+		})
+
+		calls := 0
+		err := spoke.retryTransient(context.TODO(), testCase.name, func() error {
+			calls++
+
+			if calls <= testCase.failures {
+				return testCase.failureErr
+			}
+
+			return nil
+		})
+
+		assert.Equal(t, testCase.expectedCalls, calls, testCase.name)
+
+		if testCase.expectedErr == "" {
+			assert.Nil(t, err, testCase.name)
+		} else {
+			assert.EqualError(t, err, testCase.expectedErr, testCase.name)
+			assert.ErrorIs(t, err, testCase.failureErr, testCase.name)
+		}
+	}
+}
+
+func TestCreateRetriesTransientErrors(t *testing.T) {
+	testCases := []struct {
+		failureErr    error
+		expectedCalls int
+		expectedErr   bool
+	}{
+		{failureErr: k8serrors.NewServiceUnavailable("unavailable"), expectedCalls: 2, expectedErr: false},
+		{failureErr: k8serrors.NewServerTimeout(testClusterDeploymentResource, "create", 1), expectedCalls: 2},
+		{failureErr: fmt.Errorf("dial tcp: %w", syscall.ECONNREFUSED), expectedCalls: 2, expectedErr: false},
+		{
+			failureErr:    k8serrors.NewInvalid(schema.GroupKind{Kind: "ClusterDeployment"}, "spoke", nil),
+			expectedCalls: 1,
+			expectedErr:   true,
+		},
+		{
+			failureErr:    k8serrors.NewConflict(testClusterDeploymentResource, "spoke", errors.New("conflict")),
+			expectedCalls: 1,
+			expectedErr:   true,
+		},
+		{
+			failureErr:    fmt.Errorf("read tcp: %w", syscall.ECONNRESET),
+			expectedCalls: 2,
+			expectedErr:   false,
+		},
+		{
+			failureErr:    testForbiddenErr,
+			expectedCalls: 1,
+			expectedErr:   true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		calls := 0
+		apiClient := newFailingTestClient(func() error {
+			calls++
+
+			if calls == 1 {
+				return testCase.failureErr
+			}
+
+			return nil
+		})
+
+		options := &WaitOptions{Interval: time.Millisecond, Timeout: time.Second, RetryInterval: time.Millisecond}
+		_, err := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultPullSecret().WithDefaultClusterDeployment().
+			WithWaitOptions(options).Create()
+
+		assert.Equal(t, testCase.expectedCalls, calls, "error: %v", testCase.failureErr)
+		assert.Equal(t, testCase.expectedErr, err != nil, "error: %v", testCase.failureErr)
+	}
+}
+
+// newFailingTestClient returns a fake client whose clusterdeployment creations first call inject, failing with
+// the returned error when it is not nil.
+func newFailingTestClient(inject func() error) *clients.Settings {
+	apiClient, testBuilder := clients.GetModifiableTestClients(clients.TestClientParams{
+		SchemeAttachers: []clients.SchemeAttacher{
+			v1beta1.AddToScheme,
+			agentInstallV1Beta1.AddToScheme,
+			hivev1.AddToScheme,
+		},
+	})
+
+	apiClient.Client = testBuilder.WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, client runtimeClient.WithWatch,
+			obj runtimeClient.Object, opts ...runtimeClient.CreateOption) error {
+			if _, isClusterDeployment := obj.(*hivev1.ClusterDeployment); isClusterDeployment {
+				if err := inject(); err != nil {
+					return err
+				}
+			}
+
+			return client.Create(ctx, obj, opts...)
+		},
+	}).Build()
+
+	return apiClient
+}
@@ -0,0 +1,69 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-goinfra/pkg/bmh"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// rollbackStep deletes a resource of kind created by CreateWithContext.
+type rollbackStep struct {
+	kind       string
+	deleteFunc func() error
+}
+
+// recordRollback records how to delete the resource of kind that CreateWithContext just created using builder, so
+// that it is removed when a later step fails. Adopted resources are never recorded. It is safe to call from the
+// concurrent creation stage of CreateWithContext.
+func (spoke *SpokeClusterResources) recordRollback(kind string, builder existenceChecker) {
+	var deleteFunc func() error
+
+	switch deleter := builder.(type) {
+	case interface{ Delete() error }:
+		deleteFunc = deleter.Delete
+	case *bmh.BmhBuilder:
+		deleteFunc = func() error {
+			_, err := deleter.Delete()
+
+			return err
+		}
+	default:
+		return
+	}
+
+	spoke.createdResourcesMutex.Lock()
+	defer spoke.createdResourcesMutex.Unlock()
+
+	spoke.rollbackSteps = append(spoke.rollbackSteps, rollbackStep{kind: kind, deleteFunc: deleteFunc})
+}
+
+// rollbackCreated deletes the resources created by CreateWithContext, in the reverse order of their creation,
+// after it failed with createErr. Every deletion is attempted and createErr is returned joined with the failures,
+// resources already gone not being failures.
+func (spoke *SpokeClusterResources) rollbackCreated(ctx context.Context, createErr error) error {
+	errs := []error{createErr}
+
+	for index := len(spoke.rollbackSteps) - 1; index >= 0; index-- {
+		step := spoke.rollbackSteps[index]
+
+		glog.V(ztpparams.ZTPLogLevel).Infof("Rolling back %s of spoke %s after failed creation", step.kind, spoke.Name)
+
+		err := spoke.retryTransient(ctx, "roll back "+step.kind, step.deleteFunc)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to roll back %s of spoke %s: %w", step.kind, spoke.Name, err))
+		}
+	}
+
+	spoke.rollbackSteps = nil
+
+	if len(errs) == 1 {
+		return createErr
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,121 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	hivev1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/hive/api/v1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+	runtimeClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestCreateRollsBackOnFailure(t *testing.T) {
+	testCases := []struct {
+		name          string
+		existing      []runtime.Object
+		deleteErr     error
+		expectedError string
+	}{
+		{name: "created resources", expectedError: "agentclusterinstall rejected"},
+		{
+			name:          "adopted namespace",
+			existing:      []runtime.Object{buildDummyNamespace("spoke", nil)},
+			expectedError: "agentclusterinstall rejected",
+		},
+		{
+			name:      "failed deletion",
+			deleteErr: errors.New("clusterdeployment deletion rejected"),
+			expectedError: "agentclusterinstall rejected\n" +
+				"failed to roll back clusterdeployment of spoke spoke: cannot delete clusterdeployment: " +
+				"clusterdeployment deletion rejected",
+		},
+	}
+
+	for _, testCase := range testCases {
+		var deletes []string
+
+		apiClient := newRollbackTestClient(testCase.existing, testCase.deleteErr, &deletes)
+		spoke := StandardHAProfile(apiClient, "spoke")
+		spoke.serialCreate = true
+
+		_, err := spoke.Create()
+		assert.EqualError(t, err, testCase.expectedError, testCase.name)
+		assert.Equal(t, []string{"infraenv", "clusterdeployment"}, deletes, testCase.name)
+		assert.Equal(t, testCase.deleteErr != nil, spoke.ClusterDeployment.Exists(), testCase.name)
+		assert.False(t, spoke.InfraEnv.Exists(), testCase.name)
+		assert.False(t, spoke.PullSecret.Exists(), testCase.name)
+		assert.Equal(t, testCase.existing != nil, spoke.Namespace.Exists(), testCase.name)
+	}
+}
+
+func TestCreateDoesNotRollBackValidationFailure(t *testing.T) {
+	var deletes []string
+
+	spoke := StandardHAProfile(newRollbackTestClient(nil, nil, &deletes), "spoke").WithSchedulableMasters(false)
+	spoke.AgentClusterInstall.Definition.Spec.ProvisionRequirements.WorkerAgents = 1
+
+	_, err := spoke.Create()
+	assert.ErrorContains(t, err, "cannot disable schedulable masters")
+	assert.Empty(t, deletes)
+	assert.Empty(t, spoke.createdResources)
+}
+
+func TestRollbackSingularVIPsAgentClusterInstall(t *testing.T) {
+	spoke := NewSpokeCluster(newPluralVIPsRejectingTestClient()).WithName("spoke").
+		WithDefaultDualStackAgentClusterInstall()
+	assert.Nil(t, spoke.createAgentClusterInstall(context.TODO()))
+	assert.Equal(t, []string{"agentclusterinstall"}, spoke.createdResources)
+	assert.True(t, spoke.AgentClusterInstall.Exists())
+
+	err := spoke.rollbackCreated(context.TODO(), errors.New("infraenv rejected"))
+	assert.EqualError(t, err, "infraenv rejected")
+	assert.False(t, spoke.AgentClusterInstall.Exists())
+}
+
+// newRollbackTestClient returns a client rejecting the creation of agentclusterinstalls, and the deletion of
+// clusterdeployments with deleteErr when set, recording the kind of each clusterdeployment and infraenv deleted in
+// deletes.
+func newRollbackTestClient(existing []runtime.Object, deleteErr error, deletes *[]string) *clients.Settings {
+	apiClient, testBuilder := clients.GetModifiableTestClients(clients.TestClientParams{
+		K8sMockObjects: append(existing, buildDummyClusterImageSet(testHubOCPXYVersion, "", "")),
+		SchemeAttachers: []clients.SchemeAttacher{
+			v1beta1.AddToScheme,
+			agentInstallV1Beta1.AddToScheme,
+			hivev1.AddToScheme,
+		},
+	})
+
+	apiClient.Client = testBuilder.WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, client runtimeClient.WithWatch,
+			obj runtimeClient.Object, opts ...runtimeClient.CreateOption) error {
+			if _, isAgentClusterInstall := obj.(*v1beta1.AgentClusterInstall); isAgentClusterInstall {
+				return errors.New("agentclusterinstall rejected")
+			}
+
+			return client.Create(ctx, obj, opts...)
+		},
+		Delete: func(ctx context.Context, client runtimeClient.WithWatch,
+			obj runtimeClient.Object, opts ...runtimeClient.DeleteOption) error {
+			switch obj.(type) {
+			case *hivev1.ClusterDeployment:
+				*deletes = append(*deletes, "clusterdeployment")
+
+				if deleteErr != nil {
+					return deleteErr
+				}
+			case *agentInstallV1Beta1.InfraEnv:
+				*deletes = append(*deletes, "infraenv")
+			}
+
+			return client.Delete(ctx, obj, opts...)
+		},
+	}).Build()
+
+	return apiClient
+}
@@ -1,32 +1,108 @@
 package setup
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"math/rand"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/openshift-kni/eco-goinfra/pkg/assisted"
+	"github.com/openshift-kni/eco-goinfra/pkg/bmh"
 	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/configmap"
 	"github.com/openshift-kni/eco-goinfra/pkg/hive"
 	"github.com/openshift-kni/eco-goinfra/pkg/namespace"
+	"github.com/openshift-kni/eco-goinfra/pkg/ocm"
 	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
 	"github.com/openshift-kni/eco-goinfra/pkg/secret"
-	. "github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpinittools"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpconfig"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	runtimeClient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	corev1 "k8s.io/api/core/v1"
 )
 
+const (
+	defaultControlPlaneAgents = 3
+	defaultWorkerAgents       = 2
+	snoControlPlaneAgents     = 1
+	snoWorkerAgents           = 0
+	defaultBaseDomain         = "assisted.test.com"
+	kernelArgumentAppend      = "append"
+
+	defaultIPv4APIVIP      = "192.168.254.5"
+	defaultIPv4IngressVIP  = "192.168.254.10"
+	defaultIPv4ClusterCIDR = "10.128.0.0/14"
+	defaultIPv4ServiceCIDR = "172.30.0.0/16"
+	defaultIPv4HostPrefix  = 23
+	defaultIPv4MachineCIDR = "192.168.254.0/24"
+	defaultIPv6APIVIP      = "fd2e:6f44:5dd8:1::5"
+	defaultIPv6IngressVIP  = "fd2e:6f44:5dd8:1::10"
+	defaultIPv6ClusterCIDR = "fd01::/48"
+	defaultIPv6ServiceCIDR = "fd02::/112"
+	defaultIPv6HostPrefix  = 64
+	defaultIPv6MachineCIDR = "fd2e:6f44:5dd8:1::/64"
+)
+
 // SpokeClusterResources contains necessary resources for creating a spoke cluster.
 type SpokeClusterResources struct {
-	Name                string
-	apiClient           *clients.Settings
-	err                 error
-	Namespace           *namespace.Builder
-	PullSecret          *secret.Builder
-	ClusterDeployment   *hive.ClusterDeploymentBuilder
-	AgentClusterInstall *assisted.AgentClusterInstallBuilder
-	InfraEnv            *assisted.InfraEnvBuilder
+	Name                      string
+	apiClient                 *clients.Settings
+	err                       error
+	Namespace                 *namespace.Builder
+	PullSecret                *secret.Builder
+	InfraEnvNamespace         *namespace.Builder
+	InfraEnvPullSecret        *secret.Builder
+	ClusterDeployment         *hive.ClusterDeploymentBuilder
+	AgentClusterInstall       *assisted.AgentClusterInstallBuilder
+	ClusterImageSet           *hive.ClusterImageSetBuilder
+	InfraEnv                  *assisted.InfraEnvBuilder
+	AdditionalInfraEnvs       []*assisted.InfraEnvBuilder
+	ExtraManifests            []*configmap.Builder
+	MirrorRegistryConfigMap   *configmap.Builder
+	NMStateConfigs            []*assisted.NmStateConfigBuilder
+	BMCSecrets                []*secret.Builder
+	BareMetalHosts            []*bmh.BmhBuilder
+	ManagedCluster            *ocm.ManagedClusterBuilder
+	KlusterletAddonConfig     *ocm.KACBuilder
+	expectedHosts             []expectedHost
+	computePools              []computePool
+	diskEncryption            *v1beta1.DiskEncryption
+	waitOptions               *WaitOptions
+	concurrencyLimit          int
+	bootMethod                BootMethod
+	checkImageSetArch         bool
+	workerArchitectures       map[string]int
+	skipInvalidExtraManifests bool
+	releaseFlavor             ReleaseFlavor
+	customPullSecret          bool
+	ownsClusterImageSet       bool
+	explicitVIPs              bool
+	checkDrift                bool
+	generatedName             bool
+	labels                    map[string]string
+	annotations               map[string]string
+	agentLabels               map[string]string
+	agentSelector             *metav1.LabelSelector
+	installOperators          []string
+	schedulableMasters        *bool
+	createdResources          []string
+	createdResourcesMutex     sync.Mutex
+	rollbackSteps             []rollbackStep
+	interruptedStep           string
+	serialCreate              bool
+	kubeconfigPath            string
+	hubCapabilities           *HubCapabilities
+	timings                   []PhaseTiming
+	timingsMutex              sync.Mutex
 }
 
 // NewSpokeCluster creates a new instance of SpokeClusterResources.
@@ -34,10 +110,20 @@ func NewSpokeCluster(apiClient *clients.Settings) *SpokeClusterResources {
 	return &SpokeClusterResources{apiClient: apiClient}
 }
 
+// GetError returns the first error recorded while building the spoke, or nil. Once an error is recorded, the
+// remaining With* calls are skipped and Create returns it without calling the API.
+func (spoke *SpokeClusterResources) GetError() error {
+	return spoke.err
+}
+
 // WithName sets an explicit name for the spoke cluster.
 func (spoke *SpokeClusterResources) WithName(name string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
 	if name == "" {
-		spoke.err = fmt.Errorf("spoke name cannot be empty")
+		spoke.err = fmt.Errorf("WithName: spoke name cannot be empty")
 	}
 
 	spoke.Name = name
@@ -45,118 +131,372 @@ func (spoke *SpokeClusterResources) WithName(name string) *SpokeClusterResources
 	return spoke
 }
 
-// WithAutoGeneratedName generates a random name for the spoke cluster.
-func (spoke *SpokeClusterResources) WithAutoGeneratedName() *SpokeClusterResources {
-	spoke.Name = generateName(12)
+// WithAutoGeneratedName generates a random name for the spoke cluster, starting with prefix when one is provided so
+// that spokes left on a shared hub can be attributed to the suite that created them. Prefixes too long for a
+// DNS-1123 label once the random suffix is appended are truncated. A name whose namespace already exists on the hub
+// is regenerated once, and Create refuses to adopt the namespace of a generated name that it did not create.
+func (spoke *SpokeClusterResources) WithAutoGeneratedName(prefix ...string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if len(prefix) > 1 {
+		spoke.err = fmt.Errorf("WithAutoGeneratedName: at most one name prefix can be provided, got %d", len(prefix))
+
+		return spoke
+	}
+
+	name, err := spoke.generateUnusedName(strings.Join(prefix, ""))
+	if err != nil {
+		spoke.err = fmt.Errorf("WithAutoGeneratedName: %w", err)
+
+		return spoke
+	}
+
+	spoke.Name = name
+	spoke.generatedName = true
 
 	return spoke
 }
 
 // WithDefaultNamespace creates a default namespace for the spoke cluster.
 func (spoke *SpokeClusterResources) WithDefaultNamespace() *SpokeClusterResources {
-	spoke.Namespace = namespace.NewBuilder(spoke.apiClient, spoke.Name)
+	if spoke.err != nil {
+		return spoke
+	}
+
+	spoke.Namespace = namespace.NewBuilder(spoke.apiClient, spoke.Name).WithLabel(SpokeOwnershipLabel, spoke.Name)
 
 	return spoke
 }
 
-// WithDefaultPullSecret creates a default pull-secret for the spoke cluster.
+// WithDefaultPullSecret creates a default pull-secret for the spoke cluster, copying the hub pull-secret. When the
+// ZTP configuration has not loaded it, the hub pull-secret is pulled from the location set in the configuration, by
+// default openshift-config/pull-secret.
 func (spoke *SpokeClusterResources) WithDefaultPullSecret() *SpokeClusterResources {
-	spoke.PullSecret = secret.NewBuilder(
-		spoke.apiClient,
-		fmt.Sprintf("%s-pull-secret", spoke.Name),
-		spoke.Name,
-		corev1.SecretTypeDockerConfigJson).WithData(ZTPConfig.HubPullSecret.Object.Data)
+	if spoke.err != nil {
+		return spoke
+	}
+
+	config := hubConfig()
+	pullSecretNamespace, pullSecretName := config.HubPullSecretLocation()
+	hubPullSecret := config.HubPullSecret
+
+	if hubPullSecret == nil || hubPullSecret.Object == nil {
+		var err error
+
+		hubPullSecret, err = ztpconfig.PullHubPullSecret(spoke.apiClient, pullSecretNamespace, pullSecretName)
+		if err != nil {
+			spoke.err = fmt.Errorf("WithDefaultPullSecret: %w", err)
+
+			return spoke
+		}
+	}
+
+	if len(hubPullSecret.Object.Data[corev1.DockerConfigJsonKey]) == 0 {
+		spoke.err = fmt.Errorf("WithDefaultPullSecret: hub pull-secret %s/%s has no %s key",
+			pullSecretNamespace, pullSecretName, corev1.DockerConfigJsonKey)
+
+		return spoke
+	}
+
+	spoke.PullSecret = spoke.newPullSecret(hubPullSecret.Object.Data)
+
+	return spoke
+}
+
+// WithPullSecretData creates the spoke pull-secret with the provided data instead of copying the hub pull-secret.
+// The data is not validated so that tests can exercise the assisted service pull-secret validations, but it
+// cannot be empty.
+func (spoke *SpokeClusterResources) WithPullSecretData(data map[string][]byte) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if len(data) == 0 {
+		spoke.err = fmt.Errorf("WithPullSecretData: pull-secret data cannot be empty")
+
+		return spoke
+	}
+
+	spoke.PullSecret = spoke.newPullSecret(data)
+	spoke.customPullSecret = true
 
 	return spoke
 }
 
-// WithDefaultClusterDeployment creates a default clusterdeployment for the spoke cluster.
+// WithPullSecretFromFile creates the spoke pull-secret with the docker config read from path, which is not
+// validated, instead of copying the hub pull-secret.
+func (spoke *SpokeClusterResources) WithPullSecretFromFile(path string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	dockerConfig, err := os.ReadFile(path)
+	if err != nil {
+		spoke.err = fmt.Errorf("WithPullSecretFromFile: failed to read pull-secret file: %w", err)
+
+		return spoke
+	}
+
+	if len(dockerConfig) == 0 {
+		spoke.err = fmt.Errorf("WithPullSecretFromFile: pull-secret file %s is empty", path)
+
+		return spoke
+	}
+
+	return spoke.WithPullSecretData(map[string][]byte{corev1.DockerConfigJsonKey: dockerConfig})
+}
+
+// WithDefaultClusterDeployment creates a default clusterdeployment for the spoke cluster. The base domain is
+// ZTPConfig.SpokeBaseDomain when set, otherwise assisted.test.com. The agent selector is the one set using
+// WithAgentSelector, otherwise ZTPConfig.SpokeAgentSelector when set, otherwise a selector matching no agent so that
+// agents are only bound through the infraenv.
 func (spoke *SpokeClusterResources) WithDefaultClusterDeployment() *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	agentSelector := metav1.LabelSelector{MatchLabels: map[string]string{"dummy": "label"}}
+
+	switch {
+	case spoke.agentSelector != nil:
+		agentSelector = *spoke.agentSelector.DeepCopy()
+	case spokeConfig().SpokeAgentSelector != "":
+		configuredSelector, err := metav1.ParseToLabelSelector(spokeConfig().SpokeAgentSelector)
+		if err != nil {
+			spoke.err = fmt.Errorf("WithDefaultClusterDeployment: invalid configured agent selector %q: %w",
+				spokeConfig().SpokeAgentSelector, err)
+
+			return spoke
+		}
+
+		agentSelector = *configuredSelector
+	}
+
 	spoke.ClusterDeployment = hive.NewABMClusterDeploymentBuilder(
 		spoke.apiClient,
 		spoke.Name,
 		spoke.Name,
 		spoke.Name,
-		"assisted.test.com",
+		defaultBaseDomain,
 		spoke.Name,
-		metav1.LabelSelector{
-			MatchLabels: map[string]string{
-				"dummy": "label",
-			},
-		}).WithPullSecret(fmt.Sprintf("%s-pull-secret", spoke.Name))
+		agentSelector).WithPullSecret(fmt.Sprintf("%s-pull-secret", spoke.Name))
+
+	if spokeConfig().SpokeBaseDomain != "" {
+		return spoke.WithBaseDomain(spokeConfig().SpokeBaseDomain)
+	}
+
+	return spoke
+}
+
+// WithAgentSelector sets the agent selector of the spoke clusterdeployment, used by the assisted-service to bind
+// matching agents to the spoke. It can be called before WithDefaultClusterDeployment, which then uses it instead of
+// the default selector.
+func (spoke *SpokeClusterResources) WithAgentSelector(selector metav1.LabelSelector) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if _, err := metav1.LabelSelectorAsSelector(&selector); err != nil {
+		spoke.err = fmt.Errorf("WithAgentSelector: invalid agent selector: %w", err)
+
+		return spoke
+	}
+
+	spoke.agentSelector = selector.DeepCopy()
+
+	if spoke.ClusterDeployment != nil && spoke.ClusterDeployment.Definition.Spec.Platform.AgentBareMetal != nil {
+		spoke.ClusterDeployment.Definition.Spec.Platform.AgentBareMetal.AgentSelector = *selector.DeepCopy()
+	}
+
+	return spoke
+}
+
+// WithBaseDomain sets the base domain of the spoke clusterdeployment, so the spoke API is served at
+// api.<name>.<domain>.
+func (spoke *SpokeClusterResources) WithBaseDomain(domain string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.ClusterDeployment == nil {
+		spoke.err = fmt.Errorf("WithBaseDomain: clusterdeployment must be defined before setting the base domain")
+
+		return spoke
+	}
+
+	if errs := validation.IsDNS1123Subdomain(domain); len(errs) > 0 {
+		spoke.err = fmt.Errorf("WithBaseDomain: invalid base domain %q: %s", domain, strings.Join(errs, ", "))
+
+		return spoke
+	}
+
+	spoke.ClusterDeployment.Definition.Spec.BaseDomain = domain
 
 	return spoke
 }
 
 // WithDefaultIPv4AgentClusterInstall creates a default agentclusterinstall with IPv4 networking for the spoke cluster.
 func (spoke *SpokeClusterResources) WithDefaultIPv4AgentClusterInstall() *SpokeClusterResources {
-	spoke.AgentClusterInstall = assisted.NewAgentClusterInstallBuilder(
-		spoke.apiClient,
-		spoke.Name,
-		spoke.Name,
-		spoke.Name,
-		3,
-		2,
-		v1beta1.Networking{
-			ClusterNetwork: []v1beta1.ClusterNetworkEntry{{
-				CIDR:       "10.128.0.0/14",
-				HostPrefix: 23,
-			}},
-			ServiceNetwork: []string{"172.30.0.0/16"},
-		}).WithImageSet(ZTPConfig.HubOCPXYVersion).WithAPIVip("192.168.254.5").WithIngressVip("192.168.254.10")
+	if spoke.err != nil {
+		return spoke
+	}
+
+	spoke.AgentClusterInstall = spoke.newAgentClusterInstall(
+		defaultControlPlaneAgents, defaultWorkerAgents, defaultIPv4Networking()).
+		WithAPIVip(configuredOrDefault(spokeConfig().SpokeAPIVIP, defaultIPv4APIVIP)).
+		WithIngressVip(configuredOrDefault(spokeConfig().SpokeIngressVIP, defaultIPv4IngressVIP))
 
 	return spoke
 }
 
 // WithDefaultIPv6AgentClusterInstall creates a default agentclusterinstall with IPv6 networking for the spoke cluster.
 func (spoke *SpokeClusterResources) WithDefaultIPv6AgentClusterInstall() *SpokeClusterResources {
-	spoke.AgentClusterInstall = assisted.NewAgentClusterInstallBuilder(
-		spoke.apiClient,
-		spoke.Name,
-		spoke.Name,
-		spoke.Name,
-		3,
-		2,
-		v1beta1.Networking{
-			ClusterNetwork: []v1beta1.ClusterNetworkEntry{{
-				CIDR:       "fd01::/48",
-				HostPrefix: 64,
-			}},
-			ServiceNetwork: []string{"fd02::/112"},
-		}).WithImageSet(ZTPConfig.HubOCPXYVersion).WithAPIVip("fd2e:6f44:5dd8:1::5").WithIngressVip("fd2e:6f44:5dd8:1::10")
+	if spoke.err != nil {
+		return spoke
+	}
+
+	spoke.AgentClusterInstall = spoke.newAgentClusterInstall(
+		defaultControlPlaneAgents, defaultWorkerAgents, defaultIPv6Networking()).
+		WithAPIVip(configuredOrDefault(spokeConfig().SpokeIPv6APIVIP, defaultIPv6APIVIP)).
+		WithIngressVip(configuredOrDefault(spokeConfig().SpokeIPv6IngressVIP, defaultIPv6IngressVIP))
 
 	return spoke
 }
 
 // WithDefaultDualStackAgentClusterInstall creates a default agentclusterinstall
-// with dual-stack networking for the spoke cluster.
+// with dual-stack networking for the spoke cluster. The api and ingress vips contain one address of each family.
 func (spoke *SpokeClusterResources) WithDefaultDualStackAgentClusterInstall() *SpokeClusterResources {
-	spoke.AgentClusterInstall = assisted.NewAgentClusterInstallBuilder(
-		spoke.apiClient,
-		spoke.Name,
-		spoke.Name,
-		spoke.Name,
-		3,
-		2,
-		v1beta1.Networking{
-			ClusterNetwork: []v1beta1.ClusterNetworkEntry{
-				{
-					CIDR:       "10.128.0.0/14",
-					HostPrefix: 23,
-				},
-				{
-					CIDR:       "fd01::/48",
-					HostPrefix: 64,
-				},
-			},
-			ServiceNetwork: []string{"172.30.0.0/16", "fd02::/112"},
-		}).WithImageSet(ZTPConfig.HubOCPXYVersion).WithAPIVip("192.168.254.5").WithIngressVip("192.168.254.10")
+	if spoke.err != nil {
+		return spoke
+	}
+
+	spoke.AgentClusterInstall = spoke.newAgentClusterInstall(
+		defaultControlPlaneAgents, defaultWorkerAgents, defaultDualStackNetworking())
+
+	spoke.WithVIPs(
+		[]string{
+			configuredOrDefault(spokeConfig().SpokeAPIVIP, defaultIPv4APIVIP),
+			configuredOrDefault(spokeConfig().SpokeIPv6APIVIP, defaultIPv6APIVIP),
+		},
+		[]string{
+			configuredOrDefault(spokeConfig().SpokeIngressVIP, defaultIPv4IngressVIP),
+			configuredOrDefault(spokeConfig().SpokeIPv6IngressVIP, defaultIPv6IngressVIP),
+		})
+	spoke.explicitVIPs = false
+
+	return spoke
+}
+
+// WithDefaultDualStackIPv6PrimaryAgentClusterInstall creates a default agentclusterinstall with IPv6-primary
+// dual-stack networking for the spoke cluster. The IPv6 cluster, service and machine networks and vips come first,
+// followed by the IPv4 ones.
+func (spoke *SpokeClusterResources) WithDefaultDualStackIPv6PrimaryAgentClusterInstall() *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	spoke.AgentClusterInstall = spoke.newAgentClusterInstall(
+		defaultControlPlaneAgents, defaultWorkerAgents, defaultIPv6PrimaryDualStackNetworking())
+
+	spoke.WithVIPs(
+		[]string{
+			configuredOrDefault(spokeConfig().SpokeIPv6APIVIP, defaultIPv6APIVIP),
+			configuredOrDefault(spokeConfig().SpokeAPIVIP, defaultIPv4APIVIP),
+		},
+		[]string{
+			configuredOrDefault(spokeConfig().SpokeIPv6IngressVIP, defaultIPv6IngressVIP),
+			configuredOrDefault(spokeConfig().SpokeIngressVIP, defaultIPv4IngressVIP),
+		})
+	spoke.explicitVIPs = false
+
+	return spoke
+}
+
+// WithDefaultSNOAgentClusterInstall creates a default single-node agentclusterinstall with IPv4 networking for the
+// spoke cluster. It has 1 control-plane agent, 0 workers and a /24 machine network. User-managed networking is
+// enabled and no VIPs are set since the API and ingress use the node IP.
+func (spoke *SpokeClusterResources) WithDefaultSNOAgentClusterInstall() *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	spoke.AgentClusterInstall = spoke.newAgentClusterInstall(
+		snoControlPlaneAgents, snoWorkerAgents, defaultIPv4Networking())
+
+	return spoke.WithUserManagedNetworking(true)
+}
+
+// WithAgentCounts overrides the control-plane and worker agent counts of the spoke agentclusterinstall, allowing
+// compact and expanded topologies to reuse the default agentclusterinstall builders. The control-plane count must
+// be 1 or 3 and the worker count cannot be negative.
+func (spoke *SpokeClusterResources) WithAgentCounts(controlPlane, workers int) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.AgentClusterInstall == nil {
+		spoke.err = fmt.Errorf("WithAgentCounts: agentclusterinstall must be defined before setting agent counts")
+
+		return spoke
+	}
+
+	if err := validateAgentCounts(controlPlane, workers); err != nil {
+		spoke.err = fmt.Errorf("WithAgentCounts: %w", err)
+
+		return spoke
+	}
+
+	spoke.AgentClusterInstall.WithControlPlaneAgents(controlPlane).WithWorkerAgents(workers)
+
+	return spoke
+}
+
+// WithAgentClusterInstall creates an agentclusterinstall for the spoke cluster with the provided agent counts and
+// networking, for topologies and subnets the default agentclusterinstall builders do not cover. The networking needs
+// at least one cluster and one service network. Single-node spokes get user-managed networking, as with
+// WithDefaultSNOAgentClusterInstall; no vips are set on multi-node spokes, which need WithVIPs or
+// WithUserManagedNetworking.
+func (spoke *SpokeClusterResources) WithAgentClusterInstall(
+	masters, workers int, networking v1beta1.Networking) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if err := validateAgentCounts(masters, workers); err != nil {
+		spoke.err = fmt.Errorf("WithAgentClusterInstall: %w", err)
+
+		return spoke
+	}
+
+	if len(networking.ClusterNetwork) == 0 || len(networking.ServiceNetwork) == 0 {
+		spoke.err = fmt.Errorf("WithAgentClusterInstall: at least one cluster and one service network are required")
+
+		return spoke
+	}
+
+	spoke.AgentClusterInstall = spoke.newAgentClusterInstall(masters, workers, networking)
+
+	if err := spoke.validateNetworkFamilies(); err != nil {
+		spoke.err = fmt.Errorf("WithAgentClusterInstall: %w", err)
+
+		return spoke
+	}
+
+	if masters == snoControlPlaneAgents && workers == snoWorkerAgents {
+		return spoke.WithUserManagedNetworking(true)
+	}
 
 	return spoke
 }
 
 // WithDefaultInfraEnv creates a default infraenv for the spoke cluster.
 func (spoke *SpokeClusterResources) WithDefaultInfraEnv() *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
 	spoke.InfraEnv = assisted.NewInfraEnvBuilder(
 		spoke.apiClient,
 		spoke.Name,
@@ -166,64 +506,718 @@ func (spoke *SpokeClusterResources) WithDefaultInfraEnv() *SpokeClusterResources
 	return spoke
 }
 
-// Create creates the instantiated spoke cluster resources.
-func (spoke *SpokeClusterResources) Create() (*SpokeClusterResources, error) {
-	if spoke.Namespace != nil && spoke.err == nil {
-		spoke.Namespace, spoke.err = spoke.Namespace.Create()
+// WithSSHPublicKey sets the ssh public key authorized on the discovery image of the spoke infraenvs, allowing
+// hosts to be debugged during discovery.
+func (spoke *SpokeClusterResources) WithSSHPublicKey(key string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.InfraEnv == nil {
+		spoke.err = fmt.Errorf("WithSSHPublicKey: infraenv must be defined before setting the ssh public key")
+
+		return spoke
+	}
+
+	key = strings.TrimSpace(key)
+	if len(strings.Fields(key)) < 2 {
+		spoke.err = fmt.Errorf("WithSSHPublicKey: ssh public key must be in authorized_keys format")
+
+		return spoke
+	}
+
+	spoke.InfraEnv.WithSSHAuthorizedKey(key)
+
+	for _, infraEnv := range spoke.AdditionalInfraEnvs {
+		infraEnv.WithSSHAuthorizedKey(key)
+	}
+
+	return spoke
+}
+
+// WithSSHPublicKeyFromFile sets the ssh public key authorized on the discovery image of the spoke infraenvs to the
+// key read from path, such as ~/.ssh/id_ed25519.pub.
+func (spoke *SpokeClusterResources) WithSSHPublicKeyFromFile(path string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	key, err := os.ReadFile(path)
+	if err != nil {
+		spoke.err = fmt.Errorf("WithSSHPublicKeyFromFile: failed to read ssh public key file: %w", err)
+
+		return spoke
 	}
 
-	if spoke.PullSecret != nil && spoke.err == nil {
-		spoke.PullSecret, spoke.err = spoke.PullSecret.Create()
+	return spoke.WithSSHPublicKey(string(key))
+}
+
+// WithAdditionalNTPSources appends the NTP sources, hostnames or IP addresses, to the spoke infraenv so that
+// discovery hosts in isolated networks can synchronize their clocks. Sources already present are skipped.
+func (spoke *SpokeClusterResources) WithAdditionalNTPSources(sources ...string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
 	}
 
-	if spoke.ClusterDeployment != nil && spoke.err == nil {
-		spoke.ClusterDeployment, spoke.err = spoke.ClusterDeployment.Create()
+	if spoke.InfraEnv == nil {
+		spoke.err = fmt.Errorf("WithAdditionalNTPSources: infraenv must be defined before adding ntp sources")
+
+		return spoke
 	}
 
-	if spoke.AgentClusterInstall != nil && spoke.err == nil {
-		spoke.AgentClusterInstall, spoke.err = spoke.AgentClusterInstall.Create()
+	if len(sources) == 0 {
+		spoke.err = fmt.Errorf("WithAdditionalNTPSources: at least one ntp source must be provided")
+
+		return spoke
 	}
 
-	if spoke.InfraEnv != nil && spoke.err == nil {
-		spoke.InfraEnv, spoke.err = spoke.InfraEnv.Create()
+	for _, source := range sources {
+		if net.ParseIP(source) == nil && len(validation.IsDNS1123Subdomain(source)) > 0 {
+			spoke.err = fmt.Errorf(
+				"WithAdditionalNTPSources: invalid ntp source %q, must be a hostname or an IP address", source)
+
+			return spoke
+		}
 	}
 
+	for _, source := range sources {
+		if !slices.Contains(spoke.InfraEnv.Definition.Spec.AdditionalNTPSources, source) {
+			spoke.InfraEnv.WithAdditionalNTPSource(source)
+		}
+	}
+
+	return spoke
+}
+
+// WithKernelArguments appends the kernel arguments, each of the form parameter or parameter=value, to the boot of
+// the spoke discovery image. Arguments already appended are skipped.
+func (spoke *SpokeClusterResources) WithKernelArguments(args ...string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.InfraEnv == nil {
+		spoke.err = fmt.Errorf("WithKernelArguments: infraenv must be defined before adding kernel arguments")
+
+		return spoke
+	}
+
+	for _, arg := range args {
+		if strings.TrimSpace(arg) == "" || len(strings.Fields(arg)) > 1 {
+			spoke.err = fmt.Errorf(
+				"WithKernelArguments: invalid kernel argument %q, must be a single non-empty argument", arg)
+
+			return spoke
+		}
+	}
+
+	for _, arg := range args {
+		kernelArg := agentInstallV1Beta1.KernelArgument{Operation: kernelArgumentAppend, Value: arg}
+
+		if !slices.Contains(spoke.InfraEnv.Definition.Spec.KernelArguments, kernelArg) {
+			spoke.InfraEnv.WithKernelArgument(kernelArg)
+		}
+	}
+
+	return spoke
+}
+
+// AdditionalNTPSources returns the additional NTP sources of the spoke infraenv.
+func (spoke *SpokeClusterResources) AdditionalNTPSources() []string {
+	if spoke.InfraEnv == nil {
+		return nil
+	}
+
+	return slices.Clone(spoke.InfraEnv.Definition.Spec.AdditionalNTPSources)
+}
+
+// CreateInterruptedError is returned by CreateWithContext when its context is done before every spoke resource is
+// created. Created lists the kinds of the resources created or adopted so far, in creation order. Step is the step in
+// flight when the context was done, such as "create agentclusterinstall", empty when it was done between steps.
+type CreateInterruptedError struct {
+	Spoke   string
+	Step    string
+	Created []string
+	Err     error
+}
+
+// Error returns the interruption along with the step in flight and the resources created before it.
+func (interrupted *CreateInterruptedError) Error() string {
+	created := "no resources"
+	if len(interrupted.Created) > 0 {
+		created = strings.Join(interrupted.Created, ", ")
+	}
+
+	step := ""
+	if interrupted.Step != "" {
+		step = " during " + interrupted.Step
+	}
+
+	return fmt.Sprintf("creation of spoke %s interrupted%s after creating %s: %v", interrupted.Spoke, step, created,
+		interrupted.Err)
+}
+
+// Unwrap returns the error of the context that interrupted the creation.
+func (interrupted *CreateInterruptedError) Unwrap() error {
+	return interrupted.Err
+}
+
+// Create creates the instantiated spoke cluster resources. Resources that already exist, such as those left by an
+// earlier run, are adopted instead of created, so Create can be rerun after a partial failure. The clusterdeployment,
+// agentclusterinstall and infraenvs are created concurrently once the namespaces, secrets and configmaps they
+// reference exist, and the errors of their creation are joined. When a step fails, the resources created by the call
+// are deleted in the reverse order of their creation, adopted ones being left in place, and the error of the step is
+// returned joined with the errors of their deletion. An error recorded while building the spoke is returned without
+// calling the API.
+func (spoke *SpokeClusterResources) Create() (*SpokeClusterResources, error) {
+	return spoke.CreateWithContext(context.Background())
+}
+
+// CreateWithContext creates the instantiated spoke cluster resources like Create, checking ctx before each resource
+// and stopping the waits it performs when ctx is done. When ctx is done before every resource is created, the
+// returned CreateInterruptedError wraps the error of ctx and lists the resources created so far; they are not rolled
+// back and can be removed using Delete.
+func (spoke *SpokeClusterResources) CreateWithContext(ctx context.Context) (*SpokeClusterResources, error) {
+	if spoke.err != nil {
+		return spoke, spoke.err
+	}
+
+	start := time.Now()
+	spoke.createdResources = nil
+	spoke.rollbackSteps = nil
+	spoke.interruptedStep = ""
+
+	spoke.err = spoke.prepareCreate(ctx)
+
+	for _, createStage := range []func(context.Context) error{
+		spoke.createNamespaceResources,
+		spoke.createConfigResources,
+		spoke.createInstallResources,
+		spoke.createManagedClusterResources,
+		spoke.createHostResources,
+	} {
+		if spoke.err == nil {
+			spoke.err = createStage(ctx)
+		}
+	}
+
+	spoke.err = spoke.finishCreate(ctx, spoke.err)
+	spoke.recordPhase("create", start, 0, spoke.err)
+
 	return spoke, spoke.err
 }
 
-// Delete removes all instantiated spoke cluster resources.
+// prepareCreate validates the spoke and applies the settings resolved at creation to its definitions, then waits
+// for a free spoke slot.
+func (spoke *SpokeClusterResources) prepareCreate(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := spoke.Validate(); err != nil {
+		return err
+	}
+
+	if err := spoke.applyComputePools(); err != nil {
+		return err
+	}
+
+	if spoke.diskEncryption != nil && spoke.AgentClusterInstall == nil {
+		return fmt.Errorf("disk encryption requires an agentclusterinstall")
+	}
+
+	spoke.applyDiskEncryption()
+	spoke.applyMetadata()
+
+	err := spoke.waitForSpokeSlot(ctx)
+	if ctx.Err() != nil {
+		spoke.recordInterruptedStep("wait for spoke slot")
+	}
+
+	return err
+}
+
+// createNamespaceResources creates the spoke and infraenv namespaces along with their pull-secrets.
+func (spoke *SpokeClusterResources) createNamespaceResources(ctx context.Context) error {
+	if spoke.Namespace != nil {
+		if err := spoke.verifyGeneratedNamespace(); err != nil {
+			return err
+		}
+
+		err := spoke.createOrAdopt(ctx, "namespace", spoke.Namespace, func() (err error) {
+			spoke.Namespace, err = spoke.Namespace.Create()
+			if err == nil {
+				spoke.generatedName = false
+			}
+
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if spoke.PullSecret != nil {
+		err := spoke.createOrAdopt(ctx, "pull-secret", spoke.PullSecret, func() (err error) {
+			spoke.PullSecret, err = spoke.PullSecret.Create()
+
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if spoke.InfraEnvNamespace != nil {
+		err := spoke.createOrAdopt(ctx, "infraenv namespace", spoke.InfraEnvNamespace, func() (err error) {
+			spoke.InfraEnvNamespace, err = spoke.InfraEnvNamespace.Create()
+
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if spoke.InfraEnvPullSecret != nil {
+		return spoke.createOrAdopt(ctx, "infraenv pull-secret", spoke.InfraEnvPullSecret, func() (err error) {
+			spoke.InfraEnvPullSecret, err = spoke.InfraEnvPullSecret.Create()
+
+			return err
+		})
+	}
+
+	return nil
+}
+
+// createConfigResources creates the configmaps, clusterimageset and nmstateconfigs referenced by the install
+// resources.
+func (spoke *SpokeClusterResources) createConfigResources(ctx context.Context) error {
+	if spoke.MirrorRegistryConfigMap != nil {
+		err := spoke.createOrAdopt(ctx, "mirror registry configmap", spoke.MirrorRegistryConfigMap,
+			func() (err error) {
+				spoke.MirrorRegistryConfigMap, err = spoke.MirrorRegistryConfigMap.Create()
+
+				return err
+			})
+		if err != nil {
+			return err
+		}
+	}
+
+	for index := range spoke.ExtraManifests {
+		err := spoke.createOrAdopt(ctx, "extra manifests", spoke.ExtraManifests[index], func() (err error) {
+			spoke.ExtraManifests[index], err = spoke.ExtraManifests[index].Create()
+
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if spoke.ClusterImageSet != nil {
+		if err := spoke.createClusterImageSet(ctx); err != nil {
+			return err
+		}
+	}
+
+	spoke.applyNMStateConfigSelector()
+
+	for index := range spoke.NMStateConfigs {
+		err := spoke.createOrAdopt(ctx, "nmstateconfig", spoke.NMStateConfigs[index], func() (err error) {
+			spoke.NMStateConfigs[index], err = spoke.NMStateConfigs[index].Create()
+
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createManagedClusterResources creates the managedcluster and klusterletaddonconfig importing the spoke.
+func (spoke *SpokeClusterResources) createManagedClusterResources(ctx context.Context) error {
+	if spoke.ManagedCluster != nil {
+		err := spoke.createOrAdopt(ctx, "managedcluster", spoke.ManagedCluster, func() (err error) {
+			spoke.ManagedCluster, err = spoke.ManagedCluster.Create()
+
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if spoke.KlusterletAddonConfig != nil {
+		return spoke.createOrAdopt(ctx, "klusterletaddonconfig", spoke.KlusterletAddonConfig, func() (err error) {
+			spoke.KlusterletAddonConfig, err = spoke.KlusterletAddonConfig.Create()
+
+			return err
+		})
+	}
+
+	return nil
+}
+
+// createHostResources creates the bmc secrets and baremetalhosts of the spoke hosts.
+func (spoke *SpokeClusterResources) createHostResources(ctx context.Context) error {
+	for index := range spoke.BMCSecrets {
+		err := spoke.createOrAdopt(ctx, "bmc secret", spoke.BMCSecrets[index], func() (err error) {
+			spoke.BMCSecrets[index], err = spoke.BMCSecrets[index].Create()
+
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for index := range spoke.BareMetalHosts {
+		err := spoke.createOrAdopt(ctx, "baremetalhost", spoke.BareMetalHosts[index], func() (err error) {
+			spoke.BareMetalHosts[index], err = spoke.BareMetalHosts[index].Create()
+
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// finishCreate returns the error of CreateWithContext for createErr: the resources created by the call are rolled
+// back when a step failed, and a CreateInterruptedError is returned when ctx is done.
+func (spoke *SpokeClusterResources) finishCreate(ctx context.Context, createErr error) error {
+	if createErr == nil {
+		return nil
+	}
+
+	if ctx.Err() == nil {
+		return spoke.rollbackCreated(ctx, createErr)
+	}
+
+	if !errors.Is(createErr, ctx.Err()) {
+		return createErr
+	}
+
+	return &CreateInterruptedError{
+		Spoke:   spoke.Name,
+		Step:    spoke.interruptedStep,
+		Created: slices.Clone(spoke.createdResources),
+		Err:     ctx.Err(),
+	}
+}
+
+// Delete removes all instantiated spoke cluster resources. Resources with finalizers, such as the agentclusterinstall
+// deprovision finalizer, are waited on until they are removed, up to the delete timeout of the wait options for each,
+// before deleting the resources they depend on. Every deletion is attempted even when an earlier one fails and the
+// returned error joins the failures, each naming the resource that could not be deleted and the finalizers left on
+// resources that were not removed in time. Resources that are already gone are not failures.
 func (spoke *SpokeClusterResources) Delete() error {
+	return spoke.DeleteWithContext(context.Background())
+}
+
+// DeleteWithContext removes the instantiated spoke cluster resources like Delete, stopping the waits it performs
+// when ctx is done. Once ctx is done, the remaining resources are left in place and the returned error wraps the
+// error of ctx, naming the deletion in flight when it was done, along with the failures seen until then.
+func (spoke *SpokeClusterResources) DeleteWithContext(ctx context.Context) error {
+	start := time.Now()
+	deletion := &spokeDeletion{spoke: spoke, ctx: ctx}
+
+	spoke.deleteHostResources(deletion)
+	spoke.deleteInstallResources(deletion)
+	spoke.deleteConfigResources(deletion)
+	spoke.deleteNamespaceResources(deletion)
+
+	spoke.err = deletion.err()
+	spoke.recordPhase("delete", start, 0, spoke.err)
+
+	return spoke.err
+}
+
+// spokeDeletion records the failures of DeleteWithContext and the deletion in flight when its context was done.
+type spokeDeletion struct {
+	spoke           *SpokeClusterResources
+	ctx             context.Context
+	errs            []error
+	interruptedStep string
+}
+
+// recordFailure records the failure to delete the resource of kind named name with err, resources already gone and
+// failures once the context is done not being failures.
+func (deletion *spokeDeletion) recordFailure(kind, name string, err error) {
+	if deletion.ctx.Err() != nil && deletion.interruptedStep == "" {
+		deletion.interruptedStep = fmt.Sprintf("delete %s %s", kind, name)
+	}
+
+	if err != nil && !k8serrors.IsNotFound(err) && deletion.ctx.Err() == nil {
+		deletion.errs = append(deletion.errs, fmt.Errorf("failed to delete %s %s: %w", kind, name, err))
+	}
+}
+
+// deleteResource deletes the resource of kind named name using deleteFunc, unless the context is done.
+func (deletion *spokeDeletion) deleteResource(kind, name string, deleteFunc func() error) {
+	if deletion.ctx.Err() != nil {
+		return
+	}
+
+	deleteStart := time.Now()
+	err := deletion.spoke.retryTransient(deletion.ctx, "delete "+kind, deleteFunc)
+
+	deletion.spoke.recordPhase("delete "+kind, deleteStart, 0, runtimeClient.IgnoreNotFound(err))
+	deletion.recordFailure(kind, name, err)
+}
+
+// deleteResourceAndWait deletes the resource of kind using deleteFunc and waits until object is removed, unless the
+// context is done.
+func (deletion *spokeDeletion) deleteResourceAndWait(
+	kind string, object runtimeClient.Object, deleteFunc func() error) {
+	if deletion.ctx.Err() != nil {
+		return
+	}
+
+	deleteStart := time.Now()
+
+	err := deletion.spoke.retryTransient(deletion.ctx, "delete "+kind, deleteFunc)
+	if err == nil {
+		err = deletion.spoke.waitForDeletion(deletion.ctx, kind, object)
+	}
+
+	deletion.spoke.recordPhase("delete "+kind, deleteStart, 0, runtimeClient.IgnoreNotFound(err))
+	deletion.recordFailure(kind, object.GetName(), err)
+}
+
+// err returns the recorded failures joined, along with the interruption when the context is done.
+func (deletion *spokeDeletion) err() error {
+	if err := deletion.ctx.Err(); err != nil && deletion.interruptedStep != "" {
+		deletion.errs = append(deletion.errs, fmt.Errorf("deletion of spoke %s interrupted during %s: %w",
+			deletion.spoke.Name, deletion.interruptedStep, err))
+	} else if err != nil {
+		deletion.errs = append(deletion.errs,
+			fmt.Errorf("deletion of spoke %s interrupted: %w", deletion.spoke.Name, err))
+	}
+
+	return errors.Join(deletion.errs...)
+}
+
+// deleteHostResources deletes the klusterletaddonconfig, managedcluster, baremetalhosts and bmc secrets of the spoke.
+func (spoke *SpokeClusterResources) deleteHostResources(deletion *spokeDeletion) {
+	if spoke.KlusterletAddonConfig != nil {
+		deletion.deleteResource("klusterletaddonconfig",
+			spoke.KlusterletAddonConfig.Definition.Name, spoke.KlusterletAddonConfig.Delete)
+	}
+
+	if spoke.ManagedCluster != nil {
+		deletion.deleteResourceAndWait(
+			"managedcluster", spoke.ManagedCluster.Definition.DeepCopy(), spoke.ManagedCluster.Delete)
+	}
+
+	for _, bareMetalHost := range spoke.BareMetalHosts {
+		deletion.deleteResourceAndWait("baremetalhost", bareMetalHost.Definition.DeepCopy(), func() error {
+			_, err := bareMetalHost.Delete()
+
+			return err
+		})
+	}
+
+	for _, bmcSecret := range spoke.BMCSecrets {
+		deletion.deleteResource("bmc secret", bmcSecret.Definition.Name, bmcSecret.Delete)
+	}
+}
+
+// deleteInstallResources deletes the infraenvs, nmstateconfigs, agentclusterinstall, clusterdeployment and, when
+// owned by the spoke and no longer in use, the clusterimageset.
+func (spoke *SpokeClusterResources) deleteInstallResources(deletion *spokeDeletion) {
+	for _, infraEnv := range spoke.AdditionalInfraEnvs {
+		deletion.deleteResourceAndWait("infraenv", infraEnv.Definition.DeepCopy(), infraEnv.Delete)
+	}
+
 	if spoke.InfraEnv != nil {
-		spoke.err = spoke.InfraEnv.Delete()
+		deletion.deleteResourceAndWait("infraenv", spoke.InfraEnv.Definition.DeepCopy(), spoke.InfraEnv.Delete)
+	}
+
+	for _, nmStateConfig := range spoke.NMStateConfigs {
+		deletion.deleteResource("nmstateconfig", nmStateConfig.Definition.Name, nmStateConfig.Delete)
 	}
 
 	if spoke.AgentClusterInstall != nil {
-		spoke.err = spoke.AgentClusterInstall.Delete()
+		deletion.deleteResourceAndWait(
+			"agentclusterinstall", spoke.AgentClusterInstall.Definition.DeepCopy(), spoke.AgentClusterInstall.Delete)
 	}
 
 	if spoke.ClusterDeployment != nil {
-		spoke.err = spoke.ClusterDeployment.Delete()
+		deletion.deleteResourceAndWait(
+			"clusterdeployment", spoke.ClusterDeployment.Definition.DeepCopy(), spoke.ClusterDeployment.Delete)
+	}
+
+	if spoke.ClusterImageSet != nil && spoke.ownsClusterImageSet && deletion.ctx.Err() == nil {
+		inUse, err := spoke.clusterImageSetInUse(deletion.ctx)
+		if err == nil && !inUse {
+			deletion.deleteResource(
+				"clusterimageset", spoke.ClusterImageSet.Definition.Name, spoke.ClusterImageSet.Delete)
+		}
+
+		deletion.recordFailure("clusterimageset", spoke.ClusterImageSet.Definition.Name, err)
+	}
+}
+
+// deleteConfigResources deletes the extra manifests and mirror registry configmaps, and the pull-secrets.
+func (spoke *SpokeClusterResources) deleteConfigResources(deletion *spokeDeletion) {
+	for _, extraManifest := range spoke.ExtraManifests {
+		deletion.deleteResource("extra manifests configmap", extraManifest.Definition.Name, extraManifest.Delete)
+	}
+
+	if spoke.MirrorRegistryConfigMap != nil {
+		deletion.deleteResource("mirror registry configmap",
+			spoke.MirrorRegistryConfigMap.Definition.Name, spoke.MirrorRegistryConfigMap.Delete)
 	}
 
 	if spoke.PullSecret != nil {
-		spoke.err = spoke.PullSecret.Delete()
+		deletion.deleteResource("pull-secret", spoke.PullSecret.Definition.Name, spoke.PullSecret.Delete)
 	}
 
-	if spoke.Namespace != nil {
-		spoke.err = spoke.Namespace.DeleteAndWait(time.Second * 120)
+	if spoke.InfraEnvPullSecret != nil {
+		deletion.deleteResource("pull-secret",
+			spoke.InfraEnvPullSecret.Definition.Name, spoke.InfraEnvPullSecret.Delete)
 	}
+}
 
-	return spoke.err
+// deleteNamespaceResources deletes the infraenv namespace and the spoke namespace, waiting until they are removed.
+func (spoke *SpokeClusterResources) deleteNamespaceResources(deletion *spokeDeletion) {
+	if spoke.InfraEnvNamespace != nil && deletion.ctx.Err() == nil {
+		deletion.recordFailure("namespace",
+			spoke.InfraEnvNamespace.Definition.Name, spoke.deleteInfraEnvNamespace(deletion.ctx))
+	}
+
+	if spoke.Namespace != nil && deletion.ctx.Err() == nil {
+		deletion.recordFailure("namespace",
+			spoke.Namespace.Definition.Name, spoke.deleteNamespaceAndWait(deletion.ctx, spoke.Namespace))
+	}
+}
+
+// deleteNamespaceAndWait deletes the namespace and waits until it is removed.
+func (spoke *SpokeClusterResources) deleteNamespaceAndWait(
+	ctx context.Context, nsBuilder *namespace.Builder) (err error) {
+	start := time.Now()
+
+	defer func() {
+		spoke.recordPhase("delete namespace", start, 0, runtimeClient.IgnoreNotFound(err))
+	}()
+
+	err = spoke.retryTransient(ctx, "delete namespace", nsBuilder.Delete)
+	if err != nil {
+		return err
+	}
+
+	return spoke.waitForDeletion(ctx, "namespace", nsBuilder.Definition.DeepCopy())
+}
+
+// newPullSecret returns the spoke pull-secret builder with the provided data, named so that the clusterdeployment
+// and infraenv defaults reference it.
+func (spoke *SpokeClusterResources) newPullSecret(data map[string][]byte) *secret.Builder {
+	return secret.NewBuilder(
+		spoke.apiClient,
+		fmt.Sprintf("%s-pull-secret", spoke.Name),
+		spoke.Name,
+		corev1.SecretTypeDockerConfigJson).WithData(data)
+}
+
+// validateAgentCounts checks that the control-plane agent count is 1 or 3 and the worker count is not negative.
+func validateAgentCounts(controlPlane, workers int) error {
+	if controlPlane != snoControlPlaneAgents && controlPlane != defaultControlPlaneAgents {
+		return fmt.Errorf("control-plane agent count must be %d or %d, got %d",
+			snoControlPlaneAgents, defaultControlPlaneAgents, controlPlane)
+	}
+
+	if workers < 0 {
+		return fmt.Errorf("worker agent count cannot be negative, got %d", workers)
+	}
+
+	return nil
 }
 
-// generateName generates a random string matching the length supplied.
-func generateName(n int) string {
-	var letterRunes = []rune("abcdefghijklmnopqrstuvwxyz")
+// newAgentClusterInstall returns an agentclusterinstall builder for the spoke cluster with the provided
+// agent counts and networking, using the hub's OCP version as the image set. The vips set by WithVIPs on the
+// agentclusterinstall it replaces are forgotten.
+func (spoke *SpokeClusterResources) newAgentClusterInstall(
+	controlPlaneAgents, workerAgents int, networking v1beta1.Networking) *assisted.AgentClusterInstallBuilder {
+	spoke.explicitVIPs = false
+
+	return assisted.NewAgentClusterInstallBuilder(
+		spoke.apiClient,
+		spoke.Name,
+		spoke.Name,
+		spoke.Name,
+		controlPlaneAgents,
+		workerAgents,
+		networking).WithImageSet(hubConfig().HubOCPXYVersion)
+}
+
+// defaultIPv4Networking returns the default IPv4 cluster and service networks, and the machine network when
+// configured. Networks set in ZTPConfig take precedence over the defaults.
+func defaultIPv4Networking() v1beta1.Networking {
+	networking := v1beta1.Networking{
+		ClusterNetwork: []v1beta1.ClusterNetworkEntry{{
+			CIDR:       configuredOrDefault(spokeConfig().SpokeClusterCIDR, defaultIPv4ClusterCIDR),
+			HostPrefix: defaultIPv4HostPrefix,
+		}},
+		ServiceNetwork: []string{configuredOrDefault(spokeConfig().SpokeServiceCIDR, defaultIPv4ServiceCIDR)},
+	}
+
+	if spokeConfig().SpokeMachineCIDR != "" {
+		networking.MachineNetwork = []v1beta1.MachineNetworkEntry{{CIDR: spokeConfig().SpokeMachineCIDR}}
+	}
+
+	return networking
+}
+
+// defaultIPv6Networking returns the default IPv6 cluster and service networks, and the machine network when
+// configured. Networks set in ZTPConfig take precedence over the defaults.
+func defaultIPv6Networking() v1beta1.Networking {
+	networking := v1beta1.Networking{
+		ClusterNetwork: []v1beta1.ClusterNetworkEntry{{
+			CIDR:       configuredOrDefault(spokeConfig().SpokeIPv6ClusterCIDR, defaultIPv6ClusterCIDR),
+			HostPrefix: defaultIPv6HostPrefix,
+		}},
+		ServiceNetwork: []string{configuredOrDefault(spokeConfig().SpokeIPv6ServiceCIDR, defaultIPv6ServiceCIDR)},
+	}
+
+	if spokeConfig().SpokeIPv6MachineCIDR != "" {
+		networking.MachineNetwork = []v1beta1.MachineNetworkEntry{{CIDR: spokeConfig().SpokeIPv6MachineCIDR}}
+	}
+
+	return networking
+}
+
+// defaultDualStackNetworking returns the default dual-stack cluster, service and machine networks, IPv4 first.
+func defaultDualStackNetworking() v1beta1.Networking {
+	return joinNetworking(defaultIPv4Networking(), defaultIPv6Networking())
+}
+
+// defaultIPv6PrimaryDualStackNetworking returns the default dual-stack cluster, service and machine networks, IPv6
+// first.
+func defaultIPv6PrimaryDualStackNetworking() v1beta1.Networking {
+	return joinNetworking(defaultIPv6Networking(), defaultIPv4Networking())
+}
+
+// joinNetworking returns the networks of primary followed by the networks of secondary.
+func joinNetworking(primary, secondary v1beta1.Networking) v1beta1.Networking {
+	return v1beta1.Networking{
+		ClusterNetwork: append(primary.ClusterNetwork, secondary.ClusterNetwork...),
+		ServiceNetwork: append(primary.ServiceNetwork, secondary.ServiceNetwork...),
+		MachineNetwork: append(primary.MachineNetwork, secondary.MachineNetwork...),
+	}
+}
 
-	b := make([]rune, n)
-	for i := range b {
-		b[i] = letterRunes[rand.Intn(len(letterRunes))]
+// configuredOrDefault returns the configured value when set, otherwise the fallback.
+func configuredOrDefault(configured, fallback string) string {
+	if configured != "" {
+		return configured
 	}
 
-	return string(b)
+	return fallback
 }
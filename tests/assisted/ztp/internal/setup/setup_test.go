@@ -0,0 +1,663 @@
+package setup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	hivev1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/hive/api/v1"
+	"github.com/openshift-kni/eco-goinfra/pkg/secret"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpconfig"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	runtimeClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+const (
+	testHubOCPXYVersion = "4.16"
+	testPullSecretData  = `{"auths":{"registry.example.com":{"auth":"dGVzdDp0ZXN0"}}}`
+)
+
+var (
+	updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+	// testZTPConfig is the ZTP configuration set for the whole test suite, which tests modify to exercise
+	// configured defaults.
+	testZTPConfig = &ztpconfig.ZTPConfig{
+		HubConfig: &ztpconfig.HubConfig{
+			HubOCPXYVersion: testHubOCPXYVersion,
+			HubPullSecret: &secret.Builder{
+				Object: &corev1.Secret{
+					Data: map[string][]byte{corev1.DockerConfigJsonKey: []byte(testPullSecretData)},
+				},
+			},
+		},
+		SpokeConfig: &ztpconfig.SpokeConfig{},
+	}
+)
+
+func TestMain(m *testing.M) {
+	SetConfig(testZTPConfig)
+
+	os.Exit(m.Run())
+}
+
+func TestWithName(t *testing.T) {
+	testCases := []struct {
+		name        string
+		expectedErr bool
+	}{
+		{name: "spoke", expectedErr: false},
+		{name: "", expectedErr: true},
+	}
+
+	for _, testCase := range testCases {
+		spoke := NewSpokeCluster(newTestClient()).WithName(testCase.name)
+
+		assert.Equal(t, testCase.name, spoke.Name)
+		assert.Equal(t, testCase.expectedErr, spoke.err != nil)
+	}
+}
+
+func TestGetError(t *testing.T) {
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultNamespace()
+	assert.Nil(t, spoke.GetError())
+
+	spoke.WithSSHPublicKey("").WithDefaultPullSecret().WithDefaultClusterDeployment().WithBaseDomain("")
+	assert.EqualError(t, spoke.GetError(),
+		"WithSSHPublicKey: infraenv must be defined before setting the ssh public key")
+	assert.Nil(t, spoke.PullSecret)
+	assert.Nil(t, spoke.ClusterDeployment)
+
+	_, err := spoke.Create()
+	assert.Equal(t, spoke.GetError(), err)
+	assert.False(t, spoke.Namespace.Exists())
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("").WithName("spoke").WithDefaultNamespace()
+	assert.EqualError(t, spoke.GetError(), "WithName: spoke name cannot be empty")
+	assert.Empty(t, spoke.Name)
+	assert.Nil(t, spoke.Namespace)
+}
+
+func TestWithDefaultSNOAgentClusterInstall(t *testing.T) {
+	spoke := NewSpokeCluster(newHubTestClient()).WithName("sno").WithDefaultPullSecret().WithDefaultClusterDeployment().
+		WithDefaultSNOAgentClusterInstall().WithDefaultInfraEnv()
+
+	assert.Nil(t, spoke.err)
+	assert.Nil(t, spoke.Validate())
+
+	spec := spoke.AgentClusterInstall.Definition.Spec
+	assert.Equal(t, 1, spec.ProvisionRequirements.ControlPlaneAgents)
+	assert.Equal(t, 0, spec.ProvisionRequirements.WorkerAgents)
+	assert.Equal(t, testHubOCPXYVersion, spec.ImageSetRef.Name)
+	assert.Empty(t, spec.APIVIP)
+	assert.Empty(t, spec.IngressVIP)
+	assert.Empty(t, spec.APIVIPs)
+	assert.Empty(t, spec.IngressVIPs)
+	assert.True(t, *spec.Networking.UserManagedNetworking)
+	assert.Equal(t, []v1beta1.MachineNetworkEntry{{CIDR: "192.168.254.0/24"}}, spec.Networking.MachineNetwork)
+	assert.Equal(t, []v1beta1.ClusterNetworkEntry{{CIDR: "10.128.0.0/14", HostPrefix: 23}},
+		spec.Networking.ClusterNetwork)
+	assert.Equal(t, []string{"172.30.0.0/16"}, spec.Networking.ServiceNetwork)
+	assert.Equal(t, "sno", spoke.InfraEnv.Definition.Name)
+}
+
+func TestWithAgentCounts(t *testing.T) {
+	testCases := []struct {
+		controlPlane  int
+		workers       int
+		definedACI    bool
+		expectedError string
+	}{
+		{controlPlane: 3, workers: 0, definedACI: true},
+		{controlPlane: 3, workers: 5, definedACI: true},
+		{controlPlane: 1, workers: 0, definedACI: true},
+		{
+			controlPlane:  2,
+			workers:       2,
+			definedACI:    true,
+			expectedError: "WithAgentCounts: control-plane agent count must be 1 or 3, got 2",
+		},
+		{
+			controlPlane:  3,
+			workers:       -1,
+			definedACI:    true,
+			expectedError: "WithAgentCounts: worker agent count cannot be negative, got -1",
+		},
+		{
+			controlPlane:  3,
+			workers:       0,
+			expectedError: "WithAgentCounts: agentclusterinstall must be defined before setting agent counts",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := NewSpokeCluster(newTestClient()).WithName("spoke")
+		if testCase.definedACI {
+			spoke.WithDefaultIPv4AgentClusterInstall()
+		}
+
+		spoke.WithAgentCounts(testCase.controlPlane, testCase.workers)
+
+		if testCase.expectedError != "" {
+			assert.EqualError(t, spoke.err, testCase.expectedError)
+
+			continue
+		}
+
+		assert.Nil(t, spoke.err)
+
+		requirements := spoke.AgentClusterInstall.Definition.Spec.ProvisionRequirements
+		assert.Equal(t, testCase.controlPlane, requirements.ControlPlaneAgents)
+		assert.Equal(t, testCase.workers, requirements.WorkerAgents)
+	}
+}
+
+func TestWithAgentClusterInstall(t *testing.T) {
+	customNetworking := v1beta1.Networking{
+		ClusterNetwork: []v1beta1.ClusterNetworkEntry{{CIDR: "10.132.0.0/14", HostPrefix: 23}},
+		ServiceNetwork: []string{"172.31.0.0/16"},
+		MachineNetwork: []v1beta1.MachineNetworkEntry{{CIDR: "192.168.50.0/24"}},
+	}
+
+	testCases := []struct {
+		name          string
+		masters       int
+		workers       int
+		networking    v1beta1.Networking
+		expectedUMN   bool
+		expectedError string
+	}{
+		{name: "compact", masters: 3, workers: 0, networking: customNetworking},
+		{name: "workers", masters: 3, workers: 4, networking: customNetworking},
+		{name: "single-node", masters: 1, workers: 0, networking: customNetworking, expectedUMN: true},
+		{
+			name:          "invalid masters",
+			masters:       2,
+			networking:    customNetworking,
+			expectedError: "WithAgentClusterInstall: control-plane agent count must be 1 or 3, got 2",
+		},
+		{
+			name:          "negative workers",
+			masters:       3,
+			workers:       -1,
+			networking:    customNetworking,
+			expectedError: "WithAgentClusterInstall: worker agent count cannot be negative, got -1",
+		},
+		{
+			name:    "missing service network",
+			masters: 3,
+			networking: v1beta1.Networking{
+				ClusterNetwork: customNetworking.ClusterNetwork,
+			},
+			expectedError: "WithAgentClusterInstall: at least one cluster and one service network are required",
+		},
+		{
+			name:    "mismatched families",
+			masters: 3,
+			networking: v1beta1.Networking{
+				ClusterNetwork: customNetworking.ClusterNetwork,
+				ServiceNetwork: []string{"fd02::/112"},
+			},
+			expectedError: "WithAgentClusterInstall: invalid agentclusterinstall networking: " +
+				"cluster networks are IPv4 but service networks are IPv6",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := NewSpokeCluster(newTestClient()).WithName("spoke").
+			WithAgentClusterInstall(testCase.masters, testCase.workers, testCase.networking)
+
+		if testCase.expectedError != "" {
+			assert.EqualError(t, spoke.err, testCase.expectedError, testCase.name)
+
+			continue
+		}
+
+		assert.Nil(t, spoke.err, testCase.name)
+
+		spec := spoke.AgentClusterInstall.Definition.Spec
+		assert.Equal(t, testCase.masters, spec.ProvisionRequirements.ControlPlaneAgents, testCase.name)
+		assert.Equal(t, testCase.workers, spec.ProvisionRequirements.WorkerAgents, testCase.name)
+		assert.Equal(t, testHubOCPXYVersion, spec.ImageSetRef.Name, testCase.name)
+		assert.Equal(t, customNetworking.ClusterNetwork, spec.Networking.ClusterNetwork, testCase.name)
+		assert.Equal(t, customNetworking.ServiceNetwork, spec.Networking.ServiceNetwork, testCase.name)
+		assert.Equal(t, customNetworking.MachineNetwork, spec.Networking.MachineNetwork, testCase.name)
+		assert.Empty(t, spec.APIVIPs, testCase.name)
+		assert.Equal(t, testCase.expectedUMN,
+			spec.Networking.UserManagedNetworking != nil && *spec.Networking.UserManagedNetworking, testCase.name)
+	}
+}
+
+func TestWithBaseDomain(t *testing.T) {
+	testCases := []struct {
+		domain         string
+		configDomain   string
+		useDefault     bool
+		expectedDomain string
+		expectedError  string
+	}{
+		{useDefault: true, expectedDomain: "assisted.test.com"},
+		{useDefault: true, configDomain: "lab.example.com", expectedDomain: "lab.example.com"},
+		{domain: "spokes.example.com", expectedDomain: "spokes.example.com"},
+		{domain: "", expectedError: "invalid base domain \"\""},
+		{domain: "Invalid_Domain", expectedError: "invalid base domain \"Invalid_Domain\""},
+		{useDefault: true, configDomain: "-bad-", expectedError: "invalid base domain \"-bad-\""},
+	}
+
+	for _, testCase := range testCases {
+		testZTPConfig.SpokeBaseDomain = testCase.configDomain
+
+		spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultClusterDeployment()
+		if !testCase.useDefault {
+			spoke.WithBaseDomain(testCase.domain)
+		}
+
+		if testCase.expectedError != "" {
+			assert.ErrorContains(t, spoke.err, testCase.expectedError)
+
+			continue
+		}
+
+		assert.Nil(t, spoke.err)
+		assert.Equal(t, testCase.expectedDomain, spoke.ClusterDeployment.Definition.Spec.BaseDomain)
+	}
+
+	testZTPConfig.SpokeBaseDomain = ""
+
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithBaseDomain("example.com")
+	assert.EqualError(t, spoke.err, "WithBaseDomain: clusterdeployment must be defined before setting the base domain")
+}
+
+func TestWithDefaultPullSecret(t *testing.T) {
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultPullSecret()
+	assert.Nil(t, spoke.err)
+	assert.Equal(t, testPullSecretData, string(spoke.PullSecret.Definition.Data[corev1.DockerConfigJsonKey]))
+
+	testCases := []struct {
+		name          string
+		data          map[string][]byte
+		expectedData  string
+		expectedError string
+	}{
+		{
+			name:         "scoped pull-secret",
+			data:         map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{}}`)},
+			expectedData: `{"auths":{}}`,
+		},
+		{
+			name:          "missing docker config",
+			data:          map[string][]byte{"token": []byte("secret")},
+			expectedError: "WithDefaultPullSecret: hub pull-secret ci/scoped-pull-secret has no .dockerconfigjson key",
+		},
+		{
+			name: "missing secret",
+			expectedError: "WithDefaultPullSecret: failed to pull hub pull-secret ci/scoped-pull-secret: " +
+				"secret object scoped-pull-secret does not exist in namespace ci",
+		},
+	}
+
+	for _, testCase := range testCases {
+		restore := SetConfig(&ztpconfig.ZTPConfig{HubConfig: &ztpconfig.HubConfig{
+			HubPullSecretNamespace: "ci", HubPullSecretName: "scoped-pull-secret",
+		}})
+
+		var objects []runtime.Object
+
+		if testCase.data != nil {
+			objects = append(objects, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "scoped-pull-secret", Namespace: "ci"},
+				Data:       testCase.data,
+			})
+		}
+
+		spoke := NewSpokeCluster(newTestClient(objects...)).WithName("spoke").WithDefaultPullSecret()
+
+		restore()
+
+		if testCase.expectedError != "" {
+			assert.EqualError(t, spoke.err, testCase.expectedError, testCase.name)
+
+			continue
+		}
+
+		assert.Nil(t, spoke.err, testCase.name)
+		assert.Equal(t, testCase.expectedData,
+			string(spoke.PullSecret.Definition.Data[corev1.DockerConfigJsonKey]), testCase.name)
+	}
+
+	restore := SetConfig(&ztpconfig.ZTPConfig{HubConfig: &ztpconfig.HubConfig{
+		HubPullSecret: &secret.Builder{Object: &corev1.Secret{}},
+	}})
+	defer restore()
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultPullSecret()
+	assert.EqualError(t, spoke.err,
+		"WithDefaultPullSecret: hub pull-secret openshift-config/pull-secret has no .dockerconfigjson key")
+}
+
+func TestWithAgentSelector(t *testing.T) {
+	selector := metav1.LabelSelector{MatchLabels: map[string]string{"pool": "a"}}
+	defaultSelector := metav1.LabelSelector{MatchLabels: map[string]string{"dummy": "label"}}
+
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultClusterDeployment()
+	assert.Equal(t, defaultSelector, spoke.ClusterDeployment.Definition.Spec.Platform.AgentBareMetal.AgentSelector)
+
+	spoke.WithAgentSelector(selector)
+	assert.Nil(t, spoke.err)
+	assert.Equal(t, selector, spoke.ClusterDeployment.Definition.Spec.Platform.AgentBareMetal.AgentSelector)
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithAgentSelector(selector).
+		WithDefaultClusterDeployment()
+	assert.Nil(t, spoke.err)
+	assert.Equal(t, selector, spoke.ClusterDeployment.Definition.Spec.Platform.AgentBareMetal.AgentSelector)
+
+	testZTPConfig.SpokeAgentSelector = "pool=b,rack in (r1,r2)"
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultClusterDeployment()
+	assert.Nil(t, spoke.err)
+	assert.Equal(t, metav1.LabelSelector{
+		MatchLabels: map[string]string{"pool": "b"},
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "rack", Operator: metav1.LabelSelectorOpIn, Values: []string{"r1", "r2"}},
+		},
+	}, spoke.ClusterDeployment.Definition.Spec.Platform.AgentBareMetal.AgentSelector)
+
+	testZTPConfig.SpokeAgentSelector = "pool in (a"
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultClusterDeployment()
+	assert.ErrorContains(t, spoke.err, `WithDefaultClusterDeployment: invalid configured agent selector "pool in (a": `)
+
+	testZTPConfig.SpokeAgentSelector = ""
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithAgentSelector(metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "pool", Operator: "Matches"}},
+	})
+	assert.ErrorContains(t, spoke.err, "WithAgentSelector: invalid agent selector: ")
+}
+
+func TestWithPullSecretData(t *testing.T) {
+	malformedData := map[string][]byte{corev1.DockerConfigJsonKey: []byte("not-a-docker-config")}
+
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithPullSecretData(malformedData)
+	assert.Nil(t, spoke.err)
+	assert.Nil(t, spoke.Validate())
+	assert.Equal(t, "spoke-pull-secret", spoke.PullSecret.Definition.Name)
+	assert.Equal(t, "spoke", spoke.PullSecret.Definition.Namespace)
+	assert.Equal(t, malformedData, spoke.PullSecret.Definition.Data)
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithPullSecretData(map[string][]byte{})
+	assert.EqualError(t, spoke.err, "WithPullSecretData: pull-secret data cannot be empty")
+}
+
+func TestWithPullSecretFromFile(t *testing.T) {
+	pullSecretPath := filepath.Join(t.TempDir(), "pull-secret.json")
+	assert.Nil(t, os.WriteFile(pullSecretPath, []byte(testPullSecretData), 0o600))
+
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithPullSecretFromFile(pullSecretPath)
+	assert.Nil(t, spoke.err)
+	assert.Equal(t, []byte(testPullSecretData), spoke.PullSecret.Definition.Data[corev1.DockerConfigJsonKey])
+
+	emptyPath := filepath.Join(t.TempDir(), "empty.json")
+	assert.Nil(t, os.WriteFile(emptyPath, nil, 0o600))
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithPullSecretFromFile(emptyPath)
+	assert.EqualError(t, spoke.err, fmt.Sprintf("WithPullSecretFromFile: pull-secret file %s is empty", emptyPath))
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithPullSecretFromFile(pullSecretPath + ".missing")
+	assert.ErrorContains(t, spoke.err, "failed to read pull-secret file")
+}
+
+func TestWithSSHPublicKey(t *testing.T) {
+	const testSSHPublicKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGtleQ== user@example.com"
+
+	testCases := []struct {
+		key           string
+		definedInfra  bool
+		expectedError string
+	}{
+		{key: testSSHPublicKey, definedInfra: true},
+		{key: testSSHPublicKey + "\n", definedInfra: true},
+		{
+			key:           "AAAAC3NzaC1lZDI1NTE5",
+			definedInfra:  true,
+			expectedError: "WithSSHPublicKey: ssh public key must be in authorized_keys format",
+		},
+		{
+			key:           testSSHPublicKey,
+			expectedError: "WithSSHPublicKey: infraenv must be defined before setting the ssh public key",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := NewSpokeCluster(newTestClient()).WithName("spoke")
+		if testCase.definedInfra {
+			spoke.WithDefaultInfraEnv()
+		}
+
+		spoke.WithSSHPublicKey(testCase.key)
+
+		if testCase.expectedError != "" {
+			assert.EqualError(t, spoke.err, testCase.expectedError)
+
+			continue
+		}
+
+		assert.Nil(t, spoke.err)
+		assert.Equal(t, testSSHPublicKey, spoke.InfraEnv.Definition.Spec.SSHAuthorizedKey)
+	}
+
+	keyPath := filepath.Join(t.TempDir(), "id_ed25519.pub")
+	assert.Nil(t, os.WriteFile(keyPath, []byte(testSSHPublicKey+"\n"), 0o600))
+
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultInfraEnv().WithSSHPublicKeyFromFile(keyPath)
+	assert.Nil(t, spoke.err)
+	assert.Equal(t, testSSHPublicKey, spoke.InfraEnv.Definition.Spec.SSHAuthorizedKey)
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultInfraEnv().
+		WithSSHPublicKeyFromFile(keyPath + ".missing")
+	assert.ErrorContains(t, spoke.err, "failed to read ssh public key file")
+}
+
+func TestWithAdditionalNTPSources(t *testing.T) {
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultInfraEnv().
+		WithAdditionalNTPSources("ntp.example.com", "192.168.254.1").
+		WithAdditionalNTPSources("192.168.254.1", "fd2e:6f44:5dd8:1::1", "ntp.example.com")
+	assert.Nil(t, spoke.err)
+	assert.Equal(t, []string{"ntp.example.com", "192.168.254.1", "fd2e:6f44:5dd8:1::1"}, spoke.AdditionalNTPSources())
+	assert.Equal(t, spoke.AdditionalNTPSources(), spoke.InfraEnv.Definition.Spec.AdditionalNTPSources)
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultInfraEnv().WithAdditionalNTPSources()
+	assert.EqualError(t, spoke.err, "WithAdditionalNTPSources: at least one ntp source must be provided")
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultInfraEnv().
+		WithAdditionalNTPSources("ntp.example.com", "not a host")
+	assert.EqualError(t, spoke.err,
+		"WithAdditionalNTPSources: invalid ntp source \"not a host\", must be a hostname or an IP address")
+	assert.Empty(t, spoke.AdditionalNTPSources())
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithAdditionalNTPSources("ntp.example.com")
+	assert.EqualError(t, spoke.err, "WithAdditionalNTPSources: infraenv must be defined before adding ntp sources")
+	assert.Nil(t, spoke.AdditionalNTPSources())
+}
+
+func TestWithKernelArguments(t *testing.T) {
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultInfraEnv().
+		WithKernelArguments("console=ttyS0,115200n8", "fips=1").
+		WithKernelArguments("fips=1", "rd.net.timeout.carrier=60")
+	assert.Nil(t, spoke.err)
+	assert.Equal(t, []agentInstallV1Beta1.KernelArgument{
+		{Operation: "append", Value: "console=ttyS0,115200n8"},
+		{Operation: "append", Value: "fips=1"},
+		{Operation: "append", Value: "rd.net.timeout.carrier=60"},
+	}, spoke.InfraEnv.Definition.Spec.KernelArguments)
+
+	for _, invalidArg := range []string{"", " ", "quiet splash"} {
+		spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultInfraEnv().
+			WithKernelArguments("fips=1", invalidArg)
+		assert.EqualError(t, spoke.err,
+			fmt.Sprintf("WithKernelArguments: invalid kernel argument %q, must be a single non-empty argument",
+				invalidArg))
+		assert.Empty(t, spoke.InfraEnv.Definition.Spec.KernelArguments)
+	}
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithKernelArguments("fips=1")
+	assert.EqualError(t, spoke.err, "WithKernelArguments: infraenv must be defined before adding kernel arguments")
+}
+
+// newTestClient returns a fake client with the schemes used by the spoke cluster resources attached.
+func newTestClient(objects ...runtime.Object) *clients.Settings {
+	return clients.GetTestClients(clients.TestClientParams{
+		K8sMockObjects: objects,
+		SchemeAttachers: []clients.SchemeAttacher{
+			v1beta1.AddToScheme,
+			agentInstallV1Beta1.AddToScheme,
+			hivev1.AddToScheme,
+		},
+	})
+}
+
+// newHubTestClient returns a test client like newTestClient that also contains the clusterimageset of the hub
+// version referenced by the default agentclusterinstalls.
+func newHubTestClient(objects ...runtime.Object) *clients.Settings {
+	return newTestClient(append(objects, buildDummyClusterImageSet(testHubOCPXYVersion, "", ""))...)
+}
+
+// definitionsYAML renders the definitions of every instantiated spoke resource as a multi-document YAML.
+func definitionsYAML(t *testing.T, spoke *SpokeClusterResources) []byte {
+	t.Helper()
+
+	var definitions []runtime.Object
+
+	if spoke.Namespace != nil {
+		definitions = append(definitions, spoke.Namespace.Definition)
+	}
+
+	if spoke.PullSecret != nil {
+		definitions = append(definitions, spoke.PullSecret.Definition)
+	}
+
+	if spoke.ClusterDeployment != nil {
+		definitions = append(definitions, spoke.ClusterDeployment.Definition)
+	}
+
+	if spoke.AgentClusterInstall != nil {
+		definitions = append(definitions, spoke.AgentClusterInstall.Definition)
+	}
+
+	if spoke.InfraEnv != nil {
+		definitions = append(definitions, spoke.InfraEnv.Definition)
+	}
+
+	var buffer bytes.Buffer
+
+	for _, definition := range definitions {
+		content, err := definitionToUnstructured(definition)
+		assert.Nil(t, err)
+
+		out, err := yaml.Marshal(content)
+		assert.Nil(t, err)
+
+		buffer.WriteString("---\n")
+		buffer.Write(out)
+	}
+
+	return buffer.Bytes()
+}
+
+// assertGolden compares actual against the named golden file in testdata, rewriting it when -update is set.
+func assertGolden(t *testing.T, name string, actual []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+
+	if *updateGolden {
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, os.WriteFile(path, actual, 0600))
+	}
+
+	expected, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, string(expected), string(actual))
+}
+
+func TestDeleteAggregatesErrors(t *testing.T) {
+	apiClient, testBuilder := clients.GetModifiableTestClients(clients.TestClientParams{
+		K8sMockObjects: []runtime.Object{buildDummyClusterImageSet(testHubOCPXYVersion, "", "")},
+		SchemeAttachers: []clients.SchemeAttacher{
+			v1beta1.AddToScheme,
+			agentInstallV1Beta1.AddToScheme,
+			hivev1.AddToScheme,
+		},
+	})
+
+	apiClient.Client = testBuilder.WithInterceptorFuncs(interceptor.Funcs{
+		Delete: func(ctx context.Context, client runtimeClient.WithWatch,
+			obj runtimeClient.Object, opts ...runtimeClient.DeleteOption) error {
+			switch obj.(type) {
+			case *agentInstallV1Beta1.InfraEnv:
+				return testForbiddenErr
+			case *v1beta1.AgentClusterInstall:
+				_ = client.Delete(ctx, obj, opts...)
+
+				return k8serrors.NewNotFound(schema.GroupResource{Resource: "agentclusterinstalls"}, obj.GetName())
+			case *hivev1.ClusterDeployment:
+				return errors.New("clusterdeployment has finalizers")
+			}
+
+			return client.Delete(ctx, obj, opts...)
+		},
+	}).Build()
+
+	spoke, err := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultNamespace().WithDefaultPullSecret().
+		WithDefaultClusterDeployment().WithDefaultIPv4AgentClusterInstall().WithDefaultInfraEnv().Create()
+	assert.Nil(t, err)
+
+	err = spoke.Delete()
+	assert.NotNil(t, err)
+	assert.ErrorIs(t, err, testForbiddenErr)
+	assert.ErrorContains(t, err, "failed to delete infraenv spoke: ")
+	assert.ErrorContains(t, err, "failed to delete clusterdeployment spoke: ")
+	assert.ErrorContains(t, err, "clusterdeployment has finalizers")
+	assert.NotContains(t, err.Error(), "agentclusterinstall")
+	assert.False(t, spoke.PullSecret.Exists())
+	assert.False(t, spoke.Namespace.Exists())
+
+	assert.Nil(t, NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultNamespace().Delete())
+}
+
+func TestCreateOrderingAndIdempotency(t *testing.T) {
+	expected := []string{
+		"namespace", "pull-secret", "clusterdeployment", "agentclusterinstall", "infraenv", "managedcluster",
+		"klusterletaddonconfig",
+	}
+
+	spoke := StandardHAProfile(newHubTestClient(), "spoke").WithDefaultManagedCluster().
+		WithDefaultKlusterletAddonConfig()
+	spoke.serialCreate = true
+
+	_, err := spoke.Create()
+	assert.Nil(t, err)
+	assert.Equal(t, expected, spoke.createdResources)
+
+	_, err = spoke.Create()
+	assert.Nil(t, err)
+	assert.Equal(t, expected, spoke.createdResources)
+
+	assert.Nil(t, spoke.Delete())
+	assert.False(t, spoke.Namespace.Exists())
+	assert.False(t, spoke.ClusterDeployment.Exists())
+	assert.Nil(t, spoke.Delete())
+}
@@ -0,0 +1,117 @@
+package setup
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+)
+
+var (
+	sharedSpokes      = map[string]*sharedSpoke{}
+	sharedSpokesMutex sync.Mutex
+)
+
+// sharedSpoke is a spoke created once and shared by every user of its key.
+type sharedSpoke struct {
+	mutex      sync.Mutex
+	spoke      *SpokeClusterResources
+	references int
+}
+
+// SharedSpoke returns the spoke shared under key, creating it from builder for the first user, and a release
+// function that must be called once by every user, typically in an AfterAll or DeferCleanup. The spoke is deleted
+// when the last user releases it. Specs running in parallel within a process share the spoke but separate ginkgo
+// processes each create their own, so the spoke built for a key must not collide with the one of another process.
+func SharedSpoke(key string, builder func() *SpokeClusterResources) (*SpokeClusterResources, func(), error) {
+	if key == "" {
+		return nil, nil, fmt.Errorf("shared spoke key cannot be empty")
+	}
+
+	if builder == nil {
+		return nil, nil, fmt.Errorf("shared spoke builder cannot be nil")
+	}
+
+	shared := acquireSharedSpoke(key)
+
+	shared.mutex.Lock()
+	defer shared.mutex.Unlock()
+
+	if shared.spoke == nil {
+		spoke := builder()
+		if spoke == nil {
+			releaseSharedSpoke(key, shared)
+
+			return nil, nil, fmt.Errorf("shared spoke %s builder returned nil", key)
+		}
+
+		if _, err := spoke.Create(); err != nil {
+			releaseSharedSpoke(key, shared)
+
+			return nil, nil, fmt.Errorf("failed to create shared spoke %s: %w", key, err)
+		}
+
+		shared.spoke = spoke
+	}
+
+	var releaseOnce sync.Once
+
+	release := func() {
+		releaseOnce.Do(func() {
+			if !releaseSharedSpoke(key, shared) {
+				return
+			}
+
+			shared.mutex.Lock()
+			defer shared.mutex.Unlock()
+
+			if shared.spoke == nil {
+				return
+			}
+
+			if err := shared.spoke.Delete(); err != nil {
+				glog.V(ztpparams.ZTPLogLevel).Infof("Failed to delete shared spoke %s: %v", key, err)
+			}
+
+			shared.spoke = nil
+		})
+	}
+
+	return shared.spoke, release, nil
+}
+
+// acquireSharedSpoke returns the shared spoke registered under key, registering a new one if needed, and adds a
+// reference to it.
+func acquireSharedSpoke(key string) *sharedSpoke {
+	sharedSpokesMutex.Lock()
+	defer sharedSpokesMutex.Unlock()
+
+	shared, found := sharedSpokes[key]
+	if !found {
+		shared = &sharedSpoke{}
+		sharedSpokes[key] = shared
+	}
+
+	shared.references++
+
+	return shared
+}
+
+// releaseSharedSpoke removes a reference to the shared spoke and unregisters it from key when no reference is left.
+// It returns true when the last reference was removed.
+func releaseSharedSpoke(key string, shared *sharedSpoke) bool {
+	sharedSpokesMutex.Lock()
+	defer sharedSpokesMutex.Unlock()
+
+	shared.references--
+	if shared.references > 0 {
+		return false
+	}
+
+	if sharedSpokes[key] == shared {
+		delete(sharedSpokes, key)
+	}
+
+	return true
+}
@@ -0,0 +1,111 @@
+package setup
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/namespace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedSpoke(t *testing.T) {
+	apiClient := newTestClient()
+	builds := 0
+
+	builder := func() *SpokeClusterResources {
+		builds++
+
+		return NewSpokeCluster(apiClient).WithName("shared").WithDefaultNamespace()
+	}
+
+	assert.Equal(t, 0, builds)
+
+	firstSpoke, firstRelease, err := SharedSpoke("shared", builder)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, builds)
+	assert.True(t, namespace.NewBuilder(apiClient, "shared").Exists())
+
+	secondSpoke, secondRelease, err := SharedSpoke("shared", builder)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, builds)
+	assert.Same(t, firstSpoke, secondSpoke)
+
+	firstRelease()
+	firstRelease()
+	assert.True(t, namespace.NewBuilder(apiClient, "shared").Exists())
+
+	secondRelease()
+	assert.False(t, namespace.NewBuilder(apiClient, "shared").Exists())
+
+	_, thirdRelease, err := SharedSpoke("shared", builder)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, builds)
+
+	thirdRelease()
+}
+
+func TestSharedSpokeConcurrent(t *testing.T) {
+	apiClient := newTestClient()
+	users := 10
+
+	var (
+		buildsMutex sync.Mutex
+		builds      int
+		waitGroup   sync.WaitGroup
+	)
+
+	builder := func() *SpokeClusterResources {
+		buildsMutex.Lock()
+		defer buildsMutex.Unlock()
+
+		builds++
+
+		return NewSpokeCluster(apiClient).WithName("concurrent").WithDefaultNamespace()
+	}
+
+	releases := make(chan func(), users)
+
+	for range users {
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+
+			_, release, err := SharedSpoke("concurrent", builder)
+			assert.Nil(t, err)
+
+			releases <- release
+		}()
+	}
+
+	waitGroup.Wait()
+	close(releases)
+
+	assert.Equal(t, 1, builds)
+
+	for release := range releases {
+		assert.True(t, namespace.NewBuilder(apiClient, "concurrent").Exists())
+
+		release()
+	}
+
+	assert.False(t, namespace.NewBuilder(apiClient, "concurrent").Exists())
+}
+
+func TestSharedSpokeErrors(t *testing.T) {
+	_, _, err := SharedSpoke("", func() *SpokeClusterResources { return nil })
+	assert.NotNil(t, err)
+
+	_, _, err = SharedSpoke("nil-builder", nil)
+	assert.NotNil(t, err)
+
+	_, _, err = SharedSpoke("nil-spoke", func() *SpokeClusterResources { return nil })
+	assert.NotNil(t, err)
+
+	_, _, err = SharedSpoke("invalid", func() *SpokeClusterResources {
+		return NewSpokeCluster(newTestClient()).WithName("")
+	})
+	assert.NotNil(t, err)
+
+	assert.Empty(t, sharedSpokes)
+}
@@ -0,0 +1,163 @@
+package setup
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+)
+
+// spokeSetNamePrefix prefixes the generated names of the spokes of a SpokeClusterSet.
+const spokeSetNamePrefix = "spokeset-"
+
+// SpokeOption configures a spoke of a SpokeClusterSet. Method expressions of the With* methods without arguments,
+// such as (*SpokeClusterResources).WithDefaultNamespace, are spoke options.
+type SpokeOption func(spoke *SpokeClusterResources) *SpokeClusterResources
+
+// SpokeClusterSet is a set of spokes built with the same options and created or deleted in parallel.
+type SpokeClusterSet struct {
+	Spokes []*SpokeClusterResources
+	err    error
+}
+
+// SpokeSetError is returned by SpokeClusterSet.CreateAll and SpokeClusterSet.DeleteAll when some spokes failed.
+// Errors maps the name of every failed spoke to its error.
+type SpokeSetError struct {
+	Errors map[string]error
+}
+
+// Error returns the failures of the spokes, sorted by spoke name.
+func (setErr *SpokeSetError) Error() string {
+	failures := make([]string, 0, len(setErr.Errors))
+
+	for _, name := range slices.Sorted(maps.Keys(setErr.Errors)) {
+		failures = append(failures, fmt.Sprintf("spoke %s: %v", name, setErr.Errors[name]))
+	}
+
+	return fmt.Sprintf("%d spokes failed: %s", len(setErr.Errors), strings.Join(failures, "; "))
+}
+
+// Unwrap returns the errors of the failed spokes so that errors.Is and errors.As match any of them.
+func (setErr *SpokeSetError) Unwrap() []error {
+	errs := make([]error, 0, len(setErr.Errors))
+
+	for _, err := range setErr.Errors {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+// NewSpokeClusterSet returns a set of count spokes with generated names sharing the spokeset- prefix, each
+// configured by applying opts in order. Errors recorded while building a spoke are returned for that spoke by
+// CreateAll.
+func NewSpokeClusterSet(apiClient *clients.Settings, count int, opts ...SpokeOption) *SpokeClusterSet {
+	spokeSet := &SpokeClusterSet{}
+
+	if apiClient == nil {
+		spokeSet.err = fmt.Errorf("apiClient cannot be nil")
+
+		return spokeSet
+	}
+
+	if count <= 0 {
+		spokeSet.err = fmt.Errorf("spoke count must be greater than 0, got %d", count)
+
+		return spokeSet
+	}
+
+	for range count {
+		spoke := NewSpokeCluster(apiClient).WithAutoGeneratedName(spokeSetNamePrefix)
+
+		for _, opt := range opts {
+			spoke = opt(spoke)
+		}
+
+		spokeSet.Spokes = append(spokeSet.Spokes, spoke)
+	}
+
+	return spokeSet
+}
+
+// CreateAll creates the spokes of the set, running up to concurrency Create calls at a time. A failed spoke does
+// not stop the creation of the others. It returns a *SpokeSetError holding the error of every failed spoke, or nil
+// when all of them were created.
+func (spokeSet *SpokeClusterSet) CreateAll(concurrency int) error {
+	return spokeSet.runAll("create", concurrency, func(spoke *SpokeClusterResources) error {
+		_, err := spoke.Create()
+
+		return err
+	})
+}
+
+// DeleteAll deletes the spokes of the set, running up to concurrency Delete calls at a time. It can be called on a
+// partially created set, since resources that do not exist are not failures. It returns a *SpokeSetError holding
+// the error of every spoke whose deletion failed, or nil.
+func (spokeSet *SpokeClusterSet) DeleteAll(concurrency int) error {
+	return spokeSet.runAll("delete", concurrency, (*SpokeClusterResources).Delete)
+}
+
+// runAll calls action on every spoke of the set from concurrency workers and collects the spoke errors.
+func (spokeSet *SpokeClusterSet) runAll(
+	operation string, concurrency int, action func(spoke *SpokeClusterResources) error) error {
+	if spokeSet.err != nil {
+		return spokeSet.err
+	}
+
+	if concurrency <= 0 {
+		return fmt.Errorf("concurrency must be greater than 0, got %d", concurrency)
+	}
+
+	var (
+		waitGroup sync.WaitGroup
+		mutex     sync.Mutex
+		errs      = make(map[string]error)
+		spokes    = make(chan int)
+	)
+
+	for range min(concurrency, len(spokeSet.Spokes)) {
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+
+			for index := range spokes {
+				spoke := spokeSet.Spokes[index]
+
+				err := action(spoke)
+				if err == nil {
+					continue
+				}
+
+				name := spoke.Name
+				if name == "" {
+					name = fmt.Sprintf("#%d", index)
+				}
+
+				glog.V(ztpparams.ZTPLogLevel).Infof("Failed to %s spoke %s of spoke set: %v", operation, name, err)
+
+				mutex.Lock()
+				errs[name] = err
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	for index := range spokeSet.Spokes {
+		spokes <- index
+	}
+
+	close(spokes)
+	waitGroup.Wait()
+
+	if len(errs) > 0 {
+		return &SpokeSetError{Errors: errs}
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package setup
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpokeClusterSet(t *testing.T) {
+	spokeSet := NewSpokeClusterSet(newHubTestClient(), 3,
+		(*SpokeClusterResources).WithDefaultNamespace,
+		(*SpokeClusterResources).WithDefaultPullSecret,
+		(*SpokeClusterResources).WithDefaultClusterDeployment,
+		(*SpokeClusterResources).WithDefaultIPv4AgentClusterInstall)
+	assert.Len(t, spokeSet.Spokes, 3)
+
+	names := map[string]bool{}
+
+	for _, spoke := range spokeSet.Spokes {
+		assert.True(t, strings.HasPrefix(spoke.Name, spokeSetNamePrefix), spoke.Name)
+		assert.NotNil(t, spoke.AgentClusterInstall, spoke.Name)
+
+		names[spoke.Name] = true
+	}
+
+	assert.Len(t, names, 3)
+
+	failed := spokeSet.Spokes[1].WithConcurrencyLimit(0)
+
+	err := spokeSet.CreateAll(2)
+
+	var setErr *SpokeSetError
+
+	assert.True(t, errors.As(err, &setErr))
+	assert.Equal(t, map[string]error{failed.Name: failed.err}, setErr.Errors)
+	assert.EqualError(t, err, "1 spokes failed: spoke "+failed.Name+
+		": WithConcurrencyLimit: concurrency limit must be greater than 0")
+	assert.True(t, spokeSet.Spokes[0].AgentClusterInstall.Exists())
+	assert.True(t, spokeSet.Spokes[2].AgentClusterInstall.Exists())
+
+	assert.Nil(t, spokeSet.DeleteAll(3))
+
+	for _, spoke := range spokeSet.Spokes {
+		assert.False(t, spoke.Namespace.Exists(), spoke.Name)
+	}
+}
+
+func TestSpokeClusterSetConcurrency(t *testing.T) {
+	spokeSet := NewSpokeClusterSet(newTestClient(), 6)
+
+	var (
+		mutex             sync.Mutex
+		active, maxActive int
+	)
+
+	err := spokeSet.runAll("test", 2, func(spoke *SpokeClusterResources) error {
+		mutex.Lock()
+		active++
+		maxActive = max(maxActive, active)
+		mutex.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mutex.Lock()
+		active--
+		mutex.Unlock()
+
+		if spoke == spokeSet.Spokes[4] {
+			return errors.New("failed")
+		}
+
+		return nil
+	})
+	assert.EqualError(t, err, "1 spokes failed: spoke "+spokeSet.Spokes[4].Name+": failed")
+	assert.Equal(t, 2, maxActive)
+}
+
+func TestSpokeClusterSetErrors(t *testing.T) {
+	assert.EqualError(t, NewSpokeClusterSet(nil, 1).CreateAll(1), "apiClient cannot be nil")
+	assert.EqualError(t, NewSpokeClusterSet(newTestClient(), 0).DeleteAll(1),
+		"spoke count must be greater than 0, got 0")
+	assert.EqualError(t, NewSpokeClusterSet(newTestClient(), 1).CreateAll(0),
+		"concurrency must be greater than 0, got 0")
+}
@@ -0,0 +1,169 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	assistedHiveV1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/hive/api/v1"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+	"golang.org/x/sync/errgroup"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// clusterDeploymentSyncRetries is the number of times the agentclusterinstall is checked again, one wait interval
+// apart, while it reports its clusterdeployment missing after both were created concurrently.
+const clusterDeploymentSyncRetries = 3
+
+// createInstallResources creates the clusterdeployment, the agentclusterinstall and the infraenvs of the spoke
+// concurrently, since they only reference each other by name and the assisted service reconciles them in any order.
+// Every creation runs to completion and their errors are joined.
+func (spoke *SpokeClusterResources) createInstallResources(ctx context.Context) error {
+	var creates []func() error
+
+	if spoke.ClusterDeployment != nil {
+		creates = append(creates, func() error {
+			return spoke.createOrAdopt(ctx, "clusterdeployment", spoke.ClusterDeployment, func() (err error) {
+				spoke.ClusterDeployment, err = spoke.ClusterDeployment.Create()
+
+				return err
+			})
+		})
+	}
+
+	if spoke.AgentClusterInstall != nil {
+		creates = append(creates, func() error {
+			spoke.attachExtraManifests()
+
+			return spoke.createAgentClusterInstall(ctx)
+		})
+	}
+
+	if spoke.InfraEnv != nil || len(spoke.AdditionalInfraEnvs) > 0 {
+		creates = append(creates, func() error {
+			return spoke.createInfraEnvs(ctx)
+		})
+	}
+
+	if err := spoke.runCreateStage(creates); err != nil {
+		return err
+	}
+
+	if spoke.ClusterDeployment != nil && spoke.AgentClusterInstall != nil {
+		err := spoke.waitForClusterDeploymentSync(ctx)
+		if ctx.Err() != nil {
+			spoke.recordInterruptedStep("wait for clusterdeployment sync")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// createInfraEnvs creates the infraenv and then the additional infraenvs of the spoke, stopping at the first error.
+func (spoke *SpokeClusterResources) createInfraEnvs(ctx context.Context) error {
+	if spoke.InfraEnv != nil {
+		err := spoke.createOrAdopt(ctx, "infraenv", spoke.InfraEnv, func() (err error) {
+			spoke.InfraEnv, err = spoke.InfraEnv.Create()
+
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for index, infraEnv := range spoke.AdditionalInfraEnvs {
+		err := spoke.createOrAdopt(ctx, "additional infraenv", infraEnv, func() (err error) {
+			spoke.AdditionalInfraEnvs[index], err = infraEnv.Create()
+
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runCreateStage runs creates concurrently, or one after the other in order when serialCreate is set, and returns
+// their errors joined. A failed creation does not cancel the others.
+func (spoke *SpokeClusterResources) runCreateStage(creates []func() error) error {
+	var group errgroup.Group
+
+	if spoke.serialCreate {
+		group.SetLimit(1)
+	}
+
+	errs := make([]error, len(creates))
+
+	for index, create := range creates {
+		group.Go(func() error {
+			errs[index] = create()
+
+			return nil
+		})
+	}
+
+	_ = group.Wait()
+
+	return errors.Join(errs...)
+}
+
+// waitForClusterDeploymentSync checks that the agentclusterinstall does not report its clusterdeployment missing,
+// which can happen when the assisted service reconciled it before the concurrently created clusterdeployment
+// existed. The check is retried up to clusterDeploymentSyncRetries times while the condition persists.
+func (spoke *SpokeClusterResources) waitForClusterDeploymentSync(ctx context.Context) error {
+	interval := spoke.resolveWaitOptions().Interval
+	clusterDeploymentName := spoke.ClusterDeployment.Definition.Name
+
+	var condition *assistedHiveV1.ClusterInstallCondition
+
+	for attempt := 0; ; attempt++ {
+		agentClusterInstall, err := spoke.AgentClusterInstall.Get()
+		if err != nil {
+			return fmt.Errorf("failed to get agentclusterinstall of spoke %s: %w", spoke.Name, err)
+		}
+
+		condition = findClusterInstallCondition(agentClusterInstall, v1beta1.ClusterSpecSyncedCondition)
+		if !reportsClusterDeploymentMissing(condition, clusterDeploymentName) {
+			return nil
+		}
+
+		if attempt == clusterDeploymentSyncRetries {
+			break
+		}
+
+		glog.V(ztpparams.ZTPLogLevel).Infof(
+			"Agentclusterinstall of spoke %s reports clusterdeployment %s missing, checking again in %s",
+			spoke.Name, clusterDeploymentName, interval)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+
+	return fmt.Errorf("agentclusterinstall %s reports clusterdeployment %s missing: %s",
+		spoke.AgentClusterInstall.Definition.Name, clusterDeploymentName, condition.Message)
+}
+
+// reportsClusterDeploymentMissing returns true when condition is a failed spec synced condition caused by the
+// clusterdeployment named clusterDeploymentName not being found.
+func reportsClusterDeploymentMissing(
+	condition *assistedHiveV1.ClusterInstallCondition, clusterDeploymentName string) bool {
+	if condition == nil || condition.Status != corev1.ConditionFalse {
+		return false
+	}
+
+	message := strings.ToLower(condition.Message)
+
+	return strings.Contains(message, "not found") && strings.Contains(message, strings.ToLower(clusterDeploymentName))
+}
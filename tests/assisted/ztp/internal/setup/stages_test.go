@@ -0,0 +1,188 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	assistedHiveV1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/hive/api/v1"
+	hivev1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/hive/api/v1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	runtimeClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// testRoundTripLatency is the latency added to every Get and Create of the test clients simulating a real hub.
+const testRoundTripLatency = 10 * time.Millisecond
+
+func TestCreateStagesConcurrent(t *testing.T) {
+	serial := StandardHAProfile(newStagedTestClient(testRoundTripLatency, nil), "spoke")
+	serial.serialCreate = true
+
+	start := time.Now()
+	_, err := serial.Create()
+	serialDuration := time.Since(start)
+	assert.Nil(t, err)
+
+	staged := StandardHAProfile(newStagedTestClient(testRoundTripLatency, nil), "spoke")
+
+	start = time.Now()
+	_, err = staged.Create()
+	stagedDuration := time.Since(start)
+	assert.Nil(t, err)
+
+	assert.ElementsMatch(t, serial.createdResources, staged.createdResources)
+	assert.True(t, staged.ClusterDeployment.Exists())
+	assert.True(t, staged.AgentClusterInstall.Exists())
+	assert.True(t, staged.InfraEnv.Exists())
+
+	// The clusterdeployment, agentclusterinstall and infraenv each take an existence check and a create, which the
+	// staged path overlaps, saving at least four round trips over the serial path.
+	assert.Less(t, stagedDuration, serialDuration-2*testRoundTripLatency,
+		"staged create took %s, serial create took %s", stagedDuration, serialDuration)
+}
+
+func TestCreateStagesJoinErrors(t *testing.T) {
+	apiClient := newStagedTestClient(0, func(obj runtimeClient.Object) error {
+		switch obj.(type) {
+		case *hivev1.ClusterDeployment:
+			return testForbiddenErr
+		case *agentInstallV1Beta1.InfraEnv:
+			return errors.New("infraenv rejected")
+		}
+
+		return nil
+	})
+
+	spoke := StandardHAProfile(apiClient, "spoke")
+
+	_, err := spoke.Create()
+	assert.ErrorIs(t, err, testForbiddenErr)
+	assert.ErrorContains(t, err, "infraenv rejected")
+	assert.Equal(t, []string{"namespace", "pull-secret", "agentclusterinstall"}, spoke.createdResources)
+	assert.False(t, spoke.AgentClusterInstall.Exists(), "agentclusterinstall is rolled back after the failures")
+}
+
+func TestWaitForClusterDeploymentSync(t *testing.T) {
+	missing := assistedHiveV1.ClusterInstallCondition{
+		Type:    v1beta1.ClusterSpecSyncedCondition,
+		Status:  corev1.ConditionFalse,
+		Message: `clusterdeployments.hive.openshift.io "spoke" not found`,
+	}
+
+	testCases := []struct {
+		condition   *assistedHiveV1.ClusterInstallCondition
+		expectedErr string
+	}{
+		{condition: nil},
+		{condition: &assistedHiveV1.ClusterInstallCondition{
+			Type: v1beta1.ClusterSpecSyncedCondition, Status: corev1.ConditionTrue}},
+		{condition: &assistedHiveV1.ClusterInstallCondition{
+			Type: v1beta1.ClusterSpecSyncedCondition, Status: corev1.ConditionFalse, Message: "invalid pull secret"}},
+		{
+			condition: &missing,
+			expectedErr: `agentclusterinstall spoke reports clusterdeployment spoke missing: ` +
+				`clusterdeployments.hive.openshift.io "spoke" not found`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		agentClusterInstall := &v1beta1.AgentClusterInstall{
+			ObjectMeta: metav1.ObjectMeta{Name: "spoke", Namespace: "spoke"},
+		}
+
+		if testCase.condition != nil {
+			agentClusterInstall.Status.Conditions = []assistedHiveV1.ClusterInstallCondition{*testCase.condition}
+		}
+
+		spoke := NewSpokeCluster(newHubTestClient(agentClusterInstall)).WithName("spoke").
+			WithDefaultClusterDeployment().WithDefaultIPv4AgentClusterInstall().
+			WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Second})
+
+		err := spoke.waitForClusterDeploymentSync(context.Background())
+
+		if testCase.expectedErr == "" {
+			assert.Nil(t, err, testCase.condition)
+		} else {
+			assert.EqualError(t, err, testCase.expectedErr)
+		}
+	}
+}
+
+func TestRunCreateStageSerial(t *testing.T) {
+	spoke := NewSpokeCluster(newTestClient())
+	spoke.serialCreate = true
+
+	var order []int
+
+	err := spoke.runCreateStage([]func() error{
+		func() error { order = append(order, 0); return errors.New("first") },
+		func() error { order = append(order, 1); return nil },
+		func() error { order = append(order, 2); return errors.New("third") },
+	})
+	assert.EqualError(t, err, "first\nthird")
+	assert.Equal(t, []int{0, 1, 2}, order)
+}
+
+func BenchmarkCreate(b *testing.B) {
+	for _, serialCreate := range []bool{true, false} {
+		name := "staged"
+		if serialCreate {
+			name = "serial"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			for range b.N {
+				spoke := StandardHAProfile(newStagedTestClient(testRoundTripLatency, nil), "spoke")
+				spoke.serialCreate = serialCreate
+
+				if _, err := spoke.Create(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// newStagedTestClient returns a test client like newHubTestClient that adds latency to every Get and Create,
+// simulating the round trips to a real hub, and fails the creations for which fail returns an error.
+func newStagedTestClient(latency time.Duration, fail func(obj runtimeClient.Object) error) *clients.Settings {
+	apiClient, testBuilder := clients.GetModifiableTestClients(clients.TestClientParams{
+		K8sMockObjects: []runtime.Object{buildDummyClusterImageSet(testHubOCPXYVersion, "", "")},
+		SchemeAttachers: []clients.SchemeAttacher{
+			v1beta1.AddToScheme,
+			agentInstallV1Beta1.AddToScheme,
+			hivev1.AddToScheme,
+		},
+	})
+
+	apiClient.Client = testBuilder.WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, client runtimeClient.WithWatch, key runtimeClient.ObjectKey,
+			obj runtimeClient.Object, opts ...runtimeClient.GetOption) error {
+			time.Sleep(latency)
+
+			return client.Get(ctx, key, obj, opts...)
+		},
+		Create: func(ctx context.Context, client runtimeClient.WithWatch,
+			obj runtimeClient.Object, opts ...runtimeClient.CreateOption) error {
+			time.Sleep(latency)
+
+			if fail != nil {
+				if err := fail(obj); err != nil {
+					return err
+				}
+			}
+
+			return client.Create(ctx, obj, opts...)
+		},
+	}).Build()
+
+	return apiClient
+}
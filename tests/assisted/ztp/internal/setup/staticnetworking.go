@@ -0,0 +1,519 @@
+package setup
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/assisted"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	"gopkg.in/yaml.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// StaticNetworkingLabel is the label key set to the spoke name on the nmstateconfigs selected by the spoke infraenv.
+const StaticNetworkingLabel = "eco-gotests/static-networking"
+
+const (
+	ipv4DefaultRoute = "0.0.0.0/0"
+	ipv6DefaultRoute = "::/0"
+)
+
+// StaticRoute is a route to Destination, a CIDR, through the NextHop address.
+type StaticRoute struct {
+	Destination string
+	NextHop     string
+}
+
+// StaticHostConfig describes the static network configuration of a single spoke host. Address is the host address
+// in CIDR notation and Gateway, when set, adds a default route through it.
+type StaticHostConfig struct {
+	Hostname      string
+	InterfaceName string
+	MACAddress    string
+	Address       string
+	Gateway       string
+	DNSServers    []string
+	Routes        []StaticRoute
+}
+
+// NMStateHostConfig describes the static network configuration WithStaticNetworkConfig applies to a spoke host.
+// ExtraInterfaces maps the names of the other interfaces of the host to their MAC addresses, so that they keep a
+// predictable name on the discovery image; they are left unconfigured.
+type NMStateHostConfig struct {
+	StaticHostConfig
+	ExtraInterfaces map[string]string
+}
+
+// WithStaticNetworkConfig adds an nmstateconfig for each host, rendering its static network configuration and
+// mapping its interfaces to their MAC addresses, and sets the label selector matching them on the infraenv, as soon
+// as it is defined. Unlike WithMinimalISOStaticNetworking, the boot method of the spoke is left unchanged.
+func (spoke *SpokeClusterResources) WithStaticNetworkConfig(hosts []NMStateHostConfig) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if err := validateStaticHosts(hosts); err != nil {
+		spoke.err = fmt.Errorf("WithStaticNetworkConfig: %w", err)
+
+		return spoke
+	}
+
+	if err := spoke.addStaticHosts(hosts); err != nil {
+		spoke.err = fmt.Errorf("WithStaticNetworkConfig: %w", err)
+	}
+
+	return spoke
+}
+
+// WithMinimalISOStaticNetworking switches the spoke to the minimal discovery ISO, so that WaitForDiscoveryISO and
+// GetISODownloadURL return the minimal ISO URL, and adds an nmstateconfig for each host, selected by the infraenv.
+// Since minimal ISO hosts download the rootfs from the hub image service once booted, every host must have a route
+// covering ZTPConfig.HubImageServiceURL when it is set. The check is made against the static configuration only and
+// does not probe the network.
+func (spoke *SpokeClusterResources) WithMinimalISOStaticNetworking(hosts []StaticHostConfig) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.InfraEnv == nil {
+		spoke.err = fmt.Errorf(
+			"WithMinimalISOStaticNetworking: infraenv must be defined before adding static networking")
+
+		return spoke
+	}
+
+	nmStateHosts := make([]NMStateHostConfig, 0, len(hosts))
+	for _, host := range hosts {
+		nmStateHosts = append(nmStateHosts, NMStateHostConfig{StaticHostConfig: host})
+	}
+
+	if err := validateStaticHosts(nmStateHosts); err != nil {
+		spoke.err = fmt.Errorf("WithMinimalISOStaticNetworking: %w", err)
+
+		return spoke
+	}
+
+	if hubConfig().HubImageServiceURL != "" {
+		if err := validateImageServiceRoutes(hubConfig().HubImageServiceURL, hosts); err != nil {
+			spoke.err = fmt.Errorf("WithMinimalISOStaticNetworking: %w", err)
+
+			return spoke
+		}
+	}
+
+	if err := spoke.addStaticHosts(nmStateHosts); err != nil {
+		spoke.err = fmt.Errorf("WithMinimalISOStaticNetworking: %w", err)
+
+		return spoke
+	}
+
+	return spoke.WithBootMethod(BootMethodMinimalISO)
+}
+
+// WithNMStateConfig adds an nmstateconfig named name in the spoke namespace with the nmstate YAML network
+// configuration and the interfaces, a map of interface names to MAC addresses, and sets the label selector matching
+// the nmstateconfigs on the infraenv. It may be called several times and before the infraenv is defined, in which
+// case the label selector is set when the spoke is created.
+func (spoke *SpokeClusterResources) WithNMStateConfig(
+	name, nmstateYAML string, interfaces map[string]string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		spoke.err = fmt.Errorf("WithNMStateConfig: invalid nmstateconfig name %q: %s", name, strings.Join(errs, ", "))
+
+		return spoke
+	}
+
+	var netConfig map[string]interface{}
+	if err := yaml.Unmarshal([]byte(nmstateYAML), &netConfig); err != nil || len(netConfig) == 0 {
+		spoke.err = fmt.Errorf(
+			"WithNMStateConfig: nmstateconfig %s requires a non-empty nmstate yaml network configuration", name)
+
+		return spoke
+	}
+
+	if len(interfaces) == 0 {
+		spoke.err = fmt.Errorf("WithNMStateConfig: nmstateconfig %s requires at least one interface", name)
+
+		return spoke
+	}
+
+	for interfaceName, macAddress := range interfaces {
+		if _, err := net.ParseMAC(macAddress); interfaceName == "" || err != nil {
+			spoke.err = fmt.Errorf("WithNMStateConfig: nmstateconfig %s interface %q has an invalid mac address %q",
+				name, interfaceName, macAddress)
+
+			return spoke
+		}
+	}
+
+	nmStateConfig := assisted.NewNmStateConfigBuilder(spoke.apiClient, name, spoke.Name)
+	if nmStateConfig == nil {
+		spoke.err = fmt.Errorf("WithNMStateConfig: failed to create nmstateconfig builder %s", name)
+
+		return spoke
+	}
+
+	nmStateConfig.Definition.Labels = map[string]string{StaticNetworkingLabel: spoke.Name}
+	nmStateConfig.Definition.Spec.NetConfig.Raw = []byte(nmstateYAML)
+
+	for _, interfaceName := range slices.Sorted(maps.Keys(interfaces)) {
+		nmStateConfig.Definition.Spec.Interfaces = append(nmStateConfig.Definition.Spec.Interfaces,
+			&agentInstallV1Beta1.Interface{Name: interfaceName, MacAddress: interfaces[interfaceName]})
+	}
+
+	if err := spoke.addNMStateConfig(nmStateConfig); err != nil {
+		spoke.err = fmt.Errorf("WithNMStateConfig: %w", err)
+	}
+
+	return spoke
+}
+
+// validateStaticHosts checks that there is at least one host, that every host is well formed and that no MAC
+// address is used by several hosts.
+func validateStaticHosts(hosts []NMStateHostConfig) error {
+	if len(hosts) == 0 {
+		return fmt.Errorf("static networking requires at least one host")
+	}
+
+	macAddresses := map[string]string{}
+
+	for _, host := range hosts {
+		if err := host.validate(); err != nil {
+			return err
+		}
+
+		for _, macAddress := range host.macAddresses() {
+			if owner, found := macAddresses[macAddress]; found {
+				return fmt.Errorf("mac address %s is used by static hosts %s and %s",
+					macAddress, owner, host.Hostname)
+			}
+
+			macAddresses[macAddress] = host.Hostname
+		}
+	}
+
+	return nil
+}
+
+// addStaticHosts adds an nmstateconfig rendering the static network configuration of each validated host and
+// records the hosts as expected to register.
+func (spoke *SpokeClusterResources) addStaticHosts(hosts []NMStateHostConfig) error {
+	for _, host := range hosts {
+		nmStateConfig, err := spoke.newNMStateConfig(host.StaticHostConfig)
+		if err != nil {
+			return err
+		}
+
+		for _, interfaceName := range slices.Sorted(maps.Keys(host.ExtraInterfaces)) {
+			nmStateConfig.Definition.Spec.Interfaces = append(nmStateConfig.Definition.Spec.Interfaces,
+				&agentInstallV1Beta1.Interface{Name: interfaceName, MacAddress: host.ExtraInterfaces[interfaceName]})
+		}
+
+		if err := spoke.addNMStateConfig(nmStateConfig); err != nil {
+			return err
+		}
+
+		spoke.expectedHosts = append(
+			spoke.expectedHosts, expectedHost{hostname: host.Hostname, macAddress: host.MACAddress})
+	}
+
+	return nil
+}
+
+// addNMStateConfig adds the nmstateconfig to the spoke, unless one with the same name is already defined, and sets
+// the label selector matching the spoke nmstateconfigs on the infraenv.
+func (spoke *SpokeClusterResources) addNMStateConfig(nmStateConfig *assisted.NmStateConfigBuilder) error {
+	for _, existing := range spoke.NMStateConfigs {
+		if existing.Definition.Name == nmStateConfig.Definition.Name {
+			return fmt.Errorf("nmstateconfig %s is already defined for spoke %s", existing.Definition.Name, spoke.Name)
+		}
+	}
+
+	spoke.NMStateConfigs = append(spoke.NMStateConfigs, nmStateConfig)
+	spoke.applyNMStateConfigSelector()
+
+	return nil
+}
+
+// applyNMStateConfigSelector sets the label selector matching the spoke nmstateconfigs on the infraenv, unless the
+// infraenv already selects nmstateconfigs.
+func (spoke *SpokeClusterResources) applyNMStateConfigSelector() {
+	if spoke.InfraEnv == nil || len(spoke.NMStateConfigs) == 0 {
+		return
+	}
+
+	selector := spoke.InfraEnv.Definition.Spec.NMStateConfigLabelSelector
+	if len(selector.MatchLabels) > 0 || len(selector.MatchExpressions) > 0 {
+		return
+	}
+
+	spoke.InfraEnv.WithNmstateConfigLabelSelector(metav1.LabelSelector{
+		MatchLabels: map[string]string{StaticNetworkingLabel: spoke.Name},
+	})
+}
+
+// validate checks that the host static network configuration is complete and well formed.
+func (host StaticHostConfig) validate() error {
+	if errs := validation.IsDNS1123Subdomain(host.Hostname); len(errs) > 0 {
+		return fmt.Errorf("invalid static host hostname %q: %s", host.Hostname, strings.Join(errs, ", "))
+	}
+
+	if host.InterfaceName == "" {
+		return fmt.Errorf("static host %s interface name cannot be empty", host.Hostname)
+	}
+
+	if _, err := net.ParseMAC(host.MACAddress); err != nil {
+		return fmt.Errorf("static host %s has an invalid mac address %q", host.Hostname, host.MACAddress)
+	}
+
+	address, _, err := net.ParseCIDR(host.Address)
+	if err != nil {
+		return fmt.Errorf("static host %s address %q must be in CIDR notation", host.Hostname, host.Address)
+	}
+
+	isIPv4 := address.To4() != nil
+
+	for _, server := range host.DNSServers {
+		if net.ParseIP(server) == nil {
+			return fmt.Errorf("static host %s has an invalid dns server %q", host.Hostname, server)
+		}
+	}
+
+	nextHops := []string{host.Gateway}
+
+	for _, route := range host.Routes {
+		destination, _, err := net.ParseCIDR(route.Destination)
+		if err != nil || (destination.To4() != nil) != isIPv4 {
+			return fmt.Errorf("static host %s route destination %q must be a CIDR of the host address family",
+				host.Hostname, route.Destination)
+		}
+
+		nextHops = append(nextHops, route.NextHop)
+	}
+
+	for index, nextHop := range nextHops {
+		if index == 0 && nextHop == "" {
+			continue
+		}
+
+		if ip := net.ParseIP(nextHop); ip == nil || (ip.To4() != nil) != isIPv4 {
+			return fmt.Errorf("static host %s next hop %q must be an address of the host address family",
+				host.Hostname, nextHop)
+		}
+	}
+
+	return nil
+}
+
+// validate checks that the host static network configuration is complete and well formed, and that its extra
+// interfaces have distinct names and valid, distinct MAC addresses.
+func (host NMStateHostConfig) validate() error {
+	if err := host.StaticHostConfig.validate(); err != nil {
+		return err
+	}
+
+	macAddresses := []string{host.MACAddress}
+
+	for interfaceName, macAddress := range host.ExtraInterfaces {
+		if interfaceName == "" || interfaceName == host.InterfaceName {
+			return fmt.Errorf("static host %s has an invalid extra interface name %q", host.Hostname, interfaceName)
+		}
+
+		if _, err := net.ParseMAC(macAddress); err != nil {
+			return fmt.Errorf("static host %s interface %s has an invalid mac address %q",
+				host.Hostname, interfaceName, macAddress)
+		}
+
+		if slices.ContainsFunc(macAddresses, func(other string) bool { return strings.EqualFold(other, macAddress) }) {
+			return fmt.Errorf("static host %s has several interfaces with mac address %s", host.Hostname, macAddress)
+		}
+
+		macAddresses = append(macAddresses, macAddress)
+	}
+
+	return nil
+}
+
+// macAddresses returns the lowercased MAC addresses of every interface of the host.
+func (host NMStateHostConfig) macAddresses() []string {
+	macAddresses := []string{strings.ToLower(host.MACAddress)}
+
+	for _, macAddress := range host.ExtraInterfaces {
+		macAddresses = append(macAddresses, strings.ToLower(macAddress))
+	}
+
+	return macAddresses
+}
+
+// validateImageServiceRoutes checks that every host has a route covering the image service. Image services
+// addressed by hostname are only reachable through a default route, since their address is unknown.
+func validateImageServiceRoutes(imageServiceURL string, hosts []StaticHostConfig) error {
+	endpoint, err := ParseEndpoint(imageServiceURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse hub image service url: %w", err)
+	}
+
+	for _, host := range hosts {
+		if !host.routesTo(net.ParseIP(endpoint.Host)) {
+			return fmt.Errorf("static host %s has no route to the hub image service %s", host.Hostname, endpoint.Host)
+		}
+	}
+
+	return nil
+}
+
+// routesTo returns true when the host network or one of its routes covers the destination. A nil destination
+// is only covered by a default route.
+func (host StaticHostConfig) routesTo(destination net.IP) bool {
+	address, network, _ := net.ParseCIDR(host.Address)
+	isIPv4 := address.To4() != nil
+
+	if destination != nil && (destination.To4() != nil) != isIPv4 {
+		return false
+	}
+
+	if host.Gateway != "" || (destination != nil && network.Contains(destination)) {
+		return true
+	}
+
+	for _, route := range host.Routes {
+		_, routeNetwork, _ := net.ParseCIDR(route.Destination)
+
+		if routeNetwork.String() == ipv4DefaultRoute || routeNetwork.String() == ipv6DefaultRoute {
+			return true
+		}
+
+		if destination != nil && routeNetwork.Contains(destination) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newNMStateConfig returns an nmstateconfig builder in the spoke namespace for the host, labeled so that it is
+// selected by the spoke infraenv.
+func (spoke *SpokeClusterResources) newNMStateConfig(host StaticHostConfig) (*assisted.NmStateConfigBuilder, error) {
+	netConfig, err := host.nmStateYAML()
+	if err != nil {
+		return nil, fmt.Errorf("failed to render nmstate config for static host %s: %w", host.Hostname, err)
+	}
+
+	nmStateConfig := assisted.NewNmStateConfigBuilder(
+		spoke.apiClient, fmt.Sprintf("%s-%s", spoke.Name, host.Hostname), spoke.Name)
+	if nmStateConfig == nil {
+		return nil, fmt.Errorf("failed to create nmstateconfig builder for static host %s", host.Hostname)
+	}
+
+	nmStateConfig.Definition.Labels = map[string]string{StaticNetworkingLabel: spoke.Name}
+	nmStateConfig.Definition.Spec.Interfaces = []*agentInstallV1Beta1.Interface{
+		{Name: host.InterfaceName, MacAddress: host.MACAddress},
+	}
+	nmStateConfig.Definition.Spec.NetConfig.Raw = netConfig
+
+	return nmStateConfig, nil
+}
+
+// nmStateYAML renders the host static network configuration as nmstate YAML.
+func (host StaticHostConfig) nmStateYAML() ([]byte, error) {
+	address, network, _ := net.ParseCIDR(host.Address)
+	prefixLength, _ := network.Mask.Size()
+
+	ipConfig := &nmStateIPConfig{
+		Enabled: true,
+		Address: []nmStateAddress{{IP: address.String(), PrefixLength: prefixLength}},
+	}
+	disabledIPConfig := &nmStateIPConfig{Enabled: false}
+
+	nmInterface := nmStateInterface{
+		Name:       host.InterfaceName,
+		Type:       "ethernet",
+		State:      "up",
+		MACAddress: host.MACAddress,
+		IPv4:       ipConfig,
+		IPv6:       disabledIPConfig,
+	}
+
+	defaultRoute := ipv4DefaultRoute
+
+	if address.To4() == nil {
+		nmInterface.IPv4, nmInterface.IPv6 = disabledIPConfig, ipConfig
+		defaultRoute = ipv6DefaultRoute
+	}
+
+	config := nmStateNetConfig{Interfaces: []nmStateInterface{nmInterface}}
+
+	if len(host.DNSServers) > 0 {
+		config.DNSResolver = &nmStateDNSResolver{Config: nmStateDNSConfig{Server: host.DNSServers}}
+	}
+
+	routes := host.Routes
+	if host.Gateway != "" {
+		routes = append([]StaticRoute{{Destination: defaultRoute, NextHop: host.Gateway}}, routes...)
+	}
+
+	if len(routes) > 0 {
+		config.Routes = &nmStateRoutes{}
+
+		for _, route := range routes {
+			config.Routes.Config = append(config.Routes.Config, nmStateRoute{
+				Destination:      route.Destination,
+				NextHopAddress:   route.NextHop,
+				NextHopInterface: host.InterfaceName,
+			})
+		}
+	}
+
+	return yaml.Marshal(config)
+}
+
+type nmStateNetConfig struct {
+	Interfaces  []nmStateInterface  `yaml:"interfaces"`
+	DNSResolver *nmStateDNSResolver `yaml:"dns-resolver,omitempty"`
+	Routes      *nmStateRoutes      `yaml:"routes,omitempty"`
+}
+
+type nmStateInterface struct {
+	Name       string           `yaml:"name"`
+	Type       string           `yaml:"type"`
+	State      string           `yaml:"state"`
+	MACAddress string           `yaml:"mac-address"`
+	IPv4       *nmStateIPConfig `yaml:"ipv4"`
+	IPv6       *nmStateIPConfig `yaml:"ipv6"`
+}
+
+type nmStateIPConfig struct {
+	Enabled bool             `yaml:"enabled"`
+	DHCP    bool             `yaml:"dhcp"`
+	Address []nmStateAddress `yaml:"address,omitempty"`
+}
+
+type nmStateAddress struct {
+	IP           string `yaml:"ip"`
+	PrefixLength int    `yaml:"prefix-length"`
+}
+
+type nmStateDNSResolver struct {
+	Config nmStateDNSConfig `yaml:"config"`
+}
+
+type nmStateDNSConfig struct {
+	Server []string `yaml:"server"`
+}
+
+type nmStateRoutes struct {
+	Config []nmStateRoute `yaml:"config"`
+}
+
+type nmStateRoute struct {
+	Destination      string `yaml:"destination"`
+	NextHopAddress   string `yaml:"next-hop-address"`
+	NextHopInterface string `yaml:"next-hop-interface"`
+}
@@ -0,0 +1,381 @@
+package setup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithMinimalISOStaticNetworking(t *testing.T) {
+	hosts := []StaticHostConfig{
+		{
+			Hostname:      "master-0",
+			InterfaceName: "eth0",
+			MACAddress:    "52:54:00:00:00:01",
+			Address:       "192.168.10.20/24",
+			Gateway:       "192.168.10.1",
+			DNSServers:    []string{"192.168.10.1"},
+			Routes:        []StaticRoute{{Destination: "10.20.0.0/16", NextHop: "192.168.10.254"}},
+		},
+		{
+			Hostname:      "master-1",
+			InterfaceName: "eth0",
+			MACAddress:    "52:54:00:00:00:02",
+			Address:       "fd2e:6f44:5dd8:1::21/64",
+			Gateway:       "fd2e:6f44:5dd8:1::1",
+		},
+	}
+
+	spoke := StandardHAProfile(newHubTestClient(), "static-spoke").WithMinimalISOStaticNetworking(hosts)
+
+	assert.Nil(t, spoke.Validate())
+	assert.Equal(t, BootMethodMinimalISO, spoke.resolveBootMethod())
+	assert.Equal(t, map[string]string{StaticNetworkingLabel: "static-spoke"},
+		spoke.InfraEnv.Definition.Spec.NMStateConfigLabelSelector.MatchLabels)
+	assert.Len(t, spoke.NMStateConfigs, 2)
+
+	for index, nmStateConfig := range spoke.NMStateConfigs {
+		assert.Equal(t, "static-spoke-"+hosts[index].Hostname, nmStateConfig.Definition.Name)
+		assert.Equal(t, "static-spoke", nmStateConfig.Definition.Namespace)
+		assert.Equal(t, map[string]string{StaticNetworkingLabel: "static-spoke"}, nmStateConfig.Definition.Labels)
+		assert.Equal(t, hosts[index].MACAddress, nmStateConfig.Definition.Spec.Interfaces[0].MacAddress)
+	}
+
+	assertGolden(t, "staticnetworking/ipv4-host.yaml", spoke.NMStateConfigs[0].Definition.Spec.NetConfig.Raw)
+	assertGolden(t, "staticnetworking/ipv6-host.yaml", spoke.NMStateConfigs[1].Definition.Spec.NetConfig.Raw)
+
+	spoke = StandardHAProfile(newTestClient(), "static-spoke").
+		WithInfraEnvCPUArchitecture(CPUArchitecturePPC64LE).
+		WithMinimalISOStaticNetworking(hosts[:1])
+	assert.EqualError(t, spoke.Validate(), "boot method minimal-iso is not supported for ppc64le hosts")
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("static-spoke").WithMinimalISOStaticNetworking(hosts)
+	assert.EqualError(t, spoke.err,
+		"WithMinimalISOStaticNetworking: infraenv must be defined before adding static networking")
+
+	spoke = StandardHAProfile(newTestClient(), "static-spoke").
+		WithMinimalISOStaticNetworking(hosts[:1]).WithMinimalISOStaticNetworking(hosts[:1])
+	assert.EqualError(t, spoke.err,
+		"WithMinimalISOStaticNetworking: nmstateconfig static-spoke-master-0 is already defined for spoke static-spoke")
+}
+
+func TestWithStaticNetworkConfig(t *testing.T) {
+	hosts := []NMStateHostConfig{
+		{
+			StaticHostConfig: StaticHostConfig{
+				Hostname:      "master-0",
+				InterfaceName: "eth0",
+				MACAddress:    "52:54:00:00:00:01",
+				Address:       "192.168.10.20/24",
+				Gateway:       "192.168.10.1",
+				DNSServers:    []string{"192.168.10.1"},
+				Routes:        []StaticRoute{{Destination: "10.20.0.0/16", NextHop: "192.168.10.254"}},
+			},
+			ExtraInterfaces: map[string]string{"eth2": "52:54:00:00:02:01", "eth1": "52:54:00:00:01:01"},
+		},
+		{
+			StaticHostConfig: StaticHostConfig{
+				Hostname:      "master-1",
+				InterfaceName: "eth0",
+				MACAddress:    "52:54:00:00:00:02",
+				Address:       "192.168.10.21/24",
+				Gateway:       "192.168.10.1",
+			},
+		},
+	}
+
+	spoke := NewSpokeCluster(newTestClient()).WithName("static-spoke").WithStaticNetworkConfig(hosts).
+		WithDefaultInfraEnv()
+	assert.Nil(t, spoke.err)
+	assert.Len(t, spoke.NMStateConfigs, 2)
+	assert.Len(t, spoke.expectedHosts, 2)
+	assert.Equal(t, BootMethodFullISO, spoke.resolveBootMethod())
+
+	interfaces := spoke.NMStateConfigs[0].Definition.Spec.Interfaces
+	assert.Len(t, interfaces, 3)
+
+	for index, expected := range [][]string{
+		{"eth0", "52:54:00:00:00:01"}, {"eth1", "52:54:00:00:01:01"}, {"eth2", "52:54:00:00:02:01"},
+	} {
+		assert.Equal(t, expected[0], interfaces[index].Name)
+		assert.Equal(t, expected[1], interfaces[index].MacAddress)
+	}
+
+	assert.Equal(t, "static-spoke-master-1", spoke.NMStateConfigs[1].Definition.Name)
+	assertGolden(t, "staticnetworking/ipv4-host.yaml", spoke.NMStateConfigs[0].Definition.Spec.NetConfig.Raw)
+
+	spoke = StandardHAProfile(newTestClient(), "static-spoke").WithStaticNetworkConfig(hosts)
+	assert.Nil(t, spoke.err)
+	assert.Equal(t, map[string]string{StaticNetworkingLabel: "static-spoke"},
+		spoke.InfraEnv.Definition.Spec.NMStateConfigLabelSelector.MatchLabels)
+
+	invalidHost := hosts[1]
+	invalidHost.ExtraInterfaces = map[string]string{"eth0": "52:54:00:00:01:02"}
+
+	duplicateHost := hosts[1]
+	duplicateHost.ExtraInterfaces = map[string]string{"eth1": "52:54:00:00:00:02"}
+
+	sharedMACHost := hosts[1]
+	sharedMACHost.ExtraInterfaces = map[string]string{"eth1": "52:54:00:00:01:01"}
+
+	testCases := []struct {
+		name          string
+		hosts         []NMStateHostConfig
+		expectedError string
+	}{
+		{name: "no hosts", expectedError: "WithStaticNetworkConfig: static networking requires at least one host"},
+		{
+			name:  "extra interface name",
+			hosts: []NMStateHostConfig{invalidHost},
+			expectedError: "WithStaticNetworkConfig: static host master-1 has an invalid extra interface " +
+				`name "eth0"`,
+		},
+		{
+			name:  "duplicate mac address in host",
+			hosts: []NMStateHostConfig{duplicateHost},
+			expectedError: "WithStaticNetworkConfig: static host master-1 has several interfaces with " +
+				"mac address 52:54:00:00:00:02",
+		},
+		{
+			name:  "mac address shared by hosts",
+			hosts: []NMStateHostConfig{hosts[0], sharedMACHost},
+			expectedError: "WithStaticNetworkConfig: mac address 52:54:00:00:01:01 is used by static hosts " +
+				"master-0 and master-1",
+		},
+		{
+			name:  "duplicate host",
+			hosts: []NMStateHostConfig{hosts[1], hosts[1]},
+			expectedError: "WithStaticNetworkConfig: mac address 52:54:00:00:00:02 is used by static hosts " +
+				"master-1 and master-1",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := NewSpokeCluster(newTestClient()).WithName("static-spoke").WithStaticNetworkConfig(testCase.hosts)
+		assert.EqualError(t, spoke.err, testCase.expectedError, testCase.name)
+	}
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("static-spoke").
+		WithStaticNetworkConfig(hosts[1:]).WithStaticNetworkConfig(hosts[1:])
+	assert.EqualError(t, spoke.err,
+		"WithStaticNetworkConfig: nmstateconfig static-spoke-master-1 is already defined for spoke static-spoke")
+}
+
+func TestWithNMStateConfig(t *testing.T) {
+	const testNMStateYAML = "interfaces:\n- name: eth0\n  type: ethernet\n  state: up\n"
+
+	spoke := NewSpokeCluster(newTestClient()).WithName("static-spoke").WithDefaultPullSecret().
+		WithNMStateConfig("master-0", testNMStateYAML, map[string]string{
+			"eth1": "52:54:00:00:01:01",
+			"eth0": "52:54:00:00:00:01",
+		}).
+		WithNMStateConfig("master-1", testNMStateYAML, map[string]string{"eth0": "52:54:00:00:00:02"}).
+		WithDefaultInfraEnv()
+	assert.Nil(t, spoke.err)
+	assert.Len(t, spoke.NMStateConfigs, 2)
+	assert.Empty(t, spoke.InfraEnv.Definition.Spec.NMStateConfigLabelSelector.MatchLabels)
+
+	interfaces := spoke.NMStateConfigs[0].Definition.Spec.Interfaces
+	assert.Equal(t, "eth0", interfaces[0].Name)
+	assert.Equal(t, "52:54:00:00:00:01", interfaces[0].MacAddress)
+	assert.Equal(t, "eth1", interfaces[1].Name)
+	assert.Equal(t, testNMStateYAML, string(spoke.NMStateConfigs[0].Definition.Spec.NetConfig.Raw))
+
+	spoke, err := spoke.Create()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{StaticNetworkingLabel: "static-spoke"},
+		spoke.InfraEnv.Object.Spec.NMStateConfigLabelSelector.MatchLabels)
+
+	for _, nmStateConfig := range spoke.NMStateConfigs {
+		assert.True(t, nmStateConfig.Exists())
+		assert.Equal(t, "static-spoke", nmStateConfig.Object.Labels[StaticNetworkingLabel])
+	}
+
+	assert.Nil(t, spoke.Delete())
+
+	for _, nmStateConfig := range spoke.NMStateConfigs {
+		assert.False(t, nmStateConfig.Exists())
+	}
+}
+
+func TestWithNMStateConfigErrors(t *testing.T) {
+	const testNMStateYAML = "interfaces:\n- name: eth0\n"
+
+	testCases := []struct {
+		name          string
+		nmstateYAML   string
+		interfaces    map[string]string
+		expectedError string
+	}{
+		{
+			name:          "Master_0",
+			nmstateYAML:   testNMStateYAML,
+			interfaces:    map[string]string{"eth0": "52:54:00:00:00:01"},
+			expectedError: "invalid nmstateconfig name \"Master_0\"",
+		},
+		{
+			name:          "master-0",
+			nmstateYAML:   "",
+			interfaces:    map[string]string{"eth0": "52:54:00:00:00:01"},
+			expectedError: "nmstateconfig master-0 requires a non-empty nmstate yaml network configuration",
+		},
+		{
+			name:          "master-0",
+			nmstateYAML:   testNMStateYAML,
+			expectedError: "nmstateconfig master-0 requires at least one interface",
+		},
+		{
+			name:          "master-0",
+			nmstateYAML:   testNMStateYAML,
+			interfaces:    map[string]string{"eth0": "52:54:00:00:00"},
+			expectedError: "nmstateconfig master-0 interface \"eth0\" has an invalid mac address \"52:54:00:00:00\"",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := NewSpokeCluster(newTestClient()).WithName("static-spoke").
+			WithNMStateConfig(testCase.name, testCase.nmstateYAML, testCase.interfaces)
+		assert.ErrorContains(t, spoke.err, testCase.expectedError)
+	}
+
+	spoke := NewSpokeCluster(newTestClient()).WithName("static-spoke").WithDefaultPullSecret().
+		WithNMStateConfig("master-0", testNMStateYAML, map[string]string{"eth0": "52:54:00:00:00:01"}).
+		WithNMStateConfig("master-0", testNMStateYAML, map[string]string{"eth0": "52:54:00:00:00:02"})
+	assert.EqualError(t, spoke.err,
+		"WithNMStateConfig: nmstateconfig master-0 is already defined for spoke static-spoke")
+
+	spoke = StandardHAProfile(newTestClient(), "static-spoke").
+		WithNMStateConfig("static-spoke-host-0", testNMStateYAML, map[string]string{"eth0": "52:54:00:00:00:01"})
+	assert.Nil(t, spoke.err)
+	assert.Equal(t, map[string]string{StaticNetworkingLabel: "static-spoke"},
+		spoke.InfraEnv.Definition.Spec.NMStateConfigLabelSelector.MatchLabels)
+
+	spoke.WithMinimalISOStaticNetworking([]StaticHostConfig{buildDummyStaticHost("192.168.254.10/24", "")})
+	assert.EqualError(t, spoke.err,
+		"WithMinimalISOStaticNetworking: nmstateconfig static-spoke-host-0 is already defined for spoke static-spoke")
+}
+
+func TestStaticHostConfigValidate(t *testing.T) {
+	testCases := []struct {
+		host        StaticHostConfig
+		expectedErr string
+	}{
+		{host: buildDummyStaticHost("192.168.10.20/24", "192.168.10.1")},
+		{host: buildDummyStaticHost("fd2e:6f44:5dd8:1::21/64", "")},
+		{
+			host:        StaticHostConfig{Hostname: "Host_0"},
+			expectedErr: `invalid static host hostname "Host_0"`,
+		},
+		{
+			host:        StaticHostConfig{Hostname: "host-0", InterfaceName: "eth0", MACAddress: "invalid"},
+			expectedErr: `static host host-0 has an invalid mac address "invalid"`,
+		},
+		{
+			host:        buildDummyStaticHost("192.168.10.20", ""),
+			expectedErr: `static host host-0 address "192.168.10.20" must be in CIDR notation`,
+		},
+		{
+			host:        buildDummyStaticHost("192.168.10.20/24", "fd2e:6f44:5dd8:1::1"),
+			expectedErr: `static host host-0 next hop "fd2e:6f44:5dd8:1::1" must be an address of the host`,
+		},
+		{
+			host: func() StaticHostConfig {
+				host := buildDummyStaticHost("192.168.10.20/24", "")
+				host.Routes = []StaticRoute{{Destination: "fd00::/8", NextHop: "192.168.10.254"}}
+
+				return host
+			}(),
+			expectedErr: `static host host-0 route destination "fd00::/8" must be a CIDR of the host address family`,
+		},
+		{
+			host: func() StaticHostConfig {
+				host := buildDummyStaticHost("192.168.10.20/24", "")
+				host.DNSServers = []string{"dns.example.com"}
+
+				return host
+			}(),
+			expectedErr: `static host host-0 has an invalid dns server "dns.example.com"`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		err := testCase.host.validate()
+
+		if testCase.expectedErr == "" {
+			assert.Nil(t, err)
+		} else {
+			assert.ErrorContains(t, err, testCase.expectedErr)
+		}
+	}
+}
+
+func TestValidateImageServiceRoutes(t *testing.T) {
+	routedHost := buildDummyStaticHost("192.168.10.20/24", "")
+	routedHost.Routes = []StaticRoute{{Destination: "10.20.0.0/16", NextHop: "192.168.10.254"}}
+
+	defaultRouteHost := buildDummyStaticHost("192.168.10.20/24", "")
+	defaultRouteHost.Routes = []StaticRoute{{Destination: "0.0.0.0/0", NextHop: "192.168.10.254"}}
+
+	testCases := []struct {
+		imageServiceURL string
+		host            StaticHostConfig
+		expectedErr     string
+	}{
+		{imageServiceURL: "https://192.168.10.5:8443/images", host: buildDummyStaticHost("192.168.10.20/24", "")},
+		{imageServiceURL: "https://10.20.1.5/images", host: routedHost},
+		{imageServiceURL: "https://10.30.1.5/images", host: defaultRouteHost},
+		{imageServiceURL: "https://10.30.1.5/images", host: buildDummyStaticHost("192.168.10.20/24", "192.168.10.1")},
+		{imageServiceURL: "https://images.hub.example.com/images", host: defaultRouteHost},
+		{
+			imageServiceURL: "https://[fd2e:6f44:5dd8:1::5]:8443/images",
+			host:            buildDummyStaticHost("fd2e:6f44:5dd8:1::21/64", ""),
+		},
+		{
+			imageServiceURL: "https://10.30.1.5/images",
+			host:            routedHost,
+			expectedErr:     "static host host-0 has no route to the hub image service 10.30.1.5",
+		},
+		{
+			imageServiceURL: "https://images.hub.example.com/images",
+			host:            routedHost,
+			expectedErr:     "static host host-0 has no route to the hub image service images.hub.example.com",
+		},
+		{
+			imageServiceURL: "https://[fd2e:6f44:5dd8:1::5]:8443/images",
+			host:            buildDummyStaticHost("192.168.10.20/24", "192.168.10.1"),
+			expectedErr:     "static host host-0 has no route to the hub image service fd2e:6f44:5dd8:1::5",
+		},
+	}
+
+	for _, testCase := range testCases {
+		err := validateImageServiceRoutes(testCase.imageServiceURL, []StaticHostConfig{testCase.host})
+
+		if testCase.expectedErr == "" {
+			assert.Nil(t, err)
+		} else {
+			assert.EqualError(t, err, testCase.expectedErr)
+		}
+	}
+
+	testZTPConfig.HubImageServiceURL = "https://10.30.1.5/images"
+
+	t.Cleanup(func() {
+		testZTPConfig.HubImageServiceURL = ""
+	})
+
+	spoke := StandardHAProfile(newTestClient(), "static-spoke").WithMinimalISOStaticNetworking(
+		[]StaticHostConfig{routedHost})
+	assert.EqualError(t, spoke.err,
+		"WithMinimalISOStaticNetworking: static host host-0 has no route to the hub image service 10.30.1.5")
+	assert.Empty(t, spoke.NMStateConfigs)
+}
+
+func buildDummyStaticHost(address, gateway string) StaticHostConfig {
+	return StaticHostConfig{
+		Hostname:      "host-0",
+		InterfaceName: "eth0",
+		MACAddress:    "52:54:00:00:00:01",
+		Address:       address,
+		Gateway:       gateway,
+	}
+}
@@ -0,0 +1,257 @@
+package setup
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	hivev1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/hive/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+var (
+	// installStatusAgentClusterInstallConditions are the agentclusterinstall conditions reported by GetInstallStatus.
+	installStatusAgentClusterInstallConditions = []string{
+		string(v1beta1.ClusterSpecSyncedCondition),
+		string(v1beta1.ClusterValidatedCondition),
+		string(v1beta1.ClusterRequirementsMetCondition),
+		string(v1beta1.ClusterCompletedCondition),
+		string(v1beta1.ClusterStoppedCondition),
+	}
+	// installStatusClusterDeploymentConditions are the clusterdeployment conditions reported by GetInstallStatus.
+	installStatusClusterDeploymentConditions = []string{
+		string(hivev1.ProvisionedCondition),
+		string(hivev1.ProvisionFailedCondition),
+		string(hivev1.ProvisionStoppedCondition),
+	}
+	// installStatusInfraEnvConditions are the infraenv conditions reported by GetInstallStatus.
+	installStatusInfraEnvConditions = []string{string(agentInstallV1Beta1.ImageCreatedCondition)}
+)
+
+// InstallStatus is a snapshot of the installation of a spoke, fetched from the hub by GetInstallStatus.
+// MastersSchedulable is whether the control plane nodes are schedulable, either requested by the agentclusterinstall
+// or forced by the assisted service for spokes with fewer than two workers.
+type InstallStatus struct {
+	AgentClusterInstall ResourceStatus
+	Progress            int64
+	MastersSchedulable  bool
+	ClusterDeployment   ResourceStatus
+	Installed           bool
+	InfraEnv            ResourceStatus
+	Agents              []AgentSummary
+	AgentsError         string
+}
+
+// ResourceStatus is the status of a spoke resource. Created is false when the resource is not built or not found
+// on the hub, and Error is set when it could not be retrieved.
+type ResourceStatus struct {
+	Name       string
+	Created    bool
+	Error      string
+	Conditions []ConditionSummary
+}
+
+// ConditionSummary is a condition of a spoke resource.
+type ConditionSummary struct {
+	Type    string
+	Status  string
+	Reason  string
+	Message string
+}
+
+// AgentSummary is the state of an agent registered to the spoke infraenvs.
+type AgentSummary struct {
+	Name      string
+	Hostname  string
+	Role      string
+	Approved  bool
+	State     string
+	StateInfo string
+}
+
+// GetInstallStatus fetches the installation related conditions of the spoke agentclusterinstall, clusterdeployment
+// and infraenv, along with the state of each agent, from the hub. Resources that are not built or do not exist are
+// reported as not created instead of failing.
+func (spoke *SpokeClusterResources) GetInstallStatus() InstallStatus {
+	var status InstallStatus
+
+	if spoke.AgentClusterInstall != nil {
+		status.AgentClusterInstall.Name = spoke.AgentClusterInstall.Definition.Name
+
+		agentClusterInstall, err := spoke.AgentClusterInstall.Get()
+		if status.AgentClusterInstall.setError(err) {
+			status.AgentClusterInstall.Conditions = filterConditions(fromClusterInstallConditions(
+				agentClusterInstall.Name, agentClusterInstall.Status.Conditions),
+				installStatusAgentClusterInstallConditions)
+			status.Progress = agentClusterInstall.Status.Progress.TotalPercentage
+			status.MastersSchedulable = mastersSchedulable(agentClusterInstall.Spec)
+		}
+	}
+
+	if spoke.ClusterDeployment != nil {
+		status.ClusterDeployment.Name = spoke.ClusterDeployment.Definition.Name
+
+		clusterDeployment, err := spoke.ClusterDeployment.Get()
+		if status.ClusterDeployment.setError(err) {
+			status.ClusterDeployment.Conditions = filterConditions(fromClusterDeploymentConditions(
+				clusterDeployment.Name, clusterDeployment.Status.Conditions), installStatusClusterDeploymentConditions)
+			status.Installed = clusterDeployment.Spec.Installed
+		}
+	}
+
+	if spoke.InfraEnv != nil {
+		status.InfraEnv.Name = spoke.InfraEnv.Definition.Name
+
+		infraEnv, err := spoke.InfraEnv.Get()
+		if status.InfraEnv.setError(err) {
+			status.InfraEnv.Conditions = filterConditions(
+				fromConditionsV1(infraEnv.Name, infraEnv.Status.Conditions), installStatusInfraEnvConditions)
+
+			spoke.addAgentSummaries(&status)
+		}
+	}
+
+	return status
+}
+
+// String renders the install status compactly, one line per resource and agent. Messages are only included for
+// conditions that are not true.
+func (status InstallStatus) String() string {
+	lines := []string{
+		status.AgentClusterInstall.render("AgentClusterInstall", fmt.Sprintf("progress %d%%", status.Progress)),
+		status.ClusterDeployment.render("ClusterDeployment", fmt.Sprintf("installed %t", status.Installed)),
+		status.InfraEnv.render("InfraEnv", ""),
+	}
+
+	if status.AgentsError != "" {
+		lines = append(lines, fmt.Sprintf("Agents: error: %s", status.AgentsError))
+	}
+
+	if status.InfraEnv.Created && len(status.Agents) == 0 && status.AgentsError == "" {
+		lines = append(lines, "Agents: none registered")
+	}
+
+	for _, agent := range status.Agents {
+		approval := "not approved"
+		if agent.Approved {
+			approval = "approved"
+		}
+
+		line := fmt.Sprintf("Agent %s (%s, %s, %s): %s", agent.Name, valueOrUnknown(agent.Hostname),
+			valueOrUnknown(agent.Role), approval, valueOrUnknown(agent.State))
+		if agent.StateInfo != "" {
+			line = fmt.Sprintf("%s: %s", line, agent.StateInfo)
+		}
+
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// addAgentSummaries adds the state of every agent registered to the spoke infraenvs to status.
+func (spoke *SpokeClusterResources) addAgentSummaries(status *InstallStatus) {
+	agents, err := spoke.listAgents()
+	if err != nil {
+		status.AgentsError = err.Error()
+
+		return
+	}
+
+	for _, agent := range agents {
+		status.Agents = append(status.Agents, AgentSummary{
+			Name:      agent.Name,
+			Hostname:  agentHostname(agent),
+			Role:      string(agent.Spec.Role),
+			Approved:  agent.Spec.Approved,
+			State:     agent.Status.DebugInfo.State,
+			StateInfo: agent.Status.DebugInfo.StateInfo,
+		})
+	}
+
+	slices.SortFunc(status.Agents, func(a, b AgentSummary) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
+// setError records err on the resource status and returns true when the resource was retrieved.
+func (resource *ResourceStatus) setError(err error) bool {
+	if err == nil {
+		resource.Created = true
+
+		return true
+	}
+
+	if !k8serrors.IsNotFound(err) {
+		resource.Error = err.Error()
+	}
+
+	return false
+}
+
+// render returns the line describing the resource of kind, followed by details when it was retrieved.
+func (resource ResourceStatus) render(kind, details string) string {
+	switch {
+	case resource.Name == "" || (!resource.Created && resource.Error == ""):
+		return fmt.Sprintf("%s: not created", kind)
+	case resource.Error != "":
+		return fmt.Sprintf("%s %s: error: %s", kind, resource.Name, resource.Error)
+	}
+
+	parts := make([]string, 0, len(resource.Conditions)+1)
+
+	for _, condition := range resource.Conditions {
+		part := fmt.Sprintf("%s=%s", condition.Type, condition.Status)
+
+		switch {
+		case condition.Status != string(corev1.ConditionTrue) && condition.Message != "":
+			part = fmt.Sprintf("%s (%s: %s)", part, condition.Reason, condition.Message)
+		case condition.Reason != "":
+			part = fmt.Sprintf("%s (%s)", part, condition.Reason)
+		}
+
+		parts = append(parts, part)
+	}
+
+	if details != "" {
+		parts = append(parts, details)
+	}
+
+	if len(parts) == 0 {
+		parts = append(parts, "no conditions")
+	}
+
+	return fmt.Sprintf("%s %s: %s", kind, resource.Name, strings.Join(parts, ", "))
+}
+
+// filterConditions returns the conditions of the listed types, in the order of condTypes.
+func filterConditions(conditions []resourceCondition, condTypes []string) []ConditionSummary {
+	var filtered []ConditionSummary
+
+	for _, condType := range condTypes {
+		for _, condition := range conditions {
+			if condition.Type == condType {
+				filtered = append(filtered, ConditionSummary{
+					Type:    condition.Type,
+					Status:  condition.Status,
+					Reason:  condition.Reason,
+					Message: condition.Message,
+				})
+			}
+		}
+	}
+
+	return filtered
+}
+
+// valueOrUnknown returns value, or unknown when it is empty.
+func valueOrUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+
+	return value
+}
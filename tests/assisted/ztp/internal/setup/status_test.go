@@ -0,0 +1,78 @@
+package setup
+
+import (
+	"testing"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	assistedHiveV1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/hive/api/v1"
+	hivev1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/hive/api/v1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetInstallStatus(t *testing.T) {
+	agentClusterInstall := &v1beta1.AgentClusterInstall{
+		ObjectMeta: metav1.ObjectMeta{Name: "spoke", Namespace: "spoke"},
+		Status: v1beta1.AgentClusterInstallStatus{
+			Conditions: []assistedHiveV1.ClusterInstallCondition{
+				{Type: v1beta1.ClusterCompletedCondition, Status: corev1.ConditionFalse,
+					Reason: v1beta1.ClusterInstallationInProgressReason, Message: "Installation in progress"},
+				{Type: v1beta1.ClusterSpecSyncedCondition, Status: corev1.ConditionTrue,
+					Reason: v1beta1.ClusterSyncedOkReason, Message: v1beta1.ClusterSyncedOkMsg},
+				{Type: "Unrelated", Status: corev1.ConditionTrue},
+			},
+			Progress: v1beta1.ClusterProgressInfo{TotalPercentage: 42},
+		},
+	}
+	clusterDeployment := &hivev1.ClusterDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "spoke", Namespace: "spoke"},
+		Status: hivev1.ClusterDeploymentStatus{
+			Conditions: []hivev1.ClusterDeploymentCondition{
+				{Type: hivev1.ProvisionedCondition, Status: corev1.ConditionFalse, Reason: "Provisioning"},
+			},
+		},
+	}
+
+	agent := buildDummyApprovableAgent("agent-0", "spoke-host-0", "52:54:00:00:00:01")
+	agent.Spec.Approved = true
+	agent.Spec.Role = "master"
+	agent.Status.DebugInfo = agentInstallV1Beta1.DebugInfo{State: "installing", StateInfo: "Writing image to disk"}
+
+	spoke := NewSpokeCluster(newTestClient(agentClusterInstall, clusterDeployment, buildDummyInfraEnvObject("spoke"),
+		buildDummyApprovableAgent("agent-1", "spoke-host-1", "52:54:00:00:00:02"), agent)).WithName("spoke").
+		WithDefaultClusterDeployment().WithDefaultIPv4AgentClusterInstall().WithDefaultInfraEnv()
+
+	status := spoke.GetInstallStatus()
+	assert.True(t, status.AgentClusterInstall.Created)
+	assert.Equal(t, int64(42), status.Progress)
+	assert.True(t, status.MastersSchedulable)
+	assert.Len(t, status.AgentClusterInstall.Conditions, 2)
+	assert.False(t, status.Installed)
+	assert.Len(t, status.Agents, 2)
+	assert.Equal(t, "AgentClusterInstall spoke: SpecSynced=True (SyncOK), "+
+		"Completed=False (InstallationInProgress: Installation in progress), progress 42%\n"+
+		"ClusterDeployment spoke: Provisioned=False (Provisioning), installed false\n"+
+		"InfraEnv spoke: no conditions\n"+
+		"Agent agent-0 (spoke-host-0, master, approved): installing: Writing image to disk\n"+
+		"Agent agent-1 (spoke-host-1, unknown, not approved): unknown", status.String())
+}
+
+func TestGetInstallStatusNotCreated(t *testing.T) {
+	status := NewSpokeCluster(newTestClient()).WithName("spoke").GetInstallStatus()
+	assert.Equal(t, "AgentClusterInstall: not created\nClusterDeployment: not created\nInfraEnv: not created",
+		status.String())
+
+	status = NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultClusterDeployment().
+		WithDefaultIPv4AgentClusterInstall().WithDefaultInfraEnv().GetInstallStatus()
+	assert.False(t, status.AgentClusterInstall.Created)
+	assert.Empty(t, status.AgentClusterInstall.Error)
+	assert.Equal(t, "AgentClusterInstall: not created\nClusterDeployment: not created\nInfraEnv: not created",
+		status.String())
+
+	status = NewSpokeCluster(newTestClient(buildDummyInfraEnvObject("spoke"))).WithName("spoke").
+		WithDefaultInfraEnv().GetInstallStatus()
+	assert.Equal(t, "AgentClusterInstall: not created\nClusterDeployment: not created\nInfraEnv spoke: no conditions\n"+
+		"Agents: none registered", status.String())
+}
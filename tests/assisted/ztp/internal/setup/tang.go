@@ -0,0 +1,204 @@
+package setup
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/deployment"
+	"github.com/openshift-kni/eco-goinfra/pkg/pod"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// DefaultTangServerImage is the tang container image used when ZTPConfig.HubTangServerImage is not set.
+	DefaultTangServerImage = "registry.redhat.io/rhel9/tang:latest"
+
+	tangServerName         = "tang"
+	tangServerPort         = 8080
+	tangServerReadyTimeout = 3 * time.Minute
+)
+
+// TangInfo contains the URL and signing key thumbprint of a tang server.
+type TangInfo struct {
+	URL        string
+	Thumbprint string
+}
+
+// DeployTangServer deploys a tang server in the existing namespace on the hub and waits for it to be ready. The
+// server is exposed through a route when the route API is available, otherwise through its service, and its
+// SHA-256 signing key thumbprint is read from the advertisement. Use DeleteTangServer to remove it.
+func DeployTangServer(apiClient *clients.Settings, namespace string) (TangInfo, error) {
+	if apiClient == nil {
+		return TangInfo{}, fmt.Errorf("apiClient cannot be nil")
+	}
+
+	_, err := newTangServerDeployment(apiClient, namespace).CreateAndWaitUntilReady(tangServerReadyTimeout)
+	if err != nil {
+		return TangInfo{}, fmt.Errorf("failed to deploy tang server: %w", err)
+	}
+
+	tangURL, err := exposeHubService(apiClient, tangServerName, namespace, tangServerPort)
+	if err != nil {
+		return TangInfo{}, err
+	}
+
+	advertisement, err := fetchTangAdvertisement(apiClient, namespace)
+	if err != nil {
+		return TangInfo{}, err
+	}
+
+	thumbprint, err := tangThumbprint(advertisement)
+	if err != nil {
+		return TangInfo{}, fmt.Errorf("failed to get tang server thumbprint: %w", err)
+	}
+
+	return TangInfo{URL: tangURL, Thumbprint: thumbprint}, nil
+}
+
+// DeleteTangServer removes the tang server deployed by DeployTangServer from the namespace.
+func DeleteTangServer(apiClient *clients.Settings, namespace string) error {
+	if apiClient == nil {
+		return fmt.Errorf("apiClient cannot be nil")
+	}
+
+	if err := deleteHubService(apiClient, tangServerName, namespace); err != nil {
+		return err
+	}
+
+	if err := newTangServerDeployment(apiClient, namespace).DeleteAndWait(tangServerReadyTimeout); err != nil {
+		return fmt.Errorf("failed to delete tang deployment: %w", err)
+	}
+
+	return nil
+}
+
+// newTangServerDeployment returns the deployment builder of the tang server in namespace.
+func newTangServerDeployment(apiClient *clients.Settings, namespace string) *deployment.Builder {
+	image := DefaultTangServerImage
+	if hubConfig().HubTangServerImage != "" {
+		image = hubConfig().HubTangServerImage
+	}
+
+	return deployment.NewBuilder(apiClient, tangServerName, namespace, map[string]string{"app": tangServerName},
+		corev1.Container{
+			Name:  tangServerName,
+			Image: image,
+			Ports: []corev1.ContainerPort{{ContainerPort: tangServerPort, Protocol: corev1.ProtocolTCP}},
+			ReadinessProbe: &corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{
+					HTTPGet: &corev1.HTTPGetAction{Path: "/adv", Port: intstr.FromInt32(tangServerPort)},
+				},
+			},
+		})
+}
+
+// fetchTangAdvertisement returns the advertisement served by the tang server pod.
+func fetchTangAdvertisement(apiClient *clients.Settings, namespace string) ([]byte, error) {
+	tangPods, err := pod.List(apiClient, namespace, metav1.ListOptions{LabelSelector: "app=" + tangServerName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tang server pods: %w", err)
+	}
+
+	if len(tangPods) == 0 {
+		return nil, fmt.Errorf("no tang server pod found in namespace %s", namespace)
+	}
+
+	output, err := tangPods[0].ExecCommand(
+		[]string{"curl", "-sf", fmt.Sprintf("http://localhost:%d/adv", tangServerPort)}, tangServerName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tang server advertisement: %w", err)
+	}
+
+	return output.Bytes(), nil
+}
+
+// tangThumbprint returns the RFC 7638 SHA-256 thumbprint of the signing key in a tang advertisement, which is a
+// JWS whose payload is the server JWK set.
+func tangThumbprint(advertisement []byte) (string, error) {
+	var jws struct {
+		Payload string `json:"payload"`
+	}
+
+	if err := json.Unmarshal(advertisement, &jws); err != nil {
+		return "", fmt.Errorf("failed to parse tang advertisement: %w", err)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(jws.Payload, "="))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode tang advertisement payload: %w", err)
+	}
+
+	var jwkSet struct {
+		Keys []map[string]interface{} `json:"keys"`
+	}
+
+	if err := json.Unmarshal(payload, &jwkSet); err != nil {
+		return "", fmt.Errorf("failed to parse tang advertisement keys: %w", err)
+	}
+
+	for _, jwk := range jwkSet.Keys {
+		if !jwkHasOperation(jwk, "verify") {
+			continue
+		}
+
+		return jwkThumbprint(jwk)
+	}
+
+	return "", fmt.Errorf("tang advertisement does not contain a signing key")
+}
+
+// jwkHasOperation returns true when the key_ops of the JWK contain operation.
+func jwkHasOperation(jwk map[string]interface{}, operation string) bool {
+	operations, _ := jwk["key_ops"].([]interface{})
+
+	for _, keyOperation := range operations {
+		if keyOperation == operation {
+			return true
+		}
+	}
+
+	return false
+}
+
+// jwkThumbprint computes the RFC 7638 SHA-256 thumbprint of the JWK from its required members.
+func jwkThumbprint(jwk map[string]interface{}) (string, error) {
+	requiredMembers := map[string][]string{
+		"EC":  {"crv", "kty", "x", "y"},
+		"RSA": {"e", "kty", "n"},
+		"OKP": {"crv", "kty", "x"},
+	}
+
+	keyType, _ := jwk["kty"].(string)
+
+	members, found := requiredMembers[keyType]
+	if !found {
+		return "", fmt.Errorf("unsupported tang key type %q", keyType)
+	}
+
+	var canonical []string
+
+	for _, member := range members {
+		value, isString := jwk[member].(string)
+		if !isString {
+			return "", fmt.Errorf("tang %s key is missing member %s", keyType, member)
+		}
+
+		encodedValue, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+
+		canonical = append(canonical, fmt.Sprintf("%q:%s", member, encodedValue))
+	}
+
+	digest := sha256.Sum256([]byte("{" + strings.Join(canonical, ",") + "}"))
+
+	return base64.RawURLEncoding.EncodeToString(digest[:]), nil
+}
@@ -0,0 +1,88 @@
+package setup
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTangServerDeployment(t *testing.T) {
+	tangDeployment := newTangServerDeployment(newTestClient(), "tang-ns")
+
+	assert.Equal(t, "tang", tangDeployment.Definition.Name)
+	assert.Equal(t, "tang-ns", tangDeployment.Definition.Namespace)
+	assert.Equal(t, map[string]string{"app": "tang"}, tangDeployment.Definition.Spec.Selector.MatchLabels)
+
+	containers := tangDeployment.Definition.Spec.Template.Spec.Containers
+	assert.Len(t, containers, 1)
+	assert.Equal(t, DefaultTangServerImage, containers[0].Image)
+	assert.Equal(t, int32(tangServerPort), containers[0].Ports[0].ContainerPort)
+	assert.Equal(t, "/adv", containers[0].ReadinessProbe.HTTPGet.Path)
+
+	testZTPConfig.HubTangServerImage = "registry.example.com/tang:test"
+
+	t.Cleanup(func() {
+		testZTPConfig.HubTangServerImage = ""
+	})
+
+	tangDeployment = newTangServerDeployment(newTestClient(), "tang-ns")
+	assert.Equal(t, "registry.example.com/tang:test", tangDeployment.Definition.Spec.Template.Spec.Containers[0].Image)
+}
+
+func TestTangThumbprint(t *testing.T) {
+	advertisement, err := os.ReadFile(filepath.Join("testdata", "tang", "advertisement.json"))
+	assert.Nil(t, err)
+
+	encode := func(payload string) []byte {
+		return []byte(`{"payload":"` + base64.RawURLEncoding.EncodeToString([]byte(payload)) + `"}`)
+	}
+
+	testCases := []struct {
+		advertisement      []byte
+		expectedThumbprint string
+		expectedErr        string
+	}{
+		{advertisement: advertisement, expectedThumbprint: "A3mZlYUhW5dLYL6iwYZWtuVvWWFKBprhjfKmh1b-uec"},
+		{
+			advertisement: encode(`{"keys":[{"kty":"EC","crv":"P-521","x":"AUFP","y":"AJOx","key_ops":["wrapKey"]}]}`),
+			expectedErr:   "tang advertisement does not contain a signing key",
+		},
+		{
+			advertisement: encode(`{"keys":[{"kty":"oct","k":"AUFP","key_ops":["verify"]}]}`),
+			expectedErr:   `unsupported tang key type "oct"`,
+		},
+		{
+			advertisement: encode(`{"keys":[{"kty":"EC","crv":"P-521","x":"AUFP","key_ops":["verify"]}]}`),
+			expectedErr:   "tang EC key is missing member y",
+		},
+		{
+			advertisement: []byte(`{"payload":"!!"}`),
+			expectedErr:   "failed to decode tang advertisement payload: illegal base64 data at input byte 0",
+		},
+		{
+			advertisement: []byte(`curl: (7) Failed to connect`),
+			expectedErr:   "failed to parse tang advertisement: invalid character 'c' looking for beginning of value",
+		},
+	}
+
+	for _, testCase := range testCases {
+		thumbprint, err := tangThumbprint(testCase.advertisement)
+
+		if testCase.expectedErr == "" {
+			assert.Nil(t, err)
+		} else {
+			assert.EqualError(t, err, testCase.expectedErr)
+		}
+
+		assert.Equal(t, testCase.expectedThumbprint, thumbprint)
+	}
+}
+
+func TestDeployTangServerNilClient(t *testing.T) {
+	_, err := DeployTangServer(nil, "tang-ns")
+	assert.EqualError(t, err, "apiClient cannot be nil")
+	assert.EqualError(t, DeleteTangServer(nil, "tang-ns"), "apiClient cannot be nil")
+}
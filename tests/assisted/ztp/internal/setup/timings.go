@@ -0,0 +1,86 @@
+package setup
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// PhaseTiming records a step of the creation, installation or deletion of a spoke. Polls is the number of polling
+// iterations of a wait, 0 for steps that do not poll, and Error is the error the step failed with, if any.
+type PhaseTiming struct {
+	Phase          string    `json:"phase"`
+	Start          time.Time `json:"start"`
+	End            time.Time `json:"end"`
+	ElapsedSeconds float64   `json:"elapsedSeconds"`
+	Polls          int       `json:"polls,omitempty"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// Elapsed returns the duration of the step.
+func (timing PhaseTiming) Elapsed() time.Duration {
+	return timing.End.Sub(timing.Start)
+}
+
+// Timings holds the steps recorded for a spoke, in the order they completed.
+type Timings struct {
+	Spoke  string        `json:"spoke"`
+	Phases []PhaseTiming `json:"phases"`
+}
+
+// JSON renders the timings as a JSON document for the reporter.
+func (timings Timings) JSON() ([]byte, error) {
+	return json.Marshal(timings)
+}
+
+// GetTimings returns the timings recorded for every resource created by Create, every wait and every resource
+// deleted by Delete since the spoke was built. Recording is automatic and the timings accumulate across calls.
+func (spoke *SpokeClusterResources) GetTimings() Timings {
+	spoke.timingsMutex.Lock()
+	defer spoke.timingsMutex.Unlock()
+
+	return Timings{Spoke: spoke.Name, Phases: slices.Clone(spoke.timings)}
+}
+
+// recordPhase records the step named phase that started at start and is completing now after polls polling
+// iterations, failing with err. It is safe to call from the concurrent creation stage of CreateWithContext.
+func (spoke *SpokeClusterResources) recordPhase(phase string, start time.Time, polls int, err error) {
+	end := time.Now()
+	timing := PhaseTiming{
+		Phase:          phase,
+		Start:          start,
+		End:            end,
+		ElapsedSeconds: end.Sub(start).Seconds(),
+		Polls:          polls,
+	}
+
+	if err != nil {
+		timing.Error = err.Error()
+	}
+
+	spoke.timingsMutex.Lock()
+	defer spoke.timingsMutex.Unlock()
+
+	spoke.timings = append(spoke.timings, timing)
+}
+
+// poll polls condition using options, recording the wait as the step named phase along with the number of
+// polling iterations.
+func (spoke *SpokeClusterResources) poll(
+	ctx context.Context, phase string, options WaitOptions, condition wait.ConditionWithContextFunc) error {
+	start := time.Now()
+	polls := 0
+
+	err := options.poll(ctx, func(ctx context.Context) (bool, error) {
+		polls++
+
+		return condition(ctx)
+	})
+
+	spoke.recordPhase(phase, start, polls, err)
+
+	return err
+}
@@ -0,0 +1,78 @@
+package setup
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTimings(t *testing.T) {
+	spoke := StandardHAProfile(newHubTestClient(), "spoke").
+		WithWaitOptions(&WaitOptions{Interval: time.Millisecond, Timeout: time.Second})
+	assert.Empty(t, spoke.GetTimings().Phases)
+
+	_, err := spoke.Create()
+	assert.Nil(t, err)
+
+	_, err = spoke.WaitForDiscoveryISO(50 * time.Millisecond)
+	assert.NotNil(t, err)
+	assert.Nil(t, spoke.Delete())
+
+	timings := spoke.GetTimings()
+	assert.Equal(t, "spoke", timings.Spoke)
+
+	phases := map[string]PhaseTiming{}
+
+	for _, timing := range timings.Phases {
+		assert.False(t, timing.End.Before(timing.Start), timing.Phase)
+		assert.Equal(t, timing.Elapsed().Seconds(), timing.ElapsedSeconds, timing.Phase)
+
+		phases[timing.Phase] = timing
+	}
+
+	for _, phase := range []string{
+		"create namespace", "create pull-secret", "create clusterdeployment", "create agentclusterinstall",
+		"create infraenv", "create", "wait for discovery iso", "delete agentclusterinstall", "delete namespace",
+		"delete",
+	} {
+		assert.Contains(t, phases, phase)
+	}
+
+	assert.Greater(t, phases["wait for discovery iso"].Polls, 1)
+	assert.NotEmpty(t, phases["wait for discovery iso"].Error)
+	assert.Zero(t, phases["create namespace"].Polls)
+	assert.Empty(t, phases["create"].Error)
+	assert.Equal(t, "delete", timings.Phases[len(timings.Phases)-1].Phase)
+
+	rendered, err := timings.JSON()
+	assert.Nil(t, err)
+
+	var decoded Timings
+
+	assert.Nil(t, json.Unmarshal(rendered, &decoded))
+	assert.Len(t, decoded.Phases, len(timings.Phases))
+	assert.Equal(t, "create namespace", decoded.Phases[0].Phase)
+}
+
+func TestRecordPhase(t *testing.T) {
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke")
+	start := time.Now().Add(-time.Second)
+
+	spoke.recordPhase("wait for agents registered", start, 3, errors.New("timed out"))
+
+	timings := spoke.GetTimings()
+	assert.Len(t, timings.Phases, 1)
+	assert.Equal(t, 3, timings.Phases[0].Polls)
+	assert.Equal(t, "timed out", timings.Phases[0].Error)
+	assert.GreaterOrEqual(t, timings.Phases[0].ElapsedSeconds, 1.0)
+
+	timings.Phases[0].Phase = "modified"
+	assert.Equal(t, "wait for agents registered", spoke.GetTimings().Phases[0].Phase)
+
+	rendered, err := NewSpokeCluster(newTestClient()).WithName("spoke").GetTimings().JSON()
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"spoke": "spoke", "phases": null}`, string(rendered))
+}
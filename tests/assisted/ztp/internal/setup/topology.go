@@ -0,0 +1,418 @@
+package setup
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+)
+
+// Networking rules enforced by the assisted service for the topology, user-managed networking and VIPs of an
+// agentclusterinstall. Validation errors cite the violated rule.
+const (
+	ruleSNORequiresUMN            = "single-node spokes require user-managed networking"
+	ruleUMNForbidsVIPs            = "spokes with user-managed networking cannot set api or ingress vips"
+	ruleUMNRequiresMachineNetwork = "multi-node spokes with user-managed networking require a machine network"
+	ruleVIPsRequired              = "multi-node spokes without user-managed networking require api and ingress vips"
+	ruleVIPsInMachineNetwork      = "api and ingress vips must be inside a machine network of their address family"
+	ruleNonePlatformRequiresUMN   = "spokes with platform type None require user-managed networking"
+)
+
+// minWorkersForUnschedulableMasters is the number of workers below which the assisted service always makes the
+// control plane nodes schedulable, whatever the agentclusterinstall requests.
+const minWorkersForUnschedulableMasters = 2
+
+// WithUserManagedNetworking enables or disables user-managed networking on the spoke agentclusterinstall, for
+// spokes whose api and ingress are served by an external load balancer. Enabling it sets the platform type to None,
+// removes the vips set by the agentclusterinstall defaults, since assisted rejects VIPs for user-managed
+// networking, and adds a default machine network for every address family of the cluster networks without one. It
+// cannot be combined with WithVIPs. Disabling it restores the default platform type.
+func (spoke *SpokeClusterResources) WithUserManagedNetworking(enabled bool) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.AgentClusterInstall == nil {
+		spoke.err = fmt.Errorf(
+			"WithUserManagedNetworking: agentclusterinstall must be defined before setting user-managed networking")
+
+		return spoke
+	}
+
+	if enabled && spoke.explicitVIPs {
+		spoke.err = fmt.Errorf("WithUserManagedNetworking: %s, vips were set using WithVIPs", ruleUMNForbidsVIPs)
+
+		return spoke
+	}
+
+	spoke.AgentClusterInstall.WithUserManagedNetworking(enabled)
+
+	spec := &spoke.AgentClusterInstall.Definition.Spec
+
+	if !enabled {
+		if spec.PlatformType == v1beta1.NonePlatformType {
+			spec.PlatformType = ""
+		}
+
+		return spoke
+	}
+
+	spec.PlatformType = v1beta1.NonePlatformType
+	spec.APIVIP, spec.APIVIPs, spec.IngressVIP, spec.IngressVIPs = "", nil, "", nil
+	spec.Networking.MachineNetwork = withDefaultMachineNetworks(
+		spec.Networking.ClusterNetwork, spec.Networking.MachineNetwork)
+
+	return spoke
+}
+
+// WithMachineNetworks replaces the machine networks of the spoke agentclusterinstall with cidrs, so that the
+// assisted service does not derive them from the host inventory of multi-homed hosts. The machine networks must
+// cover the same address families as the cluster networks, in the same order. Validate checks that the api and
+// ingress vips are inside them.
+func (spoke *SpokeClusterResources) WithMachineNetworks(cidrs ...string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.AgentClusterInstall == nil {
+		spoke.err = fmt.Errorf(
+			"WithMachineNetworks: agentclusterinstall must be defined before setting machine networks")
+
+		return spoke
+	}
+
+	if len(cidrs) == 0 {
+		spoke.err = fmt.Errorf("WithMachineNetworks: at least one machine network is required")
+
+		return spoke
+	}
+
+	machineFamilies, err := networkFamilies("machine", cidrs)
+	if err != nil {
+		spoke.err = fmt.Errorf("WithMachineNetworks: %w", err)
+
+		return spoke
+	}
+
+	networking := &spoke.AgentClusterInstall.Definition.Spec.Networking
+
+	var clusterCIDRs []string
+
+	for _, clusterNetwork := range networking.ClusterNetwork {
+		clusterCIDRs = append(clusterCIDRs, clusterNetwork.CIDR)
+	}
+
+	clusterFamilies, err := networkFamilies("cluster", clusterCIDRs)
+	if err != nil {
+		spoke.err = fmt.Errorf("WithMachineNetworks: %w", err)
+
+		return spoke
+	}
+
+	if !slices.Equal(clusterFamilies, machineFamilies) {
+		spoke.err = fmt.Errorf("WithMachineNetworks: machine networks %v are %s but cluster networks are %s",
+			cidrs, strings.Join(machineFamilies, "+"), strings.Join(clusterFamilies, "+"))
+
+		return spoke
+	}
+
+	networking.MachineNetwork = nil
+
+	for _, cidr := range cidrs {
+		networking.MachineNetwork = append(networking.MachineNetwork, v1beta1.MachineNetworkEntry{CIDR: cidr})
+	}
+
+	return spoke
+}
+
+// WithClusterNetwork sets the cluster network of the address family of cidr on the spoke agentclusterinstall,
+// replacing the existing one of that family or adding it, so calling it once per family builds dual-stack
+// networking. The host prefix must be longer than the prefix of cidr. Validate checks that the cluster, service and
+// machine networks cover the same address families.
+func (spoke *SpokeClusterResources) WithClusterNetwork(cidr string, hostPrefix int32) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.AgentClusterInstall == nil {
+		spoke.err = fmt.Errorf(
+			"WithClusterNetwork: agentclusterinstall must be defined before setting the cluster network")
+
+		return spoke
+	}
+
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		spoke.err = fmt.Errorf("WithClusterNetwork: invalid agentclusterinstall cluster network %q", cidr)
+
+		return spoke
+	}
+
+	ones, bits := network.Mask.Size()
+	if int(hostPrefix) <= ones || int(hostPrefix) > bits {
+		spoke.err = fmt.Errorf("WithClusterNetwork: host prefix %d of cluster network %s must be between %d and %d",
+			hostPrefix, cidr, ones+1, bits)
+
+		return spoke
+	}
+
+	networking := &spoke.AgentClusterInstall.Definition.Spec.Networking
+	entry := v1beta1.ClusterNetworkEntry{CIDR: cidr, HostPrefix: hostPrefix}
+
+	index := slices.IndexFunc(networking.ClusterNetwork, func(clusterNetwork v1beta1.ClusterNetworkEntry) bool {
+		return sameNetworkFamily(clusterNetwork.CIDR, cidr)
+	})
+	if index < 0 {
+		networking.ClusterNetwork = append(networking.ClusterNetwork, entry)
+	} else {
+		networking.ClusterNetwork[index] = entry
+	}
+
+	return spoke
+}
+
+// WithServiceNetwork sets the service network of the address family of cidr on the spoke agentclusterinstall,
+// replacing the existing one of that family or adding it.
+func (spoke *SpokeClusterResources) WithServiceNetwork(cidr string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.AgentClusterInstall == nil {
+		spoke.err = fmt.Errorf(
+			"WithServiceNetwork: agentclusterinstall must be defined before setting the service network")
+
+		return spoke
+	}
+
+	if _, err := networkFamilies("service", []string{cidr}); err != nil {
+		spoke.err = fmt.Errorf("WithServiceNetwork: %w", err)
+
+		return spoke
+	}
+
+	networking := &spoke.AgentClusterInstall.Definition.Spec.Networking
+
+	index := slices.IndexFunc(networking.ServiceNetwork, func(serviceNetwork string) bool {
+		return sameNetworkFamily(serviceNetwork, cidr)
+	})
+	if index < 0 {
+		networking.ServiceNetwork = append(networking.ServiceNetwork, cidr)
+	} else {
+		networking.ServiceNetwork[index] = cidr
+	}
+
+	return spoke
+}
+
+// WithMachineNetwork sets the machine network of the address family of cidr on the spoke agentclusterinstall,
+// replacing the existing one of that family or adding it. Unlike WithMachineNetworks, the other machine networks are
+// kept and the address families are only checked by Validate.
+func (spoke *SpokeClusterResources) WithMachineNetwork(cidr string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.AgentClusterInstall == nil {
+		spoke.err = fmt.Errorf(
+			"WithMachineNetwork: agentclusterinstall must be defined before setting the machine network")
+
+		return spoke
+	}
+
+	if _, err := networkFamilies("machine", []string{cidr}); err != nil {
+		spoke.err = fmt.Errorf("WithMachineNetwork: %w", err)
+
+		return spoke
+	}
+
+	networking := &spoke.AgentClusterInstall.Definition.Spec.Networking
+	entry := v1beta1.MachineNetworkEntry{CIDR: cidr}
+
+	index := slices.IndexFunc(networking.MachineNetwork, func(machineNetwork v1beta1.MachineNetworkEntry) bool {
+		return sameNetworkFamily(machineNetwork.CIDR, cidr)
+	})
+	if index < 0 {
+		networking.MachineNetwork = append(networking.MachineNetwork, entry)
+	} else {
+		networking.MachineNetwork[index] = entry
+	}
+
+	return spoke
+}
+
+// sameNetworkFamily returns true when both cidrs are valid and of the same address family.
+func sameNetworkFamily(cidr, other string) bool {
+	families, err := networkFamilies("", []string{cidr, other})
+
+	return err == nil && len(families) == 1
+}
+
+// WithSchedulableMasters sets whether workloads can be scheduled on the control plane nodes of the spoke. When it is
+// not called, mastersSchedulable is left unset and the assisted service decides from the worker count. Disabling it
+// fails the spoke when the agentclusterinstall has fewer than two workers, as the assisted service then always makes
+// the control plane nodes schedulable; Validate checks it again against the final worker count.
+func (spoke *SpokeClusterResources) WithSchedulableMasters(enabled bool) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.AgentClusterInstall == nil {
+		spoke.err = fmt.Errorf(
+			"WithSchedulableMasters: agentclusterinstall must be defined before setting schedulable masters")
+
+		return spoke
+	}
+
+	spoke.schedulableMasters = &enabled
+	spoke.AgentClusterInstall.Definition.Spec.MastersSchedulable = enabled
+
+	if err := spoke.validateSchedulableMasters(); err != nil {
+		spoke.err = fmt.Errorf("WithSchedulableMasters: %w", err)
+	}
+
+	return spoke
+}
+
+// validateSchedulableMasters checks that schedulable masters are not disabled using WithSchedulableMasters on an
+// agentclusterinstall whose worker count makes the assisted service ignore it.
+func (spoke *SpokeClusterResources) validateSchedulableMasters() error {
+	if spoke.AgentClusterInstall == nil || spoke.schedulableMasters == nil || *spoke.schedulableMasters {
+		return nil
+	}
+
+	workers := spoke.AgentClusterInstall.Definition.Spec.ProvisionRequirements.WorkerAgents
+	if workers < minWorkersForUnschedulableMasters {
+		return fmt.Errorf("cannot disable schedulable masters of agentclusterinstall %s with %d workers, "+
+			"control plane nodes of spokes with fewer than %d workers are always schedulable",
+			spoke.AgentClusterInstall.Definition.Name, workers, minWorkersForUnschedulableMasters)
+	}
+
+	return nil
+}
+
+// mastersSchedulable returns whether the assisted service makes the control plane nodes described by spec
+// schedulable.
+func mastersSchedulable(spec v1beta1.AgentClusterInstallSpec) bool {
+	return spec.MastersSchedulable || spec.ProvisionRequirements.WorkerAgents < minWorkersForUnschedulableMasters
+}
+
+// userManagedNetworking returns true when user-managed networking is enabled on the spoke agentclusterinstall.
+func (spoke *SpokeClusterResources) userManagedNetworking() bool {
+	if spoke.AgentClusterInstall == nil {
+		return false
+	}
+
+	userManaged := spoke.AgentClusterInstall.Definition.Spec.Networking.UserManagedNetworking
+
+	return userManaged != nil && *userManaged
+}
+
+// withDefaultMachineNetworks returns machineNetworks with the default machine network appended for every address
+// family of clusterNetworks that has no machine network, in the order of the cluster networks.
+func withDefaultMachineNetworks(
+	clusterNetworks []v1beta1.ClusterNetworkEntry,
+	machineNetworks []v1beta1.MachineNetworkEntry) []v1beta1.MachineNetworkEntry {
+	hasFamily := func(ipv4 bool) bool {
+		return slices.ContainsFunc(machineNetworks, func(machineNetwork v1beta1.MachineNetworkEntry) bool {
+			return isIPv4CIDR(machineNetwork.CIDR) == ipv4
+		})
+	}
+
+	for _, clusterNetwork := range clusterNetworks {
+		ipv4 := isIPv4CIDR(clusterNetwork.CIDR)
+		if hasFamily(ipv4) {
+			continue
+		}
+
+		cidr := defaultIPv6MachineCIDR
+		if ipv4 {
+			cidr = defaultIPv4MachineCIDR
+		}
+
+		machineNetworks = append(machineNetworks, v1beta1.MachineNetworkEntry{CIDR: cidr})
+	}
+
+	return machineNetworks
+}
+
+// isIPv4CIDR returns true when cidr is an IPv4 network.
+func isIPv4CIDR(cidr string) bool {
+	ip, _, _ := strings.Cut(cidr, "/")
+
+	return isIPv4Address(ip)
+}
+
+// validateNetworkingTopology checks the agentclusterinstall against the topology and user-managed networking rules.
+func (spoke *SpokeClusterResources) validateNetworkingTopology() error {
+	if spoke.AgentClusterInstall == nil {
+		return nil
+	}
+
+	spec := spoke.AgentClusterInstall.Definition.Spec
+	singleNode := spec.ProvisionRequirements.ControlPlaneAgents == 1 && spec.ProvisionRequirements.WorkerAgents == 0
+	userManaged := spec.Networking.UserManagedNetworking != nil && *spec.Networking.UserManagedNetworking
+	hasAPIVIP := spec.APIVIP != "" || len(spec.APIVIPs) > 0
+	hasIngressVIP := spec.IngressVIP != "" || len(spec.IngressVIPs) > 0
+
+	switch {
+	case singleNode && !userManaged:
+		return fmt.Errorf("invalid agentclusterinstall networking: %s", ruleSNORequiresUMN)
+	case spec.PlatformType == v1beta1.NonePlatformType && !userManaged:
+		return fmt.Errorf("invalid agentclusterinstall networking: %s", ruleNonePlatformRequiresUMN)
+	case userManaged && (hasAPIVIP || hasIngressVIP):
+		return fmt.Errorf("invalid agentclusterinstall networking: %s", ruleUMNForbidsVIPs)
+	case !singleNode && userManaged && len(spec.Networking.MachineNetwork) == 0:
+		return fmt.Errorf("invalid agentclusterinstall networking: %s", ruleUMNRequiresMachineNetwork)
+	case !singleNode && !userManaged && (!hasAPIVIP || !hasIngressVIP):
+		return fmt.Errorf("invalid agentclusterinstall networking: %s", ruleVIPsRequired)
+	}
+
+	return validateVIPMachineNetworks(spec.Networking.MachineNetwork,
+		slices.Concat([]string{spec.APIVIP, spec.IngressVIP}, spec.APIVIPs, spec.IngressVIPs))
+}
+
+// validateVIPMachineNetworks checks that every vip is inside one of the machine networks. VIPs are not checked
+// when no machine network is set, since the assisted service then derives it from the host inventory.
+func validateVIPMachineNetworks(machineNetworks []v1beta1.MachineNetworkEntry, vips []string) error {
+	if len(machineNetworks) == 0 {
+		return nil
+	}
+
+	var networks []*net.IPNet
+
+	for _, machineNetwork := range machineNetworks {
+		_, network, err := net.ParseCIDR(machineNetwork.CIDR)
+		if err != nil {
+			return fmt.Errorf("invalid agentclusterinstall machine network %q", machineNetwork.CIDR)
+		}
+
+		networks = append(networks, network)
+	}
+
+	for _, vip := range vips {
+		if vip == "" {
+			continue
+		}
+
+		if !slices.ContainsFunc(networks, func(network *net.IPNet) bool { return network.Contains(net.ParseIP(vip)) }) {
+			return fmt.Errorf("invalid agentclusterinstall networking: %s, vip %s is outside %v",
+				ruleVIPsInMachineNetwork, vip, machineNetworkCIDRs(machineNetworks))
+		}
+	}
+
+	return nil
+}
+
+// machineNetworkCIDRs returns the CIDRs of the machine networks.
+func machineNetworkCIDRs(machineNetworks []v1beta1.MachineNetworkEntry) []string {
+	cidrs := make([]string, 0, len(machineNetworks))
+
+	for _, machineNetwork := range machineNetworks {
+		cidrs = append(cidrs, machineNetwork.CIDR)
+	}
+
+	return cidrs
+}
@@ -0,0 +1,445 @@
+package setup
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateNetworkingTopology(t *testing.T) {
+	topologies := []struct {
+		name               string
+		controlPlaneAgents int
+		workerAgents       int
+	}{
+		{name: "sno", controlPlaneAgents: 1, workerAgents: 0},
+		{name: "compact", controlPlaneAgents: 3, workerAgents: 0},
+		{name: "ha", controlPlaneAgents: 3, workerAgents: 2},
+	}
+
+	expectedErrs := map[string]string{
+		"sno/umn=true/vips=false":      "",
+		"sno/umn=true/vips=true":       ruleUMNForbidsVIPs,
+		"sno/umn=false/vips=false":     ruleSNORequiresUMN,
+		"sno/umn=false/vips=true":      ruleSNORequiresUMN,
+		"compact/umn=true/vips=false":  "",
+		"compact/umn=true/vips=true":   ruleUMNForbidsVIPs,
+		"compact/umn=false/vips=false": ruleVIPsRequired,
+		"compact/umn=false/vips=true":  "",
+		"ha/umn=true/vips=false":       "",
+		"ha/umn=true/vips=true":        ruleUMNForbidsVIPs,
+		"ha/umn=false/vips=false":      ruleVIPsRequired,
+		"ha/umn=false/vips=true":       "",
+	}
+
+	for _, topology := range topologies {
+		for _, userManaged := range []bool{true, false} {
+			for _, withVIPs := range []bool{true, false} {
+				caseName := fmt.Sprintf("%s/umn=%t/vips=%t", topology.name, userManaged, withVIPs)
+
+				networking := defaultIPv4Networking()
+				networking.MachineNetwork = []v1beta1.MachineNetworkEntry{{CIDR: "192.168.254.0/24"}}
+
+				spoke := newProfile(newHubTestClient(), "topology-spoke")
+				spoke.AgentClusterInstall = spoke.newAgentClusterInstall(
+					topology.controlPlaneAgents, topology.workerAgents, networking).
+					WithUserManagedNetworking(userManaged)
+
+				if withVIPs {
+					spoke.AgentClusterInstall.WithAPIVip("192.168.254.5").WithIngressVip("192.168.254.10")
+				}
+
+				err := spoke.validateNetworkingTopology()
+
+				if expectedErrs[caseName] == "" {
+					assert.Nil(t, err, caseName)
+				} else {
+					assert.EqualError(t, err,
+						"invalid agentclusterinstall networking: "+expectedErrs[caseName], caseName)
+				}
+			}
+		}
+	}
+}
+
+func TestValidateNetworkingTopologyVIPs(t *testing.T) {
+	spoke := StandardHAProfile(newHubTestClient(), "topology-spoke")
+	spoke.AgentClusterInstall.Definition.Spec.IngressVIP = ""
+	assert.EqualError(t, spoke.Validate(), "invalid agentclusterinstall networking: "+ruleVIPsRequired)
+
+	spoke.AgentClusterInstall.Definition.Spec.IngressVIPs = []string{"192.168.254.10"}
+	assert.Nil(t, spoke.Validate())
+
+	spoke.WithUserManagedNetworking(true)
+	assert.Nil(t, spoke.Validate())
+
+	spoke.AgentClusterInstall.Definition.Spec.Networking.MachineNetwork = nil
+	assert.EqualError(t, spoke.Validate(), "invalid agentclusterinstall networking: "+ruleUMNRequiresMachineNetwork)
+
+	spoke.AgentClusterInstall.Definition.Spec.Networking.UserManagedNetworking = nil
+	spoke.AgentClusterInstall.Definition.Spec.IngressVIPs = []string{"192.168.254.10"}
+	spoke.AgentClusterInstall.Definition.Spec.APIVIPs = []string{"192.168.254.5"}
+	assert.EqualError(t, spoke.Validate(), "invalid agentclusterinstall networking: "+ruleNonePlatformRequiresUMN)
+}
+
+func TestValidateVIPMachineNetworks(t *testing.T) {
+	testCases := []struct {
+		machineNetworks []string
+		vips            []string
+		expectedError   string
+	}{
+		{machineNetworks: nil, vips: []string{"10.0.0.5"}},
+		{machineNetworks: []string{"192.168.254.0/24"}, vips: []string{"192.168.254.5", "", "192.168.254.10"}},
+		{
+			machineNetworks: []string{"192.168.254.0/24", "fd2e:6f44:5dd8:1::/64"},
+			vips:            []string{"192.168.254.5", "fd2e:6f44:5dd8:1::5"},
+		},
+		{
+			machineNetworks: []string{"192.168.254.0/24"},
+			vips:            []string{"192.168.254.5", "10.0.0.10"},
+			expectedError: "invalid agentclusterinstall networking: " + ruleVIPsInMachineNetwork +
+				", vip 10.0.0.10 is outside [192.168.254.0/24]",
+		},
+		{
+			machineNetworks: []string{"192.168.254.0/33"},
+			vips:            []string{"192.168.254.5"},
+			expectedError:   "invalid agentclusterinstall machine network \"192.168.254.0/33\"",
+		},
+	}
+
+	for _, testCase := range testCases {
+		var machineNetworks []v1beta1.MachineNetworkEntry
+
+		for _, cidr := range testCase.machineNetworks {
+			machineNetworks = append(machineNetworks, v1beta1.MachineNetworkEntry{CIDR: cidr})
+		}
+
+		err := validateVIPMachineNetworks(machineNetworks, testCase.vips)
+
+		if testCase.expectedError == "" {
+			assert.Nil(t, err)
+		} else {
+			assert.EqualError(t, err, testCase.expectedError)
+		}
+	}
+}
+
+func TestConfiguredAgentClusterInstallNetworking(t *testing.T) {
+	testZTPConfig.SpokeAPIVIP = "10.1.0.5"
+	testZTPConfig.SpokeIngressVIP = "10.1.0.10"
+	testZTPConfig.SpokeMachineCIDR = "10.1.0.0/24"
+	testZTPConfig.SpokeClusterCIDR = "10.132.0.0/14"
+	testZTPConfig.SpokeServiceCIDR = "172.31.0.0/16"
+	testZTPConfig.SpokeIPv6APIVIP = "fd00:1::5"
+	testZTPConfig.SpokeIPv6IngressVIP = "fd00:1::10"
+	testZTPConfig.SpokeIPv6MachineCIDR = "fd00:1::/64"
+
+	defer func() {
+		testZTPConfig.SpokeAPIVIP, testZTPConfig.SpokeIngressVIP, testZTPConfig.SpokeMachineCIDR = "", "", ""
+		testZTPConfig.SpokeClusterCIDR, testZTPConfig.SpokeServiceCIDR = "", ""
+		testZTPConfig.SpokeIPv6APIVIP, testZTPConfig.SpokeIPv6IngressVIP = "", ""
+		testZTPConfig.SpokeIPv6MachineCIDR = ""
+	}()
+
+	spoke := DualStackProfile(newHubTestClient(), "topology-spoke")
+	assert.Nil(t, spoke.Validate())
+
+	spec := spoke.AgentClusterInstall.Definition.Spec
+	assert.Equal(t, []string{"10.1.0.5", "fd00:1::5"}, spec.APIVIPs)
+	assert.Equal(t, []string{"10.1.0.10", "fd00:1::10"}, spec.IngressVIPs)
+	assert.Equal(t, []v1beta1.MachineNetworkEntry{{CIDR: "10.1.0.0/24"}, {CIDR: "fd00:1::/64"}},
+		spec.Networking.MachineNetwork)
+	assert.Equal(t, []v1beta1.ClusterNetworkEntry{
+		{CIDR: "10.132.0.0/14", HostPrefix: 23}, {CIDR: "fd01::/48", HostPrefix: 64}}, spec.Networking.ClusterNetwork)
+	assert.Equal(t, []string{"172.31.0.0/16", "fd02::/112"}, spec.Networking.ServiceNetwork)
+
+	testZTPConfig.SpokeAPIVIP = "10.2.0.5"
+
+	spoke = StandardHAProfile(newHubTestClient(), "topology-spoke")
+	assert.EqualError(t, spoke.Validate(), "invalid agentclusterinstall networking: "+ruleVIPsInMachineNetwork+
+		", vip 10.2.0.5 is outside [10.1.0.0/24]")
+}
+
+func TestWithUserManagedNetworking(t *testing.T) {
+	spoke := DualStackProfile(newHubTestClient(), "topology-spoke").WithUserManagedNetworking(true)
+	spoke.AgentClusterInstall.Definition.Spec.Networking.MachineNetwork = []v1beta1.MachineNetworkEntry{
+		{CIDR: "192.168.254.0/24"}, {CIDR: "fd2e:6f44:5dd8:1::/64"},
+	}
+
+	spec := spoke.AgentClusterInstall.Definition.Spec
+	assert.Nil(t, spoke.Validate())
+	assert.True(t, *spec.Networking.UserManagedNetworking)
+	assert.Empty(t, spec.APIVIP)
+	assert.Empty(t, spec.APIVIPs)
+	assert.Empty(t, spec.IngressVIP)
+	assert.Empty(t, spec.IngressVIPs)
+
+	spoke = StandardHAProfile(newHubTestClient(), "topology-spoke").WithUserManagedNetworking(false)
+	assert.Nil(t, spoke.Validate())
+	assert.Equal(t, "192.168.254.5", spoke.AgentClusterInstall.Definition.Spec.APIVIP)
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("topology-spoke").WithUserManagedNetworking(true)
+	assert.EqualError(t, spoke.err,
+		"WithUserManagedNetworking: agentclusterinstall must be defined before setting user-managed networking")
+}
+
+func TestWithUserManagedNetworkingTopologies(t *testing.T) {
+	testCases := []struct {
+		name                    string
+		withAgentClusterInstall func(*SpokeClusterResources) *SpokeClusterResources
+		expectedMachineNetworks []v1beta1.MachineNetworkEntry
+	}{
+		{
+			name:                    "ipv4",
+			withAgentClusterInstall: (*SpokeClusterResources).WithDefaultIPv4AgentClusterInstall,
+			expectedMachineNetworks: []v1beta1.MachineNetworkEntry{{CIDR: defaultIPv4MachineCIDR}},
+		},
+		{
+			name:                    "ipv6",
+			withAgentClusterInstall: (*SpokeClusterResources).WithDefaultIPv6AgentClusterInstall,
+			expectedMachineNetworks: []v1beta1.MachineNetworkEntry{{CIDR: defaultIPv6MachineCIDR}},
+		},
+		{
+			name:                    "dual-stack",
+			withAgentClusterInstall: (*SpokeClusterResources).WithDefaultDualStackAgentClusterInstall,
+			expectedMachineNetworks: []v1beta1.MachineNetworkEntry{
+				{CIDR: defaultIPv4MachineCIDR}, {CIDR: defaultIPv6MachineCIDR},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		for _, counts := range [][2]int{{1, 0}, {3, 0}, {3, 2}} {
+			caseName := fmt.Sprintf("%s/%d+%d", testCase.name, counts[0], counts[1])
+
+			spoke := testCase.withAgentClusterInstall(newProfile(newHubTestClient(), "topology-spoke")).
+				WithAgentCounts(counts[0], counts[1]).WithUserManagedNetworking(true)
+			assert.Nil(t, spoke.err, caseName)
+			assert.Nil(t, spoke.Validate(), caseName)
+
+			spec := spoke.AgentClusterInstall.Definition.Spec
+			assert.Equal(t, v1beta1.NonePlatformType, spec.PlatformType, caseName)
+			assert.Equal(t, testCase.expectedMachineNetworks, spec.Networking.MachineNetwork, caseName)
+			assert.Empty(t, spec.APIVIPs, caseName)
+			assert.Empty(t, spec.IngressVIP, caseName)
+		}
+	}
+
+	spoke := StandardHAProfile(newHubTestClient(), "topology-spoke").WithUserManagedNetworking(true).
+		WithUserManagedNetworking(false)
+	assert.Empty(t, spoke.AgentClusterInstall.Definition.Spec.PlatformType)
+}
+
+func TestWithUserManagedNetworkingVIPsConflict(t *testing.T) {
+	spoke := StandardHAProfile(newHubTestClient(), "topology-spoke").
+		WithVIPs([]string{"192.168.254.5"}, []string{"192.168.254.10"}).WithUserManagedNetworking(true)
+	assert.EqualError(t, spoke.err,
+		"WithUserManagedNetworking: "+ruleUMNForbidsVIPs+", vips were set using WithVIPs")
+
+	spoke = StandardHAProfile(newHubTestClient(), "topology-spoke").WithUserManagedNetworking(true).
+		WithVIPs([]string{"192.168.254.5"}, []string{"192.168.254.10"})
+	assert.EqualError(t, spoke.err, "WithVIPs: "+ruleUMNForbidsVIPs)
+
+	spoke = StandardHAProfile(newHubTestClient(), "topology-spoke").
+		WithVIPs([]string{"192.168.254.5"}, []string{"192.168.254.10"}).WithDefaultSNOAgentClusterInstall()
+	assert.Nil(t, spoke.err)
+}
+
+func TestWithMachineNetworks(t *testing.T) {
+	testCases := []struct {
+		name        string
+		spoke       func() *SpokeClusterResources
+		cidrs       []string
+		expectedErr string
+	}{
+		{
+			name:  "ipv4",
+			spoke: NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultIPv4AgentClusterInstall,
+			cidrs: []string{"192.168.10.0/24"},
+		},
+		{
+			name:  "dual-stack",
+			spoke: NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultDualStackAgentClusterInstall,
+			cidrs: []string{"192.168.10.0/24", "fd2e:6f44:5dd8:2::/64"},
+		},
+		{
+			name:  "ipv4 with ipv6 machine network",
+			spoke: NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultIPv4AgentClusterInstall,
+			cidrs: []string{"fd2e:6f44:5dd8:2::/64"},
+			expectedErr: "WithMachineNetworks: machine networks [fd2e:6f44:5dd8:2::/64] are IPv6 " +
+				"but cluster networks are IPv4",
+		},
+		{
+			name:  "dual-stack families reversed",
+			spoke: NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultDualStackAgentClusterInstall,
+			cidrs: []string{"fd2e:6f44:5dd8:2::/64", "192.168.10.0/24"},
+			expectedErr: "WithMachineNetworks: machine networks [fd2e:6f44:5dd8:2::/64 192.168.10.0/24] are " +
+				"IPv6+IPv4 but cluster networks are IPv4+IPv6",
+		},
+		{
+			name:        "invalid cidr",
+			spoke:       NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultIPv4AgentClusterInstall,
+			cidrs:       []string{"192.168.10.0"},
+			expectedErr: `WithMachineNetworks: invalid agentclusterinstall machine network "192.168.10.0"`,
+		},
+		{
+			name:        "no cidrs",
+			spoke:       NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultIPv4AgentClusterInstall,
+			expectedErr: "WithMachineNetworks: at least one machine network is required",
+		},
+		{
+			name:        "missing agentclusterinstall",
+			spoke:       NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultNamespace,
+			cidrs:       []string{"192.168.10.0/24"},
+			expectedErr: "WithMachineNetworks: agentclusterinstall must be defined before setting machine networks",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := testCase.spoke().WithMachineNetworks(testCase.cidrs...)
+
+		if testCase.expectedErr != "" {
+			assert.EqualError(t, spoke.err, testCase.expectedErr, testCase.name)
+
+			continue
+		}
+
+		assert.Nil(t, spoke.err, testCase.name)
+		assert.Equal(t, testCase.cidrs,
+			machineNetworkCIDRs(spoke.AgentClusterInstall.Definition.Spec.Networking.MachineNetwork), testCase.name)
+	}
+}
+
+func TestWithMachineNetworksVIPs(t *testing.T) {
+	spoke := StandardHAProfile(newHubTestClient(), "topology-spoke").WithMachineNetworks("10.0.0.0/24")
+	assert.Nil(t, spoke.err)
+
+	_, err := spoke.Create()
+	assert.ErrorContains(t, err, "invalid agentclusterinstall networking: "+ruleVIPsInMachineNetwork)
+
+	spoke = StandardHAProfile(newHubTestClient(), "topology-spoke").WithMachineNetworks("10.0.0.0/24").
+		WithVIPs([]string{"10.0.0.5"}, []string{"10.0.0.10"})
+	assert.Nil(t, spoke.Validate())
+}
+
+func TestWithSchedulableMasters(t *testing.T) {
+	testCases := []struct {
+		name          string
+		spoke         *SpokeClusterResources
+		enabled       bool
+		expectedError string
+	}{
+		{name: "compact enabled", spoke: CompactProfile(newHubTestClient(), "spoke"), enabled: true},
+		{name: "ha enabled", spoke: StandardHAProfile(newHubTestClient(), "spoke"), enabled: true},
+		{name: "ha disabled", spoke: StandardHAProfile(newHubTestClient(), "spoke")},
+		{
+			name:  "compact disabled",
+			spoke: CompactProfile(newHubTestClient(), "spoke"),
+			expectedError: "WithSchedulableMasters: cannot disable schedulable masters of agentclusterinstall spoke " +
+				"with 0 workers, control plane nodes of spokes with fewer than 2 workers are always schedulable",
+		},
+		{
+			name:  "sno disabled",
+			spoke: SNOProfile(newHubTestClient(), "spoke"),
+			expectedError: "WithSchedulableMasters: cannot disable schedulable masters of agentclusterinstall spoke " +
+				"with 0 workers, control plane nodes of spokes with fewer than 2 workers are always schedulable",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := testCase.spoke.WithSchedulableMasters(testCase.enabled)
+
+		if testCase.expectedError != "" {
+			assert.EqualError(t, spoke.err, testCase.expectedError, testCase.name)
+
+			continue
+		}
+
+		assert.Nil(t, spoke.Validate(), testCase.name)
+		assert.Equal(t, testCase.enabled, spoke.AgentClusterInstall.Definition.Spec.MastersSchedulable, testCase.name)
+		assert.Equal(t, testCase.enabled, mastersSchedulable(spoke.AgentClusterInstall.Definition.Spec),
+			testCase.name)
+	}
+
+	spoke := StandardHAProfile(newHubTestClient(), "spoke").WithSchedulableMasters(false)
+	spoke.AgentClusterInstall.Definition.Spec.ProvisionRequirements.WorkerAgents = 1
+	assert.EqualError(t, spoke.Validate(), "cannot disable schedulable masters of agentclusterinstall spoke with "+
+		"1 workers, control plane nodes of spokes with fewer than 2 workers are always schedulable")
+
+	assert.True(t, mastersSchedulable(CompactProfile(newHubTestClient(), "spoke").AgentClusterInstall.Definition.Spec))
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithSchedulableMasters(true)
+	assert.EqualError(t, spoke.err,
+		"WithSchedulableMasters: agentclusterinstall must be defined before setting schedulable masters")
+}
+
+func TestWithClusterServiceAndMachineNetwork(t *testing.T) {
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultIPv4AgentClusterInstall().
+		WithClusterNetwork("10.132.0.0/14", 24).
+		WithServiceNetwork("172.31.0.0/16").
+		WithMachineNetwork("192.168.50.0/24").
+		WithClusterNetwork("fd01::/48", 64).
+		WithServiceNetwork("fd02::/112").
+		WithMachineNetwork("fd2e:6f44:5dd8:2::/64")
+	assert.Nil(t, spoke.err)
+
+	networking := spoke.AgentClusterInstall.Definition.Spec.Networking
+	assert.Equal(t, []v1beta1.ClusterNetworkEntry{
+		{CIDR: "10.132.0.0/14", HostPrefix: 24}, {CIDR: "fd01::/48", HostPrefix: 64}}, networking.ClusterNetwork)
+	assert.Equal(t, []string{"172.31.0.0/16", "fd02::/112"}, networking.ServiceNetwork)
+	assert.Equal(t, []v1beta1.MachineNetworkEntry{{CIDR: "192.168.50.0/24"}, {CIDR: "fd2e:6f44:5dd8:2::/64"}},
+		networking.MachineNetwork)
+
+	testCases := []struct {
+		name          string
+		spoke         func(*SpokeClusterResources) *SpokeClusterResources
+		expectedError string
+	}{
+		{
+			name: "invalid cluster network",
+			spoke: func(spoke *SpokeClusterResources) *SpokeClusterResources {
+				return spoke.WithClusterNetwork("10.132.0.0", 23)
+			},
+			expectedError: `WithClusterNetwork: invalid agentclusterinstall cluster network "10.132.0.0"`,
+		},
+		{
+			name: "host prefix too short",
+			spoke: func(spoke *SpokeClusterResources) *SpokeClusterResources {
+				return spoke.WithClusterNetwork("10.132.0.0/14", 14)
+			},
+			expectedError: "WithClusterNetwork: host prefix 14 of cluster network 10.132.0.0/14 " +
+				"must be between 15 and 32",
+		},
+		{
+			name: "invalid service network",
+			spoke: func(spoke *SpokeClusterResources) *SpokeClusterResources {
+				return spoke.WithServiceNetwork("172.31.0.0")
+			},
+			expectedError: `WithServiceNetwork: invalid agentclusterinstall service network "172.31.0.0"`,
+		},
+		{
+			name: "invalid machine network",
+			spoke: func(spoke *SpokeClusterResources) *SpokeClusterResources {
+				return spoke.WithMachineNetwork("192.168.50.0")
+			},
+			expectedError: `WithMachineNetwork: invalid agentclusterinstall machine network "192.168.50.0"`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := testCase.spoke(NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultIPv4AgentClusterInstall())
+		assert.EqualError(t, spoke.err, testCase.expectedError, testCase.name)
+	}
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithServiceNetwork("172.31.0.0/16")
+	assert.EqualError(t, spoke.err,
+		"WithServiceNetwork: agentclusterinstall must be defined before setting the service network")
+
+	spoke = NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultIPv4AgentClusterInstall().
+		WithServiceNetwork("fd02::/112")
+	assert.Nil(t, spoke.err)
+	assert.EqualError(t, spoke.validateNetworkFamilies(), "invalid agentclusterinstall networking: "+
+		"cluster networks are IPv4 but service networks are IPv4+IPv6")
+}
@@ -0,0 +1,340 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/assisted"
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	hivev1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/hive/api/v1"
+	"github.com/openshift-kni/eco-goinfra/pkg/secret"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	runtimeClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Validate checks the spoke cluster configuration for problems that would otherwise only surface once the resources are
+// created on the hub. It returns the first error recorded while building the spoke, if any, then checks that the
+// resources reference each other consistently, that the clusterdeployment agent selector matches the agent labels, that
+// the hub capabilities set using WithHubCapabilities support the features used, that the networking matches the
+// declared stack, that schedulable masters are not disabled with fewer than two workers, that the
+// agentclusterinstall has the workers required by the operators to install and that the referenced clusterimageset
+// exists on the hub. Create calls Validate before creating anything.
+func (spoke *SpokeClusterResources) Validate() error {
+	if spoke.err != nil {
+		return spoke.err
+	}
+
+	if err := spoke.validateReferences(); err != nil {
+		return err
+	}
+
+	if err := spoke.validatePullSecret(); err != nil {
+		return err
+	}
+
+	if err := spoke.validateAgentSelector(); err != nil {
+		return err
+	}
+
+	if err := spoke.validateHubCapabilities(); err != nil {
+		return err
+	}
+
+	if err := spoke.validateReleaseImage(); err != nil {
+		return err
+	}
+
+	if err := spoke.validateImageSetVersion(); err != nil {
+		return err
+	}
+
+	if err := spoke.validateNetworkingTopology(); err != nil {
+		return err
+	}
+
+	if err := spoke.validateNetworkFamilies(); err != nil {
+		return err
+	}
+
+	if err := spoke.validateSchedulableMasters(); err != nil {
+		return err
+	}
+
+	if err := spoke.validateNetworkType(spoke.NetworkType()); err != nil {
+		return err
+	}
+
+	if err := spoke.validateBootMethod(); err != nil {
+		return err
+	}
+
+	if err := spoke.validateInstallOperators(); err != nil {
+		return err
+	}
+
+	if err := spoke.validateImageSetExists(); err != nil {
+		return err
+	}
+
+	return spoke.validateCPUArchitecture()
+}
+
+// validateReferences checks that the clusterdeployment, agentclusterinstall and infraenvs reference the
+// pull-secrets, clusterdeployment and agentclusterinstall built along with them.
+func (spoke *SpokeClusterResources) validateReferences() error {
+	if spoke.ClusterDeployment != nil {
+		clusterDeployment := spoke.ClusterDeployment.Definition
+
+		if clusterDeployment.Spec.PullSecretRef != nil {
+			if err := validatePullSecretReference("clusterdeployment", clusterDeployment.Name,
+				clusterDeployment.Spec.PullSecretRef.Name, spoke.PullSecret); err != nil {
+				return err
+			}
+		}
+	}
+
+	if spoke.AgentClusterInstall != nil && spoke.ClusterDeployment != nil {
+		agentClusterInstall := spoke.AgentClusterInstall.Definition
+		clusterDeployment := spoke.ClusterDeployment.Definition
+
+		if agentClusterInstall.Spec.ClusterDeploymentRef.Name != clusterDeployment.Name {
+			return fmt.Errorf(
+				"agentclusterinstall %s references clusterdeployment %s but the spoke clusterdeployment is %s",
+				agentClusterInstall.Name, agentClusterInstall.Spec.ClusterDeploymentRef.Name, clusterDeployment.Name)
+		}
+
+		if reference := clusterDeployment.Spec.ClusterInstallRef; reference != nil &&
+			reference.Name != agentClusterInstall.Name {
+			return fmt.Errorf(
+				"clusterdeployment %s references agentclusterinstall %s but the spoke agentclusterinstall is %s",
+				clusterDeployment.Name, reference.Name, agentClusterInstall.Name)
+		}
+	}
+
+	for _, infraEnv := range spoke.allInfraEnvs() {
+		definition := infraEnv.Definition
+
+		if definition.Spec.PullSecretRef != nil {
+			pullSecret := spoke.PullSecret
+			if definition.Namespace != spoke.Name {
+				pullSecret = spoke.InfraEnvPullSecret
+			}
+
+			if err := validatePullSecretReference(
+				"infraenv", definition.Name, definition.Spec.PullSecretRef.Name, pullSecret); err != nil {
+				return err
+			}
+		}
+
+		if reference := definition.Spec.ClusterRef; reference != nil && spoke.ClusterDeployment != nil &&
+			reference.Name != spoke.ClusterDeployment.Definition.Name {
+			return fmt.Errorf("infraenv %s references clusterdeployment %s but the spoke clusterdeployment is %s",
+				definition.Name, reference.Name, spoke.ClusterDeployment.Definition.Name)
+		}
+	}
+
+	return nil
+}
+
+// validatePullSecretReference checks that the pull-secret named by the resource of kind is the one built for it.
+func validatePullSecretReference(kind, name, referenced string, pullSecret *secret.Builder) error {
+	if pullSecret == nil {
+		return fmt.Errorf("%s %s references pull-secret %s but no pull-secret is defined", kind, name, referenced)
+	}
+
+	if pullSecret.Definition.Name != referenced {
+		return fmt.Errorf("%s %s references pull-secret %s but the spoke pull-secret is %s",
+			kind, name, referenced, pullSecret.Definition.Name)
+	}
+
+	return nil
+}
+
+// validateAgentSelector checks that the agent selector of the clusterdeployment matches the labels the agents of at
+// least one spoke infraenv get, the agent labels set using WithAgentLabels and the infraenv name label, when agent
+// labels are set.
+func (spoke *SpokeClusterResources) validateAgentSelector() error {
+	if len(spoke.agentLabels) == 0 || spoke.ClusterDeployment == nil ||
+		spoke.ClusterDeployment.Definition.Spec.Platform.AgentBareMetal == nil {
+		return nil
+	}
+
+	agentSelector := spoke.ClusterDeployment.Definition.Spec.Platform.AgentBareMetal.AgentSelector
+
+	selector, err := metav1.LabelSelectorAsSelector(&agentSelector)
+	if err != nil {
+		return fmt.Errorf("invalid agent selector of clusterdeployment %s: %w", spoke.Name, err)
+	}
+
+	for _, infraEnv := range spoke.allInfraEnvs() {
+		agentLabels := labels.Set{InfraEnvLabel: infraEnv.Definition.Name}
+
+		maps.Copy(agentLabels, spoke.agentLabels)
+
+		if selector.Matches(agentLabels) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("agent selector %s of clusterdeployment %s can never match the agent labels %v of the spoke "+
+		"infraenvs", selector, spoke.Name, labels.Set(spoke.agentLabels))
+}
+
+// validateNetworkFamilies checks that the cluster, service and machine networks of the agentclusterinstall use the
+// same address families in the same order, so that single-stack and dual-stack spokes declare a consistent stack,
+// and that the api and ingress vips follow that order.
+func (spoke *SpokeClusterResources) validateNetworkFamilies() error {
+	if spoke.AgentClusterInstall == nil {
+		return nil
+	}
+
+	networking := spoke.AgentClusterInstall.Definition.Spec.Networking
+
+	var clusterCIDRs, machineCIDRs []string
+
+	for _, clusterNetwork := range networking.ClusterNetwork {
+		clusterCIDRs = append(clusterCIDRs, clusterNetwork.CIDR)
+	}
+
+	for _, machineNetwork := range networking.MachineNetwork {
+		machineCIDRs = append(machineCIDRs, machineNetwork.CIDR)
+	}
+
+	clusterFamilies, err := networkFamilies("cluster", clusterCIDRs)
+	if err != nil {
+		return err
+	}
+
+	serviceFamilies, err := networkFamilies("service", networking.ServiceNetwork)
+	if err != nil {
+		return err
+	}
+
+	machineFamilies, err := networkFamilies("machine", machineCIDRs)
+	if err != nil {
+		return err
+	}
+
+	if len(serviceFamilies) > 0 && !slices.Equal(clusterFamilies, serviceFamilies) {
+		return fmt.Errorf("invalid agentclusterinstall networking: cluster networks are %s but service networks are %s",
+			strings.Join(clusterFamilies, "+"), strings.Join(serviceFamilies, "+"))
+	}
+
+	if len(machineFamilies) > 0 && !slices.Equal(clusterFamilies, machineFamilies) {
+		return fmt.Errorf("invalid agentclusterinstall networking: cluster networks are %s but machine networks are %s",
+			strings.Join(clusterFamilies, "+"), strings.Join(machineFamilies, "+"))
+	}
+
+	spec := spoke.AgentClusterInstall.Definition.Spec
+
+	if err := validateVIPFamilies("api", spec.APIVIPs, clusterFamilies); err != nil {
+		return err
+	}
+
+	return validateVIPFamilies("ingress", spec.IngressVIPs, clusterFamilies)
+}
+
+// validateVIPFamilies checks that the vips of kind list the address families of the cluster networks in the same
+// order, the first vip being of the primary family, since assisted rejects mismatches with an obscure error.
+func validateVIPFamilies(kind string, vips []string, clusterFamilies []string) error {
+	if len(vips) == 0 || len(clusterFamilies) == 0 {
+		return nil
+	}
+
+	var vipFamilies []string
+
+	for _, vip := range vips {
+		family := "IPv6"
+		if isIPv4Address(vip) {
+			family = "IPv4"
+		}
+
+		vipFamilies = append(vipFamilies, family)
+	}
+
+	if len(vipFamilies) > len(clusterFamilies) || !slices.Equal(vipFamilies, clusterFamilies[:len(vipFamilies)]) {
+		return fmt.Errorf("invalid agentclusterinstall %s vips: cluster networks are %s but %s vips are %s",
+			kind, strings.Join(clusterFamilies, "+"), kind, strings.Join(vipFamilies, "+"))
+	}
+
+	return nil
+}
+
+// networkFamilies returns the address families of the CIDRs of kind in the order they first appear.
+func networkFamilies(kind string, cidrs []string) ([]string, error) {
+	var families []string
+
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid agentclusterinstall %s network %q", kind, cidr)
+		}
+
+		family := "IPv6"
+		if network.IP.To4() != nil {
+			family = "IPv4"
+		}
+
+		if !slices.Contains(families, family) {
+			families = append(families, family)
+		}
+	}
+
+	return families, nil
+}
+
+// validateImageSetExists checks that the clusterimageset referenced by the agentclusterinstall exists on the hub.
+func (spoke *SpokeClusterResources) validateImageSetExists() error {
+	if spoke.AgentClusterInstall == nil || spoke.AgentClusterInstall.Definition.Spec.ImageSetRef == nil ||
+		spoke.AgentClusterInstall.Definition.Spec.ImageSetRef.Name == "" {
+		return nil
+	}
+
+	imageSetName := spoke.AgentClusterInstall.Definition.Spec.ImageSetRef.Name
+
+	if spoke.ClusterImageSet != nil && spoke.ClusterImageSet.Definition.Name == imageSetName {
+		return nil
+	}
+
+	if err := clusterImageSetExists(spoke.apiClient, imageSetName); err != nil {
+		return fmt.Errorf("clusterimageset %s referenced by agentclusterinstall %s was not found on the hub: %w",
+			imageSetName, spoke.AgentClusterInstall.Definition.Name, err)
+	}
+
+	return nil
+}
+
+// clusterImageSetExists returns an error when the named clusterimageset does not exist on the hub. Unlike
+// hive.PullClusterImageSet, it only attaches the hive scheme to apiClient when missing, since attaching a scheme
+// races with the spokes sharing apiClient that are created concurrently by a SpokeClusterSet.
+func clusterImageSetExists(apiClient *clients.Settings, imageSetName string) error {
+	if !apiClient.Scheme().Recognizes(hivev1.SchemeGroupVersion.WithKind("ClusterImageSet")) {
+		if err := apiClient.AttachScheme(hivev1.AddToScheme); err != nil {
+			return fmt.Errorf("failed to add hive scheme to client schemes: %w", err)
+		}
+	}
+
+	err := apiClient.Get(context.TODO(), runtimeClient.ObjectKey{Name: imageSetName}, &hivev1.ClusterImageSet{})
+	if k8serrors.IsNotFound(err) {
+		return fmt.Errorf("clusterimageset object %s does not exist", imageSetName)
+	}
+
+	return err
+}
+
+// allInfraEnvs returns the spoke infraenv, when defined, followed by the additional infraenvs.
+func (spoke *SpokeClusterResources) allInfraEnvs() []*assisted.InfraEnvBuilder {
+	var infraEnvs []*assisted.InfraEnvBuilder
+
+	if spoke.InfraEnv != nil {
+		infraEnvs = append(infraEnvs, spoke.InfraEnv)
+	}
+
+	return append(infraEnvs, spoke.AdditionalInfraEnvs...)
+}
@@ -0,0 +1,227 @@
+package setup
+
+import (
+	"testing"
+
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateReferences(t *testing.T) {
+	testCases := []struct {
+		name        string
+		mutate      func(spoke *SpokeClusterResources)
+		expectedErr string
+	}{
+		{
+			name:   "consistent",
+			mutate: func(spoke *SpokeClusterResources) {},
+		},
+		{
+			name:   "missing pull-secret",
+			mutate: func(spoke *SpokeClusterResources) { spoke.PullSecret = nil },
+			expectedErr: "clusterdeployment ref-spoke references pull-secret ref-spoke-pull-secret " +
+				"but no pull-secret is defined",
+		},
+		{
+			name: "clusterdeployment pull-secret mismatch",
+			mutate: func(spoke *SpokeClusterResources) {
+				spoke.ClusterDeployment.Definition.Spec.PullSecretRef.Name = "other-pull-secret"
+			},
+			expectedErr: "clusterdeployment ref-spoke references pull-secret other-pull-secret " +
+				"but the spoke pull-secret is ref-spoke-pull-secret",
+		},
+		{
+			name: "infraenv pull-secret mismatch",
+			mutate: func(spoke *SpokeClusterResources) {
+				spoke.InfraEnv.Definition.Spec.PullSecretRef.Name = "other-pull-secret"
+			},
+			expectedErr: "infraenv ref-spoke references pull-secret other-pull-secret " +
+				"but the spoke pull-secret is ref-spoke-pull-secret",
+		},
+		{
+			name: "agentclusterinstall clusterdeployment mismatch",
+			mutate: func(spoke *SpokeClusterResources) {
+				spoke.AgentClusterInstall.Definition.Spec.ClusterDeploymentRef.Name = "other"
+			},
+			expectedErr: "agentclusterinstall ref-spoke references clusterdeployment other " +
+				"but the spoke clusterdeployment is ref-spoke",
+		},
+		{
+			name: "clusterdeployment agentclusterinstall mismatch",
+			mutate: func(spoke *SpokeClusterResources) {
+				spoke.ClusterDeployment.Definition.Spec.ClusterInstallRef.Name = "other"
+			},
+			expectedErr: "clusterdeployment ref-spoke references agentclusterinstall other " +
+				"but the spoke agentclusterinstall is ref-spoke",
+		},
+		{
+			name: "infraenv clusterdeployment mismatch",
+			mutate: func(spoke *SpokeClusterResources) {
+				spoke.InfraEnv.Definition.Spec.ClusterRef = &agentInstallV1Beta1.ClusterReference{
+					Name: "other", Namespace: "ref-spoke",
+				}
+			},
+			expectedErr: "infraenv ref-spoke references clusterdeployment other " +
+				"but the spoke clusterdeployment is ref-spoke",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := StandardHAProfile(newHubTestClient(), "ref-spoke")
+		testCase.mutate(spoke)
+
+		err := spoke.Validate()
+		if testCase.expectedErr == "" {
+			assert.Nil(t, err, testCase.name)
+
+			continue
+		}
+
+		assert.EqualError(t, err, testCase.expectedErr, testCase.name)
+	}
+}
+
+func TestValidateNetworkFamilies(t *testing.T) {
+	testCases := []struct {
+		name        string
+		mutate      func(spoke *SpokeClusterResources)
+		expectedErr string
+	}{
+		{
+			name:   "dual-stack",
+			mutate: func(spoke *SpokeClusterResources) {},
+		},
+		{
+			name: "service network missing ipv6",
+			mutate: func(spoke *SpokeClusterResources) {
+				spoke.AgentClusterInstall.Definition.Spec.Networking.ServiceNetwork = []string{"172.30.0.0/16"}
+			},
+			expectedErr: "invalid agentclusterinstall networking: cluster networks are IPv4+IPv6 " +
+				"but service networks are IPv4",
+		},
+		{
+			name: "service network families reversed",
+			mutate: func(spoke *SpokeClusterResources) {
+				spoke.AgentClusterInstall.Definition.Spec.Networking.ServiceNetwork = []string{
+					"fd02::/112", "172.30.0.0/16",
+				}
+			},
+			expectedErr: "invalid agentclusterinstall networking: cluster networks are IPv4+IPv6 " +
+				"but service networks are IPv6+IPv4",
+		},
+		{
+			name: "invalid service network",
+			mutate: func(spoke *SpokeClusterResources) {
+				spoke.AgentClusterInstall.Definition.Spec.Networking.ServiceNetwork = []string{"172.30.0.0"}
+			},
+			expectedErr: `invalid agentclusterinstall service network "172.30.0.0"`,
+		},
+		{
+			name: "ipv6-primary",
+			mutate: func(spoke *SpokeClusterResources) {
+				spoke.WithDefaultDualStackIPv6PrimaryAgentClusterInstall()
+			},
+		},
+		{
+			name: "ipv6-primary with ipv4 vips first",
+			mutate: func(spoke *SpokeClusterResources) {
+				spoke.WithDefaultDualStackIPv6PrimaryAgentClusterInstall().WithVIPs(
+					[]string{"192.168.254.5", "fd2e:6f44:5dd8:1::5"},
+					[]string{"192.168.254.10", "fd2e:6f44:5dd8:1::10"})
+			},
+			expectedErr: "invalid agentclusterinstall api vips: cluster networks are IPv6+IPv4 " +
+				"but api vips are IPv4+IPv6",
+		},
+		{
+			name: "ingress vip of the secondary family",
+			mutate: func(spoke *SpokeClusterResources) {
+				spoke.AgentClusterInstall.Definition.Spec.IngressVIPs = []string{"fd2e:6f44:5dd8:1::10"}
+			},
+			expectedErr: "invalid agentclusterinstall ingress vips: cluster networks are IPv4+IPv6 " +
+				"but ingress vips are IPv6",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := DualStackProfile(newHubTestClient(), "family-spoke")
+		testCase.mutate(spoke)
+
+		err := spoke.validateNetworkFamilies()
+		if testCase.expectedErr == "" {
+			assert.Nil(t, err, testCase.name)
+
+			continue
+		}
+
+		assert.EqualError(t, err, testCase.expectedErr, testCase.name)
+	}
+}
+
+func TestValidateAgentSelector(t *testing.T) {
+	testCases := []struct {
+		name        string
+		selector    *metav1.LabelSelector
+		agentLabels map[string]string
+		expectedErr string
+	}{
+		{name: "default selector without agent labels"},
+		{
+			name:        "matching selector",
+			selector:    &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "a"}},
+			agentLabels: map[string]string{"pool": "a", "rack": "r1"},
+		},
+		{
+			name:        "infraenv label",
+			selector:    &metav1.LabelSelector{MatchLabels: map[string]string{InfraEnvLabel: "spoke"}},
+			agentLabels: map[string]string{"pool": "a"},
+		},
+		{
+			name:        "mismatching selector",
+			selector:    &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "b"}},
+			agentLabels: map[string]string{"pool": "a"},
+			expectedErr: "agent selector pool=b of clusterdeployment spoke can never match the agent labels pool=a " +
+				"of the spoke infraenvs",
+		},
+		{
+			name:        "default selector",
+			agentLabels: map[string]string{"pool": "a"},
+			expectedErr: "agent selector dummy=label of clusterdeployment spoke can never match the agent labels " +
+				"pool=a of the spoke infraenvs",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := StandardHAProfile(newHubTestClient(), "spoke")
+
+		if testCase.selector != nil {
+			spoke.WithAgentSelector(*testCase.selector)
+		}
+
+		if testCase.agentLabels != nil {
+			spoke.WithAgentLabels(testCase.agentLabels)
+		}
+
+		err := spoke.Validate()
+		if testCase.expectedErr == "" {
+			assert.Nil(t, err, testCase.name)
+
+			continue
+		}
+
+		assert.EqualError(t, err, testCase.expectedErr, testCase.name)
+	}
+}
+
+func TestValidateImageSetExists(t *testing.T) {
+	spoke := StandardHAProfile(newTestClient(), "imageset-spoke")
+	assert.EqualError(t, spoke.Validate(), "clusterimageset 4.16 referenced by agentclusterinstall imageset-spoke "+
+		"was not found on the hub: clusterimageset object 4.16 does not exist")
+
+	_, err := spoke.Create()
+	assert.NotNil(t, err)
+	assert.False(t, spoke.Namespace.Exists())
+
+	assert.Nil(t, StandardHAProfile(newHubTestClient(), "imageset-spoke").Validate())
+}
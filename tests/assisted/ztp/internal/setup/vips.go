@@ -0,0 +1,125 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// WithVIPs sets the api and ingress vips of the spoke agentclusterinstall. Single-stack spokes pass one address for
+// each and dual-stack spokes pass one address of each family, with the primary family first. The singular vip
+// fields are set to the first address so that hubs supporting only them keep working. VIPs cannot be set once
+// user-managed networking is enabled.
+func (spoke *SpokeClusterResources) WithVIPs(apiVIPs, ingressVIPs []string) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if spoke.AgentClusterInstall == nil {
+		spoke.err = fmt.Errorf("WithVIPs: agentclusterinstall must be defined before setting vips")
+
+		return spoke
+	}
+
+	if spoke.userManagedNetworking() {
+		spoke.err = fmt.Errorf("WithVIPs: %s", ruleUMNForbidsVIPs)
+
+		return spoke
+	}
+
+	if err := validateVIPs("api", apiVIPs); err != nil {
+		spoke.err = fmt.Errorf("WithVIPs: %w", err)
+
+		return spoke
+	}
+
+	if err := validateVIPs("ingress", ingressVIPs); err != nil {
+		spoke.err = fmt.Errorf("WithVIPs: %w", err)
+
+		return spoke
+	}
+
+	if len(apiVIPs) != len(ingressVIPs) || isIPv4Address(apiVIPs[0]) != isIPv4Address(ingressVIPs[0]) {
+		spoke.err = fmt.Errorf(
+			"WithVIPs: api vips %v and ingress vips %v must cover the same address families in the same order",
+			apiVIPs, ingressVIPs)
+
+		return spoke
+	}
+
+	spec := &spoke.AgentClusterInstall.Definition.Spec
+	spec.APIVIPs, spec.IngressVIPs = nil, nil
+
+	spoke.AgentClusterInstall.WithAPIVip(apiVIPs[0]).WithIngressVip(ingressVIPs[0])
+
+	for index := range apiVIPs {
+		spoke.AgentClusterInstall.WithAdditionalAPIVip(apiVIPs[index]).WithAdditionalIngressVip(ingressVIPs[index])
+	}
+
+	spoke.explicitVIPs = true
+
+	return spoke
+}
+
+// validateVIPs checks that vips contains one address, or two addresses of different families.
+func validateVIPs(kind string, vips []string) error {
+	if len(vips) == 0 || len(vips) > 2 {
+		return fmt.Errorf("%s vips must contain one or two addresses, got %d", kind, len(vips))
+	}
+
+	for _, vip := range vips {
+		if net.ParseIP(vip) == nil {
+			return fmt.Errorf("invalid %s vip %q", kind, vip)
+		}
+	}
+
+	if len(vips) == 2 && isIPv4Address(vips[0]) == isIPv4Address(vips[1]) {
+		return fmt.Errorf("dual-stack %s vips %v must contain one IPv4 and one IPv6 address", kind, vips)
+	}
+
+	return nil
+}
+
+// isIPv4Address returns true when address is an IPv4 address.
+func isIPv4Address(address string) bool {
+	return net.ParseIP(address).To4() != nil
+}
+
+// createAgentClusterInstall creates the spoke agentclusterinstall. Hubs whose agentclusterinstall CRD predates the
+// plural vip fields reject them under strict field validation, in which case the plural fields are dropped and the
+// create is retried with the singular fields only, recording it like any other created or adopted resource.
+func (spoke *SpokeClusterResources) createAgentClusterInstall(ctx context.Context) error {
+	create := func() (err error) {
+		spoke.AgentClusterInstall, err = spoke.AgentClusterInstall.Create()
+
+		return err
+	}
+
+	err := spoke.createOrAdopt(ctx, "agentclusterinstall", spoke.AgentClusterInstall, create)
+	if err == nil || !isUnsupportedVIPsError(err) {
+		return err
+	}
+
+	glog.V(ztpparams.ZTPLogLevel).Infof(
+		"Hub does not support plural vips, creating agentclusterinstall %s with singular vips only", spoke.Name)
+
+	spec := &spoke.AgentClusterInstall.Definition.Spec
+	spec.APIVIPs, spec.IngressVIPs = nil, nil
+
+	return spoke.createOrAdopt(ctx, "agentclusterinstall", spoke.AgentClusterInstall, create)
+}
+
+// isUnsupportedVIPsError returns true when err is the rejection of the plural vip fields as unknown.
+func isUnsupportedVIPsError(err error) bool {
+	if !k8serrors.IsBadRequest(err) && !k8serrors.IsInvalid(err) {
+		return false
+	}
+
+	return strings.Contains(err.Error(), `unknown field "spec.apiVIPs"`) ||
+		strings.Contains(err.Error(), `unknown field "spec.ingressVIPs"`)
+}
@@ -0,0 +1,137 @@
+package setup
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	hivev1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/hive/api/v1"
+	"github.com/stretchr/testify/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	runtimeClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestWithDefaultDualStackAgentClusterInstallVIPs(t *testing.T) {
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultDualStackAgentClusterInstall()
+	assert.Nil(t, spoke.err)
+
+	spec := spoke.AgentClusterInstall.Definition.Spec
+	assert.Equal(t, "192.168.254.5", spec.APIVIP)
+	assert.Equal(t, "192.168.254.10", spec.IngressVIP)
+	assert.Equal(t, []string{"192.168.254.5", "fd2e:6f44:5dd8:1::5"}, spec.APIVIPs)
+	assert.Equal(t, []string{"192.168.254.10", "fd2e:6f44:5dd8:1::10"}, spec.IngressVIPs)
+}
+
+func TestWithDefaultDualStackIPv6PrimaryAgentClusterInstall(t *testing.T) {
+	spoke := NewSpokeCluster(newHubTestClient()).WithName("spoke").
+		WithDefaultDualStackIPv6PrimaryAgentClusterInstall()
+	assert.Nil(t, spoke.err)
+
+	spec := spoke.AgentClusterInstall.Definition.Spec
+	assert.Equal(t, "fd2e:6f44:5dd8:1::5", spec.APIVIP)
+	assert.Equal(t, "fd2e:6f44:5dd8:1::10", spec.IngressVIP)
+	assert.Equal(t, []string{"fd2e:6f44:5dd8:1::5", "192.168.254.5"}, spec.APIVIPs)
+	assert.Equal(t, []string{"fd2e:6f44:5dd8:1::10", "192.168.254.10"}, spec.IngressVIPs)
+	assert.Equal(t, []string{"fd01::/48", "10.128.0.0/14"},
+		[]string{spec.Networking.ClusterNetwork[0].CIDR, spec.Networking.ClusterNetwork[1].CIDR})
+	assert.Equal(t, []string{"fd02::/112", "172.30.0.0/16"}, spec.Networking.ServiceNetwork)
+	assert.Nil(t, spoke.validateNetworkFamilies())
+}
+
+func TestWithVIPs(t *testing.T) {
+	testCases := []struct {
+		apiVIPs       []string
+		ingressVIPs   []string
+		expectedError string
+	}{
+		{apiVIPs: []string{"192.168.1.5"}, ingressVIPs: []string{"192.168.1.10"}},
+		{apiVIPs: []string{"fd00::5", "192.168.1.5"}, ingressVIPs: []string{"fd00::10", "192.168.1.10"}},
+		{
+			apiVIPs:       nil,
+			ingressVIPs:   []string{"192.168.1.10"},
+			expectedError: "WithVIPs: api vips must contain one or two addresses, got 0",
+		},
+		{
+			apiVIPs:       []string{"192.168.1.5"},
+			ingressVIPs:   []string{"192.168.1"},
+			expectedError: "WithVIPs: invalid ingress vip \"192.168.1\"",
+		},
+		{
+			apiVIPs:     []string{"192.168.1.5", "192.168.1.6"},
+			ingressVIPs: []string{"192.168.1.10", "fd00::10"},
+			expectedError: "WithVIPs: dual-stack api vips [192.168.1.5 192.168.1.6] must contain one IPv4 and one " +
+				"IPv6 address",
+		},
+		{
+			apiVIPs:     []string{"192.168.1.5", "fd00::5"},
+			ingressVIPs: []string{"fd00::10", "192.168.1.10"},
+			expectedError: "WithVIPs: api vips [192.168.1.5 fd00::5] and ingress vips [fd00::10 192.168.1.10] " +
+				"must cover the same address families in the same order",
+		},
+	}
+
+	for _, testCase := range testCases {
+		spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultDualStackAgentClusterInstall().
+			WithVIPs(testCase.apiVIPs, testCase.ingressVIPs)
+
+		if testCase.expectedError != "" {
+			assert.EqualError(t, spoke.err, testCase.expectedError)
+
+			continue
+		}
+
+		assert.Nil(t, spoke.err)
+
+		spec := spoke.AgentClusterInstall.Definition.Spec
+		assert.Equal(t, testCase.apiVIPs[0], spec.APIVIP)
+		assert.Equal(t, testCase.ingressVIPs[0], spec.IngressVIP)
+		assert.Equal(t, testCase.apiVIPs, spec.APIVIPs)
+		assert.Equal(t, testCase.ingressVIPs, spec.IngressVIPs)
+	}
+
+	spoke := NewSpokeCluster(newTestClient()).WithName("spoke").WithVIPs([]string{"192.168.1.5"}, nil)
+	assert.EqualError(t, spoke.err, "WithVIPs: agentclusterinstall must be defined before setting vips")
+}
+
+func TestCreateFallsBackToSingularVIPs(t *testing.T) {
+	apiClient := newPluralVIPsRejectingTestClient()
+
+	spoke := NewSpokeCluster(apiClient).WithName("spoke").WithDefaultDualStackAgentClusterInstall()
+	assert.Nil(t, spoke.createAgentClusterInstall(context.TODO()))
+
+	created := &v1beta1.AgentClusterInstall{}
+	err := apiClient.Get(context.TODO(), runtimeClient.ObjectKey{Name: "spoke", Namespace: "spoke"}, created)
+	assert.Nil(t, err)
+	assert.Equal(t, "192.168.254.5", created.Spec.APIVIP)
+	assert.Equal(t, "192.168.254.10", created.Spec.IngressVIP)
+	assert.Empty(t, created.Spec.APIVIPs)
+	assert.Empty(t, created.Spec.IngressVIPs)
+}
+
+// newPluralVIPsRejectingTestClient returns a test client rejecting agentclusterinstalls with plural vips like hubs
+// whose agentclusterinstall CRD predates them.
+func newPluralVIPsRejectingTestClient() *clients.Settings {
+	apiClient, testBuilder := clients.GetModifiableTestClients(clients.TestClientParams{
+		SchemeAttachers: []clients.SchemeAttacher{
+			v1beta1.AddToScheme,
+			agentInstallV1Beta1.AddToScheme,
+			hivev1.AddToScheme,
+		},
+	})
+
+	apiClient.Client = testBuilder.WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, client runtimeClient.WithWatch,
+			obj runtimeClient.Object, opts ...runtimeClient.CreateOption) error {
+			if aci, isACI := obj.(*v1beta1.AgentClusterInstall); isACI && len(aci.Spec.APIVIPs) > 0 {
+				return k8serrors.NewBadRequest(`strict decoding error: unknown field "spec.apiVIPs"`)
+			}
+
+			return client.Create(ctx, obj, opts...)
+		},
+	}).Build()
+
+	return apiClient
+}
@@ -0,0 +1,155 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	defaultWaitInterval  = time.Second * 5
+	defaultWaitTimeout   = time.Second * 120
+	defaultDeleteTimeout = time.Minute * 2
+)
+
+var (
+	defaultWaitOptions      *WaitOptions
+	defaultWaitOptionsMutex sync.RWMutex
+)
+
+// WaitOptions configures how the spoke helpers poll while waiting on resources. An interval is increased by
+// BackoffFactor after each poll; a BackoffFactor of 0 or 1 polls at a constant interval. Retries and RetryInterval
+// limit how API calls failing with transient errors are retried, doubling the interval after each retry; when
+// unset, 3 retries starting at 1 second are used and a negative Retries disables retrying. RetryBudget limits the
+// total time spent retrying a call, unlimited when unset. DeleteTimeout limits how long Delete waits for each
+// resource to be removed, 2 minutes when unset.
+type WaitOptions struct {
+	Interval      time.Duration
+	Timeout       time.Duration
+	BackoffFactor float64
+	Retries       int
+	RetryInterval time.Duration
+	RetryBudget   time.Duration
+	DeleteTimeout time.Duration
+}
+
+// SetDefaultWaitOptions sets the wait options used by every spoke that has not been given its own through
+// WithWaitOptions, taking precedence over the ZTPConfig defaults.
+func SetDefaultWaitOptions(options WaitOptions) error {
+	if err := options.validate(); err != nil {
+		return err
+	}
+
+	defaultWaitOptionsMutex.Lock()
+	defer defaultWaitOptionsMutex.Unlock()
+
+	defaultWaitOptions = &options
+
+	return nil
+}
+
+// WithWaitOptions sets the wait options used by the spoke cluster helpers. Passing nil resets them to the defaults.
+func (spoke *SpokeClusterResources) WithWaitOptions(options *WaitOptions) *SpokeClusterResources {
+	if spoke.err != nil {
+		return spoke
+	}
+
+	if options != nil {
+		if err := options.validate(); err != nil {
+			spoke.err = fmt.Errorf("WithWaitOptions: %w", err)
+
+			return spoke
+		}
+	}
+
+	spoke.waitOptions = options
+
+	return spoke
+}
+
+// resolveWaitOptions returns the wait options for the spoke, falling back to the package defaults, then to the
+// ZTPConfig defaults and finally to the built-in constants.
+func (spoke *SpokeClusterResources) resolveWaitOptions() WaitOptions {
+	if spoke.waitOptions != nil {
+		return *spoke.waitOptions
+	}
+
+	defaultWaitOptionsMutex.RLock()
+	defer defaultWaitOptionsMutex.RUnlock()
+
+	if defaultWaitOptions != nil {
+		return *defaultWaitOptions
+	}
+
+	options := WaitOptions{Interval: defaultWaitInterval, Timeout: defaultWaitTimeout}
+
+	config := spokeConfig()
+
+	if config.SpokeWaitInterval > 0 {
+		options.Interval = config.SpokeWaitInterval
+	}
+
+	if config.SpokeWaitTimeout > 0 {
+		options.Timeout = config.SpokeWaitTimeout
+	}
+
+	if options.Interval > options.Timeout {
+		options.Interval = options.Timeout
+	}
+
+	return options
+}
+
+// validate checks that the wait options describe a usable polling configuration.
+func (options WaitOptions) validate() error {
+	if options.Interval <= 0 {
+		return fmt.Errorf("wait interval must be greater than 0")
+	}
+
+	if options.Timeout <= 0 {
+		return fmt.Errorf("wait timeout must be greater than 0")
+	}
+
+	if options.Interval > options.Timeout {
+		return fmt.Errorf("wait interval %s cannot be greater than wait timeout %s", options.Interval, options.Timeout)
+	}
+
+	if options.BackoffFactor != 0 && options.BackoffFactor < 1 {
+		return fmt.Errorf("wait backoff factor must be 0 or at least 1, got %v", options.BackoffFactor)
+	}
+
+	if options.RetryInterval < 0 {
+		return fmt.Errorf("retry interval cannot be negative")
+	}
+
+	if options.RetryBudget < 0 {
+		return fmt.Errorf("retry budget cannot be negative")
+	}
+
+	if options.DeleteTimeout < 0 {
+		return fmt.Errorf("delete timeout cannot be negative")
+	}
+
+	return nil
+}
+
+// poll runs condition until it returns true, it returns an error, or the timeout expires.
+func (options WaitOptions) poll(ctx context.Context, condition wait.ConditionWithContextFunc) error {
+	ctx, cancel := context.WithTimeout(ctx, options.Timeout)
+	defer cancel()
+
+	if options.BackoffFactor <= 1 {
+		return wait.PollUntilContextCancel(ctx, options.Interval, true, condition)
+	}
+
+	return wait.ExponentialBackoffWithContext(ctx, wait.Backoff{
+		Duration: options.Interval,
+		Factor:   options.BackoffFactor,
+		Steps:    math.MaxInt32,
+		Cap:      options.Timeout,
+	}, condition)
+}
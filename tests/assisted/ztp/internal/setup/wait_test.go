@@ -0,0 +1,120 @@
+package setup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitOptionsValidate(t *testing.T) {
+	testCases := []struct {
+		options     WaitOptions
+		expectedErr string
+	}{
+		{options: WaitOptions{Interval: time.Second, Timeout: time.Minute}},
+		{options: WaitOptions{Interval: time.Second, Timeout: time.Minute, BackoffFactor: 2}},
+		{
+			options:     WaitOptions{Timeout: time.Minute},
+			expectedErr: "wait interval must be greater than 0",
+		},
+		{
+			options:     WaitOptions{Interval: time.Second},
+			expectedErr: "wait timeout must be greater than 0",
+		},
+		{
+			options:     WaitOptions{Interval: time.Minute, Timeout: time.Second},
+			expectedErr: "wait interval 1m0s cannot be greater than wait timeout 1s",
+		},
+		{
+			options:     WaitOptions{Interval: time.Second, Timeout: time.Minute, BackoffFactor: 0.5},
+			expectedErr: "wait backoff factor must be 0 or at least 1, got 0.5",
+		},
+		{
+			options:     WaitOptions{Interval: time.Second, Timeout: time.Minute, RetryBudget: -time.Second},
+			expectedErr: "retry budget cannot be negative",
+		},
+		{
+			options:     WaitOptions{Interval: time.Second, Timeout: time.Minute, DeleteTimeout: -time.Second},
+			expectedErr: "delete timeout cannot be negative",
+		},
+	}
+
+	for _, testCase := range testCases {
+		err := testCase.options.validate()
+
+		if testCase.expectedErr == "" {
+			assert.Nil(t, err)
+		} else {
+			assert.EqualError(t, err, testCase.expectedErr)
+		}
+
+		assert.Equal(t, err, SetDefaultWaitOptions(testCase.options))
+
+		spokeErr := NewSpokeCluster(newTestClient()).WithWaitOptions(&testCase.options).err
+		if testCase.expectedErr == "" {
+			assert.Nil(t, spokeErr)
+		} else {
+			assert.EqualError(t, spokeErr, "WithWaitOptions: "+testCase.expectedErr)
+		}
+	}
+
+	resetDefaultWaitOptions(t)
+}
+
+func TestResolveWaitOptions(t *testing.T) {
+	spoke := NewSpokeCluster(newTestClient())
+
+	assert.Equal(t, WaitOptions{Interval: defaultWaitInterval, Timeout: defaultWaitTimeout}, spoke.resolveWaitOptions())
+
+	testZTPConfig.SpokeWaitTimeout = time.Minute * 10
+	testZTPConfig.SpokeWaitInterval = time.Second * 30
+
+	t.Cleanup(func() {
+		testZTPConfig.SpokeWaitTimeout = 0
+		testZTPConfig.SpokeWaitInterval = 0
+	})
+
+	ztpOptions := WaitOptions{Interval: time.Second * 30, Timeout: time.Minute * 10}
+	assert.Equal(t, ztpOptions, spoke.resolveWaitOptions())
+
+	packageOptions := WaitOptions{Interval: time.Second, Timeout: time.Minute, BackoffFactor: 1.5}
+	resetDefaultWaitOptions(t)
+	assert.Nil(t, SetDefaultWaitOptions(packageOptions))
+	assert.Equal(t, packageOptions, spoke.resolveWaitOptions())
+
+	spokeOptions := WaitOptions{Interval: time.Second * 2, Timeout: time.Minute * 2}
+	assert.Equal(t, spokeOptions, spoke.WithWaitOptions(&spokeOptions).resolveWaitOptions())
+	assert.Equal(t, packageOptions, spoke.WithWaitOptions(nil).resolveWaitOptions())
+}
+
+func TestWaitOptionsPoll(t *testing.T) {
+	options := WaitOptions{Interval: time.Millisecond, Timeout: time.Second, BackoffFactor: 2}
+	attempts := 0
+
+	err := options.poll(context.TODO(), func(ctx context.Context) (bool, error) {
+		attempts++
+
+		return attempts == 4, nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 4, attempts)
+
+	options = WaitOptions{Interval: time.Millisecond, Timeout: time.Millisecond * 20}
+	err = options.poll(context.TODO(), func(ctx context.Context) (bool, error) {
+		return false, nil
+	})
+
+	assert.NotNil(t, err)
+}
+
+// resetDefaultWaitOptions clears the package wait options once the test completes.
+func resetDefaultWaitOptions(t *testing.T) {
+	t.Helper()
+
+	t.Cleanup(func() {
+		defaultWaitOptions = nil
+	})
+}
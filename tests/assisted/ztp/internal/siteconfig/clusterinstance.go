@@ -0,0 +1,223 @@
+package siteconfig
+
+import (
+	"fmt"
+	"io"
+	"maps"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	siteconfigv1alpha1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/siteconfig/v1alpha1"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/setup"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const clusterInstanceKind = "ClusterInstance"
+
+// ClusterInstance returns the clusterinstance of the spoke, named after the spoke in its namespace. The spoke
+// definitions are first rendered like setup.SpokeClusterResources.Render, without calling the API, so the labels,
+// extra manifest references and other changes made at creation are part of the generated clusterinstance.
+func (generator *Generator) ClusterInstance() (*siteconfigv1alpha1.ClusterInstance, error) {
+	if err := generator.prepareSpoke(); err != nil {
+		return nil, err
+	}
+
+	nodes, err := generator.resolveNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	spoke := generator.spoke
+	agentClusterInstall := spoke.AgentClusterInstall.Definition
+	networking := agentClusterInstall.Spec.Networking
+
+	clusterInstance := &siteconfigv1alpha1.ClusterInstance{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: siteconfigv1alpha1.GroupVersion.String(),
+			Kind:       clusterInstanceKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{Name: spoke.Name, Namespace: spoke.Namespace.Definition.Name},
+		Spec: siteconfigv1alpha1.ClusterInstanceSpec{
+			ClusterName:            spoke.ClusterDeployment.Definition.Spec.ClusterName,
+			BaseDomain:             spoke.ClusterDeployment.Definition.Spec.BaseDomain,
+			PullSecretRef:          corev1.LocalObjectReference{Name: spoke.PullSecret.Definition.Name},
+			ClusterImageSetNameRef: imageSetName(spoke),
+			SSHPublicKey:           agentClusterInstall.Spec.SSHPublicKey,
+			ApiVIPs:                vips(agentClusterInstall.Spec.APIVIPs, agentClusterInstall.Spec.APIVIP),
+			IngressVIPs:            vips(agentClusterInstall.Spec.IngressVIPs, agentClusterInstall.Spec.IngressVIP),
+			HoldInstallation:       agentClusterInstall.Spec.HoldInstallation,
+			NetworkType:            networking.NetworkType,
+			PlatformType:           siteconfigv1alpha1.PlatformType(agentClusterInstall.Spec.PlatformType),
+			InstallConfigOverrides: agentClusterInstall.Annotations[setup.InstallConfigOverridesAnnotation],
+			ClusterType:            clusterType(spoke),
+			TemplateRefs:           generator.clusterTemplateRefs,
+			ExtraLabels:            generator.clusterExtraLabels(),
+			ExtraAnnotations:       generator.extraAnnotations,
+		},
+	}
+
+	clusterInstance.Spec.MachineNetwork, clusterInstance.Spec.ClusterNetwork, clusterInstance.Spec.ServiceNetwork =
+		clusterNetworks(networking)
+
+	for _, manifestsRef := range agentClusterInstall.Spec.ManifestsConfigMapRefs {
+		clusterInstance.Spec.ExtraManifestsRefs = append(clusterInstance.Spec.ExtraManifestsRefs,
+			corev1.LocalObjectReference{Name: manifestsRef.Name})
+	}
+
+	if spoke.InfraEnv != nil {
+		infraEnv := spoke.InfraEnv.Definition
+
+		clusterInstance.Spec.AdditionalNTPSources = infraEnv.Spec.AdditionalNTPSources
+		clusterInstance.Spec.IgnitionConfigOverride = infraEnv.Spec.IgnitionConfigOverride
+		clusterInstance.Spec.Proxy = infraEnv.Spec.Proxy
+		clusterInstance.Spec.CPUArchitecture = siteconfigv1alpha1.CPUArchitecture(infraEnv.Spec.CpuArchitecture)
+	}
+
+	if len(clusterInstance.Spec.TemplateRefs) == 0 {
+		clusterInstance.Spec.TemplateRefs = []siteconfigv1alpha1.TemplateRef{
+			{Name: DefaultClusterTemplatesName, Namespace: DefaultTemplatesNamespace},
+		}
+	}
+
+	clusterInstance.Spec.Nodes = generator.nodeSpecs(nodes)
+
+	return clusterInstance, nil
+}
+
+// vips returns the plural VIPs of the agentclusterinstall, falling back to the singular VIP when only it is set.
+func vips(plural []string, singular string) []string {
+	if len(plural) > 0 || singular == "" {
+		return plural
+	}
+
+	return []string{singular}
+}
+
+// clusterNetworks returns the machine, cluster and service networks of the clusterinstance for the networking of
+// the agentclusterinstall.
+func clusterNetworks(networking v1beta1.Networking) (
+	[]siteconfigv1alpha1.MachineNetworkEntry,
+	[]siteconfigv1alpha1.ClusterNetworkEntry,
+	[]siteconfigv1alpha1.ServiceNetworkEntry) {
+	var (
+		machineNetworks []siteconfigv1alpha1.MachineNetworkEntry
+		clusterNetworks []siteconfigv1alpha1.ClusterNetworkEntry
+		serviceNetworks []siteconfigv1alpha1.ServiceNetworkEntry
+	)
+
+	for _, machineNetwork := range networking.MachineNetwork {
+		machineNetworks = append(machineNetworks, siteconfigv1alpha1.MachineNetworkEntry{CIDR: machineNetwork.CIDR})
+	}
+
+	for _, clusterNetwork := range networking.ClusterNetwork {
+		clusterNetworks = append(clusterNetworks,
+			siteconfigv1alpha1.ClusterNetworkEntry{CIDR: clusterNetwork.CIDR, HostPrefix: clusterNetwork.HostPrefix})
+	}
+
+	for _, serviceNetwork := range networking.ServiceNetwork {
+		serviceNetworks = append(serviceNetworks, siteconfigv1alpha1.ServiceNetworkEntry{CIDR: serviceNetwork})
+	}
+
+	return machineNetworks, clusterNetworks, serviceNetworks
+}
+
+// nodeSpecs returns the clusterinstance node of each node, referencing the node templates of the generator or the
+// default ones.
+func (generator *Generator) nodeSpecs(nodes []Node) []siteconfigv1alpha1.NodeSpec {
+	nodeTemplateRefs := generator.nodeTemplateRefs
+	if len(nodeTemplateRefs) == 0 {
+		nodeTemplateRefs = []siteconfigv1alpha1.TemplateRef{
+			{Name: DefaultNodeTemplatesName, Namespace: DefaultTemplatesNamespace},
+		}
+	}
+
+	var nodeSpecs []siteconfigv1alpha1.NodeSpec
+
+	for _, node := range nodes {
+		nodeSpecs = append(nodeSpecs, siteconfigv1alpha1.NodeSpec{
+			HostName:               node.HostName,
+			Role:                   node.Role,
+			BmcAddress:             node.BMCAddress,
+			BmcCredentialsName:     siteconfigv1alpha1.BmcCredentialsName{Name: node.BMCCredentialsName},
+			BootMACAddress:         node.BootMACAddress,
+			BootMode:               node.BootMode,
+			RootDeviceHints:        node.RootDeviceHints,
+			NodeNetwork:            node.NodeNetwork,
+			NodeLabels:             node.NodeLabels,
+			InstallerArgs:          node.InstallerArgs,
+			IgnitionConfigOverride: node.IgnitionConfigOverride,
+			TemplateRefs:           nodeTemplateRefs,
+		})
+	}
+
+	return nodeSpecs
+}
+
+// prepareSpoke renders the spoke definitions so that the changes made at creation are applied, and checks the spoke
+// defines the resources the CRs are generated from.
+func (generator *Generator) prepareSpoke() error {
+	if generator.err != nil {
+		return generator.err
+	}
+
+	spoke := generator.spoke
+
+	if err := spoke.Render(io.Discard); err != nil {
+		return err
+	}
+
+	switch {
+	case spoke.Namespace == nil:
+		return fmt.Errorf("namespace must be defined before generating the spoke CRs")
+	case spoke.PullSecret == nil:
+		return fmt.Errorf("pull secret must be defined before generating the spoke CRs")
+	case spoke.ClusterDeployment == nil:
+		return fmt.Errorf("clusterdeployment must be defined before generating the spoke CRs")
+	case spoke.AgentClusterInstall == nil:
+		return fmt.Errorf("agentclusterinstall must be defined before generating the spoke CRs")
+	}
+
+	return nil
+}
+
+// clusterExtraLabels returns the labels of the managedcluster of the spoke merged with the extra labels set using
+// WithExtraLabels, by kind.
+func (generator *Generator) clusterExtraLabels() map[string]map[string]string {
+	extraLabels := map[string]map[string]string{}
+
+	for kind, labels := range generator.extraLabels {
+		extraLabels[kind] = maps.Clone(labels)
+	}
+
+	if managedCluster := generator.spoke.ManagedCluster; managedCluster != nil &&
+		len(managedCluster.Definition.Labels) > 0 {
+		labels := maps.Clone(managedCluster.Definition.Labels)
+		maps.Copy(labels, extraLabels[managedClusterKind])
+		extraLabels[managedClusterKind] = labels
+	}
+
+	if len(extraLabels) == 0 {
+		return nil
+	}
+
+	return extraLabels
+}
+
+// imageSetName returns the clusterimageset referenced by the agentclusterinstall of spoke.
+func imageSetName(spoke *setup.SpokeClusterResources) string {
+	if imageSetRef := spoke.AgentClusterInstall.Definition.Spec.ImageSetRef; imageSetRef != nil {
+		return imageSetRef.Name
+	}
+
+	return ""
+}
+
+// clusterType returns SNO for a spoke with a single control-plane agent and no workers, HighlyAvailable otherwise.
+func clusterType(spoke *setup.SpokeClusterResources) siteconfigv1alpha1.ClusterType {
+	requirements := spoke.AgentClusterInstall.Definition.Spec.ProvisionRequirements
+	if requirements.ControlPlaneAgents == 1 && requirements.WorkerAgents == 0 {
+		return siteconfigv1alpha1.ClusterTypeSNO
+	}
+
+	return siteconfigv1alpha1.ClusterTypeHighlyAvailable
+}
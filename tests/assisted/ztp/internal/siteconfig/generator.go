@@ -0,0 +1,285 @@
+package siteconfig
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"text/template"
+
+	siteconfigv1alpha1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/siteconfig/v1alpha1"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/setup"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// DefaultClusterTemplatesName is the configmap of the cluster templates installed by the siteconfig operator for
+	// the assisted installer, referenced by the generated clusterinstances unless WithClusterTemplateRef is used.
+	DefaultClusterTemplatesName = "ai-cluster-templates-v1"
+	// DefaultNodeTemplatesName is the configmap of the node templates installed by the siteconfig operator for the
+	// assisted installer, referenced by the generated clusterinstances unless WithNodeTemplateRef is used.
+	DefaultNodeTemplatesName = "ai-node-templates-v1"
+	// DefaultTemplatesNamespace is the namespace of the default templates of the siteconfig operator.
+	DefaultTemplatesNamespace = "open-cluster-management"
+
+	managedClusterKind = "ManagedCluster"
+)
+
+// Generator generates the SiteConfig and ClusterInstance CRs of the spoke described by a SpokeClusterResources, so
+// that the GitOps ZTP flow installs the same spoke as the CRs created directly on the hub. The nodes are the
+// baremetalhosts of the spoke, with the network of the nmstateconfig matching their boot MAC address, along with
+// the nodes added using WithNodes. Like SpokeClusterResources, the first error of the With* methods is kept and
+// returned when generating.
+type Generator struct {
+	spoke               *setup.SpokeClusterResources
+	err                 error
+	nodes               []Node
+	networkTemplate     *networkTemplate
+	clusterTemplateRefs []siteconfigv1alpha1.TemplateRef
+	nodeTemplateRefs    []siteconfigv1alpha1.TemplateRef
+	crTemplates         map[string]string
+	nodeCRTemplates     map[string]map[string]string
+	extraLabels         map[string]map[string]string
+	extraAnnotations    map[string]map[string]string
+}
+
+// NewGenerator creates a new Generator of the CRs of spoke.
+func NewGenerator(spoke *setup.SpokeClusterResources) *Generator {
+	generator := &Generator{spoke: spoke}
+
+	if spoke == nil {
+		generator.err = fmt.Errorf("spoke cannot be nil")
+	}
+
+	return generator
+}
+
+// GetError returns the first error recorded while configuring the generator, or nil.
+func (generator *Generator) GetError() error {
+	return generator.err
+}
+
+// WithNodes adds node entries to the generated CRs, replacing the node of the same hostname, for hosts that are not
+// baremetalhosts of the spoke or whose entry needs to be changed.
+func (generator *Generator) WithNodes(nodes ...Node) *Generator {
+	if generator.err != nil {
+		return generator
+	}
+
+	if len(nodes) == 0 {
+		generator.err = fmt.Errorf("WithNodes: nodes cannot be empty")
+
+		return generator
+	}
+
+	for _, node := range nodes {
+		if err := node.validate(); err != nil {
+			generator.err = fmt.Errorf("WithNodes: %w", err)
+
+			return generator
+		}
+
+		generator.nodes = mergeNode(generator.nodes, node)
+	}
+
+	return generator
+}
+
+// WithNetworkTemplate sets the nmstate config of the nodes without a network of their own, rendered from the
+// text/template configTemplate with the NetworkTemplateData of each node. The interface named interfaceName in the
+// config is matched to the boot MAC address of the node.
+func (generator *Generator) WithNetworkTemplate(interfaceName, configTemplate string) *Generator {
+	if generator.err != nil {
+		return generator
+	}
+
+	if interfaceName == "" {
+		generator.err = fmt.Errorf("WithNetworkTemplate: interface name cannot be empty")
+
+		return generator
+	}
+
+	parsed, err := template.New("network").Option("missingkey=error").Parse(configTemplate)
+	if err != nil {
+		generator.err = fmt.Errorf("WithNetworkTemplate: failed to parse network template: %w", err)
+
+		return generator
+	}
+
+	generator.networkTemplate = &networkTemplate{interfaceName: interfaceName, template: parsed}
+
+	return generator
+}
+
+// WithClusterTemplateRef adds the configmap of cluster templates name in namespace to the template references of
+// the generated clusterinstance, in place of the default templates of the siteconfig operator.
+func (generator *Generator) WithClusterTemplateRef(name, namespace string) *Generator {
+	if generator.err != nil {
+		return generator
+	}
+
+	if name == "" || namespace == "" {
+		generator.err = fmt.Errorf("WithClusterTemplateRef: template name and namespace cannot be empty")
+
+		return generator
+	}
+
+	generator.clusterTemplateRefs = append(generator.clusterTemplateRefs,
+		siteconfigv1alpha1.TemplateRef{Name: name, Namespace: namespace})
+
+	return generator
+}
+
+// WithNodeTemplateRef adds the configmap of node templates name in namespace to the template references of every
+// node of the generated clusterinstance, in place of the default templates of the siteconfig operator.
+func (generator *Generator) WithNodeTemplateRef(name, namespace string) *Generator {
+	if generator.err != nil {
+		return generator
+	}
+
+	if name == "" || namespace == "" {
+		generator.err = fmt.Errorf("WithNodeTemplateRef: template name and namespace cannot be empty")
+
+		return generator
+	}
+
+	generator.nodeTemplateRefs = append(generator.nodeTemplateRefs,
+		siteconfigv1alpha1.TemplateRef{Name: name, Namespace: namespace})
+
+	return generator
+}
+
+// WithCRTemplate overrides the template the siteconfig generator renders the CR of kind, such as
+// AgentClusterInstall or KlusterletAddonConfig, from with the template at path for the cluster of the generated
+// siteconfig.
+func (generator *Generator) WithCRTemplate(kind, path string) *Generator {
+	if generator.err != nil {
+		return generator
+	}
+
+	if kind == "" || path == "" {
+		generator.err = fmt.Errorf("WithCRTemplate: kind and path cannot be empty")
+
+		return generator
+	}
+
+	if generator.crTemplates == nil {
+		generator.crTemplates = map[string]string{}
+	}
+
+	generator.crTemplates[kind] = path
+
+	return generator
+}
+
+// WithNodeCRTemplate overrides the template of the CR of kind, such as BareMetalHost or NMStateConfig, with the
+// template at path for the node hostName of the generated siteconfig.
+func (generator *Generator) WithNodeCRTemplate(hostName, kind, path string) *Generator {
+	if generator.err != nil {
+		return generator
+	}
+
+	if hostName == "" || kind == "" || path == "" {
+		generator.err = fmt.Errorf("WithNodeCRTemplate: hostname, kind and path cannot be empty")
+
+		return generator
+	}
+
+	if generator.nodeCRTemplates == nil {
+		generator.nodeCRTemplates = map[string]map[string]string{}
+	}
+
+	if generator.nodeCRTemplates[hostName] == nil {
+		generator.nodeCRTemplates[hostName] = map[string]string{}
+	}
+
+	generator.nodeCRTemplates[hostName][kind] = path
+
+	return generator
+}
+
+// WithExtraLabels adds labels to the CR of kind rendered from the generated clusterinstance. The labels of kind
+// ManagedCluster are also the cluster labels of the generated siteconfig.
+func (generator *Generator) WithExtraLabels(kind string, labels map[string]string) *Generator {
+	if generator.err != nil {
+		return generator
+	}
+
+	if kind == "" || len(labels) == 0 {
+		generator.err = fmt.Errorf("WithExtraLabels: kind and labels cannot be empty")
+
+		return generator
+	}
+
+	generator.extraLabels = mergeKindMetadata(generator.extraLabels, kind, labels)
+
+	return generator
+}
+
+// WithExtraAnnotations adds annotations to the CR of kind rendered from the generated clusterinstance.
+func (generator *Generator) WithExtraAnnotations(kind string, annotations map[string]string) *Generator {
+	if generator.err != nil {
+		return generator
+	}
+
+	if kind == "" || len(annotations) == 0 {
+		generator.err = fmt.Errorf("WithExtraAnnotations: kind and annotations cannot be empty")
+
+		return generator
+	}
+
+	generator.extraAnnotations = mergeKindMetadata(generator.extraAnnotations, kind, annotations)
+
+	return generator
+}
+
+// RenderClusterInstance writes the generated clusterinstance to writer as YAML.
+func (generator *Generator) RenderClusterInstance(writer io.Writer) error {
+	clusterInstance, err := generator.ClusterInstance()
+	if err != nil {
+		return err
+	}
+
+	return writeYAML(writer, clusterInstance)
+}
+
+// RenderSiteConfig writes the generated siteconfig to writer as YAML.
+func (generator *Generator) RenderSiteConfig(writer io.Writer) error {
+	siteConfig, err := generator.SiteConfig()
+	if err != nil {
+		return err
+	}
+
+	return writeYAML(writer, siteConfig)
+}
+
+// writeYAML serializes object as YAML to writer.
+func writeYAML(writer io.Writer, object any) error {
+	if writer == nil {
+		return fmt.Errorf("writer cannot be nil")
+	}
+
+	content, err := yaml.Marshal(object)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %T: %w", object, err)
+	}
+
+	_, err = writer.Write(content)
+
+	return err
+}
+
+// mergeKindMetadata returns metadata with values merged into the map of kind.
+func mergeKindMetadata(
+	metadata map[string]map[string]string, kind string, values map[string]string) map[string]map[string]string {
+	if metadata == nil {
+		metadata = map[string]map[string]string{}
+	}
+
+	if metadata[kind] == nil {
+		metadata[kind] = map[string]string{}
+	}
+
+	maps.Copy(metadata[kind], values)
+
+	return metadata
+}
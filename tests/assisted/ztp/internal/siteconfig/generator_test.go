@@ -0,0 +1,272 @@
+package siteconfig
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	hivev1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/hive/api/v1"
+	siteconfigv1alpha1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/siteconfig/v1alpha1"
+	"github.com/openshift-kni/eco-goinfra/pkg/secret"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/setup"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpconfig"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const testHubOCPXYVersion = "4.16"
+
+var testCredentials = setup.BMCCredentials{Username: "admin", Password: "password"}
+
+func TestMain(m *testing.M) {
+	setup.SetConfig(&ztpconfig.ZTPConfig{
+		HubConfig: &ztpconfig.HubConfig{
+			HubOCPXYVersion: testHubOCPXYVersion,
+			HubPullSecret: &secret.Builder{
+				Object: &corev1.Secret{
+					Data: map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{}}`)},
+				},
+			},
+		},
+		SpokeConfig: &ztpconfig.SpokeConfig{},
+	})
+
+	os.Exit(m.Run())
+}
+
+func TestClusterInstance(t *testing.T) {
+	spoke := setup.StandardHAProfile(newTestClient(), "spoke").
+		WithBareMetalHosts([]setup.BMHSpec{
+			{Name: "host-0", BMCAddress: "redfish://10.0.0.1/0", BootMACAddress: "52:54:00:00:00:01",
+				Credentials: testCredentials},
+			{Name: "host-1", BMCAddress: "redfish://10.0.0.1/1", BootMACAddress: "52:54:00:00:00:02",
+				Credentials: testCredentials, Hostname: "worker-0", Role: "worker"},
+			{Name: "host-2", BMCAddress: "redfish://10.0.0.1/2", BootMACAddress: "52:54:00:00:00:03",
+				Credentials: testCredentials},
+		}).
+		WithNMStateConfig("host-0", "interfaces: []\n", map[string]string{"eno1": "52:54:00:00:00:01"}).
+		WithLabels(map[string]string{"site": "lab"})
+	assert.Nil(t, spoke.GetError())
+
+	clusterInstance, err := NewGenerator(spoke).
+		WithExtraAnnotations("AgentClusterInstall", map[string]string{"example.com/owner": "ztp"}).
+		WithClusterTemplateRef("custom-cluster-templates", "ztp").
+		ClusterInstance()
+	assert.Nil(t, err)
+
+	assert.Equal(t, siteconfigv1alpha1.GroupVersion.String(), clusterInstance.APIVersion)
+	assert.Equal(t, "ClusterInstance", clusterInstance.Kind)
+	assert.Equal(t, metav1.ObjectMeta{Name: "spoke", Namespace: "spoke"}, clusterInstance.ObjectMeta)
+
+	spec := clusterInstance.Spec
+	assert.Equal(t, spoke.ClusterDeployment.Definition.Spec.BaseDomain, spec.BaseDomain)
+	assert.Equal(t, "spoke", spec.ClusterName)
+	assert.Equal(t, spoke.PullSecret.Definition.Name, spec.PullSecretRef.Name)
+	assert.Equal(t, testHubOCPXYVersion, spec.ClusterImageSetNameRef)
+	assert.Equal(t, siteconfigv1alpha1.ClusterTypeHighlyAvailable, spec.ClusterType)
+	assert.Equal(t, []string{"192.168.254.5"}, spec.ApiVIPs)
+	assert.Equal(t, []string{"192.168.254.10"}, spec.IngressVIPs)
+	assert.Len(t, spec.ClusterNetwork, 1)
+	assert.Len(t, spec.ServiceNetwork, 1)
+	assert.Equal(t, []siteconfigv1alpha1.TemplateRef{{Name: "custom-cluster-templates", Namespace: "ztp"}},
+		spec.TemplateRefs)
+	assert.Equal(t, map[string]map[string]string{"AgentClusterInstall": {"example.com/owner": "ztp"}},
+		spec.ExtraAnnotations)
+
+	assert.Len(t, spec.Nodes, 3)
+	assert.Equal(t, []string{"host-0", "worker-0", "host-2"},
+		[]string{spec.Nodes[0].HostName, spec.Nodes[1].HostName, spec.Nodes[2].HostName})
+	assert.Equal(t, []string{"master", "worker", "master"},
+		[]string{spec.Nodes[0].Role, spec.Nodes[1].Role, spec.Nodes[2].Role})
+	assert.Equal(t, "redfish://10.0.0.1/1", spec.Nodes[1].BmcAddress)
+	assert.Equal(t, "host-1-bmc-secret", spec.Nodes[1].BmcCredentialsName.Name)
+	assert.Equal(t, "52:54:00:00:00:02", spec.Nodes[1].BootMACAddress)
+	assert.Equal(t, "UEFI", string(spec.Nodes[1].BootMode))
+	assert.Equal(t, "52:54:00:00:00:01", spec.Nodes[0].NodeNetwork.Interfaces[0].MacAddress)
+	assert.Nil(t, spec.Nodes[1].NodeNetwork)
+	assert.Equal(t, []siteconfigv1alpha1.TemplateRef{
+		{Name: DefaultNodeTemplatesName, Namespace: DefaultTemplatesNamespace},
+	}, spec.Nodes[0].TemplateRefs)
+}
+
+func TestWithNodesAndNetworkTemplate(t *testing.T) {
+	spoke := setup.SNOProfile(newTestClient(), "spoke")
+
+	generator := NewGenerator(spoke).
+		WithNodes(Node{
+			HostName: "sno", BMCAddress: "idrac-virtualmedia://10.0.0.2", BMCCredentialsName: "sno-bmc",
+			BootMACAddress: "52:54:00:00:01:01",
+		}).
+		WithNetworkTemplate("eno1", `{{ .HostName }}-{{ .Role }}-{{ .Index }}: {{ .BootMACAddress }}`)
+
+	clusterInstance, err := generator.ClusterInstance()
+	assert.Nil(t, err)
+	assert.Equal(t, siteconfigv1alpha1.ClusterTypeSNO, clusterInstance.Spec.ClusterType)
+	assert.Len(t, clusterInstance.Spec.Nodes, 1)
+
+	node := clusterInstance.Spec.Nodes[0]
+	assert.Equal(t, "master", node.Role)
+	assert.Equal(t, []*agentInstallV1Beta1.Interface{{Name: "eno1", MacAddress: "52:54:00:00:01:01"}},
+		node.NodeNetwork.Interfaces)
+	assert.Equal(t, "sno-master-0: 52:54:00:00:01:01", node.NodeNetwork.NetConfig.String())
+
+	_, err = NewGenerator(spoke).WithNodes(Node{
+		HostName: "sno", BMCAddress: "redfish://10.0.0.2", BMCCredentialsName: "sno-bmc",
+		BootMACAddress: "52:54:00:00:01:01",
+	}).WithNetworkTemplate("eno1", "address: {{ .Gateway }}").ClusterInstance()
+	assert.ErrorContains(t, err, "failed to render the network of node sno: template: network:1:12: "+
+		`executing "network" at <.Gateway>: can't evaluate field Gateway`)
+
+	_, err = NewGenerator(spoke).WithNodes(Node{
+		HostName: "sno", BMCAddress: "redfish://10.0.0.2", BMCCredentialsName: "sno-bmc",
+		BootMACAddress: "52:54:00:00:01:01",
+	}).WithNetworkTemplate("eno1", "interfaces: [").ClusterInstance()
+	assert.ErrorContains(t, err, "failed to render the network of node sno: rendered network is not valid YAML")
+}
+
+func TestGeneratorErrors(t *testing.T) {
+	validNode := Node{
+		HostName: "sno", BMCAddress: "redfish://10.0.0.2", BMCCredentialsName: "sno-bmc",
+		BootMACAddress: "52:54:00:00:01:01",
+	}
+
+	testCases := []struct {
+		name          string
+		generator     func(*setup.SpokeClusterResources) *Generator
+		expectedError string
+	}{
+		{
+			name:          "nil spoke",
+			generator:     func(*setup.SpokeClusterResources) *Generator { return NewGenerator(nil) },
+			expectedError: "spoke cannot be nil",
+		},
+		{
+			name:          "no nodes",
+			generator:     NewGenerator,
+			expectedError: "spoke spoke has no nodes, baremetalhosts must be added or nodes set using WithNodes",
+		},
+		{
+			name: "invalid boot mac",
+			generator: func(spoke *setup.SpokeClusterResources) *Generator {
+				node := validNode
+				node.BootMACAddress = "52:54:00"
+
+				return NewGenerator(spoke).WithNodes(node)
+			},
+			expectedError: `WithNodes: invalid boot MAC address "52:54:00" for node sno: address 52:54:00: ` +
+				"invalid MAC address",
+		},
+		{
+			name: "invalid role",
+			generator: func(spoke *setup.SpokeClusterResources) *Generator {
+				node := validNode
+				node.Role = "arbiter"
+
+				return NewGenerator(spoke).WithNodes(node)
+			},
+			expectedError: `WithNodes: invalid role "arbiter" for node sno, must be master or worker`,
+		},
+		{
+			name: "missing bmc",
+			generator: func(spoke *setup.SpokeClusterResources) *Generator {
+				node := validNode
+				node.BMCCredentialsName = ""
+
+				return NewGenerator(spoke).WithNodes(node)
+			},
+			expectedError: "WithNodes: node sno must have a BMC address and credentials",
+		},
+		{
+			name: "invalid network template",
+			generator: func(spoke *setup.SpokeClusterResources) *Generator {
+				return NewGenerator(spoke).WithNetworkTemplate("eno1", "{{ .HostName")
+			},
+			expectedError: "WithNetworkTemplate: failed to parse network template: template: network:1: " +
+				"unclosed action",
+		},
+		{
+			name: "unknown node cr template",
+			generator: func(spoke *setup.SpokeClusterResources) *Generator {
+				return NewGenerator(spoke).WithNodes(validNode).
+					WithNodeCRTemplate("other", "BareMetalHost", "templates/bmh.yaml")
+			},
+			expectedError: "node CR templates are set for unknown node other",
+		},
+		{
+			name: "empty template ref",
+			generator: func(spoke *setup.SpokeClusterResources) *Generator {
+				return NewGenerator(spoke).WithNodeTemplateRef("", "ztp")
+			},
+			expectedError: "WithNodeTemplateRef: template name and namespace cannot be empty",
+		},
+		{
+			name: "no agentclusterinstall",
+			generator: func(*setup.SpokeClusterResources) *Generator {
+				return NewGenerator(setup.NewSpokeCluster(newTestClient()).WithName("spoke").WithDefaultNamespace().
+					WithDefaultPullSecret().WithDefaultClusterDeployment()).WithNodes(validNode)
+			},
+			expectedError: "agentclusterinstall must be defined before generating the spoke CRs",
+		},
+	}
+
+	for _, testCase := range testCases {
+		_, err := testCase.generator(setup.SNOProfile(newTestClient(), "spoke")).ClusterInstance()
+		assert.EqualError(t, err, testCase.expectedError, testCase.name)
+	}
+}
+
+func TestRenderSiteConfig(t *testing.T) {
+	spoke := setup.CompactProfile(newTestClient(), "spoke").
+		WithBareMetalHosts([]setup.BMHSpec{
+			{Name: "master-0", BMCAddress: "redfish://10.0.0.1/0", BootMACAddress: "52:54:00:00:00:01",
+				Credentials: testCredentials},
+		}).
+		WithLabels(map[string]string{"site": "lab"}).
+		WithDefaultManagedCluster()
+
+	generator := NewGenerator(spoke).
+		WithExtraLabels("ManagedCluster", map[string]string{"du-profile": "4.16"}).
+		WithCRTemplate("KlusterletAddonConfig", "templates/klusterlet.yaml").
+		WithNodeCRTemplate("master-0", "BareMetalHost", "templates/bmh.yaml").
+		WithNetworkTemplate("eno1", "interfaces: []\n")
+
+	var rendered bytes.Buffer
+
+	assert.Nil(t, generator.RenderSiteConfig(&rendered))
+
+	siteConfig := &SiteConfig{}
+	assert.Nil(t, yaml.Unmarshal(rendered.Bytes(), siteConfig))
+	assert.Equal(t, metav1.TypeMeta{APIVersion: SiteConfigAPIVersion, Kind: SiteConfigKind}, siteConfig.TypeMeta)
+	assert.Equal(t, testHubOCPXYVersion, siteConfig.Spec.ClusterImageSetNameRef)
+	assert.Len(t, siteConfig.Spec.Clusters, 1)
+
+	cluster := siteConfig.Spec.Clusters[0]
+	assert.Equal(t, "spoke", cluster.ClusterName)
+	assert.Equal(t, "lab", cluster.ClusterLabels["site"])
+	assert.Equal(t, "4.16", cluster.ClusterLabels["du-profile"])
+	assert.Equal(t, map[string]string{"KlusterletAddonConfig": "templates/klusterlet.yaml"}, cluster.CRTemplates)
+	assert.Len(t, cluster.Nodes, 1)
+	assert.Equal(t, map[string]string{"BareMetalHost": "templates/bmh.yaml"}, cluster.Nodes[0].CRTemplates)
+	assert.Equal(t, "interfaces: []\n", cluster.Nodes[0].NodeNetwork.NetConfig.String())
+
+	rendered.Reset()
+	assert.Nil(t, generator.RenderClusterInstance(&rendered))
+	assert.Contains(t, rendered.String(), "kind: ClusterInstance\n")
+	assert.Contains(t, rendered.String(), "  - name: ai-cluster-templates-v1\n    namespace: open-cluster-management\n")
+	assert.EqualError(t, generator.RenderSiteConfig(nil), "writer cannot be nil")
+}
+
+func newTestClient() *clients.Settings {
+	return clients.GetTestClients(clients.TestClientParams{
+		SchemeAttachers: []clients.SchemeAttacher{
+			v1beta1.AddToScheme,
+			agentInstallV1Beta1.AddToScheme,
+			hivev1.AddToScheme,
+		},
+	})
+}
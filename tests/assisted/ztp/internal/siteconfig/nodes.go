@@ -0,0 +1,200 @@
+package siteconfig
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"text/template"
+
+	bmhv1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	"github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/models"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	bmacHostnameAnnotation = "bmac.agent-install.openshift.io/hostname"
+	bmacRoleAnnotation     = "bmac.agent-install.openshift.io/role"
+)
+
+// Node is a node entry of the generated CRs. BMCCredentialsName is the secret holding the BMC username and password
+// in the spoke namespace. An empty Role is set to master for the control-plane agents of the agentclusterinstall
+// and to worker for the remaining nodes, in order.
+type Node struct {
+	HostName               string
+	Role                   string
+	BMCAddress             string
+	BMCCredentialsName     string
+	BootMACAddress         string
+	BootMode               bmhv1alpha1.BootMode
+	RootDeviceHints        *bmhv1alpha1.RootDeviceHints
+	NodeNetwork            *agentInstallV1Beta1.NMStateConfigSpec
+	NodeLabels             map[string]string
+	InstallerArgs          string
+	IgnitionConfigOverride string
+}
+
+// NetworkTemplateData is the data the network template set using WithNetworkTemplate is rendered with for a node.
+// Index is the position of the node in the generated CRs.
+type NetworkTemplateData struct {
+	HostName       string
+	Role           string
+	BootMACAddress string
+	Index          int
+}
+
+// networkTemplate is the nmstate config template of the nodes without a network of their own.
+type networkTemplate struct {
+	interfaceName string
+	template      *template.Template
+}
+
+// resolveNodes returns the nodes of the baremetalhosts of the spoke merged with the nodes set using WithNodes, with
+// their network and role filled in.
+func (generator *Generator) resolveNodes() ([]Node, error) {
+	var nodes []Node
+
+	for _, bareMetalHost := range generator.spoke.BareMetalHosts {
+		nodes = append(nodes, generator.bareMetalHostNode(bareMetalHost.Definition))
+	}
+
+	for _, node := range generator.nodes {
+		nodes = mergeNode(nodes, node)
+	}
+
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("spoke %s has no nodes, baremetalhosts must be added or nodes set using WithNodes",
+			generator.spoke.Name)
+	}
+
+	masters := generator.spoke.AgentClusterInstall.Definition.Spec.ProvisionRequirements.ControlPlaneAgents
+
+	for _, node := range nodes {
+		if err := node.validate(); err != nil {
+			return nil, err
+		}
+
+		if node.Role == string(models.HostRoleMaster) {
+			masters--
+		}
+	}
+
+	for index := range nodes {
+		node := &nodes[index]
+
+		if node.Role == "" {
+			node.Role = string(models.HostRoleWorker)
+
+			if masters > 0 {
+				node.Role = string(models.HostRoleMaster)
+				masters--
+			}
+		}
+
+		if node.NodeNetwork == nil && generator.networkTemplate != nil {
+			nodeNetwork, err := generator.networkTemplate.render(NetworkTemplateData{
+				HostName: node.HostName, Role: node.Role, BootMACAddress: node.BootMACAddress, Index: index,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to render the network of node %s: %w", node.HostName, err)
+			}
+
+			node.NodeNetwork = nodeNetwork
+		}
+	}
+
+	for hostName := range generator.nodeCRTemplates {
+		if !slices.ContainsFunc(nodes, func(node Node) bool { return node.HostName == hostName }) {
+			return nil, fmt.Errorf("node CR templates are set for unknown node %s", hostName)
+		}
+	}
+
+	return nodes, nil
+}
+
+// bareMetalHostNode returns the node of bareMetalHost, whose network is the nmstateconfig of the spoke listing its
+// boot MAC address.
+func (generator *Generator) bareMetalHostNode(bareMetalHost *bmhv1alpha1.BareMetalHost) Node {
+	node := Node{
+		HostName:           bareMetalHost.Annotations[bmacHostnameAnnotation],
+		Role:               bareMetalHost.Annotations[bmacRoleAnnotation],
+		BMCAddress:         bareMetalHost.Spec.BMC.Address,
+		BMCCredentialsName: bareMetalHost.Spec.BMC.CredentialsName,
+		BootMACAddress:     bareMetalHost.Spec.BootMACAddress,
+		BootMode:           bareMetalHost.Spec.BootMode,
+		RootDeviceHints:    bareMetalHost.Spec.RootDeviceHints,
+	}
+
+	if node.HostName == "" {
+		node.HostName = bareMetalHost.Name
+	}
+
+	for _, nmStateConfig := range generator.spoke.NMStateConfigs {
+		if slices.ContainsFunc(nmStateConfig.Definition.Spec.Interfaces, func(nic *agentInstallV1Beta1.Interface) bool {
+			return strings.EqualFold(nic.MacAddress, node.BootMACAddress)
+		}) {
+			node.NodeNetwork = nmStateConfig.Definition.Spec.DeepCopy()
+
+			break
+		}
+	}
+
+	return node
+}
+
+// validate checks the node has a valid hostname, role, BMC and boot MAC address.
+func (node Node) validate() error {
+	if errs := validation.IsDNS1123Subdomain(node.HostName); len(errs) > 0 {
+		return fmt.Errorf("invalid hostname %q for node: %s", node.HostName, strings.Join(errs, ", "))
+	}
+
+	if node.Role != "" && node.Role != string(models.HostRoleMaster) && node.Role != string(models.HostRoleWorker) {
+		return fmt.Errorf("invalid role %q for node %s, must be %s or %s",
+			node.Role, node.HostName, models.HostRoleMaster, models.HostRoleWorker)
+	}
+
+	if node.BMCAddress == "" || node.BMCCredentialsName == "" {
+		return fmt.Errorf("node %s must have a BMC address and credentials", node.HostName)
+	}
+
+	if _, err := net.ParseMAC(node.BootMACAddress); err != nil {
+		return fmt.Errorf("invalid boot MAC address %q for node %s: %w", node.BootMACAddress, node.HostName, err)
+	}
+
+	return nil
+}
+
+// render returns the nmstate config rendered for the node of data.
+func (network *networkTemplate) render(data NetworkTemplateData) (*agentInstallV1Beta1.NMStateConfigSpec, error) {
+	var config bytes.Buffer
+
+	if err := network.template.Execute(&config, data); err != nil {
+		return nil, err
+	}
+
+	if _, err := yaml.YAMLToJSON(config.Bytes()); err != nil {
+		return nil, fmt.Errorf("rendered network is not valid YAML: %w", err)
+	}
+
+	return &agentInstallV1Beta1.NMStateConfigSpec{
+		Interfaces: []*agentInstallV1Beta1.Interface{
+			{Name: network.interfaceName, MacAddress: data.BootMACAddress},
+		},
+		NetConfig: agentInstallV1Beta1.NetConfig{Raw: config.Bytes()},
+	}, nil
+}
+
+// mergeNode returns nodes with node replacing the node of the same hostname, or appended.
+func mergeNode(nodes []Node, node Node) []Node {
+	index := slices.IndexFunc(nodes, func(existing Node) bool { return existing.HostName == node.HostName })
+	if index < 0 {
+		return append(nodes, node)
+	}
+
+	nodes[index] = node
+
+	return nodes
+}
@@ -0,0 +1,133 @@
+package siteconfig
+
+import (
+	bmhv1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
+	siteconfigv1alpha1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/siteconfig/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// SiteConfigAPIVersion is the apiVersion of the siteconfigs rendered by the ztp siteconfig generator.
+	SiteConfigAPIVersion = "ran.openshift.io/v1"
+	// SiteConfigKind is the kind of the siteconfigs rendered by the ztp siteconfig generator.
+	SiteConfigKind = "SiteConfig"
+)
+
+// SiteConfig is a siteconfig of the ztp siteconfig generator. Only the fields set from a SpokeClusterResources are
+// defined, since the type is not vendored.
+type SiteConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec SiteConfigSpec `json:"spec"`
+}
+
+// SiteConfigSpec is the spec of a siteconfig.
+type SiteConfigSpec struct {
+	BaseDomain             string                      `json:"baseDomain"`
+	PullSecretRef          corev1.LocalObjectReference `json:"pullSecretRef"`
+	ClusterImageSetNameRef string                      `json:"clusterImageSetNameRef"`
+	SSHPublicKey           string                      `json:"sshPublicKey,omitempty"`
+	Clusters               []SiteConfigCluster         `json:"clusters"`
+}
+
+// SiteConfigCluster is a cluster of a siteconfig. CRTemplates overrides the templates the CRs of the cluster are
+// rendered from, by kind.
+type SiteConfigCluster struct {
+	ClusterName            string                                   `json:"clusterName"`
+	ClusterType            siteconfigv1alpha1.ClusterType           `json:"clusterType,omitempty"`
+	NetworkType            string                                   `json:"networkType,omitempty"`
+	ClusterLabels          map[string]string                        `json:"clusterLabels,omitempty"`
+	APIVIPs                []string                                 `json:"apiVIPs,omitempty"`
+	IngressVIPs            []string                                 `json:"ingressVIPs,omitempty"`
+	HoldInstallation       bool                                     `json:"holdInstallation,omitempty"`
+	AdditionalNTPSources   []string                                 `json:"additionalNTPSources,omitempty"`
+	MachineNetwork         []siteconfigv1alpha1.MachineNetworkEntry `json:"machineNetwork,omitempty"`
+	ClusterNetwork         []siteconfigv1alpha1.ClusterNetworkEntry `json:"clusterNetwork,omitempty"`
+	ServiceNetwork         []siteconfigv1alpha1.ServiceNetworkEntry `json:"serviceNetwork,omitempty"`
+	InstallConfigOverrides string                                   `json:"installConfigOverrides,omitempty"`
+	IgnitionConfigOverride string                                   `json:"ignitionConfigOverride,omitempty"`
+	Proxy                  *agentInstallV1Beta1.Proxy               `json:"proxy,omitempty"`
+	CPUArchitecture        siteconfigv1alpha1.CPUArchitecture       `json:"cpuArchitecture,omitempty"`
+	CRTemplates            map[string]string                        `json:"crTemplates,omitempty"`
+	Nodes                  []SiteConfigNode                         `json:"nodes"`
+}
+
+// SiteConfigNode is a node of a siteconfig cluster. CRTemplates overrides the templates the CRs of the node are
+// rendered from, by kind.
+type SiteConfigNode struct {
+	HostName               string                                 `json:"hostName"`
+	Role                   string                                 `json:"role,omitempty"`
+	BmcAddress             string                                 `json:"bmcAddress"`
+	BmcCredentialsName     siteconfigv1alpha1.BmcCredentialsName  `json:"bmcCredentialsName"`
+	BootMACAddress         string                                 `json:"bootMACAddress"`
+	BootMode               bmhv1alpha1.BootMode                   `json:"bootMode,omitempty"`
+	RootDeviceHints        *bmhv1alpha1.RootDeviceHints           `json:"rootDeviceHints,omitempty"`
+	NodeNetwork            *agentInstallV1Beta1.NMStateConfigSpec `json:"nodeNetwork,omitempty"`
+	NodeLabels             map[string]string                      `json:"nodeLabels,omitempty"`
+	InstallerArgs          string                                 `json:"installerArgs,omitempty"`
+	IgnitionConfigOverride string                                 `json:"ignitionConfigOverride,omitempty"`
+	CRTemplates            map[string]string                      `json:"crTemplates,omitempty"`
+}
+
+// SiteConfig returns the siteconfig of the spoke, with a single cluster, generated from the same inputs as
+// ClusterInstance. The siteconfig generator has no extra annotations nor template references, so the labels of kind
+// ManagedCluster become the cluster labels and the CR templates set using WithCRTemplate and WithNodeCRTemplate are
+// used instead.
+func (generator *Generator) SiteConfig() (*SiteConfig, error) {
+	clusterInstance, err := generator.ClusterInstance()
+	if err != nil {
+		return nil, err
+	}
+
+	spec := clusterInstance.Spec
+	cluster := SiteConfigCluster{
+		ClusterName:            spec.ClusterName,
+		ClusterType:            spec.ClusterType,
+		NetworkType:            spec.NetworkType,
+		ClusterLabels:          spec.ExtraLabels[managedClusterKind],
+		APIVIPs:                spec.ApiVIPs,
+		IngressVIPs:            spec.IngressVIPs,
+		HoldInstallation:       spec.HoldInstallation,
+		AdditionalNTPSources:   spec.AdditionalNTPSources,
+		MachineNetwork:         spec.MachineNetwork,
+		ClusterNetwork:         spec.ClusterNetwork,
+		ServiceNetwork:         spec.ServiceNetwork,
+		InstallConfigOverrides: spec.InstallConfigOverrides,
+		IgnitionConfigOverride: spec.IgnitionConfigOverride,
+		Proxy:                  spec.Proxy,
+		CPUArchitecture:        spec.CPUArchitecture,
+		CRTemplates:            generator.crTemplates,
+	}
+
+	for _, node := range spec.Nodes {
+		cluster.Nodes = append(cluster.Nodes, SiteConfigNode{
+			HostName:               node.HostName,
+			Role:                   node.Role,
+			BmcAddress:             node.BmcAddress,
+			BmcCredentialsName:     node.BmcCredentialsName,
+			BootMACAddress:         node.BootMACAddress,
+			BootMode:               node.BootMode,
+			RootDeviceHints:        node.RootDeviceHints,
+			NodeNetwork:            node.NodeNetwork,
+			NodeLabels:             node.NodeLabels,
+			InstallerArgs:          node.InstallerArgs,
+			IgnitionConfigOverride: node.IgnitionConfigOverride,
+			CRTemplates:            generator.nodeCRTemplates[node.HostName],
+		})
+	}
+
+	return &SiteConfig{
+		TypeMeta:   metav1.TypeMeta{APIVersion: SiteConfigAPIVersion, Kind: SiteConfigKind},
+		ObjectMeta: clusterInstance.ObjectMeta,
+		Spec: SiteConfigSpec{
+			BaseDomain:             spec.BaseDomain,
+			PullSecretRef:          spec.PullSecretRef,
+			ClusterImageSetNameRef: spec.ClusterImageSetNameRef,
+			SSHPublicKey:           spec.SSHPublicKey,
+			Clusters:               []SiteConfigCluster{cluster},
+		},
+	}, nil
+}
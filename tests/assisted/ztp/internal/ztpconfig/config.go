@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/golang/glog"
 	"github.com/kelseyhightower/envconfig"
@@ -16,8 +17,17 @@ import (
 	"github.com/openshift-kni/eco-gotests/tests/assisted/internal/assistedconfig"
 	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/find"
 	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
-	"github.com/openshift-kni/eco-gotests/tests/internal/cluster"
 	. "github.com/openshift-kni/eco-gotests/tests/internal/inittools"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// DefaultHubPullSecretNamespace is the namespace HubPullSecret is loaded from when HubPullSecretNamespace is
+	// not set.
+	DefaultHubPullSecretNamespace = "openshift-config"
+	// DefaultHubPullSecretName is the name of the secret HubPullSecret is loaded from when HubPullSecretName is not
+	// set.
+	DefaultHubPullSecretName = "pull-secret"
 )
 
 // ZTPConfig type contains ztp configuration.
@@ -39,6 +49,11 @@ type HubConfig struct {
 	HubInstallConfig           *configmap.Builder
 	HubPullSecretOverride      map[string][]byte
 	HubPullSecretOverridePath  string `envconfig:"ECO_ASSISTED_ZTP_HUB_PULL_SECRET_OVERRIDE_PATH"`
+	HubPullSecretNamespace     string `envconfig:"ECO_ASSISTED_ZTP_HUB_PULL_SECRET_NAMESPACE"`
+	HubPullSecretName          string `envconfig:"ECO_ASSISTED_ZTP_HUB_PULL_SECRET_NAME"`
+	HubImageServiceURL         string `envconfig:"ECO_ASSISTED_ZTP_HUB_IMAGE_SERVICE_URL"`
+	HubTangServerImage         string `envconfig:"ECO_ASSISTED_ZTP_HUB_TANG_SERVER_IMAGE"`
+	HubProxyServerImage        string `envconfig:"ECO_ASSISTED_ZTP_HUB_PROXY_SERVER_IMAGE"`
 }
 
 // SpokeConfig contains environment information related to the spoke cluster.
@@ -49,10 +64,24 @@ type SpokeConfig struct {
 	SpokeClusterName         string
 	SpokeKubeConfig          string `envconfig:"ECO_ASSISTED_ZTP_SPOKE_KUBECONFIG"`
 	SpokeClusterImageSet     string `envconfig:"ECO_ASSISTED_ZTP_SPOKE_CLUSTERIMAGESET"`
+	SpokeBaseDomain          string `envconfig:"ECO_ASSISTED_ZTP_SPOKE_BASE_DOMAIN"`
+	SpokeAPIVIP              string `envconfig:"ECO_ASSISTED_ZTP_SPOKE_API_VIP"`
+	SpokeIngressVIP          string `envconfig:"ECO_ASSISTED_ZTP_SPOKE_INGRESS_VIP"`
+	SpokeMachineCIDR         string `envconfig:"ECO_ASSISTED_ZTP_SPOKE_MACHINE_CIDR"`
+	SpokeClusterCIDR         string `envconfig:"ECO_ASSISTED_ZTP_SPOKE_CLUSTER_CIDR"`
+	SpokeServiceCIDR         string `envconfig:"ECO_ASSISTED_ZTP_SPOKE_SERVICE_CIDR"`
+	SpokeIPv6APIVIP          string `envconfig:"ECO_ASSISTED_ZTP_SPOKE_IPV6_API_VIP"`
+	SpokeIPv6IngressVIP      string `envconfig:"ECO_ASSISTED_ZTP_SPOKE_IPV6_INGRESS_VIP"`
+	SpokeIPv6MachineCIDR     string `envconfig:"ECO_ASSISTED_ZTP_SPOKE_IPV6_MACHINE_CIDR"`
+	SpokeIPv6ClusterCIDR     string `envconfig:"ECO_ASSISTED_ZTP_SPOKE_IPV6_CLUSTER_CIDR"`
+	SpokeIPv6ServiceCIDR     string `envconfig:"ECO_ASSISTED_ZTP_SPOKE_IPV6_SERVICE_CIDR"`
+	SpokeAgentSelector       string `envconfig:"ECO_ASSISTED_ZTP_SPOKE_AGENT_SELECTOR"`
 	SpokeClusterDeployment   *hive.ClusterDeploymentBuilder
 	SpokeAgentClusterInstall *assisted.AgentClusterInstallBuilder
 	SpokeInfraEnv            *assisted.InfraEnvBuilder
 	SpokeInstallConfig       *configmap.Builder
+	SpokeWaitInterval        time.Duration `envconfig:"ECO_ASSISTED_ZTP_SPOKE_WAIT_INTERVAL"`
+	SpokeWaitTimeout         time.Duration `envconfig:"ECO_ASSISTED_ZTP_SPOKE_WAIT_TIMEOUT"`
 }
 
 // NewZTPConfig returns instance of ZTPConfig type.
@@ -135,7 +164,10 @@ func (ztpconfig *ZTPConfig) newHubConfig() error {
 		}
 	}
 
-	ztpconfig.HubConfig.HubPullSecret, err = cluster.GetOCPPullSecret(ztpconfig.HubConfig.HubAPIClient)
+	pullSecretNamespace, pullSecretName := ztpconfig.HubConfig.HubPullSecretLocation()
+
+	ztpconfig.HubConfig.HubPullSecret, err =
+		PullHubPullSecret(ztpconfig.HubConfig.HubAPIClient, pullSecretNamespace, pullSecretName)
 	if err != nil {
 		return err
 	}
@@ -257,3 +289,35 @@ func (ztpconfig *ZTPConfig) HubAssistedImageServicePod() *pod.Builder {
 
 	return ztpconfig.hubAssistedImageServicePod
 }
+
+// HubPullSecretLocation returns the namespace and name of the secret HubPullSecret is loaded from, the
+// openshift-config/pull-secret global pull-secret unless overridden.
+func (hubconfig *HubConfig) HubPullSecretLocation() (string, string) {
+	namespace := hubconfig.HubPullSecretNamespace
+	if namespace == "" {
+		namespace = DefaultHubPullSecretNamespace
+	}
+
+	name := hubconfig.HubPullSecretName
+	if name == "" {
+		name = DefaultHubPullSecretName
+	}
+
+	return namespace, name
+}
+
+// PullHubPullSecret pulls the hub pull-secret name from namespace, checking that it holds a docker config.
+func PullHubPullSecret(apiClient *clients.Settings, namespace, name string) (*secret.Builder, error) {
+	glog.V(ztpparams.ZTPLogLevel).Infof("Pulling hub pull-secret %s/%s", namespace, name)
+
+	pullSecret, err := secret.Pull(apiClient, name, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull hub pull-secret %s/%s: %w", namespace, name, err)
+	}
+
+	if len(pullSecret.Object.Data[corev1.DockerConfigJsonKey]) == 0 {
+		return nil, fmt.Errorf("hub pull-secret %s/%s has no %s key", namespace, name, corev1.DockerConfigJsonKey)
+	}
+
+	return pullSecret, nil
+}
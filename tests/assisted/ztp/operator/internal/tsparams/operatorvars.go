@@ -5,6 +5,7 @@ import (
 	hiveextV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/hiveextension/v1beta1"
 	agentInstallV1Beta1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/api/v1beta1"
 	hivev1 "github.com/openshift-kni/eco-goinfra/pkg/schemes/assisted/hive/api/v1"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/setup"
 	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
 	"github.com/openshift-kni/k8sreporter"
 	corev1 "k8s.io/api/core/v1"
@@ -31,6 +32,9 @@ var (
 		{Cr: &agentInstallV1Beta1.AgentList{}},
 	}
 
+	// ArtifactSpokesToCollect tells to the suite which spokes to collect the artifacts of when a test case fails.
+	ArtifactSpokesToCollect = map[string]*setup.SpokeClusterResources{}
+
 	// MCENameSpace is the namespace used by the assisted service.
 	MCENameSpace = "multicluster-engine"
 )
@@ -1,16 +1,20 @@
 package operator_test
 
 import (
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"runtime"
 
+	"github.com/golang/glog"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	"github.com/openshift-kni/eco-goinfra/pkg/reportxml"
 	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/meets"
 	. "github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpinittools"
+	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/internal/ztpparams"
 	"github.com/openshift-kni/eco-gotests/tests/assisted/ztp/operator/internal/tsparams"
 	_ "github.com/openshift-kni/eco-gotests/tests/assisted/ztp/operator/tests"
 	"github.com/openshift-kni/eco-gotests/tests/internal/reporter"
@@ -50,4 +54,23 @@ var _ = JustAfterEach(func() {
 		currentFile,
 		tsparams.ReporterNamespacesToDump,
 		tsparams.ReporterCRDsToDump)
+
+	collectSpokeArtifacts(CurrentSpecReport())
 })
+
+// collectSpokeArtifacts collects the artifacts of the spokes registered by the test cases when the spec failed and
+// failed tests are dumped.
+func collectSpokeArtifacts(report SpecReport) {
+	dumpDir := ZTPConfig.GetDumpFailedTestReportLocation(currentFile)
+	if !report.Failed() || dumpDir == "" {
+		return
+	}
+
+	specDir := filepath.Join(dumpDir, strings.ReplaceAll(report.FullText(), " ", "_"))
+
+	for _, spoke := range tsparams.ArtifactSpokesToCollect {
+		if err := spoke.CollectArtifacts(specDir); err != nil {
+			glog.V(ztpparams.ZTPLogLevel).Infof("Failed to collect artifacts of spoke %s: %v", spoke.Name, err)
+		}
+	}
+}
@@ -58,15 +58,10 @@ var _ = Describe(
 					WithDefaultIPv4AgentClusterInstall().WithDefaultInfraEnv().Create()
 				Expect(err).ToNot(HaveOccurred(), "error creating %s spoke resources", rootfsSpokeName)
 
-				Eventually(func() (string, error) {
-					rootfsSpokeResources.InfraEnv.Object, err = rootfsSpokeResources.InfraEnv.Get()
-					if err != nil {
-						return "", err
-					}
+				tsparams.ArtifactSpokesToCollect[rootfsSpokeName] = rootfsSpokeResources
 
-					return rootfsSpokeResources.InfraEnv.Object.Status.ISODownloadURL, nil
-				}).WithTimeout(time.Minute*3).ProbeEvery(time.Second*3).
-					Should(Not(BeEmpty()), "error waiting for download url to be created")
+				isoDownloadURL, err := rootfsSpokeResources.WaitForDiscoveryISO(time.Minute * 3)
+				Expect(err).ToNot(HaveOccurred(), "error waiting for download url to be created")
 
 				if _, err = os.Stat(rootfsDownloadDir); err != nil {
 					err = os.RemoveAll(rootfsDownloadDir)
@@ -76,7 +71,7 @@ var _ = Describe(
 				err = os.Mkdir(rootfsDownloadDir, 0755)
 				Expect(err).ToNot(HaveOccurred(), "error creating downloads directory")
 
-				err = url.DownloadToDir(rootfsSpokeResources.InfraEnv.Object.Status.ISODownloadURL, rootfsDownloadDir, true)
+				err = url.DownloadToDir(isoDownloadURL, rootfsDownloadDir, true)
 				Expect(err).ToNot(HaveOccurred(), "error downloading ISO")
 
 				err = url.DownloadToDir(rootfsSpokeResources.InfraEnv.Object.Status.BootArtifacts.RootfsURL,
@@ -121,6 +116,8 @@ var _ = Describe(
 			)
 
 			AfterAll(func() {
+				delete(tsparams.ArtifactSpokesToCollect, rootfsSpokeName)
+
 				err = rootfsSpokeResources.Delete()
 				Expect(err).ToNot(HaveOccurred(), "error deleting %s spoke resources", rootfsSpokeName)
 
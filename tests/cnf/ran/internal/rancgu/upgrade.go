@@ -0,0 +1,279 @@
+package rancgu
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/cluster-group-upgrades-operator/pkg/api/clustergroupupgrades/v1alpha1"
+	"github.com/openshift-kni/eco-goinfra/pkg/cgu"
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-gotests/tests/cnf/ran/internal/ranparam"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// BatchTimeoutContinue makes TALM continue with the next batch when a batch times out.
+	BatchTimeoutContinue = "Continue"
+	// BatchTimeoutAbort makes TALM stop the clustergroupupgrade when a batch times out.
+	BatchTimeoutAbort = "Abort"
+)
+
+// UpgradeBuilder provides a struct for building a clustergroupupgrade, through which TALM remediates the managed
+// policies on the selected clusters, in batches of at most the batch size clusters. The first error of the With*
+// methods is kept and returned by Create.
+type UpgradeBuilder struct {
+	// Definition of the clustergroupupgrade.
+	Definition *v1alpha1.ClusterGroupUpgrade
+	apiClient  *clients.Settings
+	err        error
+}
+
+// NewUpgradeBuilder creates a new instance of UpgradeBuilder. The clustergroupupgrade remediates one cluster at a
+// time and is created disabled, so that TALM only starts remediating once it is enabled, unless WithBatchSize and
+// WithEnable are used.
+func NewUpgradeBuilder(apiClient *clients.Settings, name, nsname string) *UpgradeBuilder {
+	builder := &UpgradeBuilder{
+		apiClient: apiClient,
+		Definition: &v1alpha1.ClusterGroupUpgrade{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: nsname},
+			Spec: v1alpha1.ClusterGroupUpgradeSpec{
+				Enable:              ptr.To(false),
+				RemediationStrategy: &v1alpha1.RemediationStrategySpec{MaxConcurrency: 1},
+			},
+		},
+	}
+
+	if apiClient == nil {
+		builder.err = fmt.Errorf("apiClient cannot be nil")
+
+		return builder
+	}
+
+	if name == "" {
+		builder.err = fmt.Errorf("clustergroupupgrade name cannot be empty")
+
+		return builder
+	}
+
+	if nsname == "" {
+		builder.err = fmt.Errorf("clustergroupupgrade namespace cannot be empty")
+	}
+
+	return builder
+}
+
+// GetError returns the first error recorded while building the clustergroupupgrade, or nil.
+func (builder *UpgradeBuilder) GetError() error {
+	return builder.err
+}
+
+// WithClusters adds clusters, by name, to the clusters remediated by the clustergroupupgrade.
+func (builder *UpgradeBuilder) WithClusters(clusters ...string) *UpgradeBuilder {
+	if builder.err != nil {
+		return builder
+	}
+
+	for _, cluster := range clusters {
+		if cluster == "" {
+			builder.err = fmt.Errorf("WithClusters: cluster name cannot be empty")
+
+			return builder
+		}
+	}
+
+	builder.Definition.Spec.Clusters = append(builder.Definition.Spec.Clusters, clusters...)
+
+	return builder
+}
+
+// WithClusterLabelSelector adds a selector of the clusters remediated by the clustergroupupgrade. The clusters
+// matching any of the selectors are remediated, along with the clusters added by WithClusters.
+func (builder *UpgradeBuilder) WithClusterLabelSelector(selector metav1.LabelSelector) *UpgradeBuilder {
+	if builder.err != nil {
+		return builder
+	}
+
+	if len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0 {
+		builder.err = fmt.Errorf("WithClusterLabelSelector: cluster label selector cannot be empty")
+
+		return builder
+	}
+
+	builder.Definition.Spec.ClusterLabelSelectors = append(builder.Definition.Spec.ClusterLabelSelectors, selector)
+
+	return builder
+}
+
+// WithManagedPolicies adds policies, by name, to the policies the clustergroupupgrade remediates, in order.
+func (builder *UpgradeBuilder) WithManagedPolicies(policies ...string) *UpgradeBuilder {
+	if builder.err != nil {
+		return builder
+	}
+
+	for _, policy := range policies {
+		if policy == "" {
+			builder.err = fmt.Errorf("WithManagedPolicies: policy name cannot be empty")
+
+			return builder
+		}
+	}
+
+	builder.Definition.Spec.ManagedPolicies = append(builder.Definition.Spec.ManagedPolicies, policies...)
+
+	return builder
+}
+
+// WithCanaries adds canary clusters, remediated in batches of one cluster before the other clusters.
+func (builder *UpgradeBuilder) WithCanaries(clusters ...string) *UpgradeBuilder {
+	if builder.err != nil {
+		return builder
+	}
+
+	for _, cluster := range clusters {
+		if cluster == "" {
+			builder.err = fmt.Errorf("WithCanaries: canary cluster name cannot be empty")
+
+			return builder
+		}
+	}
+
+	strategy := builder.Definition.Spec.RemediationStrategy
+	strategy.Canaries = append(strategy.Canaries, clusters...)
+
+	return builder
+}
+
+// WithBatchSize sets the maximum number of clusters remediated at the same time, the size of the batches.
+func (builder *UpgradeBuilder) WithBatchSize(batchSize int) *UpgradeBuilder {
+	if builder.err != nil {
+		return builder
+	}
+
+	if batchSize < 1 {
+		builder.err = fmt.Errorf("WithBatchSize: batch size must be at least 1, got %d", batchSize)
+
+		return builder
+	}
+
+	builder.Definition.Spec.RemediationStrategy.MaxConcurrency = batchSize
+
+	return builder
+}
+
+// WithTimeout sets how long TALM remediates the clusters before the clustergroupupgrade times out. TALM counts the
+// timeout in minutes, so it is rounded up to the next minute.
+func (builder *UpgradeBuilder) WithTimeout(timeout time.Duration) *UpgradeBuilder {
+	if builder.err != nil {
+		return builder
+	}
+
+	if timeout <= 0 {
+		builder.err = fmt.Errorf("WithTimeout: timeout must be positive, got %s", timeout)
+
+		return builder
+	}
+
+	builder.Definition.Spec.RemediationStrategy.Timeout = int(math.Ceil(timeout.Minutes()))
+
+	return builder
+}
+
+// WithBatchTimeoutAction sets what TALM does when a batch times out, BatchTimeoutContinue or BatchTimeoutAbort.
+func (builder *UpgradeBuilder) WithBatchTimeoutAction(action string) *UpgradeBuilder {
+	if builder.err != nil {
+		return builder
+	}
+
+	if action != BatchTimeoutContinue && action != BatchTimeoutAbort {
+		builder.err = fmt.Errorf("WithBatchTimeoutAction: unsupported batch timeout action %q, must be %s or %s",
+			action, BatchTimeoutContinue, BatchTimeoutAbort)
+
+		return builder
+	}
+
+	builder.Definition.Spec.BatchTimeoutAction = action
+
+	return builder
+}
+
+// WithEnable sets whether the clustergroupupgrade is enabled when created, in which case TALM starts remediating
+// right away.
+func (builder *UpgradeBuilder) WithEnable(enable bool) *UpgradeBuilder {
+	if builder.err != nil {
+		return builder
+	}
+
+	builder.Definition.Spec.Enable = ptr.To(enable)
+
+	return builder
+}
+
+// Create creates the clustergroupupgrade on the hub and returns its builder, which the waiters of this package
+// poll. It is an error for the clustergroupupgrade to have no policies or no clusters.
+func (builder *UpgradeBuilder) Create() (*cgu.CguBuilder, error) {
+	if builder == nil {
+		return nil, fmt.Errorf("clustergroupupgrade builder cannot be nil")
+	}
+
+	if builder.err != nil {
+		return nil, builder.err
+	}
+
+	spec := builder.Definition.Spec
+
+	if len(spec.ManagedPolicies) == 0 {
+		return nil, fmt.Errorf("clustergroupupgrade %s has no managed policies", builder.Definition.Name)
+	}
+
+	if len(spec.Clusters) == 0 && len(spec.ClusterLabelSelectors) == 0 {
+		return nil, fmt.Errorf("clustergroupupgrade %s has no clusters or cluster label selectors",
+			builder.Definition.Name)
+	}
+
+	cguBuilder := cgu.NewCguBuilder(builder.apiClient, builder.Definition.Name, builder.Definition.Namespace,
+		spec.RemediationStrategy.MaxConcurrency)
+	if cguBuilder == nil {
+		return nil, fmt.Errorf("failed to define clustergroupupgrade %s", builder.Definition.Name)
+	}
+
+	cguBuilder.Definition.Spec = *spec.DeepCopy()
+
+	glog.V(ranparam.LogLevel).Infof("Creating clustergroupupgrade %s in namespace %s with batch size %d, enabled: %t",
+		builder.Definition.Name, builder.Definition.Namespace, spec.RemediationStrategy.MaxConcurrency, *spec.Enable)
+
+	cguBuilder, err := cguBuilder.Create()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clustergroupupgrade %s: %w", builder.Definition.Name, err)
+	}
+
+	return cguBuilder, nil
+}
+
+// SetEnable enables or disables the existing clustergroupupgrade of cguBuilder. Disabling a clustergroupupgrade in
+// progress makes TALM stop remediating after the current policy.
+func SetEnable(cguBuilder *cgu.CguBuilder, enable bool) (*cgu.CguBuilder, error) {
+	if cguBuilder == nil {
+		return nil, fmt.Errorf("cgu builder cannot be nil")
+	}
+
+	current, err := cguBuilder.Get()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clustergroupupgrade %s: %w", cguBuilder.Definition.Name, err)
+	}
+
+	glog.V(ranparam.LogLevel).Infof("Setting enable of clustergroupupgrade %s in namespace %s to %t",
+		current.Name, current.Namespace, enable)
+
+	cguBuilder.Definition = current
+	cguBuilder.Definition.Spec.Enable = ptr.To(enable)
+
+	cguBuilder, err = cguBuilder.Update(false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update clustergroupupgrade %s: %w", current.Name, err)
+	}
+
+	return cguBuilder, nil
+}
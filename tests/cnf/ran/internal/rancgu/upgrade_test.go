@@ -0,0 +1,134 @@
+package rancgu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift-kni/cluster-group-upgrades-operator/pkg/api/clustergroupupgrades/v1alpha1"
+	"github.com/openshift-kni/eco-goinfra/pkg/cgu"
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+)
+
+func TestUpgradeBuilder(t *testing.T) {
+	testCases := []struct {
+		name          string
+		builder       *UpgradeBuilder
+		expectedError string
+	}{
+		{
+			name:          "nil apiClient",
+			builder:       NewUpgradeBuilder(nil, "upgrade", "ztp-install"),
+			expectedError: "apiClient cannot be nil",
+		},
+		{
+			name:          "empty name",
+			builder:       NewUpgradeBuilder(newTestClient(), "", "ztp-install"),
+			expectedError: "clustergroupupgrade name cannot be empty",
+		},
+		{
+			name:          "empty namespace",
+			builder:       NewUpgradeBuilder(newTestClient(), "upgrade", ""),
+			expectedError: "clustergroupupgrade namespace cannot be empty",
+		},
+		{
+			name:          "no policies",
+			builder:       NewUpgradeBuilder(newTestClient(), "upgrade", "ztp-install").WithClusters("spoke-1"),
+			expectedError: "clustergroupupgrade upgrade has no managed policies",
+		},
+		{
+			name:          "no clusters",
+			builder:       NewUpgradeBuilder(newTestClient(), "upgrade", "ztp-install").WithManagedPolicies("policy"),
+			expectedError: "clustergroupupgrade upgrade has no clusters or cluster label selectors",
+		},
+		{
+			name:          "empty cluster",
+			builder:       newTestBuilder(newTestClient()).WithClusters(""),
+			expectedError: "WithClusters: cluster name cannot be empty",
+		},
+		{
+			name:          "empty selector",
+			builder:       newTestBuilder(newTestClient()).WithClusterLabelSelector(metav1.LabelSelector{}),
+			expectedError: "WithClusterLabelSelector: cluster label selector cannot be empty",
+		},
+		{
+			name:          "empty policy",
+			builder:       newTestBuilder(newTestClient()).WithManagedPolicies(""),
+			expectedError: "WithManagedPolicies: policy name cannot be empty",
+		},
+		{
+			name:          "empty canary",
+			builder:       newTestBuilder(newTestClient()).WithCanaries(""),
+			expectedError: "WithCanaries: canary cluster name cannot be empty",
+		},
+		{
+			name:          "invalid batch size",
+			builder:       newTestBuilder(newTestClient()).WithBatchSize(0),
+			expectedError: "WithBatchSize: batch size must be at least 1, got 0",
+		},
+		{
+			name:          "invalid timeout",
+			builder:       newTestBuilder(newTestClient()).WithTimeout(0),
+			expectedError: "WithTimeout: timeout must be positive, got 0s",
+		},
+		{
+			name:    "invalid batch timeout action",
+			builder: newTestBuilder(newTestClient()).WithBatchTimeoutAction("Retry"),
+			expectedError: `WithBatchTimeoutAction: unsupported batch timeout action "Retry", ` +
+				"must be Continue or Abort",
+		},
+	}
+
+	for _, testCase := range testCases {
+		_, err := testCase.builder.Create()
+		assert.EqualError(t, err, testCase.expectedError, testCase.name)
+	}
+
+	apiClient := newTestClient()
+
+	cguBuilder, err := newTestBuilder(apiClient).Create()
+	assert.Nil(t, err)
+	assert.True(t, cguBuilder.Exists())
+
+	spec := cguBuilder.Definition.Spec
+	assert.Equal(t, []string{"spoke-1"}, spec.Clusters)
+	assert.Equal(t, []metav1.LabelSelector{{MatchLabels: map[string]string{"du-profile": "4.16"}}},
+		spec.ClusterLabelSelectors)
+	assert.Equal(t, []string{"group-du-sno-config-policy", "group-du-sno-log-policy"}, spec.ManagedPolicies)
+	assert.Equal(t, &v1alpha1.RemediationStrategySpec{Canaries: []string{"spoke-1"}, MaxConcurrency: 2, Timeout: 11},
+		spec.RemediationStrategy)
+	assert.Equal(t, BatchTimeoutAbort, spec.BatchTimeoutAction)
+	assert.Equal(t, ptr.To(false), spec.Enable)
+
+	cguBuilder, err = SetEnable(cguBuilder, true)
+	assert.Nil(t, err)
+
+	pulled, err := cgu.Pull(apiClient, "upgrade", "ztp-install")
+	assert.Nil(t, err)
+	assert.Equal(t, ptr.To(true), pulled.Object.Spec.Enable)
+	assert.Equal(t, ptr.To(true), cguBuilder.Object.Spec.Enable)
+
+	_, err = SetEnable(nil, true)
+	assert.EqualError(t, err, "cgu builder cannot be nil")
+}
+
+func newTestBuilder(apiClient *clients.Settings) *UpgradeBuilder {
+	return NewUpgradeBuilder(apiClient, "upgrade", "ztp-install").
+		WithClusters("spoke-1").
+		WithClusterLabelSelector(metav1.LabelSelector{MatchLabels: map[string]string{"du-profile": "4.16"}}).
+		WithManagedPolicies("group-du-sno-config-policy", "group-du-sno-log-policy").
+		WithCanaries("spoke-1").
+		WithBatchSize(2).
+		WithTimeout(10*time.Minute + time.Second).
+		WithBatchTimeoutAction(BatchTimeoutAbort)
+}
+
+func newTestClient(objects ...runtime.Object) *clients.Settings {
+	return clients.GetTestClients(clients.TestClientParams{
+		K8sMockObjects:  objects,
+		SchemeAttachers: []clients.SchemeAttacher{v1alpha1.AddToScheme},
+	})
+}
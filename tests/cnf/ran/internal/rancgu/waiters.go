@@ -0,0 +1,197 @@
+package rancgu
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/cluster-group-upgrades-operator/pkg/api/clustergroupupgrades/v1alpha1"
+	"github.com/openshift-kni/eco-goinfra/pkg/cgu"
+	"github.com/openshift-kni/eco-gotests/tests/cnf/ran/internal/ranparam"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// SucceededType is the type of the condition TALM sets once the clustergroupupgrade is done.
+	SucceededType = "Succeeded"
+	// TimedOutReason is the reason of the Succeeded condition when the clustergroupupgrade timed out.
+	TimedOutReason = "TimedOut"
+	// InProgressReason is the reason of the Succeeded condition while the clustergroupupgrade is not done yet.
+	InProgressReason = v1alpha1.InProgress
+	// NotStartedState is the progress of the clusters TALM did not start remediating yet.
+	NotStartedState = v1alpha1.NotStarted
+)
+
+// progressInterval is how often the clustergroupupgrade is checked by the waiters.
+var progressInterval = 3 * time.Second
+
+// GetClusterProgress returns the remediation progress of each cluster of the clustergroupupgrade, as last pulled
+// into cguBuilder.Object. The clusters of the current batch are InProgress or Completed, those of the previous
+// batches have the state TALM recorded once their batch ended, such as complete or timedout, and the others are
+// NotStarted.
+func GetClusterProgress(cguBuilder *cgu.CguBuilder) map[string]string {
+	progress := map[string]string{}
+
+	if cguBuilder == nil || cguBuilder.Object == nil {
+		return progress
+	}
+
+	status := cguBuilder.Object.Status
+
+	for _, batch := range status.RemediationPlan {
+		for _, cluster := range batch {
+			progress[cluster] = NotStartedState
+		}
+	}
+
+	for _, cluster := range status.Clusters {
+		progress[cluster.Name] = cluster.State
+	}
+
+	for cluster, clusterProgress := range status.Status.CurrentBatchRemediationProgress {
+		if clusterProgress != nil {
+			progress[cluster] = clusterProgress.State
+		}
+	}
+
+	return progress
+}
+
+// WaitForSucceeded waits up to timeout until the clustergroupupgrade of cguBuilder succeeds. It fails early when
+// the clustergroupupgrade ends any other way, such as timing out, and the error lists the progress of every cluster.
+func WaitForSucceeded(cguBuilder *cgu.CguBuilder, timeout time.Duration) error {
+	return waitForSucceededCondition(cguBuilder, false, timeout)
+}
+
+// WaitForTimedOut waits up to timeout until the clustergroupupgrade of cguBuilder times out. It fails early when
+// the clustergroupupgrade ends any other way, such as succeeding, and the error lists the progress of every cluster.
+func WaitForTimedOut(cguBuilder *cgu.CguBuilder, timeout time.Duration) error {
+	return waitForSucceededCondition(cguBuilder, true, timeout)
+}
+
+// WaitForClusterState waits up to timeout until the cluster of the clustergroupupgrade of cguBuilder has the
+// remediation progress state, as returned by GetClusterProgress.
+func WaitForClusterState(cguBuilder *cgu.CguBuilder, cluster, state string, timeout time.Duration) error {
+	if cguBuilder == nil {
+		return fmt.Errorf("cgu builder cannot be nil")
+	}
+
+	if cluster == "" {
+		return fmt.Errorf("cluster name cannot be empty")
+	}
+
+	if state == "" {
+		return fmt.Errorf("state cannot be empty")
+	}
+
+	var current string
+
+	err := pollUpgrade(cguBuilder, timeout, func(upgrade *v1alpha1.ClusterGroupUpgrade) (bool, error) {
+		current = GetClusterProgress(cguBuilder)[cluster]
+
+		return current == state, nil
+	})
+	if err != nil {
+		if current == "" {
+			current = "NotFound"
+		}
+
+		return fmt.Errorf("timed out waiting for cluster %s of clustergroupupgrade %s to be %s, it is %s: %w",
+			cluster, cguBuilder.Definition.Name, state, current, err)
+	}
+
+	return nil
+}
+
+// waitForSucceededCondition waits until the Succeeded condition of the clustergroupupgrade is terminal, that is true
+// or with a reason other than InProgress, and checks that it is true, or has the TimedOut reason when timedOut is
+// true.
+func waitForSucceededCondition(cguBuilder *cgu.CguBuilder, timedOut bool, timeout time.Duration) error {
+	if cguBuilder == nil {
+		return fmt.Errorf("cgu builder cannot be nil")
+	}
+
+	expected := "succeed"
+	if timedOut {
+		expected = "time out"
+	}
+
+	var done *metav1.Condition
+
+	err := pollUpgrade(cguBuilder, timeout, func(upgrade *v1alpha1.ClusterGroupUpgrade) (bool, error) {
+		condition := meta.FindStatusCondition(upgrade.Status.Conditions, SucceededType)
+		if condition == nil || (condition.Status != metav1.ConditionTrue &&
+			(condition.Reason == "" || condition.Reason == InProgressReason)) {
+			return false, nil
+		}
+
+		done = condition
+
+		return true, nil
+	})
+
+	progress := formatProgress(GetClusterProgress(cguBuilder))
+
+	if err != nil {
+		return fmt.Errorf("timed out waiting for clustergroupupgrade %s to %s: %s: %w",
+			cguBuilder.Definition.Name, expected, progress, err)
+	}
+
+	succeeded := done.Status == metav1.ConditionTrue
+	if timedOut {
+		succeeded = done.Status != metav1.ConditionTrue && done.Reason == TimedOutReason
+	}
+
+	if !succeeded {
+		return fmt.Errorf("clustergroupupgrade %s did not %s, %s: %s: %s",
+			cguBuilder.Definition.Name, expected, done.Reason, done.Message, progress)
+	}
+
+	return nil
+}
+
+// pollUpgrade pulls the clustergroupupgrade of cguBuilder until condition is met or timeout elapses. Errors pulling
+// the clustergroupupgrade are logged and retried.
+func pollUpgrade(
+	cguBuilder *cgu.CguBuilder,
+	timeout time.Duration,
+	condition func(upgrade *v1alpha1.ClusterGroupUpgrade) (bool, error)) error {
+	return wait.PollUntilContextTimeout(
+		context.TODO(), min(progressInterval, timeout), timeout, true, func(ctx context.Context) (bool, error) {
+			upgrade, err := cguBuilder.Get()
+			if err != nil {
+				glog.V(ranparam.LogLevel).Infof(
+					"Failed to get clustergroupupgrade %s: %v", cguBuilder.Definition.Name, err)
+
+				return false, nil
+			}
+
+			cguBuilder.Object = upgrade
+
+			glog.V(ranparam.LogLevel).Infof("Clustergroupupgrade %s progress: %s",
+				upgrade.Name, formatProgress(GetClusterProgress(cguBuilder)))
+
+			return condition(upgrade)
+		})
+}
+
+// formatProgress formats the progress of the clusters, sorted by name, as cluster (state) pairs.
+func formatProgress(progress map[string]string) string {
+	if len(progress) == 0 {
+		return "no clusters"
+	}
+
+	clusters := make([]string, 0, len(progress))
+
+	for _, cluster := range slices.Sorted(maps.Keys(progress)) {
+		clusters = append(clusters, fmt.Sprintf("%s (%s)", cluster, progress[cluster]))
+	}
+
+	return strings.Join(clusters, ", ")
+}
@@ -0,0 +1,108 @@
+package rancgu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift-kni/cluster-group-upgrades-operator/pkg/api/clustergroupupgrades/v1alpha1"
+	"github.com/openshift-kni/eco-goinfra/pkg/cgu"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestWaitForSucceeded(t *testing.T) {
+	progressInterval = time.Millisecond
+
+	apiClient := newTestClient(
+		buildDummyUpgrade("succeeded", metav1.ConditionTrue, "Completed"),
+		buildDummyUpgrade("timedout", metav1.ConditionFalse, TimedOutReason),
+		buildDummyUpgrade("progressing", metav1.ConditionFalse, InProgressReason),
+		buildDummyUpgrade("failed", metav1.ConditionFalse, "Failed"),
+	)
+
+	pull := func(name string) *cgu.CguBuilder {
+		cguBuilder, err := cgu.Pull(apiClient, name, "ztp-install")
+		assert.Nil(t, err, name)
+
+		return cguBuilder
+	}
+
+	assert.Nil(t, WaitForSucceeded(pull("succeeded"), time.Second))
+	assert.Nil(t, WaitForTimedOut(pull("timedout"), time.Second))
+
+	assert.EqualError(t, WaitForSucceeded(pull("timedout"), time.Second),
+		"clustergroupupgrade timedout did not succeed, TimedOut: Policy remediation took too long: "+
+			"spoke-1 (complete), spoke-2 (InProgress), spoke-3 (NotStarted)")
+	assert.EqualError(t, WaitForTimedOut(pull("succeeded"), time.Second),
+		"clustergroupupgrade succeeded did not time out, Completed: "+
+			"All clusters are compliant with all the managed policies: "+
+			"spoke-1 (complete), spoke-2 (InProgress), spoke-3 (NotStarted)")
+	assert.EqualError(t, WaitForSucceeded(pull("failed"), time.Second),
+		"clustergroupupgrade failed did not succeed, Failed: Policy remediation failed: "+
+			"spoke-1 (complete), spoke-2 (InProgress), spoke-3 (NotStarted)")
+	assert.EqualError(t, WaitForTimedOut(pull("failed"), time.Second),
+		"clustergroupupgrade failed did not time out, Failed: Policy remediation failed: "+
+			"spoke-1 (complete), spoke-2 (InProgress), spoke-3 (NotStarted)")
+
+	err := WaitForSucceeded(pull("progressing"), 10*time.Millisecond)
+	assert.ErrorContains(t, err, "timed out waiting for clustergroupupgrade progressing to succeed: "+
+		"spoke-1 (complete), spoke-2 (InProgress), spoke-3 (NotStarted)")
+	assert.True(t, wait.Interrupted(err))
+	assert.EqualError(t, WaitForSucceeded(nil, time.Second), "cgu builder cannot be nil")
+}
+
+func TestWaitForClusterState(t *testing.T) {
+	progressInterval = time.Millisecond
+
+	apiClient := newTestClient(buildDummyUpgrade("progressing", metav1.ConditionFalse, InProgressReason))
+
+	cguBuilder, err := cgu.Pull(apiClient, "progressing", "ztp-install")
+	assert.Nil(t, err)
+
+	assert.Equal(t, map[string]string{
+		"spoke-1": "complete", "spoke-2": v1alpha1.InProgress, "spoke-3": NotStartedState,
+	}, GetClusterProgress(cguBuilder))
+	assert.Empty(t, GetClusterProgress(nil))
+
+	assert.Nil(t, WaitForClusterState(cguBuilder, "spoke-2", v1alpha1.InProgress, time.Second))
+	err = WaitForClusterState(cguBuilder, "spoke-3", v1alpha1.Completed, 10*time.Millisecond)
+	assert.ErrorContains(t, err,
+		"timed out waiting for cluster spoke-3 of clustergroupupgrade progressing to be Completed, it is NotStarted")
+	assert.True(t, wait.Interrupted(err))
+	assert.ErrorContains(t, WaitForClusterState(cguBuilder, "spoke-4", v1alpha1.Completed, 10*time.Millisecond),
+		"timed out waiting for cluster spoke-4 of clustergroupupgrade progressing to be Completed, it is NotFound")
+	assert.EqualError(t, WaitForClusterState(cguBuilder, "", v1alpha1.Completed, time.Second),
+		"cluster name cannot be empty")
+	assert.EqualError(t, WaitForClusterState(cguBuilder, "spoke-1", "", time.Second), "state cannot be empty")
+}
+
+func buildDummyUpgrade(name string, status metav1.ConditionStatus, reason string) *v1alpha1.ClusterGroupUpgrade {
+	message := "All clusters are compliant with all the managed policies"
+
+	switch reason {
+	case TimedOutReason:
+		message = "Policy remediation took too long"
+	case "Failed":
+		message = "Policy remediation failed"
+	}
+
+	return &v1alpha1.ClusterGroupUpgrade{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ztp-install"},
+		Spec: v1alpha1.ClusterGroupUpgradeSpec{
+			RemediationStrategy: &v1alpha1.RemediationStrategySpec{MaxConcurrency: 1},
+		},
+		Status: v1alpha1.ClusterGroupUpgradeStatus{
+			Conditions: []metav1.Condition{{
+				Type: SucceededType, Status: status, Reason: reason, Message: message,
+			}},
+			RemediationPlan: [][]string{{"spoke-1"}, {"spoke-2"}, {"spoke-3"}},
+			Clusters:        []v1alpha1.ClusterState{{Name: "spoke-1", State: "complete"}},
+			Status: v1alpha1.UpgradeStatus{
+				CurrentBatchRemediationProgress: map[string]*v1alpha1.ClusterRemediationProgress{
+					"spoke-2": {State: v1alpha1.InProgress},
+				},
+			},
+		},
+	}
+}
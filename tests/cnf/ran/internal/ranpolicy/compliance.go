@@ -0,0 +1,126 @@
+package ranpolicy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-gotests/tests/cnf/ran/internal/ranparam"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+// complianceInterval is how often the compliance of the policies is checked.
+var complianceInterval = 5 * time.Second
+
+// WaitForPoliciesCompliant waits up to timeout until every policy in names, in nsname on the hub, is compliant. On
+// timeout, the error lists the compliance state of the policies that are not compliant yet.
+func WaitForPoliciesCompliant(apiClient *clients.Settings, nsname string, names []string, timeout time.Duration) error {
+	return waitForCompliance(apiClient, nsname, names, "", timeout)
+}
+
+// WaitForClusterCompliant waits up to timeout until every policy in names, in nsname on the hub, reports the
+// cluster clusterName as compliant, regardless of the compliance of the other clusters it is bound to. On timeout,
+// the error lists the compliance state of the policies that are not compliant on the cluster yet.
+func WaitForClusterCompliant(
+	apiClient *clients.Settings, nsname string, names []string, clusterName string, timeout time.Duration) error {
+	if clusterName == "" {
+		return fmt.Errorf("cluster name cannot be empty")
+	}
+
+	return waitForCompliance(apiClient, nsname, names, clusterName, timeout)
+}
+
+// waitForCompliance waits until the policies are compliant, on clusterName when it is not empty.
+func waitForCompliance(
+	apiClient *clients.Settings, nsname string, names []string, clusterName string, timeout time.Duration) error {
+	if apiClient == nil {
+		return fmt.Errorf("apiClient cannot be nil")
+	}
+
+	if len(names) == 0 {
+		return fmt.Errorf("policy names cannot be empty")
+	}
+
+	if err := apiClient.AttachScheme(policiesv1.AddToScheme); err != nil {
+		return fmt.Errorf("failed to add policies scheme to apiClient: %w", err)
+	}
+
+	var pending []string
+
+	err := wait.PollUntilContextTimeout(
+		context.TODO(), min(complianceInterval, timeout), timeout, true, func(ctx context.Context) (bool, error) {
+			pending = nil
+
+			for _, name := range names {
+				state, err := policyComplianceState(ctx, apiClient, nsname, name, clusterName)
+				if err != nil {
+					glog.V(ranparam.LogLevel).Infof("Failed to get compliance of policy %s: %v", name, err)
+
+					pending = append(pending, fmt.Sprintf("%s (error: %v)", name, err))
+
+					continue
+				}
+
+				if state != policiesv1.Compliant {
+					pending = append(pending, fmt.Sprintf("%s (%s)", name, state))
+				}
+			}
+
+			if len(pending) > 0 {
+				glog.V(ranparam.LogLevel).Infof("Waiting for policies to be compliant: %s", strings.Join(pending, ", "))
+			}
+
+			return len(pending) == 0, nil
+		})
+	if err != nil {
+		target := "policies"
+		if clusterName != "" {
+			target = fmt.Sprintf("policies on cluster %s", clusterName)
+		}
+
+		return fmt.Errorf("timed out waiting for %s to be compliant: %s", target, strings.Join(pending, ", "))
+	}
+
+	return nil
+}
+
+// policyComplianceState returns the compliance state of the policy, on clusterName when it is not empty. The state
+// is NotFound when the policy does not exist and Unknown when it has no state yet. Any other error getting the
+// policy is returned.
+func policyComplianceState(ctx context.Context,
+	apiClient *clients.Settings, nsname, name, clusterName string) (policiesv1.ComplianceState, error) {
+	policy := &policiesv1.Policy{}
+
+	err := apiClient.Get(ctx, types.NamespacedName{Name: name, Namespace: nsname}, policy)
+	if k8serrors.IsNotFound(err) {
+		return "NotFound", nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to get policy %s in namespace %s: %w", name, nsname, err)
+	}
+
+	state := policy.Status.ComplianceState
+
+	if clusterName != "" {
+		state = ""
+
+		for _, clusterStatus := range policy.Status.Status {
+			if clusterStatus != nil && clusterStatus.ClusterName == clusterName {
+				state = clusterStatus.ComplianceState
+			}
+		}
+	}
+
+	if state == "" {
+		return "Unknown", nil
+	}
+
+	return state, nil
+}
@@ -0,0 +1,130 @@
+package ranpolicy
+
+import (
+	"fmt"
+	"io/fs"
+	"maps"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// OverlaySourceCR returns a copy of the source CR with the metadata, spec and data of sourceFile merged over it like
+// the ztp policy generator does: maps are merged key by key, while other values, lists included, replace the source
+// ones. The placeholders of the source CR, values starting with $, left without an overlay are then removed.
+func OverlaySourceCR(sourceCR map[string]any, sourceFile SourceFile) map[string]any {
+	overlaid := deepCopyMap(sourceCR)
+
+	for field, overlay := range map[string]map[string]any{
+		"metadata": sourceFile.Metadata,
+		"spec":     sourceFile.Spec,
+		"data":     sourceFile.Data,
+	} {
+		if len(overlay) == 0 {
+			continue
+		}
+
+		existing, _ := overlaid[field].(map[string]any)
+		overlaid[field] = mergeMaps(existing, overlay)
+	}
+
+	return removePlaceholders(overlaid)
+}
+
+// loadSourceCR reads the source CR fileName from sourceCRs and checks it has a kind and a name.
+func loadSourceCR(sourceCRs fs.FS, fileName string) (map[string]any, error) {
+	content, err := fs.ReadFile(sourceCRs, fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source CR %s: %w", fileName, err)
+	}
+
+	sourceCR := map[string]any{}
+
+	if err := yaml.Unmarshal(content, &sourceCR); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal source CR %s: %w", fileName, err)
+	}
+
+	if kind, _ := sourceCR["kind"].(string); kind == "" {
+		return nil, fmt.Errorf("source CR %s has no kind", fileName)
+	}
+
+	return sourceCR, nil
+}
+
+// mergeMaps returns a copy of base with overlay merged over it.
+func mergeMaps(base, overlay map[string]any) map[string]any {
+	merged := deepCopyMap(base)
+	if merged == nil {
+		merged = map[string]any{}
+	}
+
+	for key, value := range overlay {
+		overlayMap, isOverlayMap := value.(map[string]any)
+		baseMap, isBaseMap := merged[key].(map[string]any)
+
+		if isOverlayMap && isBaseMap {
+			merged[key] = mergeMaps(baseMap, overlayMap)
+
+			continue
+		}
+
+		merged[key] = deepCopyValue(value)
+	}
+
+	return merged
+}
+
+// removePlaceholders removes the string values starting with $ from object, and from the maps and lists it holds.
+func removePlaceholders(object map[string]any) map[string]any {
+	for key, value := range object {
+		switch typed := value.(type) {
+		case string:
+			if strings.HasPrefix(typed, "$") {
+				delete(object, key)
+			}
+		case map[string]any:
+			object[key] = removePlaceholders(typed)
+		case []any:
+			for index, item := range typed {
+				if itemMap, isMap := item.(map[string]any); isMap {
+					typed[index] = removePlaceholders(itemMap)
+				}
+			}
+		}
+	}
+
+	return object
+}
+
+// deepCopyMap returns a copy of object sharing none of its maps and lists.
+func deepCopyMap(object map[string]any) map[string]any {
+	if object == nil {
+		return nil
+	}
+
+	copied := maps.Clone(object)
+
+	for key, value := range copied {
+		copied[key] = deepCopyValue(value)
+	}
+
+	return copied
+}
+
+// deepCopyValue returns a copy of value sharing none of its maps and lists.
+func deepCopyValue(value any) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		return deepCopyMap(typed)
+	case []any:
+		copied := make([]any, len(typed))
+
+		for index, item := range typed {
+			copied[index] = deepCopyValue(item)
+		}
+
+		return copied
+	default:
+		return value
+	}
+}
@@ -0,0 +1,92 @@
+package ranpolicy
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverlaySourceCR(t *testing.T) {
+	sourceCR := map[string]any{
+		"apiVersion": "ptp.openshift.io/v1",
+		"kind":       "PtpConfig",
+		"metadata": map[string]any{
+			"name":        "du-ptp-slave",
+			"namespace":   "openshift-ptp",
+			"annotations": map[string]any{"ran.openshift.io/ztp-deploy-wave": "10"},
+		},
+		"spec": map[string]any{
+			"profile": []any{map[string]any{"name": "slave", "interface": "$interface"}},
+			"recommend": []any{
+				map[string]any{"profile": "slave", "priority": int64(4)},
+			},
+		},
+	}
+
+	overlaid := OverlaySourceCR(sourceCR, SourceFile{
+		Metadata: map[string]any{"labels": map[string]any{"du": "true"}},
+		Spec: map[string]any{
+			"recommend": []any{map[string]any{"profile": "grandmaster", "priority": int64(5)}},
+			"extra":     map[string]any{"enabled": true},
+		},
+		Data: map[string]any{"key": "value"},
+	})
+
+	assert.Equal(t, map[string]any{
+		"apiVersion": "ptp.openshift.io/v1",
+		"kind":       "PtpConfig",
+		"metadata": map[string]any{
+			"name":        "du-ptp-slave",
+			"namespace":   "openshift-ptp",
+			"annotations": map[string]any{"ran.openshift.io/ztp-deploy-wave": "10"},
+			"labels":      map[string]any{"du": "true"},
+		},
+		"spec": map[string]any{
+			"profile":   []any{map[string]any{"name": "slave"}},
+			"recommend": []any{map[string]any{"profile": "grandmaster", "priority": int64(5)}},
+			"extra":     map[string]any{"enabled": true},
+		},
+		"data": map[string]any{"key": "value"},
+	}, overlaid)
+
+	profile := sourceCR["spec"].(map[string]any)["profile"].([]any)[0].(map[string]any)
+	assert.Equal(t, "$interface", profile["interface"], "source CR must not be modified")
+
+	overlaid = OverlaySourceCR(sourceCR, SourceFile{
+		Spec: map[string]any{"profile": []any{map[string]any{"name": "slave", "interface": "ens5f0"}}},
+	})
+	assert.Equal(t, []any{map[string]any{"name": "slave", "interface": "ens5f0"}},
+		overlaid["spec"].(map[string]any)["profile"])
+}
+
+func TestLoadSourceCR(t *testing.T) {
+	sourceCRs := fstest.MapFS{
+		"ConfigMap.yaml": {Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n")},
+		"NoKind.yaml":    {Data: []byte("apiVersion: v1\nmetadata:\n  name: config\n")},
+		"Invalid.yaml":   {Data: []byte("kind: [")},
+	}
+
+	testCases := []struct {
+		fileName      string
+		expectedKind  string
+		expectedError string
+	}{
+		{fileName: "ConfigMap.yaml", expectedKind: "ConfigMap"},
+		{fileName: "NoKind.yaml", expectedError: "source CR NoKind.yaml has no kind"},
+		{fileName: "Invalid.yaml", expectedError: "failed to unmarshal source CR Invalid.yaml"},
+		{fileName: "Missing.yaml", expectedError: "failed to read source CR Missing.yaml"},
+	}
+
+	for _, testCase := range testCases {
+		sourceCR, err := loadSourceCR(sourceCRs, testCase.fileName)
+		if testCase.expectedError != "" {
+			assert.ErrorContains(t, err, testCase.expectedError, testCase.fileName)
+
+			continue
+		}
+
+		assert.Nil(t, err, testCase.fileName)
+		assert.Equal(t, testCase.expectedKind, sourceCR["kind"], testCase.fileName)
+	}
+}
@@ -0,0 +1,286 @@
+package ranpolicy
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+
+	"github.com/golang/glog"
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/ocm"
+	"github.com/openshift-kni/eco-gotests/tests/cnf/ran/internal/ranparam"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	configurationPolicyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+)
+
+// GeneratedPolicies are the ACM policies generated from a policygentemplate, along with the placementrule selecting
+// the clusters matching its binding rules and the placementbinding binding the policies to it.
+type GeneratedPolicies struct {
+	Policies         []*ocm.PolicyBuilder
+	PlacementRule    *ocm.PlacementRuleBuilder
+	PlacementBinding *ocm.PlacementBindingBuilder
+}
+
+// Policies generates the ACM policies of the policygentemplate like the ztp policy generator, without calling the
+// API. Each policy named <policygentemplate>-<policyName> holds a configuration policy with the source CRs of that
+// policy, read from sourceCRs and overlaid using OverlaySourceCR, in the order they were added. Every policy is
+// bound to the placementrule <policygentemplate>-placementrules, which selects the clusters using the binding rules.
+func (builder *PolicyGenTemplateBuilder) Policies(
+	apiClient *clients.Settings, sourceCRs fs.FS) (*GeneratedPolicies, error) {
+	if valid, err := builder.validate(); !valid {
+		return nil, err
+	}
+
+	if apiClient == nil {
+		return nil, fmt.Errorf("apiClient cannot be nil")
+	}
+
+	if sourceCRs == nil {
+		return nil, fmt.Errorf("source CRs cannot be nil")
+	}
+
+	pgt := builder.Definition
+
+	policyNames, objectTemplates, err := builder.objectTemplates(sourceCRs)
+	if err != nil {
+		return nil, err
+	}
+
+	generated := &GeneratedPolicies{
+		PlacementRule: ocm.NewPlacementRuleBuilder(apiClient, pgt.Name+"-placementrules", pgt.Namespace),
+	}
+
+	if generated.PlacementRule == nil {
+		return nil, fmt.Errorf("failed to define placementrule of policygentemplate %s", pgt.Name)
+	}
+
+	generated.PlacementRule.Definition.Spec.ClusterSelector = builder.clusterSelector()
+
+	for _, policyName := range policyNames {
+		policy := ocm.NewPolicyBuilder(apiClient, pgt.Name+"-"+policyName, pgt.Namespace,
+			builder.policyTemplate(policyName, objectTemplates[policyName])).
+			WithRemediationAction(pgt.Spec.RemediationAction)
+		if policy == nil {
+			return nil, fmt.Errorf("failed to define policy %s of policygentemplate %s", policyName, pgt.Name)
+		}
+
+		generated.bindPolicy(apiClient, pgt.Name, policy)
+	}
+
+	if generated.PlacementBinding == nil {
+		return nil, fmt.Errorf("failed to define placementbinding of policygentemplate %s", pgt.Name)
+	}
+
+	return generated, nil
+}
+
+// objectTemplates returns the names of the policies of the policygentemplate, in the order they were added, and the
+// object templates of each policy, built from the source CRs read from sourceCRs and overlaid using OverlaySourceCR.
+func (builder *PolicyGenTemplateBuilder) objectTemplates(
+	sourceCRs fs.FS) ([]string, map[string][]*configurationPolicyv1.ObjectTemplate, error) {
+	pgt := builder.Definition
+
+	var (
+		policyNames     []string
+		objectTemplates = map[string][]*configurationPolicyv1.ObjectTemplate{}
+	)
+
+	for _, sourceFile := range pgt.Spec.SourceFiles {
+		sourceCR, err := loadSourceCR(sourceCRs, sourceFile.FileName)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		complianceType := sourceFile.ComplianceType
+		if complianceType == "" {
+			complianceType = pgt.Spec.ComplianceType
+		}
+
+		if complianceType == "" {
+			complianceType = configurationPolicyv1.MustHave
+		}
+
+		if !slices.Contains(policyNames, sourceFile.PolicyName) {
+			policyNames = append(policyNames, sourceFile.PolicyName)
+		}
+
+		objectTemplates[sourceFile.PolicyName] = append(objectTemplates[sourceFile.PolicyName],
+			&configurationPolicyv1.ObjectTemplate{
+				ComplianceType: complianceType,
+				ObjectDefinition: runtime.RawExtension{
+					Object: &unstructured.Unstructured{Object: OverlaySourceCR(sourceCR, sourceFile)},
+				},
+			})
+	}
+
+	return policyNames, objectTemplates, nil
+}
+
+// bindPolicy adds policy to the generated policies and binds it to the placementrule, defining the placementbinding
+// <pgtName>-placementbinding with the first policy.
+func (generated *GeneratedPolicies) bindPolicy(apiClient *clients.Settings, pgtName string, policy *ocm.PolicyBuilder) {
+	generated.Policies = append(generated.Policies, policy)
+
+	subject := policiesv1.Subject{
+		APIGroup: policiesv1.SchemeGroupVersion.Group,
+		Kind:     policiesv1.Kind,
+		Name:     policy.Definition.Name,
+	}
+
+	if generated.PlacementBinding == nil {
+		generated.PlacementBinding = ocm.NewPlacementBindingBuilder(apiClient,
+			pgtName+"-placementbinding", policy.Definition.Namespace, policiesv1.PlacementSubject{
+				APIGroup: "apps.open-cluster-management.io",
+				Kind:     "PlacementRule",
+				Name:     generated.PlacementRule.Definition.Name,
+			}, subject)
+
+		return
+	}
+
+	generated.PlacementBinding.WithAdditionalSubject(subject)
+}
+
+// Create creates the placementrule, policies and placementbinding on the hub, updating the ones that already exist
+// so that the generated content is applied when a test changes a policygentemplate.
+func (generated *GeneratedPolicies) Create() error {
+	if generated == nil {
+		return fmt.Errorf("generated policies cannot be nil")
+	}
+
+	glog.V(ranparam.LogLevel).Infof("Creating placementrule %s and %d policies in namespace %s",
+		generated.PlacementRule.Definition.Name, len(generated.Policies), generated.PlacementRule.Definition.Namespace)
+
+	if err := createOrUpdate[*ocm.PlacementRuleBuilder](generated.PlacementRule); err != nil {
+		return fmt.Errorf("failed to apply placementrule %s: %w", generated.PlacementRule.Definition.Name, err)
+	}
+
+	for _, policy := range generated.Policies {
+		if err := createOrUpdate[*ocm.PolicyBuilder](policy); err != nil {
+			return fmt.Errorf("failed to apply policy %s: %w", policy.Definition.Name, err)
+		}
+	}
+
+	if err := createOrUpdate[*ocm.PlacementBindingBuilder](generated.PlacementBinding); err != nil {
+		return fmt.Errorf("failed to apply placementbinding %s: %w", generated.PlacementBinding.Definition.Name, err)
+	}
+
+	return nil
+}
+
+// Delete deletes the placementbinding, policies and placementrule from the hub, ignoring the ones that do not exist.
+// Every deletion is attempted and the errors of the ones that failed are joined.
+func (generated *GeneratedPolicies) Delete() error {
+	if generated == nil {
+		return fmt.Errorf("generated policies cannot be nil")
+	}
+
+	var errs []error
+
+	if _, err := generated.PlacementBinding.Delete(); err != nil && !k8serrors.IsNotFound(err) {
+		errs = append(errs,
+			fmt.Errorf("failed to delete placementbinding %s: %w", generated.PlacementBinding.Definition.Name, err))
+	}
+
+	for _, policy := range generated.Policies {
+		if _, err := policy.Delete(); err != nil && !k8serrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to delete policy %s: %w", policy.Definition.Name, err))
+		}
+	}
+
+	if _, err := generated.PlacementRule.Delete(); err != nil && !k8serrors.IsNotFound(err) {
+		errs = append(errs,
+			fmt.Errorf("failed to delete placementrule %s: %w", generated.PlacementRule.Definition.Name, err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// PolicyNames returns the names of the generated policies.
+func (generated *GeneratedPolicies) PolicyNames() []string {
+	names := make([]string, 0, len(generated.Policies))
+
+	for _, policy := range generated.Policies {
+		names = append(names, policy.Definition.Name)
+	}
+
+	return names
+}
+
+// policyTemplate returns the template of the policy policyName, a configuration policy with objectTemplates.
+func (builder *PolicyGenTemplateBuilder) policyTemplate(
+	policyName string, objectTemplates []*configurationPolicyv1.ObjectTemplate) *policiesv1.PolicyTemplate {
+	pgt := builder.Definition
+	configurationPolicy := &configurationPolicyv1.ConfigurationPolicy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: configurationPolicyv1.GroupVersion.String(),
+			Kind:       "ConfigurationPolicy",
+		},
+		ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-%s-config", pgt.Name, policyName)},
+		Spec: &configurationPolicyv1.ConfigurationPolicySpec{
+			Severity:          "low",
+			RemediationAction: configurationPolicyv1.RemediationAction(pgt.Spec.RemediationAction),
+			NamespaceSelector: configurationPolicyv1.Target{
+				Include: []configurationPolicyv1.NonEmptyString{"*"},
+				Exclude: []configurationPolicyv1.NonEmptyString{"kube-*", "openshift-*"},
+			},
+			ObjectTemplates: objectTemplates,
+		},
+	}
+
+	if pgt.Spec.EvaluationInterval != nil {
+		configurationPolicy.Spec.EvaluationInterval = configurationPolicyv1.EvaluationInterval{
+			Compliant:    pgt.Spec.EvaluationInterval.Compliant,
+			NonCompliant: pgt.Spec.EvaluationInterval.NonCompliant,
+		}
+	}
+
+	return &policiesv1.PolicyTemplate{ObjectDefinition: runtime.RawExtension{Object: configurationPolicy}}
+}
+
+// clusterSelector returns the selector of the clusters matching every binding rule and no binding excluded rule.
+func (builder *PolicyGenTemplateBuilder) clusterSelector() *metav1.LabelSelector {
+	selector := &metav1.LabelSelector{}
+
+	for _, rules := range []struct {
+		rules    map[string]string
+		operator metav1.LabelSelectorOperator
+	}{
+		{rules: builder.Definition.Spec.BindingRules, operator: metav1.LabelSelectorOpIn},
+		{rules: builder.Definition.Spec.BindingExcludedRules, operator: metav1.LabelSelectorOpNotIn},
+	} {
+		for _, key := range slices.Sorted(maps.Keys(rules.rules)) {
+			selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+				Key: key, Operator: rules.operator, Values: []string{rules.rules[key]},
+			})
+		}
+	}
+
+	return selector
+}
+
+// hubBuilder is an ocm builder of type T that can be created or updated.
+type hubBuilder[T any] interface {
+	Exists() bool
+	Create() (T, error)
+	Update(force bool) (T, error)
+}
+
+// createOrUpdate creates the resource of builder, or force updates it when it already exists.
+func createOrUpdate[T any](builder hubBuilder[T]) error {
+	var err error
+
+	if builder.Exists() {
+		_, err = builder.Update(true)
+	} else {
+		_, err = builder.Create()
+	}
+
+	return err
+}
@@ -0,0 +1,294 @@
+package ranpolicy
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/openshift-kni/eco-goinfra/pkg/clients"
+	"github.com/openshift-kni/eco-goinfra/pkg/ocm"
+	"github.com/stretchr/testify/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	configurationPolicyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	placementrulev1 "open-cluster-management.io/multicloud-operators-subscription/pkg/apis/apps/placementrule/v1"
+	runtimeClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/yaml"
+)
+
+var testSourceCRs = fstest.MapFS{
+	"ClusterLogForwarder.yaml": {Data: []byte("apiVersion: logging.openshift.io/v1\nkind: ClusterLogForwarder\n" +
+		"metadata:\n  name: instance\n  namespace: openshift-logging\nspec:\n  outputs: $outputs\n")},
+	"PerformanceProfile.yaml": {Data: []byte("apiVersion: performance.openshift.io/v2\nkind: PerformanceProfile\n" +
+		"metadata:\n  name: $name\nspec:\n  cpu:\n    isolated: $isolated\n    reserved: $reserved\n")},
+	"ReduceMonitoringFootprint.yaml": {Data: []byte("apiVersion: v1\nkind: ConfigMap\n" +
+		"metadata:\n  name: cluster-monitoring-config\n  namespace: openshift-monitoring\n" +
+		"data:\n  config.yaml: |\n    grafana:\n      enabled: false\n")},
+}
+
+func TestPolicyGenTemplateBuilder(t *testing.T) {
+	testCases := []struct {
+		name          string
+		builder       *PolicyGenTemplateBuilder
+		expectedError string
+	}{
+		{
+			name:          "empty name",
+			builder:       NewPolicyGenTemplateBuilder("", "ztp-group"),
+			expectedError: "policygentemplate name cannot be empty",
+		},
+		{
+			name:          "empty namespace",
+			builder:       NewPolicyGenTemplateBuilder("group-du-sno", ""),
+			expectedError: "policygentemplate namespace cannot be empty",
+		},
+		{
+			name:          "no source CRs",
+			builder:       NewPolicyGenTemplateBuilder("group-du-sno", "ztp-group"),
+			expectedError: "policygentemplate group-du-sno has no source CRs",
+		},
+		{
+			name:          "invalid mcp",
+			builder:       newTestBuilder().WithMCP("infra"),
+			expectedError: `WithMCP: unsupported mcp "infra", must be master or worker`,
+		},
+		{
+			name:    "invalid remediation action",
+			builder: newTestBuilder().WithRemediationAction("remediate"),
+			expectedError: `WithRemediationAction: unsupported remediation action "remediate", ` +
+				"must be Inform or Enforce",
+		},
+		{
+			name: "invalid compliance type",
+			builder: newTestBuilder().WithSourceCR(SourceFile{
+				FileName: "ClusterLogForwarder.yaml", PolicyName: "log-policy", ComplianceType: "MustMatch",
+			}),
+			expectedError: `WithSourceCR: unsupported compliance type "MustMatch" for source CR ` +
+				"ClusterLogForwarder.yaml",
+		},
+		{
+			name:          "empty source CR",
+			builder:       newTestBuilder().WithSourceCR(SourceFile{FileName: "ClusterLogForwarder.yaml"}),
+			expectedError: "WithSourceCR: source CR file name and policy name cannot be empty",
+		},
+		{
+			name:          "empty binding rules",
+			builder:       newTestBuilder().WithBindingRules(nil),
+			expectedError: "WithBindingRules: binding rules cannot be empty",
+		},
+	}
+
+	for _, testCase := range testCases {
+		assert.EqualError(t, testCase.builder.Render(&bytes.Buffer{}), testCase.expectedError, testCase.name)
+	}
+
+	var rendered bytes.Buffer
+
+	assert.Nil(t, newTestBuilder().Render(&rendered))
+
+	pgt := &PolicyGenTemplate{}
+	assert.Nil(t, yaml.Unmarshal(rendered.Bytes(), pgt))
+	assert.Equal(t, newTestBuilder().Definition, pgt)
+	assert.Contains(t, rendered.String(), "kind: PolicyGenTemplate\n")
+	assert.EqualError(t, newTestBuilder().Render(nil), "writer cannot be nil")
+}
+
+func TestPolicies(t *testing.T) {
+	apiClient := newTestClient()
+
+	generated, err := newTestBuilder().Policies(apiClient, testSourceCRs)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"group-du-sno-config-policy", "group-du-sno-log-policy"}, generated.PolicyNames())
+
+	assert.Equal(t, &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+		{Key: "group-du-sno", Operator: metav1.LabelSelectorOpIn, Values: []string{""}},
+		{Key: "sites", Operator: metav1.LabelSelectorOpIn, Values: []string{"lab"}},
+		{Key: "ztp-done", Operator: metav1.LabelSelectorOpNotIn, Values: []string{""}},
+	}}, generated.PlacementRule.Definition.Spec.ClusterSelector)
+	assert.Equal(t, "group-du-sno-placementrules", generated.PlacementBinding.Definition.PlacementRef.Name)
+	assert.Len(t, generated.PlacementBinding.Definition.Subjects, 2)
+	assert.Equal(t, "group-du-sno-log-policy", generated.PlacementBinding.Definition.Subjects[1].Name)
+
+	policy := generated.Policies[0].Definition
+	assert.Equal(t, policiesv1.Enforce, policy.Spec.RemediationAction)
+
+	configurationPolicy, ok :=
+		policy.Spec.PolicyTemplates[0].ObjectDefinition.Object.(*configurationPolicyv1.ConfigurationPolicy)
+	assert.True(t, ok)
+	assert.Equal(t, "group-du-sno-config-policy-config", configurationPolicy.Name)
+	assert.Equal(t, "10m", configurationPolicy.Spec.EvaluationInterval.Compliant)
+	assert.Len(t, configurationPolicy.Spec.ObjectTemplates, 2)
+	assert.Equal(t, configurationPolicyv1.MustOnlyHave, configurationPolicy.Spec.ObjectTemplates[0].ComplianceType)
+	assert.Equal(t, configurationPolicyv1.MustHave, configurationPolicy.Spec.ObjectTemplates[1].ComplianceType)
+
+	performanceProfile, ok :=
+		configurationPolicy.Spec.ObjectTemplates[0].ObjectDefinition.Object.(*unstructured.Unstructured)
+	assert.True(t, ok)
+	assert.Equal(t, "openshift-node-performance-profile", performanceProfile.GetName())
+	assert.Equal(t, map[string]any{"isolated": "2-31", "reserved": "0-1"},
+		performanceProfile.Object["spec"].(map[string]any)["cpu"])
+
+	assert.Nil(t, generated.Create())
+	assert.Nil(t, generated.Create(), "existing policies are updated")
+
+	for _, policyName := range generated.PolicyNames() {
+		_, err := ocm.PullPolicy(apiClient, policyName, "ztp-group")
+		assert.Nil(t, err, policyName)
+	}
+
+	assert.Nil(t, generated.Delete())
+	assert.False(t, generated.Policies[0].Exists())
+	assert.False(t, generated.PlacementRule.Exists())
+
+	assert.Nil(t, generated.Delete(), "missing policies are ignored")
+
+	failingClient := newFailingTestClient("group-du-sno-config-policy")
+	failing, err := newTestBuilder().Policies(failingClient, testSourceCRs)
+	assert.Nil(t, err)
+	assert.Nil(t, failing.Create())
+
+	err = failing.Delete()
+	assert.ErrorContains(t, err, "failed to delete policy group-du-sno-config-policy")
+	assert.True(t, k8serrors.IsInternalError(err))
+	assert.False(t, failing.Policies[1].Exists(), "the remaining policies are still deleted")
+	assert.False(t, failing.PlacementRule.Exists(), "the placementrule is still deleted")
+
+	missing := NewPolicyGenTemplateBuilder("group-du-sno", "ztp-group").
+		WithSourceCR(SourceFile{FileName: "Missing.yaml", PolicyName: "config-policy"})
+	_, err = missing.Policies(apiClient, testSourceCRs)
+	assert.ErrorContains(t, err, "failed to read source CR Missing.yaml")
+
+	_, err = newTestBuilder().Policies(nil, testSourceCRs)
+	assert.EqualError(t, err, "apiClient cannot be nil")
+}
+
+func TestWaitForCompliance(t *testing.T) {
+	complianceInterval = time.Millisecond
+
+	apiClient := newTestClient(
+		buildDummyPolicy("compliant", policiesv1.Compliant, map[string]policiesv1.ComplianceState{
+			"spoke-1": policiesv1.Compliant, "spoke-2": policiesv1.Compliant,
+		}),
+		buildDummyPolicy("partial", policiesv1.NonCompliant, map[string]policiesv1.ComplianceState{
+			"spoke-1": policiesv1.Compliant, "spoke-2": policiesv1.NonCompliant,
+		}),
+		buildDummyPolicy("pending", "", nil),
+	)
+
+	assert.Nil(t, WaitForPoliciesCompliant(apiClient, "ztp-group", []string{"compliant"}, time.Second))
+	assert.Nil(t, WaitForClusterCompliant(apiClient, "ztp-group", []string{"compliant", "partial"}, "spoke-1",
+		time.Second))
+
+	err := WaitForPoliciesCompliant(apiClient, "ztp-group", []string{"compliant", "partial", "pending", "missing"},
+		10*time.Millisecond)
+	assert.EqualError(t, err, "timed out waiting for policies to be compliant: partial (NonCompliant), "+
+		"pending (Unknown), missing (NotFound)")
+
+	err = WaitForClusterCompliant(apiClient, "ztp-group", []string{"partial"}, "spoke-2", 10*time.Millisecond)
+	assert.EqualError(t, err,
+		"timed out waiting for policies on cluster spoke-2 to be compliant: partial (NonCompliant)")
+
+	err = WaitForPoliciesCompliant(newFailingTestClient("broken", buildDummyPolicy("broken", "", nil)), "ztp-group",
+		[]string{"broken"}, 10*time.Millisecond)
+	assert.ErrorContains(t, err, "timed out waiting for policies to be compliant: broken (error: failed to get "+
+		"policy broken in namespace ztp-group")
+
+	assert.EqualError(t, WaitForClusterCompliant(apiClient, "ztp-group", []string{"partial"}, "", time.Second),
+		"cluster name cannot be empty")
+	assert.EqualError(t, WaitForPoliciesCompliant(apiClient, "ztp-group", nil, time.Second),
+		"policy names cannot be empty")
+}
+
+func newTestBuilder() *PolicyGenTemplateBuilder {
+	return NewPolicyGenTemplateBuilder("group-du-sno", "ztp-group").
+		WithBindingRules(map[string]string{"group-du-sno": "", "sites": "lab"}).
+		WithBindingExcludedRules(map[string]string{"ztp-done": ""}).
+		WithMCP("master").
+		WithRemediationAction(policiesv1.Enforce).
+		WithEvaluationInterval("10m", "10s").
+		WithSourceCR(SourceFile{
+			FileName:       "PerformanceProfile.yaml",
+			PolicyName:     "config-policy",
+			ComplianceType: configurationPolicyv1.MustOnlyHave,
+			Metadata:       map[string]any{"name": "openshift-node-performance-profile"},
+			Spec:           map[string]any{"cpu": map[string]any{"isolated": "2-31", "reserved": "0-1"}},
+		}).
+		WithSourceCR(SourceFile{FileName: "ReduceMonitoringFootprint.yaml", PolicyName: "config-policy"}).
+		WithSourceCR(SourceFile{FileName: "ClusterLogForwarder.yaml", PolicyName: "log-policy"})
+}
+
+func newTestClient(objects ...runtime.Object) *clients.Settings {
+	return clients.GetTestClients(clients.TestClientParams{
+		K8sMockObjects: objects,
+		SchemeAttachers: []clients.SchemeAttacher{
+			policiesv1.AddToScheme,
+			placementrulev1.AddToScheme,
+		},
+	})
+}
+
+// newFailingTestClient returns a fake client with objects whose gets of the policy failingName, once it exists, and
+// deletions of it fail with an internal error.
+func newFailingTestClient(failingName string, objects ...runtime.Object) *clients.Settings {
+	apiClient, testBuilder := clients.GetModifiableTestClients(clients.TestClientParams{
+		K8sMockObjects: objects,
+		SchemeAttachers: []clients.SchemeAttacher{
+			policiesv1.AddToScheme,
+			placementrulev1.AddToScheme,
+		},
+	})
+
+	failure := k8serrors.NewInternalError(assert.AnError)
+	isFailing := func(obj runtimeClient.Object) bool {
+		_, isPolicy := obj.(*policiesv1.Policy)
+
+		return isPolicy && obj.GetName() == failingName
+	}
+
+	apiClient.Client = testBuilder.WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, client runtimeClient.WithWatch, key runtimeClient.ObjectKey,
+			obj runtimeClient.Object, opts ...runtimeClient.GetOption) error {
+			if err := client.Get(ctx, key, obj, opts...); err != nil || key.Name != failingName {
+				return err
+			}
+
+			if _, isPolicy := obj.(*policiesv1.Policy); isPolicy {
+				return failure
+			}
+
+			return nil
+		},
+		Delete: func(ctx context.Context, client runtimeClient.WithWatch,
+			obj runtimeClient.Object, opts ...runtimeClient.DeleteOption) error {
+			if isFailing(obj) {
+				return failure
+			}
+
+			return client.Delete(ctx, obj, opts...)
+		},
+	}).Build()
+
+	return apiClient
+}
+
+func buildDummyPolicy(
+	name string, state policiesv1.ComplianceState, clusters map[string]policiesv1.ComplianceState) *policiesv1.Policy {
+	policy := &policiesv1.Policy{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ztp-group"},
+		Status:     policiesv1.PolicyStatus{ComplianceState: state},
+	}
+
+	for clusterName, clusterState := range clusters {
+		policy.Status.Status = append(policy.Status.Status, &policiesv1.CompliancePerClusterStatus{
+			ClusterName: clusterName, ClusterNamespace: clusterName, ComplianceState: clusterState,
+		})
+	}
+
+	return policy
+}
@@ -0,0 +1,252 @@
+package ranpolicy
+
+import (
+	"fmt"
+	"io"
+	"maps"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	configurationPolicyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+	policiesv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// PolicyGenTemplateAPIVersion is the apiVersion of the policygentemplates rendered by the ztp policy generator.
+	PolicyGenTemplateAPIVersion = "ran.openshift.io/v1"
+	// PolicyGenTemplateKind is the kind of the policygentemplates rendered by the ztp policy generator.
+	PolicyGenTemplateKind = "PolicyGenTemplate"
+)
+
+// PolicyGenTemplate is a policygentemplate of the ztp policy generator. Only the fields used by the RAN DU tests are
+// defined, since the type is not vendored.
+type PolicyGenTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec PolicyGenTemplateSpec `json:"spec"`
+}
+
+// PolicyGenTemplateSpec is the spec of a policygentemplate. The policies are bound to the managed clusters whose
+// labels match every binding rule and none of the binding excluded rules.
+type PolicyGenTemplateSpec struct {
+	BindingRules         map[string]string                    `json:"bindingRules,omitempty"`
+	BindingExcludedRules map[string]string                    `json:"bindingExcludedRules,omitempty"`
+	MCP                  string                               `json:"mcp,omitempty"`
+	RemediationAction    policiesv1.RemediationAction         `json:"remediationAction,omitempty"`
+	ComplianceType       configurationPolicyv1.ComplianceType `json:"complianceType,omitempty"`
+	EvaluationInterval   *EvaluationInterval                  `json:"evaluationInterval,omitempty"`
+	SourceFiles          []SourceFile                         `json:"sourceFiles"`
+}
+
+// EvaluationInterval is how often the configuration policies are evaluated once compliant and non compliant.
+type EvaluationInterval struct {
+	Compliant    string `json:"compliant,omitempty"`
+	NonCompliant string `json:"noncompliant,omitempty"`
+}
+
+// SourceFile is a source CR of a policygentemplate, read from FileName in the source CRs and wrapped in the policy
+// PolicyName. Metadata, Spec and Data are overlaid on the source CR, see OverlaySourceCR.
+type SourceFile struct {
+	FileName       string                               `json:"fileName"`
+	PolicyName     string                               `json:"policyName"`
+	ComplianceType configurationPolicyv1.ComplianceType `json:"complianceType,omitempty"`
+	Metadata       map[string]any                       `json:"metadata,omitempty"`
+	Spec           map[string]any                       `json:"spec,omitempty"`
+	Data           map[string]any                       `json:"data,omitempty"`
+}
+
+// PolicyGenTemplateBuilder provides a struct for building a policygentemplate, and the ACM policies it generates,
+// for the RAN DU configuration tests. The first error of the With* methods is kept and returned by Render and
+// Policies.
+type PolicyGenTemplateBuilder struct {
+	// Definition of the policygentemplate.
+	Definition *PolicyGenTemplate
+	err        error
+}
+
+// NewPolicyGenTemplateBuilder creates a new instance of PolicyGenTemplateBuilder. The policies are informative
+// unless WithRemediationAction is used.
+func NewPolicyGenTemplateBuilder(name, nsname string) *PolicyGenTemplateBuilder {
+	builder := &PolicyGenTemplateBuilder{
+		Definition: &PolicyGenTemplate{
+			TypeMeta:   metav1.TypeMeta{APIVersion: PolicyGenTemplateAPIVersion, Kind: PolicyGenTemplateKind},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: nsname},
+			Spec:       PolicyGenTemplateSpec{RemediationAction: policiesv1.Inform},
+		},
+	}
+
+	if name == "" {
+		builder.err = fmt.Errorf("policygentemplate name cannot be empty")
+
+		return builder
+	}
+
+	if nsname == "" {
+		builder.err = fmt.Errorf("policygentemplate namespace cannot be empty")
+	}
+
+	return builder
+}
+
+// GetError returns the first error recorded while building the policygentemplate, or nil.
+func (builder *PolicyGenTemplateBuilder) GetError() error {
+	return builder.err
+}
+
+// WithBindingRules adds binding rules, labels the managed clusters must have for the policies to be bound to them.
+func (builder *PolicyGenTemplateBuilder) WithBindingRules(rules map[string]string) *PolicyGenTemplateBuilder {
+	if builder.err != nil {
+		return builder
+	}
+
+	if len(rules) == 0 {
+		builder.err = fmt.Errorf("WithBindingRules: binding rules cannot be empty")
+
+		return builder
+	}
+
+	if builder.Definition.Spec.BindingRules == nil {
+		builder.Definition.Spec.BindingRules = map[string]string{}
+	}
+
+	maps.Copy(builder.Definition.Spec.BindingRules, rules)
+
+	return builder
+}
+
+// WithBindingExcludedRules adds binding excluded rules, labels excluding the managed clusters having them from the
+// policies.
+func (builder *PolicyGenTemplateBuilder) WithBindingExcludedRules(rules map[string]string) *PolicyGenTemplateBuilder {
+	if builder.err != nil {
+		return builder
+	}
+
+	if len(rules) == 0 {
+		builder.err = fmt.Errorf("WithBindingExcludedRules: binding excluded rules cannot be empty")
+
+		return builder
+	}
+
+	if builder.Definition.Spec.BindingExcludedRules == nil {
+		builder.Definition.Spec.BindingExcludedRules = map[string]string{}
+	}
+
+	maps.Copy(builder.Definition.Spec.BindingExcludedRules, rules)
+
+	return builder
+}
+
+// WithMCP sets the machine config pool, master or worker, the source CRs of the policygentemplate target.
+func (builder *PolicyGenTemplateBuilder) WithMCP(mcp string) *PolicyGenTemplateBuilder {
+	if builder.err != nil {
+		return builder
+	}
+
+	if mcp != "master" && mcp != "worker" {
+		builder.err = fmt.Errorf("WithMCP: unsupported mcp %q, must be master or worker", mcp)
+
+		return builder
+	}
+
+	builder.Definition.Spec.MCP = mcp
+
+	return builder
+}
+
+// WithRemediationAction sets the remediation action of the generated policies, Inform or Enforce.
+func (builder *PolicyGenTemplateBuilder) WithRemediationAction(
+	action policiesv1.RemediationAction) *PolicyGenTemplateBuilder {
+	if builder.err != nil {
+		return builder
+	}
+
+	if action != policiesv1.Inform && action != policiesv1.Enforce {
+		builder.err = fmt.Errorf("WithRemediationAction: unsupported remediation action %q, must be %s or %s",
+			action, policiesv1.Inform, policiesv1.Enforce)
+
+		return builder
+	}
+
+	builder.Definition.Spec.RemediationAction = action
+
+	return builder
+}
+
+// WithEvaluationInterval sets how often the generated configuration policies are evaluated once compliant and non
+// compliant, as durations such as 10m or never.
+func (builder *PolicyGenTemplateBuilder) WithEvaluationInterval(
+	compliant, nonCompliant string) *PolicyGenTemplateBuilder {
+	if builder.err != nil {
+		return builder
+	}
+
+	builder.Definition.Spec.EvaluationInterval = &EvaluationInterval{Compliant: compliant, NonCompliant: nonCompliant}
+
+	return builder
+}
+
+// WithSourceCR adds a source CR wrapped in the policy sourceFile.PolicyName, overlaid with the metadata, spec and
+// data of sourceFile.
+func (builder *PolicyGenTemplateBuilder) WithSourceCR(sourceFile SourceFile) *PolicyGenTemplateBuilder {
+	if builder.err != nil {
+		return builder
+	}
+
+	if sourceFile.FileName == "" || sourceFile.PolicyName == "" {
+		builder.err = fmt.Errorf("WithSourceCR: source CR file name and policy name cannot be empty")
+
+		return builder
+	}
+
+	switch sourceFile.ComplianceType {
+	case "", configurationPolicyv1.MustHave, configurationPolicyv1.MustOnlyHave, configurationPolicyv1.MustNotHave:
+	default:
+		builder.err = fmt.Errorf("WithSourceCR: unsupported compliance type %q for source CR %s",
+			sourceFile.ComplianceType, sourceFile.FileName)
+
+		return builder
+	}
+
+	builder.Definition.Spec.SourceFiles = append(builder.Definition.Spec.SourceFiles, sourceFile)
+
+	return builder
+}
+
+// Render writes the policygentemplate to writer as YAML, to be committed to the policies git repository of the ZTP
+// GitOps flow.
+func (builder *PolicyGenTemplateBuilder) Render(writer io.Writer) error {
+	if valid, err := builder.validate(); !valid {
+		return err
+	}
+
+	if writer == nil {
+		return fmt.Errorf("writer cannot be nil")
+	}
+
+	content, err := yaml.Marshal(builder.Definition)
+	if err != nil {
+		return fmt.Errorf("failed to marshal policygentemplate %s: %w", builder.Definition.Name, err)
+	}
+
+	_, err = writer.Write(content)
+
+	return err
+}
+
+// validate checks the builder has no error and the policygentemplate has source CRs.
+func (builder *PolicyGenTemplateBuilder) validate() (bool, error) {
+	if builder == nil {
+		return false, fmt.Errorf("policygentemplate builder cannot be nil")
+	}
+
+	if builder.err != nil {
+		return false, builder.err
+	}
+
+	if len(builder.Definition.Spec.SourceFiles) == 0 {
+		return false, fmt.Errorf("policygentemplate %s has no source CRs", builder.Definition.Name)
+	}
+
+	return true, nil
+}